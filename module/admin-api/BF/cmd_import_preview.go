@@ -0,0 +1,111 @@
+package BF
+
+import (
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"github.com/tealeg/xlsx"
+)
+
+const previewModuleCmds = "cmds"
+
+// column of each invalid record status in template
+var recordStatusColumn = map[int]int{
+	RecordStatusClassExceedMax: 0,
+	RecordStatusNameExceedMax:  1,
+	RecordStatusNameError:      1,
+	RecordStatusTargetError:    2,
+	RecordStatusPriodError:     6,
+	RecordStatusResponseError:  8,
+}
+
+// template checking status which can not be previewed
+var fileStatusMsgKey = map[int]string{
+	StatusFileOpenError:  "CmdUploadSheetErr",
+	StatusFileFormat:     "CmdUploadSheetErr",
+	StatusTemplateFormat: "CmdUploadTemplateErr",
+	StatusSizeExceed:     "CmdUploadSizeExceed",
+	StatusRowsExceed:     "CmdUploadRowsExceed",
+}
+
+// parseImportCmdsPreview checks uploaded commands like ProcessImportCmdFile,
+// invalid records are returned as row errors instead of being skipped when import.
+func parseImportCmdsPreview(r *http.Request, appid string, upload *importpreview.Upload) (importpreview.RowErrors, *importpreview.Diff, func() error, AdminErrors.AdminError) {
+	locale := requestheader.GetLocale(r)
+	userid := requestheader.GetUserID(r)
+	info := &multipart.FileHeader{
+		Filename: upload.Filename,
+		Size:     int64(len(upload.Content)),
+	}
+
+	fileErr := func(status int) importpreview.RowErrors {
+		return importpreview.RowErrors{
+			&importpreview.RowError{Message: localemsg.Get(locale, fileStatusMsgKey[status])},
+		}
+	}
+	file, err := xlsx.OpenBinary(upload.Content)
+	if err != nil {
+		return fileErr(StatusFileOpenError), nil, nil, nil
+	}
+	if status, _ := checkTemplateFormat(file, info, locale); status != StatusOk {
+		return fileErr(status), nil, nil, nil
+	}
+	if status, _ := checkFileContent(file, locale); status != StatusOk {
+		return fileErr(status), nil, nil, nil
+	}
+
+	commands, valid, err := parseCommandFromFile(file, locale)
+	if err != nil {
+		return importpreview.ToRowErrors(err), nil, nil, nil
+	}
+	sheetName := localemsg.Get(locale, "CmdSheetName")
+	rowErrs := importpreview.RowErrors{}
+	for idx, command := range commands {
+		if command.CheckStatus.Status != RecordStatusOk {
+			column := templateHeader(locale)[recordStatusColumn[command.CheckStatus.Status]]
+			// first row of sheet is header
+			rowErrs.Add(sheetName, idx+2, column, command.CheckStatus.Content)
+		}
+	}
+	if len(rowErrs) > 0 {
+		return rowErrs, nil, nil, nil
+	}
+
+	current, err := GetFormatCmdForExport(appid, locale)
+	if err != nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	diff := importpreview.DiffRecords(cmdRecords(current), cmdRecords(commands))
+
+	commit := func() error {
+		record, err := addCmdImportHistoryRecord(appid, userid, info.Filename, len(commands), valid)
+		if err != nil {
+			return err
+		}
+		err = processImportToDb(appid, commands, locale, record)
+		if err != nil {
+			return err
+		}
+		go util.ConsulUpdateCmd(appid, util.NewConsulChange(r, "import commands"))
+		return nil
+	}
+	return nil, diff, commit, nil
+}
+
+// cmdRecords uses class/name as key and other columns as content
+func cmdRecords(commands []*CommandRecord) map[string]string {
+	records := map[string]string{}
+	for _, command := range commands {
+		records[command.Class+"/"+command.Name] = strings.Join([]string{
+			command.Target, command.Tags, command.Keywords, command.Regex,
+			command.Period, command.Answer, command.ResponseType,
+		}, "\n")
+	}
+	return records
+}
@@ -11,6 +11,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/auth"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/pkg/logger"
 )
@@ -116,6 +117,8 @@ func init() {
 			util.NewEntryPoint("POST", "ai/", []string{}, handleRedirect),
 		},
 	}
+	ModuleInfo.EntryPoints = append(ModuleInfo.EntryPoints,
+		importpreview.EntryPoints("cmds/import/preview", []string{"edit"}, 1, previewModuleCmds, "file", parseImportCmdsPreview)...)
 }
 func handleAddEnterprise(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
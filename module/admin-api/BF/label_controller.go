@@ -116,7 +116,7 @@ func handleUpdateCmd(w http.ResponseWriter, r *http.Request) {
 	}
 	cmd.ID = id
 	retObj = cmd
-	go util.ConsulUpdateCmd(appid)
+	go util.ConsulUpdateCmd(appid, util.NewConsulChange(r, "update command"))
 }
 func handleAddCmd(w http.ResponseWriter, r *http.Request) {
 	var retObj interface{}
@@ -149,7 +149,7 @@ func handleAddCmd(w http.ResponseWriter, r *http.Request) {
 	}
 	cmd.ID = id
 	retObj = cmd
-	go util.ConsulUpdateCmd(appid)
+	go util.ConsulUpdateCmd(appid, util.NewConsulChange(r, "add command"))
 }
 func handleDeleteCmd(w http.ResponseWriter, r *http.Request) {
 	var retObj interface{}
@@ -175,7 +175,7 @@ func handleDeleteCmd(w http.ResponseWriter, r *http.Request) {
 		retCode = ApiError.DB_ERROR
 	}
 	if err == nil {
-		go util.ConsulUpdateCmd(appid)
+		go util.ConsulUpdateCmd(appid, util.NewConsulChange(r, "delete command"))
 	}
 }
 func handleGetCmdsOfLabel(w http.ResponseWriter, r *http.Request) {
@@ -483,7 +483,7 @@ func handleMoveCmd(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	go util.ConsulUpdateCmd(appid)
+	go util.ConsulUpdateCmd(appid, util.NewConsulChange(r, "move command"))
 }
 
 
@@ -530,7 +530,7 @@ func handleImportCmds(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go util.ConsulUpdateCmd(appid)
+	go util.ConsulUpdateCmd(appid, util.NewConsulChange(r, "import commands"))
 	return
 }
 func handleGetImportCmdsStatus(w http.ResponseWriter, r *http.Request) {
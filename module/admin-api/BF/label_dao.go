@@ -263,6 +263,14 @@ func addCmd(appid string, cmd *Cmd, cid int) (int, error) {
 	}
 	defer util.ClearTransition(tx)
 
+	id, err := addCmdWithTx(tx, appid, cmd, cid)
+	if err != nil {
+		return -1, err
+	}
+	return id, tx.Commit()
+}
+
+func addCmdWithTx(tx *sql.Tx, appid string, cmd *Cmd, cid int) (int, error) {
 	queryStr := `
 		INSERT INTO cmd
 		(cid, name, target, rule, answer, response_type, status, begin_time, end_time, appid)
@@ -306,8 +314,7 @@ func addCmd(appid string, cmd *Cmd, cid int) (int, error) {
 			return -1, err
 		}
 	}
-
-	return int(id), tx.Commit()
+	return int(id), nil
 }
 
 func updateCmd(appid string, id int, cmd *Cmd) error {
@@ -674,6 +681,14 @@ func addCmdClass(appid string, pid *int, className string) (id int, err error) {
 	}
 	defer util.ClearTransition(t)
 
+	id, err = addCmdClassWithTx(t, appid, pid, className)
+	if err != nil {
+		return
+	}
+	err = t.Commit()
+	return
+}
+func addCmdClassWithTx(t *sql.Tx, appid string, pid *int, className string) (id int, err error) {
 	queryStr := `SELECT count(*) FROM cmd_class WHERE appid = ? AND name = ?`
 	row := t.QueryRow(queryStr, appid, className)
 	count := 0
@@ -693,7 +708,7 @@ func addCmdClass(appid string, pid *int, className string) (id int, err error) {
 
 	// parent will always be nil
 	queryStr = `INSERT INTO cmd_class (appid, name, parent) VALUES (?, ?, ?)`
-	result, err := mySQL.Exec(queryStr, appid, className, pid)
+	result, err := t.Exec(queryStr, appid, className, pid)
 	if err != nil {
 		return
 	}
@@ -703,7 +718,6 @@ func addCmdClass(appid string, pid *int, className string) (id int, err error) {
 		return
 	}
 	id = int(id64)
-	err = t.Commit()
 	return
 }
 func deleteCmdClass(appid string, classID int) (err error) {
@@ -891,40 +905,12 @@ func updateCommandReportFile(recordId int, path string) (error) {
 
 	return  tx.Commit()
 }
-func deleteAllCommand(appid string) (error) {
-	var err error
-	defer func() {
-		util.ShowError(err)
-	}()
-	mySQL := util.GetMainDB()
-	if mySQL == nil {
-		err = errDBNotInit
-		return err
-	}
-
-	tx, err := mySQL.Begin()
-	if err != nil {
-		return  err
-	}
-	defer util.ClearTransition(tx)
+func deleteAllCommandWithTx(tx *sql.Tx, appid string) error {
 	queryStr := `
 		DELETE FROM cmd
         WHERE appid = ?`
-
-
-	queryParams := []interface{}{
-		appid,
-	}
-	_, err = tx.Exec(queryStr, queryParams...)
-	if err != nil {
-		return  err
-	}
-	//id, err := result.LastInsertId()
-	//if err != nil {
-	//	return -1, err
-	//}
-
-	return  tx.Commit()
+	_, err := tx.Exec(queryStr, appid)
+	return err
 }
 
 
@@ -160,15 +160,15 @@ func GetFormatCmdByteForExport(appid string, locale string) (ret []byte, err Adm
 	for _, sheet := range sheets {
 		if sheet.Name == localemsg.Get(locale, "CmdSheetName") {
 			headerRow := sheet.AddRow()
-			headerRow.AddCell().SetString(headerTitle[locale][0])
-			headerRow.AddCell().SetString(headerTitle[locale][1])
-			headerRow.AddCell().SetString(headerTitle[locale][2])
-			headerRow.AddCell().SetString(headerTitle[locale][3])
-			headerRow.AddCell().SetString(headerTitle[locale][4])
-			headerRow.AddCell().SetString(headerTitle[locale][5])
-			headerRow.AddCell().SetString(headerTitle[locale][6])
-			headerRow.AddCell().SetString(headerTitle[locale][7])
-			headerRow.AddCell().SetString(headerTitle[locale][8])
+			headerRow.AddCell().SetString(templateHeader(locale)[0])
+			headerRow.AddCell().SetString(templateHeader(locale)[1])
+			headerRow.AddCell().SetString(templateHeader(locale)[2])
+			headerRow.AddCell().SetString(templateHeader(locale)[3])
+			headerRow.AddCell().SetString(templateHeader(locale)[4])
+			headerRow.AddCell().SetString(templateHeader(locale)[5])
+			headerRow.AddCell().SetString(templateHeader(locale)[6])
+			headerRow.AddCell().SetString(templateHeader(locale)[7])
+			headerRow.AddCell().SetString(templateHeader(locale)[8])
 		}
 	}
 
@@ -331,11 +331,11 @@ func checkTemplateFormat(file *xlsx.File, info *multipart.FileHeader, locale str
 
 	headerRow := contentSheet.Row(0)
 
-	if len(headerRow.Cells) != len(headerTitle[locale]){
+	if len(headerRow.Cells) != len(templateHeader(locale)){
 		return StatusTemplateFormat, nil
 	} else {
 		for idx, cell := range headerRow.Cells {
-			if cell.Value != headerTitle[locale][idx]{
+			if cell.Value != templateHeader(locale)[idx]{
 				return StatusTemplateFormat, nil
 			}
 		}
@@ -378,7 +378,7 @@ func parseCommandFromFile(file *xlsx.File, locale string) (commandRecords []*Com
 		}
 		commandRecord := CommandRecord{}
 		//commandRecord.Class = row.Cells[]
-		//if len(row.Cells) != len(headerTitle[locale]){
+		//if len(row.Cells) != len(templateHeader(locale)){
 		//	return StatusTemplateFormat, nil
 		//}
 
@@ -453,7 +453,15 @@ func checkStatus(row *xlsx.Row, locale string) (RecordStatus) {
 	return status
 }
 
-func processImportToDb(appid string, commandRecords []*CommandRecord, locale string, recordId int) (err error)  {
+// processImportToDb replaces all commands of appid with the valid records in one transaction
+func processImportToDb(appid string, commandRecords []*CommandRecord, locale string, recordId int) (err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+	mySQL := util.GetMainDB()
+	if mySQL == nil {
+		return errDBNotInit
+	}
 
 	labels, err := getSSMLabels(appid)
 	labelsMap := make(map[string]int)
@@ -465,65 +473,66 @@ func processImportToDb(appid string, commandRecords []*CommandRecord, locale str
 
 	cmdClass, err := getCmdClassList(appid)
 	cmdClassMap := make(map[string]int)
-
 	if err == nil {
 		for _, cls := range cmdClass {
 			cmdClassMap[cls.Name] = cls.ID
 		}
 	}
 
-	err = deleteAllCmd(appid)
-
+	tx, err := mySQL.Begin()
 	if err != nil {
-		return  err
+		return err
 	}
+	defer util.ClearTransition(tx)
 
-	for idx, command := range commandRecords {
-		if command.CheckStatus.Status == 0 {
-			commandObj := CommandObj{}
+	err = deleteAllCommandWithTx(tx, appid)
+	if err != nil {
+		return err
+	}
 
-			if _, ok := cmdClassMap[command.Class];!ok{
-				classMap, err := saveAndUpdateCmdClass(appid, command.Class, cmdClassMap)
-				if err != nil {
-					cmdClassMap = classMap
-				}
-			}
-			commandObj.ClassId = cmdClassMap[command.Class]
-			commandObj.Target = convertTargetToFlag(command.Target, locale)
-			commandObj.Rule = combineKeywordsAndRegexToRule(command.Keywords, command.Regex)
-			commandObj.ResponseType = convertResponseTypeToFlag(command.ResponseType, locale)
-			commandObj.Name = command.Name
-			commandObj.Answer = command.Answer
-			commandObj.Tags = convertTagsToId(labelsMap, command.Tags)
-			begin, end := convertPeriodToBeginAndEndTime(command.Period)
-			commandObj.BeginTime = begin
-			commandObj.EndTime = end
-			commandObj.Status = 0
-
-			cmd := Cmd{}
-			cmd.Name = commandObj.Name
-			cmd.Target = CmdTarget(commandObj.Target)
-			cmd.Status = commandObj.Status > 0
-			cmd.Answer = commandObj.Answer
-			cmd.Rule = combineKeywordsAndRegexToRuleObj(command.Keywords, command.Regex)
-			cmd.Type = ResponseType(commandObj.ResponseType)
-			cmd.Begin = commandObj.BeginTime
-			cmd.End = commandObj.EndTime
-			cmd.LinkLabel = commandObj.Tags
-
-			_, retCode, err := AddCmd(appid, &cmd, commandObj.ClassId)
-			if err != nil  || retCode != 0{
-				recordStatus := RecordStatus{}
-				recordStatus.Status = retCode
-				recordStatus.Content = localemsg.Get(locale, "CmdImportStatusError")
-				command.CheckStatus = recordStatus
+	for _, command := range commandRecords {
+		if command.CheckStatus.Status != RecordStatusOk {
+			continue
+		}
+		if _, ok := cmdClassMap[command.Class]; !ok {
+			var classID int
+			classID, err = addCmdClassWithTx(tx, appid, nil, command.Class)
+			if err != nil {
 				return err
 			}
+			cmdClassMap[command.Class] = classID
 		}
-		err = updateCmdImportProgress(recordId, idx + 1)
 
+		begin, end := convertPeriodToBeginAndEndTime(command.Period)
+		cmd := Cmd{
+			Name:      command.Name,
+			Target:    CmdTarget(convertTargetToFlag(command.Target, locale)),
+			Answer:    command.Answer,
+			Rule:      combineKeywordsAndRegexToRuleObj(command.Keywords, command.Regex),
+			Type:      ResponseType(convertResponseTypeToFlag(command.ResponseType, locale)),
+			Begin:     begin,
+			End:       end,
+			LinkLabel: convertTagsToId(labelsMap, command.Tags),
+		}
+		_, err = addCmdWithTx(tx, appid, &cmd, cmdClassMap[command.Class])
+		if err != nil {
+			command.CheckStatus = RecordStatus{
+				Status:  ApiError.DB_ERROR,
+				Content: localemsg.Get(locale, "CmdImportStatusError"),
+			}
+			return err
+		}
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	err = updateCmdImportProgress(recordId, len(commandRecords))
+	if err != nil {
+		return err
+	}
 	err = recordImportStatusToFile(appid, commandRecords, locale, recordId)
 	return err
 }
@@ -542,15 +551,15 @@ func recordImportStatusToFile(appid string, commandRecords []*CommandRecord, loc
 	for _, sheet := range sheets {
 		if sheet.Name == localemsg.Get(locale, "CmdSheetName") {
 			headerRow := sheet.AddRow()
-			headerRow.AddCell().SetString(headerTitle[locale][0])
-			headerRow.AddCell().SetString(headerTitle[locale][1])
-			headerRow.AddCell().SetString(headerTitle[locale][2])
-			headerRow.AddCell().SetString(headerTitle[locale][3])
-			headerRow.AddCell().SetString(headerTitle[locale][4])
-			headerRow.AddCell().SetString(headerTitle[locale][5])
-			headerRow.AddCell().SetString(headerTitle[locale][6])
-			headerRow.AddCell().SetString(headerTitle[locale][7])
-			headerRow.AddCell().SetString(headerTitle[locale][8])
+			headerRow.AddCell().SetString(templateHeader(locale)[0])
+			headerRow.AddCell().SetString(templateHeader(locale)[1])
+			headerRow.AddCell().SetString(templateHeader(locale)[2])
+			headerRow.AddCell().SetString(templateHeader(locale)[3])
+			headerRow.AddCell().SetString(templateHeader(locale)[4])
+			headerRow.AddCell().SetString(templateHeader(locale)[5])
+			headerRow.AddCell().SetString(templateHeader(locale)[6])
+			headerRow.AddCell().SetString(templateHeader(locale)[7])
+			headerRow.AddCell().SetString(templateHeader(locale)[8])
 			headerRow.AddCell().SetString(localemsg.Get(locale, "CmdImportErrorReason"))
 		}
 	}
@@ -759,17 +768,6 @@ func convertFlagToTarget(targetId int, local string)(string){
 	}
 }
 
-func saveAndUpdateCmdClass(appid string, className string, classMap map[string]int)( map[string]int, error){
-	var pid *int
-	classID, _, err := AddCmdClass(appid, pid, className)
-	if err != nil {
-		return classMap, err
-	}
-	classMap[className] = classID
-
-	return classMap, nil
-}
-
 func addCmdImportHistoryRecord(appid string, userid string, filename string, rows int, valid_rows int) (id int, err error) {
       return addCmdImportRecord(appid, userid, filename, rows, valid_rows)
 }
@@ -781,6 +779,3 @@ func updateCmdImportReportFile(recordId int, path string)  (err error){
     return updateCommandReportFile(recordId, path)
 }
 
-func deleteAllCmd(appid string)  (err error){
-     return deleteAllCommand(appid)
-}
@@ -1,8 +1,11 @@
 package BF
 
+import "emotibot.com/emotigo/module/admin-api/util/localemsg"
+
 const (
 	ZhCn = "zh-cn"
 	ZhTw = "zh-tw"
+	EnUs = "en-us"
 	MaxFileSize = 10 * 1024 *1024
 	MaxFileLength = 10000
 
@@ -28,6 +31,7 @@ const (
 	RecordStatusResponseError = 205
 )
 
+// headerTitle is headers of command template, locales missing in it use headers of default locale
 var headerTitle = map[string]map[int]string{
 	ZhCn: map[int]string{
 		0:            "分类",
@@ -51,4 +55,24 @@ var headerTitle = map[string]map[int]string{
 		7:            "回復內容",
 		8:            "回復位置",
 	},
+	EnUs: map[int]string{
+		0:            "Category",
+		1:            "Command Name",
+		2:            "Rule Target",
+		3:            "Labels",
+		4:            "Keywords",
+		5:            "Regular Expressions",
+		6:            "Effective Period",
+		7:            "Response",
+		8:            "Response Position",
+	},
+}
+
+func init() {
+	localemsg.RegisterTable("BF/headerTitle", headerTitle)
+}
+
+// templateHeader returns headers of command template in locale
+func templateHeader(locale string) map[int]string {
+	return headerTitle[localemsg.TableLocale(headerTitle, locale)]
 }
@@ -10,6 +10,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
 	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/module/admin-api/util/validate"
@@ -32,7 +33,11 @@ func init() {
 				util.NewEntryPointWithVer("POST", "import/question", []string{"view"}, handleImportCustomChatQuestion, 1),
 				util.NewEntryPointWithVer("POST", "import/extend", []string{"view"}, handleImportCustomChatExtend, 1),
 				util.NewEntryPointWithVer("GET", "export/question", []string{"export"}, handleExportCustomChatQuestion, 1),
-			}),
+			},
+			append(
+				importpreview.EntryPoints("import/question/preview", []string{"view"}, 1, previewModuleQuestion, "file", parseImportQuestionPreview),
+				importpreview.EntryPoints("import/extend/preview", []string{"view"}, 1, previewModuleExtend, "file", parseImportExtendPreview)...,
+			)...),
 	}
 
 	initDao()
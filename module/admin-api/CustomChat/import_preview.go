@@ -0,0 +1,99 @@
+package CustomChat
+
+import (
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+)
+
+const (
+	previewModuleQuestion = "customchat_question"
+	previewModuleExtend   = "customchat_extend"
+)
+
+// parseImportQuestionPreview parses uploaded questions and diffs them with questions in DB
+func parseImportQuestionPreview(r *http.Request, appid string, upload *importpreview.Upload) (importpreview.RowErrors, *importpreview.Diff, func() error, AdminErrors.AdminError) {
+	locale := requestheader.GetLocale(r)
+	customQuestions, err := ParseImportQuestionFile(upload.Content, locale)
+	if err != nil {
+		return importpreview.ToRowErrors(err), nil, nil, nil
+	}
+
+	current, err := dao.GetCustomChatDetail(appid)
+	if err != nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	diff := importpreview.DiffRecords(questionRecords(current), questionRecords(customQuestions))
+
+	commit := func() error {
+		if err := UpdateLatestCustomChatQuestions(appid, customQuestions); err != nil {
+			return err
+		}
+		go SyncCustomChat(appid, false)
+		return nil
+	}
+	return nil, diff, commit, nil
+}
+
+// parseImportExtendPreview parses uploaded extend questions and diffs them with extends in DB
+func parseImportExtendPreview(r *http.Request, appid string, upload *importpreview.Upload) (importpreview.RowErrors, *importpreview.Diff, func() error, AdminErrors.AdminError) {
+	locale := requestheader.GetLocale(r)
+	questions, err := ParseImportExtendFile(upload.Content, locale)
+	if err != nil {
+		return importpreview.ToRowErrors(err), nil, nil, nil
+	}
+
+	current, err := dao.GetCustomChatDetail(appid)
+	if err != nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	currentQuestions := []*Question{}
+	for _, cq := range current {
+		for idx := range cq.Questions {
+			currentQuestions = append(currentQuestions, &cq.Questions[idx])
+		}
+	}
+	diff := importpreview.DiffRecords(extendRecords(currentQuestions), extendRecords(questions))
+
+	commit := func() error {
+		if err := UpdateLatestCustomChatExtends(appid, questions); err != nil {
+			return err
+		}
+		go SyncCustomChat(appid, false)
+		return nil
+	}
+	return nil, diff, commit, nil
+}
+
+// questionRecords uses category/question as key and answers as content
+func questionRecords(customQuestions []*CustomQuestions) map[string]string {
+	records := map[string]string{}
+	for _, cq := range customQuestions {
+		for _, question := range cq.Questions {
+			answers := []string{}
+			for _, answer := range question.Answers {
+				answers = append(answers, answer.Content)
+			}
+			records[cq.Category+"/"+question.Content] = importpreview.RecordContent(answers...)
+		}
+	}
+	return records
+}
+
+// extendRecords uses question as key and extends as content, questions without extend are skipped
+func extendRecords(questions []*Question) map[string]string {
+	records := map[string]string{}
+	for _, question := range questions {
+		if len(question.Extends) == 0 {
+			continue
+		}
+		extends := []string{}
+		for _, extend := range question.Extends {
+			extends = append(extends, extend.Content)
+		}
+		records[question.Content] = importpreview.RecordContent(extends...)
+	}
+	return records
+}
@@ -16,6 +16,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/Service"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
 	"emotibot.com/emotigo/pkg/logger"
@@ -53,6 +54,7 @@ func ParseImportQuestionFile(buf []byte, locale string) (customQuestions []*Cust
 func parseCustomChatQuestionSheets(sheets []*xlsx.Sheet, locale string) (customQuestions []*CustomQuestions, err error) {
 	customQuestionsMap := map[string]*CustomQuestions{}
 	questionsMap := map[string]*Question{}
+	rowErrs := importpreview.RowErrors{}
 
 	for idx := range sheets {
 
@@ -68,12 +70,14 @@ func parseCustomChatQuestionSheets(sheets []*xlsx.Sheet, locale string) (customQ
 					fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadNoHeaderTpl"), sheets[idx].Name))
 			}
 
+			header := rows[0]
 			rows = rows[1:]
 			for rowIdx := range rows {
 				cells := rows[rowIdx].Cells
 				if len(cells) < 2 {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, "",
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionRowInvalidTpl"), sheets[idx].Name, rowIdx+1))
+					continue
 				}
 				category := strings.TrimSpace(cells[categoryIdx].String())
 				question := strings.TrimSpace(cells[questionIdx].String())
@@ -81,27 +85,31 @@ func parseCustomChatQuestionSheets(sheets []*xlsx.Sheet, locale string) (customQ
 
 				qLength := utf8.RuneCountInString(question)
 				if qLength > 50 {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[questionIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionExceedLimit"), sheets[idx].Name, rowIdx+1, 50))
+					continue
 
 				}
 				aLength := utf8.RuneCountInString(answer)
 				if aLength > 1500 {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[answerIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionExceedLimit"), sheets[idx].Name, rowIdx+1, 1500))
+					continue
 				}
 
 				if question == "" && answer == "" {
 					continue
 				}
 				if question == "" {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[questionIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionRowNoQuestionTpl"), sheets[idx].Name, rowIdx+1))
+					continue
 
 				}
 				if answer == "" {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[answerIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionRowNoAnswerTpl"), sheets[idx].Name, rowIdx+1))
+					continue
 				}
 				if _, ok := questionsMap[question]; !ok {
 					questionEnt := Question{}
@@ -123,8 +131,9 @@ func parseCustomChatQuestionSheets(sheets []*xlsx.Sheet, locale string) (customQ
 
 				ansCount := questionsMap[question].AnswerCount
 				if ansCount > 10{
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[answerIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionAnswerCountExceedLimit"), sheets[idx].Name, question, 10))
+					continue
 				}
 			}
 
@@ -149,6 +158,10 @@ func parseCustomChatQuestionSheets(sheets []*xlsx.Sheet, locale string) (customQ
 		}
 	}
 
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
 	customQuestions = []*CustomQuestions{}
 	for category := range customQuestionsMap {
 		customQuestions = append(customQuestions, customQuestionsMap[category])
@@ -203,6 +216,7 @@ func ParseImportExtendFile(buf []byte, locale string) (extends []*Question, err
 func parseCustomChatExtendSheets(sheets []*xlsx.Sheet, locale string) (questions []*Question, err error) {
 	//questionsArray := map[string]*Question{}
 	questionsMap := map[string]*Question{}
+	rowErrs := importpreview.RowErrors{}
 
 	globalExtendsMap  := map[string]*string{}//[]string{}
 	for idx := range sheets {
@@ -219,12 +233,14 @@ func parseCustomChatExtendSheets(sheets []*xlsx.Sheet, locale string) (questions
 					fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadNoHeaderTpl"), sheets[idx].Name))
 			}
 
+			header := rows[0]
 			rows = rows[1:]
 			for rowIdx := range rows {
 				cells := rows[rowIdx].Cells
 				if len(cells) < 2 {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, "",
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionRowInvalidTpl"), sheets[idx].Name, rowIdx+1))
+					continue
 				}
 
 				question := strings.TrimSpace(cells[questionIdx].String())
@@ -235,32 +251,37 @@ func parseCustomChatExtendSheets(sheets []*xlsx.Sheet, locale string) (questions
 					globalExtendsMap[extend] = &extend
 
 				}else {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[extendIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadExtendDuplicate"), sheets[idx].Name, rowIdx+1))
+					continue
 				}
 
 				qLength := utf8.RuneCountInString(question)
 				if qLength > 50{
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[questionIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionExceedLimit"), sheets[idx].Name, rowIdx+1, 50))
+					continue
 
 				}
 
 				if extendLength > 50{
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[extendIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionExceedLimit"), sheets[idx].Name, rowIdx+1, 50))
+					continue
 				}
 
 				if question == "" && extend == "" {
 					continue
 				}
 				if question == "" {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[questionIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionRowNoQuestionTpl"), sheets[idx].Name, rowIdx+1))
+					continue
 				}
 				if extend == "" {
-					return nil, AdminErrors.New(AdminErrors.ErrnoRequestError,
+					rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[extendIdx].String(),
 						fmt.Sprintf(localemsg.Get(locale, "CustomChatUploadQuestionRowNoExtendTpl"), sheets[idx].Name, rowIdx+1))
+					continue
 				}
 
 				if _, ok := questionsMap[question]; !ok {
@@ -285,6 +306,10 @@ func parseCustomChatExtendSheets(sheets []*xlsx.Sheet, locale string) (questions
 
 	}
 
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
 	questionsArray := []*Question{}
 	for _, q := range questionsMap {
 		questionsArray = append(questionsArray, q)
@@ -617,7 +642,7 @@ func ForceSyncCustomChatToES(appid string, force bool) (err error) {
 	UpdateCustomChatStatus(customQuestions)
 
 	logger.Trace.Printf("Update profile consul of appid [%s]\n", appid)
-	_, consulErr := util.ConsulUpdateProfile(appid)
+	_, consulErr := util.ConsulUpdateProfile(appid, &util.ConsulChange{Reason: "sync custom chat"})
 	if err != nil {
 		logger.Error.Println("Update consul error:", consulErr.Error())
 	}
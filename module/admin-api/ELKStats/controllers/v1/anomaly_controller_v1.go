@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/controllers"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	servicesV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+)
+
+// AnomaliesGetHandler returns alerts of app in the range of start_time and end_time,
+// alerts of the last 7 days are returned if not specified
+func AnomaliesGetHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	query := dataV1.AnomalyAlertsQuery{
+		AppID:     requestheader.GetAppID(r),
+		StartTime: now.AddDate(0, 0, -7).Unix(),
+		EndTime:   now.Unix(),
+		Metric:    r.URL.Query().Get("metric"),
+	}
+
+	if startTimeString := r.URL.Query().Get("start_time"); startTimeString != "" {
+		startTime, err := strconv.ParseInt(startTimeString, 10, 64)
+		if err != nil {
+			errResponse := data.NewBadRequestResponse(data.ErrCodeInvalidParameterStartTime, "start_time")
+			controllers.ReturnBadRequest(w, errResponse)
+			return
+		}
+		query.StartTime = startTime
+	}
+
+	if endTimeString := r.URL.Query().Get("end_time"); endTimeString != "" {
+		endTime, err := strconv.ParseInt(endTimeString, 10, 64)
+		if err != nil {
+			errResponse := data.NewBadRequestResponse(data.ErrCodeInvalidParameterEndTime, "end_time")
+			controllers.ReturnBadRequest(w, errResponse)
+			return
+		}
+		query.EndTime = endTime
+	}
+
+	if query.StartTime > query.EndTime {
+		errResponse := data.NewErrorResponseWithCode(data.ErrCodeInvalidParameterStartTime,
+			"start_time cannot greater than end_time")
+		controllers.ReturnBadRequest(w, errResponse)
+		return
+	}
+
+	alerts, err := servicesV1.GetAnomalyAlerts(&query)
+	if err != nil {
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+
+	controllers.ReturnOK(w, dataV1.AnomalyAlertsResponse{
+		Data: alerts,
+	})
+}
+
+func AnomalySettingsGetHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := servicesV1.GetAnomalySettings(requestheader.GetAppID(r))
+	if err != nil {
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+
+	controllers.ReturnOK(w, settings)
+}
+
+func AnomalySettingsUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	settings := dataV1.AnomalySettings{}
+	err := json.NewDecoder(r.Body).Decode(&settings)
+	if err != nil {
+		errResponse := data.NewErrorResponseWithCode(data.ErrCodeInvalidRequestBody,
+			data.ErrInvalidRequestBody.Error())
+		controllers.ReturnBadRequest(w, errResponse)
+		return
+	}
+
+	if err = servicesV1.ValidateAnomalySettings(&settings); err != nil {
+		errResponse := data.NewErrorResponseWithCode(data.ErrCodeInvalidRequestBody, err.Error())
+		controllers.ReturnBadRequest(w, errResponse)
+		return
+	}
+
+	err = servicesV1.UpdateAnomalySettings(requestheader.GetAppID(r), &settings)
+	if err != nil {
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+
+	controllers.ReturnOK(w, settings)
+}
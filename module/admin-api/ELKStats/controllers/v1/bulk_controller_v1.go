@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/controllers"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+)
+
+// BulkResponse is the response of RecordsBulkHandler
+type BulkResponse struct {
+	Count int `json:"count"`
+}
+
+// RecordsBulkHandler ingests records of app in Elasticsearch bulk format into the embedded repository.
+// Deployments with Elasticsearch ingest records by the log pipeline, so it is rejected for them.
+func RecordsBulkHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	repo := repository.Get()
+	if _, ok := repo.(*repository.Embedded); !ok {
+		errResponse := data.NewErrorResponseWithCode(data.ErrCodeInvalidRequestBody,
+			"bulk is only supported by embedded repository")
+		controllers.ReturnBadRequest(w, errResponse)
+		return
+	}
+
+	count, err := repository.Bulk(repo, r.Body, requestheader.GetAppID(r))
+	if err != nil {
+		if _, ok := err.(*repository.BulkError); ok {
+			errResponse := data.NewErrorResponseWithCode(data.ErrCodeInvalidRequestBody, err.Error())
+			controllers.ReturnBadRequest(w, errResponse)
+			return
+		}
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+	controllers.ReturnOK(w, BulkResponse{Count: count})
+}
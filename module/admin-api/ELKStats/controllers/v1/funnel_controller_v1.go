@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/controllers"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	servicesV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
+	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+)
+
+func FunnelGetHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	query, err, errCode := newFunnelQuery(r)
+	if err != nil {
+		errResponse := data.NewErrorResponseWithCode(errCode, err.Error())
+		controllers.ReturnBadRequest(w, errResponse)
+		return
+	}
+
+	stepHits, err := fetchFunnelStepHits(query)
+	if err != nil {
+		var errResponse data.ErrorResponse
+		if rootCauseErrors, ok := elasticsearch.ExtractElasticsearchRootCauseErrors(err); ok {
+			errResponse = data.NewErrorResponse(strings.Join(rootCauseErrors, "; "))
+		} else {
+			errResponse = data.NewErrorResponse(err.Error())
+		}
+		controllers.ReturnInternalServerError(w, errResponse)
+		return
+	}
+
+	response := servicesV1.ComputeFunnel(query.Steps, stepHits, query.SampleSize, query.Breakdown != "")
+	controllers.ReturnOK(w, response)
+}
+
+func fetchFunnelStepHits(query *dataV1.FunnelQuery) ([]dataV1.FunnelHits, error) {
+	stepHits := make([]dataV1.FunnelHits, len(query.Steps))
+	done := make(chan error, len(query.Steps))
+
+	// Fetch hits of steps concurrently, each goroutine writes its own index
+	for i, step := range query.Steps {
+		go func(index int, step dataV1.FunnelStep) {
+			hits, err := servicesV1.FunnelStepHits(*query, step)
+			stepHits[index] = hits
+			done <- err
+		}(i, step)
+	}
+
+	var queryError error
+	for range query.Steps {
+		if err := <-done; err != nil {
+			queryError = err
+		}
+	}
+	return stepHits, queryError
+}
+
+func newFunnelQuery(r *http.Request) (query *dataV1.FunnelQuery, err error, errCode int) {
+	request := dataV1.FunnelRequest{}
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		errCode = data.ErrCodeInvalidRequestBody
+		return
+	}
+
+	if request.StartTime > request.EndTime {
+		err = errors.New("start_time cannot greater than end_time")
+		errCode = data.ErrCodeInvalidParameterStartTime
+		return
+	}
+
+	if len(request.Steps) == 0 || len(request.Steps) > dataV1.FunnelMaxSteps {
+		err = errors.New("steps should be 1 to 10 items")
+		errCode = data.ErrCodeInvalidParameterType
+		return
+	}
+
+	for _, step := range request.Steps {
+		switch step.Type {
+		case dataV1.FunnelStepFAQ, dataV1.FunnelStepIntent, dataV1.FunnelStepToHuman:
+		case dataV1.FunnelStepScenarioNode:
+			if step.ScenarioID == "" {
+				err = errors.New("scenario_id is required in scenario_node step")
+				errCode = data.ErrCodeInvalidParameterType
+				return
+			}
+		default:
+			err = errors.New("invalid step type: " + step.Type)
+			errCode = data.ErrCodeInvalidParameterType
+			return
+		}
+	}
+
+	sampleSize := request.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = dataV1.FunnelDefaultSampleSize
+	} else if sampleSize > dataV1.FunnelMaxSampleSize {
+		sampleSize = dataV1.FunnelMaxSampleSize
+	}
+
+	query = &dataV1.FunnelQuery{
+		CommonQuery: data.CommonQuery{
+			EnterpriseID: requestheader.GetEnterpriseID(r),
+			AppID:        requestheader.GetAppID(r),
+			StartTime:    time.Unix(request.StartTime, 0).UTC(),
+			EndTime:      time.Unix(request.EndTime, 0).UTC(),
+		},
+		Steps:      request.Steps,
+		Breakdown:  request.Breakdown,
+		Filters:    request.Filters,
+		SampleSize: sampleSize,
+	}
+	return
+}
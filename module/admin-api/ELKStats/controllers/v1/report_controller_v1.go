@@ -0,0 +1,222 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/controllers"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	servicesV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"github.com/gorilla/mux"
+)
+
+func ReportsGetHandler(w http.ResponseWriter, r *http.Request) {
+	reports, err := servicesV1.GetReports(requestheader.GetAppID(r))
+	if err != nil {
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+
+	controllers.ReturnOK(w, reports)
+}
+
+func ReportCreateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	request, ok := newReportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := servicesV1.NewReport(requestheader.GetEnterpriseID(r), requestheader.GetAppID(r),
+		requestheader.GetLocale(r), request)
+	if err != nil {
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+
+	controllers.ReturnOK(w, report)
+}
+
+func ReportGetHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := getReportID(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := servicesV1.GetReport(requestheader.GetAppID(r), reportID)
+	if err != nil {
+		returnReportError(w, err)
+		return
+	}
+
+	controllers.ReturnOK(w, report)
+}
+
+func ReportUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	reportID, ok := getReportID(w, r)
+	if !ok {
+		return
+	}
+	request, ok := newReportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := servicesV1.UpdateReport(requestheader.GetAppID(r), reportID, request)
+	if err != nil {
+		returnReportError(w, err)
+		return
+	}
+
+	controllers.ReturnOK(w, report)
+}
+
+func ReportDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := getReportID(w, r)
+	if !ok {
+		return
+	}
+
+	err := servicesV1.DeleteReport(requestheader.GetAppID(r), reportID)
+	if err != nil {
+		returnReportError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ReportRunHandler runs report in background immediately, the created history is returned
+func ReportRunHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := getReportID(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := servicesV1.GetReport(requestheader.GetAppID(r), reportID)
+	if err != nil {
+		returnReportError(w, err)
+		return
+	}
+
+	history, err := servicesV1.StartReport(report, time.Now())
+	if err != nil {
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+		return
+	}
+
+	controllers.ReturnOK(w, history)
+}
+
+func ReportHistoriesGetHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := getReportID(w, r)
+	if !ok {
+		return
+	}
+
+	histories, err := servicesV1.GetReportHistories(requestheader.GetAppID(r), reportID)
+	if err != nil {
+		returnReportError(w, err)
+		return
+	}
+
+	controllers.ReturnOK(w, dataV1.ReportHistoryResponse{
+		Data: histories,
+	})
+}
+
+func ReportHistoryDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, historyID, ok := getReportHistoryID(w, r)
+	if !ok {
+		return
+	}
+
+	err := servicesV1.ReportHistoryDownload(w, requestheader.GetAppID(r), reportID, historyID)
+	if err != nil {
+		switch err {
+		case data.ErrExportTaskInProcess:
+			controllers.ReturnForbiddenRequest(w, data.NewErrorResponse(err.Error()))
+		case data.ErrExportTaskEmpty:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			returnReportError(w, err)
+		}
+		return
+	}
+}
+
+func ReportHistorySummaryHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, historyID, ok := getReportHistoryID(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := servicesV1.ReportHistorySummary(requestheader.GetAppID(r), reportID, historyID)
+	if err != nil {
+		returnReportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(summary))
+}
+
+func newReportRequest(w http.ResponseWriter, r *http.Request) (*dataV1.ReportRequest, bool) {
+	request := dataV1.ReportRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		errResponse := data.NewErrorResponseWithCode(data.ErrCodeInvalidRequestBody,
+			data.ErrInvalidRequestBody.Error())
+		controllers.ReturnBadRequest(w, errResponse)
+		return nil, false
+	}
+
+	err, errCode := servicesV1.ValidateReportRequest(&request)
+	if err != nil {
+		controllers.ReturnBadRequest(w, data.NewErrorResponseWithCode(errCode, err.Error()))
+		return nil, false
+	}
+	return &request, true
+}
+
+func getReportID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	reportID, err := util.GetMuxInt64Var(r, "report_id")
+	if err != nil || reportID <= 0 {
+		errResponse := data.NewBadRequestResponse(data.ErrCodeInvalidRequestBody, "report_id")
+		controllers.ReturnBadRequest(w, errResponse)
+		return 0, false
+	}
+	return reportID, true
+}
+
+func getReportHistoryID(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	reportID, ok := getReportID(w, r)
+	if !ok {
+		return 0, "", false
+	}
+
+	historyID := mux.Vars(r)["history_id"]
+	if historyID == "" {
+		errResponse := data.NewBadRequestResponse(data.ErrCodeInvalidRequestBody, "history_id")
+		controllers.ReturnBadRequest(w, errResponse)
+		return 0, "", false
+	}
+	return reportID, historyID, true
+}
+
+func returnReportError(w http.ResponseWriter, err error) {
+	switch err {
+	case data.ErrReportNotFound, data.ErrReportHistoryNotFound:
+		controllers.ReturnNotFoundRequest(w, data.NewErrorResponse(err.Error()))
+	default:
+		controllers.ReturnInternalServerError(w, data.NewErrorResponse(err.Error()))
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 )
 
@@ -49,7 +50,7 @@ func SessionsGetHandler(w http.ResponseWriter, r *http.Request) {
 
 	locale := requestheader.GetLocale(r)
 	response := dataV1.SessionsResponse{
-		TableHeader: dataV1.SessionsTableHeader[locale],
+		TableHeader: dataV1.SessionsTableHeader[localemsg.TableLocale(dataV1.SessionsTableHeader, locale)],
 		Data:        result,
 		Limit:       query.Limit,
 		Page:        query.From/query.Limit + 1,
@@ -18,6 +18,7 @@ import (
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
 	servicesV2 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v2"
 	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	dal "emotibot.com/emotigo/pkg/api/dal/v1"
 	"emotibot.com/emotigo/pkg/logger"
@@ -58,7 +59,7 @@ func VisitRecordsGetHandler(w http.ResponseWriter, r *http.Request) {
 
 	locale := requestheader.GetLocale(r)
 	response := dataV2.VisitRecordsResponse{
-		TableHeader: dataV2.VisitRecordsTableHeader[locale],
+		TableHeader: dataV2.VisitRecordsTableHeader[localemsg.TableLocale(dataV2.VisitRecordsTableHeader, locale)],
 		Data:        result.Data,
 		TotalSize:   result.TotalSize,
 		IgnoredSize: result.IgnoredSize,
@@ -0,0 +1,283 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+const (
+	AnomalyBaselineTable = "stats_anomaly_baselines"
+	AnomalyAlertTable    = "stats_anomaly_alerts"
+	AnomalySettingsTable = "stats_anomaly_settings"
+)
+
+// GetAnomalyBaseline returns the baseline of slot, an empty baseline is returned if not found
+func GetAnomalyBaseline(appID string, metric string, weekday int, hour int) (*dataV1.AnomalyBaseline, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	baseline := &dataV1.AnomalyBaseline{
+		AppID:   appID,
+		Metric:  metric,
+		Weekday: weekday,
+		Hour:    hour,
+	}
+
+	queryStr := fmt.Sprintf(`
+		SELECT samples, mean, variance, last_slot
+		FROM %s
+		WHERE app_id = ? AND metric = ? AND weekday = ? AND hour = ?`, AnomalyBaselineTable)
+	queryParams := []interface{}{appID, metric, weekday, hour}
+
+	err := db.QueryRow(queryStr, queryParams...).Scan(&baseline.Samples, &baseline.Mean,
+		&baseline.Variance, &baseline.LastSlot)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+func GetAnomalyBaselines(appID string) ([]*dataV1.AnomalyBaseline, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`
+		SELECT metric, weekday, hour, samples, mean, variance, last_slot
+		FROM %s
+		WHERE app_id = ?
+		ORDER BY metric, weekday, hour`, AnomalyBaselineTable)
+
+	rows, err := db.Query(queryStr, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baselines := make([]*dataV1.AnomalyBaseline, 0)
+	for rows.Next() {
+		baseline := dataV1.AnomalyBaseline{AppID: appID}
+		err = rows.Scan(&baseline.Metric, &baseline.Weekday, &baseline.Hour,
+			&baseline.Samples, &baseline.Mean, &baseline.Variance, &baseline.LastSlot)
+		if err != nil {
+			return nil, err
+		}
+		baselines = append(baselines, &baseline)
+	}
+
+	return baselines, rows.Err()
+}
+
+// SaveAnomalyBaseline saves baseline which has learned the slot starting at slot,
+// the baseline is saved only if it is not changed since it was read by GetAnomalyBaseline,
+// so a slot is learned once even if the detection is run by several admin-api nodes
+func SaveAnomalyBaseline(baseline *dataV1.AnomalyBaseline, slot int64) (saved bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		err = errors.New("DB not init")
+		return
+	}
+
+	var queryStr string
+	var queryParams []interface{}
+	if baseline.LastSlot == 0 {
+		queryStr = fmt.Sprintf(`
+			INSERT IGNORE INTO %s (app_id, metric, weekday, hour, samples, mean, variance,
+				last_slot, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, AnomalyBaselineTable)
+		queryParams = []interface{}{baseline.AppID, baseline.Metric, baseline.Weekday, baseline.Hour,
+			baseline.Samples, baseline.Mean, baseline.Variance, slot, time.Now().Unix()}
+	} else {
+		queryStr = fmt.Sprintf(`
+			UPDATE %s
+			SET samples = ?, mean = ?, variance = ?, last_slot = ?, updated_at = ?
+			WHERE app_id = ? AND metric = ? AND weekday = ? AND hour = ? AND last_slot = ?`,
+			AnomalyBaselineTable)
+		queryParams = []interface{}{baseline.Samples, baseline.Mean, baseline.Variance, slot,
+			time.Now().Unix(), baseline.AppID, baseline.Metric, baseline.Weekday, baseline.Hour,
+			baseline.LastSlot}
+	}
+
+	result, err := db.Exec(queryStr, queryParams...)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return
+	}
+
+	baseline.LastSlot = slot
+	return true, nil
+}
+
+// CreateAnomalyAlert records alert, created is false if the alert of the same
+// app, metric and time range already exists
+func CreateAnomalyAlert(alert *dataV1.AnomalyAlert) (created bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		err = errors.New("DB not init")
+		return
+	}
+
+	queryStr := fmt.Sprintf(`
+		INSERT IGNORE INTO %s (app_id, metric, direction, start_time, end_time,
+			value, expected, std_dev, score, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, AnomalyAlertTable)
+	queryParams := []interface{}{alert.AppID, alert.Metric, alert.Direction, alert.StartTime,
+		alert.EndTime, alert.Value, alert.Expected, alert.StdDev, alert.Score, alert.CreatedAt}
+
+	result, err := db.Exec(queryStr, queryParams...)
+	if err != nil {
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return
+	}
+
+	alert.ID, err = result.LastInsertId()
+	return err == nil, err
+}
+
+// ClaimAnomalyAlertNotify increases the notify attempts of alert from attempts,
+// false is returned if the alert has been notified or claimed by another admin-api node
+func ClaimAnomalyAlertNotify(alertID int64, attempts int) (bool, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s SET notify_attempts = notify_attempts + 1
+		WHERE id = ? AND notified = 0 AND notify_attempts = ?`, AnomalyAlertTable)
+	result, err := db.Exec(queryStr, alertID, attempts)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func AnomalyAlertNotified(alertID int64) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`UPDATE %s SET notified = 1 WHERE id = ?`, AnomalyAlertTable)
+	_, err := db.Exec(queryStr, alertID)
+	return err
+}
+
+const anomalyAlertColumns = `id, app_id, metric, direction, start_time, end_time,
+	value, expected, std_dev, score, notified, notify_attempts, created_at`
+
+func GetAnomalyAlerts(query *dataV1.AnomalyAlertsQuery) ([]*dataV1.AnomalyAlert, error) {
+	queryStr := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE app_id = ? AND start_time >= ? AND start_time <= ?`, anomalyAlertColumns, AnomalyAlertTable)
+	queryParams := []interface{}{query.AppID, query.StartTime, query.EndTime}
+	if query.Metric != "" {
+		queryStr += " AND metric = ?"
+		queryParams = append(queryParams, query.Metric)
+	}
+	queryStr += " ORDER BY start_time DESC, id DESC"
+	return queryAnomalyAlerts(queryStr, queryParams...)
+}
+
+// GetUnnotifiedAnomalyAlerts returns alerts of all apps which are not notified
+// and have been posted less than maxAttempts times
+func GetUnnotifiedAnomalyAlerts(maxAttempts int) ([]*dataV1.AnomalyAlert, error) {
+	queryStr := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE notified = 0 AND notify_attempts < ?
+		ORDER BY id`, anomalyAlertColumns, AnomalyAlertTable)
+	return queryAnomalyAlerts(queryStr, maxAttempts)
+}
+
+func queryAnomalyAlerts(queryStr string, queryParams ...interface{}) ([]*dataV1.AnomalyAlert, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	rows, err := db.Query(queryStr, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]*dataV1.AnomalyAlert, 0)
+	for rows.Next() {
+		alert := dataV1.AnomalyAlert{}
+		err = rows.Scan(&alert.ID, &alert.AppID, &alert.Metric, &alert.Direction,
+			&alert.StartTime, &alert.EndTime, &alert.Value, &alert.Expected,
+			&alert.StdDev, &alert.Score, &alert.Notified, &alert.NotifyAttempts, &alert.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// GetAnomalySettings returns settings of app, the default settings is returned if not set
+func GetAnomalySettings(appID string) (*dataV1.AnomalySettings, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	settings := &dataV1.AnomalySettings{
+		AppID:     appID,
+		Enabled:   true,
+		Threshold: dataV1.AnomalyDefaultThreshold,
+	}
+
+	queryStr := fmt.Sprintf(`
+		SELECT enabled, threshold, webhook_url, webhook_secret, updated_at
+		FROM %s
+		WHERE app_id = ?`, AnomalySettingsTable)
+
+	err := db.QueryRow(queryStr, appID).Scan(&settings.Enabled, &settings.Threshold,
+		&settings.WebhookURL, &settings.WebhookSecret, &settings.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func SaveAnomalySettings(settings *dataV1.AnomalySettings) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`
+		INSERT INTO %s (app_id, enabled, threshold, webhook_url, webhook_secret, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE enabled = VALUES(enabled), threshold = VALUES(threshold),
+			webhook_url = VALUES(webhook_url), webhook_secret = VALUES(webhook_secret),
+			updated_at = VALUES(updated_at)`, AnomalySettingsTable)
+	queryParams := []interface{}{settings.AppID, settings.Enabled, settings.Threshold,
+		settings.WebhookURL, settings.WebhookSecret, settings.UpdatedAt}
+
+	_, err := db.Exec(queryStr, queryParams...)
+	return err
+}
@@ -484,11 +484,11 @@ func RemoveAllOutdatedExportTasks(timestamp int64) error {
 		return errors.New("DB not init")
 	}
 
-	// Get all outdated tasks
+	// Get all outdated tasks, histories of reports are removed with their reports
 	queryStr := fmt.Sprintf(`
 		SELECT uuid
 		FROM %s
-		WHERE created_at < ?`, RecordsExportTable)
+		WHERE created_at < ? AND report_id = 0`, RecordsExportTable)
 	queryParams := []interface{}{timestamp}
 
 	rows, err := db.Query(queryStr, queryParams...)
@@ -0,0 +1,376 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/util"
+	uuid "github.com/satori/go.uuid"
+)
+
+const ReportTable = "stats_reports"
+
+const reportColumns = `id, enterprise_id, app_id, name, stats, filters, period, schedule,
+	delivery, locale, enabled, next_run_at, created_at, updated_at`
+
+// Histories of reports are export tasks in RecordsExportTable with report_id,
+// they are kept out of RemoveAllOutdatedExportTasks and removed by RemoveOutdatedReportHistories
+const reportHistoryColumns = `uuid, report_id, status, start_time, end_time, IFNULL(path, ''),
+	IFNULL(summary, ''), delivered, IFNULL(err_reason, ''), created_at, finished_at`
+
+func CreateReport(report *dataV1.Report) (id int64, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		err = errors.New("DB not init")
+		return
+	}
+
+	filters, delivery, err := marshalReport(report)
+	if err != nil {
+		return
+	}
+
+	queryStr := fmt.Sprintf(`
+		INSERT INTO %s (enterprise_id, app_id, name, stats, filters, period, schedule,
+			delivery, locale, enabled, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, ReportTable)
+	queryParams := []interface{}{report.EnterpriseID, report.AppID, report.Name,
+		strings.Join(report.Stats, ","), filters, report.Period, report.Schedule, delivery,
+		report.Locale, report.Enabled, report.NextRunAt, report.CreatedAt, report.UpdatedAt}
+
+	result, err := db.Exec(queryStr, queryParams...)
+	if err != nil {
+		return
+	}
+
+	return result.LastInsertId()
+}
+
+func UpdateReport(report *dataV1.Report) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return errors.New("DB not init")
+	}
+
+	filters, delivery, err := marshalReport(report)
+	if err != nil {
+		return err
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET name = ?, stats = ?, filters = ?, period = ?, schedule = ?, delivery = ?,
+			locale = ?, enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ? AND app_id = ?`, ReportTable)
+	queryParams := []interface{}{report.Name, strings.Join(report.Stats, ","), filters,
+		report.Period, report.Schedule, delivery, report.Locale, report.Enabled,
+		report.NextRunAt, report.UpdatedAt, report.ID, report.AppID}
+
+	_, err = db.Exec(queryStr, queryParams...)
+	return err
+}
+
+// ClaimReportRun moves the next run time of report from nextRunAt to newNextRunAt,
+// false is returned if the run has been claimed by another admin-api node
+// or the report has been rescheduled
+func ClaimReportRun(reportID int64, nextRunAt int64, newNextRunAt int64) (bool, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`UPDATE %s SET next_run_at = ? WHERE id = ? AND next_run_at = ?`,
+		ReportTable)
+	result, err := db.Exec(queryStr, newNextRunAt, reportID, nextRunAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// DeleteReport deletes report and its histories
+func DeleteReport(appID string, reportID int64) (err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return errors.New("DB not init")
+	}
+
+	tx, _ := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	queryStr := fmt.Sprintf(`DELETE FROM %s WHERE id = ? AND app_id = ?`, ReportTable)
+	result, err := tx.Exec(queryStr, reportID, appID)
+	if err != nil {
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		err = data.ErrReportNotFound
+		return
+	}
+
+	queryStr = fmt.Sprintf(`DELETE FROM %s WHERE report_id = ?`, RecordsExportTable)
+	_, err = tx.Exec(queryStr, reportID)
+	return
+}
+
+// GetReport returns report of app, any app is matched if appID is empty
+func GetReport(appID string, reportID int64) (*dataV1.Report, error) {
+	queryStr := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, reportColumns, ReportTable)
+	queryParams := []interface{}{reportID}
+	if appID != "" {
+		queryStr += " AND app_id = ?"
+		queryParams = append(queryParams, appID)
+	}
+
+	reports, err := queryReports(queryStr, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, data.ErrReportNotFound
+	}
+	return reports[0], nil
+}
+
+func GetReports(appID string) ([]*dataV1.Report, error) {
+	queryStr := fmt.Sprintf(`SELECT %s FROM %s WHERE app_id = ? ORDER BY id`, reportColumns, ReportTable)
+	return queryReports(queryStr, appID)
+}
+
+// GetDueReports returns enabled reports which should be run at timestamp
+func GetDueReports(timestamp int64) ([]*dataV1.Report, error) {
+	queryStr := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE enabled = 1 AND next_run_at > 0 AND next_run_at <= ?
+		ORDER BY next_run_at`, reportColumns, ReportTable)
+	return queryReports(queryStr, timestamp)
+}
+
+func queryReports(queryStr string, queryParams ...interface{}) ([]*dataV1.Report, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	rows, err := db.Query(queryStr, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]*dataV1.Report, 0)
+	for rows.Next() {
+		report := dataV1.Report{}
+		var stats, filters, delivery string
+		err = rows.Scan(&report.ID, &report.EnterpriseID, &report.AppID, &report.Name, &stats,
+			&filters, &report.Period, &report.Schedule, &delivery, &report.Locale, &report.Enabled,
+			&report.NextRunAt, &report.CreatedAt, &report.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Stats = strings.Split(stats, ",")
+		if err = json.Unmarshal([]byte(filters), &report.Filters); err != nil {
+			return nil, fmt.Errorf("invalid filters of report %d, %v", report.ID, err)
+		}
+		if err = json.Unmarshal([]byte(delivery), &report.Delivery); err != nil {
+			return nil, fmt.Errorf("invalid delivery of report %d, %v", report.ID, err)
+		}
+		reports = append(reports, &report)
+	}
+
+	return reports, rows.Err()
+}
+
+func marshalReport(report *dataV1.Report) (filters string, delivery string, err error) {
+	filtersJSON, err := json.Marshal(report.Filters)
+	if err != nil {
+		return
+	}
+	deliveryJSON, err := json.Marshal(report.Delivery)
+	if err != nil {
+		return
+	}
+	return string(filtersJSON), string(deliveryJSON), nil
+}
+
+func CreateReportHistory(history *dataV1.ReportHistory) (id string, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		err = errors.New("DB not init")
+		return
+	}
+
+	historyUUID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	id = historyUUID.String()
+
+	queryStr := fmt.Sprintf(`
+		INSERT INTO %s (uuid, report_id, status, start_time, end_time, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, RecordsExportTable)
+	queryParams := []interface{}{id, history.ReportID, data.CodeExportTaskRunning,
+		history.StartTime, history.EndTime, history.CreatedAt}
+
+	_, err = db.Exec(queryStr, queryParams...)
+	return
+}
+
+// ReportHistoryFinished updates status, generated file, summary and delivery result of history
+func ReportHistoryFinished(history *dataV1.ReportHistory) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET status = ?, path = ?, summary = ?, delivered = ?, err_reason = ?, finished_at = ?
+		WHERE uuid = ?`, RecordsExportTable)
+	queryParams := []interface{}{history.Status, history.Path, history.Summary,
+		history.Delivered, history.ErrReason, history.FinishedAt, history.ID}
+
+	_, err := db.Exec(queryStr, queryParams...)
+	return err
+}
+
+// FailStaleReportHistories marks histories still running since before timestamp as failed
+// with reason, and returns the number of them
+func FailStaleReportHistories(timestamp int64, reason string, finishedAt int64) (int64, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return 0, errors.New("DB not init")
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET status = ?, err_reason = ?, finished_at = ?
+		WHERE report_id > 0 AND status = ? AND created_at < ?`, RecordsExportTable)
+	result, err := db.Exec(queryStr, data.CodeExportTaskFailed, reason, finishedAt,
+		data.CodeExportTaskRunning, timestamp)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RemoveOutdatedReportHistories removes finished histories of reports created before timestamp,
+// and returns the paths of their files
+func RemoveOutdatedReportHistories(timestamp int64) ([]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	condition := "report_id > 0 AND status != ? AND created_at < ?"
+	queryParams := []interface{}{data.CodeExportTaskRunning, timestamp}
+	paths, err := queryReportHistoryPaths(db, condition, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	queryStr := fmt.Sprintf(`DELETE FROM %s WHERE %s`, RecordsExportTable, condition)
+	_, err = db.Exec(queryStr, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// GetReportHistoryPaths returns the paths of files of all histories of report
+func GetReportHistoryPaths(reportID int64) ([]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+	return queryReportHistoryPaths(db, "report_id = ?", reportID)
+}
+
+func queryReportHistoryPaths(db *sql.DB, condition string, queryParams ...interface{}) ([]string, error) {
+	queryStr := fmt.Sprintf(`SELECT path FROM %s WHERE %s AND path IS NOT NULL AND path != ''`,
+		RecordsExportTable, condition)
+	rows, err := db.Query(queryStr, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err = rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// GetReportHistories returns the latest histories of report
+func GetReportHistories(reportID int64, limit int) ([]*dataV1.ReportHistory, error) {
+	queryStr := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE report_id = ?
+		ORDER BY created_at DESC
+		LIMIT ?`, reportHistoryColumns, RecordsExportTable)
+	return queryReportHistories(queryStr, reportID, limit)
+}
+
+func GetReportHistory(reportID int64, historyID string) (*dataV1.ReportHistory, error) {
+	queryStr := fmt.Sprintf(`SELECT %s FROM %s WHERE report_id = ? AND uuid = ?`,
+		reportHistoryColumns, RecordsExportTable)
+	histories, err := queryReportHistories(queryStr, reportID, historyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(histories) == 0 {
+		return nil, data.ErrReportHistoryNotFound
+	}
+	return histories[0], nil
+}
+
+func queryReportHistories(queryStr string, queryParams ...interface{}) ([]*dataV1.ReportHistory, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	rows, err := db.Query(queryStr, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histories := make([]*dataV1.ReportHistory, 0)
+	for rows.Next() {
+		history := dataV1.ReportHistory{}
+		err = rows.Scan(&history.ID, &history.ReportID, &history.Status, &history.StartTime,
+			&history.EndTime, &history.Path, &history.Summary, &history.Delivered,
+			&history.ErrReason, &history.CreatedAt, &history.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		history.StatusText = data.ExportTaskCodesMap[history.Status]
+		histories = append(histories, &history)
+	}
+
+	return histories, rows.Err()
+}
@@ -17,6 +17,8 @@ var (
 	ErrInvalidFeedbacksType    = errors.New("Invalid feedbacks type")
 	ErrFaqCategoryPathNotFound = errors.New("FAQ category path not found")
 	ErrFaqRobotTagNotFound     = errors.New("FAQ robot tag not found")
+	ErrReportNotFound          = errors.New("Report not found")
+	ErrReportHistoryNotFound   = errors.New("Report history not found")
 )
 
 const (
@@ -32,6 +34,8 @@ const (
 	ErrCodeInvalidParameterPlatform
 	ErrCodeInvalidParameterGender
 	ErrCodeInvalidParameterTop
+	ErrCodeInvalidParameterSchedule
+	ErrCodeInvalidParameterDelivery
 )
 
 type ErrorResponse struct {
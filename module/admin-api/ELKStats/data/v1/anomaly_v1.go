@@ -0,0 +1,107 @@
+package v1
+
+const (
+	AnomalyMetricTotalCalls  = "total_calls"
+	AnomalyMetricToHumanRate = "to_human_rate"
+	AnomalyMetricUnknownRate = "unknown_rate"
+	AnomalyMetricAvgRating   = "avg_rating"
+)
+
+const (
+	AnomalyDirectionRise = "rise"
+	AnomalyDirectionDrop = "drop"
+	AnomalyDirectionBoth = "both"
+)
+
+const (
+	// AnomalyMinSamples is the number of samples a baseline slot needs before detecting
+	AnomalyMinSamples = 4
+	// AnomalyMaxSamples caps the weight of history, so baselines follow long-term changes
+	AnomalyMaxSamples = 8
+	// AnomalyDefaultThreshold is the default z-score to flag an anomaly
+	AnomalyDefaultThreshold = 3.0
+	// AnomalyDailyHour is the hour of baseline slots of daily metrics
+	AnomalyDailyHour = -1
+	// AnomalyMaxNotifyAttempts is the times to post an alert to webhook, failed alerts are
+	// posted again by the hourly detection, so an alert is retried for about a day
+	AnomalyMaxNotifyAttempts = 24
+)
+
+// AnomalyRule describes how a metric is detected.
+// Values with volume less than MinVolume are neither detected nor learned,
+// MinStdDev keeps flat baselines from flagging small changes.
+type AnomalyRule struct {
+	Metric    string
+	Direction string
+	Daily     bool
+	MinVolume float64
+	MinStdDev float64
+}
+
+var AnomalyRules = []AnomalyRule{
+	{Metric: AnomalyMetricTotalCalls, Direction: AnomalyDirectionBoth, MinVolume: 0, MinStdDev: 1},
+	{Metric: AnomalyMetricToHumanRate, Direction: AnomalyDirectionRise, MinVolume: 20, MinStdDev: 0.02},
+	{Metric: AnomalyMetricUnknownRate, Direction: AnomalyDirectionRise, MinVolume: 20, MinStdDev: 0.02},
+	{Metric: AnomalyMetricAvgRating, Direction: AnomalyDirectionDrop, Daily: true, MinVolume: 10, MinStdDev: 0.1},
+}
+
+// AnomalyBaseline is the running mean and variance of a metric of app
+// at a weekday and hour, Hour is AnomalyDailyHour for daily metrics.
+// LastSlot is the start time of the latest slot learned by the baseline.
+type AnomalyBaseline struct {
+	AppID    string  `json:"app_id"`
+	Metric   string  `json:"metric"`
+	Weekday  int     `json:"weekday"`
+	Hour     int     `json:"hour"`
+	Samples  int     `json:"samples"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	LastSlot int64   `json:"last_slot"`
+}
+
+// AnomalyMetricValue is the value of a metric in a time range,
+// Volume is the number of calls, asks or ratings the value computed from
+type AnomalyMetricValue struct {
+	Metric string
+	Value  float64
+	Volume float64
+}
+
+type AnomalyAlert struct {
+	ID        int64   `json:"id"`
+	AppID     string  `json:"app_id"`
+	Metric    string  `json:"metric"`
+	Direction string  `json:"direction"`
+	StartTime int64   `json:"start_time"`
+	EndTime   int64   `json:"end_time"`
+	Value     float64 `json:"value"`
+	Expected  float64 `json:"expected"`
+	StdDev    float64 `json:"std_dev"`
+	Score     float64 `json:"score"`
+	Notified  bool    `json:"notified"`
+	// NotifyAttempts is the times the alert has been posted to webhook
+	NotifyAttempts int   `json:"notify_attempts"`
+	CreatedAt      int64 `json:"created_at"`
+}
+
+type AnomalyAlertsQuery struct {
+	AppID     string
+	StartTime int64
+	EndTime   int64
+	Metric    string
+}
+
+type AnomalyAlertsResponse struct {
+	Data []*AnomalyAlert `json:"data"`
+}
+
+// AnomalySettings is the detection setting of app, anomalies are posted to WebhookURL if not empty.
+// WebhookSecret signs the posted alerts the same as report webhooks, it is generated if it is empty.
+type AnomalySettings struct {
+	AppID         string  `json:"app_id"`
+	Enabled       bool    `json:"enabled"`
+	Threshold     float64 `json:"threshold"`
+	WebhookURL    string  `json:"webhook_url"`
+	WebhookSecret string  `json:"webhook_secret,omitempty"`
+	UpdatedAt     int64   `json:"updated_at"`
+}
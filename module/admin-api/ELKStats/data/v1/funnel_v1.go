@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+)
+
+const (
+	FunnelStepFAQ          = "faq"
+	FunnelStepIntent       = "intent"
+	FunnelStepScenarioNode = "scenario_node"
+	FunnelStepToHuman      = "to_human"
+)
+
+const (
+	FunnelMaxSteps          = 10
+	FunnelDefaultSampleSize = 10
+	FunnelMaxSampleSize     = 100
+)
+
+// FunnelStep is a step of funnel, Value is the standard question of faq,
+// the intent name of intent, or the node id of scenario_node.
+// Empty value matches any faq hit, intent or node of the scenario.
+type FunnelStep struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	ScenarioID string `json:"scenario_id,omitempty"`
+}
+
+type FunnelQuery struct {
+	data.CommonQuery
+	Steps []FunnelStep
+	// Breakdown is the tag type, such as platform, to group sessions by
+	Breakdown string
+	// Filters are tag type and tag value which sessions must have
+	Filters    map[string]string
+	SampleSize int
+}
+
+// FunnelRequest is the request body of funnel API
+type FunnelRequest struct {
+	StartTime  int64             `json:"start_time"`
+	EndTime    int64             `json:"end_time"`
+	Steps      []FunnelStep      `json:"steps"`
+	Breakdown  string            `json:"breakdown"`
+	Filters    map[string]string `json:"filters"`
+	SampleSize int               `json:"sample_size"`
+}
+
+// FunnelHit is the first time a session reached a step, Time is in milliseconds
+type FunnelHit struct {
+	Time int64
+	// Tag is the value of breakdown tag of session, empty if no breakdown
+	Tag string
+}
+
+// FunnelHits are hits of a step, key is session id
+type FunnelHits map[string]*FunnelHit
+
+type FunnelStepResult struct {
+	FunnelStep
+	Sessions int64 `json:"sessions"`
+	// ConversionRate is the rate of sessions from previous step
+	ConversionRate string `json:"conversion_rate"`
+	// OverallRate is the rate of sessions from the first step
+	OverallRate string `json:"overall_rate"`
+	// DropOffs are sessions reached this step but not the next step
+	DropOffs       int64    `json:"drop_offs"`
+	DropOffRate    string   `json:"drop_off_rate"`
+	SampleSessions []string `json:"sample_sessions"`
+}
+
+type FunnelBreakdown struct {
+	Tag   string             `json:"tag"`
+	Steps []FunnelStepResult `json:"steps"`
+}
+
+type FunnelResponse struct {
+	Steps      []FunnelStepResult `json:"steps"`
+	Breakdowns []FunnelBreakdown  `json:"breakdowns,omitempty"`
+}
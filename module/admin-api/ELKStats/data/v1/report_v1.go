@@ -0,0 +1,125 @@
+package v1
+
+const (
+	ReportStatRecords   = "records"
+	ReportStatSessions  = "sessions"
+	ReportStatTERecords = "te_records"
+)
+
+const (
+	ReportPeriodDay   = "day"
+	ReportPeriodWeek  = "week"
+	ReportPeriodMonth = "month"
+)
+
+const (
+	ReportDeliverySMTP    = "smtp"
+	ReportDeliveryWebhook = "webhook"
+	ReportDeliveryFile    = "file"
+)
+
+const (
+	ReportDir            = "reports"
+	ReportSummaryFile    = "summary.html"
+	ReportMaxHistorySize = 100
+	// ReportMaxConcurrentRuns is the max number of scheduled runs in progress of an admin-api node
+	ReportMaxConcurrentRuns = 4
+	// ReportMinioNamespace is the minio bucket of generated files, so every admin-api node can serve them
+	ReportMinioNamespace = "stats-reports"
+	// ReportHistoryRetentionDays is the days to keep histories and their files
+	ReportHistoryRetentionDays = 90
+	// ReportRunTimeout is the seconds after which a running history is taken as interrupted,
+	// such as the admin-api node running it is restarted
+	ReportRunTimeout = 6 * 60 * 60
+)
+
+// ReportFilters are conditions applied to every stats of a report.
+// Platforms and Genders are tag codes, Keyword, Emotions and QTypes only apply to records,
+// ScenarioName only applies to te_records.
+type ReportFilters struct {
+	Platforms    []string `json:"platforms,omitempty"`
+	Genders      []string `json:"genders,omitempty"`
+	UserID       *string  `json:"uid,omitempty"`
+	Keyword      *string  `json:"keyword,omitempty"`
+	Emotions     []string `json:"emotions,omitempty"`
+	QTypes       []string `json:"question_types,omitempty"`
+	ScenarioName *string  `json:"scenario_name,omitempty"`
+	Feedback     *string  `json:"feedback,omitempty"`
+}
+
+// ReportDelivery is where a report is sent to. Targets are email addresses of smtp,
+// urls of webhook, or directories under the report drop directory of file.
+// Secret signs the payload posted to webhook targets, it is generated if it is empty.
+type ReportDelivery struct {
+	Type    string   `json:"type"`
+	Targets []string `json:"targets"`
+	Secret  string   `json:"secret,omitempty"`
+}
+
+type Report struct {
+	ID           int64         `json:"id"`
+	EnterpriseID string        `json:"-"`
+	AppID        string        `json:"app_id"`
+	Name         string        `json:"name"`
+	Stats        []string      `json:"stats"`
+	Filters      ReportFilters `json:"filters"`
+	// Period is the time range covered by a run, which ends at the beginning of the day of the run
+	Period string `json:"period"`
+	// Schedule is a cron spec with seconds, such as "0 0 8 * * 1" for every Monday 08:00
+	Schedule  string         `json:"schedule"`
+	Delivery  ReportDelivery `json:"delivery"`
+	Locale    string         `json:"locale"`
+	Enabled   bool           `json:"enabled"`
+	NextRunAt int64          `json:"next_run_at"`
+	CreatedAt int64          `json:"created_at"`
+	UpdatedAt int64          `json:"updated_at"`
+}
+
+// ReportRequest is the request body of creating or updating a report
+type ReportRequest struct {
+	Name     string         `json:"name"`
+	Stats    []string       `json:"stats"`
+	Filters  ReportFilters  `json:"filters"`
+	Period   string         `json:"period"`
+	Schedule string         `json:"schedule"`
+	Delivery ReportDelivery `json:"delivery"`
+	Enabled  *bool          `json:"enabled,omitempty"`
+}
+
+// ReportHistory is a run of report, Status is one of data.ExportTaskCodesMap
+type ReportHistory struct {
+	ID          string `json:"id"`
+	ReportID    int64  `json:"report_id"`
+	Status      int    `json:"-"`
+	StatusText  string `json:"status"`
+	StartTime   int64  `json:"start_time"`
+	EndTime     int64  `json:"end_time"`
+	Path        string `json:"-"`
+	Summary     string `json:"-"`
+	Delivered   bool   `json:"delivered"`
+	ErrReason   string `json:"err_reason"`
+	CreatedAt   int64  `json:"created_at"`
+	FinishedAt  int64  `json:"finished_at"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type ReportHistoryResponse struct {
+	Data []*ReportHistory `json:"data"`
+}
+
+type ReportStatCount struct {
+	Stat  string `json:"stat"`
+	Count int    `json:"count"`
+}
+
+// ReportSummary is the content of HTML summary and webhook payload of a run
+type ReportSummary struct {
+	ReportID    int64             `json:"report_id"`
+	HistoryID   string            `json:"history_id"`
+	Name        string            `json:"name"`
+	AppID       string            `json:"app_id"`
+	StartTime   string            `json:"start_time"`
+	EndTime     string            `json:"end_time"`
+	Counts      []ReportStatCount `json:"counts"`
+	DownloadURL string            `json:"download_url"`
+}
@@ -149,6 +149,40 @@ var SessionsTableHeader = map[string][]data.TableHeaderItem{
 			ID:   common.SessionsMetricFeedbackTime,
 		},
 	},
+	"en-us": []data.TableHeaderItem{
+		data.TableHeaderItem{
+			Text: "Session ID",
+			ID:   common.SessionsMetricSessionID,
+		},
+		data.TableHeaderItem{
+			Text: "Start Time",
+			ID:   common.SessionsMetricStartTime,
+		},
+		data.TableHeaderItem{
+			Text: "End Time",
+			ID:   common.SessionsMetricEndTime,
+		},
+		data.TableHeaderItem{
+			Text: "User ID",
+			ID:   common.SessionsMetricUserID,
+		},
+		data.TableHeaderItem{
+			Text: "Rating",
+			ID:   common.SessionsMetricRating,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback",
+			ID:   common.SessionsMetricFeedback,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback Text",
+			ID:   common.SessionsMetricCustomFeedback,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback Time",
+			ID:   common.SessionsMetricFeedbackTime,
+		},
+	},
 }
 
 func GetSessionsExportHeader(locale string) []string {
@@ -173,3 +207,7 @@ type SessionsExportResponse struct {
 type SessionsExportStatusResponse struct {
 	Status string `json:"status"`
 }
+
+func init() {
+	localemsg.RegisterTable("ELKStats/v1/SessionsTableHeader", SessionsTableHeader)
+}
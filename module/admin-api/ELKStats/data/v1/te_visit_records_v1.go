@@ -3,6 +3,7 @@ package v1
 import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 )
 
 type TEVisitRecordsRequest struct {
@@ -163,6 +164,56 @@ var TEVisitRecordsTableHeader = map[string][]data.TableHeaderItem{
 			ID:   common.TEVisitRecordsMetricFeedbackTime,
 		},
 	},
+	"en-us": []data.TableHeaderItem{
+		data.TableHeaderItem{
+			Text: "Scenario Session ID",
+			ID:   common.TEVisitRecordsMetricTESessionID,
+		},
+		data.TableHeaderItem{
+			Text: "Session ID",
+			ID:   common.TEVisitRecordsMetricSessionID,
+		},
+		data.TableHeaderItem{
+			Text: "User ID",
+			ID:   common.TEVisitRecordsMetricUserID,
+		},
+		data.TableHeaderItem{
+			Text: "Scenario ID",
+			ID:   common.TEVisitRecordsMetricScenarioID,
+		},
+		data.TableHeaderItem{
+			Text: "Scenario Name",
+			ID:   common.TEVisitRecordsMetricScenarioName,
+		},
+		data.TableHeaderItem{
+			Text: "Last Node ID",
+			ID:   common.TEVisitRecordsMetricLastNodeID,
+		},
+		data.TableHeaderItem{
+			Text: "Last Node Name",
+			ID:   common.TEVisitRecordsMetricLastNodeName,
+		},
+		data.TableHeaderItem{
+			Text: "Trigger Time",
+			ID:   common.TEVisitRecordsMetricTriggerTime,
+		},
+		data.TableHeaderItem{
+			Text: "Finish Time",
+			ID:   common.TEVisitRecordsMetricFinishTime,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback",
+			ID:   common.TEVisitRecordsMetricFeedback,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback Text",
+			ID:   common.TEVisitRecordsMetricCustomFeedback,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback Time",
+			ID:   common.TEVisitRecordsMetricFeedbackTime,
+		},
+	},
 }
 
 var TEVisitRecordsExportHeader = []string{
@@ -187,3 +238,7 @@ type TEVisitRecordsExportResponse struct {
 type TEVisitRecordsExportStatusResponse struct {
 	Status string `json:"status"`
 }
+
+func init() {
+	localemsg.RegisterTable("ELKStats/v1/TEVisitRecordsTableHeader", TEVisitRecordsTableHeader)
+}
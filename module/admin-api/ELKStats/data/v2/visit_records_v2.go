@@ -318,6 +318,96 @@ var VisitRecordsTableHeader = map[string][]data.TableHeaderItem{
 			ID:   common.VisitRecordsMetricTSpan,
 		},
 	},
+	"en-us": []data.TableHeaderItem{
+		data.TableHeaderItem{
+			Text: "Record ID",
+			ID:   common.VisitRecordsMetricUnique,
+		},
+		data.TableHeaderItem{
+			Text: "Session ID",
+			ID:   common.VisitRecordsMetricSessionID,
+		},
+		data.TableHeaderItem{
+			Text: "Task Engine ID",
+			ID:   common.VisitRecordsMetricTESessionID,
+		},
+		data.TableHeaderItem{
+			Text: "User ID",
+			ID:   common.VisitRecordsMetricUserID,
+		},
+		data.TableHeaderItem{
+			Text: "User Question",
+			ID:   common.VisitRecordsMetricUserQ,
+		},
+		data.TableHeaderItem{
+			Text: "Match Score",
+			ID:   common.VisitRecordsMetricScore,
+		},
+		data.TableHeaderItem{
+			Text: "Standard Question",
+			ID:   common.VisitRecordsMetricStdQ,
+		},
+		data.TableHeaderItem{
+			Text: "Robot Answer",
+			ID:   common.VisitRecordsMetricAnswer,
+		},
+		data.TableHeaderItem{
+			Text: "Time",
+			ID:   common.VisitRecordsMetricLogTime,
+		},
+		data.TableHeaderItem{
+			Text: "Emotion",
+			ID:   common.VisitRecordsMetricEmotion,
+		},
+		data.TableHeaderItem{
+			Text: "Emotion Score",
+			ID:   common.VisitRecordsMetricEmotionScore,
+		},
+		data.TableHeaderItem{
+			Text: "Intent",
+			ID:   common.VisitRecordsMetricIntent,
+		},
+		data.TableHeaderItem{
+			Text: "Intent Score",
+			ID:   common.VisitRecordsMetricIntentScore,
+		},
+		data.TableHeaderItem{
+			Text: "Module",
+			ID:   common.VisitRecordsMetricModule,
+		},
+		data.TableHeaderItem{
+			Text: "Source",
+			ID:   common.VisitRecordsMetricSource,
+		},
+		data.TableHeaderItem{
+			Text: "FAQ Category",
+			ID:   common.VisitRecordsMetricFaqCategoryName,
+		},
+		data.TableHeaderItem{
+			Text: "FAQ Label",
+			ID:   common.VisitRecordsMetricFaqRobotTagName,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback",
+			ID:   common.VisitRecordsMetricFeedback,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback Text",
+			ID:   common.VisitRecordsMetricCustomFeedback,
+		},
+		data.TableHeaderItem{
+			Text: "Feedback Time",
+			ID:   common.VisitRecordsMetricFeedbackTime,
+		},
+		data.TableHeaderItem{
+			Text: "Threshold",
+			ID:   common.VisitRecordsMetricThreshold,
+		},
+		data.TableHeaderItem{
+			Text: "Response Time",
+			ID:   common.VisitRecordsMetricTSpan,
+		},
+	},
 }
 
 func GetVisitRecordsExportHeader(locale string) []string {
@@ -357,3 +447,7 @@ type VisitRecordsExportResponse struct {
 type VisitRecordsExportStatusResponse struct {
 	Status string `json:"status"`
 }
+
+func init() {
+	localemsg.RegisterTable("ELKStats/v2/VisitRecordsTableHeader", VisitRecordsTableHeader)
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
+	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+// esRepository stores documents in Elasticsearch configured by util/elasticsearch
+type esRepository struct{}
+
+// NewElasticsearch returns the repository backed by Elasticsearch
+func NewElasticsearch() Repository {
+	return &esRepository{}
+}
+
+func (repo *esRepository) Search(req *SearchRequest) (*elastic.SearchResult, error) {
+	ctx, client := elasticsearch.GetClient()
+	if client == nil {
+		return nil, esData.ErrNotInit
+	}
+
+	ss := client.Search().Index(strings.Split(req.Index, ",")...)
+	if req.Type != "" {
+		ss = ss.Type(req.Type)
+	}
+	if req.Query != nil {
+		ss = ss.Query(req.Query)
+	}
+	for name, agg := range req.Aggregations {
+		ss = ss.Aggregation(name, agg)
+	}
+	if req.Source != nil {
+		ss = ss.FetchSourceContext(req.Source)
+	}
+	for _, s := range req.Sort {
+		ss = ss.Sort(s.Field, s.Ascending)
+	}
+	return ss.From(req.From).Size(req.Size).Do(ctx)
+}
+
+func (repo *esRepository) Scroll(req *SearchRequest) Scroller {
+	ctx, client := elasticsearch.GetClient()
+	if client == nil {
+		return &esScroller{err: esData.ErrNotInit}
+	}
+
+	service := client.Scroll().Index(strings.Split(req.Index, ",")...)
+	if req.Type != "" {
+		service = service.Type(req.Type)
+	}
+	if req.Query != nil {
+		service = service.Query(req.Query)
+	}
+	if req.Source != nil {
+		service = service.FetchSourceContext(req.Source)
+	}
+	for _, s := range req.Sort {
+		service = service.Sort(s.Field, s.Ascending)
+	}
+	if req.KeepAlive != "" {
+		service = service.KeepAlive(req.KeepAlive)
+	}
+	return &esScroller{ctx: ctx, service: service.Size(req.Size)}
+}
+
+func (repo *esRepository) UpdateByQuery(req *UpdateRequest) (int64, error) {
+	ctx, client := elasticsearch.GetClient()
+	if client == nil {
+		return 0, esData.ErrNotInit
+	}
+
+	s := client.UpdateByQuery(strings.Split(req.Index, ",")...)
+	if req.Type != "" {
+		s = s.Type(req.Type)
+	}
+	if req.Query != nil {
+		s = s.Query(req.Query)
+	}
+	s = s.Script(newUpdateScript(req)).ProceedOnVersionConflict()
+
+	resp, err := s.Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Updated, nil
+}
+
+// ownedIndexScript replaces the document only if it has the same app_id, so an app can not overwrite documents of others
+const ownedIndexScript = "if (ctx._source.app_id != params.app_id) { ctx.op = 'none' } else { ctx._source = params.doc }"
+
+func (repo *esRepository) Index(index string, indexType string, id string, doc interface{}) error {
+	ctx, client := elasticsearch.GetClient()
+	if client == nil {
+		return esData.ErrNotInit
+	}
+
+	if id == "" {
+		_, err := client.Index().Index(index).Type(indexType).BodyJson(doc).Do(ctx)
+		return err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	source := map[string]interface{}{}
+	if err = json.Unmarshal(raw, &source); err != nil {
+		return err
+	}
+	script := elastic.NewScript(ownedIndexScript).Params(map[string]interface{}{
+		"app_id": source["app_id"],
+		"doc":    source,
+	})
+	resp, err := client.Update().Index(index).Type(indexType).Id(id).Script(script).Upsert(source).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if resp.Result == "noop" {
+		return ErrDocumentOwned
+	}
+	return nil
+}
+
+func (repo *esRepository) Create(index string, indexType string, id string, doc interface{}) error {
+	ctx, client := elasticsearch.GetClient()
+	if client == nil {
+		return esData.ErrNotInit
+	}
+
+	s := client.Index().Index(index).Type(indexType).BodyJson(doc)
+	if id != "" {
+		s = s.Id(id).OpType("create")
+	}
+	_, err := s.Do(ctx)
+	if elastic.IsConflict(err) {
+		return ErrDocumentExists
+	}
+	return err
+}
+
+// newUpdateScript converts fields to set and remove into a painless script
+func newUpdateScript(req *UpdateRequest) *elastic.Script {
+	fields := make([]string, 0, len(req.Set))
+	for field := range req.Set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	statements := []string{}
+	params := map[string]interface{}{}
+	for i, field := range fields {
+		param := fmt.Sprintf("p%d", i)
+		statements = append(statements, fmt.Sprintf("ctx._source['%s'] = params.%s", field, param))
+		params[param] = req.Set[field]
+	}
+	for _, field := range req.Remove {
+		statements = append(statements, fmt.Sprintf("ctx._source.remove('%s')", field))
+	}
+	return elastic.NewScript(strings.Join(statements, "; ")).Params(params)
+}
+
+type esScroller struct {
+	ctx     context.Context
+	service *elastic.ScrollService
+	err     error
+}
+
+func (s *esScroller) Next() (*elastic.SearchResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.service.Do(s.ctx)
+}
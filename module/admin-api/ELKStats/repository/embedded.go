@@ -0,0 +1,440 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+// dateFields are the date fields in the index templates of records, sessions, users and task engine records.
+// They are compared as time and aggregated in milliseconds like Elasticsearch does.
+var dateFields = map[string]bool{
+	"log_time":       true,
+	"first_log_time": true,
+	"start_time":     true,
+	"end_time":       true,
+	"feedback_time":  true,
+	"to_human_time":  true,
+	"trigger_time":   true,
+	"finish_time":    true,
+}
+
+type document struct {
+	Index  string                 `json:"_index"`
+	Type   string                 `json:"_type"`
+	ID     string                 `json:"_id"`
+	Source map[string]interface{} `json:"_source"`
+}
+
+// Embedded is a repository which keeps documents in memory and evaluates Elasticsearch query DSL by itself,
+// so stats can run without an Elasticsearch cluster. Script queries are not supported.
+//
+// It is meant for small single instance deployments, whose records fit in memory: every query scans
+// the documents, which is simple and fast enough for such size and keeps the behavior the same as
+// Elasticsearch without translating query DSL to another query language. Deployments with more records
+// or instances should use Elasticsearch.
+//
+// Documents are persisted in a JSON Lines file per index if dir is given. Writes are appended to the file,
+// a document written again replaces the previous line when loading, and files are compacted after loading.
+// Documents are never modified in place, so pages of a scroll are built from a snapshot without holding the lock.
+type Embedded struct {
+	dir     string
+	indices map[string][]*document
+	// positions are the position of documents in indices, by index and id
+	positions map[string]map[string]int
+	nextID    int64
+	lock      sync.RWMutex
+}
+
+// NewEmbedded returns an embedded repository and loads documents from dir, documents are kept in memory only if dir is empty
+func NewEmbedded(dir string) (*Embedded, error) {
+	repo := &Embedded{
+		dir:       dir,
+		indices:   map[string][]*document{},
+		positions: map[string]map[string]int{},
+	}
+	if dir == "" {
+		return repo, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := repo.load(file); err != nil {
+			return nil, fmt.Errorf("load %s failed, %v", file, err)
+		}
+	}
+	return repo, nil
+}
+
+func (repo *Embedded) load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	index := strings.TrimSuffix(filepath.Base(file), ".jsonl")
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		doc := &document{}
+		if err := json.Unmarshal(line, doc); err != nil {
+			return err
+		}
+		doc.Index = index
+		repo.put(doc)
+		lines++
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if lines > len(repo.indices[index]) {
+		return repo.compact(index)
+	}
+	return nil
+}
+
+// put adds or replaces doc in memory, caller should hold the lock
+func (repo *Embedded) put(doc *document) {
+	if id, err := strconv.ParseInt(doc.ID, 10, 64); err == nil && id > repo.nextID {
+		repo.nextID = id
+	}
+	positions, ok := repo.positions[doc.Index]
+	if !ok {
+		positions = map[string]int{}
+		repo.positions[doc.Index] = positions
+	}
+	if i, ok := positions[doc.ID]; ok {
+		repo.indices[doc.Index][i] = doc
+		return
+	}
+	positions[doc.ID] = len(repo.indices[doc.Index])
+	repo.indices[doc.Index] = append(repo.indices[doc.Index], doc)
+}
+
+// write appends docs to the file of index, caller should hold the lock
+func (repo *Embedded) write(index string, docs []*document) error {
+	if repo.dir == "" || len(docs) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(repo.dir, index+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, doc := range docs {
+		if err = encoder.Encode(doc); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// compact rewrites the file of index with the current documents, caller should hold the lock
+func (repo *Embedded) compact(index string) error {
+	if repo.dir == "" {
+		return nil
+	}
+	file := filepath.Join(repo.dir, index+".jsonl")
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, doc := range repo.indices[index] {
+		if err = encoder.Encode(doc); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func (repo *Embedded) Index(index string, indexType string, id string, doc interface{}) error {
+	return repo.add(index, indexType, id, doc, false)
+}
+
+func (repo *Embedded) Create(index string, indexType string, id string, doc interface{}) error {
+	return repo.add(index, indexType, id, doc, true)
+}
+
+// add writes doc, existing document of id is only replaced if it is not create and the document has the same app_id
+func (repo *Embedded) add(index string, indexType string, id string, doc interface{}, create bool) error {
+	// Convert doc to a generic JSON object, so it is evaluated the same as loaded documents
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	source := map[string]interface{}{}
+	if err = json.Unmarshal(raw, &source); err != nil {
+		return err
+	}
+
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+	if id == "" {
+		repo.nextID++
+		id = strconv.FormatInt(repo.nextID, 10)
+	} else if i, ok := repo.positions[index][id]; ok {
+		if create {
+			return ErrDocumentExists
+		}
+		if documentAppID(repo.indices[index][i].Source) != documentAppID(source) {
+			return ErrDocumentOwned
+		}
+	}
+	newDoc := &document{Index: index, Type: indexType, ID: id, Source: source}
+	repo.put(newDoc)
+	return repo.write(index, []*document{newDoc})
+}
+
+func documentAppID(source map[string]interface{}) string {
+	appID, _ := source["app_id"].(string)
+	return appID
+}
+
+func (repo *Embedded) Search(req *SearchRequest) (*elastic.SearchResult, error) {
+	repo.lock.RLock()
+	defer repo.lock.RUnlock()
+
+	docs, err := repo.match(req.Index, req.Type, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	sortDocuments(docs, req.Sort)
+
+	aggs := map[string]interface{}{}
+	for name, agg := range req.Aggregations {
+		src, err := agg.Source()
+		if err != nil {
+			return nil, err
+		}
+		aggs[name], err = evalAggregation(toMap(src), docs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	from := req.From
+	if from > len(docs) {
+		from = len(docs)
+	}
+	to := from + req.Size
+	if req.Size < 0 || to > len(docs) {
+		to = len(docs)
+	}
+	return newSearchResult(int64(len(docs)), docs[from:to], aggs)
+}
+
+// Scroll keeps the matched documents as a snapshot, and converts them to a search result page by page
+func (repo *Embedded) Scroll(req *SearchRequest) Scroller {
+	repo.lock.RLock()
+	docs, err := repo.match(req.Index, req.Type, req.Query)
+	repo.lock.RUnlock()
+	if err == nil {
+		sortDocuments(docs, req.Sort)
+	}
+
+	size := req.Size
+	if size <= 0 {
+		size = 10
+	}
+	return &embeddedScroller{docs: docs, err: err, size: size}
+}
+
+func (repo *Embedded) UpdateByQuery(req *UpdateRequest) (int64, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	docs, err := repo.match(req.Index, req.Type, req.Query)
+	if err != nil {
+		return 0, err
+	}
+
+	// Updated documents are copies, so snapshots of scrolls are not changed
+	updated := map[string][]*document{}
+	for _, doc := range docs {
+		source := make(map[string]interface{}, len(doc.Source))
+		for field, value := range doc.Source {
+			source[field] = value
+		}
+		for field, value := range req.Set {
+			source[field] = normalizeValue(value)
+		}
+		for _, field := range req.Remove {
+			delete(source, field)
+		}
+		newDoc := &document{Index: doc.Index, Type: doc.Type, ID: doc.ID, Source: source}
+		repo.put(newDoc)
+		updated[doc.Index] = append(updated[doc.Index], newDoc)
+	}
+	for index, docs := range updated {
+		if err := repo.write(index, docs); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(docs)), nil
+}
+
+// match returns documents in indices matched pattern, which are matched query, caller should hold the lock
+func (repo *Embedded) match(pattern string, indexType string, query elastic.Query) ([]*document, error) {
+	var src map[string]interface{}
+	if query != nil {
+		s, err := query.Source()
+		if err != nil {
+			return nil, err
+		}
+		src = toMap(s)
+	}
+
+	names := make([]string, 0, len(repo.indices))
+	for name := range repo.indices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	docs := []*document{}
+	for _, name := range names {
+		if !matchIndex(pattern, name) {
+			continue
+		}
+		for _, doc := range repo.indices[name] {
+			if indexType != "" && doc.Type != "" && doc.Type != indexType {
+				continue
+			}
+			ok, err := matchQuery(src, doc.Source)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
+func matchIndex(pattern string, index string) bool {
+	for _, p := range strings.Split(pattern, ",") {
+		if ok, _ := path.Match(strings.TrimSpace(p), index); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortDocuments(docs []*document, sorts []SortField) {
+	if len(sorts) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, s := range sorts {
+			c := compareFieldValues(s.Field, docs[i].Source, docs[j].Source)
+			if c == 0 {
+				continue
+			}
+			if s.Ascending {
+				return c < 0
+			}
+			return c > 0
+		}
+		return false
+	})
+}
+
+// newSearchResult converts documents and aggregations to an Elasticsearch search response
+func newSearchResult(total int64, hits []*document, aggs map[string]interface{}) (*elastic.SearchResult, error) {
+	response := map[string]interface{}{
+		"took":      0,
+		"timed_out": false,
+		"hits":      newSearchHits(total, hits),
+	}
+	if len(aggs) > 0 {
+		response["aggregations"] = aggs
+	}
+
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	result := &elastic.SearchResult{}
+	if err = json.Unmarshal(raw, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func newSearchHits(total int64, docs []*document) map[string]interface{} {
+	hits := make([]*document, len(docs))
+	copy(hits, docs)
+	return map[string]interface{}{
+		"total":     total,
+		"max_score": nil,
+		"hits":      hits,
+	}
+}
+
+type embeddedScroller struct {
+	docs   []*document
+	err    error
+	size   int
+	offset int
+}
+
+func (s *embeddedScroller) Next() (*elastic.SearchResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.offset >= len(s.docs) {
+		return nil, io.EOF
+	}
+	end := s.offset + s.size
+	if end > len(s.docs) {
+		end = len(s.docs)
+	}
+	page, err := newSearchResult(int64(len(s.docs)), s.docs[s.offset:end], nil)
+	if err != nil {
+		return nil, err
+	}
+	s.offset = end
+	return page, nil
+}
@@ -0,0 +1,466 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var fixedIntervalRegex = regexp.MustCompile(`^(\d+)(ms|s|m|h|d)$`)
+
+// evalAggregation evaluates an aggregation in Elasticsearch DSL on docs, and returns the result in Elasticsearch format
+func evalAggregation(agg map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	subAggs, _ := agg["aggregations"].(map[string]interface{})
+	if subAggs == nil {
+		subAggs, _ = agg["aggs"].(map[string]interface{})
+	}
+
+	for typ, body := range agg {
+		if typ == "aggregations" || typ == "aggs" || typ == "meta" {
+			continue
+		}
+		setting, _ := body.(map[string]interface{})
+		switch typ {
+		case "terms":
+			return evalTermsAggregation(setting, subAggs, docs)
+		case "date_histogram":
+			return evalDateHistogramAggregation(setting, subAggs, docs)
+		case "min", "max", "avg", "sum":
+			return evalMetricAggregation(typ, setting, docs), nil
+		case "value_count", "cardinality":
+			return evalCountAggregation(typ, setting, docs), nil
+		case "filter":
+			return evalFilterAggregation(setting, subAggs, docs)
+		case "filters":
+			return evalFiltersAggregation(setting, subAggs, docs)
+		case "top_hits":
+			return evalTopHitsAggregation(setting, docs), nil
+		}
+		return nil, fmt.Errorf("%s: %v", typ, ErrUnsupportedQuery)
+	}
+	return nil, ErrUnsupportedQuery
+}
+
+// newBucket returns a bucket of docs with results of sub aggregations
+func newBucket(subAggs map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	bucket := map[string]interface{}{
+		"doc_count": len(docs),
+	}
+	for name, sub := range subAggs {
+		subAgg, _ := sub.(map[string]interface{})
+		result, err := evalAggregation(subAgg, docs)
+		if err != nil {
+			return nil, err
+		}
+		bucket[name] = result
+	}
+	return bucket, nil
+}
+
+func filterDocuments(query map[string]interface{}, docs []*document) ([]*document, error) {
+	matched := []*document{}
+	for _, doc := range docs {
+		ok, err := matchQuery(query, doc.Source)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+	return matched, nil
+}
+
+func intSetting(setting map[string]interface{}, key string, defaultValue int) int {
+	if v, ok := toFloat(setting[key]); ok {
+		return int(v)
+	}
+	return defaultValue
+}
+
+func evalTermsAggregation(setting map[string]interface{}, subAggs map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	field := toString(setting["field"])
+	size := intSetting(setting, "size", 10)
+	minDocCount := intSetting(setting, "min_doc_count", 1)
+
+	keys := []interface{}{}
+	groups := map[string][]*document{}
+	for _, doc := range docs {
+		seen := map[string]bool{}
+		for _, v := range getFieldValues(doc.Source, field) {
+			if v == nil {
+				continue
+			}
+			k := toString(v)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, v)
+			}
+			groups[k] = append(groups[k], doc)
+		}
+	}
+
+	byKey, ascending := false, false
+	for _, order := range toList(setting["order"]) {
+		o, _ := order.(map[string]interface{})
+		for k, direction := range o {
+			byKey = k == "_key" || k == "_term"
+			ascending = toString(direction) == "asc"
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		ci, cj := len(groups[toString(keys[i])]), len(groups[toString(keys[j])])
+		if !byKey && ci != cj {
+			if ascending {
+				return ci < cj
+			}
+			return ci > cj
+		}
+		c := compareValues(field, keys[i], keys[j], time.UTC)
+		if byKey && !ascending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	buckets := []interface{}{}
+	others := 0
+	for _, key := range keys {
+		group := groups[toString(key)]
+		if len(group) < minDocCount {
+			continue
+		}
+		if len(buckets) >= size {
+			others += len(group)
+			continue
+		}
+		bucket, err := newBucket(subAggs, group)
+		if err != nil {
+			return nil, err
+		}
+		bucket["key"] = key
+		buckets = append(buckets, bucket)
+	}
+	return map[string]interface{}{
+		"doc_count_error_upper_bound": 0,
+		"sum_other_doc_count":         others,
+		"buckets":                     buckets,
+	}, nil
+}
+
+// javaDateLayouts converts Joda date format used by Elasticsearch to Go layout
+var javaDateLayouts = strings.NewReplacer(
+	"yyyy", "2006",
+	"MM", "01",
+	"dd", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+	"SSS", "000",
+	"'T'", "T",
+	"'Z'", "Z",
+	"Z", "-0700",
+)
+
+func formatDate(t time.Time, format string) string {
+	switch format {
+	case "":
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	case "epoch_millis":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "epoch_second":
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(javaDateLayouts.Replace(format))
+}
+
+// truncateDate returns the start of the interval which t is in
+func truncateDate(t time.Time, interval string) (time.Time, error) {
+	switch interval {
+	case "year", "1y":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location()), nil
+	case "quarter", "1q":
+		return time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, t.Location()), nil
+	case "month", "1M":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), nil
+	case "week", "1w":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7), nil
+	case "day", "1d":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+	}
+
+	d, err := parseFixedInterval(interval)
+	if err != nil {
+		return t, err
+	}
+	// fixed intervals are aligned to the epoch in the time zone
+	_, offset := t.Zone()
+	local := t.UnixNano() + int64(offset)*int64(time.Second)
+	start := local - local%int64(d)
+	if local < 0 && local%int64(d) != 0 {
+		start -= int64(d)
+	}
+	return time.Unix(0, start-int64(offset)*int64(time.Second)).In(t.Location()), nil
+}
+
+func parseFixedInterval(interval string) (time.Duration, error) {
+	switch interval {
+	case "hour":
+		return time.Hour, nil
+	case "minute":
+		return time.Minute, nil
+	case "second":
+		return time.Second, nil
+	}
+	matches := fixedIntervalRegex.FindStringSubmatch(interval)
+	if matches == nil {
+		return 0, fmt.Errorf("interval %s: %v", interval, ErrUnsupportedQuery)
+	}
+	n, _ := strconv.Atoi(matches[1])
+	unit := map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+	}[matches[2]]
+	return time.Duration(n) * unit, nil
+}
+
+// nextDate returns the start of the interval after the one starts at t
+func nextDate(t time.Time, interval string) time.Time {
+	switch interval {
+	case "year", "1y":
+		return t.AddDate(1, 0, 0)
+	case "quarter", "1q":
+		return t.AddDate(0, 3, 0)
+	case "month", "1M":
+		return t.AddDate(0, 1, 0)
+	case "week", "1w":
+		return t.AddDate(0, 0, 7)
+	case "day", "1d":
+		return t.AddDate(0, 0, 1)
+	}
+	d, _ := parseFixedInterval(interval)
+	return t.Add(d)
+}
+
+func evalDateHistogramAggregation(setting map[string]interface{}, subAggs map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	field := toString(setting["field"])
+	interval := toString(setting["interval"])
+	format := toString(setting["format"])
+	loc := parseTimeZone(toString(setting["time_zone"]))
+	minDocCount := intSetting(setting, "min_doc_count", 0)
+
+	groups := map[int64][]*document{}
+	for _, doc := range docs {
+		values := getFieldValues(doc.Source, field)
+		if len(values) == 0 {
+			continue
+		}
+		t, ok := parseDate(values[0], time.UTC)
+		if !ok {
+			continue
+		}
+		start, err := truncateDate(t.In(loc), interval)
+		if err != nil {
+			return nil, err
+		}
+		groups[start.UnixNano()] = append(groups[start.UnixNano()], doc)
+	}
+
+	var first, last *time.Time
+	extend := func(t time.Time) {
+		if first == nil || t.Before(*first) {
+			first = &t
+		}
+		if last == nil || t.After(*last) {
+			last = &t
+		}
+	}
+	for start := range groups {
+		extend(time.Unix(0, start).In(loc))
+	}
+	if bounds, ok := setting["extended_bounds"].(map[string]interface{}); ok {
+		for _, key := range []string{"min", "max"} {
+			if t, ok := parseDate(bounds[key], loc); ok {
+				start, err := truncateDate(t.In(loc), interval)
+				if err != nil {
+					return nil, err
+				}
+				extend(start)
+			}
+		}
+	}
+
+	buckets := []interface{}{}
+	if first != nil {
+		for t := *first; !t.After(*last); t = nextDate(t, interval) {
+			group := groups[t.UnixNano()]
+			if len(group) < minDocCount {
+				continue
+			}
+			bucket, err := newBucket(subAggs, group)
+			if err != nil {
+				return nil, err
+			}
+			bucket["key"] = t.UnixNano() / int64(time.Millisecond)
+			bucket["key_as_string"] = formatDate(t, format)
+			buckets = append(buckets, bucket)
+		}
+	}
+	return map[string]interface{}{
+		"buckets": buckets,
+	}, nil
+}
+
+func evalMetricAggregation(typ string, setting map[string]interface{}, docs []*document) map[string]interface{} {
+	field := toString(setting["field"])
+	isDate := isDateField(field)
+
+	var result *float64
+	count := 0
+	for _, doc := range docs {
+		for _, v := range getFieldValues(doc.Source, field) {
+			var f float64
+			if isDate {
+				t, ok := parseDate(v, time.UTC)
+				if !ok {
+					continue
+				}
+				f = float64(t.UnixNano() / int64(time.Millisecond))
+			} else if n, ok := toFloat(v); ok {
+				f = n
+			} else {
+				continue
+			}
+
+			count++
+			switch {
+			case result == nil:
+				result = &f
+			case typ == "min" && f < *result:
+				*result = f
+			case typ == "max" && f > *result:
+				*result = f
+			case typ == "avg" || typ == "sum":
+				*result += f
+			}
+		}
+	}
+
+	if typ == "sum" && result == nil {
+		zero := float64(0)
+		result = &zero
+	}
+	if typ == "avg" && result != nil {
+		*result = *result / float64(count)
+	}
+
+	output := map[string]interface{}{
+		"value": result,
+	}
+	if result != nil && isDate {
+		t := time.Unix(0, int64(*result)*int64(time.Millisecond)).In(time.UTC)
+		output["value_as_string"] = formatDate(t, toString(setting["format"]))
+	}
+	return output
+}
+
+func evalCountAggregation(typ string, setting map[string]interface{}, docs []*document) map[string]interface{} {
+	field := toString(setting["field"])
+	count := 0
+	distinct := map[string]bool{}
+	for _, doc := range docs {
+		for _, v := range getFieldValues(doc.Source, field) {
+			if v == nil {
+				continue
+			}
+			count++
+			distinct[toString(v)] = true
+		}
+	}
+	if typ == "cardinality" {
+		count = len(distinct)
+	}
+	return map[string]interface{}{
+		"value": count,
+	}
+}
+
+func evalFilterAggregation(query map[string]interface{}, subAggs map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	matched, err := filterDocuments(query, docs)
+	if err != nil {
+		return nil, err
+	}
+	return newBucket(subAggs, matched)
+}
+
+func evalFiltersAggregation(setting map[string]interface{}, subAggs map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	switch filters := setting["filters"].(type) {
+	case map[string]interface{}:
+		buckets := map[string]interface{}{}
+		for name, filter := range filters {
+			query, _ := filter.(map[string]interface{})
+			bucket, err := evalFilterAggregation(query, subAggs, docs)
+			if err != nil {
+				return nil, err
+			}
+			buckets[name] = bucket
+		}
+		return map[string]interface{}{"buckets": buckets}, nil
+	case []interface{}:
+		buckets := []interface{}{}
+		for _, filter := range filters {
+			query, _ := filter.(map[string]interface{})
+			bucket, err := evalFilterAggregation(query, subAggs, docs)
+			if err != nil {
+				return nil, err
+			}
+			buckets = append(buckets, bucket)
+		}
+		return map[string]interface{}{"buckets": buckets}, nil
+	}
+	return nil, fmt.Errorf("filters: %v", ErrUnsupportedQuery)
+}
+
+func evalTopHitsAggregation(setting map[string]interface{}, docs []*document) map[string]interface{} {
+	sorts := []SortField{}
+	for _, s := range toList(setting["sort"]) {
+		switch v := s.(type) {
+		case string:
+			sorts = append(sorts, SortField{Field: v, Ascending: true})
+		case map[string]interface{}:
+			for field, order := range v {
+				if o, ok := order.(map[string]interface{}); ok {
+					order = o["order"]
+				}
+				sorts = append(sorts, SortField{Field: field, Ascending: toString(order) != "desc"})
+			}
+		}
+	}
+
+	sorted := make([]*document, len(docs))
+	copy(sorted, docs)
+	sortDocuments(sorted, sorts)
+
+	from := intSetting(setting, "from", 0)
+	size := intSetting(setting, "size", 3)
+	if from > len(sorted) {
+		from = len(sorted)
+	}
+	to := from + size
+	if to > len(sorted) {
+		to = len(sorted)
+	}
+	return map[string]interface{}{
+		"hits": newSearchHits(int64(len(sorted)), sorted[from:to]),
+	}
+}
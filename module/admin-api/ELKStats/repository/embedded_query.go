@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedQuery is returned if a query or aggregation cannot be evaluated by the embedded repository
+var ErrUnsupportedQuery = errors.New("query is not supported by embedded repository")
+
+// toMap converts source of query or aggregation to a generic JSON object
+func toMap(src interface{}) map[string]interface{} {
+	if m, ok := src.(map[string]interface{}); ok {
+		return normalizeValue(m).(map[string]interface{})
+	}
+	m, _ := normalizeValue(src).(map[string]interface{})
+	return m
+}
+
+// normalizeValue converts value to types decoded from JSON, such as float64 for all numbers
+func normalizeValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, float64:
+		return value
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var v interface{}
+	if err = json.Unmarshal(raw, &v); err != nil {
+		return value
+	}
+	return v
+}
+
+// getFieldValues returns all values of field in source, arrays of objects are flattened like nested documents.
+// The keyword sub-field is the same as the field itself.
+func getFieldValues(source map[string]interface{}, field string) []interface{} {
+	values := collectFieldValues(source, strings.Split(field, "."))
+	if len(values) == 0 && strings.HasSuffix(field, ".keyword") {
+		values = collectFieldValues(source, strings.Split(strings.TrimSuffix(field, ".keyword"), "."))
+	}
+	return values
+}
+
+func collectFieldValues(value interface{}, keys []string) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := []interface{}{}
+		for _, item := range v {
+			values = append(values, collectFieldValues(item, keys)...)
+		}
+		return values
+	case map[string]interface{}:
+		if len(keys) == 0 {
+			return []interface{}{v}
+		}
+		// field name may contain dots, such as custom_info.platform in a flattened object
+		for i := len(keys); i > 0; i-- {
+			if child, ok := v[strings.Join(keys[:i], ".")]; ok {
+				return collectFieldValues(child, keys[i:])
+			}
+		}
+		return nil
+	default:
+		if len(keys) > 0 {
+			return nil
+		}
+		return []interface{}{v}
+	}
+}
+
+func isDateField(field string) bool {
+	return dateFields[strings.TrimSuffix(field, ".keyword")]
+}
+
+// parseDate parses value of date field, numbers are epoch seconds or epoch milliseconds
+func parseDate(value interface{}, loc *time.Location) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		if math.Abs(v) >= 1e11 {
+			return time.Unix(0, int64(v)*int64(time.Millisecond)), true
+		}
+		return time.Unix(int64(v), 0), true
+	case string:
+		if loc == nil {
+			loc = time.UTC
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				return t, true
+			}
+		}
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return parseDate(n, loc)
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseTimeZone parses time zone of Elasticsearch, such as +08:00 or Asia/Taipei
+func parseTimeZone(tz string) *time.Location {
+	if tz == "" {
+		return time.UTC
+	}
+	if t, err := time.Parse("-07:00", tz); err == nil {
+		_, offset := t.Zone()
+		return time.FixedZone(tz, offset)
+	}
+	if loc, err := time.LoadLocation(tz); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// compareValues compares a and b as time if field is a date field, then as number, then as string.
+// Date without time zone in a is in UTC as Elasticsearch stores it, and in loc for b.
+func compareValues(field string, a interface{}, b interface{}, loc *time.Location) int {
+	if isDateField(field) {
+		ta, okA := parseDate(a, time.UTC)
+		tb, okB := parseDate(b, loc)
+		if okA && okB {
+			switch {
+			case ta.Before(tb):
+				return -1
+			case ta.After(tb):
+				return 1
+			}
+			return 0
+		}
+	}
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(toString(a), toString(b))
+}
+
+// compareFieldValues compares the first values of field in sources, documents without the field are sorted last
+func compareFieldValues(field string, a map[string]interface{}, b map[string]interface{}) int {
+	va := getFieldValues(a, field)
+	vb := getFieldValues(b, field)
+	switch {
+	case len(va) == 0 && len(vb) == 0:
+		return 0
+	case len(va) == 0:
+		return 1
+	case len(vb) == 0:
+		return -1
+	}
+	return compareValues(field, va[0], vb[0], time.UTC)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		return 0, false
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	if fa, ok := a.(float64); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	if fb, ok := b.(float64); ok {
+		fa, ok := toFloat(a)
+		return ok && fa == fb
+	}
+	return toString(a) == toString(b)
+}
+
+// toList returns value as a list of clauses, a single clause is also accepted as Elasticsearch does
+func toList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	}
+	return []interface{}{value}
+}
+
+// singleField returns the only field and its setting of a query, such as term and range
+func singleField(value interface{}) (string, interface{}) {
+	if m, ok := value.(map[string]interface{}); ok {
+		for field, setting := range m {
+			return field, setting
+		}
+	}
+	return "", nil
+}
+
+// matchQuery returns true if source matches query, all sources match an empty query
+func matchQuery(query map[string]interface{}, source map[string]interface{}) (bool, error) {
+	for typ, body := range query {
+		ok, err := matchClause(typ, body, source)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// countMatches returns the number of clauses matched by source
+func countMatches(clauses []interface{}, source map[string]interface{}) (int, error) {
+	count := 0
+	for _, clause := range clauses {
+		q, _ := clause.(map[string]interface{})
+		ok, err := matchQuery(q, source)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func matchClause(typ string, body interface{}, source map[string]interface{}) (bool, error) {
+	switch typ {
+	case "match_all":
+		return true, nil
+	case "match_none":
+		return false, nil
+	case "bool":
+		return matchBool(body, source)
+	case "term":
+		field, setting := singleField(body)
+		expected := setting
+		if m, ok := setting.(map[string]interface{}); ok {
+			expected = m["value"]
+		}
+		for _, v := range getFieldValues(source, field) {
+			if equalValues(v, expected) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "terms":
+		field, setting := singleField(body)
+		for _, v := range getFieldValues(source, field) {
+			for _, expected := range toList(setting) {
+				if equalValues(v, expected) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case "exists":
+		m, _ := body.(map[string]interface{})
+		field, _ := m["field"].(string)
+		for _, v := range getFieldValues(source, field) {
+			if v != nil {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "range":
+		field, setting := singleField(body)
+		m, _ := setting.(map[string]interface{})
+		return matchRange(field, m, source), nil
+	case "match", "match_phrase":
+		field, setting := singleField(body)
+		text := setting
+		if m, ok := setting.(map[string]interface{}); ok {
+			text = m["query"]
+		}
+		return matchText(getFieldValues(source, field), toString(text), typ == "match_phrase"), nil
+	case "nested":
+		m, _ := body.(map[string]interface{})
+		q, _ := m["query"].(map[string]interface{})
+		// fields of nested documents are flattened, so nested query is the same as its inner query
+		return matchQuery(q, source)
+	}
+	return false, fmt.Errorf("%s: %v", typ, ErrUnsupportedQuery)
+}
+
+func matchBool(body interface{}, source map[string]interface{}) (bool, error) {
+	m, _ := body.(map[string]interface{})
+	for _, key := range []string{"must", "filter"} {
+		clauses := toList(m[key])
+		count, err := countMatches(clauses, source)
+		if err != nil || count != len(clauses) {
+			return false, err
+		}
+	}
+
+	count, err := countMatches(toList(m["must_not"]), source)
+	if err != nil || count > 0 {
+		return false, err
+	}
+
+	should := toList(m["should"])
+	if len(should) == 0 {
+		return true, nil
+	}
+	minimum := 0
+	if m["must"] == nil && m["filter"] == nil {
+		minimum = 1
+	}
+	if v, ok := m["minimum_should_match"]; ok {
+		if n, ok := toFloat(v); ok {
+			minimum = int(n)
+		}
+	}
+	count, err = countMatches(should, source)
+	return count >= minimum, err
+}
+
+func matchRange(field string, setting map[string]interface{}, source map[string]interface{}) bool {
+	loc := parseTimeZone(toString(setting["time_zone"]))
+	includeLower, includeUpper := true, true
+	if v, ok := setting["include_lower"].(bool); ok {
+		includeLower = v
+	}
+	if v, ok := setting["include_upper"].(bool); ok {
+		includeUpper = v
+	}
+
+	for _, v := range getFieldValues(source, field) {
+		if from, ok := setting["from"]; ok && from != nil {
+			c := compareValues(field, v, from, loc)
+			if c < 0 || (c == 0 && !includeLower) {
+				continue
+			}
+		}
+		if to, ok := setting["to"]; ok && to != nil {
+			c := compareValues(field, v, to, loc)
+			if c > 0 || (c == 0 && !includeUpper) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// matchText returns true if any value contains the phrase, or any word of text if not phrase
+func matchText(values []interface{}, text string, phrase bool) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	words := []string{text}
+	if !phrase {
+		words = strings.Fields(text)
+	}
+	for _, v := range values {
+		value := strings.ToLower(toString(v))
+		for _, word := range words {
+			if strings.Contains(value, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
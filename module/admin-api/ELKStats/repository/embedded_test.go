@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+func newTestEmbedded(t *testing.T, dir string) *Embedded {
+	repo, err := NewEmbedded(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []map[string]interface{}{
+		{"app_id": "csbot", "session_id": "s1", "user_q": "How to Pay", "module": "faq", "log_time": "2018-07-01T01:00:00.000Z",
+			"answer": []interface{}{map[string]interface{}{"value": "by card"}}, "custom_info": map[string]interface{}{"platform": "web"}},
+		{"app_id": "csbot", "session_id": "s1", "user_q": "hello", "module": "chat", "log_time": "2018-07-01T02:00:00.000Z",
+			"custom_info": map[string]interface{}{"platform": "web"}},
+		{"app_id": "csbot", "session_id": "s2", "user_q": "refund", "module": "faq", "log_time": "2018-07-02T17:00:00.000Z",
+			"custom_info": map[string]interface{}{"platform": "app"}},
+		{"app_id": "other", "session_id": "s3", "user_q": "refund", "module": "faq", "log_time": "2018-07-02T03:00:00.000Z"},
+	}
+	for _, record := range records {
+		if err := repo.Index("emotibot-records-2018-07", "doc", "", record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func searchCount(t *testing.T, repo Repository, query elastic.Query) int64 {
+	result, err := repo.Search(&SearchRequest{Index: "emotibot-records-*", Type: "doc", Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.TotalHits()
+}
+
+func TestEmbeddedQuery(t *testing.T) {
+	repo := newTestEmbedded(t, "")
+	testTable := map[string]struct {
+		query    elastic.Query
+		expected int64
+	}{
+		"all":     {nil, 4},
+		"term":    {elastic.NewTermQuery("app_id", "csbot"), 3},
+		"keyword": {elastic.NewTermQuery("custom_info.platform.keyword", "web"), 2},
+		"terms":   {elastic.NewTermsQuery("session_id", "s2", "s3"), 2},
+		"exists":  {elastic.NewExistsQuery("custom_info.platform"), 3},
+		"range": {elastic.NewRangeQuery("log_time").Gte("2018-07-01 09:00:00").Lte("2018-07-02 11:00:00").
+			Format("yyyy-MM-dd HH:mm:ss").TimeZone("+08:00"), 3},
+		"bool": {elastic.NewBoolQuery().Filter(elastic.NewTermQuery("module", "faq")).
+			MustNot(elastic.NewTermQuery("app_id", "other")), 2},
+		"should": {elastic.NewBoolQuery().Should(elastic.NewMatchQuery("user_q", "pay"),
+			elastic.NewNestedQuery("answer", elastic.NewMatchQuery("answer.value", "card"))), 1},
+	}
+	for name, tc := range testTable {
+		if count := searchCount(t, repo, tc.query); count != tc.expected {
+			t.Errorf("%s: expect %d documents, but got %d", name, tc.expected, count)
+		}
+	}
+
+	_, err := repo.Search(&SearchRequest{Index: "emotibot-records-*",
+		Query: elastic.NewScriptQuery(elastic.NewScript("doc['score'].value > 0"))})
+	if err == nil {
+		t.Error("expect error of script query")
+	}
+}
+
+func TestEmbeddedSearchHits(t *testing.T) {
+	repo := newTestEmbedded(t, "")
+	result, err := repo.Search(&SearchRequest{
+		Index: "emotibot-records-*",
+		Query: elastic.NewTermQuery("app_id", "csbot"),
+		Sort:  []SortField{{Field: "log_time", Ascending: false}},
+		From:  1,
+		Size:  5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalHits() != 3 || len(result.Hits.Hits) != 2 {
+		t.Fatalf("expect 2 of 3 hits, but got %d of %d", len(result.Hits.Hits), result.TotalHits())
+	}
+	if result.Hits.Hits[0].Id != "2" || result.Hits.Hits[1].Id != "1" {
+		t.Errorf("expect hits sorted by log_time desc, but got %s, %s", result.Hits.Hits[0].Id, result.Hits.Hits[1].Id)
+	}
+}
+
+func TestEmbeddedAggregations(t *testing.T) {
+	repo := newTestEmbedded(t, "")
+	req := &SearchRequest{
+		Index: "emotibot-records-*",
+		Query: elastic.NewTermQuery("app_id", "csbot"),
+	}
+	req.Aggregation("sessions", elastic.NewTermsAggregation().Field("session_id").
+		SubAggregation("first", elastic.NewMinAggregation().Field("log_time")))
+	req.Aggregation("daily", elastic.NewDateHistogramAggregation().Field("log_time").Interval("day").
+		Format("yyyy-MM-dd HH:mm:ss").TimeZone("+08:00").MinDocCount(0).
+		ExtendedBounds("2018-06-30 00:00:00", "2018-07-03 00:00:00"))
+	req.Aggregation("users", elastic.NewCardinalityAggregation().Field("custom_info.platform.keyword"))
+	req.Aggregation("categories", elastic.NewFiltersAggregation().
+		FilterWithName("faq", elastic.NewTermQuery("module", "faq")).
+		FilterWithName("chat", elastic.NewTermQuery("module", "chat")))
+	req.Aggregation("latest", elastic.NewTopHitsAggregation().Sort("log_time", false).Size(1))
+
+	result, err := repo.Search(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, found := result.Aggregations.Terms("sessions")
+	if !found || len(sessions.Buckets) != 2 || sessions.Buckets[0].Key != "s1" || sessions.Buckets[0].DocCount != 2 {
+		t.Fatalf("unexpected terms aggregation: %+v", sessions)
+	}
+	first, found := sessions.Buckets[0].Min("first")
+	if !found || first.Value == nil || int64(*first.Value) != 1530406800000 {
+		t.Errorf("expect first log time of s1 in milliseconds, but got %+v", first)
+	}
+
+	daily, found := result.Aggregations.DateHistogram("daily")
+	if !found {
+		t.Fatal("expect date histogram")
+	}
+	counts := map[string]int64{}
+	for _, bucket := range daily.Buckets {
+		counts[*bucket.KeyAsString] = bucket.DocCount
+	}
+	expected := map[string]int64{
+		"2018-06-30 00:00:00": 0,
+		"2018-07-01 00:00:00": 2,
+		"2018-07-02 00:00:00": 0,
+		"2018-07-03 00:00:00": 1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expect daily counts in +08:00 to be %v, but got %v", expected, counts)
+	}
+
+	if users, found := result.Aggregations.Cardinality("users"); !found || *users.Value != 2 {
+		t.Errorf("expect 2 platforms, but got %+v", users)
+	}
+	categories, found := result.Aggregations.Filters("categories")
+	if !found || categories.NamedBuckets["faq"].DocCount != 2 || categories.NamedBuckets["chat"].DocCount != 1 {
+		t.Errorf("unexpected filters aggregation: %+v", categories)
+	}
+	latest, found := result.Aggregations.TopHits("latest")
+	if !found || latest.Hits.TotalHits != 3 || len(latest.Hits.Hits) != 1 || latest.Hits.Hits[0].Id != "3" {
+		t.Errorf("unexpected top hits: %+v", latest)
+	}
+}
+
+func TestEmbeddedUpdateAndScroll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo := newTestEmbedded(t, dir)
+	updated, err := repo.UpdateByQuery(&UpdateRequest{
+		Index: "emotibot-records-*",
+		Query: elastic.NewTermQuery("session_id", "s1"),
+		Set:   map[string]interface{}{"isMarked": true},
+	})
+	if err != nil || updated != 2 {
+		t.Fatalf("expect 2 updated records, but got %d, %v", updated, err)
+	}
+
+	// documents and updates should be loaded from dir
+	reloaded, err := NewEmbedded(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := searchCount(t, reloaded, elastic.NewTermQuery("isMarked", true)); count != 2 {
+		t.Errorf("expect 2 marked records after reload, but got %d", count)
+	}
+	if err = reloaded.Index("emotibot-records-2018-07", "doc", "", map[string]interface{}{"app_id": "csbot"}); err != nil {
+		t.Fatal(err)
+	}
+	if count := searchCount(t, reloaded, nil); count != 5 {
+		t.Errorf("expect new document to be added, but got %d documents", count)
+	}
+
+	scroller := reloaded.Scroll(&SearchRequest{Index: "emotibot-records-*", Size: 2})
+	pages, hits := 0, 0
+	for {
+		result, err := scroller.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		hits += len(result.Hits.Hits)
+	}
+	if pages != 3 || hits != 5 {
+		t.Errorf("expect 5 hits in 3 pages, but got %d in %d", hits, pages)
+	}
+}
+
+func TestNewUpdateScript(t *testing.T) {
+	script := newUpdateScript(&UpdateRequest{
+		Set:    map[string]interface{}{"isMarked": true, "isIgnored": false},
+		Remove: []string{"marked_intent"},
+	})
+	src, err := script.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(src)
+	expected := `{"params":{"p0":false,"p1":true},"source":"ctx._source['isIgnored'] = params.p0; ctx._source['isMarked'] = params.p1; ctx._source.remove('marked_intent')"}`
+	if string(raw) != expected {
+		t.Errorf("expect script %s, but got %s", expected, raw)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err := NewEmbedded(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"index": {"_index": "emotibot-records-2018-07", "_type": "doc", "_id": "r1"}}
+{"app_id": "other", "session_id": "s1", "user_q": "hi"}
+
+{"create": {"_index": "emotibot-sessions-2018-07", "_type": "doc"}}
+{"session_id": "s1"}
+{"index": {"_index": "emotibot-records-2018-07", "_type": "doc", "_id": "r1"}}
+{"app_id": "csbot", "session_id": "s1", "user_q": "hello"}
+`
+	count, err := Bulk(repo, strings.NewReader(body), "csbot")
+	if err != nil || count != 3 {
+		t.Fatalf("expect 3 documents written, but got %d, %v", count, err)
+	}
+
+	// the file is appended, and the replaced line is dropped when reloading
+	reloaded, err := NewEmbedded(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := searchCount(t, reloaded, elastic.NewTermQuery("app_id", "csbot")); count != 1 {
+		t.Errorf("expect replaced record of app, but got %d", count)
+	}
+	if count := searchCount(t, reloaded, elastic.NewMatchQuery("user_q", "hello")); count != 1 {
+		t.Errorf("expect latest record to be loaded, but got %d", count)
+	}
+
+	invalid := []string{
+		`{"delete": {"_index": "emotibot-records-2018-07", "_id": "r1"}}`,
+		`{"index": {"_index": "other-index", "_id": "r1"}}` + "\n{}",
+		`{"index": {"_index": "emotibot-records-2018-07"}}`,
+	}
+	for _, body := range invalid {
+		if _, err := Bulk(repo, strings.NewReader(body), "csbot"); err == nil {
+			t.Errorf("expect error of %s", body)
+		}
+	}
+}
+
+func TestBulkCrossApp(t *testing.T) {
+	repo, err := NewEmbedded("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"index": {"_index": "emotibot-records-2018-07", "_type": "doc", "_id": "r1"}}
+{"session_id": "s1", "user_q": "hi"}
+`
+	if _, err = Bulk(repo, strings.NewReader(body), "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Bulk(repo, strings.NewReader(body), "csbot"); err != nil {
+		t.Fatal(err)
+	}
+	if count := searchCount(t, repo, elastic.NewTermQuery("app_id", "other")); count != 1 {
+		t.Errorf("expect record of other app not to be overwritten, but got %d", count)
+	}
+	if count := searchCount(t, repo, elastic.NewTermQuery("app_id", "csbot")); count != 1 {
+		t.Errorf("expect record of app to be written, but got %d", count)
+	}
+
+	create := `{"create": {"_index": "emotibot-records-2018-07", "_type": "doc", "_id": "r1"}}
+{"session_id": "s2"}
+`
+	if _, err = Bulk(repo, strings.NewReader(create), "csbot"); err == nil {
+		t.Error("expect create of existing document to fail")
+	} else if _, ok := err.(*BulkError); !ok {
+		t.Errorf("expect bulk error, but got %v", err)
+	}
+
+	if err = repo.Index("emotibot-records-2018-07", "doc", "other:r1", map[string]interface{}{"app_id": "csbot"}); err != ErrDocumentOwned {
+		t.Errorf("expect document of other app not to be replaced, but got %v", err)
+	}
+	if err = repo.Create("emotibot-records-2018-07", "doc", "csbot:r1", map[string]interface{}{"app_id": "csbot"}); err != ErrDocumentExists {
+		t.Errorf("expect create of existing document to fail, but got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
+)
+
+// ingestIndices are the prefixes of indices which can be written by Bulk,
+// the suffix is the month of documents, such as emotibot-records-2018-07
+var ingestIndices = []string{
+	esData.ESRecordsIndex,
+	esData.ESCcsRecordsIndex,
+	esData.ESSessionsIndex,
+	esData.ESUsersIndex,
+	esData.ESTERecordsIndex,
+}
+
+// BulkError is returned by Bulk if the body is not in the bulk format
+type BulkError struct {
+	Line   int
+	Reason string
+}
+
+func (err *BulkError) Error() string {
+	return fmt.Sprintf("invalid bulk body, line %d: %s", err.Line, err.Reason)
+}
+
+type bulkAction struct {
+	Name  string `json:"-"`
+	Index string `json:"_index"`
+	Type  string `json:"_type"`
+	ID    string `json:"_id"`
+}
+
+// bulkDocumentID returns the id of document of app in repository, ids are prefixed by app,
+// so documents of different apps never share an id
+func bulkDocumentID(appID string, id string) string {
+	if id == "" {
+		return ""
+	}
+	return appID + ":" + id
+}
+
+func allowIngest(index string) bool {
+	for _, prefix := range ingestIndices {
+		if strings.HasPrefix(index, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// Bulk writes documents in the Elasticsearch bulk format into repo, which is used to ingest records
+// when there is no Elasticsearch for the log pipeline to write to. Each document is an action line,
+// such as {"index": {"_index": "emotibot-records-2018-07", "_type": "doc", "_id": "1"}}, followed by
+// its source line. Only index and create actions are supported, app_id of documents is set to appID.
+// Ids of documents are prefixed by appID, and create fails if the document exists.
+// The number of written documents is returned, documents before an error are kept.
+func Bulk(repo Repository, body io.Reader, appID string) (int, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	count, line := 0, 0
+	var action *bulkAction
+	for scanner.Scan() {
+		line++
+		content := strings.TrimSpace(scanner.Text())
+		if content == "" {
+			continue
+		}
+
+		if action == nil {
+			actions := map[string]*bulkAction{}
+			if err := json.Unmarshal([]byte(content), &actions); err != nil || len(actions) != 1 {
+				return count, &BulkError{Line: line, Reason: "should be an action"}
+			}
+			for name, a := range actions {
+				if (name != "index" && name != "create") || a == nil {
+					return count, &BulkError{Line: line, Reason: fmt.Sprintf("action %s is not supported", name)}
+				}
+				if !allowIngest(a.Index) {
+					return count, &BulkError{Line: line, Reason: fmt.Sprintf("index %s can not be written", a.Index)}
+				}
+				a.Name = name
+				action = a
+			}
+			continue
+		}
+
+		source := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(content), &source); err != nil {
+			return count, &BulkError{Line: line, Reason: "should be a document"}
+		}
+		source["app_id"] = appID
+		id := bulkDocumentID(appID, action.ID)
+		var err error
+		if action.Name == "create" {
+			err = repo.Create(action.Index, action.Type, id, source)
+		} else {
+			err = repo.Index(action.Index, action.Type, id, source)
+		}
+		if err == ErrDocumentExists || err == ErrDocumentOwned {
+			return count, &BulkError{Line: line, Reason: err.Error()}
+		} else if err != nil {
+			return count, err
+		}
+		count++
+		action = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	if action != nil {
+		return count, &BulkError{Line: line, Reason: "document of action is missing"}
+	}
+	return count, nil
+}
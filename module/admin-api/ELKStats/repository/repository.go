@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+// Repository is the storage of records, sessions, users and task engine records used by stats services.
+// Queries and aggregations are described in Elasticsearch query DSL and results are returned
+// in the format of Elasticsearch search response, so services do not depend on where records are stored.
+type Repository interface {
+	// Search returns documents and aggregations of documents matched the request
+	Search(req *SearchRequest) (*elastic.SearchResult, error)
+	// Scroll returns a scroller to iterate all documents matched the request, page by page
+	Scroll(req *SearchRequest) Scroller
+	// UpdateByQuery updates all documents matched the request, and returns number of updated documents
+	UpdateByQuery(req *UpdateRequest) (int64, error)
+	// Index adds or replaces a document, a new id is generated if id is empty.
+	// ErrDocumentOwned is returned if the document to replace has another app_id.
+	Index(index string, indexType string, id string, doc interface{}) error
+	// Create adds a document, ErrDocumentExists is returned if the id exists
+	Create(index string, indexType string, id string, doc interface{}) error
+}
+
+// Errors of writing documents
+var (
+	ErrDocumentExists = errors.New("document already exists")
+	ErrDocumentOwned  = errors.New("document is owned by another app")
+)
+
+// Scroller iterates documents of a scroll, Next returns io.EOF after the last page
+type Scroller interface {
+	Next() (*elastic.SearchResult, error)
+}
+
+// SortField is the field to sort documents by
+type SortField struct {
+	Field     string
+	Ascending bool
+}
+
+// SearchRequest is the condition of searching documents.
+// Index can be a pattern such as emotibot-records-*, and multiple indices are separated by comma.
+type SearchRequest struct {
+	Index        string
+	Type         string
+	Query        elastic.Query
+	Aggregations map[string]elastic.Aggregation
+	Source       *elastic.FetchSourceContext
+	Sort         []SortField
+	From         int
+	// Size is the number of documents returned, only aggregations are returned if Size is 0
+	Size int
+	// KeepAlive is how long a scroll should be kept alive between pages, such as 30s
+	KeepAlive string
+}
+
+// Aggregation adds an aggregation to the request
+func (req *SearchRequest) Aggregation(name string, agg elastic.Aggregation) *SearchRequest {
+	if req.Aggregations == nil {
+		req.Aggregations = map[string]elastic.Aggregation{}
+	}
+	req.Aggregations[name] = agg
+	return req
+}
+
+// UpdateRequest sets fields to values and removes fields of documents matched Query
+type UpdateRequest struct {
+	Index  string
+	Type   string
+	Query  elastic.Query
+	Set    map[string]interface{}
+	Remove []string
+}
+
+var (
+	current Repository = &esRepository{}
+	lock    sync.RWMutex
+)
+
+// Get returns the repository used by stats services, which is Elasticsearch by default
+func Get() Repository {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Set changes the repository used by stats services
+func Set(repo Repository) {
+	lock.Lock()
+	defer lock.Unlock()
+	current = repo
+}
@@ -9,6 +9,7 @@ import (
 	controllersV2 "emotibot.com/emotigo/module/admin-api/ELKStats/controllers/v2"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
+	servicesV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
 	"emotibot.com/emotigo/module/admin-api/util"
 	dac "emotibot.com/emotigo/pkg/api/dac/v1"
 	"emotibot.com/emotigo/pkg/api/dal/v1"
@@ -75,6 +76,7 @@ func Init() error {
 				[]string{"view", "export"}, controllersV1.VisitRecordsExportStatusHandler),
 			util.NewEntryPoint("POST", "records/mark", []string{"view", "export"}, controllersV1.NewRecordsMarkUpdateHandler(dalClient)),
 			util.NewEntryPoint("POST", "records/ignore", []string{"view", "export"}, controllersV1.RecordsIgnoredUpdateHandler),
+			util.NewEntryPoint("POST", "records/bulk", []string{"view", "export"}, controllersV1.RecordsBulkHandler),
 			util.NewEntryPoint("GET", "records/{id}/marked", []string{"view", "export"}, controllersV1.NewRecordSSMHandler(dalClient)),
 			util.NewEntryPoint("POST", "sessions/query", []string{"view"}, controllersV1.SessionsGetHandler),
 			util.NewEntryPoint("POST", "sessions/export", []string{"view", "export"}, controllersV1.SessionsExportHandler),
@@ -96,6 +98,22 @@ func Init() error {
 			util.NewEntryPoint("GET", "feedbacks", []string{"view"}, controllersV1.FeedbacksGetHandler),
 			util.NewEntryPoint("GET", "feedback/avg", []string{"view"}, controllersV1.FeedbackRatingAvgGetHandler),
 			util.NewEntryPoint("GET", "call", []string{"view"}, controllersV1.CallStatsGetHandler),
+			util.NewEntryPoint("POST", "funnel", []string{"view"}, controllersV1.FunnelGetHandler),
+			util.NewEntryPoint("GET", "reports", []string{"view"}, controllersV1.ReportsGetHandler),
+			util.NewEntryPoint("POST", "reports", []string{"view", "export"}, controllersV1.ReportCreateHandler),
+			util.NewEntryPoint("GET", "reports/{report_id}", []string{"view"}, controllersV1.ReportGetHandler),
+			util.NewEntryPoint("PUT", "reports/{report_id}", []string{"view", "export"}, controllersV1.ReportUpdateHandler),
+			util.NewEntryPoint("DELETE", "reports/{report_id}", []string{"view", "export"}, controllersV1.ReportDeleteHandler),
+			util.NewEntryPoint("POST", "reports/{report_id}/run", []string{"view", "export"}, controllersV1.ReportRunHandler),
+			util.NewEntryPoint("GET", "reports/{report_id}/history",
+				[]string{"view", "export"}, controllersV1.ReportHistoriesGetHandler),
+			util.NewEntryPoint("GET", "reports/{report_id}/history/{history_id}/download",
+				[]string{"view", "export"}, controllersV1.ReportHistoryDownloadHandler),
+			util.NewEntryPoint("GET", "reports/{report_id}/history/{history_id}/summary",
+				[]string{"view", "export"}, controllersV1.ReportHistorySummaryHandler),
+			util.NewEntryPoint("GET", "anomalies", []string{"view"}, controllersV1.AnomaliesGetHandler),
+			util.NewEntryPoint("GET", "anomalies/settings", []string{"view"}, controllersV1.AnomalySettingsGetHandler),
+			util.NewEntryPoint("PUT", "anomalies/settings", []string{"view", "export"}, controllersV1.AnomalySettingsUpdateHandler),
 			// v2 APIs
 			util.NewEntryPointWithVer("POST", "records/query", []string{"view"}, controllersV2.VisitRecordsGetHandler, 2),
 			util.NewEntryPointWithVer("POST", "records/ccs/query", []string{"view"}, controllersV2.VisitCcsRecordsGetHandler, 2),
@@ -117,6 +135,20 @@ func Init() error {
 			util.NewEntryPointWithVer("POST", "records/mark", []string{"view", "export"}, controllersV2.NewRecordsMarkUpdateHandlerV3(dacClient), 3),
 			util.NewEntryPointWithVer("GET", "records/{id}/marked", []string{"view", "export"}, controllersV2.NewRecordSSMHandlerV3(dacClient), 3),
 		},
+		Cronjobs: map[string]util.CronTask{
+			"stats_reports": util.CronTask{
+				Period:  "@every 1m",
+				Handler: servicesV1.RunDueReports,
+			},
+			"stats_report_histories": util.CronTask{
+				Period:  "@hourly",
+				Handler: servicesV1.CleanReportHistories,
+			},
+			"stats_anomalies": util.CronTask{
+				Period:  "0 5 * * * *",
+				Handler: servicesV1.DetectAnomalies,
+			},
+		},
 	}
 
 	err = services.InitTags()
@@ -124,5 +156,10 @@ func Init() error {
 		return err
 	}
 
-	return common.RecordsServiceInit()
+	err = common.RecordsServiceInit()
+	if err != nil {
+		return err
+	}
+
+	return servicesV1.ReportServiceInit()
 }
@@ -14,6 +14,7 @@ import (
 
 	"emotibot.com/emotigo/module/admin-api/ELKStats/dao"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	"emotibot.com/emotigo/pkg/logger"
@@ -223,22 +224,20 @@ func DownloadExportRecords(w http.ResponseWriter, exportTaskID string) error {
 		return err
 	}
 
-	switch path.Ext(filePath) {
-	case ".zip":
-		w.Header().Set("Content-type", "application/zip")
-	case ".xlsx":
-		w.Header().Set("Content-type",
-			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	default:
-		return fmt.Errorf("Invalid exported records file format: %s", filePath)
-	}
+	return WriteExportFile(w, filePath)
+}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s", path.Base(filePath)))
+// WriteExportFile writes the exported Excel or zip file to w as an attachment
+func WriteExportFile(w http.ResponseWriter, filePath string) error {
+	if err := setExportFileHeader(w, filePath); err != nil {
+		return err
+	}
 
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
 	_, err = io.Copy(w, file)
 	if err != nil {
@@ -248,6 +247,30 @@ func DownloadExportRecords(w http.ResponseWriter, exportTaskID string) error {
 	return nil
 }
 
+// WriteExportContent writes content of exported file, which is not stored locally, to w
+func WriteExportContent(w http.ResponseWriter, filePath string, content []byte) error {
+	if err := setExportFileHeader(w, filePath); err != nil {
+		return err
+	}
+	_, err := w.Write(content)
+	return err
+}
+
+func setExportFileHeader(w http.ResponseWriter, filePath string) error {
+	switch path.Ext(filePath) {
+	case ".zip":
+		w.Header().Set("Content-type", "application/zip")
+	case ".xlsx":
+		w.Header().Set("Content-type",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	default:
+		return fmt.Errorf("Invalid exported records file format: %s", filePath)
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s", path.Base(filePath)))
+	return nil
+}
+
 func DeleteExportRecords(exportTaskID string) error {
 	exists, err := dao.ExportRecordsExists(exportTaskID)
 	if !exists {
@@ -299,21 +322,43 @@ func ExportTask(option *data.ExportTaskOption, locale string) {
 		}
 	}()
 
-	ctx, client := elasticsearch.GetClient()
-
 	logger.Info.Printf("Start exporting task: %s...\n", option.TaskID)
 
-	service := client.Scroll().
-		Index(option.Index).
-		Query(option.BoolQuery).
-		Size(data.ESScrollSize).
-		FetchSourceContext(option.Source).
-		Sort(option.SortField, false).
-		KeepAlive(data.ESScrollKeepAlive)
+	filePath, numOfRecords, err := ExportXlsx(option, locale)
+	if err != nil {
+		return
+	}
+
+	if filePath == "" {
+		err = dao.ExportTaskEmpty(option.TaskID)
+		if err == nil {
+			logger.Info.Printf("Task %s: Exporting completed, no results\n", option.TaskID)
+		}
+		return
+	}
+
+	err = dao.ExportTaskCompleted(option.TaskID, filePath)
+	if err == nil {
+		logger.Info.Printf("Task %s: Exporting completed, total %d records\n",
+			option.TaskID, numOfRecords)
+	}
+}
+
+// ExportXlsx scrolls all the records of option and writes them to Excel files,
+// multiple Excel files are compressed into a zip file.
+// filePath is empty if there are no records.
+func ExportXlsx(option *data.ExportTaskOption, locale string) (filePath string, numOfRecords int, err error) {
+	service := repository.Get().Scroll(&repository.SearchRequest{
+		Index:     option.Index,
+		Query:     option.BoolQuery,
+		Source:    option.Source,
+		Sort:      []repository.SortField{{Field: option.SortField, Ascending: false}},
+		Size:      data.ESScrollSize,
+		KeepAlive: data.ESScrollKeepAlive,
+	})
 
 	records := make([]interface{}, 0)
 	xlsxFilePaths := make([]string, 0)
-	numOfRecords := 0
 	numOfRecordsPerXlsx := 0
 	timestamp := time.Now().Format(data.XlsxFileTimestampFormat)
 	numOfXlsxFiles := 0
@@ -325,7 +370,7 @@ func ExportTask(option *data.ExportTaskOption, locale string) {
 
 	for {
 		logger.Trace.Printf("Task %s: Fetching results..\n", option.TaskID)
-		results, _err := service.Do(ctx)
+		results, _err := service.Next()
 		logger.Trace.Printf("Task %s: Fetch results completed!!\n", option.TaskID)
 		if _err != nil {
 			// No more data, scroll finished
@@ -344,46 +389,33 @@ func ExportTask(option *data.ExportTaskOption, locale string) {
 				}
 
 				if len(xlsxFilePaths) == 0 {
-					err = dao.ExportTaskEmpty(option.TaskID)
-					if err == nil {
-						logger.Info.Printf("Task %s: Exporting completed, no results\n", option.TaskID)
-					}
+					return
 				} else if len(xlsxFilePaths) == 1 {
-					err = dao.ExportTaskCompleted(option.TaskID, xlsxFilePaths[0])
-					if err == nil {
-						logger.Info.Printf("Task %s: Exporting completed, total %d records\n",
-							option.TaskID, numOfRecords)
-					}
-				} else {
-					// Multiple Excel files, zip the files
-					logger.Info.Printf("Task %s: Start compressing %d Excel files...\n",
-						option.TaskID, len(xlsxFilePaths))
+					filePath = xlsxFilePaths[0]
+					return
+				}
 
-					zipFilePath, _err := compressExportRecordsXLSX(xlsxFilePaths, timestamp)
-					if _err != nil {
-						err = _err
-						return
-					}
+				// Multiple Excel files, zip the files
+				logger.Info.Printf("Task %s: Start compressing %d Excel files...\n",
+					option.TaskID, len(xlsxFilePaths))
 
-					logger.Info.Printf("Task %s: Compresion finished!!\n", option.TaskID)
+				zipFilePath, _err := compressExportRecordsXLSX(xlsxFilePaths, timestamp)
+				if _err != nil {
+					err = _err
+					return
+				}
+
+				logger.Info.Printf("Task %s: Compresion finished!!\n", option.TaskID)
 
-					err = dao.ExportTaskCompleted(option.TaskID, zipFilePath)
+				// Delete Excel files
+				for _, xlsxFilePath := range xlsxFilePaths {
+					err = os.Remove(xlsxFilePath)
 					if err != nil {
 						return
 					}
-
-					// Delete Excel files
-					for _, xlsxFilePath := range xlsxFilePaths {
-						err = os.Remove(xlsxFilePath)
-						if err != nil {
-							return
-						}
-					}
-
-					logger.Info.Printf("Task %s: Exporting completed, total %d records\n",
-						option.TaskID, numOfRecords)
 				}
 
+				filePath = zipFilePath
 				return
 			}
 
@@ -3,24 +3,24 @@ package common
 import (
 	"net/http"
 
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	elastic "gopkg.in/olivere/elastic.v6"
 )
 
-type ElasticSearchCommand func(*elastic.SearchService) *elastic.SearchService
+type ElasticSearchCommand func(*repository.SearchRequest) *repository.SearchRequest
 
-func AggregateFilterMarkedRecord(ss *elastic.SearchService) *elastic.SearchService {
+func AggregateFilterMarkedRecord(req *repository.SearchRequest) *repository.SearchRequest {
 	markFilter := elastic.NewFilterAggregation().Filter(elastic.NewTermQuery("isMarked", true))
-	ss = ss.Aggregation("isMarked", markFilter)
-	return ss
+	return req.Aggregation("isMarked", markFilter)
 }
 
-func AggregateFilterIgnoredRecord(ss *elastic.SearchService) *elastic.SearchService {
+func AggregateFilterIgnoredRecord(req *repository.SearchRequest) *repository.SearchRequest {
 	ignoreFilter := elastic.NewFilterAggregation().Filter(elastic.NewTermQuery("isIgnored", true))
-	ss = ss.Aggregation("isIgnored", ignoreFilter)
-	return ss
+	return req.Aggregation("isIgnored", ignoreFilter)
 }
 
-type UpdateCommand func(*elastic.UpdateByQueryService) *elastic.UpdateByQueryService
+// UpdateCommand sets the fields to update of an update request
+type UpdateCommand func(*repository.UpdateRequest) *repository.UpdateRequest
 
 func VisitRecordsExportDownload(w http.ResponseWriter, exportTaskID string) error {
 	return DownloadExportRecords(w, exportTaskID)
@@ -36,32 +36,26 @@ func VisitRecordsExportStatus(exportTaskID string) (status string, err error) {
 }
 
 func UpdateRecordMark(status bool) UpdateCommand {
-	return func(qs *elastic.UpdateByQueryService) *elastic.UpdateByQueryService {
-		script := elastic.NewScript("ctx._source.isMarked = params.mark")
-		script.Param("mark", status)
-		qs.Script(script)
-		return qs
+	return func(req *repository.UpdateRequest) *repository.UpdateRequest {
+		req.Set = map[string]interface{}{"isMarked": status}
+		return req
 	}
 }
 
 func UpdateRecordIntentMark(intentID int64) UpdateCommand {
-	return func(qs *elastic.UpdateByQueryService) *elastic.UpdateByQueryService {
-		script := elastic.NewScript("ctx._source.marked_intent = params.mark")
-		script.Param("mark", intentID)
-
+	return func(req *repository.UpdateRequest) *repository.UpdateRequest {
 		if intentID < 0 {
-			script = elastic.NewScript("ctx._source.remove(\"marked_intent\")")
+			req.Remove = []string{"marked_intent"}
+		} else {
+			req.Set = map[string]interface{}{"marked_intent": intentID}
 		}
-		qs.Script(script)
-		return qs
+		return req
 	}
 }
 
 func UpdateRecordIgnore(status bool) UpdateCommand {
-	return func(qs *elastic.UpdateByQueryService) *elastic.UpdateByQueryService {
-		script := elastic.NewScript("ctx._source.isIgnored = params.ignore")
-		script.Param("ignore", status)
-		qs.Script(script)
-		return qs
+	return func(req *repository.UpdateRequest) *repository.UpdateRequest {
+		req.Set = map[string]interface{}{"isIgnored": status}
+		return req
 	}
 }
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"context"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -9,7 +8,7 @@ import (
 
 	"emotibot.com/emotigo/module/admin-api/ELKStats/dao"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
-	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 
 	elastic "gopkg.in/olivere/elastic.v6"
 )
@@ -52,20 +51,14 @@ func CreateDateHistogramAggregation(query data.CommonQuery, queryField string) *
 		ExtendedBounds(query.StartTime.Format(data.ESTimeFormat), query.EndTime.Format(data.ESTimeFormat))
 }
 
-func CreateSearchService(ctx context.Context, client *elastic.Client,
-	query elastic.Query, index string, indexType string,
+func CreateSearchService(query elastic.Query, index string, indexType string,
 	aggName string, agg elastic.Aggregation) (*elastic.SearchResult, error) {
-	if client == nil {
-		return nil, esData.ErrNotInit
+	req := &repository.SearchRequest{
+		Index: index,
+		Type:  indexType,
+		Query: query,
 	}
-
-	return client.Search().
-		Index(index).
-		Type(indexType).
-		Query(query).
-		Aggregation(aggName, agg).
-		Size(0).
-		Do(ctx)
+	return repository.Get().Search(req.Aggregation(aggName, agg))
 }
 
 func ExtractCountsFromAggDateHistogramBuckets(result *elastic.SearchResult, aggName string) map[string]interface{} {
@@ -107,9 +100,9 @@ func ExtractCountsFromAggTermsBuckets(result *elastic.SearchResult, aggName stri
 	return counts
 }
 
-func DoDateHistogramAggService(ctx context.Context, client *elastic.Client, query elastic.Query,
-	index string, indexType string, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
-	result, err := CreateSearchService(ctx, client, query, index, indexType, aggName, agg)
+func DoDateHistogramAggService(query elastic.Query, index string, indexType string,
+	aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+	result, err := CreateSearchService(query, index, indexType, aggName, agg)
 	if err != nil {
 		return nil, err
 	}
@@ -118,9 +111,9 @@ func DoDateHistogramAggService(ctx context.Context, client *elastic.Client, quer
 	return counts, nil
 }
 
-func DoTermsAggService(ctx context.Context, client *elastic.Client, query elastic.Query,
-	index string, indexType string, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
-	result, err := CreateSearchService(ctx, client, query, index, indexType, aggName, agg)
+func DoTermsAggService(query elastic.Query, index string, indexType string,
+	aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+	result, err := CreateSearchService(query, index, indexType, aggName, agg)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,420 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/dao"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
+	"emotibot.com/emotigo/module/admin-api/util"
+	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
+	"emotibot.com/emotigo/pkg/logger"
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+// anomalyActiveDays is the number of days an app without any traffic is still detected,
+// so an app which suddenly stops serving is flagged as well
+const anomalyActiveDays = 7
+
+// UpdateAnomalyBaseline adds value into the running mean and variance of baseline.
+// Samples is capped by AnomalyMaxSamples, so older values are weighted down exponentially
+// and the baseline follows long-term changes of traffic.
+func UpdateAnomalyBaseline(baseline *dataV1.AnomalyBaseline, value float64) {
+	if baseline.Samples < dataV1.AnomalyMaxSamples {
+		baseline.Samples++
+	}
+	n := float64(baseline.Samples)
+
+	delta := value - baseline.Mean
+	baseline.Mean += delta / n
+	baseline.Variance += (delta*(value-baseline.Mean) - baseline.Variance) / n
+}
+
+// ScoreAnomaly returns the z-score of value against baseline and whether
+// it exceeds threshold in the direction of rule.
+// Baselines with less than AnomalyMinSamples samples never flag an anomaly.
+func ScoreAnomaly(rule dataV1.AnomalyRule, baseline *dataV1.AnomalyBaseline, value float64,
+	threshold float64) (score float64, stdDev float64, anomalous bool) {
+	stdDev = math.Max(math.Sqrt(baseline.Variance), rule.MinStdDev)
+	stdDev = math.Max(stdDev, math.Abs(baseline.Mean)*0.05)
+	if stdDev == 0 {
+		return
+	}
+
+	score = (value - baseline.Mean) / stdDev
+	if baseline.Samples < dataV1.AnomalyMinSamples {
+		return
+	}
+
+	switch rule.Direction {
+	case dataV1.AnomalyDirectionRise:
+		anomalous = score >= threshold
+	case dataV1.AnomalyDirectionDrop:
+		anomalous = -score >= threshold
+	default:
+		anomalous = math.Abs(score) >= threshold
+	}
+	return
+}
+
+// DetectAnomalies is run hourly by cron, it evaluates the metrics of every active app
+// in the previous hour against their seasonal baselines.
+// Daily metrics are evaluated after the last hour of a day.
+// Every admin-api node runs the job, a slot is learned and alerted only once
+// since both baselines and alerts are saved per slot.
+func DetectAnomalies() {
+	now := time.Now()
+	endTime := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	startTime := endTime.Add(-time.Hour)
+
+	retryAnomalyAlerts()
+
+	appIDs, err := anomalyActiveApps(endTime)
+	if err != nil {
+		logger.Error.Printf("Get active apps of anomaly detection failed: %s\n", err.Error())
+		return
+	}
+
+	for _, appID := range appIDs {
+		if err = DetectAppAnomalies(appID, startTime); err != nil {
+			logger.Error.Printf("Detect anomalies of app %s failed: %s\n", appID, err.Error())
+		}
+	}
+}
+
+// DetectAppAnomalies evaluates the metrics of app in the hour starting at startTime,
+// alerts are recorded and posted to the webhook of app
+func DetectAppAnomalies(appID string, startTime time.Time) error {
+	settings, err := dao.GetAnomalySettings(appID)
+	if err != nil {
+		return err
+	}
+	if !settings.Enabled {
+		return nil
+	}
+
+	for _, rule := range dataV1.AnomalyRules {
+		slotStart, slotEnd, hour := startTime, startTime.Add(time.Hour), startTime.Hour()
+		if rule.Daily {
+			if startTime.Hour() != 23 {
+				continue
+			}
+			slotStart = time.Date(startTime.Year(), startTime.Month(), startTime.Day(),
+				0, 0, 0, 0, startTime.Location())
+			slotEnd = slotStart.AddDate(0, 0, 1)
+			hour = dataV1.AnomalyDailyHour
+		}
+
+		value, err := AnomalyMetricValue(rule.Metric, appID, slotStart, slotEnd)
+		if err != nil {
+			return err
+		}
+		if value.Volume < rule.MinVolume {
+			continue
+		}
+
+		baseline, err := dao.GetAnomalyBaseline(appID, rule.Metric, int(slotStart.Weekday()), hour)
+		if err != nil {
+			return err
+		}
+		if baseline.LastSlot >= slotStart.Unix() {
+			// The slot has been evaluated by another node
+			continue
+		}
+
+		score, stdDev, anomalous := ScoreAnomaly(rule, baseline, value.Value, settings.Threshold)
+		if anomalous {
+			alert := &dataV1.AnomalyAlert{
+				AppID:     appID,
+				Metric:    rule.Metric,
+				Direction: dataV1.AnomalyDirectionRise,
+				StartTime: slotStart.Unix(),
+				EndTime:   slotEnd.Unix(),
+				Value:     value.Value,
+				Expected:  baseline.Mean,
+				StdDev:    stdDev,
+				Score:     score,
+				CreatedAt: time.Now().Unix(),
+			}
+			if score < 0 {
+				alert.Direction = dataV1.AnomalyDirectionDrop
+			}
+			if err = raiseAnomalyAlert(settings, alert); err != nil {
+				logger.Error.Printf("Raise anomaly alert of app %s failed: %s\n", appID, err.Error())
+			}
+		}
+
+		// Anomalous values are learned as well, so a lasting change of traffic
+		// becomes the new normal instead of alerting forever
+		UpdateAnomalyBaseline(baseline, value.Value)
+		saved, err := dao.SaveAnomalyBaseline(baseline, slotStart.Unix())
+		if err != nil {
+			return err
+		} else if !saved {
+			logger.Info.Printf("Baseline of %s of app %s has been updated by another node\n",
+				rule.Metric, appID)
+		}
+	}
+	return nil
+}
+
+func raiseAnomalyAlert(settings *dataV1.AnomalySettings, alert *dataV1.AnomalyAlert) error {
+	created, err := dao.CreateAnomalyAlert(alert)
+	if err != nil || !created || settings.WebhookURL == "" {
+		return err
+	}
+	return notifyAnomalyAlert(settings, alert)
+}
+
+// retryAnomalyAlerts posts alerts failed to be posted again, until they are posted
+// AnomalyMaxNotifyAttempts times
+func retryAnomalyAlerts() {
+	alerts, err := dao.GetUnnotifiedAnomalyAlerts(dataV1.AnomalyMaxNotifyAttempts)
+	if err != nil {
+		logger.Error.Printf("Get unnotified anomaly alerts failed: %s\n", err.Error())
+		return
+	}
+
+	settingsOfApps := map[string]*dataV1.AnomalySettings{}
+	for _, alert := range alerts {
+		settings, ok := settingsOfApps[alert.AppID]
+		if !ok {
+			settings, err = dao.GetAnomalySettings(alert.AppID)
+			if err != nil {
+				logger.Error.Printf("Get anomaly settings of app %s failed: %s\n", alert.AppID, err.Error())
+				continue
+			}
+			settingsOfApps[alert.AppID] = settings
+		}
+		if !settings.Enabled || settings.WebhookURL == "" {
+			continue
+		}
+		if err = notifyAnomalyAlert(settings, alert); err != nil {
+			logger.Error.Printf("Retry anomaly alert %d of app %s failed: %s\n", alert.ID, alert.AppID, err.Error())
+		}
+	}
+}
+
+// notifyAnomalyAlert claims an attempt of alert and posts it to webhook,
+// nothing is posted if another admin-api node has claimed the attempt
+func notifyAnomalyAlert(settings *dataV1.AnomalySettings, alert *dataV1.AnomalyAlert) error {
+	claimed, err := dao.ClaimAnomalyAlertNotify(alert.ID, alert.NotifyAttempts)
+	if err != nil || !claimed {
+		return err
+	}
+	alert.NotifyAttempts++
+
+	if err = postAnomalyWebhook(settings.WebhookURL, settings.WebhookSecret, alert); err != nil {
+		return err
+	}
+	alert.Notified = true
+	return dao.AnomalyAlertNotified(alert.ID)
+}
+
+func postAnomalyWebhook(target string, secret string, alert *dataV1.AnomalyAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	request, err := newReportWebhookRequest(target, secret, body, time.Now())
+	if err != nil {
+		return err
+	}
+	resp, err := reportWebhookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// AnomalyMetricValue computes metric of app in [startTime, endTime)
+func AnomalyMetricValue(metric string, appID string, startTime time.Time,
+	endTime time.Time) (*dataV1.AnomalyMetricValue, error) {
+	commonQuery := data.CommonQuery{
+		AppID:     appID,
+		StartTime: startTime,
+		EndTime:   endTime.Add(-time.Second),
+	}
+	value := &dataV1.AnomalyMetricValue{Metric: metric}
+
+	switch metric {
+	case dataV1.AnomalyMetricTotalCalls, dataV1.AnomalyMetricToHumanRate:
+		query := dataV1.CallStatsQuery{
+			CommonQuery: commonQuery,
+			AggInterval: data.IntervalHour,
+		}
+		totalCounts, err := TotalCallCounts(query)
+		if err != nil {
+			return nil, err
+		}
+		value.Volume = sumAnomalyCounts(totalCounts)
+		value.Value = value.Volume
+		if metric == dataV1.AnomalyMetricTotalCalls {
+			return value, nil
+		}
+
+		toHumanCounts, err := ToHumanCallCounts(query)
+		if err != nil {
+			return nil, err
+		}
+		value.Value = anomalyRate(sumAnomalyCounts(toHumanCounts), value.Volume)
+	case dataV1.AnomalyMetricUnknownRate:
+		query := dataV1.VisitStatsQuery{
+			StatsQuery: data.StatsQuery{
+				CommonQuery: commonQuery,
+				AggBy:       data.AggByTime,
+				AggInterval: data.IntervalHour,
+			},
+		}
+		totalCounts, err := TotalAskCounts(query)
+		if err != nil {
+			return nil, err
+		}
+		unknownCounts, err := UnknownQnACounts(query)
+		if err != nil {
+			return nil, err
+		}
+		value.Volume = sumAnomalyCounts(totalCounts)
+		value.Value = anomalyRate(sumAnomalyCounts(unknownCounts), value.Volume)
+	case dataV1.AnomalyMetricAvgRating:
+		ratings, err := DailyAvgRating(dataV1.FeedbacksQuery{
+			CommonQuery: commonQuery,
+			Type:        dataCommon.FeedbacksStatsTypeSessions,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Buckets are UTC days, merge them into the local day weighted by rating counts
+		var total float64
+		for _, rating := range ratings {
+			var count int64
+			for _, c := range rating.Count {
+				count += c
+			}
+			total += rating.Average * float64(count)
+			value.Volume += float64(count)
+		}
+		value.Value = anomalyRate(total, value.Volume)
+	default:
+		return nil, fmt.Errorf("invalid anomaly metric: %s", metric)
+	}
+
+	return value, nil
+}
+
+func sumAnomalyCounts(counts map[string]interface{}) float64 {
+	var sum float64
+	for _, count := range counts {
+		if c, ok := count.(int64); ok {
+			sum += float64(c)
+		}
+	}
+	return sum
+}
+
+func anomalyRate(count float64, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return count / total
+}
+
+// anomalyActiveApps returns apps with sessions or records in the last anomalyActiveDays days
+func anomalyActiveApps(endTime time.Time) ([]string, error) {
+	aggName := "apps"
+	commonQuery := data.CommonQuery{
+		StartTime: endTime.AddDate(0, 0, -anomalyActiveDays),
+		EndTime:   endTime,
+	}
+	agg := elastic.NewTermsAggregation().Field("app_id").Size(data.ESTermAggSize)
+
+	sources := []struct {
+		index     string
+		indexType string
+		field     string
+	}{
+		{esData.ESSessionsIndex, esData.ESSessionsType, data.SessionEndTimeFieldName},
+		{esData.ESRecordsIndex, esData.ESRecordType, data.LogTimeFieldName},
+	}
+
+	apps := make(map[string]bool)
+	appIDs := make([]string, 0)
+	for _, source := range sources {
+		boolQuery := services.CreateBoolQuery(commonQuery)
+		boolQuery = boolQuery.Filter(services.CreateRangeQuery(commonQuery, source.field))
+		index := fmt.Sprintf("%s-*", source.index)
+
+		counts, err := services.DoTermsAggService(boolQuery, index, source.indexType, aggName, agg)
+		if err != nil {
+			return nil, err
+		}
+		for appID := range counts {
+			if !apps[appID] {
+				apps[appID] = true
+				appIDs = append(appIDs, appID)
+			}
+		}
+	}
+	return appIDs, nil
+}
+
+func GetAnomalyAlerts(query *dataV1.AnomalyAlertsQuery) ([]*dataV1.AnomalyAlert, error) {
+	return dao.GetAnomalyAlerts(query)
+}
+
+func GetAnomalySettings(appID string) (*dataV1.AnomalySettings, error) {
+	return dao.GetAnomalySettings(appID)
+}
+
+// ValidateAnomalySettings checks threshold and webhook of settings
+func ValidateAnomalySettings(settings *dataV1.AnomalySettings) error {
+	if settings.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	if settings.WebhookURL != "" {
+		u, err := url.Parse(settings.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url: %s", settings.WebhookURL)
+		}
+		if err = util.CheckWebhookHost(u.Hostname()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateAnomalySettings saves settings of app, the webhook secret is kept if it is not given,
+// or generated if the app has no secret yet
+func UpdateAnomalySettings(appID string, settings *dataV1.AnomalySettings) error {
+	if settings.WebhookURL == "" {
+		settings.WebhookSecret = ""
+	} else if settings.WebhookSecret == "" {
+		current, err := dao.GetAnomalySettings(appID)
+		if err != nil {
+			return err
+		}
+		settings.WebhookSecret = current.WebhookSecret
+		if settings.WebhookSecret == "" {
+			settings.WebhookSecret = util.GenRandomString(32)
+		}
+	}
+	settings.AppID = appID
+	settings.UpdatedAt = time.Now().Unix()
+	return dao.SaveAnomalySettings(settings)
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+)
+
+func TestUpdateAnomalyBaseline(t *testing.T) {
+	baseline := &dataV1.AnomalyBaseline{}
+	for _, value := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		UpdateAnomalyBaseline(baseline, value)
+	}
+	if baseline.Samples != 8 || math.Abs(baseline.Mean-5) > 1e-9 || math.Abs(baseline.Variance-4) > 1e-9 {
+		t.Errorf("expect 8 samples with mean 5 and variance 4, but got %+v", baseline)
+	}
+
+	// Samples are capped, so new values weight more than the first ones
+	UpdateAnomalyBaseline(baseline, 13)
+	if baseline.Samples != dataV1.AnomalyMaxSamples || math.Abs(baseline.Mean-6) > 1e-9 {
+		t.Errorf("expect %d samples with mean 6, but got %+v", dataV1.AnomalyMaxSamples, baseline)
+	}
+}
+
+func TestScoreAnomaly(t *testing.T) {
+	baseline := &dataV1.AnomalyBaseline{Samples: 8, Mean: 0.1, Variance: 0.0004}
+	rise := dataV1.AnomalyRule{Direction: dataV1.AnomalyDirectionRise, MinStdDev: 0.02}
+	drop := dataV1.AnomalyRule{Direction: dataV1.AnomalyDirectionDrop, MinStdDev: 0.02}
+	both := dataV1.AnomalyRule{Direction: dataV1.AnomalyDirectionBoth, MinStdDev: 0.02}
+
+	testTable := []struct {
+		rule      dataV1.AnomalyRule
+		value     float64
+		anomalous bool
+	}{
+		{rise, 0.2, true},
+		{rise, 0.15, false},
+		{rise, 0, false},
+		{drop, 0, true},
+		{drop, 0.2, false},
+		{both, 0.2, true},
+		{both, 0, true},
+	}
+	for _, tc := range testTable {
+		score, stdDev, anomalous := ScoreAnomaly(tc.rule, baseline, tc.value, dataV1.AnomalyDefaultThreshold)
+		if anomalous != tc.anomalous {
+			t.Errorf("%s %v: expect anomalous %v, but got score %v", tc.rule.Direction, tc.value, tc.anomalous, score)
+		}
+		if math.Abs(stdDev-0.02) > 1e-9 {
+			t.Errorf("expect std dev 0.02, but got %v", stdDev)
+		}
+	}
+
+	// Flat baseline uses MinStdDev to avoid flagging tiny changes
+	flat := &dataV1.AnomalyBaseline{Samples: 8, Mean: 0.1}
+	if _, _, anomalous := ScoreAnomaly(rise, flat, 0.11, dataV1.AnomalyDefaultThreshold); anomalous {
+		t.Error("expect small change of flat baseline not anomalous")
+	}
+
+	// Baseline without enough samples never flags
+	young := &dataV1.AnomalyBaseline{Samples: dataV1.AnomalyMinSamples - 1, Mean: 0.1, Variance: 0.0004}
+	if _, _, anomalous := ScoreAnomaly(rise, young, 1, dataV1.AnomalyDefaultThreshold); anomalous {
+		t.Error("expect baseline without enough samples not anomalous")
+	}
+}
+
+func TestAnomalyMetricValue(t *testing.T) {
+	start := time.Date(2018, 7, 2, 10, 0, 0, 0, time.Local)
+	endTime := func(d time.Duration) string {
+		return start.Add(d).UTC().Format(time.RFC3339)
+	}
+	setupEmbeddedRepository(t, "emotibot-sessions-2018-07", []map[string]interface{}{
+		{"app_id": "csbot", "session_id": "s1", "end_time": endTime(time.Minute), "status": 1},
+		{"app_id": "csbot", "session_id": "s2", "end_time": endTime(20 * time.Minute), "status": -1},
+		{"app_id": "csbot", "session_id": "s3", "end_time": endTime(40 * time.Minute), "status": 1},
+		{"app_id": "csbot", "session_id": "s4", "end_time": endTime(50 * time.Minute), "status": -1},
+		{"app_id": "csbot", "session_id": "s5", "end_time": endTime(2 * time.Hour), "status": -1},
+		{"app_id": "other", "session_id": "s6", "end_time": endTime(time.Minute), "status": -1},
+	})
+
+	value, err := AnomalyMetricValue(dataV1.AnomalyMetricTotalCalls, "csbot", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Value != 4 || value.Volume != 4 {
+		t.Errorf("expect 4 calls, but got %+v", value)
+	}
+
+	value, err = AnomalyMetricValue(dataV1.AnomalyMetricToHumanRate, "csbot", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Value != 0.5 || value.Volume != 4 {
+		t.Errorf("expect to human rate 0.5 of 4 calls, but got %+v", value)
+	}
+}
@@ -1,7 +1,6 @@
 package v1
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -11,7 +10,6 @@ import (
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	elastic "gopkg.in/olivere/elastic.v6"
 )
@@ -22,10 +20,9 @@ func createCallStatsDateHistogramAggregation(query dataV1.CallStatsQuery) *elast
 	return agg
 }
 
-func doCallStatsDateHistogramAggService(ctx context.Context, client *elastic.Client,
-	query elastic.Query, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+func doCallStatsDateHistogramAggService(query elastic.Query, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
 	index := fmt.Sprintf("%s-*", esData.ESSessionsIndex)
-	result, err := services.CreateSearchService(ctx, client, query, index, esData.ESSessionsType, aggName, agg)
+	result, err := services.CreateSearchService(query, index, esData.ESSessionsType, aggName, agg)
 	if err != nil {
 		return nil, err
 	}
@@ -51,18 +48,16 @@ func calculateCallRates(totalCallCounts map[string]interface{},
 }
 
 func TotalCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "total_calls"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQuery(query.CommonQuery, data.SessionEndTimeFieldName)
 	boolQuery = boolQuery.Filter(rangeQuery)
 
 	dateHistogramAgg := createCallStatsDateHistogramAggregation(query)
-	return doCallStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+	return doCallStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 }
 
 func CompleteCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "complete_calls"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("status", dataCommon.CallStatusComplete)
@@ -71,7 +66,7 @@ func CompleteCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, er
 	boolQuery = boolQuery.Filter(rangeQuery)
 
 	dateHistogramAgg := createCallStatsDateHistogramAggregation(query)
-	return doCallStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+	return doCallStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 }
 
 func CompleteCallRates(completeCallCounts map[string]interface{},
@@ -80,7 +75,6 @@ func CompleteCallRates(completeCallCounts map[string]interface{},
 }
 
 func ToHumanCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "to_human_calls"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("status", dataCommon.CallStatusTranserToHuman)
@@ -89,7 +83,7 @@ func ToHumanCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, err
 	boolQuery = boolQuery.Filter(rangeQuery)
 
 	dateHistogramAgg := createCallStatsDateHistogramAggregation(query)
-	return doCallStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+	return doCallStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 }
 
 func ToHumanCallRates(toHumanCounts map[string]interface{},
@@ -98,7 +92,6 @@ func ToHumanCallRates(toHumanCounts map[string]interface{},
 }
 
 func TimeoutCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "timeout_calls"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("status", dataCommon.CallStatusTimeout)
@@ -107,7 +100,7 @@ func TimeoutCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, err
 	boolQuery = boolQuery.Filter(rangeQuery)
 
 	dateHistogramAgg := createCallStatsDateHistogramAggregation(query)
-	return doCallStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+	return doCallStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 }
 
 func TimeoutCallRates(timeoutCallCounts map[string]interface{},
@@ -116,7 +109,6 @@ func TimeoutCallRates(timeoutCallCounts map[string]interface{},
 }
 
 func CancelCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "cancel_calls"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("status", dataCommon.CallStatusCancel)
@@ -125,7 +117,7 @@ func CancelCallCounts(query dataV1.CallStatsQuery) (map[string]interface{}, erro
 	boolQuery = boolQuery.Filter(rangeQuery)
 
 	dateHistogramAgg := createCallStatsDateHistogramAggregation(query)
-	return doCallStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+	return doCallStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 }
 
 func CancelCallRates(cancelCallCounts map[string]interface{},
@@ -134,7 +126,6 @@ func CancelCallRates(cancelCallCounts map[string]interface{},
 }
 
 func Unknowns(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	// TODO: Metric definition not defined yet, return maps with all zero values now
 	aggName := "unknowns"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
@@ -144,11 +135,10 @@ func Unknowns(query dataV1.CallStatsQuery) (map[string]interface{}, error) {
 	boolQuery = boolQuery.Filter(rangeQuery)
 
 	dateHistogramAgg := createCallStatsDateHistogramAggregation(query)
-	return doCallStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+	return doCallStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 }
 
 func TopToHumanAnswers(query dataV1.CallStatsQuery, topN int) (dataV1.ToHumanAnswers, error) {
-	ctx, client := elasticsearch.GetClient()
 	// Query all the session IDs in the query date range
 	groupBySessionsAggName := "group_by_sessions"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
@@ -163,13 +153,8 @@ func TopToHumanAnswers(query dataV1.CallStatsQuery, topN int) (dataV1.ToHumanAns
 
 	index := fmt.Sprintf("%s-*", esData.ESSessionsIndex)
 
-	result, err := client.Search().
-		Index(index).
-		Type(esData.ESSessionsType).
-		Query(boolQuery).
-		Aggregation(groupBySessionsAggName, groupBySessionsAgg).
-		Size(0).
-		Do(ctx)
+	result, err := services.CreateSearchService(boolQuery, index, esData.ESSessionsType,
+		groupBySessionsAggName, groupBySessionsAgg)
 	if err != nil {
 		return nil, err
 	}
@@ -198,13 +183,8 @@ func TopToHumanAnswers(query dataV1.CallStatsQuery, topN int) (dataV1.ToHumanAns
 
 	index = fmt.Sprintf("%s-*", esData.ESRecordsIndex)
 
-	result, err = client.Search().
-		Index(index).
-		Type(esData.ESRecordType).
-		Query(termsQuery).
-		Aggregation(groupBySessionsAggName, groupBySessionsAgg).
-		Size(0).
-		Do(ctx)
+	result, err = services.CreateSearchService(termsQuery, index, esData.ESRecordType,
+		groupBySessionsAggName, groupBySessionsAgg)
 	if err != nil {
 		return nil, err
 	}
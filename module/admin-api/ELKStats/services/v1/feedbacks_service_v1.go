@@ -9,13 +9,11 @@ import (
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	elastic "gopkg.in/olivere/elastic.v6"
 )
 
 func DailyAvgRating(query dataV1.FeedbacksQuery) (map[int64]*dataV1.RatingAverageInfo, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "avg_rating"
 	countAggName := "count"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
@@ -47,7 +45,7 @@ func DailyAvgRating(query dataV1.FeedbacksQuery) (map[int64]*dataV1.RatingAverag
 		return nil, err
 	}
 
-	result, err := services.CreateSearchService(ctx, client, boolQuery, index, indexType,
+	result, err := services.CreateSearchService(boolQuery, index, indexType,
 		aggName, avgAgg)
 	if err != nil {
 		return nil, err
@@ -103,7 +101,6 @@ func getRatingCount(bucket *elastic.AggregationBucketKeyItem) (map[int64]int64,
 }
 
 func AvgRating(query dataV1.FeedbacksQuery) (float64, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "avg_rating"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQueryUnixTime(query.CommonQuery,
@@ -122,7 +119,7 @@ func AvgRating(query dataV1.FeedbacksQuery) (float64, error) {
 		return 0, err
 	}
 
-	result, err := services.CreateSearchService(ctx, client, boolQuery, index, indexType,
+	result, err := services.CreateSearchService(boolQuery, index, indexType,
 		aggName, avgAgg)
 	if err != nil {
 		return 0, err
@@ -138,7 +135,6 @@ func AvgRating(query dataV1.FeedbacksQuery) (float64, error) {
 }
 
 func Ratings(query dataV1.FeedbacksQuery, topN int) (dataV1.FeedbackRatings, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "ratings"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQueryUnixTime(query.CommonQuery,
@@ -158,7 +154,7 @@ func Ratings(query dataV1.FeedbacksQuery, topN int) (dataV1.FeedbackRatings, err
 		return nil, err
 	}
 
-	result, err := services.DoTermsAggService(ctx, client, boolQuery, index, indexType,
+	result, err := services.DoTermsAggService(boolQuery, index, indexType,
 		aggName, ratingsAgg)
 	if err != nil {
 		return nil, err
@@ -178,7 +174,6 @@ func Ratings(query dataV1.FeedbacksQuery, topN int) (dataV1.FeedbackRatings, err
 }
 
 func Feedbacks(query dataV1.FeedbacksQuery, topN int) (dataV1.FeedbackCounts, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "feedbacks"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQueryUnixTime(query.CommonQuery,
@@ -198,7 +193,7 @@ func Feedbacks(query dataV1.FeedbacksQuery, topN int) (dataV1.FeedbackCounts, er
 		return nil, err
 	}
 
-	result, err := services.DoTermsAggService(ctx, client, boolQuery, index, indexType,
+	result, err := services.DoTermsAggService(boolQuery, index, indexType,
 		aggName, feedbacksAgg)
 	if err != nil {
 		return nil, err
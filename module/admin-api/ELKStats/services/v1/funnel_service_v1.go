@@ -0,0 +1,243 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
+	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+const (
+	funnelSessionsAggName  = "sessions"
+	funnelFirstTimeAggName = "first_time"
+	funnelTagAggName       = "tag"
+)
+
+// FunnelStepHits returns the sessions reached step, with the first time of each session.
+// Records of scenario nodes have no custom_info, so filters and breakdown of them
+// are applied by joining with the records of the same session.
+func FunnelStepHits(query dataV1.FunnelQuery, step dataV1.FunnelStep) (dataV1.FunnelHits, error) {
+	boolQuery := services.CreateBoolQuery(query.CommonQuery)
+
+	var index, indexType, timeField string
+	switch step.Type {
+	case dataV1.FunnelStepFAQ:
+		index, indexType, timeField = esData.ESRecordsIndex, esData.ESRecordType, data.LogTimeFieldName
+		boolQuery = boolQuery.Filter(elastic.NewTermQuery("module", "faq"))
+		if step.Value != "" {
+			boolQuery = boolQuery.Filter(elastic.NewTermQuery("std_q.keyword", step.Value))
+		} else {
+			boolQuery = boolQuery.MustNot(elastic.NewTermQuery("std_q.keyword", ""))
+		}
+		boolQuery = boolQuery.Filter(services.CreateRangeQuery(query.CommonQuery, timeField))
+	case dataV1.FunnelStepIntent:
+		index, indexType, timeField = esData.ESRecordsIndex, esData.ESRecordType, data.LogTimeFieldName
+		if step.Value != "" {
+			boolQuery = boolQuery.Filter(elastic.NewTermQuery("intent", step.Value))
+		} else {
+			boolQuery = boolQuery.Filter(elastic.NewExistsQuery("intent"))
+		}
+		boolQuery = boolQuery.Filter(services.CreateRangeQuery(query.CommonQuery, timeField))
+	case dataV1.FunnelStepScenarioNode:
+		boolQuery = boolQuery.Filter(elastic.NewTermQuery(dataCommon.TEVisitRecordsMetricScenarioID, step.ScenarioID))
+		if step.Value != "" {
+			boolQuery = boolQuery.Filter(elastic.NewTermQuery(dataCommon.TEVisitRecordsMetricLastNodeID, step.Value))
+		}
+		boolQuery = boolQuery.Filter(services.CreateRangeQueryUnixTime(query.CommonQuery, data.TERecordsTriggerTimeFieldName))
+		hits, err := funnelSessionHits(boolQuery, esData.ESTERecordsIndex, esData.ESTERecordsType,
+			data.TERecordsTriggerTimeFieldName, "")
+		if err != nil || (len(query.Filters) == 0 && query.Breakdown == "") {
+			return hits, err
+		}
+		return joinFunnelRecordSessions(query, hits)
+	case dataV1.FunnelStepToHuman:
+		index, indexType, timeField = esData.ESSessionsIndex, esData.ESSessionsType, data.SessionEndTimeFieldName
+		boolQuery = boolQuery.Filter(elastic.NewTermQuery("status", dataCommon.CallStatusTranserToHuman))
+		boolQuery = boolQuery.Filter(services.CreateRangeQuery(query.CommonQuery, timeField))
+	default:
+		return nil, data.ErrInvalidAggType
+	}
+
+	boolQuery = filterFunnelTags(boolQuery, query.Filters)
+	return funnelSessionHits(boolQuery, index, indexType, timeField, query.Breakdown)
+}
+
+func filterFunnelTags(boolQuery *elastic.BoolQuery, filters map[string]string) *elastic.BoolQuery {
+	for tag, value := range filters {
+		boolQuery = boolQuery.Filter(elastic.NewTermQuery(fmt.Sprintf("custom_info.%s.keyword", tag), value))
+	}
+	return boolQuery
+}
+
+// joinFunnelRecordSessions keeps hits of sessions which have records matching filters,
+// and sets the tag of hits from the records
+func joinFunnelRecordSessions(query dataV1.FunnelQuery, hits dataV1.FunnelHits) (dataV1.FunnelHits, error) {
+	boolQuery := services.CreateBoolQuery(query.CommonQuery)
+	boolQuery = boolQuery.Filter(services.CreateRangeQuery(query.CommonQuery, data.LogTimeFieldName))
+	boolQuery = filterFunnelTags(boolQuery, query.Filters)
+	sessions, err := funnelSessionHits(boolQuery, esData.ESRecordsIndex, esData.ESRecordType,
+		data.LogTimeFieldName, query.Breakdown)
+	if err != nil {
+		return nil, err
+	}
+
+	joined := dataV1.FunnelHits{}
+	for sessionID, hit := range hits {
+		session, ok := sessions[sessionID]
+		if !ok {
+			if len(query.Filters) > 0 {
+				continue
+			}
+		} else {
+			hit.Tag = session.Tag
+		}
+		joined[sessionID] = hit
+	}
+	return joined, nil
+}
+
+// funnelSessionHits aggregates the matched documents by session, breakdown is the tag
+// of session in custom_info, it is not aggregated if empty
+func funnelSessionHits(boolQuery *elastic.BoolQuery, index, indexType, timeField, breakdown string) (dataV1.FunnelHits, error) {
+	sessionsAgg := elastic.NewTermsAggregation().Field("session_id").Size(data.ESTermAggSize)
+	sessionsAgg.SubAggregation(funnelFirstTimeAggName, elastic.NewMinAggregation().Field(timeField))
+	if breakdown != "" {
+		sessionsAgg.SubAggregation(funnelTagAggName, common.CreateStatsTagTermsAggregation(breakdown).Size(1))
+	}
+
+	result, err := services.CreateSearchService(boolQuery, fmt.Sprintf("%s-*", index), indexType,
+		funnelSessionsAggName, sessionsAgg)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := dataV1.FunnelHits{}
+	agg, found := result.Aggregations.Terms(funnelSessionsAggName)
+	if !found {
+		return hits, nil
+	}
+	for _, bucket := range agg.Buckets {
+		sessionID, ok := bucket.Key.(string)
+		if !ok || sessionID == "" {
+			continue
+		}
+		hit := &dataV1.FunnelHit{}
+		if firstTime, found := bucket.Min(funnelFirstTimeAggName); found && firstTime.Value != nil {
+			hit.Time = int64(*firstTime.Value)
+		}
+		if tagAgg, found := bucket.Terms(funnelTagAggName); found && len(tagAgg.Buckets) > 0 {
+			hit.Tag, _ = tagAgg.Buckets[0].Key.(string)
+		}
+		hits[sessionID] = hit
+	}
+	return hits, nil
+}
+
+// ComputeFunnel computes conversion and drop-off of each step from hits of steps.
+// A session converts to a step if it reached the step not earlier than the previous step.
+// Sessions are grouped by the tag of first step if breakdown is true.
+func ComputeFunnel(steps []dataV1.FunnelStep, stepHits []dataV1.FunnelHits, sampleSize int, breakdown bool) dataV1.FunnelResponse {
+	response := dataV1.FunnelResponse{
+		Steps: computeFunnelSteps(steps, stepHits, sampleSize, nil),
+	}
+	if !breakdown || len(stepHits) == 0 {
+		return response
+	}
+
+	tags := map[string]bool{}
+	for _, hit := range stepHits[0] {
+		tags[hit.Tag] = true
+	}
+	tagNames := make([]string, 0, len(tags))
+	for tag := range tags {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Strings(tagNames)
+
+	response.Breakdowns = make([]dataV1.FunnelBreakdown, 0, len(tagNames))
+	for _, tag := range tagNames {
+		t := tag
+		response.Breakdowns = append(response.Breakdowns, dataV1.FunnelBreakdown{
+			Tag:   tag,
+			Steps: computeFunnelSteps(steps, stepHits, sampleSize, &t),
+		})
+	}
+	return response
+}
+
+// computeFunnelSteps computes the funnel of sessions with tag, all sessions are used if tag is nil
+func computeFunnelSteps(steps []dataV1.FunnelStep, stepHits []dataV1.FunnelHits, sampleSize int, tag *string) []dataV1.FunnelStepResult {
+	results := make([]dataV1.FunnelStepResult, len(steps))
+	// reached is the sessions converted to current step, with the time reaching the step
+	var reached map[string]int64
+	for i, step := range steps {
+		hits := dataV1.FunnelHits{}
+		if i < len(stepHits) {
+			hits = stepHits[i]
+		}
+
+		current := map[string]int64{}
+		if i == 0 {
+			for sessionID, hit := range hits {
+				if tag == nil || hit.Tag == *tag {
+					current[sessionID] = hit.Time
+				}
+			}
+		} else {
+			for sessionID, prevTime := range reached {
+				if hit, ok := hits[sessionID]; ok && hit.Time >= prevTime {
+					current[sessionID] = hit.Time
+				}
+			}
+		}
+
+		results[i] = dataV1.FunnelStepResult{
+			FunnelStep:     step,
+			Sessions:       int64(len(current)),
+			ConversionRate: "N/A",
+			OverallRate:    "N/A",
+			DropOffRate:    "N/A",
+			SampleSessions: []string{},
+		}
+		if i == 0 {
+			results[i].ConversionRate = formatFunnelRate(int64(len(current)), int64(len(current)))
+		} else {
+			results[i].ConversionRate = formatFunnelRate(int64(len(current)), results[i-1].Sessions)
+			results[i-1].DropOffs = results[i-1].Sessions - int64(len(current))
+			results[i-1].DropOffRate = formatFunnelRate(results[i-1].DropOffs, results[i-1].Sessions)
+			results[i-1].SampleSessions = sampleDropOffs(reached, current, sampleSize)
+		}
+		results[i].OverallRate = formatFunnelRate(int64(len(current)), results[0].Sessions)
+		reached = current
+	}
+	return results
+}
+
+// sampleDropOffs returns at most size sessions which are in reached but not in next
+func sampleDropOffs(reached map[string]int64, next map[string]int64, size int) []string {
+	sessions := []string{}
+	for sessionID := range reached {
+		if _, ok := next[sessionID]; !ok {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	sort.Strings(sessions)
+	if len(sessions) > size {
+		sessions = sessions[:size]
+	}
+	return sessions
+}
+
+func formatFunnelRate(count int64, total int64) string {
+	if total == 0 {
+		return "N/A"
+	}
+	return strconv.FormatFloat(float64(count)/float64(total), 'f', 2, 64)
+}
@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+)
+
+func TestFunnelStepHits(t *testing.T) {
+	setupEmbeddedRepository(t, "emotibot-records-2018-07", []map[string]interface{}{
+		{"app_id": "csbot", "session_id": "a", "module": "faq", "std_q": "how to pay",
+			"log_time": "2018-07-01T02:00:00.000Z", "custom_info": map[string]interface{}{"platform": "web"}},
+		{"app_id": "csbot", "session_id": "a", "module": "faq", "std_q": "how to pay",
+			"log_time": "2018-07-01T01:00:00.000Z", "custom_info": map[string]interface{}{"platform": "web"}},
+		{"app_id": "csbot", "session_id": "b", "module": "faq", "std_q": "refund",
+			"log_time": "2018-07-01T03:00:00.000Z", "custom_info": map[string]interface{}{"platform": "app"}},
+		{"app_id": "csbot", "session_id": "c", "module": "chat", "std_q": "",
+			"log_time": "2018-07-01T03:00:00.000Z"},
+	})
+
+	query := dataV1.FunnelQuery{
+		CommonQuery: data.CommonQuery{
+			AppID:     "csbot",
+			StartTime: time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2018, 7, 2, 0, 0, 0, 0, time.UTC),
+		},
+		Breakdown: "platform",
+	}
+	hits, err := FunnelStepHits(query, dataV1.FunnelStep{Type: dataV1.FunnelStepFAQ})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := dataV1.FunnelHits{
+		"a": {Time: time.Date(2018, 7, 1, 1, 0, 0, 0, time.UTC).Unix() * 1000, Tag: "web"},
+		"b": {Time: time.Date(2018, 7, 1, 3, 0, 0, 0, time.UTC).Unix() * 1000, Tag: "app"},
+	}
+	if !reflect.DeepEqual(hits, expected) {
+		t.Errorf("expect hits %+v, but got %+v", expected, hits)
+	}
+
+	hits, err = FunnelStepHits(query, dataV1.FunnelStep{Type: dataV1.FunnelStepFAQ, Value: "refund"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits["b"] == nil {
+		t.Errorf("expect only session b hit refund, but got %+v", hits)
+	}
+}
+
+func TestFunnelStepHitsScenarioNode(t *testing.T) {
+	repo := setupEmbeddedRepository(t, "emotibot-records-2018-07", []map[string]interface{}{
+		{"app_id": "csbot", "session_id": "a", "module": "task_engine",
+			"log_time": "2018-07-01T01:00:00.000Z", "custom_info": map[string]interface{}{"platform": "web"}},
+		{"app_id": "csbot", "session_id": "b", "module": "task_engine",
+			"log_time": "2018-07-01T01:00:00.000Z", "custom_info": map[string]interface{}{"platform": "app"}},
+	})
+	triggerTime := time.Date(2018, 7, 1, 2, 0, 0, 0, time.UTC).Unix()
+	for _, sessionID := range []string{"a", "b", "c"} {
+		doc := map[string]interface{}{"app_id": "csbot", "session_id": sessionID, "scenario_id": "s1",
+			"last_node_id": "n1", "trigger_time": triggerTime}
+		if err := repo.Index("emotibot-te-records-2018-07", "doc", "", doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := dataV1.FunnelQuery{
+		CommonQuery: data.CommonQuery{
+			AppID:     "csbot",
+			StartTime: time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2018, 7, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	step := dataV1.FunnelStep{Type: dataV1.FunnelStepScenarioNode, ScenarioID: "s1", Value: "n1"}
+	hits, err := FunnelStepHits(query, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 3 {
+		t.Errorf("expect all sessions hit without filters, but got %+v", hits)
+	}
+
+	query.Filters = map[string]string{"platform": "web"}
+	query.Breakdown = "platform"
+	hits, err = FunnelStepHits(query, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits["a"] == nil || hits["a"].Tag != "web" {
+		t.Errorf("expect only session a with tag web, but got %+v", hits)
+	}
+}
+
+func TestComputeFunnel(t *testing.T) {
+	steps := []dataV1.FunnelStep{
+		{Type: dataV1.FunnelStepFAQ},
+		{Type: dataV1.FunnelStepScenarioNode, ScenarioID: "s1", Value: "n1"},
+		{Type: dataV1.FunnelStepToHuman},
+	}
+	stepHits := []dataV1.FunnelHits{
+		{
+			"a": {Time: 100, Tag: "web"},
+			"b": {Time: 100, Tag: "app"},
+			"c": {Time: 200, Tag: "web"},
+			"d": {Time: 300, Tag: "web"},
+		},
+		{
+			"a": {Time: 150},
+			"b": {Time: 200},
+			// reached before the first step, should not be converted
+			"c": {Time: 100},
+		},
+		{
+			"b": {Time: 300},
+		},
+	}
+
+	response := ComputeFunnel(steps, stepHits, 10, true)
+	sessions := []int64{}
+	for _, step := range response.Steps {
+		sessions = append(sessions, step.Sessions)
+	}
+	if !reflect.DeepEqual(sessions, []int64{4, 2, 1}) {
+		t.Fatalf("expect sessions to be [4 2 1], but got %v", sessions)
+	}
+
+	first := response.Steps[0]
+	if first.ConversionRate != "1.00" || first.DropOffs != 2 || first.DropOffRate != "0.50" {
+		t.Errorf("unexpected first step: %+v", first)
+	}
+	if !reflect.DeepEqual(first.SampleSessions, []string{"c", "d"}) {
+		t.Errorf("expect drop-off samples to be [c d], but got %v", first.SampleSessions)
+	}
+	last := response.Steps[2]
+	if last.ConversionRate != "0.50" || last.OverallRate != "0.25" || last.DropOffRate != "N/A" {
+		t.Errorf("unexpected last step: %+v", last)
+	}
+
+	if len(response.Breakdowns) != 2 || response.Breakdowns[0].Tag != "app" || response.Breakdowns[1].Tag != "web" {
+		t.Fatalf("unexpected breakdowns: %+v", response.Breakdowns)
+	}
+	web := response.Breakdowns[1].Steps
+	if web[0].Sessions != 3 || web[1].Sessions != 1 || web[2].Sessions != 0 {
+		t.Errorf("unexpected web breakdown: %+v", web)
+	}
+
+	limited := ComputeFunnel(steps, stepHits, 1, false)
+	if len(limited.Breakdowns) != 0 || len(limited.Steps[0].SampleSessions) != 1 {
+		t.Errorf("expect 1 sample without breakdowns, but got %+v", limited)
+	}
+}
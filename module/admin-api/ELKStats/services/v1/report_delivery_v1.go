@@ -0,0 +1,335 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+const (
+	reportSignatureHeader = "X-Emotibot-Signature"
+	reportTimestampHeader = "X-Emotibot-Timestamp"
+)
+
+// reportDeliveryConfig is loaded from server envs, such as REPORT_SMTP_ADDR
+type reportDeliveryConfig struct {
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUser     string
+	SMTPPass     string
+	DropDir      string
+	DownloadHost string
+}
+
+var reportDelivery reportDeliveryConfig
+
+// reportWebhookClient refuses to connect to internal addresses
+var reportWebhookClient = util.NewWebhookClient(time.Duration(10) * time.Second)
+
+func initReportDelivery(envs map[string]string) {
+	reportDelivery = reportDeliveryConfig{
+		SMTPAddr:     envs["REPORT_SMTP_ADDR"],
+		SMTPFrom:     envs["REPORT_SMTP_FROM"],
+		SMTPUser:     envs["REPORT_SMTP_USER"],
+		SMTPPass:     envs["REPORT_SMTP_PASS"],
+		DropDir:      envs["REPORT_DROP_DIR"],
+		DownloadHost: strings.TrimRight(envs["REPORT_DOWNLOAD_HOST"], "/"),
+	}
+}
+
+func reportDownloadURL(reportID int64, historyID string) string {
+	return fmt.Sprintf("%s/api/v1/stats/reports/%d/history/%s/download",
+		reportDelivery.DownloadHost, reportID, historyID)
+}
+
+func validateReportDelivery(delivery *dataV1.ReportDelivery) error {
+	if len(delivery.Targets) == 0 {
+		return fmt.Errorf("delivery targets cannot be empty")
+	}
+
+	for _, target := range delivery.Targets {
+		switch delivery.Type {
+		case dataV1.ReportDeliverySMTP:
+			if reportDelivery.SMTPAddr == "" || reportDelivery.SMTPFrom == "" {
+				return fmt.Errorf("smtp delivery is not configured")
+			}
+			if _, err := mail.ParseAddress(target); err != nil {
+				return fmt.Errorf("invalid email address %s: %v", target, err)
+			}
+		case dataV1.ReportDeliveryWebhook:
+			u, err := url.Parse(target)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid webhook url: %s", target)
+			}
+			if err = util.CheckWebhookHost(u.Hostname()); err != nil {
+				return err
+			}
+		case dataV1.ReportDeliveryFile:
+			if _, err := reportDropPath(target); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid delivery type: %s", delivery.Type)
+		}
+	}
+	return nil
+}
+
+// applyReportDelivery replaces delivery of report, the secret of webhook delivery
+// is kept if it is not given, or generated if the report has no secret yet
+func applyReportDelivery(report *dataV1.Report, delivery dataV1.ReportDelivery) {
+	if delivery.Type != dataV1.ReportDeliveryWebhook {
+		delivery.Secret = ""
+	} else if delivery.Secret == "" {
+		delivery.Secret = report.Delivery.Secret
+		if delivery.Secret == "" {
+			delivery.Secret = util.GenRandomString(32)
+		}
+	}
+	report.Delivery = delivery
+}
+
+// reportDropPath returns the directory of target under the drop directory,
+// target cannot go outside of the drop directory
+func reportDropPath(target string) (string, error) {
+	if reportDelivery.DropDir == "" {
+		return "", fmt.Errorf("file delivery is not configured")
+	}
+	return filepath.Join(reportDelivery.DropDir, filepath.Clean("/"+target)), nil
+}
+
+// deliverReport sends summary and exported file of history to every target of report,
+// errors of targets are joined
+func deliverReport(report *dataV1.Report, summary *dataV1.ReportSummary,
+	history *dataV1.ReportHistory) error {
+	errs := []string{}
+	switch report.Delivery.Type {
+	case dataV1.ReportDeliverySMTP:
+		if err := sendReportMail(report, history, report.Delivery.Targets); err != nil {
+			errs = append(errs, err.Error())
+		}
+	case dataV1.ReportDeliveryWebhook:
+		for _, target := range report.Delivery.Targets {
+			if err := postReportWebhook(target, report.Delivery.Secret, summary, history); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", target, err.Error()))
+			}
+		}
+	case dataV1.ReportDeliveryFile:
+		for _, target := range report.Delivery.Targets {
+			if err := dropReportFiles(target, report, history); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", target, err.Error()))
+			}
+		}
+	default:
+		return fmt.Errorf("invalid delivery type: %s", report.Delivery.Type)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func sendReportMail(report *dataV1.Report, history *dataV1.ReportHistory, to []string) error {
+	subject := fmt.Sprintf("%s %s", report.Name,
+		time.Unix(history.StartTime, 0).Format("2006-01-02"))
+	msg, err := newReportMail(reportDelivery.SMTPFrom, to, subject, history.Summary, history.Path)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if reportDelivery.SMTPUser != "" {
+		host, _, err := net.SplitHostPort(reportDelivery.SMTPAddr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", reportDelivery.SMTPUser, reportDelivery.SMTPPass, host)
+	}
+	return smtp.SendMail(reportDelivery.SMTPAddr, auth, reportDelivery.SMTPFrom, to, msg)
+}
+
+// newReportMail creates a multipart mail with HTML summary as body,
+// and the exported file as attachment if attachmentPath is not empty
+func newReportMail(from string, to []string, subject string, html string,
+	attachmentPath string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = writeBase64Lines(part, []byte(html)); err != nil {
+		return nil, err
+	}
+
+	if attachmentPath != "" {
+		content, err := ioutil.ReadFile(attachmentPath)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(attachmentPath)
+		part, err = writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", reportContentType(name), name)},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeBase64Lines(part, content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines writes content in base64 with lines of 76 characters required by RFC 2045
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+func reportContentType(name string) string {
+	if path.Ext(name) == ".zip" {
+		return "application/zip"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+// reportWebhookPayload is posted to webhook targets as JSON
+type reportWebhookPayload struct {
+	*dataV1.ReportSummary
+	Status string `json:"status"`
+	HTML   string `json:"html"`
+}
+
+func postReportWebhook(target string, secret string, summary *dataV1.ReportSummary,
+	history *dataV1.ReportHistory) error {
+	payload := reportWebhookPayload{
+		ReportSummary: summary,
+		Status:        history.StatusText,
+		HTML:          history.Summary,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := newReportWebhookRequest(target, secret, body, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := reportWebhookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newReportWebhookRequest signs body with secret by HMAC-SHA256 of "<timestamp>.<body>",
+// which is the same as the signature of integration webhooks
+func newReportWebhookRequest(target string, secret string, body []byte, now time.Time) (*http.Request, error) {
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(timestamp))
+	hash.Write([]byte("."))
+	hash.Write(body)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(reportTimestampHeader, timestamp)
+	request.Header.Set(reportSignatureHeader, hex.EncodeToString(hash.Sum(nil)))
+	return request, nil
+}
+
+// dropReportFiles copies the HTML summary and exported file of history into target directory
+func dropReportFiles(target string, report *dataV1.Report, history *dataV1.ReportHistory) error {
+	dirPath, err := reportDropPath(target)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("report_%d_%s", report.ID, history.ID)
+	summaryPath := filepath.Join(dirPath, fmt.Sprintf("%s_%s", prefix, dataV1.ReportSummaryFile))
+	if err = ioutil.WriteFile(summaryPath, []byte(history.Summary), 0644); err != nil {
+		return err
+	}
+	if history.Path == "" {
+		return nil
+	}
+
+	src, err := os.Open(history.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(filepath.Join(dirPath, prefix+path.Ext(history.Path)))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,626 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/dao"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
+	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
+	"emotibot.com/emotigo/pkg/logger"
+	"emotibot.com/emotigo/pkg/services/fileservice"
+	"github.com/robfig/cron"
+)
+
+var reportBaseDir string
+
+// ReportServiceInit creates the directory of report files, loads delivery settings and cleans report histories
+func ReportServiceInit() error {
+	curDir, err := util.GetCurDir()
+	if err != nil {
+		return err
+	}
+
+	reportBaseDir = fmt.Sprintf("%s/%s", curDir, dataV1.ReportDir)
+	initReportDelivery(util.GetEnvOf("server"))
+	go CleanReportHistories()
+	return os.MkdirAll(reportBaseDir, 0755)
+}
+
+// NewReport creates a report of app with request validated by ValidateReportRequest
+func NewReport(enterpriseID string, appID string, locale string,
+	request *dataV1.ReportRequest) (*dataV1.Report, error) {
+	report := &dataV1.Report{
+		EnterpriseID: enterpriseID,
+		AppID:        appID,
+		Locale:       locale,
+		Enabled:      true,
+		CreatedAt:    time.Now().Unix(),
+	}
+	applyReportRequest(report, request)
+
+	var err error
+	report.ID, err = dao.CreateReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func GetReports(appID string) ([]*dataV1.Report, error) {
+	return dao.GetReports(appID)
+}
+
+func GetReport(appID string, reportID int64) (*dataV1.Report, error) {
+	return dao.GetReport(appID, reportID)
+}
+
+// UpdateReport replaces report of app with request validated by ValidateReportRequest
+func UpdateReport(appID string, reportID int64, request *dataV1.ReportRequest) (*dataV1.Report, error) {
+	report, err := dao.GetReport(appID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	applyReportRequest(report, request)
+
+	err = dao.UpdateReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ValidateReportRequest checks request of creating or updating report,
+// the default period is filled if it is empty
+func ValidateReportRequest(request *dataV1.ReportRequest) (err error, errCode int) {
+	errCode = data.ErrCodeInvalidRequestBody
+	if strings.TrimSpace(request.Name) == "" {
+		err = fmt.Errorf("name cannot be empty")
+		return
+	}
+
+	if len(request.Stats) == 0 {
+		err = fmt.Errorf("stats cannot be empty")
+		return
+	}
+	seen := map[string]bool{}
+	for _, stat := range request.Stats {
+		switch stat {
+		case dataV1.ReportStatRecords, dataV1.ReportStatSessions, dataV1.ReportStatTERecords:
+		default:
+			err = fmt.Errorf("invalid stats: %s", stat)
+			return
+		}
+		if seen[stat] {
+			err = fmt.Errorf("duplicate stats: %s", stat)
+			return
+		}
+		seen[stat] = true
+	}
+
+	if request.Period == "" {
+		request.Period = dataV1.ReportPeriodDay
+	}
+	switch request.Period {
+	case dataV1.ReportPeriodDay, dataV1.ReportPeriodWeek, dataV1.ReportPeriodMonth:
+	default:
+		err = fmt.Errorf("invalid period: %s", request.Period)
+		return
+	}
+
+	if _, _err := cron.Parse(request.Schedule); _err != nil {
+		err = fmt.Errorf("invalid schedule: %v", _err)
+		errCode = data.ErrCodeInvalidParameterSchedule
+		return
+	}
+
+	if err = validateReportDelivery(&request.Delivery); err != nil {
+		errCode = data.ErrCodeInvalidParameterDelivery
+		return
+	}
+
+	return nil, 0
+}
+
+// applyReportRequest updates report with request, the next run is rescheduled by the new schedule
+func applyReportRequest(report *dataV1.Report, request *dataV1.ReportRequest) {
+	report.Name = strings.TrimSpace(request.Name)
+	report.Stats = request.Stats
+	report.Filters = request.Filters
+	report.Period = request.Period
+	report.Schedule = request.Schedule
+	applyReportDelivery(report, request.Delivery)
+	if request.Enabled != nil {
+		report.Enabled = *request.Enabled
+	}
+
+	now := time.Now()
+	report.UpdatedAt = now.Unix()
+	report.NextRunAt = 0
+	if schedule, err := cron.Parse(report.Schedule); err == nil && report.Enabled {
+		report.NextRunAt = schedule.Next(now).Unix()
+	}
+}
+
+// ReportWindow returns the time range covered by a run of period at now,
+// which ends at the beginning of the day of now
+func ReportWindow(period string, now time.Time) (startTime time.Time, endTime time.Time) {
+	endTime = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case dataV1.ReportPeriodWeek:
+		startTime = endTime.AddDate(0, 0, -7)
+	case dataV1.ReportPeriodMonth:
+		// the same day of last month, or the last day of last month if it has no such day
+		lastMonth := time.Date(endTime.Year(), endTime.Month()-1, 1, 0, 0, 0, 0, endTime.Location())
+		day := endTime.Day()
+		if lastDay := lastMonth.AddDate(0, 1, -1).Day(); day > lastDay {
+			day = lastDay
+		}
+		startTime = time.Date(lastMonth.Year(), lastMonth.Month(), day, 0, 0, 0, 0, endTime.Location())
+	default:
+		startTime = endTime.AddDate(0, 0, -1)
+	}
+	return
+}
+
+// reportRuns limits the number of scheduled runs in progress
+var reportRuns = make(chan struct{}, dataV1.ReportMaxConcurrentRuns)
+
+// RunDueReports starts all the reports whose next run time has come in background,
+// it is called by cron job periodically
+func RunDueReports() {
+	now := time.Now()
+	reports, err := dao.GetDueReports(now.Unix())
+	if err != nil {
+		logger.Error.Printf("Get due reports failed: %s\n", err.Error())
+		return
+	}
+
+	for _, report := range reports {
+		// Claim the run by moving its next run time, so every run is started
+		// by only one admin-api node even if the run is slow
+		schedule, err := cron.Parse(report.Schedule)
+		if err != nil {
+			logger.Error.Printf("Invalid schedule of report %d: %s\n", report.ID, err.Error())
+			continue
+		}
+		claimed, err := dao.ClaimReportRun(report.ID, report.NextRunAt, schedule.Next(now).Unix())
+		if err != nil {
+			logger.Error.Printf("Schedule report %d failed: %s\n", report.ID, err.Error())
+			continue
+		} else if !claimed {
+			continue
+		}
+
+		go func(report *dataV1.Report) {
+			reportRuns <- struct{}{}
+			defer func() { <-reportRuns }()
+
+			if _, err := RunReport(report, now); err != nil {
+				logger.Error.Printf("Run report %d failed: %s\n", report.ID, err.Error())
+			}
+		}(report)
+	}
+}
+
+// RunReport exports the stats of report to Excel, renders the HTML summary
+// and delivers them to the targets of report, the run is recorded in report history.
+// Error is returned only if the history cannot be recorded,
+// failures of exporting and delivery are recorded in the history.
+func RunReport(report *dataV1.Report, now time.Time) (*dataV1.ReportHistory, error) {
+	history, err := newReportHistory(report, now)
+	if err != nil {
+		return nil, err
+	}
+	return history, runReportHistory(report, history)
+}
+
+// StartReport is RunReport in background, the created history is returned immediately
+func StartReport(report *dataV1.Report, now time.Time) (*dataV1.ReportHistory, error) {
+	history, err := newReportHistory(report, now)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := runReportHistory(report, history); err != nil {
+			logger.Error.Printf("Run report %d failed: %s\n", report.ID, err.Error())
+		}
+	}()
+	return history, nil
+}
+
+func newReportHistory(report *dataV1.Report, now time.Time) (*dataV1.ReportHistory, error) {
+	startTime, endTime := ReportWindow(report.Period, now)
+	history := &dataV1.ReportHistory{
+		ReportID:   report.ID,
+		Status:     data.CodeExportTaskRunning,
+		StatusText: data.ExportTaskCodesMap[data.CodeExportTaskRunning],
+		StartTime:  startTime.Unix(),
+		EndTime:    endTime.Unix() - 1,
+		CreatedAt:  now.Unix(),
+	}
+
+	var err error
+	history.ID, err = dao.CreateReportHistory(history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func runReportHistory(report *dataV1.Report, history *dataV1.ReportHistory) error {
+	logger.Info.Printf("Start report %d, history %s...\n", report.ID, history.ID)
+
+	summary, err := generateReport(report, history)
+	if err != nil {
+		history.Status = data.CodeExportTaskFailed
+		history.ErrReason = reportErrorMessage(err)
+		logger.Error.Printf("Report %d, history %s: generating failed: %s\n",
+			report.ID, history.ID, history.ErrReason)
+	} else {
+		err = deliverReport(report, summary, history)
+		if err != nil {
+			history.ErrReason = reportErrorMessage(err)
+			logger.Error.Printf("Report %d, history %s: delivering failed: %s\n",
+				report.ID, history.ID, history.ErrReason)
+		} else {
+			history.Delivered = true
+		}
+		if err = storeReportFile(report, history); err != nil {
+			history.Status = data.CodeExportTaskFailed
+			history.ErrReason = reportErrorMessage(err)
+			logger.Error.Printf("Report %d, history %s: storing failed: %s\n",
+				report.ID, history.ID, history.ErrReason)
+		}
+	}
+	removeReportDir(report, history)
+
+	history.FinishedAt = time.Now().Unix()
+	history.StatusText = data.ExportTaskCodesMap[history.Status]
+	err = dao.ReportHistoryFinished(history)
+	if err != nil {
+		return err
+	}
+
+	logger.Info.Printf("Report %d, history %s finished: %s\n", report.ID, history.ID, history.StatusText)
+	return nil
+}
+
+func reportDir(report *dataV1.Report, history *dataV1.ReportHistory) string {
+	return fmt.Sprintf("%s/%d/%s", reportBaseDir, report.ID, history.ID)
+}
+
+func removeReportDir(report *dataV1.Report, history *dataV1.ReportHistory) {
+	if err := os.RemoveAll(reportDir(report, history)); err != nil {
+		logger.Error.Printf("Remove files of report %d, history %s failed: %s\n",
+			report.ID, history.ID, err.Error())
+	}
+}
+
+// storeReportFile moves the generated file of history to minio after it is delivered,
+// path of history is changed to the path in minio
+func storeReportFile(report *dataV1.Report, history *dataV1.ReportHistory) error {
+	if history.Path == "" {
+		return nil
+	}
+	file, err := os.Open(history.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	objectPath := fmt.Sprintf("%d/%s/%s", report.ID, history.ID, path.Base(history.Path))
+	if err = fileservice.AddFile(dataV1.ReportMinioNamespace, objectPath, file); err != nil {
+		return err
+	}
+	history.Path = objectPath
+	return nil
+}
+
+// generateReport exports every stats of report into the directory of history,
+// and renders the summary into history
+func generateReport(report *dataV1.Report, history *dataV1.ReportHistory) (*dataV1.ReportSummary, error) {
+	dirPath := reportDir(report, history)
+	err := os.MkdirAll(dirPath, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &dataV1.ReportSummary{
+		ReportID:    report.ID,
+		HistoryID:   history.ID,
+		Name:        report.Name,
+		AppID:       report.AppID,
+		StartTime:   time.Unix(history.StartTime, 0).Format(data.StandardTimeFormat),
+		EndTime:     time.Unix(history.EndTime, 0).Format(data.StandardTimeFormat),
+		Counts:      make([]dataV1.ReportStatCount, 0, len(report.Stats)),
+		DownloadURL: reportDownloadURL(report.ID, history.ID),
+	}
+
+	filePaths := make([]string, 0)
+	for _, stat := range report.Stats {
+		option, err := newReportTaskOption(report, stat, history)
+		if err != nil {
+			return nil, err
+		}
+
+		// Exported files are named by time, move them to the history directory
+		// before exporting the next stats
+		filePath, numOfRecords, err := servicesCommon.ExportXlsx(option, report.Locale)
+		if err != nil {
+			return nil, err
+		}
+		if filePath != "" {
+			reportFilePath := fmt.Sprintf("%s/%s%s", dirPath, stat, path.Ext(filePath))
+			err = os.Rename(filePath, reportFilePath)
+			if err != nil {
+				return nil, err
+			}
+			filePaths = append(filePaths, reportFilePath)
+		}
+
+		summary.Counts = append(summary.Counts, dataV1.ReportStatCount{
+			Stat:  stat,
+			Count: numOfRecords,
+		})
+	}
+
+	switch len(filePaths) {
+	case 0:
+		history.Status = data.CodeExportTaskEmpty
+		summary.DownloadURL = ""
+	case 1:
+		history.Status = data.CodeExportTaskCompleted
+		history.Path = filePaths[0]
+	default:
+		history.Status = data.CodeExportTaskCompleted
+		history.Path = fmt.Sprintf("%s/report.zip", dirPath)
+		err = util.CompressFiles(filePaths, history.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, filePath := range filePaths {
+			os.Remove(filePath)
+		}
+	}
+
+	history.StatusText = data.ExportTaskCodesMap[history.Status]
+	history.Summary, err = RenderReportSummary(summary)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func newReportTaskOption(report *dataV1.Report, stat string,
+	history *dataV1.ReportHistory) (*data.ExportTaskOption, error) {
+	taskID := fmt.Sprintf("report-%d-%s-%s", report.ID, history.ID, stat)
+	filters := report.Filters
+	commonQuery := data.CommonQuery{
+		EnterpriseID: report.EnterpriseID,
+		AppID:        report.AppID,
+		StartTime:    time.Unix(history.StartTime, 0).UTC(),
+		EndTime:      time.Unix(history.EndTime, 0).UTC(),
+	}
+
+	platforms, err := reportTagNames(report.AppID, "platform", filters.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	switch stat {
+	case dataV1.ReportStatRecords:
+		// genders of records are converted by genderDict
+		query := &dataV1.RecordQuery{
+			Keyword:      filters.Keyword,
+			StartTime:    &history.StartTime,
+			EndTime:      &history.EndTime,
+			Emotions:     filters.Emotions,
+			QTypes:       filters.QTypes,
+			Platforms:    platforms,
+			Genders:      filters.Genders,
+			UserID:       filters.UserID,
+			EnterpriseID: report.EnterpriseID,
+			AppID:        report.AppID,
+		}
+		option := createExportRecordsTaskOption(query, taskID)
+		option.AppID = report.AppID
+		return option, nil
+	}
+
+	genders, err := reportTagNames(report.AppID, "sex", filters.Genders)
+	if err != nil {
+		return nil, err
+	}
+
+	switch stat {
+	case dataV1.ReportStatSessions:
+		query := &dataV1.SessionsQuery{
+			CommonQuery: commonQuery,
+			Platforms:   platforms,
+			Sex:         genders,
+			UserID:      filters.UserID,
+			Feedback:    filters.Feedback,
+		}
+		return createExportSessionsTaskOption(query, taskID, report.Locale), nil
+	case dataV1.ReportStatTERecords:
+		query := &dataV1.TEVisitRecordsQuery{
+			CommonQuery:  commonQuery,
+			ScenarioName: filters.ScenarioName,
+			Platforms:    platforms,
+			Genders:      genders,
+			UserID:       filters.UserID,
+			Feedback:     filters.Feedback,
+		}
+		return createExportTERecordsTaskOption(query, taskID, report.Locale), nil
+	}
+
+	return nil, fmt.Errorf("invalid stats: %s", stat)
+}
+
+// reportTagNames converts tag codes of filters to tag names stored in records
+func reportTagNames(appID string, tagType string, codes []string) ([]string, error) {
+	if codes == nil {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(codes))
+	for _, code := range codes {
+		name, found := services.GetTagNameByID(appID, tagType, code)
+		if !found {
+			return nil, fmt.Errorf("%s tag not found: %s", tagType, code)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func reportErrorMessage(err error) string {
+	if rootCauseErrors, ok := elasticsearch.ExtractElasticsearchRootCauseErrors(err); ok {
+		return strings.Join(rootCauseErrors, "; ")
+	}
+	return err.Error()
+}
+
+var reportSummaryTemplate = template.Must(template.New("summary").Parse(`<html>
+<body>
+<h2>{{.Name}}</h2>
+<p>{{.StartTime}} ~ {{.EndTime}}</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Stats</th><th>Count</th></tr>
+{{range .Counts}}<tr><td>{{.Stat}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{if .DownloadURL}}<p><a href="{{.DownloadURL}}">Download</a></p>{{end}}
+</body>
+</html>
+`))
+
+// RenderReportSummary renders summary of a run as HTML
+func RenderReportSummary(summary *dataV1.ReportSummary) (string, error) {
+	var buf bytes.Buffer
+	err := reportSummaryTemplate.Execute(&buf, summary)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// GetReportHistories returns the latest histories of report with download links
+func GetReportHistories(appID string, reportID int64) ([]*dataV1.ReportHistory, error) {
+	if _, err := dao.GetReport(appID, reportID); err != nil {
+		return nil, err
+	}
+
+	histories, err := dao.GetReportHistories(reportID, dataV1.ReportMaxHistorySize)
+	if err != nil {
+		return nil, err
+	}
+	for _, history := range histories {
+		if history.Path != "" {
+			history.DownloadURL = reportDownloadURL(reportID, history.ID)
+		}
+	}
+	return histories, nil
+}
+
+func getReportHistory(appID string, reportID int64, historyID string) (*dataV1.ReportHistory, error) {
+	if _, err := dao.GetReport(appID, reportID); err != nil {
+		return nil, err
+	}
+	return dao.GetReportHistory(reportID, historyID)
+}
+
+// ReportHistoryDownload writes the exported file of a history to w
+func ReportHistoryDownload(w http.ResponseWriter, appID string, reportID int64, historyID string) error {
+	history, err := getReportHistory(appID, reportID, historyID)
+	if err != nil {
+		return err
+	}
+
+	switch history.Status {
+	case data.CodeExportTaskRunning:
+		return data.ErrExportTaskInProcess
+	case data.CodeExportTaskFailed:
+		return data.ErrExportTaskFailed
+	case data.CodeExportTaskEmpty:
+		return data.ErrExportTaskEmpty
+	}
+
+	content, err := fileservice.GetFile(dataV1.ReportMinioNamespace, history.Path)
+	if err != nil {
+		return err
+	}
+	return servicesCommon.WriteExportContent(w, history.Path, content)
+}
+
+// ReportHistorySummary returns the HTML summary of a history
+func ReportHistorySummary(appID string, reportID int64, historyID string) (string, error) {
+	history, err := getReportHistory(appID, reportID, historyID)
+	if err != nil {
+		return "", err
+	}
+	return history.Summary, nil
+}
+
+// DeleteReport deletes report with its histories and generated files
+func DeleteReport(appID string, reportID int64) error {
+	if _, err := dao.GetReport(appID, reportID); err != nil {
+		return err
+	}
+	paths, err := dao.GetReportHistoryPaths(reportID)
+	if err != nil {
+		return err
+	}
+	err = dao.DeleteReport(appID, reportID)
+	if err != nil {
+		return err
+	}
+
+	removeReportFiles(paths)
+	err = os.RemoveAll(filepath.Join(reportBaseDir, fmt.Sprintf("%d", reportID)))
+	if err != nil {
+		logger.Error.Printf("Remove files of report %d failed: %s\n", reportID, err.Error())
+	}
+	return nil
+}
+
+func removeReportFiles(paths []string) {
+	for _, p := range paths {
+		if err := fileservice.DeleteFile(dataV1.ReportMinioNamespace, p); err != nil {
+			logger.Error.Printf("Remove report file %s failed: %s\n", p, err.Error())
+		}
+	}
+}
+
+// CleanReportHistories fails the histories interrupted before finished, and removes histories
+// older than ReportHistoryRetentionDays with their files. It is called by cron job on every node,
+// and is also called at start, so histories of a restarted node do not keep running forever.
+func CleanReportHistories() {
+	now := time.Now()
+	count, err := dao.FailStaleReportHistories(now.Unix()-dataV1.ReportRunTimeout,
+		"report run is interrupted", now.Unix())
+	if err != nil {
+		logger.Error.Printf("Fail interrupted report histories failed: %s\n", err.Error())
+	} else if count > 0 {
+		logger.Info.Printf("%d interrupted report histories are failed\n", count)
+	}
+
+	paths, err := dao.RemoveOutdatedReportHistories(now.AddDate(0, 0, -dataV1.ReportHistoryRetentionDays).Unix())
+	if err != nil {
+		logger.Error.Printf("Remove outdated report histories failed: %s\n", err.Error())
+		return
+	}
+	removeReportFiles(paths)
+}
@@ -0,0 +1,231 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+)
+
+func TestReportWindow(t *testing.T) {
+	now := time.Date(2018, 3, 31, 8, 0, 0, 0, time.UTC)
+	testTable := map[string]time.Time{
+		dataV1.ReportPeriodDay:   time.Date(2018, 3, 30, 0, 0, 0, 0, time.UTC),
+		dataV1.ReportPeriodWeek:  time.Date(2018, 3, 24, 0, 0, 0, 0, time.UTC),
+		dataV1.ReportPeriodMonth: time.Date(2018, 2, 28, 0, 0, 0, 0, time.UTC),
+	}
+	for period, expected := range testTable {
+		startTime, endTime := ReportWindow(period, now)
+		if !startTime.Equal(expected) || !endTime.Equal(time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: expect window from %s to the beginning of today, but got %s ~ %s",
+				period, expected, startTime, endTime)
+		}
+	}
+}
+
+func TestValidateReportRequest(t *testing.T) {
+	initReportDelivery(map[string]string{
+		"REPORT_SMTP_ADDR": "127.0.0.1:1025",
+		"REPORT_SMTP_FROM": "stats@example.com",
+		"REPORT_DROP_DIR":  "/tmp/drop",
+	})
+	defer initReportDelivery(map[string]string{})
+
+	newRequest := func() *dataV1.ReportRequest {
+		return &dataV1.ReportRequest{
+			Name:     "daily",
+			Stats:    []string{dataV1.ReportStatRecords, dataV1.ReportStatSessions},
+			Schedule: "0 0 8 * * *",
+			Delivery: dataV1.ReportDelivery{
+				Type:    dataV1.ReportDeliverySMTP,
+				Targets: []string{"manager@example.com"},
+			},
+		}
+	}
+
+	request := newRequest()
+	if err, _ := ValidateReportRequest(request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Period != dataV1.ReportPeriodDay {
+		t.Errorf("expect default period to be day, but got %s", request.Period)
+	}
+
+	testTable := map[string]struct {
+		modify  func(*dataV1.ReportRequest)
+		errCode int
+	}{
+		"stats": {func(r *dataV1.ReportRequest) { r.Stats = []string{"unknown"} },
+			data.ErrCodeInvalidRequestBody},
+		"duplicate": {func(r *dataV1.ReportRequest) { r.Stats = []string{"records", "records"} },
+			data.ErrCodeInvalidRequestBody},
+		"period": {func(r *dataV1.ReportRequest) { r.Period = "year" },
+			data.ErrCodeInvalidRequestBody},
+		"schedule": {func(r *dataV1.ReportRequest) { r.Schedule = "every day" },
+			data.ErrCodeInvalidParameterSchedule},
+		"email": {func(r *dataV1.ReportRequest) { r.Delivery.Targets = []string{"manager"} },
+			data.ErrCodeInvalidParameterDelivery},
+		"webhook": {func(r *dataV1.ReportRequest) {
+			r.Delivery = dataV1.ReportDelivery{Type: dataV1.ReportDeliveryWebhook, Targets: []string{"ftp://host"}}
+		}, data.ErrCodeInvalidParameterDelivery},
+		"webhook loopback": {func(r *dataV1.ReportRequest) {
+			r.Delivery = dataV1.ReportDelivery{Type: dataV1.ReportDeliveryWebhook, Targets: []string{"http://127.0.0.1:8080/hook"}}
+		}, data.ErrCodeInvalidParameterDelivery},
+		"webhook private": {func(r *dataV1.ReportRequest) {
+			r.Delivery = dataV1.ReportDelivery{Type: dataV1.ReportDeliveryWebhook, Targets: []string{"https://10.0.0.1/hook"}}
+		}, data.ErrCodeInvalidParameterDelivery},
+		"webhook metadata": {func(r *dataV1.ReportRequest) {
+			r.Delivery = dataV1.ReportDelivery{Type: dataV1.ReportDeliveryWebhook, Targets: []string{"http://169.254.169.254/latest"}}
+		}, data.ErrCodeInvalidParameterDelivery},
+	}
+	for name, tc := range testTable {
+		request := newRequest()
+		tc.modify(request)
+		err, errCode := ValidateReportRequest(request)
+		if err == nil || errCode != tc.errCode {
+			t.Errorf("%s: expect error code %d, but got %d, %v", name, tc.errCode, errCode, err)
+		}
+	}
+}
+
+func TestApplyReportDelivery(t *testing.T) {
+	report := &dataV1.Report{}
+	webhook := dataV1.ReportDelivery{Type: dataV1.ReportDeliveryWebhook, Targets: []string{"https://example.com/hook"}}
+
+	applyReportDelivery(report, webhook)
+	secret := report.Delivery.Secret
+	if len(secret) != 32 {
+		t.Fatalf("expect secret generated, but got %q", secret)
+	}
+
+	// secret is kept when the delivery is updated without secret
+	applyReportDelivery(report, webhook)
+	if report.Delivery.Secret != secret {
+		t.Errorf("expect secret %s kept, but got %s", secret, report.Delivery.Secret)
+	}
+
+	applyReportDelivery(report, dataV1.ReportDelivery{Type: dataV1.ReportDeliverySMTP, Targets: []string{"a@example.com"}})
+	if report.Delivery.Secret != "" {
+		t.Errorf("expect no secret of smtp delivery, but got %s", report.Delivery.Secret)
+	}
+}
+
+func TestNewReportWebhookRequest(t *testing.T) {
+	body := []byte(`{"report_id":3}`)
+	request, err := newReportWebhookRequest("https://example.com/hook", "secret", body, time.Unix(1530000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if timestamp := request.Header.Get(reportTimestampHeader); timestamp != "1530000000" {
+		t.Errorf("unexpected timestamp: %s", timestamp)
+	}
+	hash := hmac.New(sha256.New, []byte("secret"))
+	hash.Write([]byte("1530000000." + string(body)))
+	if signature := request.Header.Get(reportSignatureHeader); signature != hex.EncodeToString(hash.Sum(nil)) {
+		t.Errorf("unexpected signature: %s", signature)
+	}
+}
+
+func TestRenderReportSummary(t *testing.T) {
+	summary, err := RenderReportSummary(&dataV1.ReportSummary{
+		Name:        "<daily>",
+		StartTime:   "2018-07-01 00:00:00",
+		EndTime:     "2018-07-01 23:59:59",
+		Counts:      []dataV1.ReportStatCount{{Stat: "records", Count: 12}},
+		DownloadURL: "http://host/download",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"&lt;daily&gt;", "<td>records</td><td>12</td>", `href="http://host/download"`} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("expect summary contains %s, but got %s", expected, summary)
+		}
+	}
+}
+
+func TestNewReportMail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	attachment := filepath.Join(dir, "records.xlsx")
+	if err = ioutil.WriteFile(attachment, bytes.Repeat([]byte("x"), 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := newReportMail("stats@example.com", []string{"a@example.com", "b@example.com"},
+		"日报", "<p>summary</p>", attachment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "日报" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	filenames := []string{}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		filenames = append(filenames, part.FileName())
+	}
+	if len(filenames) != 2 || filenames[0] != "" || filenames[1] != "records.xlsx" {
+		t.Errorf("expect HTML body and records.xlsx attachment, but got %v", filenames)
+	}
+}
+
+func TestDropReportFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	initReportDelivery(map[string]string{"REPORT_DROP_DIR": filepath.Join(dir, "drop")})
+	defer initReportDelivery(map[string]string{})
+
+	exported := filepath.Join(dir, "report.zip")
+	if err = ioutil.WriteFile(exported, []byte("zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := &dataV1.Report{ID: 3}
+	history := &dataV1.ReportHistory{ID: "7", Path: exported, Summary: "<p>summary</p>"}
+	// target cannot escape from the drop directory
+	if err = dropReportFiles("../../managers", report, history); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"report_3_7_summary.html", "report_3_7.zip"} {
+		if _, err := os.Stat(filepath.Join(dir, "drop", "managers", name)); err != nil {
+			t.Errorf("expect %s dropped, but got %v", name, err)
+		}
+	}
+}
@@ -11,15 +11,14 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	elastic "gopkg.in/olivere/elastic.v6"
 	"github.com/tealeg/xlsx"
 )
 
 func SessionsQuery(query *dataV1.SessionsQuery) (sessions []*dataV1.SessionsData, totalSize int64, err error) {
-	ctx, client := elasticsearch.GetClient()
 	boolQuery := newSessionBoolQuery(query)
 
 	source := elastic.NewFetchSourceContext(true)
@@ -37,15 +36,15 @@ func SessionsQuery(query *dataV1.SessionsQuery) (sessions []*dataV1.SessionsData
 
 	index := fmt.Sprintf("%s-*", esData.ESSessionsIndex)
 
-	results, err := client.Search().
-		Index(index).
-		Type(esData.ESSessionsType).
-		Query(boolQuery).
-		FetchSourceContext(source).
-		From(int(query.From)).
-		Size(int(query.Limit)).
-		Sort(data.SessionStartTimeFieldName, false).
-		Do(ctx)
+	results, err := repository.Get().Search(&repository.SearchRequest{
+		Index:  index,
+		Type:   esData.ESSessionsType,
+		Query:  boolQuery,
+		Source: source,
+		From:   int(query.From),
+		Size:   int(query.Limit),
+		Sort:   []repository.SortField{{Field: data.SessionStartTimeFieldName, Ascending: false}},
+	})
 	if err != nil {
 		return nil, 0, err
 	}
@@ -10,16 +10,15 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	"github.com/tealeg/xlsx"
 	elastic "gopkg.in/olivere/elastic.v6"
 )
 
 func TEVisitRecordsQuery(query *dataV1.TEVisitRecordsQuery) (teRecords []*dataV1.TEVisitRecordsData, totalSize int64, err error) {
-	ctx, client := elasticsearch.GetClient()
 	boolQuery := newTEBoolQueryWithRecordQuery(query)
 
 	source := elastic.NewFetchSourceContext(true)
@@ -40,15 +39,15 @@ func TEVisitRecordsQuery(query *dataV1.TEVisitRecordsQuery) (teRecords []*dataV1
 
 	index := fmt.Sprintf("%s-*", esData.ESTERecordsIndex)
 
-	results, err := client.Search().
-		Index(index).
-		Type(esData.ESTERecordsType).
-		Query(boolQuery).
-		FetchSourceContext(source).
-		From(int(query.From)).
-		Size(int(query.Limit)).
-		Sort(data.TERecordsTriggerTimeFieldName, false).
-		Do(ctx)
+	results, err := repository.Get().Search(&repository.SearchRequest{
+		Index:  index,
+		Type:   esData.ESTERecordsType,
+		Query:  boolQuery,
+		Source: source,
+		From:   int(query.From),
+		Size:   int(query.Limit),
+		Sort:   []repository.SortField{{Field: data.TERecordsTriggerTimeFieldName, Ascending: false}},
+	})
 	if err != nil {
 		return nil, 0, err
 	}
@@ -1,20 +1,17 @@
 package v1
 
 import (
-	"context"
 	"fmt"
 
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	elastic "gopkg.in/olivere/elastic.v6"
 )
 
 func TriggerCounts(query dataV1.TEVisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "triggers"
 	boolQuery := createTEVisitStatsBoolQuery(query)
 	rangeQuery := services.CreateRangeQueryUnixTime(query.StatsQuery.CommonQuery,
@@ -24,13 +21,13 @@ func TriggerCounts(query dataV1.TEVisitStatsQuery) (map[string]interface{}, erro
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createTEVisitStatsDateHistogramAggregation(query)
-		return doTEVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doTEVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doTEVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doTEVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -42,7 +39,6 @@ func TriggerCounts(query dataV1.TEVisitStatsQuery) (map[string]interface{}, erro
 }
 
 func UnfinishedCounts(query dataV1.TEVisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "unfinished"
 	boolQuery := createTEVisitStatsBoolQuery(query)
 	rangeQuery := services.CreateRangeQueryUnixTime(query.CommonQuery, data.TERecordsTriggerTimeFieldName)
@@ -53,13 +49,13 @@ func UnfinishedCounts(query dataV1.TEVisitStatsQuery) (map[string]interface{}, e
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createTEVisitStatsDateHistogramAggregation(query)
-		return doTEVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doTEVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doTEVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doTEVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -92,10 +88,9 @@ func createTEVisitStatsDateHistogramAggregation(query dataV1.TEVisitStatsQuery)
 	return agg
 }
 
-func doTEVisitStatsDateHistogramAggService(ctx context.Context, client *elastic.Client, query elastic.Query,
-	aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+func doTEVisitStatsDateHistogramAggService(query elastic.Query, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
 	index := fmt.Sprintf("%s-*", esData.ESTERecordsIndex)
-	result, err := services.CreateSearchService(ctx, client, query, index, esData.ESTERecordsType, aggName, agg)
+	result, err := services.CreateSearchService(query, index, esData.ESTERecordsType, aggName, agg)
 	if err != nil {
 		return nil, err
 	}
@@ -104,8 +99,7 @@ func doTEVisitStatsDateHistogramAggService(ctx context.Context, client *elastic.
 	return counts, nil
 }
 
-func doTEVisitStatsTermsAggService(ctx context.Context, client *elastic.Client, query elastic.Query,
-	aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+func doTEVisitStatsTermsAggService(query elastic.Query, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
 	index := fmt.Sprintf("%s-*", esData.ESTERecordsIndex)
-	return services.DoTermsAggService(ctx, client, query, index, esData.ESTERecordsType, aggName, agg)
+	return services.DoTermsAggService(query, index, esData.ESTERecordsType, aggName, agg)
 }
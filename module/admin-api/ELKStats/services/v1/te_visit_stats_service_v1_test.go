@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
+	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+)
+
+func TestTriggerCounts(t *testing.T) {
+	start := time.Date(2018, 7, 1, 0, 0, 0, 0, time.Local)
+	setupEmbeddedRepository(t, "emotibot-te-records-2018-07", []map[string]interface{}{
+		{"app_id": "csbot", "scenario_id": "s1", "trigger_time": start.Add(time.Hour).Unix(), "finish_time": start.Add(2 * time.Hour).Unix()},
+		{"app_id": "csbot", "scenario_id": "s1", "trigger_time": start.Add(3 * time.Hour).Unix()},
+		{"app_id": "csbot", "scenario_id": "s1", "trigger_time": start.Add(25 * time.Hour).Unix()},
+		{"app_id": "csbot", "scenario_id": "s2", "trigger_time": start.Add(25 * time.Hour).Unix()},
+		{"app_id": "csbot", "scenario_id": "s1", "trigger_time": start.Add(72 * time.Hour).Unix()},
+		{"app_id": "other", "scenario_id": "s1", "trigger_time": start.Add(time.Hour).Unix()},
+	})
+
+	query := dataV1.TEVisitStatsQuery{
+		StatsQuery: data.StatsQuery{
+			CommonQuery: data.CommonQuery{
+				AppID:     "csbot",
+				StartTime: start,
+				EndTime:   start.Add(48*time.Hour - time.Second),
+			},
+			AggBy:       data.AggByTime,
+			AggInterval: data.IntervalDay,
+		},
+		ScenarioID: "s1",
+	}
+
+	counts, err := TriggerCounts(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"2018-07-01 00:00:00": int64(2),
+		"2018-07-02 00:00:00": int64(1),
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expect trigger counts %v, but got %v", expected, counts)
+	}
+
+	counts, err = UnfinishedCounts(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected["2018-07-01 00:00:00"] = int64(1)
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expect unfinished counts %v, but got %v", expected, counts)
+	}
+}
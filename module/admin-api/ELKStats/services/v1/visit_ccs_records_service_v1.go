@@ -4,8 +4,8 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	"emotibot.com/emotigo/pkg/logger"
 	"encoding/json"
@@ -20,7 +20,6 @@ type CcsRecordResult struct {
 }
 
 func VisitCcsRecordsQuery(query dataV1.CcsRecordQuery, aggs ...servicesCommon.ElasticSearchCommand) (*CcsRecordResult, error) {
-	ctx, client := elasticsearch.GetClient()
 	index := fmt.Sprintf("%s-*", esData.ESCcsRecordsIndex)
 	logger.Trace.Printf("index: %s\n", index)
 	boolQuery := newBoolQueryWithCcsRecordQuery(&query)
@@ -42,19 +41,19 @@ func VisitCcsRecordsQuery(query dataV1.CcsRecordQuery, aggs ...servicesCommon.El
 		dataCommon.VisitTagsMetricDataset,
 	)
 
-	ss := client.Search()
+	req := &repository.SearchRequest{
+		Index:  index,
+		Type:   esData.ESRecordType,
+		Query:  boolQuery,
+		Source: source,
+		From:   int(query.From),
+		Size:   query.Limit,
+		Sort:   []repository.SortField{{Field: data.LogTimeFieldName, Ascending: false}},
+	}
 	for _, agg := range aggs {
-		agg(ss)
-	}
-	result, err := ss.
-		Index(index).
-		Type(esData.ESRecordType).
-		Query(boolQuery).
-		From(int(query.From)).
-		Size(query.Limit).
-		FetchSourceContext(source).
-		Sort(data.LogTimeFieldName, false).
-		Do(ctx)
+		agg(req)
+	}
+	result, err := repository.Get().Search(req)
 	if err != nil {
 		return nil, err
 	}
@@ -12,8 +12,8 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/pkg/logger"
@@ -51,7 +51,6 @@ type RecordResult struct {
 //input query decide which raw data to fetchand & aggs will decided Result's aggs
 //In the return, result.Aggs may contain isMarked or isIgnored key if correspond ElasticSearchCommand have bee given in aggs
 func VisitRecordsQuery(query dataV1.RecordQuery, aggs ...servicesCommon.ElasticSearchCommand) (*RecordResult, error) {
-	ctx, client := elasticsearch.GetClient()
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
 	logger.Trace.Printf("index: %s\n", index)
 	boolQuery := newBoolQueryWithRecordQuery(&query)
@@ -72,19 +71,19 @@ func VisitRecordsQuery(query dataV1.RecordQuery, aggs ...servicesCommon.ElasticS
 		"isIgnored",
 	)
 
-	ss := client.Search()
+	req := &repository.SearchRequest{
+		Index:  index,
+		Type:   esData.ESRecordType,
+		Query:  boolQuery,
+		Source: source,
+		From:   int(query.From),
+		Size:   query.Limit,
+		Sort:   []repository.SortField{{Field: data.LogTimeFieldName, Ascending: false}},
+	}
 	for _, agg := range aggs {
-		agg(ss)
-	}
-	result, err := ss.
-		Index(index).
-		Type(esData.ESRecordType).
-		Query(boolQuery).
-		From(int(query.From)).
-		Size(query.Limit).
-		FetchSourceContext(source).
-		Sort(data.LogTimeFieldName, false).
-		Do(ctx)
+		agg(req)
+	}
+	result, err := repository.Get().Search(req)
 	if err != nil {
 		return nil, err
 	}
@@ -202,21 +201,20 @@ func UpdateRecordIgnore(status bool) servicesCommon.UpdateCommand {
 // UpdateRecords will update the records based on given query
 // It will return error if any problem occur.
 func UpdateRecords(query dataV1.RecordQuery, cmd servicesCommon.UpdateCommand) error {
-	ctx, client := elasticsearch.GetClient()
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
 	bq := newBoolQueryWithRecordQuery(&query)
 	traceQuery(bq)
-	s := client.UpdateByQuery(index)
-	s.Type(esData.ESRecordType)
-	s.Query(bq)
-	s.ProceedOnVersionConflict()
-	s = cmd(s)
+	req := cmd(&repository.UpdateRequest{
+		Index: index,
+		Type:  esData.ESRecordType,
+		Query: bq,
+	})
 
-	resp, err := s.Do(ctx)
+	updated, err := repository.Get().UpdateByQuery(req)
 	if err != nil {
 		return err
 	}
-	logger.Trace.Printf("response: %+v\n", resp)
+	logger.Trace.Printf("updated: %d\n", updated)
 	return nil
 }
 
@@ -2,54 +2,60 @@ package v1
 
 import (
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/http/httptest"
-	"net/url"
 	"testing"
 
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
+	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
 )
 
-func TestUpdateRecords(t *testing.T) {
-	// isIntegrate := flag.Bool("integrate", false, "run integration test")
-	// flag.Parse()
-	// if !*isIntegrate {
-	// 	t.Skip("integration only run with --integrate flag")
-	// }
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// if path := r.URL.String(); path != "emotibot-records-csbot-*" {
-		// 	t.Fatalf("expect request url to be emotibot-records-csbot-* but got %s", path)
-		// }
-		data, _ := ioutil.ReadAll(r.Body)
-		fmt.Println(string(data))
-		// expectQuery := `{"query":{"bool":{"filter":{"terms":{"unique_id":["20180823183657462364352"]}}}},"script":{"params":{"mark":true},"source":"ctx._source.isMarked = params.mark"}}`
-		// if string(data) != expectQuery {
-		// 	t.Fatalf("expect query to be %s but got %s", expectQuery, data)
-		// }
-	}))
-	addr, _ := url.Parse(server.URL)
-	baseAuthUsername := "username"
-	baseAuthPassword := "password"
-	err := elasticsearch.Setup(addr.Hostname(), addr.Port(), baseAuthUsername, baseAuthPassword)
+// setupEmbeddedRepository makes services use an embedded repository with docs in index
+func setupEmbeddedRepository(t *testing.T, index string, docs []map[string]interface{}) *repository.Embedded {
+	repo, err := repository.NewEmbedded("")
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer server.Close()
-	VisitRecordsQuery(dataV1.RecordQuery{
+	for _, doc := range docs {
+		if err = repo.Index(index, "doc", "", doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repository.Set(repo)
+	return repo
+}
+
+func TestUpdateRecords(t *testing.T) {
+	setupEmbeddedRepository(t, "emotibot-records-2018-08", []map[string]interface{}{
+		{"app_id": "csbot", "unique_id": "20180823183657462364352", "session_id": "s1", "user_q": "hi",
+			"module": "chat", "log_time": "2018-08-23T10:36:57.000Z", "answer": []map[string]interface{}{{"value": "hello"}}},
+		{"app_id": "csbot", "unique_id": "20180823183657462364353", "session_id": "s1", "user_q": "bye",
+			"module": "faq", "log_time": "2018-08-23T10:37:57.000Z"},
+	})
+
+	query := dataV1.RecordQuery{
 		AppID:   "csbot",
 		Records: []interface{}{"20180823183657462364352"},
-		Limit:   3})
-	return
-	cmd := UpdateRecordMark(true)
-	err = UpdateRecords(dataV1.RecordQuery{
-		AppID:   "csbot",
-		Records: []interface{}{"20180823183657462364352"}}, cmd)
+		Limit:   3,
+	}
+	err := UpdateRecords(query, UpdateRecordMark(true))
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	query.Records = nil
+	result, err := VisitRecordsQuery(query, servicesCommon.AggregateFilterMarkedRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 2 || result.Aggs["isMarked"] != int64(1) {
+		t.Fatalf("expect 1 of 2 records to be marked, but got %+v", result.Aggs)
+	}
+	if result.Hits[0].UniqueID != "20180823183657462364353" || result.Hits[0].IsMarked || !result.Hits[1].IsMarked {
+		t.Errorf("expect the earlier record to be marked, but got %+v, %+v", result.Hits[0], result.Hits[1])
+	}
+	if result.Hits[1].Answer != "hello" || result.Hits[1].QType != "聊天类" {
+		t.Errorf("unexpected record: %+v", result.Hits[1])
+	}
 }
 
 func TestNewBoolQueryWithRecordQuery(t *testing.T) {
@@ -1,7 +1,6 @@
 package v1
 
 import (
-	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -11,13 +10,11 @@ import (
 	dataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	elastic "gopkg.in/olivere/elastic.v6"
 )
 
 func ConversationCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "conversations"
 	boolQuery := services.CreateBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQuery(query.CommonQuery, data.SessionStartTimeFieldName)
@@ -29,7 +26,7 @@ func ConversationCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, e
 			Interval(query.AggInterval)
 
 		index := fmt.Sprintf("%s-*", esData.ESSessionsIndex)
-		result, err := services.CreateSearchService(ctx, client, boolQuery, index, esData.ESSessionsType, aggName, dateHistogramAgg)
+		result, err := services.CreateSearchService(boolQuery, index, esData.ESSessionsType, aggName, dateHistogramAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -42,7 +39,7 @@ func ConversationCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, e
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
 		index := fmt.Sprintf("%s-*", esData.ESSessionsIndex)
-		result, err := services.CreateSearchService(ctx, client, boolQuery, index, esData.ESSessionsType, aggName, tagTermAgg)
+		result, err := services.CreateSearchService(boolQuery, index, esData.ESSessionsType, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -55,7 +52,6 @@ func ConversationCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, e
 }
 
 func UniqueUserCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "unique_users"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQuery(query.CommonQuery, data.LogTimeFieldName)
@@ -85,7 +81,7 @@ func UniqueUserCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, err
 		return nil, data.ErrInvalidAggType
 	}
 
-	result, err := createVisitStatsSearchService(ctx, client, boolQuery, aggName, _agg)
+	result, err := createVisitStatsSearchService(boolQuery, aggName, _agg)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +114,6 @@ func UniqueUserCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, err
 }
 
 func NewUserCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "new_users"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQuery(query.CommonQuery, data.FirstLogTimeFieldName)
@@ -149,7 +144,7 @@ func NewUserCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error)
 	}
 
 	index := fmt.Sprintf("%s-*", esData.ESUsersIndex)
-	result, err := services.CreateSearchService(ctx, client, boolQuery, index, esData.ESUsersType, aggName, _agg)
+	result, err := services.CreateSearchService(boolQuery, index, esData.ESUsersType, aggName, _agg)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +184,6 @@ func NewUserCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error)
 }
 
 func TotalAskCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "total_asks"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQuery(query.CommonQuery, data.LogTimeFieldName)
@@ -198,13 +192,13 @@ func TotalAskCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createVisitStatsDateHistogramAggregation(query)
-		return doVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -216,7 +210,6 @@ func TotalAskCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error
 }
 
 func NormalResponseCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "normal_responses"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	termsQuery := elastic.NewTermsQuery("module", "faq", "task_engine", "domain_kg")
@@ -227,13 +220,13 @@ func NormalResponseCounts(query dataV1.VisitStatsQuery) (map[string]interface{},
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createVisitStatsDateHistogramAggregation(query)
-		return doVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -245,7 +238,6 @@ func NormalResponseCounts(query dataV1.VisitStatsQuery) (map[string]interface{},
 }
 
 func ChatCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "chats"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("module", "chat")
@@ -256,13 +248,13 @@ func ChatCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createVisitStatsDateHistogramAggregation(query)
-		return doVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -274,7 +266,6 @@ func ChatCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
 }
 
 func OtherCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "others"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	termsQuery := elastic.NewTermsQuery("module", "faq", "task_engine", "domain_kg", "knowledge", "chat", "backfill")
@@ -285,13 +276,13 @@ func OtherCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createVisitStatsDateHistogramAggregation(query)
-		return doVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -303,7 +294,6 @@ func OtherCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
 }
 
 func UnknownQnACounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "unknown_qna"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("module", "backfill")
@@ -314,13 +304,13 @@ func UnknownQnACounts(query dataV1.VisitStatsQuery) (map[string]interface{}, err
 	switch query.AggBy {
 	case data.AggByTime:
 		dateHistogramAgg := createVisitStatsDateHistogramAggregation(query)
-		return doVisitStatsDateHistogramAggService(ctx, client, boolQuery, aggName, dateHistogramAgg)
+		return doVisitStatsDateHistogramAggService(boolQuery, aggName, dateHistogramAgg)
 	case data.AggByTag:
 		tagExistsQuery := common.CreateStatsTagExistsQuery(query.AggTagType)
 		boolQuery = boolQuery.Filter(tagExistsQuery)
 		tagTermAgg := common.CreateStatsTagTermsAggregation(query.AggTagType)
 
-		counts, err := doVisitStatsTermsAggService(ctx, client, boolQuery, aggName, tagTermAgg)
+		counts, err := doVisitStatsTermsAggService(boolQuery, aggName, tagTermAgg)
 		if err != nil {
 			return nil, err
 		}
@@ -364,7 +354,6 @@ func CoversationsPerSessionCounts(conversationCounts map[string]interface{},
 }
 
 func TopQuestions(query dataV1.VisitStatsQuery, topN int) (dataV1.Questions, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "top_questions"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("module", "faq")
@@ -375,7 +364,7 @@ func TopQuestions(query dataV1.VisitStatsQuery, topN int) (dataV1.Questions, err
 	boolQuery = boolQuery.MustNot(stdQEmptyQuery)
 
 	topQTermAgg := elastic.NewTermsAggregation().Field("std_q.keyword").Size(topN).ShardSize(10000).OrderByCount(false)
-	result, err := doVisitStatsTermsAggService(ctx, client, boolQuery, aggName, topQTermAgg)
+	result, err := doVisitStatsTermsAggService(boolQuery, aggName, topQTermAgg)
 	if err != nil {
 		return nil, err
 	}
@@ -394,7 +383,6 @@ func TopQuestions(query dataV1.VisitStatsQuery, topN int) (dataV1.Questions, err
 }
 
 func TopUnmatchQuestions(query dataV1.VisitStatsQuery, topN int) ([]*dataV1.UnmatchQuestion, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "top_unmatch_q"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	termQuery := elastic.NewTermQuery("module", "backfill")
@@ -409,7 +397,7 @@ func TopUnmatchQuestions(query dataV1.VisitStatsQuery, topN int) ([]*dataV1.Unma
 	topUnmatchQTermAgg.SubAggregation(maxLogTimeAggName, maxLogTimeAgg)
 	topUnmatchQTermAgg.SubAggregation(minLogTimeAggName, minLogTimeAgg)
 
-	result, err := createVisitStatsSearchService(ctx, client, boolQuery, aggName, topUnmatchQTermAgg)
+	result, err := createVisitStatsSearchService(boolQuery, aggName, topUnmatchQTermAgg)
 	if err != nil {
 		return nil, err
 	}
@@ -456,7 +444,6 @@ func TopUnmatchQuestions(query dataV1.VisitStatsQuery, topN int) ([]*dataV1.Unma
 }
 
 func AnswerCategoryCounts(query dataV1.VisitStatsQuery) (map[string]interface{}, error) {
-	ctx, client := elasticsearch.GetClient()
 	aggName := "answer_categories"
 	boolQuery := common.CreateStatsBoolQuery(query.CommonQuery)
 	rangeQuery := services.CreateRangeQuery(query.CommonQuery, data.LogTimeFieldName)
@@ -479,7 +466,7 @@ func AnswerCategoryCounts(query dataV1.VisitStatsQuery) (map[string]interface{},
 	filtersAgg = filtersAgg.FilterWithName(chatFilterName, chatTermQuery)
 	filtersAgg = filtersAgg.FilterWithName(otherFilterName, otherBoolQuery)
 
-	result, err := createVisitStatsSearchService(ctx, client, boolQuery, aggName, filtersAgg)
+	result, err := createVisitStatsSearchService(boolQuery, aggName, filtersAgg)
 	if err != nil {
 		return nil, err
 	}
@@ -519,21 +506,18 @@ func createVisitStatsDateHistogramAggregation(query dataV1.VisitStatsQuery) *ela
 	return agg
 }
 
-func createVisitStatsSearchService(ctx context.Context, client *elastic.Client,
-	query elastic.Query, aggName string, agg elastic.Aggregation) (*elastic.SearchResult, error) {
+func createVisitStatsSearchService(query elastic.Query, aggName string, agg elastic.Aggregation) (*elastic.SearchResult, error) {
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
-	return services.CreateSearchService(ctx, client, query, index, esData.ESRecordType, aggName, agg)
+	return services.CreateSearchService(query, index, esData.ESRecordType, aggName, agg)
 }
 
-func doVisitStatsDateHistogramAggService(ctx context.Context, client *elastic.Client, query elastic.Query,
-	aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+func doVisitStatsDateHistogramAggService(query elastic.Query, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
-	return services.DoDateHistogramAggService(ctx, client, query,
+	return services.DoDateHistogramAggService(query,
 		index, esData.ESRecordType, aggName, agg)
 }
 
-func doVisitStatsTermsAggService(ctx context.Context, client *elastic.Client, query elastic.Query,
-	aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
+func doVisitStatsTermsAggService(query elastic.Query, aggName string, agg elastic.Aggregation) (map[string]interface{}, error) {
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
-	return services.DoTermsAggService(ctx, client, query, index, esData.ESRecordType, aggName, agg)
+	return services.DoTermsAggService(query, index, esData.ESRecordType, aggName, agg)
 }
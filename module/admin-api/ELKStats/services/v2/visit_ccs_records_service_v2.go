@@ -4,9 +4,9 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV2 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v2"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	"encoding/json"
 	"fmt"
@@ -16,7 +16,6 @@ import (
 
 func VisitCcsRecordsQuery(query *dataV2.VisitCcsRecordsQuery,
 	aggs ...servicesCommon.ElasticSearchCommand) (queryResult *dataV2.VisitCcsRecordsQueryResult, err error) {
-	ctx, client := elasticsearch.GetClient()
 	boolQuery := NewBoolQueryWithCcsRecordQuery(query)
 
 	source := elastic.NewFetchSourceContext(true)
@@ -31,21 +30,21 @@ func VisitCcsRecordsQuery(query *dataV2.VisitCcsRecordsQuery,
 	)
 
 	index := fmt.Sprintf("%s-*", esData.ESCcsRecordsIndex)
-	ss := client.Search()
+	req := &repository.SearchRequest{
+		Index:  index,
+		Type:   esData.ESRecordType,
+		Query:  boolQuery,
+		Source: source,
+		From:   int(query.From),
+		Size:   int(query.Limit),
+		Sort:   []repository.SortField{{Field: data.LogTimeFieldName, Ascending: false}},
+	}
 
 	for _, agg := range aggs {
-		agg(ss)
+		agg(req)
 	}
 
-	result, err := ss.
-		Index(index).
-		Type(esData.ESRecordType).
-		Query(boolQuery).
-		FetchSourceContext(source).
-		From(int(query.From)).
-		Size(int(query.Limit)).
-		Sort(data.LogTimeFieldName, false).
-		Do(ctx)
+	result, err := repository.Get().Search(req)
 	if err != nil {
 		return nil, err
 	}
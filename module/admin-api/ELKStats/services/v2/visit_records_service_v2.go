@@ -15,9 +15,9 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ELKStats/data"
 	dataCommon "emotibot.com/emotigo/module/admin-api/ELKStats/data/common"
 	dataV2 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v2"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	"emotibot.com/emotigo/module/admin-api/ELKStats/services"
 	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
-	"emotibot.com/emotigo/module/admin-api/util/elasticsearch"
 	esData "emotibot.com/emotigo/module/admin-api/util/elasticsearch/data"
 	"emotibot.com/emotigo/pkg/logger"
 	"github.com/tealeg/xlsx"
@@ -28,7 +28,6 @@ const maxFAQCategoryLevels = 10
 
 func VisitRecordsQuery(query *dataV2.VisitRecordsQuery,
 	aggs ...servicesCommon.ElasticSearchCommand) (queryResult *dataV2.VisitRecordsQueryResult, err error) {
-	ctx, client := elasticsearch.GetClient()
 	boolQuery := newBoolQueryWithRecordQuery(query)
 
 	source := elastic.NewFetchSourceContext(true)
@@ -62,21 +61,21 @@ func VisitRecordsQuery(query *dataV2.VisitRecordsQuery,
 	)
 
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
-	ss := client.Search()
+	req := &repository.SearchRequest{
+		Index:  index,
+		Type:   esData.ESRecordType,
+		Query:  boolQuery,
+		Source: source,
+		From:   int(query.From),
+		Size:   int(query.Limit),
+		Sort:   []repository.SortField{{Field: data.LogTimeFieldName, Ascending: false}},
+	}
 
 	for _, agg := range aggs {
-		agg(ss)
-	}
-
-	result, err := ss.
-		Index(index).
-		Type(esData.ESRecordType).
-		Query(boolQuery).
-		FetchSourceContext(source).
-		From(int(query.From)).
-		Size(int(query.Limit)).
-		Sort(data.LogTimeFieldName, false).
-		Do(ctx)
+		agg(req)
+	}
+
+	result, err := repository.Get().Search(req)
 	if err != nil {
 		return nil, err
 	}
@@ -184,22 +183,21 @@ func UpdateRecordIgnore(status bool) servicesCommon.UpdateCommand {
 // UpdateRecords will update the records based on given query
 // It will return error if any problem occur.
 func UpdateRecords(query *dataV2.VisitRecordsQuery, cmd servicesCommon.UpdateCommand) error {
-	ctx, client := elasticsearch.GetClient()
 	boolQuery := newBoolQueryWithRecordQuery(query)
 
 	index := fmt.Sprintf("%s-*", esData.ESRecordsIndex)
-	s := client.UpdateByQuery(index)
-	s.Type(esData.ESRecordType)
-	s.Query(boolQuery)
-	s.ProceedOnVersionConflict()
-	s = cmd(s)
+	req := cmd(&repository.UpdateRequest{
+		Index: index,
+		Type:  esData.ESRecordType,
+		Query: boolQuery,
+	})
 
-	resp, err := s.Do(ctx)
+	updated, err := repository.Get().UpdateByQuery(req)
 	if err != nil {
 		return err
 	}
 
-	logger.Trace.Printf("Response: %+v\n", resp)
+	logger.Trace.Printf("Updated: %d\n", updated)
 	return nil
 }
 
@@ -0,0 +1,202 @@
+package FAQ
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
+)
+
+const (
+	conflictTypeExact      = "exact"
+	conflictTypeNormalized = "normalized"
+	conflictTypeSimilar    = "similar"
+)
+
+//conflictSimilarityThreshold is the minimum n-gram similarity between two texts to be reported
+var conflictSimilarityThreshold = 0.8
+
+//SimilarQuestionConflict is a similar question whose text is same or near-identical to the text of another standard question
+type SimilarQuestionConflict struct {
+	Content            string  `json:"content"`
+	QuestionID         int     `json:"questionId"`
+	Question           string  `json:"question"`
+	ConflictContent    string  `json:"conflictContent"`
+	ConflictQuestionID int     `json:"conflictQuestionId"`
+	ConflictQuestion   string  `json:"conflictQuestion"`
+	Type               string  `json:"type"`
+	Similarity         float64 `json:"similarity"`
+}
+
+//faqText is a standard question or similar question with its standard question
+type faqText struct {
+	QuestionID int
+	Question   string
+	Content    string
+}
+
+//normalizeQuestion converts text to simplified chinese in lower case, and removes punctuations and spaces
+func normalizeQuestion(content string) string {
+	content = strings.ToLower(zhconverter.T2S(content))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, content)
+}
+
+//questionNgrams returns the set of character bigrams, text with only one character is its own gram
+func questionNgrams(normalized string) map[string]bool {
+	runes := []rune(normalized)
+	grams := map[string]bool{}
+	if len(runes) == 1 {
+		grams[normalized] = true
+	}
+	for idx := 0; idx+1 < len(runes); idx++ {
+		grams[string(runes[idx:idx+2])] = true
+	}
+	return grams
+}
+
+//ngramSimilarity is the dice coefficient of the bigram sets
+func ngramSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for gram := range a {
+		if b[gram] {
+			shared++
+		}
+	}
+	return float64(2*shared) / float64(len(a)+len(b))
+}
+
+//conflictIndex indexes texts of an app for looking up conflicts
+type conflictIndex struct {
+	texts      []*faqText
+	normalized []string
+	grams      []map[string]bool
+	gramIndex  map[string][]int
+}
+
+func newConflictIndex(texts []*faqText) *conflictIndex {
+	index := &conflictIndex{
+		texts:      texts,
+		normalized: make([]string, len(texts)),
+		grams:      make([]map[string]bool, len(texts)),
+		gramIndex:  map[string][]int{},
+	}
+	for idx, text := range texts {
+		index.normalized[idx] = normalizeQuestion(text.Content)
+		index.grams[idx] = questionNgrams(index.normalized[idx])
+		for gram := range index.grams[idx] {
+			index.gramIndex[gram] = append(index.gramIndex[gram], idx)
+		}
+	}
+	return index
+}
+
+//find returns conflicts of text against indexed texts belonging to other standard questions,
+//only indexed texts with index larger than skipBefore are checked
+func (index *conflictIndex) find(text *faqText, skipBefore int) []*SimilarQuestionConflict {
+	normalized := normalizeQuestion(text.Content)
+	grams := questionNgrams(normalized)
+
+	candidates := map[int]bool{}
+	for gram := range grams {
+		for _, idx := range index.gramIndex[gram] {
+			candidates[idx] = true
+		}
+	}
+
+	conflicts := []*SimilarQuestionConflict{}
+	for idx := range candidates {
+		target := index.texts[idx]
+		if idx <= skipBefore || target.QuestionID == text.QuestionID {
+			continue
+		}
+		conflict := &SimilarQuestionConflict{
+			Content:            text.Content,
+			QuestionID:         text.QuestionID,
+			Question:           text.Question,
+			ConflictContent:    target.Content,
+			ConflictQuestionID: target.QuestionID,
+			ConflictQuestion:   target.Question,
+			Similarity:         1,
+		}
+		if text.Content == target.Content {
+			conflict.Type = conflictTypeExact
+		} else if normalized == index.normalized[idx] {
+			conflict.Type = conflictTypeNormalized
+		} else {
+			conflict.Similarity = ngramSimilarity(grams, index.grams[idx])
+			if conflict.Similarity < conflictSimilarityThreshold {
+				continue
+			}
+			conflict.Type = conflictTypeSimilar
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	sortConflicts(conflicts)
+	return conflicts
+}
+
+func sortConflicts(conflicts []*SimilarQuestionConflict) {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Similarity != conflicts[j].Similarity {
+			return conflicts[i].Similarity > conflicts[j].Similarity
+		}
+		if conflicts[i].QuestionID != conflicts[j].QuestionID {
+			return conflicts[i].QuestionID < conflicts[j].QuestionID
+		}
+		if conflicts[i].Content != conflicts[j].Content {
+			return conflicts[i].Content < conflicts[j].Content
+		}
+		return conflicts[i].ConflictContent < conflicts[j].ConflictContent
+	})
+}
+
+//CheckSimilarQuestionConflicts returns conflicts of similar questions of qid with texts of other standard questions
+func CheckSimilarQuestionConflicts(appid string, qid int, sqs []SimilarQuestion) ([]*SimilarQuestionConflict, error) {
+	texts, err := selectQuestionTexts(appid)
+	if err != nil {
+		return nil, err
+	}
+	question := ""
+	for _, text := range texts {
+		if text.QuestionID == qid {
+			question = text.Question
+			break
+		}
+	}
+
+	index := newConflictIndex(texts)
+	conflicts := []*SimilarQuestionConflict{}
+	for _, sq := range sqs {
+		text := &faqText{QuestionID: qid, Question: question, Content: sq.Content}
+		conflicts = append(conflicts, index.find(text, -1)...)
+	}
+	return conflicts, nil
+}
+
+//ListSimilarQuestionConflicts returns all conflicts between standard questions of appid, each pair is reported once
+func ListSimilarQuestionConflicts(appid string) ([]*SimilarQuestionConflict, error) {
+	texts, err := selectQuestionTexts(appid)
+	if err != nil {
+		return nil, err
+	}
+	return findAllConflicts(texts), nil
+}
+
+func findAllConflicts(texts []*faqText) []*SimilarQuestionConflict {
+	index := newConflictIndex(texts)
+	conflicts := []*SimilarQuestionConflict{}
+	for idx, text := range texts {
+		conflicts = append(conflicts, index.find(text, idx)...)
+	}
+	sortConflicts(conflicts)
+	return conflicts
+}
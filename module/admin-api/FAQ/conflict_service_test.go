@@ -0,0 +1,51 @@
+package FAQ
+
+import (
+	"testing"
+)
+
+func TestNormalizeQuestion(t *testing.T) {
+	if ret := normalizeQuestion(" 如何 办理，信用卡？ "); ret != "如何办理信用卡" {
+		t.Errorf("Expect punctuations and spaces removed, but got %s", ret)
+	}
+	if ret := normalizeQuestion("ATM在哪里!"); ret != "atm在哪里" {
+		t.Errorf("Expect lower case text, but got %s", ret)
+	}
+}
+
+func TestFindAllConflicts(t *testing.T) {
+	texts := []*faqText{
+		&faqText{QuestionID: 1, Question: "如何办理信用卡", Content: "如何办理信用卡"},
+		&faqText{QuestionID: 1, Question: "如何办理信用卡", Content: "怎么办信用卡"},
+		&faqText{QuestionID: 1, Question: "如何办理信用卡", Content: "信用卡要怎么申请办理呢"},
+		&faqText{QuestionID: 2, Question: "信用卡额度", Content: "信用卡额度"},
+		&faqText{QuestionID: 2, Question: "信用卡额度", Content: "怎么办信用卡"},
+		&faqText{QuestionID: 2, Question: "信用卡额度", Content: "如何办理 信用卡?"},
+		&faqText{QuestionID: 3, Question: "信用卡要怎么申请办理", Content: "信用卡要怎么申请办理"},
+	}
+
+	conflicts := findAllConflicts(texts)
+	types := map[string]string{}
+	for _, conflict := range conflicts {
+		if conflict.QuestionID == conflict.ConflictQuestionID {
+			t.Errorf("Expect no conflict in same question, but got %+v", conflict)
+		}
+		types[conflict.Content+"|"+conflict.ConflictContent] = conflict.Type
+	}
+	expect := map[string]string{
+		"如何办理信用卡|如何办理 信用卡?":      conflictTypeNormalized,
+		"怎么办信用卡|怎么办信用卡":          conflictTypeExact,
+		"信用卡要怎么申请办理呢|信用卡要怎么申请办理": conflictTypeSimilar,
+	}
+	if len(types) != len(expect) {
+		t.Errorf("Expect conflicts %v, but got %v", expect, types)
+	}
+	for key, conflictType := range expect {
+		if types[key] != conflictType {
+			t.Errorf("Expect %s conflict of %s, but got %s", conflictType, key, types[key])
+		}
+	}
+	if conflicts[0].Similarity != 1 || conflicts[len(conflicts)-1].Type != conflictTypeSimilar {
+		t.Errorf("Expect conflicts sorted by similarity, but got %+v", conflicts)
+	}
+}
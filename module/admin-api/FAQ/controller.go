@@ -28,6 +28,7 @@ func init() {
 			util.NewEntryPoint("GET", "question/{qid}/similar-questions", []string{"edit"}, handleQuerySimilarQuestions),
 			util.NewEntryPoint("POST", "question/{qid}/similar-questions", []string{"edit"}, handleUpdateSimilarQuestions),
 			util.NewEntryPoint("DELETE", "question/{qid}/similar-questions", []string{"edit"}, handleDeleteSimilarQuestions),
+			util.NewEntryPoint("GET", "similar-questions/conflicts", []string{"view"}, handleListSimilarQuestionConflicts),
 			util.NewEntryPoint("GET", "questions/search", []string{"view"}, handleSearchQuestion),
 			util.NewEntryPoint("GET", "questions/filter", []string{"view"}, handleQuestionFilter),
 
@@ -45,6 +46,7 @@ func init() {
 			util.NewEntryPoint("POST", "label", []string{"view"}, handleAddLabel),
 			util.NewEntryPoint("DELETE", "label/{id}", []string{"view"}, handleDeleteLabel),
 			util.NewEntryPoint("PUT", "question/{qid}/answer/{aid}/label", []string{"edit"}, handleUpdateQuestionLabel),
+			util.NewEntryPoint("PUT", "answer/{aid}/media", []string{"edit"}, handleSyncAnswerMedia),
 
 			util.NewEntryPoint("GET", "rules", []string{"view"}, handleGetRules),
 			util.NewEntryPoint("GET", "rule/{id}", []string{"edit"}, handleGetRule),
@@ -65,6 +67,15 @@ func init() {
 			util.NewEntryPointWithVer("GET", "tag-types", []string{"view"}, handleGetTagTypesV2, 2),
 			util.NewEntryPointWithVer("GET", "tag-type/{id}", []string{"view"}, handleGetTagTypeV2, 2),
 		},
+		OneTimeFunc: map[string]func(){
+			"BackfillAnswerMedia": backfillAnswerMedia,
+		},
+		Cronjobs: map[string]util.CronTask{
+			"faq_answer_media": util.CronTask{
+				Period:  "@hourly",
+				Handler: backfillAnswerMedia,
+			},
+		},
 	}
 }
 
@@ -143,7 +154,7 @@ func handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
 	}
 	enterpriseID := requestheader.GetEnterpriseID(r)
 	audit.AddAuditLog(enterpriseID, appid, userID, userIP, audit.AuditModuleFAQ, audit.AuditOperationEdit, auditMessage, auditRet)
-	util.ConsulUpdateFAQ(appid)
+	util.ConsulUpdateFAQ(appid, util.NewConsulChange(r, "delete category"))
 }
 
 func handleUpdateCategories(w http.ResponseWriter, r *http.Request) {
@@ -285,6 +296,26 @@ contentMatching:
 	enterpriseID := requestheader.GetEnterpriseID(r)
 	audit.AddAuditLog(enterpriseID, appid, userID, userIP, audit.AuditModuleFAQ, operation, auditMessage, proccessStatus)
 
+	// conflicts are only warnings, similar questions are saved anyway
+	conflicts, err := CheckSimilarQuestionConflicts(appid, qid, sqs)
+	if err != nil {
+		logger.Error.Printf("Check similar question conflicts of %d failed: %s\n", qid, err.Error())
+		conflicts = []*SimilarQuestionConflict{}
+	}
+	util.WriteJSON(w, map[string]interface{}{
+		"conflicts": conflicts,
+	})
+}
+
+func handleListSimilarQuestionConflicts(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	conflicts, err := ListSimilarQuestionConflicts(appid)
+	if err != nil {
+		logger.Error.Printf("List similar question conflicts failed: %s\n", err.Error())
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	util.WriteJSON(w, conflicts)
 }
 
 func handleDeleteSimilarQuestions(w http.ResponseWriter, r *http.Request) {
@@ -327,7 +358,7 @@ func handleSetRFQuestions(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "", http.StatusBadRequest)
 		return
 	}
-	if err = SetRFQuestions(args.Contents, appid); err != nil {
+	if err = SetRFQuestions(args.Contents, appid, util.NewConsulChange(r, "set RF questions")); err != nil {
 		http.Error(w, "", http.StatusInternalServerError)
 		logger.Error.Println(err)
 		return
@@ -498,6 +529,25 @@ func handleGetTagTypeV2(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleSyncAnswerMedia records images used by an answer in media library. Answers are edited by
+// multicustomer service, which calls it after an answer is created, updated or deleted.
+// Content is read from DB, so only references of answers of the app are changed.
+func handleSyncAnswerMedia(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	answerID, err := util.GetMuxIntVar(r, "aid")
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "invalid aid"), http.StatusBadRequest)
+		return
+	}
+
+	if err = SyncAnswerMediaOf(appid, answerID); err != nil {
+		logger.Error.Printf("Sync media references of answer %d fail: %s\n", answerID, err.Error())
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, nil))
+}
+
 func handleUpdateQuestionLabel(w http.ResponseWriter, r *http.Request) {
 	status := http.StatusOK
 	errno := ApiError.SUCCESS
@@ -347,7 +347,7 @@ func GetRFQuestions(appid string) ([]RFQuestion, error) {
 
 // SetRFQuestions will reset RFQuestion table and save given content as RFQuestion.
 // It will try to Update consul as well, if failed, table will be rolled back.
-func SetRFQuestions(contents []string, appid string) error {
+func SetRFQuestions(contents []string, appid string, change *util.ConsulChange) error {
 
 	db := util.GetMainDB()
 	if db == nil {
@@ -382,7 +382,7 @@ func SetRFQuestions(contents []string, appid string) error {
 
 	unixTime := time.Now().UnixNano() / 1000000
 	consulKey := fmt.Sprintf("%sdata/RFQuestion", appid)
-	_, err = util.ConsulUpdateVal(consulKey, unixTime)
+	_, err = util.ConsulUpdateValWithChange(consulKey, unixTime, change)
 	if err != nil {
 		return fmt.Errorf("consul update failed, %v", err)
 	}
@@ -1199,3 +1199,99 @@ func updateAnswerLabel(appid string, answerID int, labelIDs []int) (err error) {
 	err = t.Commit()
 	return
 }
+
+//selectQuestionTexts returns all enabled standard questions and their similar questions of appid
+func selectQuestionTexts(appid string) ([]*faqText, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, fmt.Errorf("Main DB has not init")
+	}
+	texts := []*faqText{}
+
+	rawQuery := fmt.Sprintf("SELECT Question_Id, Content FROM %s_question WHERE Status >= 0", appid)
+	rows, err := db.Query(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query %s error: %s", rawQuery, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		text := &faqText{}
+		if err = rows.Scan(&text.QuestionID, &text.Content); err != nil {
+			return nil, err
+		}
+		text.Question = text.Content
+		texts = append(texts, text)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rawQuery = fmt.Sprintf(`SELECT s.Question_Id, q.Content, s.Content
+		FROM %s_squestion AS s INNER JOIN %s_question AS q ON s.Question_Id = q.Question_Id
+		WHERE q.Status >= 0`, appid, appid)
+	sqRows, err := db.Query(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query %s error: %s", rawQuery, err)
+	}
+	defer sqRows.Close()
+	for sqRows.Next() {
+		text := &faqText{}
+		if err = sqRows.Scan(&text.QuestionID, &text.Question, &text.Content); err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	if err = sqRows.Err(); err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+//selectAnswerContents returns contents of answers of enabled standard questions of appid, keyed by answer id
+func selectAnswerContents(appid string) (map[string][]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, fmt.Errorf("Main DB has not init")
+	}
+
+	rawQuery := fmt.Sprintf(`SELECT a.Answer_Id, a.Content, a.Content_String
+		FROM %s_answer AS a INNER JOIN %s_question AS q ON a.Question_Id = q.Question_Id
+		WHERE q.Status >= 0`, appid, appid)
+	rows, err := db.Query(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("SQL query %s error: %s", rawQuery, err)
+	}
+	defer rows.Close()
+
+	contents := map[string][]string{}
+	for rows.Next() {
+		var answerID int
+		var content, contentString sql.NullString
+		if err = rows.Scan(&answerID, &content, &contentString); err != nil {
+			return nil, err
+		}
+		contents[strconv.Itoa(answerID)] = []string{content.String, contentString.String}
+	}
+	return contents, rows.Err()
+}
+
+// selectAnswerContent returns content of an answer, nil is returned if the answer is deleted
+// or its question is disabled
+func selectAnswerContent(appid string, answerID int) ([]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, fmt.Errorf("Main DB has not init")
+	}
+
+	rawQuery := fmt.Sprintf(`SELECT a.Content, a.Content_String
+		FROM %s_answer AS a INNER JOIN %s_question AS q ON a.Question_Id = q.Question_Id
+		WHERE a.Answer_Id = ? AND q.Status >= 0`, appid, appid)
+	var content, contentString sql.NullString
+	err := db.QueryRow(rawQuery, answerID).Scan(&content, &contentString)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("SQL query %s error: %s", rawQuery, err)
+	}
+	return []string{content.String, contentString.String}, nil
+}
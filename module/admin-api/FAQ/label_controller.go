@@ -34,7 +34,7 @@ func handleDeleteLabel(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if status == http.StatusOK {
 			util.WriteJSON(w, util.GenRetObj(retCode, retObj))
-			util.ConsulUpdateRule(appid)
+			util.ConsulUpdateRule(appid, util.NewConsulChange(r, "delete label"))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(retCode, retObj), status)
 		}
@@ -67,7 +67,7 @@ func handleUpdateLabel(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if status == http.StatusOK {
 			util.WriteJSON(w, util.GenRetObj(retCode, retObj))
-			util.ConsulUpdateRule(appid)
+			util.ConsulUpdateRule(appid, util.NewConsulChange(r, "update label"))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(retCode, retObj), status)
 		}
@@ -102,7 +102,7 @@ func handleAddLabel(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if status == http.StatusOK {
 			util.WriteJSON(w, util.GenRetObj(retCode, retObj))
-			util.ConsulUpdateRule(appid)
+			util.ConsulUpdateRule(appid, util.NewConsulChange(r, "add label"))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(retCode, retObj), status)
 		}
@@ -199,7 +199,7 @@ func handleUpdateRule(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if status == http.StatusOK {
 			util.WriteJSON(w, util.GenRetObj(retCode, retObj))
-			util.ConsulUpdateRule(appid)
+			util.ConsulUpdateRule(appid, util.NewConsulChange(r, "update rule"))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(retCode, retObj), status)
 		}
@@ -244,7 +244,7 @@ func handleAddRule(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if status == http.StatusOK {
 			util.WriteJSON(w, util.GenRetObj(retCode, retObj))
-			util.ConsulUpdateRule(appid)
+			util.ConsulUpdateRule(appid, util.NewConsulChange(r, "add rule"))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(retCode, retObj), status)
 		}
@@ -276,7 +276,7 @@ func handleDeleteRule(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if status == http.StatusOK {
 			util.WriteJSON(w, util.GenRetObj(retCode, retObj))
-			util.ConsulUpdateRule(appid)
+			util.ConsulUpdateRule(appid, util.NewConsulChange(r, "delete rule"))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(retCode, retObj), status)
 		}
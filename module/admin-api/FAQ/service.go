@@ -10,7 +10,10 @@ import (
 	// "strings"
 
 	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/auth"
+	"emotibot.com/emotigo/module/admin-api/media"
 	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/pkg/logger"
 )
 
 func AddAPICategory(appid string, name string, parentID int, level int) (*APICategory, error) {
@@ -112,6 +115,10 @@ func DeleteAPICategory(appid string, category *APICategory) error {
 		return err
 	}
 
+	// answers of disabled questions do not use images anymore
+	if syncErr := SyncAnswerMedia(appid); syncErr != nil {
+		logger.Error.Printf("Sync media references of FAQ answers fail: %s\n", syncErr.Error())
+	}
 	return err
 }
 
@@ -295,3 +302,61 @@ func UpdateQALabel(appid string, questionID, answerID int, labelIDs []int) (errn
 	}
 	return ApiError.SUCCESS, nil
 }
+
+// SyncAnswerMedia records images used by answers of appid in media library, it should be called after
+// answers are changed in bulk, such as importing. Answers of disabled questions lose their references.
+func SyncAnswerMedia(appid string) error {
+	contents, err := selectAnswerContents(appid)
+	if err != nil {
+		return err
+	}
+	if adminErr := media.ReplaceReferences(appid, media.RefTypeFAQAnswer, contents); adminErr != nil {
+		return adminErr
+	}
+	return nil
+}
+
+// SyncAnswerMediaOf records images used by an answer in media library, it should be called after
+// the answer is created, updated or deleted. References of deleted answer are removed.
+func SyncAnswerMediaOf(appid string, answerID int) error {
+	content, err := selectAnswerContent(appid, answerID)
+	if err != nil {
+		return err
+	}
+	refID := strconv.Itoa(answerID)
+	if content == nil {
+		if adminErr := media.RemoveReferences(appid, media.RefTypeFAQAnswer, refID); adminErr != nil {
+			return adminErr
+		}
+		return nil
+	}
+	if adminErr := media.SyncReferences(appid, media.RefTypeFAQAnswer, refID, content...); adminErr != nil {
+		return adminErr
+	}
+	return nil
+}
+
+// backfillAnswerMedia records images used by answers of all apps. Besides answers saved before
+// media library, it syncs answers imported by multicustomer service, so it runs hourly.
+func backfillAnswerMedia() {
+	appids, err := auth.GetAllApps()
+	if err != nil {
+		logger.Error.Printf("Get apps for FAQ media references fail: %s\n", err.Error())
+		return
+	}
+
+	contents := map[string]map[string][]string{}
+	for _, appid := range appids {
+		appContents, err := selectAnswerContents(appid)
+		if err != nil {
+			// app may have no FAQ tables, its references are kept
+			logger.Warn.Printf("Get FAQ answers of %s for media references fail: %s\n", appid, err.Error())
+			contents[appid] = nil
+			continue
+		}
+		contents[appid] = appContents
+	}
+	if adminErr := media.BackfillReferences(media.RefTypeFAQAnswer, contents); adminErr != nil {
+		logger.Error.Printf("Backfill media references of FAQ answers fail: %s\n", adminErr.Error())
+	}
+}
@@ -101,7 +101,7 @@ func handleMultiChatModify(w http.ResponseWriter, r *http.Request) {
 		util.WriteJSON(w, util.GenSimpleRetObj(errCode))
 		auditMultiChatModify(r, origInfos, validInput, 1)
 	}
-	ret, err := util.ConsulUpdateRobotChat(appid)
+	ret, err := util.ConsulUpdateRobotChat(appid, util.NewConsulChange(r, "update multi chat"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", ret, err.Error())
 	} else {
@@ -236,7 +236,7 @@ func handleAddRobotWordContent(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		httpStatus, ret = ApiError.GetHttpStatus(errno), err.Error()
 	}
-	updateWordsConsul(appid)
+	updateWordsConsul(appid, util.NewConsulChange(r, "add robot word content"))
 }
 func handleUpdateRobotWordContent(w http.ResponseWriter, r *http.Request) {
 	httpStatus := http.StatusOK
@@ -302,7 +302,7 @@ func handleUpdateRobotWordContent(w http.ResponseWriter, r *http.Request) {
 			Content: content,
 		}
 	}
-	updateWordsConsul(appid)
+	updateWordsConsul(appid, util.NewConsulChange(r, "update robot word content"))
 }
 func handleDeleteRobotWordContent(w http.ResponseWriter, r *http.Request) {
 	httpStatus := http.StatusOK
@@ -356,10 +356,10 @@ func handleDeleteRobotWordContent(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		httpStatus, ret = ApiError.GetHttpStatus(errno), err.Error()
 	}
-	updateWordsConsul(appid)
+	updateWordsConsul(appid, util.NewConsulChange(r, "delete robot word content"))
 }
-func updateWordsConsul(appid string) {
-	consulRet, consulErr := util.ConsulUpdateRobotChat(appid)
+func updateWordsConsul(appid string, change *util.ConsulChange) {
+	consulRet, consulErr := util.ConsulUpdateRobotChat(appid, change)
 	if consulErr != nil {
 		logger.Info.Printf("Update consul result: %d, %s", consulRet, consulErr.Error())
 	} else {
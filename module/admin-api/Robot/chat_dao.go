@@ -306,3 +306,38 @@ func deleteRobotWordContent(appid string, typeID int, contentID int) (err error)
 
 	return
 }
+
+// getAllRobotWordContents returns contents of robot words of all apps, keyed by appid and type
+func getAllRobotWordContents() (ret map[string]map[string][]string, err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+
+	mySQL := util.GetMainDB()
+	if mySQL == nil {
+		err = util.ErrDBNotInit
+		return
+	}
+
+	rows, err := mySQL.Query("SELECT appid, type, content FROM robot_words")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	ret = map[string]map[string][]string{}
+	for rows.Next() {
+		appid, wordType, content := "", 0, ""
+		err = rows.Scan(&appid, &wordType, &content)
+		if err != nil {
+			return
+		}
+		if _, ok := ret[appid]; !ok {
+			ret[appid] = map[string][]string{}
+		}
+		id := fmt.Sprintf("%d", wordType)
+		ret[appid][id] = append(ret[appid][id], content)
+	}
+	err = rows.Err()
+	return
+}
@@ -3,8 +3,11 @@ package Robot
 import (
 	"database/sql"
 	"errors"
+	"strconv"
 
 	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/media"
+	"emotibot.com/emotigo/pkg/logger"
 )
 
 func GetRobotWords(appid string, locale string) ([]*ChatInfoV2, int, error) {
@@ -51,6 +54,7 @@ func UpdateRobotWord(appid string, id int, contents []string) ([]*ChatContentInf
 		}
 		return nil, ApiError.DB_ERROR, err
 	}
+	syncRobotWordMedia(appid, id)
 
 	return ret, ApiError.SUCCESS, nil
 }
@@ -74,6 +78,7 @@ func AddRobotWordContent(appid string, id int, content string) (*ChatContentInfo
 		}
 		return nil, ApiError.DB_ERROR, err
 	}
+	syncRobotWordMedia(appid, id)
 
 	return ret, ApiError.SUCCESS, nil
 }
@@ -108,6 +113,7 @@ func UpdateRobotWordContent(appid string, id int, cid int, content string) (int,
 		}
 		return ApiError.DB_ERROR, err
 	}
+	syncRobotWordMedia(appid, id)
 
 	return ApiError.SUCCESS, nil
 }
@@ -119,6 +125,36 @@ func DeleteRobotWordContent(appid string, id int, cid int) (int, error) {
 			return ApiError.DB_ERROR, err
 		}
 	}
+	syncRobotWordMedia(appid, id)
 
 	return ApiError.SUCCESS, nil
 }
+
+// syncRobotWordMedia records images used by contents of robot word in media library.
+// Failure is only logged, because contents have been saved.
+func syncRobotWordMedia(appid string, id int) {
+	robotWord, err := getRobotWord(appid, id)
+	if err != nil {
+		logger.Error.Printf("Get robot word %d for media references fail: %s\n", id, err.Error())
+		return
+	}
+	contents := []string{}
+	for _, content := range robotWord.Contents {
+		contents = append(contents, content.Content)
+	}
+	if adminErr := media.SyncReferences(appid, media.RefTypeRobotWord, strconv.Itoa(id), contents...); adminErr != nil {
+		logger.Error.Printf("Sync media references of robot word %d fail: %s\n", id, adminErr.Error())
+	}
+}
+
+// backfillRobotWordMedia records images used by robot words saved before media library
+func backfillRobotWordMedia() {
+	contents, err := getAllRobotWordContents()
+	if err != nil {
+		logger.Error.Printf("Get robot words for media references fail: %s\n", err.Error())
+		return
+	}
+	if adminErr := media.BackfillReferences(media.RefTypeRobotWord, contents); adminErr != nil {
+		logger.Error.Printf("Backfill media references of robot words fail: %s\n", adminErr.Error())
+	}
+}
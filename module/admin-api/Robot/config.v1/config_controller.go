@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"emotibot.com/emotigo/module/admin-api/util/audit"
 
@@ -45,13 +46,25 @@ func HandleSetRobotConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var err AdminErrors.AdminError
-	if value == "" {
-		err = SetConfigToDefault(appid, module, configName)
-	} else {
-		err = SetConfig(appid, module, configName, value)
+	schema, err := GetSchema(configName)
+	if err != nil {
+		util.Return(w, err, nil)
+		return
+	}
+	if module != schema.Module {
+		util.ReturnError(w, AdminErrors.ErrnoRequestError, localemsg.Get(locale, "RobotConfigModuleMismatch"))
+		return
+	}
+	if value != "" {
+		if validErr := schema.Validate(value); validErr != nil {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError,
+				fmt.Sprintf(localemsg.Get(locale, "RobotConfigInvalidValue"), configName, validErr.Error()))
+			return
+		}
 	}
 
+	_, err = UpdateConfig(appid, module, configName, value, requestheader.GetUserID(r))
+
 	auditMsg := fmt.Sprintf(localemsg.Get(locale, "AuditRobotConfigChangeTemplate"),
 		configName, value)
 	result := 1
@@ -62,8 +75,63 @@ func HandleSetRobotConfig(w http.ResponseWriter, r *http.Request) {
 
 	util.Return(w, err, err == nil)
 	if module == moduleBFSource {
-		go util.ConsulUpdateBFSetting(appid)
+		go util.ConsulUpdateBFSetting(appid, util.NewConsulChange(r, "set robot config"))
 	} else {
-		go util.ConsulUpdateBFOPSetting()
+		go util.ConsulUpdateBFOPSetting(util.NewConsulChange(r, "set robot config"))
+	}
+}
+
+func HandleGetRobotConfigSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := GetSchemas()
+	util.Return(w, err, schemas)
+}
+
+func HandleGetRobotConfigHistories(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	limit, convErr := strconv.Atoi(r.FormValue("limit"))
+	if convErr != nil || limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	histories, err := GetHistories(appid, limit)
+	util.Return(w, err, histories)
+}
+
+func HandleGetRobotConfigDiff(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	diff, err := GetConfigDiff(appid)
+	util.Return(w, err, diff)
+}
+
+func HandleRollbackRobotConfig(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	locale := requestheader.GetLocale(r)
+	historyID, convErr := strconv.ParseInt(r.FormValue("history"), 10, 64)
+	if convErr != nil {
+		util.ReturnError(w, AdminErrors.ErrnoRequestError, "invalid history")
+		return
+	}
+
+	histories, err := Rollback(appid, historyID, requestheader.GetUserID(r))
+	auditMsg := fmt.Sprintf(localemsg.Get(locale, "AuditRobotConfigRollbackTemplate"), historyID)
+	result := 1
+	if err != nil {
+		result = 0
+	}
+	audit.AddAuditFromRequest(r, audit.AuditModuleRobotConfig, audit.AuditOperationEdit, auditMsg, result)
+
+	util.Return(w, err, histories)
+	updateBF, updateBFOP := false, false
+	for _, h := range histories {
+		if h.Module == moduleBFSource {
+			updateBF = true
+		} else {
+			updateBFOP = true
+		}
+	}
+	if updateBF {
+		go util.ConsulUpdateBFSetting(appid, util.NewConsulChange(r, "rollback robot config"))
+	}
+	if updateBFOP {
+		go util.ConsulUpdateBFOPSetting(util.NewConsulChange(r, "rollback robot config"))
 	}
 }
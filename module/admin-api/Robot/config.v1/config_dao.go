@@ -2,6 +2,8 @@ package config
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"emotibot.com/emotigo/module/admin-api/util"
@@ -9,12 +11,25 @@ import (
 
 const moduleBFSource = "bf-env"
 
+const defaultHistoryLimit = 100
+
 type configDaoInterface interface {
 	GetDefaultConfigs() ([]*Config, error)
 	GetConfigs(appid string) ([]*Config, error)
 	GetConfig(appid, configName string) (*Config, error)
 	SetConfig(appid, module, configName, value string) error
 	SetConfigToDefault(appid, module, configName string) error
+	AddHistory(appid string, history *History) error
+	GetHistories(appid string, limit int) ([]*History, error)
+	GetHistoriesSince(appid string, id int64) ([]*History, error)
+	// RestoreConfigs sets each config to NewValue of history and adds the history in a transaction
+	RestoreConfigs(appid string, histories []*History) error
+	GetDeclaredSchemas() (map[string]Schema, error)
+}
+
+// execer is sql.DB or sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
 type configMySQL struct {
@@ -234,13 +249,17 @@ func (dao configMySQL) SetConfig(appid, module, configName, value string) error
 	if !dao.CheckDB() {
 		return util.ErrDBNotInit
 	}
+	err = execSetConfig(dao.db, appid, module, configName, value)
+	return err
+}
 
+func execSetConfig(db execer, appid, module, configName, value string) (err error) {
 	if module == moduleBFSource {
 		queryStr := `
 			INSERT INTO ent_config_appid_customization
 			(name, app_id, value) VALUES (?, ?, ?)
 			ON DUPLICATE KEY UPDATE value = ?`
-		_, err = dao.db.Exec(queryStr,
+		_, err = db.Exec(queryStr,
 			configName, appid, value, value)
 		if err != nil {
 			return err
@@ -251,7 +270,7 @@ func (dao configMySQL) SetConfig(appid, module, configName, value string) error
 			INSERT INTO bfop_config
 			(appid, code, module, value, update_time) VALUES (?, ?, ?, ?, ?)
 			ON DUPLICATE KEY UPDATE value = ?, update_time = ?`
-		_, err = dao.db.Exec(queryStr,
+		_, err = db.Exec(queryStr,
 			appid, configName, module, value, now.Unix(), value, now.Unix())
 		if err != nil {
 			return err
@@ -300,3 +319,148 @@ func (dao configMySQL) SetConfigToDefault(appid, module, configName string) erro
 
 	return nil
 }
+
+func (dao configMySQL) AddHistory(appid string, history *History) error {
+	var err error
+	defer func() {
+		util.ShowError(err)
+	}()
+	if !dao.CheckDB() {
+		return util.ErrDBNotInit
+	}
+	err = execAddHistory(dao.db, appid, history)
+	return err
+}
+
+func execAddHistory(db execer, appid string, history *History) error {
+	queryStr := `
+		INSERT INTO robot_config_histories
+		(appid, code, module, old_value, new_value, user_id, create_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.Exec(queryStr, appid, history.Code, history.Module,
+		history.OldValue, history.NewValue, history.UserID, history.CreateTime)
+	if err != nil {
+		return err
+	}
+	history.ID, err = result.LastInsertId()
+	return err
+}
+
+func (dao configMySQL) RestoreConfigs(appid string, histories []*History) error {
+	var err error
+	defer func() {
+		util.ShowError(err)
+	}()
+	if !dao.CheckDB() {
+		return util.ErrDBNotInit
+	}
+
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer util.ClearTransition(tx)
+
+	for _, h := range histories {
+		if err = execSetConfig(tx, appid, h.Module, h.Code, h.NewValue); err != nil {
+			return err
+		}
+		if err = execAddHistory(tx, appid, h); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (dao configMySQL) GetDeclaredSchemas() (map[string]Schema, error) {
+	var err error
+	defer func() {
+		util.ShowError(err)
+	}()
+	if !dao.CheckDB() {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := `
+		SELECT code, type, min_value, max_value, enum_values
+		FROM robot_config_schemas`
+	rows, err := dao.db.Query(queryStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := map[string]Schema{}
+	for rows.Next() {
+		schema := Schema{}
+		var min, max sql.NullFloat64
+		var enum sql.NullString
+		err = rows.Scan(&schema.Code, &schema.Type, &min, &max, &enum)
+		if err != nil {
+			return nil, err
+		}
+		if min.Valid {
+			schema.Min = &min.Float64
+		}
+		if max.Valid {
+			schema.Max = &max.Float64
+		}
+		if enum.Valid && enum.String != "" {
+			if err = json.Unmarshal([]byte(enum.String), &schema.Enum); err != nil {
+				return nil, fmt.Errorf("invalid enum of %s, %s", schema.Code, err.Error())
+			}
+		}
+		ret[schema.Code] = schema
+	}
+	err = rows.Err()
+	return ret, err
+}
+
+// GetHistories will get latest histories of appid, order by id desc
+func (dao configMySQL) GetHistories(appid string, limit int) ([]*History, error) {
+	queryStr := `
+		SELECT id, code, module, old_value, new_value, user_id, create_time
+		FROM robot_config_histories
+		WHERE appid = ?
+		ORDER BY id DESC LIMIT ?`
+	return dao.queryHistories(queryStr, appid, limit)
+}
+
+// GetHistoriesSince will get histories of appid which id is not less than id, order by id desc
+func (dao configMySQL) GetHistoriesSince(appid string, id int64) ([]*History, error) {
+	queryStr := `
+		SELECT id, code, module, old_value, new_value, user_id, create_time
+		FROM robot_config_histories
+		WHERE appid = ? AND id >= ?
+		ORDER BY id DESC`
+	return dao.queryHistories(queryStr, appid, id)
+}
+
+func (dao configMySQL) queryHistories(queryStr string, args ...interface{}) ([]*History, error) {
+	var err error
+	defer func() {
+		util.ShowError(err)
+	}()
+	if !dao.CheckDB() {
+		return nil, util.ErrDBNotInit
+	}
+
+	rows, err := dao.db.Query(queryStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []*History{}
+	for rows.Next() {
+		h := History{}
+		err = rows.Scan(&h.ID, &h.Code, &h.Module, &h.OldValue, &h.NewValue, &h.UserID, &h.CreateTime)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &h)
+	}
+	err = rows.Err()
+	return ret, err
+}
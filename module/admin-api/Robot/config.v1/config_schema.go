@@ -0,0 +1,242 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Types of config value
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeEnum   = "enum"
+	TypeJSON   = "json"
+	TypeURL    = "url"
+)
+
+// Schema describe the valid values of a config
+type Schema struct {
+	Code    string   `json:"code"`
+	Module  string   `json:"module"`
+	Type    string   `json:"type"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Enum    []string `json:"enum,omitempty"`
+	Default string   `json:"default"`
+
+	// check validates the content of value after its type is checked,
+	// used by json configs which have a fixed structure
+	check func(value string) error
+}
+
+// knownSchemas are schemas which can not be inferred from default value and are used by admin-api.
+// Ranges and enums of other configs are declared in robot_config_schemas, and configs not declared
+// use the type of their default value
+var knownSchemas = map[string]Schema{
+	"uploadimg_server":            Schema{Type: TypeURL},
+	"score_standard":              Schema{Type: TypeJSON, check: checkScoreStandard},
+	"lq_sq_rate_remark":           Schema{Type: TypeJSON, check: checkRemarks},
+	"lq_distribution_recommended": Schema{Type: TypeJSON, check: checkDistribution},
+	"lq_sq_rate_range":            Schema{Type: TypeJSON, check: checkScoreRanges},
+	"lq_conflict_range":           Schema{Type: TypeJSON, check: checkScoreRanges},
+	"health_report_score_weight":  Schema{Type: TypeJSON, check: checkScoreWeights},
+}
+
+// maxHealthScore is the full score of health check report
+const maxHealthScore = 100
+
+// checkScoreStandard checks score standard of health check, which is labels
+// with the score of each label
+func checkScoreStandard(value string) error {
+	standard := struct {
+		Label []string `json:"label"`
+		Score []int    `json:"score"`
+	}{}
+	if err := json.Unmarshal([]byte(value), &standard); err != nil {
+		return fmt.Errorf("value should be object of label and score")
+	}
+	if len(standard.Label) != len(standard.Score) {
+		return fmt.Errorf("count of label and score should be the same")
+	}
+	for _, score := range standard.Score {
+		if score < 0 || score > maxHealthScore {
+			return fmt.Errorf("score should be between 0 and %d", maxHealthScore)
+		}
+	}
+	return nil
+}
+
+// checkRemarks checks remarks of health check, which is list of string
+func checkRemarks(value string) error {
+	remarks := []string{}
+	if err := json.Unmarshal([]byte(value), &remarks); err != nil {
+		return fmt.Errorf("value should be list of string")
+	}
+	return nil
+}
+
+// checkDistribution checks recommended distribution of lq count of sq
+func checkDistribution(value string) error {
+	distributions := []struct {
+		Label  string  `json:"label"`
+		From   *int    `json:"from"`
+		To     *int    `json:"to"`
+		SqRate float64 `json:"sq_rate"`
+	}{}
+	if err := json.Unmarshal([]byte(value), &distributions); err != nil {
+		return fmt.Errorf("value should be list of distribution")
+	}
+	for _, d := range distributions {
+		if d.Label == "" {
+			return fmt.Errorf("label of distribution should not be empty")
+		}
+		if d.From != nil && d.To != nil && *d.From > *d.To {
+			return fmt.Errorf("from should not be greater than to")
+		}
+		if d.SqRate < 0 || d.SqRate > 1 {
+			return fmt.Errorf("sq_rate should be between 0 and 1")
+		}
+	}
+	return nil
+}
+
+// checkScoreRanges checks list of range with the score given in the range
+func checkScoreRanges(value string) error {
+	ranges := []struct {
+		From  *float64 `json:"from"`
+		To    *float64 `json:"to"`
+		Score *float64 `json:"score"`
+	}{}
+	if err := json.Unmarshal([]byte(value), &ranges); err != nil {
+		return fmt.Errorf("value should be list of score range")
+	}
+	for _, r := range ranges {
+		if r.From == nil || r.To == nil || r.Score == nil {
+			return fmt.Errorf("from, to and score of range are required")
+		}
+		if *r.From > *r.To {
+			return fmt.Errorf("from should not be greater than to")
+		}
+		if *r.Score < 0 || *r.Score > maxHealthScore {
+			return fmt.Errorf("score should be between 0 and %d", maxHealthScore)
+		}
+	}
+	return nil
+}
+
+// checkScoreWeights checks weights of scores in health report, sum of weights should be 1
+func checkScoreWeights(value string) error {
+	weights := map[string]struct {
+		Score  float64  `json:"score"`
+		Weight *float64 `json:"weight"`
+	}{}
+	if err := json.Unmarshal([]byte(value), &weights); err != nil {
+		return fmt.Errorf("value should be object of score weight")
+	}
+	sum := float64(0)
+	for name, w := range weights {
+		if w.Weight == nil || *w.Weight < 0 || *w.Weight > 1 {
+			return fmt.Errorf("weight of %s should be between 0 and 1", name)
+		}
+		sum += *w.Weight
+	}
+	if len(weights) > 0 && math.Abs(sum-1) > 1e-6 {
+		return fmt.Errorf("sum of weights should be 1")
+	}
+	return nil
+}
+
+func inferType(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "true" || value == "false" {
+		return TypeBool
+	}
+	// Integer default is taken as integer config, declare schema with TypeFloat if decimals are allowed
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return TypeInt
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return TypeFloat
+	}
+	if strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[") {
+		if json.Valid([]byte(value)) {
+			return TypeJSON
+		}
+	}
+	return TypeString
+}
+
+// newSchema returns schema of config, declared is the schemas declared in db
+func newSchema(defaultConfig *Config, declared map[string]Schema) *Schema {
+	schema := Schema{}
+	if known, ok := knownSchemas[defaultConfig.Code]; ok {
+		schema = known
+	} else if d, ok := declared[defaultConfig.Code]; ok {
+		schema = d
+	}
+	schema.Code = defaultConfig.Code
+	schema.Module = defaultConfig.Module
+	schema.Default = defaultConfig.Value
+	if schema.Type == "" {
+		schema.Type = inferType(defaultConfig.Value)
+	}
+	return &schema
+}
+
+// Validate check the value is valid for schema
+func (schema *Schema) Validate(value string) error {
+	var number float64
+	var err error
+	switch schema.Type {
+	case TypeInt:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		number = float64(i)
+	case TypeFloat:
+		number, err = strconv.ParseFloat(value, 64)
+	case TypeBool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("value should be true or false")
+		}
+		return nil
+	case TypeEnum:
+		for _, e := range schema.Enum {
+			if e == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("value should be one of %s", strings.Join(schema.Enum, ", "))
+	case TypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("value should be valid json")
+		}
+		if schema.check != nil {
+			return schema.check(value)
+		}
+		return nil
+	case TypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("value should be valid url")
+		}
+		return nil
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("value should be %s", schema.Type)
+	}
+	if schema.Min != nil && number < *schema.Min {
+		return fmt.Errorf("value should not be less than %v", *schema.Min)
+	}
+	if schema.Max != nil && number > *schema.Max {
+		return fmt.Errorf("value should not be greater than %v", *schema.Max)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	min, max := float64(0), float64(100)
+	testCases := []struct {
+		schema Schema
+		value  string
+		valid  bool
+	}{
+		{Schema{Type: TypeInt, Min: &min, Max: &max}, "50", true},
+		{Schema{Type: TypeInt, Min: &min, Max: &max}, "101", false},
+		{Schema{Type: TypeInt}, "1.5", false},
+		{Schema{Type: TypeFloat, Max: &max}, "0.5", true},
+		{Schema{Type: TypeBool}, "true", true},
+		{Schema{Type: TypeBool}, "yes", false},
+		{Schema{Type: TypeEnum, Enum: []string{"a", "b"}}, "b", true},
+		{Schema{Type: TypeEnum, Enum: []string{"a", "b"}}, "c", false},
+		{Schema{Type: TypeJSON}, `{"a":1}`, true},
+		{Schema{Type: TypeJSON}, `{"a":`, false},
+		{Schema{Type: TypeURL}, "http://127.0.0.1:8080/img", true},
+		{Schema{Type: TypeURL}, "127.0.0.1", false},
+		{Schema{Type: TypeString}, "anything", true},
+		{*newSchema(&Config{Code: "test", Value: "10"}, nil), "1.5", false},
+		{*newSchema(&Config{Code: "test", Value: "0.5"}, nil), "1.5", true},
+		{*newSchema(&Config{Code: "test", Value: "a"}, map[string]Schema{"test": Schema{Type: TypeEnum, Enum: []string{"a", "b"}}}), "c", false},
+		{knownSchemas["lq_sq_rate_range"], `[{"from":0,"to":1,"score":60}]`, true},
+		{knownSchemas["lq_sq_rate_range"], `[{"from":2,"to":1,"score":60}]`, false},
+		{knownSchemas["lq_conflict_range"], `[{"from":0,"to":1,"score":120}]`, false},
+		{knownSchemas["score_standard"], `{"label":["good","bad"],"score":[80,0]}`, true},
+		{knownSchemas["score_standard"], `{"label":["good"],"score":[80,0]}`, false},
+		{knownSchemas["lq_sq_rate_remark"], `["a","b"]`, true},
+		{knownSchemas["lq_sq_rate_remark"], `{"a":1}`, false},
+		{knownSchemas["lq_distribution_recommended"], `[{"label":"1-5","from":1,"to":5,"sq_rate":0.2}]`, true},
+		{knownSchemas["lq_distribution_recommended"], `[{"label":"1-5","from":1,"to":5,"sq_rate":2}]`, false},
+		{knownSchemas["health_report_score_weight"], `{"a":{"score":0,"weight":0.4},"b":{"score":0,"weight":0.6}}`, true},
+		{knownSchemas["health_report_score_weight"], `{"a":{"score":0,"weight":0.4}}`, false},
+	}
+	for _, tc := range testCases {
+		err := tc.schema.Validate(tc.value)
+		if (err == nil) != tc.valid {
+			t.Errorf("Expect %s valid for %s to be %v, but got %v", tc.value, tc.schema.Type, tc.valid, err)
+		}
+	}
+}
+
+func TestNewSchema(t *testing.T) {
+	testCases := map[string]string{
+		"10":      TypeInt,
+		"0.8":     TypeFloat,
+		"false":   TypeBool,
+		"[1,2]":   TypeJSON,
+		"[abc":    TypeString,
+		"content": TypeString,
+	}
+	for value, expect := range testCases {
+		schema := newSchema(&Config{Code: "test", Module: "test", Value: value}, nil)
+		if schema.Type != expect || schema.Default != value {
+			t.Errorf("Expect type of default %s to be %s, but got %s", value, expect, schema.Type)
+		}
+	}
+	schema := newSchema(&Config{Code: "uploadimg_server", Value: "abc"}, nil)
+	if schema.Type != TypeURL {
+		t.Errorf("Expect known schema used, but got %s", schema.Type)
+	}
+	max := float64(10)
+	declared := map[string]Schema{"test": Schema{Type: TypeInt, Max: &max}}
+	schema = newSchema(&Config{Code: "test", Module: "test", Value: "5"}, declared)
+	if schema.Type != TypeInt || schema.Max == nil || *schema.Max != max || schema.Default != "5" || schema.Module != "test" {
+		t.Errorf("Expect declared schema used, but got %+v", schema)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	defaults := []*Config{
+		&Config{Code: "a", Value: "1"},
+		&Config{Code: "b", Value: "2"},
+	}
+	configs := []*Config{
+		&Config{Code: "b", Value: "3"},
+		&Config{Code: "a", Value: "1"},
+		&Config{Code: "c", Value: "4"},
+	}
+	diff := diffConfigs(configs, defaults)
+	if len(diff) != 1 || diff[0].Code != "b" || diff[0].DefaultValue != "2" {
+		t.Errorf("Expect only b is different from default, but got %+v", diff)
+	}
+}
+
+func TestRollbackValues(t *testing.T) {
+	histories := []*History{
+		&History{ID: 4, Code: "a", OldValue: "3", NewValue: "4"},
+		&History{ID: 3, Code: "b", OldValue: "x", NewValue: "y"},
+		&History{ID: 2, Code: "a", OldValue: "2", NewValue: "3"},
+	}
+	values := rollbackValues(histories)
+	if values["a"].OldValue != "2" || values["b"].OldValue != "x" {
+		t.Errorf("Expect values before earliest change, but got a=%s, b=%s", values["a"].OldValue, values["b"].OldValue)
+	}
+}
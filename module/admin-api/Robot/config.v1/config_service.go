@@ -2,6 +2,8 @@ package config
 
 import (
 	"database/sql"
+	"sort"
+	"time"
 
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
 )
@@ -63,3 +65,194 @@ func SetConfigToDefault(appid, module, configName string) AdminErrors.AdminError
 	}
 	return nil
 }
+
+// GetSchemas will get schemas of all configs, based on system default
+func GetSchemas() ([]*Schema, AdminErrors.AdminError) {
+	defaults, err := GetDefaultConfigs()
+	if err != nil {
+		return nil, err
+	}
+	declared, err := getDeclaredSchemas()
+	if err != nil {
+		return nil, err
+	}
+	ret := []*Schema{}
+	for _, c := range defaults {
+		ret = append(ret, newSchema(c, declared))
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Code < ret[j].Code
+	})
+	return ret, nil
+}
+
+// GetSchema will get schema of specific config
+func GetSchema(configName string) (*Schema, AdminErrors.AdminError) {
+	defaults, err := GetDefaultConfigs()
+	if err != nil {
+		return nil, err
+	}
+	declared, err := getDeclaredSchemas()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range defaults {
+		if c.Code == configName {
+			return newSchema(c, declared), nil
+		}
+	}
+	return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "config not found")
+}
+
+func getDeclaredSchemas() (map[string]Schema, AdminErrors.AdminError) {
+	declared, err := dao.GetDeclaredSchemas()
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return declared, nil
+}
+
+// UpdateConfig set value of specific config and record the change in history.
+// If value is empty, config will be set to system default
+func UpdateConfig(appid, module, configName, value, userID string) (*History, AdminErrors.AdminError) {
+	schema, err := GetSchema(configName)
+	if err != nil {
+		return nil, err
+	}
+	current, err := GetConfig(appid, configName)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == "" {
+		err = SetConfigToDefault(appid, module, configName)
+		value = schema.Default
+	} else {
+		err = SetConfig(appid, module, configName, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return addHistory(appid, module, configName, current.Value, value, userID)
+}
+
+func addHistory(appid, module, configName, oldValue, newValue, userID string) (*History, AdminErrors.AdminError) {
+	history := &History{
+		Code:       configName,
+		Module:     module,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		UserID:     userID,
+		CreateTime: time.Now().Unix(),
+	}
+	err := dao.AddHistory(appid, history)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return history, nil
+}
+
+// GetHistories will get latest changes of configs of appid
+func GetHistories(appid string, limit int) ([]*History, AdminErrors.AdminError) {
+	histories, err := dao.GetHistories(appid, limit)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return histories, nil
+}
+
+// GetConfigDiff will get configs of appid which value is different from system default
+func GetConfigDiff(appid string) ([]*Diff, AdminErrors.AdminError) {
+	defaults, err := GetDefaultConfigs()
+	if err != nil {
+		return nil, err
+	}
+	configs, err := GetConfigs(appid)
+	if err != nil {
+		return nil, err
+	}
+	return diffConfigs(configs, defaults), nil
+}
+
+func diffConfigs(configs, defaults []*Config) []*Diff {
+	defaultValues := map[string]string{}
+	for _, c := range defaults {
+		defaultValues[c.Code] = c.Value
+	}
+	ret := []*Diff{}
+	for _, c := range configs {
+		defaultValue, ok := defaultValues[c.Code]
+		if !ok || defaultValue == c.Value {
+			continue
+		}
+		ret = append(ret, &Diff{
+			Code:         c.Code,
+			Module:       c.Module,
+			Value:        c.Value,
+			DefaultValue: defaultValue,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Code < ret[j].Code
+	})
+	return ret
+}
+
+// rollbackValues returns the value of each config before the change of the last history,
+// histories must be ordered by id desc
+func rollbackValues(histories []*History) map[string]*History {
+	ret := map[string]*History{}
+	for _, h := range histories {
+		ret[h.Code] = h
+	}
+	return ret
+}
+
+// Rollback will restore configs of appid to the state before the change with historyID.
+// Every restored config is recorded in history, so rollback can be rollbacked too.
+// All configs are restored in one transaction, nothing is changed if any of them fails.
+func Rollback(appid string, historyID int64, userID string) ([]*History, AdminErrors.AdminError) {
+	histories, err := dao.GetHistoriesSince(appid, historyID)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	if len(histories) == 0 || histories[len(histories)-1].ID != historyID {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "history not found")
+	}
+
+	values := rollbackValues(histories)
+	codes := []string{}
+	for code := range values {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	now := time.Now().Unix()
+	ret := []*History{}
+	for _, code := range codes {
+		h := values[code]
+		current, adminErr := GetConfig(appid, code)
+		if adminErr != nil {
+			return nil, adminErr
+		}
+		if current.Value == h.OldValue {
+			continue
+		}
+		ret = append(ret, &History{
+			Code:       code,
+			Module:     h.Module,
+			OldValue:   current.Value,
+			NewValue:   h.OldValue,
+			UserID:     userID,
+			CreateTime: now,
+		})
+	}
+	if len(ret) == 0 {
+		return ret, nil
+	}
+	err = dao.RestoreConfigs(appid, ret)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return ret, nil
+}
@@ -8,3 +8,22 @@ type Config struct {
 	UpdateTime int64  `json:"update_time"`
 	Status     bool   `json:"status"`
 }
+
+// History is a change of config value of an app
+type History struct {
+	ID         int64  `json:"id"`
+	Code       string `json:"code"`
+	Module     string `json:"module"`
+	OldValue   string `json:"old_value"`
+	NewValue   string `json:"new_value"`
+	UserID     string `json:"user_id"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// Diff is a config of app which value is different from default
+type Diff struct {
+	Code         string `json:"code"`
+	Module       string `json:"module"`
+	Value        string `json:"value"`
+	DefaultValue string `json:"default_value"`
+}
@@ -85,10 +85,11 @@ func handleUpdateDBFunctionV2(w http.ResponseWriter, r *http.Request) {
 		result = 1
 	}
 	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, result)
-	consulRet, err := util.ConsulUpdateFunctionStatus(appid)
+	consulRet, err := util.ConsulUpdateFunctionStatus(appid, util.NewConsulChange(r, "update function"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", consulRet, err.Error())
 	}
+	go publishFunctionRollout(appid, 2, locale, util.NewConsulChange(r, "update function"))
 }
 
 func handleUpdateAllDBFunctionV2(w http.ResponseWriter, r *http.Request) {
@@ -149,10 +150,11 @@ func handleUpdateAllDBFunctionV2(w http.ResponseWriter, r *http.Request) {
 		result = 1
 	}
 	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, result)
-	ret, err := util.ConsulUpdateFunctionStatus(appid)
+	ret, err := util.ConsulUpdateFunctionStatus(appid, util.NewConsulChange(r, "update all functions"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", ret, err.Error())
 	}
+	go publishFunctionRollout(appid, 2, locale, util.NewConsulChange(r, "update all functions"))
 }
 
 // ==========================================
@@ -227,10 +229,11 @@ func handleUpdateDBFunction(w http.ResponseWriter, r *http.Request) {
 		result = 1
 	}
 	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, result)
-	consulRet, err := util.ConsulUpdateFunctionStatus(appid)
+	consulRet, err := util.ConsulUpdateFunctionStatus(appid, util.NewConsulChange(r, "update function"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", consulRet, err.Error())
 	}
+	go publishFunctionRollout(appid, 1, locale, util.NewConsulChange(r, "update function"))
 }
 
 func handleUpdateAllDBFunction(w http.ResponseWriter, r *http.Request) {
@@ -291,10 +294,11 @@ func handleUpdateAllDBFunction(w http.ResponseWriter, r *http.Request) {
 		result = 1
 	}
 	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, result)
-	ret, err := util.ConsulUpdateFunctionStatus(appid)
+	ret, err := util.ConsulUpdateFunctionStatus(appid, util.NewConsulChange(r, "update all functions"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", ret, err.Error())
 	}
+	go publishFunctionRollout(appid, 1, locale, util.NewConsulChange(r, "update all functions"))
 }
 
 // ==========================================
@@ -365,7 +369,7 @@ func handleUpdateFunction(w http.ResponseWriter, r *http.Request) {
 		util.McUpdateFunction(appid)
 	}
 	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, result)
-	consulRet, err := util.ConsulUpdateFunctionStatus(appid)
+	consulRet, err := util.ConsulUpdateFunctionStatus(appid, util.NewConsulChange(r, "update function"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", consulRet, err.Error())
 	}
@@ -427,7 +431,7 @@ func handleUpdateAllFunction(w http.ResponseWriter, r *http.Request) {
 		util.McUpdateFunction(appid)
 	}
 	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, result)
-	ret, err := util.ConsulUpdateRobotChat(appid)
+	ret, err := util.ConsulUpdateRobotChat(appid, util.NewConsulChange(r, "update all functions"))
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", ret, err.Error())
 	}
@@ -0,0 +1,102 @@
+package Robot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/admin-api/util/rolloutrule"
+	"emotibot.com/emotigo/pkg/logger"
+	"emotibot.com/emotigo/pkg/misc/rollout"
+)
+
+func GetDBFunctionRollout(appid string, function string) (*rollout.Rule, int, error) {
+	rule, err := rolloutrule.GetRule(appid, rolloutrule.TypeFunction, function)
+	if err != nil {
+		return nil, ApiError.DB_ERROR, err
+	}
+	return rule, ApiError.SUCCESS, nil
+}
+
+func UpdateDBFunctionRollout(appid string, function string, rule *rollout.Rule, version int, locale string) (int, error) {
+	_, err := getDBFunction(appid, function, version, locale)
+	if err != nil {
+		return ApiError.REQUEST_ERROR, err
+	}
+	if rule != nil {
+		if err = rule.Validate(); err != nil {
+			return ApiError.REQUEST_ERROR, err
+		}
+	}
+	err = rolloutrule.SetRule(appid, rolloutrule.TypeFunction, function, rule)
+	if err != nil {
+		return ApiError.DB_ERROR, err
+	}
+	return ApiError.SUCCESS, nil
+}
+
+// publishFunctionRollout publish active status and rollout rules of all functions to consul
+func publishFunctionRollout(appid string, version int, locale string, change *util.ConsulChange) {
+	functions, errCode, err := GetDBFunctions(appid, version, locale)
+	if errCode != ApiError.SUCCESS {
+		logger.Error.Printf("Get functions for rollout fail: %s\n", err.Error())
+		return
+	}
+	status := map[string]bool{}
+	for _, f := range functions {
+		status[f.Code] = f.Active
+	}
+	rolloutrule.Publish(appid, rolloutrule.TypeFunction, status, change)
+}
+
+func handleDBFunctionRolloutV2(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	function := util.GetMuxVar(r, "name")
+
+	rule, errCode, err := GetDBFunctionRollout(appid, function)
+	if errCode != ApiError.SUCCESS {
+		util.WriteJSON(w, util.GenRetObj(errCode, err))
+	} else {
+		util.WriteJSON(w, util.GenRetObj(errCode, rule))
+	}
+}
+
+// handleUpdateDBFunctionRolloutV2 set rollout rule of function, body null will remove the rule
+func handleUpdateDBFunctionRolloutV2(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	function := util.GetMuxVar(r, "name")
+	locale := requestheader.GetLocale(r)
+
+	funcName, ok := util.Msg[function]
+	if !ok {
+		funcName = function
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	var rule *rollout.Rule
+	if err := json.Unmarshal(body, &rule); err != nil {
+		logger.Info.Printf("Bad request when loading from input: %s", err.Error())
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	errCode, err := UpdateDBFunctionRollout(appid, function, rule, 2, locale)
+	if errCode != ApiError.SUCCESS {
+		util.WriteJSON(w, util.GenRetObj(errCode, err))
+		auditLog := fmt.Sprintf("%s%s, %s rollout (%s)",
+			util.Msg["Modify"], util.Msg["Error"], funcName, ApiError.GetErrorMsg(errCode))
+		addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, 0)
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(errCode, rule))
+	auditLog := fmt.Sprintf("%s%s, %s rollout: %s",
+		util.Msg["Modify"], util.Msg["Success"], funcName, body)
+	addAudit(r, audit.AuditModuleRobotFunction, audit.AuditOperationEdit, auditLog, 1)
+	go publishFunctionRollout(appid, 2, locale, util.NewConsulChange(r, "update function rollout"))
+}
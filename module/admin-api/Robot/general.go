@@ -35,6 +35,8 @@ func init() {
 			util.NewEntryPointWithVer("GET", "functions", []string{"view"}, handleDBFunctionListV2, 2),
 			util.NewEntryPointWithVer("POST", "functions", []string{"edit"}, handleUpdateAllDBFunctionV2, 2),
 			util.NewEntryPointWithVer("POST", "function/{name}", []string{"edit"}, handleUpdateDBFunctionV2, 2),
+			util.NewEntryPointWithVer("GET", "function/{name}/rollout", []string{"view"}, handleDBFunctionRolloutV2, 2),
+			util.NewEntryPointWithVer("PUT", "function/{name}/rollout", []string{"edit"}, handleUpdateDBFunctionRolloutV2, 2),
 
 			util.NewEntryPoint("GET", "qas", []string{"view"}, handleRobotQAList),
 			util.NewEntryPoint("POST", "qabuild", []string{"edit"}, handleRobotQAModelRebuild),
@@ -78,6 +80,10 @@ func init() {
 			util.NewEntryPoint("GET", "config", []string{"view"}, config.HandleGetRobotConfig),
 			util.NewEntryPoint("GET", "configs", []string{"view"}, config.HandleGetRobotConfigs),
 			util.NewEntryPoint("PUT", "config", []string{"edit"}, config.HandleSetRobotConfig),
+			util.NewEntryPoint("GET", "config/schemas", []string{"view"}, config.HandleGetRobotConfigSchemas),
+			util.NewEntryPoint("GET", "config/histories", []string{"view"}, config.HandleGetRobotConfigHistories),
+			util.NewEntryPoint("GET", "config/diff", []string{"view"}, config.HandleGetRobotConfigDiff),
+			util.NewEntryPoint("POST", "config/rollback", []string{"edit"}, config.HandleRollbackRobotConfig),
 
 
 			util.NewEntryPoint("GET", "ssmconfig/get/{name}/{type}", []string{"view"}, HandleGetSSMConfig),
@@ -85,7 +91,9 @@ func init() {
 			util.NewEntryPoint("GET", "ssmconfig/publish", []string{"edit"}, HandlePublishSSMConfig),
 		},
 		OneTimeFunc: map[string]func(){
-			"SyncRobotProfile": SyncOnce,
+			"SyncRobotProfile":         SyncOnce,
+			"BackfillRobotWordMedia":   backfillRobotWordMedia,
+			"BackfillRobotAnswerMedia": backfillRobotAnswerMedia,
 		},
 	}
 }
@@ -261,9 +269,9 @@ func handleInitRobotData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// after init data, update consul to notify controller to init data
-	go util.ConsulUpdateRobotChat(appid)
-	go util.ConsulUpdateFunctionStatus(appid)
-	go dictionary.TriggerUpdateWordbankV3(appid)
+	go util.ConsulUpdateRobotChat(appid, util.NewConsulChange(r, "init robot data"))
+	go util.ConsulUpdateFunctionStatus(appid, util.NewConsulChange(r, "init robot data"))
+	go dictionary.TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "init robot data"))
 
 	// call multicustomer to handle robot QA
 	go util.McRebuildRobotQA(appid)
@@ -931,3 +931,40 @@ func needProcessRobotData() (ret bool, err error) {
 	err = t.Commit()
 	return
 }
+
+// getAllRobotQAAnswerContents returns valid answers of all apps, keyed by appid and "qid/id"
+func getAllRobotQAAnswerContents() (ret map[string]map[string][]string, err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+
+	mySQL := util.GetMainDB()
+	if mySQL == nil {
+		err = util.ErrDBNotInit
+		return
+	}
+
+	queryStr := `SELECT appid, qid, id, content FROM robot_profile_answer
+		WHERE status >= 0`
+	rows, err := mySQL.Query(queryStr)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	ret = map[string]map[string][]string{}
+	for rows.Next() {
+		appid, qid, id, content := "", 0, 0, ""
+		err = rows.Scan(&appid, &qid, &id, &content)
+		if err != nil {
+			return
+		}
+		if _, ok := ret[appid]; !ok {
+			ret[appid] = map[string][]string{}
+		}
+		refID := fmt.Sprintf("%d/%d", qid, id)
+		ret[appid][refID] = append(ret[appid][refID], content)
+	}
+	err = rows.Err()
+	return
+}
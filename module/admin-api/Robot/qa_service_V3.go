@@ -10,6 +10,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ApiError"
 	qaServices "emotibot.com/emotigo/module/admin-api/QADoc/services"
 	"emotibot.com/emotigo/module/admin-api/Service"
+	"emotibot.com/emotigo/module/admin-api/media"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/pkg/logger"
 )
@@ -50,6 +51,7 @@ func AddRobotQAAnswerV3(appid string, qid int, answer string) (int, int, error)
 	} else if err != nil {
 		return 0, ApiError.DB_ERROR, err
 	}
+	syncRobotAnswerMedia(appid, qid, id, answer)
 	return id, ApiError.SUCCESS, nil
 }
 
@@ -83,6 +85,7 @@ func UpdateRobotQAAnswerV3(appid string, qid int, aid int, answer string) (int,
 	} else if err != nil {
 		return ApiError.DB_ERROR, err
 	}
+	syncRobotAnswerMedia(appid, qid, aid, answer)
 	return ApiError.SUCCESS, nil
 }
 
@@ -93,9 +96,31 @@ func DeleteRobotQAAnswerV3(appid string, qid, aid int) (int, error) {
 	} else if err != nil {
 		return ApiError.DB_ERROR, err
 	}
+	syncRobotAnswerMedia(appid, qid, aid)
 	return ApiError.SUCCESS, nil
 }
 
+// syncRobotAnswerMedia records images used by robot answer in media library,
+// references are removed if answer is not given. Failure is only logged.
+func syncRobotAnswerMedia(appid string, qid, aid int, answer ...string) {
+	refID := fmt.Sprintf("%d/%d", qid, aid)
+	if adminErr := media.SyncReferences(appid, media.RefTypeRobotAnswer, refID, answer...); adminErr != nil {
+		logger.Error.Printf("Sync media references of robot answer %s fail: %s\n", refID, adminErr.Error())
+	}
+}
+
+// backfillRobotAnswerMedia records images used by robot answers saved before media library
+func backfillRobotAnswerMedia() {
+	contents, err := getAllRobotQAAnswerContents()
+	if err != nil {
+		logger.Error.Printf("Get robot answers for media references fail: %s\n", err.Error())
+		return
+	}
+	if adminErr := media.BackfillReferences(media.RefTypeRobotAnswer, contents); adminErr != nil {
+		logger.Error.Printf("Backfill media references of robot answers fail: %s\n", adminErr.Error())
+	}
+}
+
 func AddRobotQARQuestionV3(appid string, qid int, question string) (int, int, error) {
 	id, err := addRobotQARQuestionV3(appid, qid, question)
 	if err == sql.ErrNoRows {
@@ -302,7 +327,7 @@ func ForceSyncRobotProfile(force bool) (err error) {
 			continue
 		}
 		logger.Trace.Printf("Update profile consul of appid [%s]\n", appid)
-		_, consulErr := util.ConsulUpdateProfile(appid)
+		_, consulErr := util.ConsulUpdateProfile(appid, &util.ConsulChange{Reason: "sync robot profile"})
 		if err != nil {
 			logger.Error.Println("Update consul error:", consulErr.Error())
 		}
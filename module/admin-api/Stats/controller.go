@@ -14,6 +14,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/ApiError"
 	statsV2 "emotibot.com/emotigo/module/admin-api/Stats/v2"
 	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/audit"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/pkg/logger"
 	"github.com/gorilla/mux"
@@ -50,6 +51,12 @@ func init() {
 			util.NewEntryPoint("POST", "sessions/{sid}/download", []string{}, handleSessionDowload),
 			util.NewEntryPoint("GET", "sessions/{sid}/records", []string{}, handleSessionRecords),
 		}, statsV2.EntryList...),
+		Cronjobs: map[string]util.CronTask{
+			"audit_archive": util.CronTask{
+				Period:  "@daily",
+				Handler: archiveAuditRecords,
+			},
+		},
 	}
 	cacheTimeout = nil
 	cache = make(map[string]*StatRet)
@@ -78,6 +85,12 @@ func getEnvironments() map[string]string {
 	return util.GetEnvOf(ModuleInfo.ModuleName)
 }
 
+// archiveAuditRecords archives expired audit records into minio,
+// records of enterprise without retention are kept in DB
+func archiveAuditRecords() {
+	audit.ArchiveExpiredRecords()
+}
+
 func getEnvironment(key string) string {
 	envs := util.GetEnvOf(ModuleInfo.ModuleName)
 	if envs != nil {
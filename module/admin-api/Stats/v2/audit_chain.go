@@ -0,0 +1,138 @@
+package v2
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// AuditExportInput is the input of exporting audit records for SIEM
+type AuditExportInput struct {
+	EnterpriseID string `json:"enterprise_id"`
+	Start        int64  `json:"start_time"`
+	End          int64  `json:"end_time"`
+	Format       string `json:"format"`
+}
+
+// AuditRetentionInput is the input of updating retention of audit records
+type AuditRetentionInput struct {
+	EnterpriseID string `json:"enterprise_id"`
+	Days         int    `json:"days"`
+}
+
+// auditEnterprise returns enterprise in query, or enterprise of user if not set
+func auditEnterprise(r *http.Request, enterpriseID string) string {
+	if enterpriseID == "" {
+		enterpriseID = r.URL.Query().Get("enterprise")
+	}
+	if enterpriseID == "" {
+		enterpriseID = requestheader.GetEnterpriseID(r)
+	}
+	return enterpriseID
+}
+
+func handleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	enterpriseID := auditEnterprise(r, "")
+	result, err := audit.VerifyChain(enterpriseID)
+	if err != nil {
+		logger.Error.Printf("Verify audit chain of %s fail: %s\n", enterpriseID, err.Error())
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error()), nil)
+		return
+	}
+	util.Return(w, nil, result)
+}
+
+func handleGetAuditArchives(w http.ResponseWriter, r *http.Request) {
+	enterpriseID := auditEnterprise(r, "")
+	segments, err := audit.GetArchiveSegments(enterpriseID)
+	if err != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error()), nil)
+		return
+	}
+	util.Return(w, nil, segments)
+}
+
+func handleGetAuditRetention(w http.ResponseWriter, r *http.Request) {
+	enterpriseID := auditEnterprise(r, "")
+	retentions, err := audit.GetRetentions()
+	if err != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error()), nil)
+		return
+	}
+	ret := &audit.Retention{EnterpriseID: enterpriseID}
+	for _, retention := range retentions {
+		if retention.EnterpriseID == enterpriseID {
+			ret = retention
+			break
+		}
+	}
+	util.Return(w, nil, ret)
+}
+
+func handleUpdateAuditRetention(w http.ResponseWriter, r *http.Request) {
+	input := AuditRetentionInput{}
+	jsonErr := util.ReadJSON(r, &input)
+	if jsonErr != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, jsonErr.Error()), nil)
+		return
+	}
+	if input.Days < 0 {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "days should not be negative"), nil)
+		return
+	}
+	enterpriseID := auditEnterprise(r, input.EnterpriseID)
+	err := audit.SetRetention(enterpriseID, input.Days)
+	if err != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error()), nil)
+		return
+	}
+	msg := fmt.Sprintf("retention of audit: %d days", input.Days)
+	audit.AddAuditFromRequest(r, audit.AuditModuleAudit, audit.AuditOperationEdit, msg, 1)
+	util.Return(w, nil, &audit.Retention{EnterpriseID: enterpriseID, Days: input.Days})
+}
+
+// handleExportAuditSIEM exports audit records in JSON Lines, CEF or syslog format
+func handleExportAuditSIEM(w http.ResponseWriter, r *http.Request) {
+	input := AuditExportInput{}
+	jsonErr := util.ReadJSON(r, &input)
+	if jsonErr != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, jsonErr.Error()), nil)
+		return
+	}
+	if input.Format == "" {
+		input.Format = audit.ExportFormatJSONL
+	}
+	if !audit.IsValidExportFormat(input.Format) {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "invalid format"), nil)
+		return
+	}
+	if input.End == 0 {
+		input.End = time.Now().Unix()
+	}
+	enterpriseID := auditEnterprise(r, input.EnterpriseID)
+
+	buf := bytes.Buffer{}
+	count, err := audit.ExportRecords(&buf, enterpriseID, input.Start, input.End, input.Format)
+	if err != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error()), nil)
+		return
+	}
+	msg := fmt.Sprintf("export %d audit records in %s", count, input.Format)
+	audit.AddAuditFromRequest(r, audit.AuditModuleAudit, audit.AuditOperationExport, msg, 1)
+
+	ext := "log"
+	if input.Format == audit.ExportFormatJSONL {
+		ext = "jsonl"
+	}
+	filename := fmt.Sprintf("audit_%s_%s.%s", input.Format, time.Now().Format("20060102150405"), ext)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(buf.Bytes())
+}
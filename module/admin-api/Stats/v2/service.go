@@ -31,7 +31,7 @@ func GetRobotAuditRecord(filter *AuditInput, locale string) (*AuditResult, Admin
 
 	ret := AuditResult{
 		Total:  count,
-		Header: robotAuditHeaders[locale],
+		Header: auditHeaders(locale),
 		Logs:   logs,
 	}
 
@@ -61,7 +61,7 @@ func GetEnterpriseAuditRecord(filter *AuditInput, locale string) (*AuditResult,
 
 	ret := AuditResult{
 		Total:  count,
-		Header: robotAuditHeaders[locale],
+		Header: auditHeaders(locale),
 		Logs:   logs,
 	}
 
@@ -90,7 +90,7 @@ func GetSystemAuditRecord(filter *AuditInput, locale string) (*AuditResult, Admi
 
 	ret := AuditResult{
 		Total:  count,
-		Header: robotAuditHeaders[locale],
+		Header: auditHeaders(locale),
 		Logs:   logs,
 	}
 
@@ -1,6 +1,9 @@
 package v2
 
-import "emotibot.com/emotigo/module/admin-api/util"
+import (
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
+)
 
 var (
 	// moduleName used to get correct environment name
@@ -23,6 +26,26 @@ var (
 			Version:	2,
 			IgnoreAppID: true,
 		}),
+		util.NewEntryPointWithConfig("GET", "audit/verify", []string{"view"}, handleVerifyAudit, util.EntryConfig{
+			Version:     2,
+			IgnoreAppID: true,
+		}),
+		util.NewEntryPointWithConfig("GET", "audit/archives", []string{"view"}, handleGetAuditArchives, util.EntryConfig{
+			Version:     2,
+			IgnoreAppID: true,
+		}),
+		util.NewEntryPointWithConfig("GET", "audit/retention", []string{"view"}, handleGetAuditRetention, util.EntryConfig{
+			Version:     2,
+			IgnoreAppID: true,
+		}),
+		util.NewEntryPointWithConfig("PUT", "audit/retention", []string{"edit"}, handleUpdateAuditRetention, util.EntryConfig{
+			Version:     2,
+			IgnoreAppID: true,
+		}),
+		util.NewEntryPointWithConfig("POST", "audit/siem-export", []string{"export"}, handleExportAuditSIEM, util.EntryConfig{
+			Version:     2,
+			IgnoreAppID: true,
+		}),
 
 	}
 )
@@ -88,4 +111,44 @@ var robotAuditHeaders = map[string][]*AuditHeader{
 			ID:   "user_ip",
 		},
 	},
+	"en-us": []*AuditHeader{
+		&AuditHeader{
+			Text: "User ID",
+			ID:   "user",
+		},
+		&AuditHeader{
+			Text: "Module",
+			ID:   "module",
+		},
+		&AuditHeader{
+			Text: "Operation",
+			ID:   "operation",
+		},
+		&AuditHeader{
+			Text: "Content",
+			ID:   "content",
+		},
+		&AuditHeader{
+			Text: "Result",
+			ID:   "result",
+		},
+		&AuditHeader{
+			Text: "Time",
+			ID:   "create_time",
+		},
+		&AuditHeader{
+			Text: "IP Address",
+			ID:   "user_ip",
+		},
+	},
+}
+
+func init() {
+	localemsg.RegisterTable("Stats/v2/robotAuditHeaders", robotAuditHeaders)
+}
+
+// auditHeaders returns headers of audit logs in locale, headers of default locale are used
+// if locale is missing
+func auditHeaders(locale string) []*AuditHeader {
+	return robotAuditHeaders[localemsg.TableLocale(robotAuditHeaders, locale)]
 }
@@ -28,6 +28,8 @@ func init() {
 			util.NewEntryPoint("POST", "switch/{id}", []string{"edit"}, handleUpdateSwitch),
 			util.NewEntryPoint("DELETE", "switch/{id}", []string{"delete"}, handleDeleteSwitch),
 			util.NewEntryPoint("PUT", "switch/create", []string{"create"}, handleNewSwitch),
+			util.NewEntryPoint("GET", "switch/{id}/rollout", []string{"view"}, handleSwitchRollout),
+			util.NewEntryPoint("PUT", "switch/{id}/rollout", []string{"edit"}, handleUpdateSwitchRollout),
 		},
 	}
 }
@@ -97,6 +99,7 @@ func handleNewSwitch(w http.ResponseWriter, r *http.Request) {
 	} else {
 		util.WriteJSON(w, util.GenRetObj(errCode, input))
 		addAudit(r, audit.AuditOperationAdd, fmt.Sprintf("Add success %#v", input), 1)
+		go publishRollout(appid, util.NewConsulChange(r, "new switch"))
 	}
 }
 
@@ -156,11 +159,13 @@ func handleUpdateSwitch(w http.ResponseWriter, r *http.Request) {
 		addAudit(r, audit.AuditOperationEdit, msg, 1)
 	}
 
+	go publishRollout(appid, util.NewConsulChange(r, "update switch"))
+
 	var ret int
 	if orig.Code == "task_engine" {
-		ret, err = util.ConsulUpdateTaskEngine(appid, input.Status == 1)
+		ret, err = util.ConsulUpdateTaskEngine(appid, input.Status == 1, util.NewConsulChange(r, "update switch"))
 	} else {
-		ret, err = util.ConsulUpdateRobotChat(appid)
+		ret, err = util.ConsulUpdateRobotChat(appid, util.NewConsulChange(r, "update switch"))
 	}
 	if err != nil {
 		logger.Info.Printf("Update consul result: %d, %s", ret, err.Error())
@@ -181,6 +186,7 @@ func handleDeleteSwitch(w http.ResponseWriter, r *http.Request) {
 	} else {
 		util.WriteJSON(w, util.GenRetObj(errCode, nil))
 		addAudit(r, audit.AuditOperationDelete, fmt.Sprintf("Delete id %d success", id), 1)
+		go publishRollout(appid, util.NewConsulChange(r, "delete switch"))
 	}
 }
 
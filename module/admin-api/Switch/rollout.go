@@ -0,0 +1,98 @@
+package Switch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/admin-api/util/rolloutrule"
+	"emotibot.com/emotigo/pkg/logger"
+	"emotibot.com/emotigo/pkg/misc/rollout"
+)
+
+func GetSwitchRollout(appid string, id int) (*rollout.Rule, int, error) {
+	info, errCode, err := GetSwitch(appid, id)
+	if errCode != ApiError.SUCCESS {
+		return nil, errCode, err
+	}
+	rule, err := rolloutrule.GetRule(appid, rolloutrule.TypeSwitch, info.Code)
+	if err != nil {
+		return nil, ApiError.DB_ERROR, err
+	}
+	return rule, ApiError.SUCCESS, nil
+}
+
+func UpdateSwitchRollout(appid string, id int, rule *rollout.Rule) (*SwitchInfo, int, error) {
+	info, errCode, err := GetSwitch(appid, id)
+	if errCode != ApiError.SUCCESS {
+		return nil, errCode, err
+	}
+	if rule != nil {
+		if err = rule.Validate(); err != nil {
+			return nil, ApiError.REQUEST_ERROR, err
+		}
+	}
+	err = rolloutrule.SetRule(appid, rolloutrule.TypeSwitch, info.Code, rule)
+	if err != nil {
+		return nil, ApiError.DB_ERROR, err
+	}
+	return info, ApiError.SUCCESS, nil
+}
+
+// publishRollout publish on/off status and rollout rules of all switches to consul
+func publishRollout(appid string, change *util.ConsulChange) {
+	list, errCode, err := GetSwitches(appid)
+	if errCode != ApiError.SUCCESS {
+		logger.Error.Printf("Get switches for rollout fail: %s\n", err.Error())
+		return
+	}
+	status := map[string]bool{}
+	for _, info := range list {
+		status[info.Code] = info.Status == 1
+	}
+	rolloutrule.Publish(appid, rolloutrule.TypeSwitch, status, change)
+}
+
+func handleSwitchRollout(w http.ResponseWriter, r *http.Request) {
+	id, _ := util.GetMuxIntVar(r, "id")
+	appid := requestheader.GetAppID(r)
+
+	rule, errCode, err := GetSwitchRollout(appid, id)
+	if errCode != ApiError.SUCCESS {
+		util.WriteJSON(w, util.GenRetObj(errCode, err))
+	} else {
+		util.WriteJSON(w, util.GenRetObj(errCode, rule))
+	}
+}
+
+// handleUpdateSwitchRollout set rollout rule of switch, body null will remove the rule
+func handleUpdateSwitchRollout(w http.ResponseWriter, r *http.Request) {
+	id, _ := util.GetMuxIntVar(r, "id")
+	appid := requestheader.GetAppID(r)
+
+	body, _ := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	var rule *rollout.Rule
+	if err := json.Unmarshal(body, &rule); err != nil {
+		logger.Info.Printf("Bad request when loading from input: %s", err.Error())
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	info, errCode, err := UpdateSwitchRollout(appid, id, rule)
+	if errCode != ApiError.SUCCESS {
+		util.WriteJSON(w, util.GenRetObj(errCode, err))
+		addAudit(r, audit.AuditOperationEdit, fmt.Sprintf("%s%s rollout id[%d]: %s (%v)",
+			util.Msg["Modify"], util.Msg["Error"], id, ApiError.GetErrorMsg(errCode), err), 0)
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(errCode, rule))
+	addAudit(r, audit.AuditOperationEdit, fmt.Sprintf("%s%s rollout code[%s]: %s",
+		util.Msg["Modify"], util.Msg["Success"], info.Code, body), 1)
+	go publishRollout(appid, util.NewConsulChange(r, "update switch rollout"))
+}
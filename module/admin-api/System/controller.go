@@ -21,6 +21,15 @@ func init() {
 		EntryPoints: []util.EntryPoint{
 			util.NewEntryPoint("GET", "setting", []string{"view"}, handleGetSetting),
 			util.NewEntryPoint("PUT", "setting", []string{"edit"}, handleUpdateSetting),
+			util.NewEntryPoint("GET", "consul/history", []string{"view"}, handleGetConsulHistory),
+			util.NewEntryPoint("GET", "consul/diff", []string{"view"}, handleDiffConsulHistory),
+			util.NewEntryPoint("POST", "consul/restore", []string{"edit"}, handleRestoreConsulHistory),
+		},
+		Cronjobs: map[string]util.CronTask{
+			"consul_history_retention": util.CronTask{
+				Period:  "@daily",
+				Handler: removeExpiredConsulHistory,
+			},
 		},
 	}
 }
@@ -82,7 +91,8 @@ func handleUpdateSetting(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	logger.Trace.Println("Update controller setting with string: ", string(updatedStr))
-	errno, err = util.ConsulSetControllerSetting(string(updatedStr))
+	change := util.NewConsulChange(r, strings.TrimSpace(r.FormValue("reason")))
+	errno, err = util.ConsulSetControllerSetting(string(updatedStr), change)
 	if err != nil {
 		status = http.StatusInternalServerError
 	}
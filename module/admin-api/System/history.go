@@ -0,0 +1,176 @@
+package System
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+const (
+	dftHistoryLimit = 20
+	maxHistoryLimit = 200
+)
+
+// handleGetConsulHistory returns writes of consul keys under prefix, newest first
+func handleGetConsulHistory(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := &util.ConsulHistoryQuery{
+		Prefix: params.Get("prefix"),
+		Owners: historyOwners(r),
+		Limit:  dftHistoryLimit,
+	}
+
+	var err error
+	if query.From, err = parseHistoryTime(params.Get("from"), 0); err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid from"), http.StatusBadRequest)
+		return
+	}
+	if query.To, err = parseHistoryTime(params.Get("to"), 0); err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid to"), http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit <= 0 || query.Limit > maxHistoryLimit {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid limit"), http.StatusBadRequest)
+			return
+		}
+	}
+	if page := params.Get("page"); page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil || p <= 0 {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid page"), http.StatusBadRequest)
+			return
+		}
+		query.Offset = (p - 1) * query.Limit
+	}
+	// before is the id of last record of previous page, which is cheaper than page for deep pages
+	if before := params.Get("before"); before != "" {
+		query.BeforeID, err = strconv.ParseInt(before, 10, 64)
+		if err != nil || query.BeforeID <= 0 {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid before"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := util.ConfigHistory()
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+	records, err := history.History(query)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, records))
+}
+
+// handleDiffConsulHistory returns keys under prefix changed between from and to, to is now if not given
+func handleDiffConsulHistory(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	from, err := parseHistoryTime(params.Get("from"), 0)
+	if err != nil || from == 0 {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid from"), http.StatusBadRequest)
+		return
+	}
+	to, err := parseHistoryTime(params.Get("to"), time.Now().Unix())
+	if err != nil || to < from {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "Invalid to"), http.StatusBadRequest)
+		return
+	}
+
+	history, err := util.ConfigHistory()
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+	diffs, err := history.DiffHistory(params.Get("prefix"), historyOwners(r), from, to)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, diffs))
+}
+
+// handleRestoreConsulHistory writes keys under prefix back to their values at time
+func handleRestoreConsulHistory(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	errno := ApiError.SUCCESS
+	var err error
+	var ret interface{}
+
+	defer func() {
+		if err != nil {
+			util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), status)
+		} else {
+			util.WriteJSON(w, util.GenRetObj(errno, ret))
+		}
+	}()
+
+	r.ParseForm()
+	// Restoring the whole tree is not allowed to avoid rolling back unrelated modules
+	prefix := strings.Trim(strings.TrimSpace(r.FormValue("prefix")), "/")
+	if prefix == "" {
+		errno, err, status = ApiError.REQUEST_ERROR, errors.New("Invalid prefix"), http.StatusBadRequest
+		return
+	}
+	at, parseErr := parseHistoryTime(r.FormValue("time"), 0)
+	if parseErr != nil || at <= 0 || at > time.Now().Unix() {
+		errno, err, status = ApiError.REQUEST_ERROR, errors.New("Invalid time"), http.StatusBadRequest
+		return
+	}
+
+	history, err := util.ConfigHistory()
+	if err != nil {
+		errno, status = ApiError.REQUEST_ERROR, http.StatusBadRequest
+		return
+	}
+	change := util.NewConsulChange(r, strings.TrimSpace(r.FormValue("reason")))
+	ret, err = history.RestoreHistory(prefix, historyOwners(r), at, change)
+	if err != nil {
+		errno, status = ApiError.CONSUL_SERVICE_ERROR, http.StatusInternalServerError
+	}
+}
+
+// removeExpiredConsulHistory removes history records older than retention
+func removeExpiredConsulHistory() {
+	history, err := util.ConfigHistory()
+	if err != nil {
+		return
+	}
+	if err = history.RemoveExpiredHistory(); err != nil {
+		logger.Error.Println("Remove expired consul history fail: ", err.Error())
+	}
+}
+
+// historyOwners returns appid and enterprise of caller, history is limited to their keys.
+// Callers without appid and enterprise are system admins, who can access all keys.
+func historyOwners(r *http.Request) []string {
+	owners := []string{}
+	for _, owner := range []string{requestheader.GetAppID(r), requestheader.GetEnterpriseID(r)} {
+		if owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// parseHistoryTime parses unix time in seconds, dft is returned if value is empty
+func parseHistoryTime(value string, dft int64) (int64, error) {
+	if value == "" {
+		return dft, nil
+	}
+	t, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || t < 0 {
+		return 0, errors.New("invalid time")
+	}
+	return t, nil
+}
@@ -16,6 +16,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/dictionary"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/pkg/logger"
 )
@@ -29,6 +30,7 @@ const taskAppEntry = "task_engine_app"
 const taskScenarioEntry = "task_engine_editor"
 
 func init() {
+	dictionary.RegisterWordbankReference("task", getScenarioContents)
 	ModuleInfo = util.ModuleInfo{
 		ModuleName: "task",
 		EntryPoints: []util.EntryPoint{
@@ -69,8 +71,12 @@ func init() {
 			util.NewEntryPointWithVer("GET", "templates", []string{}, handleGetScenarioTemplates, 2),
 			util.NewEntryPointWithVer("POST", "scenarios-upload", []string{}, handleUploadScenarios, 2),
 			util.NewEntryPointWithVer("POST", "spreadsheet", []string{}, handleUploadSpreadSheet, 2),
+			util.NewEntryPointWithVer("POST", "scenario/lint", []string{}, handleLintScenario, 2),
+			util.NewEntryPointWithVer("POST", "scenario/run", []string{}, handleRunScenario, 2),
 		},
 	}
+	ModuleInfo.EntryPoints = append(ModuleInfo.EntryPoints,
+		importpreview.EntryPoints("spreadsheet/preview", []string{}, 2, previewModuleSpreadsheet, "spreadsheet", parseSpreadsheetPreview)...)
 }
 
 func handleUpdateScenarioIntents(w http.ResponseWriter, r *http.Request) {
@@ -371,7 +377,7 @@ func handlePutScenario(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		// update consul to inform TE to reload scenarios
-		errno, err = util.ConsulUpdateTaskEngineScenario()
+		errno, err = util.ConsulUpdateTaskEngineScenario(util.NewConsulChange(r, "put scenario"))
 		if err != nil {
 			logger.Error.Printf("Failed to update consul key:te/scenario errno: %d, %s", errno, err.Error())
 			util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
@@ -422,7 +428,7 @@ func handlePutScenarios(w http.ResponseWriter, r *http.Request) {
 			util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
 			return
 		}
-		errno, err = UpdateAppScenarioPairToConsul(appid)
+		errno, err = UpdateAppScenarioPairToConsul(appid, util.NewConsulChange(r, "put scenarios"))
 		if err != nil {
 			errno := ApiError.JSON_PARSE_ERROR
 			util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
@@ -439,7 +445,7 @@ func handlePutScenarios(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		// update consul to inform TE to reload scenarios
-		errno, err = util.ConsulUpdateTaskEngineScenario()
+		errno, err = util.ConsulUpdateTaskEngineScenario(util.NewConsulChange(r, "put scenarios"))
 		if err != nil {
 			logger.Error.Printf("Failed to update consul key:te/scenario errno: %d, %s", errno, err.Error())
 			util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
@@ -484,7 +490,7 @@ func handleDeleteScenario(w http.ResponseWriter, r *http.Request) {
 		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
 		return
 	}
-	errno, err = UpdateAppScenarioPairToConsul(appid)
+	errno, err = UpdateAppScenarioPairToConsul(appid, util.NewConsulChange(r, "delete scenario"))
 	if err != nil {
 		errno := ApiError.JSON_PARSE_ERROR
 		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
@@ -628,7 +634,7 @@ func handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
 		return
 	}
-	errno, err = UpdateAppScenarioPairToConsul(appid)
+	errno, err = UpdateAppScenarioPairToConsul(appid, util.NewConsulChange(r, "update app"))
 	if err != nil {
 		errno := ApiError.JSON_PARSE_ERROR
 		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
@@ -869,7 +875,7 @@ func handleUploadMapTable(w http.ResponseWriter, r *http.Request) {
 
 	// inform TE to reload mapping table
 	var result int
-	result, err = util.ConsulUpdateTaskEngineMappingTable()
+	result, err = util.ConsulUpdateTaskEngineMappingTable(util.NewConsulChange(r, "upload map table"))
 	if err != nil {
 		logger.Info.Printf("Update consul key:te/mapping_table result: %d, %s", result, err.Error())
 	} else {
@@ -917,7 +923,7 @@ func handleDeleteMapTable(w http.ResponseWriter, r *http.Request) {
 
 	// inform TE to reload all mapping table
 	var result int
-	result, err = util.ConsulUpdateTaskEngineMappingTableAll()
+	result, err = util.ConsulUpdateTaskEngineMappingTableAll(util.NewConsulChange(r, "delete map table"))
 	if err != nil {
 		logger.Info.Printf("Update consul key:te/mapping_table_all result: %d, %s", result, err.Error())
 	} else {
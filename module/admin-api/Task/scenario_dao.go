@@ -297,6 +297,40 @@ func deleteScenario(scenarioid, appid string) (err error) {
 	return tx.Commit()
 }
 
+// getScenarioContents returns the editing content of all scenarios of appid
+func getScenarioContents(appid string) (contents []string, err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+
+	mySQL := util.GetMainDB()
+	if mySQL == nil {
+		err = util.ErrDBNotInit
+		return nil, err
+	}
+	queryStr := `
+		SELECT editingContent
+		FROM taskenginescenario
+		WHERE appID = ?`
+	var rows *sql.Rows
+	rows, err = mySQL.Query(queryStr, appid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contents = []string{}
+	for rows.Next() {
+		var content string
+		err = rows.Scan(&content)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
 func getAppScenarioList(appid string) (scenarioids []string, err error) {
 	defer func() {
 		util.ShowError(err)
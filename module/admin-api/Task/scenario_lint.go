@@ -0,0 +1,365 @@
+package Task
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/dop251/goja/parser"
+)
+
+// Types of issue found by LintScenario
+const (
+	LintUnreachableNode = "unreachable_node"
+	LintDanglingEdge    = "dangling_edge"
+	LintUndefinedEntity = "undefined_entity"
+	LintUndefinedNer    = "undefined_ner"
+	LintMissingIntent   = "missing_intent"
+	LintJSSyntaxError   = "js_syntax_error"
+)
+
+// Levels of issue, scenario with error issues should not be published
+const (
+	LintLevelError   = "error"
+	LintLevelWarning = "warning"
+)
+
+const (
+	triggerTypeIntentV1 = "intent_engine"
+	triggerTypeIntentV2 = "intent_engine_2.0"
+	nerSourceCustom     = "custom"
+	nodeTypeEntry       = "entry"
+	jsConditionType     = "js"
+)
+
+// LintIssue is a problem found in a scenario
+type LintIssue struct {
+	Level   string `json:"level"`
+	Type    string `json:"type"`
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+// LintResult is the result of LintScenario
+type LintResult struct {
+	Valid  bool         `json:"valid"`
+	Issues []*LintIssue `json:"issues"`
+}
+
+// lintNode is a node in editing content, edges are parsed loosely
+// because only the target node is needed by linter
+type lintNode struct {
+	NodeType string                   `json:"node_type"`
+	NodeID   string                   `json:"node_id"`
+	Edges    []map[string]interface{} `json:"edges"`
+}
+
+// lintContent is the parts of editing content checked by linter
+type lintContent struct {
+	Nodes      []*lintNode           `json:"nodes"`
+	IDToNerMap map[string]*CustomNer `json:"idToNerMap"`
+	Skills     map[string]*Skill     `json:"skills"`
+	JSCode     *ContentJSCode        `json:"js_code"`
+}
+
+// LintScenario checks the node graph, entities, triggers and syntax of js code of editing content.
+// intents and intentsV1 are the names of intent engine 2.0 and 1.0 intents in app,
+// triggers of an intent engine are not checked if its intents is nil.
+func LintScenario(editingContent string, intents, intentsV1 map[string]bool) (*LintResult, error) {
+	content := &lintContent{}
+	if err := json.Unmarshal([]byte(editingContent), content); err != nil {
+		return nil, err
+	}
+
+	issues := []*LintIssue{}
+	issues = append(issues, lintNodes(content.Nodes)...)
+	issues = append(issues, lintSkills(content.Skills, content.IDToNerMap, intents, intentsV1)...)
+	issues = append(issues, lintNodeJSCode(editingContent)...)
+	if content.JSCode != nil && content.JSCode.TextType != "cipher" && content.JSCode.Main != "" {
+		if err := jsSyntaxError(content.JSCode.Main, false); err != nil {
+			issues = append(issues, &LintIssue{
+				Level:   LintLevelError,
+				Type:    LintJSSyntaxError,
+				Target:  "js_code",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	result := &LintResult{Valid: true, Issues: issues}
+	for _, issue := range issues {
+		if issue.Level == LintLevelError {
+			result.Valid = false
+			break
+		}
+	}
+	return result, nil
+}
+
+func edgeTarget(edge map[string]interface{}) string {
+	for _, key := range []string{"to_node_id", "target_node_id", "target"} {
+		if v, ok := edge[key]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// lintNodes finds edges to nonexistent nodes and nodes which can not be reached from entry nodes.
+// The first node is used as entry if there is no entry node.
+func lintNodes(nodes []*lintNode) []*LintIssue {
+	issues := []*LintIssue{}
+	if len(nodes) == 0 {
+		return issues
+	}
+
+	nodeMap := map[string]*lintNode{}
+	entries := []string{}
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		nodeMap[node.NodeID] = node
+		if node.NodeType == nodeTypeEntry {
+			entries = append(entries, node.NodeID)
+		}
+	}
+	if len(entries) == 0 && nodes[0] != nil {
+		entries = append(entries, nodes[0].NodeID)
+	}
+
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		for _, edge := range node.Edges {
+			target := edgeTarget(edge)
+			if target == "" {
+				continue
+			}
+			if _, ok := nodeMap[target]; !ok {
+				issues = append(issues, &LintIssue{
+					Level:   LintLevelError,
+					Type:    LintDanglingEdge,
+					Target:  node.NodeID,
+					Message: fmt.Sprintf("edge of node %s points to nonexistent node %s", node.NodeID, target),
+				})
+			}
+		}
+	}
+
+	visited := map[string]bool{}
+	queue := entries
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		node, ok := nodeMap[id]
+		if !ok {
+			continue
+		}
+		for _, edge := range node.Edges {
+			if target := edgeTarget(edge); target != "" && !visited[target] {
+				queue = append(queue, target)
+			}
+		}
+	}
+	for _, node := range nodes {
+		if node == nil || visited[node.NodeID] {
+			continue
+		}
+		issues = append(issues, &LintIssue{
+			Level:   LintLevelWarning,
+			Type:    LintUnreachableNode,
+			Target:  node.NodeID,
+			Message: fmt.Sprintf("node %s can not be reached from entry", node.NodeID),
+		})
+	}
+	return issues
+}
+
+// triggerIntentExisted checks intent of trigger in the intents of its intent engine
+func triggerIntentExisted(trigger *Trigger, intents, intentsV1 map[string]bool) bool {
+	switch trigger.Type {
+	case triggerTypeIntentV2:
+		return intents == nil || intents[trigger.IntentName]
+	case triggerTypeIntentV1:
+		return intentsV1 == nil || intentsV1[trigger.IntentName]
+	}
+	return true
+}
+
+// lintSkills checks that entities have valid ner and triggers refer to existed intents
+func lintSkills(skills map[string]*Skill, nerMap map[string]*CustomNer, intents, intentsV1 map[string]bool) []*LintIssue {
+	issues := []*LintIssue{}
+	customNers := map[string]bool{}
+	for _, ner := range nerMap {
+		if ner != nil {
+			customNers[ner.EntityType] = true
+		}
+	}
+
+	names := make([]string, 0, len(skills))
+	for name := range skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		skill := skills[name]
+		if skill == nil {
+			continue
+		}
+		for _, trigger := range skill.TriggerList {
+			if trigger == nil {
+				continue
+			}
+			if trigger.IntentName == "" {
+				issues = append(issues, &LintIssue{
+					Level:   LintLevelError,
+					Type:    LintMissingIntent,
+					Target:  name,
+					Message: fmt.Sprintf("trigger of skill %s has no intent", name),
+				})
+			} else if !triggerIntentExisted(trigger, intents, intentsV1) {
+				issues = append(issues, &LintIssue{
+					Level:   LintLevelError,
+					Type:    LintMissingIntent,
+					Target:  trigger.IntentName,
+					Message: fmt.Sprintf("intent %s of skill %s does not exist", trigger.IntentName, name),
+				})
+			}
+		}
+
+		for _, entity := range skill.EntityCollectorList {
+			if entity == nil {
+				continue
+			}
+			if entity.EntityName == "" || entity.Ner == nil || entity.Ner.EntityType == "" {
+				issues = append(issues, &LintIssue{
+					Level:   LintLevelError,
+					Type:    LintUndefinedEntity,
+					Target:  entity.EntityName,
+					Message: fmt.Sprintf("entity %s of skill %s has no name or ner", entity.EntityName, name),
+				})
+				continue
+			}
+			ner := entity.Ner
+			if ner.SourceType == nerSourceCustom {
+				if !customNers[ner.EntityType] {
+					issues = append(issues, &LintIssue{
+						Level:   LintLevelError,
+						Type:    LintUndefinedNer,
+						Target:  entity.EntityName,
+						Message: fmt.Sprintf("custom ner %s of entity %s is not defined", ner.EntityType, entity.EntityName),
+					})
+				}
+			} else if _, ok := SlotType[ner.EntityType]; !ok && !customNers[ner.EntityType] {
+				issues = append(issues, &LintIssue{
+					Level:   LintLevelError,
+					Type:    LintUndefinedNer,
+					Target:  entity.EntityName,
+					Message: fmt.Sprintf("ner %s of entity %s is not defined", ner.EntityType, entity.EntityName),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// jsSyntaxError parses js code and returns the first syntax error with its position.
+// Code of a node condition is parsed as function body, so it can be an expression or return a value.
+func jsSyntaxError(code string, isFunctionBody bool) error {
+	var err error
+	lineOffset := 0
+	if isFunctionBody {
+		// ParseFunction puts the body after "(function() {\n"
+		_, err = parser.ParseFunction("", code)
+		lineOffset = 1
+	} else {
+		_, err = parser.ParseFile(nil, "", code, 0)
+	}
+	if err == nil {
+		return nil
+	}
+	var first *parser.Error
+	switch e := err.(type) {
+	case parser.ErrorList:
+		if len(e) > 0 {
+			first = e[0]
+		}
+	case *parser.Error:
+		first = e
+	}
+	if first == nil {
+		return err
+	}
+	return fmt.Errorf("line %d, column %d: %s", first.Position.Line-lineOffset, first.Position.Column, first.Message)
+}
+
+// lintNodeJSCode checks js code of conditions in nodes and skills, which are objects
+// with type js in any depth of node or skill
+func lintNodeJSCode(editingContent string) []*LintIssue {
+	issues := []*LintIssue{}
+	content := struct {
+		Nodes  []map[string]interface{} `json:"nodes"`
+		Skills map[string]interface{}   `json:"skills"`
+	}{}
+	if err := json.Unmarshal([]byte(editingContent), &content); err != nil {
+		return issues
+	}
+
+	check := func(target string, v interface{}) {
+		for _, code := range collectJSCode(v) {
+			if err := jsSyntaxError(code, true); err != nil {
+				issues = append(issues, &LintIssue{
+					Level:   LintLevelError,
+					Type:    LintJSSyntaxError,
+					Target:  target,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+	for _, node := range content.Nodes {
+		if node != nil {
+			check(fmt.Sprint(node["node_id"]), node)
+		}
+	}
+	names := make([]string, 0, len(content.Skills))
+	for name := range content.Skills {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check(name, content.Skills[name])
+	}
+	return issues
+}
+
+func collectJSCode(v interface{}) []string {
+	ret := []string{}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if value["type"] == jsConditionType {
+			if code, ok := value["code"].(string); ok && code != "" {
+				ret = append(ret, code)
+			}
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ret = append(ret, collectJSCode(value[key])...)
+		}
+	case []interface{}:
+		for _, item := range value {
+			ret = append(ret, collectJSCode(item)...)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,109 @@
+package Task
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/intentengine"
+	intentenginev2 "emotibot.com/emotigo/module/admin-api/intentengine/v2"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// scenarioCheckRequest is the request of lint and run API.
+// Content is the editing content to check, the editing content of the saved
+// scenario is used if content is empty, so that scenario can be checked before upload.
+type scenarioCheckRequest struct {
+	ScenarioID string          `json:"scenarioid"`
+	Content    json.RawMessage `json:"content"`
+	Dialogues  []*RunDialogue  `json:"dialogues"`
+}
+
+// parseScenarioCheckRequest returns the request and editing content to be checked
+func parseScenarioCheckRequest(r *http.Request) (*scenarioCheckRequest, string, int, error) {
+	req := &scenarioCheckRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	defer r.Body.Close()
+	if err != nil {
+		return nil, "", ApiError.REQUEST_ERROR, err
+	}
+	if len(req.Content) > 0 && string(req.Content) != "null" {
+		return req, string(req.Content), ApiError.SUCCESS, nil
+	}
+	if req.ScenarioID == "" {
+		return nil, "", ApiError.REQUEST_ERROR, errors.New("scenarioid or content is required")
+	}
+	scenario, errno, err := GetDecryptedScenario(req.ScenarioID)
+	if err != nil {
+		return nil, "", errno, err
+	}
+	if scenario == nil || scenario.AppID != requestheader.GetAppID(r) {
+		return nil, "", ApiError.NOT_FOUND_ERROR, errors.New("scenario not found")
+	}
+	return req, scenario.EditingContent, ApiError.SUCCESS, nil
+}
+
+// handleLintScenario checks the node graph, entities, triggers and js code of a scenario
+func handleLintScenario(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	_, content, errno, err := parseScenarioCheckRequest(r)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
+		return
+	}
+
+	intents, adminErr := intentenginev2.GetIntents(appid, nil, "")
+	if adminErr != nil {
+		logger.Error.Printf("Get intents of %s fail: %s\n", appid, adminErr.Error())
+		errno := ApiError.DB_ERROR
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, adminErr.Error()), ApiError.GetHttpStatus(errno))
+		return
+	}
+	intentNames := map[string]bool{}
+	for _, intent := range intents {
+		intentNames[intent.Name] = true
+	}
+	intentsV1, errno, err := intentengine.GetIntents(appid, 0)
+	if err != nil {
+		logger.Error.Printf("Get intents v1 of %s fail: %s\n", appid, err.Error())
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
+		return
+	}
+	intentV1Names := map[string]bool{}
+	for _, intent := range intentsV1 {
+		intentV1Names[intent] = true
+	}
+
+	result, err := LintScenario(content, intentNames, intentV1Names)
+	if err != nil {
+		errno := ApiError.JSON_PARSE_ERROR
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, result))
+}
+
+// handleRunScenario replays scripted dialogues against a scenario and returns the path taken
+func handleRunScenario(w http.ResponseWriter, r *http.Request) {
+	req, content, errno, err := parseScenarioCheckRequest(r)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
+		return
+	}
+	if len(req.Dialogues) == 0 {
+		errno := ApiError.REQUEST_ERROR
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, "dialogues is required"), ApiError.GetHttpStatus(errno))
+		return
+	}
+
+	results, err := RunScenario(content, req.Dialogues)
+	if err != nil {
+		errno := ApiError.JSON_PARSE_ERROR
+		util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
+		return
+	}
+	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, results))
+}
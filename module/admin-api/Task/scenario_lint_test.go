@@ -0,0 +1,216 @@
+package Task
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testScenarioContent = `{
+	"nodes": [
+		{"node_id": "entry", "node_type": "entry", "edges": [
+			{"to_node_id": "notify", "condition_rules": [[{"entity": "city", "compare": "==", "value": "上海"}]]},
+			{"to_node_id": "collect"}
+		]},
+		{"node_id": "notify", "node_type": "action", "edges": []},
+		{"node_id": "collect", "node_type": "parameter_collecting", "edges": [{"to_node_id": "missing"}]},
+		{"node_id": "orphan", "node_type": "action", "edges": [],
+			"content": {"conditions": [{"type": "js", "code": "return a +;"}]}}
+	],
+	"idToNerMap": {
+		"ner1": {"sourceType": "custom", "entityType": "城市", "entitySynonymsList": [
+			{"entity": "北京", "synonyms": "帝都，京城"},
+			{"entity": "上海", "synonyms": "魔都"}
+		]}
+	},
+	"skills": {
+		"mainSkill": {
+			"triggerList": [
+				{"type": "intent_engine_2.0", "intent_name": "订机票"},
+				{"type": "intent_engine_2.0", "intent_name": "退机票"},
+				{"type": "intent_engine", "intent_name": "改签"}
+			],
+			"entityCollectorList": [
+				{"entityName": "city", "prompt": "去哪个城市?", "required": true, "must_retry": true, "retry_num": 1,
+					"ner": {"entityType": "城市", "sourceType": "custom"}},
+				{"entityName": "date", "prompt": "哪天出发?", "required": true,
+					"ner": {"entityType": "时间日期", "sourceType": "system"}},
+				{"entityName": "seat", "required": false,
+					"ner": {"entityType": "舱位", "sourceType": "custom"}}
+			],
+			"actionGroupList": [
+				{"actionGroupId": "done", "actionList": [{"type": "msg", "msg": "订好了"}], "conditionList": []},
+				{"actionGroupId": "beijing", "actionList": [{"type": "msg", "msg": "北京天气好"}],
+					"conditionList": [{"entity": "city", "value": "北京"}, {"entity": "date", "compare": "exists"}]},
+				{"actionGroupId": "shanghai", "actionList": [{"type": "msg", "msg": "上海下雨"}],
+					"conditionList": [{"entity": "city", "value": "上海"}]},
+				{"actionGroupId": "script", "actionList": [], "conditionList": [{"type": "js", "code": "true"}]}
+			]
+		}
+	},
+	"js_code": {"text_type": "plain", "main": "function f(a) { return a.replace(/[)]/g, '}'); "}
+}`
+
+func TestLintScenario(t *testing.T) {
+	result, err := LintScenario(testScenarioContent, map[string]bool{"订机票": true}, map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid {
+		t.Error("expect scenario to be invalid")
+	}
+	expected := map[string][]string{
+		LintDanglingEdge:    {"collect"},
+		LintUnreachableNode: {"orphan"},
+		LintMissingIntent:   {"退机票", "改签"},
+		LintUndefinedNer:    {"seat"},
+		LintJSSyntaxError:   {"orphan", "js_code"},
+	}
+	found := map[string][]string{}
+	for _, issue := range result.Issues {
+		found[issue.Type] = append(found[issue.Type], issue.Target)
+	}
+	count := 0
+	for issueType, targets := range expected {
+		count += len(targets)
+		if strings.Join(found[issueType], ",") != strings.Join(targets, ",") {
+			t.Errorf("expect %s issue of %v, but got %v", issueType, targets, found[issueType])
+		}
+	}
+	if len(result.Issues) != count {
+		t.Errorf("expect %d issues, but got %d", count, len(result.Issues))
+	}
+
+	result, err = LintScenario(testScenarioContent, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range result.Issues {
+		if issue.Type == LintMissingIntent {
+			t.Errorf("expect intents not checked, but got %v", issue)
+		}
+	}
+}
+
+func TestJSSyntaxError(t *testing.T) {
+	testCases := []struct {
+		code           string
+		isFunctionBody bool
+		err            string
+	}{
+		{"var a = {b: [1, 2]};", false, ""},
+		{"var s = 'a)' + \"b}\" + `c\n]`; // )", false, ""},
+		{"var r = /[(]\\//g; var d = a / b / c;", false, ""},
+		{"/* ( */ function f() { return (1); }", false, ""},
+		{"function f() {\n  return (1; }", false, "line 2, column 12"},
+		{"var s = 'abc;", false, "line 1"},
+		{"/* comment", false, "line 1"},
+		{"if (a) { b() ", false, "line 1"},
+		{"var a = 1 +;", false, "line 1, column 12"},
+		{"a > 1", true, ""},
+		{"if (a) {\n  return b;\n}", true, ""},
+		{"return b +;", false, "line 1"},
+		{"\nreturn b +;", true, "line 2, column 11"},
+	}
+	for _, tc := range testCases {
+		err := jsSyntaxError(tc.code, tc.isFunctionBody)
+		if tc.err == "" && err != nil {
+			t.Errorf("expect %q to be valid, but got %v", tc.code, err)
+		} else if tc.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tc.err)) {
+			t.Errorf("expect error of %q to start with %s, but got %v", tc.code, tc.err, err)
+		}
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	dialogues := []*RunDialogue{
+		&RunDialogue{
+			Name: "book",
+			Turns: []*RunTurn{
+				&RunTurn{Input: "你好"},
+				&RunTurn{Input: "我要订机票", Intent: "订机票"},
+				&RunTurn{Input: "去帝都"},
+				&RunTurn{Input: "明天", Entities: map[string]string{"date": "2019-01-01"}},
+			},
+		},
+		&RunDialogue{
+			Name: "retry",
+			Turns: []*RunTurn{
+				&RunTurn{Input: "订机票"},
+				&RunTurn{Input: "不知道"},
+				&RunTurn{Input: "不知道"},
+			},
+		},
+	}
+	results, err := RunScenario(testScenarioContent, dialogues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book := results[0]
+	if !book.Completed {
+		t.Error("expect book dialogue to be completed")
+	}
+	expectedSteps := [][]string{
+		{RunStepNoTrigger},
+		{RunStepTrigger, RunStepPrompt},
+		{RunStepEntity, RunStepPrompt},
+		{RunStepEntity, RunStepAction, RunStepAction, RunStepSkipAction, RunStepUnknownCondition,
+			RunStepNode, RunStepNode},
+	}
+	for i, steps := range expectedSteps {
+		path := book.Turns[i].Path
+		if len(path) != len(steps) {
+			t.Fatalf("expect path of turn %d to be %v, but got %d steps", i, steps, len(path))
+		}
+		for j, step := range steps {
+			if path[j].Type != step {
+				t.Errorf("expect step %d of turn %d to be %s, but got %s", j, i, step, path[j].Type)
+			}
+		}
+	}
+	if book.Turns[3].Entities["city"] != "北京" {
+		t.Errorf("expect city to be matched by synonym, but got %v", book.Turns[3].Entities)
+	}
+	if replies := book.Turns[3].Replies; len(replies) != 2 || replies[0] != "订好了" || replies[1] != "北京天气好" {
+		t.Errorf("unexpected replies %v", replies)
+	}
+	if path := book.Turns[3].Path; path[5].Target != "entry" || path[6].Target != "collect" {
+		t.Errorf("expect conditional edge to notify not to be followed, but got %v, %v", path[5], path[6])
+	}
+
+	retry := results[1]
+	if retry.Completed {
+		t.Error("expect retry dialogue not to be completed")
+	}
+	last := retry.Turns[2].Path
+	if last[len(last)-1].Type != RunStepRetryExceeded {
+		t.Errorf("expect retry exceeded, but got %v", last[len(last)-1])
+	}
+}
+
+func TestMatchConditionRules(t *testing.T) {
+	entities := map[string]string{"city": "上海", "count": "3"}
+	testCases := []struct {
+		rules   string
+		matched bool
+		known   bool
+	}{
+		{`[]`, true, true},
+		{`[[{"entity": "city", "value": "北京"}], [{"entity": "count", "compare": ">=", "value": 3}]]`, true, true},
+		{`[[{"entity": "city", "value": "上海"}, {"entity": "date", "compare": "exists"}]]`, false, true},
+		{`[[{"entity": "city", "compare": "contains", "value": "海"}]]`, true, true},
+		{`[[{"type": "js"}]]`, false, false},
+		{`{"entity": "city"}`, false, false},
+	}
+	for _, tc := range testCases {
+		var rules interface{}
+		if err := json.Unmarshal([]byte(tc.rules), &rules); err != nil {
+			t.Fatal(err)
+		}
+		matched, known := matchConditionRules(rules, entities)
+		if matched != tc.matched || known != tc.known {
+			t.Errorf("expect %s to be (%v, %v), but got (%v, %v)", tc.rules, tc.matched, tc.known, matched, known)
+		}
+	}
+}
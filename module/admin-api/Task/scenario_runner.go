@@ -0,0 +1,390 @@
+package Task
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of step in the path of a scripted dialogue
+const (
+	RunStepNoTrigger        = "no_trigger"
+	RunStepTrigger          = "trigger"
+	RunStepEntity           = "entity"
+	RunStepPrompt           = "prompt"
+	RunStepRetryExceeded    = "retry_exceeded"
+	RunStepAction           = "action"
+	RunStepSkipAction       = "skip_action"
+	RunStepNode             = "node"
+	RunStepUnknownCondition = "unknown_condition"
+)
+
+// RunTurn is a user input of scripted dialogue. NLU is not available when running offline,
+// so the intent and values of system ner are given by script. Values of custom ner
+// are matched with the synonyms in scenario if they are not given.
+type RunTurn struct {
+	Input    string            `json:"input"`
+	Intent   string            `json:"intent"`
+	Entities map[string]string `json:"entities"`
+}
+
+// RunDialogue is a scripted dialogue replayed by RunScenario
+type RunDialogue struct {
+	Name  string     `json:"name"`
+	Turns []*RunTurn `json:"turns"`
+}
+
+// RunStep is a step of the path taken by the scenario
+type RunStep struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+}
+
+// RunTurnResult is the path taken and replies of a turn
+type RunTurnResult struct {
+	Input    string            `json:"input"`
+	Path     []*RunStep        `json:"path"`
+	Replies  []string          `json:"replies"`
+	Entities map[string]string `json:"entities"`
+}
+
+// RunDialogueResult is the result of a scripted dialogue
+type RunDialogueResult struct {
+	Name  string           `json:"name"`
+	Turns []*RunTurnResult `json:"turns"`
+	// Completed is true if action groups of scenario are executed in the dialogue
+	Completed bool `json:"completed"`
+}
+
+// scenarioRun is the state of a scenario during a scripted dialogue
+type scenarioRun struct {
+	content  *lintContent
+	skill    *Skill
+	entities map[string]string
+	retries  map[string]int
+}
+
+// RunScenario replays dialogues against the triggers, entity collectors and message actions
+// of editing content, without calling any service.
+// Conditions of action groups are evaluated with the collected entities, groups whose
+// conditions are not met are reported as skip_action. When the action groups are executed,
+// the node graph is walked from entry by following the first edge whose condition rules are met.
+// Conditions which can not be evaluated offline are reported as unknown_condition.
+func RunScenario(editingContent string, dialogues []*RunDialogue) ([]*RunDialogueResult, error) {
+	content := &lintContent{}
+	if err := json.Unmarshal([]byte(editingContent), content); err != nil {
+		return nil, err
+	}
+
+	results := make([]*RunDialogueResult, 0, len(dialogues))
+	for _, dialogue := range dialogues {
+		if dialogue == nil {
+			continue
+		}
+		run := &scenarioRun{content: content}
+		result := &RunDialogueResult{Name: dialogue.Name, Turns: []*RunTurnResult{}}
+		for _, turn := range dialogue.Turns {
+			if turn == nil {
+				continue
+			}
+			turnResult, completed := run.step(turn)
+			result.Turns = append(result.Turns, turnResult)
+			result.Completed = result.Completed || completed
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// sortedSkills returns skills sorted by name, so that the result is stable
+func (run *scenarioRun) sortedSkills() []string {
+	names := make([]string, 0, len(run.content.Skills))
+	for name, skill := range run.content.Skills {
+		if skill != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (run *scenarioRun) trigger(turn *RunTurn) (string, *Trigger) {
+	for _, name := range run.sortedSkills() {
+		for _, trigger := range run.content.Skills[name].TriggerList {
+			if trigger == nil || trigger.IntentName == "" {
+				continue
+			}
+			if trigger.IntentName == turn.Intent || trigger.IntentName == strings.TrimSpace(turn.Input) {
+				return name, trigger
+			}
+		}
+	}
+	return "", nil
+}
+
+// step handles a turn, completed is true if the action groups are executed
+func (run *scenarioRun) step(turn *RunTurn) (result *RunTurnResult, completed bool) {
+	result = &RunTurnResult{
+		Input:   turn.Input,
+		Path:    []*RunStep{},
+		Replies: []string{},
+	}
+
+	if run.skill == nil {
+		name, trigger := run.trigger(turn)
+		if trigger == nil {
+			result.Path = append(result.Path, &RunStep{Type: RunStepNoTrigger})
+			return result, false
+		}
+		run.skill = run.content.Skills[name]
+		run.entities = map[string]string{}
+		run.retries = map[string]int{}
+		result.Path = append(result.Path, &RunStep{Type: RunStepTrigger, Target: trigger.IntentName})
+	}
+
+	for _, entity := range run.skill.EntityCollectorList {
+		if entity == nil || run.entities[entity.EntityName] != "" {
+			continue
+		}
+		if value := run.extract(entity, turn); value != "" {
+			run.entities[entity.EntityName] = value
+			result.Path = append(result.Path, &RunStep{Type: RunStepEntity, Target: entity.EntityName})
+		}
+	}
+	result.Entities = copyEntities(run.entities)
+
+	for _, entity := range run.skill.EntityCollectorList {
+		if entity == nil || !entity.Required || run.entities[entity.EntityName] != "" {
+			continue
+		}
+		if run.retries[entity.EntityName] > entity.RetryNum && entity.MustRetry {
+			result.Path = append(result.Path, &RunStep{Type: RunStepRetryExceeded, Target: entity.EntityName})
+			run.skill = nil
+			return result, false
+		}
+		run.retries[entity.EntityName]++
+		result.Path = append(result.Path, &RunStep{Type: RunStepPrompt, Target: entity.EntityName})
+		result.Replies = append(result.Replies, entity.Prompt)
+		return result, false
+	}
+
+	for _, group := range run.skill.ActionGroupList {
+		if group == nil {
+			continue
+		}
+		matched, known := matchConditions(derefConditions(group.ConditionList), run.entities)
+		if !known {
+			result.Path = append(result.Path, &RunStep{Type: RunStepUnknownCondition, Target: group.ActionGroupID})
+			continue
+		}
+		if !matched {
+			result.Path = append(result.Path, &RunStep{Type: RunStepSkipAction, Target: group.ActionGroupID})
+			continue
+		}
+		result.Path = append(result.Path, &RunStep{Type: RunStepAction, Target: group.ActionGroupID})
+		for _, action := range group.ActionList {
+			if action != nil && action.Type == "msg" {
+				result.Replies = append(result.Replies, action.Msg)
+			}
+		}
+	}
+	result.Path = append(result.Path, run.walkNodes()...)
+	run.skill = nil
+	return result, true
+}
+
+// walkNodes follows the node graph from entry with collected entities. The first edge
+// whose condition rules are met is followed, walking stops at a node without such edge,
+// an edge to nonexistent node or a node visited already.
+func (run *scenarioRun) walkNodes() []*RunStep {
+	steps := []*RunStep{}
+	nodes := run.content.Nodes
+	if len(nodes) == 0 {
+		return steps
+	}
+
+	nodeMap := map[string]*lintNode{}
+	var current *lintNode
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		nodeMap[node.NodeID] = node
+		if current == nil && node.NodeType == nodeTypeEntry {
+			current = node
+		}
+	}
+	if current == nil {
+		current = nodes[0]
+	}
+
+	visited := map[string]bool{}
+	for current != nil && !visited[current.NodeID] {
+		visited[current.NodeID] = true
+		steps = append(steps, &RunStep{Type: RunStepNode, Target: current.NodeID})
+
+		var next *lintNode
+		for _, edge := range current.Edges {
+			matched, known := matchConditionRules(edge["condition_rules"], run.entities)
+			if !known {
+				steps = append(steps, &RunStep{Type: RunStepUnknownCondition, Target: current.NodeID})
+				return steps
+			}
+			if matched {
+				next = nodeMap[edgeTarget(edge)]
+				break
+			}
+		}
+		current = next
+	}
+	return steps
+}
+
+func derefConditions(list []*interface{}) []interface{} {
+	conditions := make([]interface{}, 0, len(list))
+	for _, c := range list {
+		if c != nil {
+			conditions = append(conditions, *c)
+		}
+	}
+	return conditions
+}
+
+// matchConditionRules evaluates condition rules of edge, which is a list of condition lists.
+// Rules are met if any of the condition lists is met, edge without rules is always followed.
+func matchConditionRules(rules interface{}, entities map[string]string) (matched bool, known bool) {
+	if rules == nil {
+		return true, true
+	}
+	groups, ok := rules.([]interface{})
+	if !ok {
+		return false, false
+	}
+	if len(groups) == 0 {
+		return true, true
+	}
+	for _, group := range groups {
+		conditions, ok := group.([]interface{})
+		if !ok {
+			return false, false
+		}
+		matched, known := matchConditions(conditions, entities)
+		if !known {
+			return false, false
+		}
+		if matched {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// matchConditions returns if all conditions are met by entities.
+// A condition compares the value of an entity, e.g. {"entity": "city", "compare": "==", "value": "北京"},
+// known is false if a condition is not in this form.
+func matchConditions(conditions []interface{}, entities map[string]string) (matched bool, known bool) {
+	matched = true
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			return false, false
+		}
+		ret, ok := matchCondition(condition, entities)
+		if !ok {
+			return false, false
+		}
+		matched = matched && ret
+	}
+	return matched, true
+}
+
+func conditionField(condition map[string]interface{}, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if v, ok := condition[key]; ok && v != nil {
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+func matchCondition(condition map[string]interface{}, entities map[string]string) (matched bool, known bool) {
+	name, ok := conditionField(condition, "entity", "entityName", "key")
+	if !ok {
+		return false, false
+	}
+	compare, ok := conditionField(condition, "compare", "operator")
+	if !ok {
+		compare = "=="
+	}
+	expected, _ := conditionField(condition, "value", "val")
+	value := entities[name]
+
+	switch compare {
+	case "exists":
+		return value != "", true
+	case "not_exists":
+		return value == "", true
+	case "==":
+		return value == expected, true
+	case "!=":
+		return value != expected, true
+	case "contains":
+		return value != "" && strings.Contains(value, expected), true
+	case ">", ">=", "<", "<=":
+		a, errA := strconv.ParseFloat(value, 64)
+		b, errB := strconv.ParseFloat(expected, 64)
+		if errA != nil || errB != nil {
+			return false, true
+		}
+		switch compare {
+		case ">":
+			return a > b, true
+		case ">=":
+			return a >= b, true
+		case "<":
+			return a < b, true
+		default:
+			return a <= b, true
+		}
+	}
+	return false, false
+}
+
+// extract returns the value of entity in turn, value given by script is used first,
+// then the synonyms of custom ner are matched with input
+func (run *scenarioRun) extract(entity *Entity, turn *RunTurn) string {
+	if value := turn.Entities[entity.EntityName]; value != "" {
+		return value
+	}
+	if entity.Ner == nil {
+		return ""
+	}
+	for _, ner := range run.content.IDToNerMap {
+		if ner == nil || ner.EntityType != entity.Ner.EntityType {
+			continue
+		}
+		for _, synonyms := range ner.EntitySynonymsList {
+			if synonyms == nil {
+				continue
+			}
+			words := append([]string{synonyms.Entity}, strings.Split(strings.Replace(synonyms.Synonyms, "，", ",", -1), ",")...)
+			for _, word := range words {
+				word = strings.TrimSpace(word)
+				if word != "" && strings.Contains(turn.Input, word) {
+					return synonyms.Entity
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func copyEntities(entities map[string]string) map[string]string {
+	ret := make(map[string]string, len(entities))
+	for k, v := range entities {
+		ret[k] = v
+	}
+	return ret
+}
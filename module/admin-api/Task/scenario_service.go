@@ -408,13 +408,13 @@ func PublishScenario(scenarioid, appid, userid string) (int, error) {
 }
 
 // UpdateAppScenarioPairToConsul update the app-scenario pair to consul key te/app
-func UpdateAppScenarioPairToConsul(appid string) (int, error) {
+func UpdateAppScenarioPairToConsul(appid string, change *util.ConsulChange) (int, error) {
 	scenarioList, errno, err := GetAppScenarioList(appid)
 	if err != nil {
 		return errno, err
 	}
 	value := strings.Join(scenarioList, ",")
-	errno, err = util.ConsulUpdateTaskEngineApp(appid, value)
+	errno, err = util.ConsulUpdateTaskEngineApp(appid, value, change)
 	if err != nil {
 		logger.Error.Printf("Failed to update consul key:te/app/%s errno: %d, %s", appid, errno, err.Error())
 		return errno, err
@@ -0,0 +1,100 @@
+package Task
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+const previewModuleSpreadsheet = "task_spreadsheet"
+
+// parseSpreadsheetPreview parses the spreadsheet and diffs it with the editing content of scenario.
+// Trigger phrases are only registered to intent engine 1.0 when the preview is committed.
+func parseSpreadsheetPreview(r *http.Request, appid string, upload *importpreview.Upload) (importpreview.RowErrors, *importpreview.Diff, func() error, AdminErrors.AdminError) {
+	appID := upload.FormValue("appId")
+	if appID == "" {
+		appID = appid
+	}
+	scenarioID := upload.FormValue("scenarioId")
+
+	current, err := getScenario(scenarioID)
+	if err != nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	} else if current == nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "scenarioId")
+	}
+
+	scenario, triggerPhrases, err := parseSpreadsheet(upload.FormValue("scenario"), upload.Content)
+	if err != nil {
+		return importpreview.ToRowErrors(err), nil, nil, nil
+	}
+
+	currentContent := &ScenarioTDEContent{}
+	if err := json.Unmarshal([]byte(current.EditingContent), currentContent); err != nil {
+		logger.Warn.Printf("Parse editing content of scenario %s fail: %s\n", scenarioID, err.Error())
+	}
+	diff := importpreview.DiffRecords(scenarioRecords(currentContent), scenarioRecords(scenario.EditingContent))
+
+	commit := func() error {
+		content, err := json.Marshal(scenario.EditingContent)
+		if err != nil {
+			return err
+		}
+		layout, err := json.Marshal(scenario.EditingLayout)
+		if err != nil {
+			return err
+		}
+		retCode, err := UpdateSpreadsheetScenario(scenarioID, string(content), string(layout))
+		if err != nil {
+			return err
+		} else if retCode != ApiError.SUCCESS {
+			return errors.New(ApiError.GetErrorMsg(retCode))
+		}
+		// Trigger phrases are registered after the scenario is saved,
+		// so they never point to a scenario which is not updated
+		return registerTriggerPhrases(appID, scenario, triggerPhrases)
+	}
+	return nil, diff, commit, nil
+}
+
+// scenarioRecords returns the parts of main skill which can be imported by spreadsheet,
+// generated ids are not compared
+func scenarioRecords(content *ScenarioTDEContent) map[string]string {
+	records := map[string]string{}
+	if content == nil {
+		return records
+	}
+	if skill, ok := content.Skills["mainSkill"]; ok && skill != nil {
+		for _, trigger := range skill.TriggerList {
+			records["trigger/"+trigger.IntentName] = trigger.Type
+		}
+		for _, entity := range skill.EntityCollectorList {
+			entityType := ""
+			if entity.Ner != nil {
+				entityType = entity.Ner.EntityType
+			}
+			records["entity/"+entity.EntityName] = strings.Join([]string{entity.EntityCategory, entityType, entity.Prompt}, "\n")
+		}
+		for _, actionGroup := range skill.ActionGroupList {
+			for _, action := range actionGroup.ActionList {
+				if action.Type == "msg" {
+					records["message/"+action.Msg] = ""
+				}
+			}
+		}
+	}
+	for _, ner := range content.IDToNerMap {
+		synonyms := []string{}
+		for _, entitySynonyms := range ner.EntitySynonymsList {
+			synonyms = append(synonyms, entitySynonyms.Entity+":"+entitySynonyms.Synonyms)
+		}
+		records["ner/"+ner.EntityType] = importpreview.RecordContent(synonyms...)
+	}
+	return records
+}
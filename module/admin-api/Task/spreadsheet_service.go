@@ -2,26 +2,50 @@ package Task
 
 import (
 	"encoding/json"
-	"errors"
 	"strings"
 
 	"emotibot.com/emotigo/module/admin-api/dictionary"
 	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"github.com/tealeg/xlsx"
 )
 
 // ParseUploadSpreadsheet will parse and verify uploaded spreadsheet
 // Use intent_engine_2.0 intent as trigger
 func ParseUploadSpreadsheet(appID string, scenarioString string, fileBuf []byte) (*ScenarioTDE, error) {
-	var scenario ScenarioTDE
-	json.Unmarshal([]byte(scenarioString), &scenario)
-	xlFile, err := xlsx.OpenBinary(fileBuf)
+	scenario, triggerPhrases, err := parseSpreadsheet(scenarioString, fileBuf)
+	if err != nil {
+		return nil, err
+	}
+	err = registerTriggerPhrases(appID, scenario, triggerPhrases)
 	if err != nil {
 		return nil, err
 	}
+	return scenario, nil
+}
+
+// registerTriggerPhrases registers an intent to intent engine 1.0 with the trigger phrases
+func registerTriggerPhrases(appID string, scenario *ScenarioTDE, triggerPhrases []string) error {
+	if triggerPhrases == nil {
+		return nil
+	}
+	scenarioName := scenario.EditingContent.Metadata["scenario_name"]
+	return UpdateIntentV1(appID, scenarioName, triggerPhrases)
+}
+
+// parseSpreadsheet parses all sheets without any side effect, errors of all rows are
+// returned as importpreview.RowErrors. triggerPhrases is nil if there is no trigger phrase sheet.
+func parseSpreadsheet(scenarioString string, fileBuf []byte) (scenario *ScenarioTDE, triggerPhrases []string, err error) {
+	scenario = &ScenarioTDE{}
+	json.Unmarshal([]byte(scenarioString), scenario)
+	xlFile, err := xlsx.OpenBinary(fileBuf)
+	if err != nil {
+		return nil, nil, err
+	}
+	rowErrs := importpreview.RowErrors{}
 
 	var sheet *xlsx.Sheet
-	// parse triggier phrases and register an intent to intent engine 1.0
+	// parse triggier phrases, which will be registered as an intent to intent engine 1.0
 	sheet = xlFile.Sheet[SheetName["triggerPhrase"]]
 	if sheet != nil {
 		// create an intent with intent_name = scenario_name
@@ -30,24 +54,15 @@ func ParseUploadSpreadsheet(appID string, scenarioString string, fileBuf []byte)
 		triggerList := &scenario.EditingContent.Skills["mainSkill"].TriggerList
 		*triggerList = append(*triggerList, trigger)
 
-		// register an intent to intent engine 1.0
-		triggerPhrases, err := parseTriggerPhrase(sheet)
-		if err != nil {
-			return nil, err
-		}
-		err = UpdateIntentV1(appID, scenarioName, triggerPhrases)
-		if err != nil {
-			return nil, err
-		}
+		triggerPhrases, err = parseTriggerPhrase(sheet)
+		rowErrs = append(rowErrs, importpreview.ToRowErrors(err)...)
 	}
 
 	// parse triggier intents 2.0 and assign to scenario
 	sheet = xlFile.Sheet[SheetName["triggerIntent"]]
 	if sheet != nil {
 		triggerList, err := parseTriggerIntent(sheet)
-		if err != nil {
-			return nil, err
-		}
+		rowErrs = append(rowErrs, importpreview.ToRowErrors(err)...)
 		scenario.EditingContent.Skills["mainSkill"].TriggerList = triggerList
 	}
 
@@ -55,9 +70,7 @@ func ParseUploadSpreadsheet(appID string, scenarioString string, fileBuf []byte)
 	sheet = xlFile.Sheet[SheetName["entityCollecting"]]
 	if sheet != nil {
 		entityList, err := parseEntity(sheet)
-		if err != nil {
-			return nil, err
-		}
+		rowErrs = append(rowErrs, importpreview.ToRowErrors(err)...)
 		scenario.EditingContent.Skills["mainSkill"].EntityCollectorList = entityList
 	}
 
@@ -65,9 +78,7 @@ func ParseUploadSpreadsheet(appID string, scenarioString string, fileBuf []byte)
 	sheet = xlFile.Sheet[SheetName["responseMessage"]]
 	if sheet != nil {
 		actionGroupList, err := parseMsgAction(sheet)
-		if err != nil {
-			return nil, err
-		}
+		rowErrs = append(rowErrs, importpreview.ToRowErrors(err)...)
 		scenario.EditingContent.Skills["mainSkill"].ActionGroupList = actionGroupList
 	}
 
@@ -75,46 +86,51 @@ func ParseUploadSpreadsheet(appID string, scenarioString string, fileBuf []byte)
 	sheet = xlFile.Sheet[SheetName["nerMap"]]
 	if sheet != nil {
 		nerMap, err := parseNerMap(sheet)
-		if err != nil {
-			return nil, err
-		}
+		rowErrs = append(rowErrs, importpreview.ToRowErrors(err)...)
 		scenario.EditingContent.IDToNerMap = nerMap
 	}
 
-	return &scenario, err
+	if len(rowErrs) > 0 {
+		return nil, nil, rowErrs
+	}
+	return scenario, triggerPhrases, nil
 }
 
 func parseTriggerPhrase(sheet *xlsx.Sheet) ([]string, error) {
 	phrases := make([]string, 0)
 	sheetPhrase := new(SpreadsheetTrigger)
 	if sheet.MaxRow == 0 {
-		return phrases, errors.New("Missing trigger phrases")
+		return phrases, &importpreview.RowError{Sheet: sheet.Name, Message: "Missing trigger phrases"}
 	}
+	rowErrs := importpreview.RowErrors{}
 	for i := 0; i < sheet.MaxRow; i++ {
 		err := sheet.Rows[i].ReadStruct(sheetPhrase)
 		if err != nil {
-			return nil, err
+			rowErrs.Add(sheet.Name, i+1, "", err.Error())
+			continue
 		}
 		phrases = append(phrases, sheetPhrase.Phrase)
 	}
-	return phrases, nil
+	return phrases, rowErrs.Err()
 }
 
 func parseTriggerIntent(sheet *xlsx.Sheet) ([]*Trigger, error) {
 	triggerList := make([]*Trigger, 0)
 	sheetIntent := new(SpreadsheetTriggerIntent)
 	if sheet.MaxRow == 0 {
-		return triggerList, errors.New("Missing trigger intents")
+		return triggerList, &importpreview.RowError{Sheet: sheet.Name, Message: "Missing trigger intents"}
 	}
+	rowErrs := importpreview.RowErrors{}
 	for i := 0; i < sheet.MaxRow; i++ {
 		err := sheet.Rows[i].ReadStruct(sheetIntent)
 		if err != nil {
-			return nil, err
+			rowErrs.Add(sheet.Name, i+1, "", err.Error())
+			continue
 		}
 		trigger := createDefaultTrigger(sheetIntent.Intent, "intent_engine_2.0")
 		triggerList = append(triggerList, trigger)
 	}
-	return triggerList, nil
+	return triggerList, rowErrs.Err()
 }
 
 func createDefaultTrigger(intentName string, intentType string) *Trigger {
@@ -133,27 +149,31 @@ func parseEntity(sheet *xlsx.Sheet) ([]*Entity, error) {
 		// skip entity collecting
 		return nil, nil
 	}
+	rowErrs := importpreview.RowErrors{}
 	for i := 1; i < sheet.MaxRow; i++ {
 		err := sheet.Rows[i].ReadStruct(sheetEntity)
 		if err != nil {
-			return nil, err
+			rowErrs.Add(sheet.Name, i+1, "", err.Error())
+			continue
 		}
 		entity := sheetEntity.ToEntity()
 		entities = append(entities, &entity)
 	}
-	return entities, nil
+	return entities, rowErrs.Err()
 }
 
 func parseMsgAction(sheet *xlsx.Sheet) ([]*ActionGroup, error) {
 	actionGroupList := make([]*ActionGroup, 0)
 	sheetMsgAction := new(SpreadsheetMsgAction)
 	if sheet.MaxRow == 0 {
-		return nil, errors.New("Missing response message")
+		return nil, &importpreview.RowError{Sheet: sheet.Name, Message: "Missing response message"}
 	}
+	rowErrs := importpreview.RowErrors{}
 	for i := 0; i < sheet.MaxRow; i++ {
 		err := sheet.Rows[i].ReadStruct(sheetMsgAction)
 		if err != nil {
-			return nil, err
+			rowErrs.Add(sheet.Name, i+1, "", err.Error())
+			continue
 		}
 		action := Action{
 			Type: "msg",
@@ -167,7 +187,7 @@ func parseMsgAction(sheet *xlsx.Sheet) ([]*ActionGroup, error) {
 
 		actionGroupList = append(actionGroupList, &actionGroup)
 	}
-	return actionGroupList, nil
+	return actionGroupList, rowErrs.Err()
 }
 
 func parseNerMap(sheet *xlsx.Sheet) (map[string]*CustomNer, error) {
@@ -180,6 +200,7 @@ func parseNerMap(sheet *xlsx.Sheet) (map[string]*CustomNer, error) {
 		return nil, nil
 	}
 
+	rowErrs := importpreview.RowErrors{}
 	for i := 1; i < sheet.MaxRow; i++ {
 		if empty := isEmptyRow(sheet.Rows[i]); empty == true {
 			// skip empty row
@@ -188,7 +209,8 @@ func parseNerMap(sheet *xlsx.Sheet) (map[string]*CustomNer, error) {
 
 		err := sheet.Rows[i].ReadStruct(wordBankRow)
 		if err != nil {
-			return nil, err
+			rowErrs.Add(sheet.Name, i+1, "", err.Error())
+			continue
 		}
 		entitySynonyms := &EntitySynonyms{
 			Entity:   wordBankRow.Name,
@@ -205,6 +227,10 @@ func parseNerMap(sheet *xlsx.Sheet) (map[string]*CustomNer, error) {
 		pathToEntitySynonymsList[path] = append(pathToEntitySynonymsList[path], entitySynonyms)
 	}
 
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
 	for k, v := range pathToEntitySynonymsList {
 		customNer := newCustomNer()
 		customNer.EntityType = k
@@ -186,7 +186,7 @@ func handleDeleteLogo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := util.ConsulDeleteLogo(enterprise, iconType)
+	err := util.ConsulDeleteLogo(enterprise, iconType, util.NewConsulChange(r, "delete logo"))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
@@ -221,7 +221,7 @@ func handleUploadLogo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = util.ConsulUpdateLogo(enterprise, iconType, content)
+	err = util.ConsulUpdateLogo(enterprise, iconType, content, util.NewConsulChange(r, "upload logo"))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
@@ -0,0 +1,203 @@
+package clustering
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	statDataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	statServiceCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
+	statServiceV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	dac "emotibot.com/emotigo/pkg/api/dac/v1"
+	"emotibot.com/emotigo/pkg/api/dal/v1"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//NewClusterActionHandler create a http.Handler for taking action on a cluster of completed report.
+//new_question create the question as standard question, similar_question add contents to an existed standard question,
+//and the clustered records will be marked. ignore will set the clustered records as ignored.
+func NewClusterActionHandler(rs ReportsService, cs ReportClustersService, rrs ReportRecordsService, as ClusterActionsService, dalClient *dal.Client, dacClient *dac.Client) http.HandlerFunc {
+	type request struct {
+		Type     ClusterActionType `json:"type"`
+		Question string            `json:"question"`
+		//Contents is optional, only the given contents of the cluster are applied if it is set.
+		Contents []string `json:"contents"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		appid := requestheader.GetAppID(r)
+		userID := requestheader.GetUserID(r)
+		reportID, err := strconv.ParseUint(util.GetMuxVar(r, "id"), 10, 64)
+		if err != nil {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "id is invalid"), nil)
+			return
+		}
+		clusterID, err := strconv.ParseUint(util.GetMuxVar(r, "cid"), 10, 64)
+		if err != nil || clusterID == nonClusterID {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "cluster id is invalid"), nil)
+			return
+		}
+		var req request
+		err = json.NewDecoder(r.Body).Decode(&req)
+		defer r.Body.Close()
+		if err != nil {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "input format error"), nil)
+			return
+		}
+		switch req.Type {
+		case ClusterActionNewQuestion, ClusterActionSimilarQuestion:
+			if req.Question == "" {
+				util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "question is required"), nil)
+				return
+			}
+		case ClusterActionIgnore:
+			req.Question = ""
+		default:
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "type is invalid"), nil)
+			return
+		}
+
+		report, err := rs.GetReport(reportID)
+		if err != nil {
+			logger.Error.Printf("get report failed, %v\n", err)
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, "internal server error"), nil)
+			return
+		}
+		if report.AppID != appid {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "permission denied"), nil)
+			return
+		}
+		if report.Status != ReportStatusCompleted {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "report is not completed"), nil)
+			return
+		}
+		c, err := cs.GetCluster(clusterID)
+		if err != nil || c.ReportID != report.ID {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "cluster id is invalid"), nil)
+			return
+		}
+		records, err := rrs.GetRecords(report.ID)
+		if err != nil {
+			logger.Error.Println("get records failed, " + err.Error())
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, "internal server error"), nil)
+			return
+		}
+		contents, recordIDs := clusterContents(records, clusterID, req.Contents)
+		if len(contents) == 0 {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "no contents in cluster"), nil)
+			return
+		}
+
+		if req.Type != ClusterActionIgnore {
+			questions, err := dacClient.GetQuestionsMap(appid)
+			if err != nil {
+				logger.Error.Printf("Failed to get sq, %v", err)
+				util.Return(w, AdminErrors.New(AdminErrors.ErrnoAPIError, "internal server error"), nil)
+				return
+			}
+			_, existed := questions[req.Question]
+			if req.Type == ClusterActionNewQuestion && existed {
+				util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "question is already a standard question"), nil)
+				return
+			}
+			if req.Type == ClusterActionSimilarQuestion && !existed {
+				util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "question is not a standard question"), nil)
+				return
+			}
+			if req.Type == ClusterActionNewQuestion {
+				if err = dalClient.SetStandardQuestions(appid, req.Question); err != nil {
+					logger.Error.Printf("set sq failed, %v\n", err)
+					util.Return(w, AdminErrors.New(AdminErrors.ErrnoAPIError, "internal server error"), nil)
+					return
+				}
+			}
+			var lqs = []string{}
+			for _, content := range contents {
+				if _, isSQ := questions[content]; isSQ || content == req.Question {
+					continue
+				}
+				lqs = append(lqs, content)
+			}
+			if len(lqs) > 0 {
+				if err = dacClient.SetSimilarQuestionWithUser(appid, userID, req.Question, lqs...); err != nil {
+					logger.Error.Printf("set lq failed, %v\n", err)
+					util.Return(w, AdminErrors.New(AdminErrors.ErrnoAPIError, "internal server error"), nil)
+					return
+				}
+			}
+		}
+
+		//the action is already applied, so failing to update the records is not returned as error.
+		query := statDataV1.RecordQuery{
+			AppID:   appid,
+			Records: recordIDs,
+		}
+		var cmd statServiceCommon.UpdateCommand
+		if req.Type == ClusterActionIgnore {
+			cmd = statServiceV1.UpdateRecordIgnore(true)
+		} else {
+			cmd = statServiceV1.UpdateRecordMark(true)
+		}
+		if err = statServiceV1.UpdateRecords(query, cmd); err != nil {
+			logger.Warn.Printf("update records of cluster %d failed, %v\n", clusterID, err)
+		}
+
+		action := ClusterAction{
+			AppID:       appid,
+			ReportID:    report.ID,
+			ClusterID:   clusterID,
+			Type:        req.Type,
+			Question:    req.Question,
+			Contents:    contents,
+			UserID:      userID,
+			CreatedTime: time.Now().Unix(),
+		}
+		action.ID, err = as.NewClusterAction(action)
+		if err != nil {
+			logger.Error.Printf("new cluster action failed, %v\n", err)
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, "internal server error"), nil)
+			return
+		}
+		util.WriteJSON(w, action)
+	}
+}
+
+//NewGetClusterActionsHandler create a http.Handler for listing cluster actions and their results of the app
+func NewGetClusterActionsHandler(as ClusterActionsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actions, err := as.GetClusterActions(requestheader.GetAppID(r))
+		if err != nil {
+			logger.Error.Printf("get cluster actions failed, %v\n", err)
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, "internal server error"), nil)
+			return
+		}
+		util.WriteJSON(w, actions)
+	}
+}
+
+//clusterContents return the distinct contents & chat record ids of the cluster.
+//If selected is not empty, only the records with selected contents are returned.
+func clusterContents(records []ReportRecord, clusterID uint64, selected []string) ([]string, []interface{}) {
+	var isSelected = map[string]bool{}
+	for _, s := range selected {
+		isSelected[s] = true
+	}
+	var contents = []string{}
+	var recordIDs = []interface{}{}
+	var found = map[string]bool{}
+	for _, rc := range records {
+		if rc.ClusterID != clusterID || (len(selected) > 0 && !isSelected[rc.Content]) {
+			continue
+		}
+		recordIDs = append(recordIDs, rc.ChatRecordID)
+		if found[rc.Content] {
+			continue
+		}
+		found[rc.Content] = true
+		contents = append(contents, rc.Content)
+	}
+	return contents, recordIDs
+}
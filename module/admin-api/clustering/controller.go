@@ -88,6 +88,16 @@ func Init() error {
 
 			util.NewEntryPointWithVer(http.MethodPut, "reports", []string{}, NewDoReportHandlerV2(ss, ss, ss, ssfaq, clusterClient, dacClient), 2),
 			util.NewEntryPointWithVer(http.MethodGet, "reports/{id}", []string{}, NewGetReportHandler(ss, ss, ss), 2),
+			util.NewEntryPointWithVer(http.MethodPost, "reports/{id}/clusters/{cid}/actions", []string{}, NewClusterActionHandler(ss, ss, ss, ss, dalClient, dacClient), 2),
+			util.NewEntryPointWithVer(http.MethodGet, "actions", []string{}, NewGetClusterActionsHandler(ss), 2),
+			util.NewEntryPointWithVer(http.MethodGet, "schedule", []string{}, NewGetScheduleHandler(ss), 2),
+			util.NewEntryPointWithVer(http.MethodPut, "schedule", []string{}, NewSetScheduleHandler(ss), 2),
+		},
+		Cronjobs: map[string]util.CronTask{
+			"scheduled_reports": util.CronTask{
+				Period:  scheduleCronPeriod,
+				Handler: newScheduledReportsJob(ss, ss, ss, ssfaq, dacClient),
+			},
 		},
 	}
 	worker = newClusteringWork(ss, ss, ss, clusterClient)
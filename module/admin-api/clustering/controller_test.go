@@ -96,3 +96,20 @@ func TestNewClusteringWorkWithSQLService(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClusterContents(t *testing.T) {
+	records := []ReportRecord{
+		ReportRecord{ClusterID: 1, ChatRecordID: "a", Content: "怎么办信用卡"},
+		ReportRecord{ClusterID: 1, ChatRecordID: "b", Content: "怎么办信用卡"},
+		ReportRecord{ClusterID: 1, ChatRecordID: "c", Content: "信用卡怎么办"},
+		ReportRecord{ClusterID: 2, ChatRecordID: "d", Content: "信用卡额度"},
+	}
+	contents, ids := clusterContents(records, 1, nil)
+	if len(contents) != 2 || len(ids) != 3 {
+		t.Fatalf("expect 2 distinct contents of 3 records but got %v, %v", contents, ids)
+	}
+	contents, ids = clusterContents(records, 1, []string{"信用卡怎么办", "信用卡额度"})
+	if len(contents) != 1 || len(ids) != 1 || ids[0] != "c" {
+		t.Fatalf("expect only selected content of cluster but got %v, %v", contents, ids)
+	}
+}
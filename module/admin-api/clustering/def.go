@@ -102,3 +102,48 @@ type ssmParameters struct {
 	Data      string `json:"data,omitempty"`
 	Model     string `json:"model,omitempty"`
 }
+
+//ReportSchedule is the setting of scheduled clustering of an app.
+//It is an one to one mapping of the RDB table `report_schedules`
+type ReportSchedule struct {
+	AppID   string `json:"-"`
+	Enabled bool   `json:"enabled"`
+	//Period is the seconds between two scheduled reports
+	Period int64 `json:"period"`
+	//ScoreThreshold is the minimum score of answered records, records with lower score are treated as low confidence.
+	ScoreThreshold float64 `json:"score_threshold"`
+	LastRunTime    int64   `json:"last_run_time"`
+	UpdatedTime    int64   `json:"updated_time"`
+}
+
+//ClusterActionType is the type of follow-up action of a cluster
+type ClusterActionType string
+
+//defined type of cluster actions
+const (
+	//ClusterActionNewQuestion create a new standard question, and cluster contents as its similar questions
+	ClusterActionNewQuestion ClusterActionType = "new_question"
+	//ClusterActionSimilarQuestion add cluster contents as similar questions of an existed standard question
+	ClusterActionSimilarQuestion ClusterActionType = "similar_question"
+	//ClusterActionIgnore ignore the cluster, contents will not be clustered by scheduled reports again
+	ClusterActionIgnore ClusterActionType = "ignore"
+)
+
+//ClusterAction is the action taken on a cluster. an one to one mapping to RDB `cluster_actions`
+type ClusterAction struct {
+	ID        uint64            `json:"id"`
+	AppID     string            `json:"-"`
+	ReportID  uint64            `json:"report_id"`
+	ClusterID uint64            `json:"cluster_id"`
+	Type      ClusterActionType `json:"type"`
+	//Question is the standard question of the action, empty if type is ignore
+	Question string `json:"question"`
+	//Contents is the cluster contents which the action applied to
+	Contents    []string `json:"contents"`
+	UserID      string   `json:"user_id"`
+	CreatedTime int64    `json:"created_time"`
+	//HitCount is how many later user questions matched the contents of the action
+	HitCount int64 `json:"hit_count"`
+	//ResolvedCount is how many of the hit user questions are answered by the question of the action
+	ResolvedCount int64 `json:"resolved_count"`
+}
@@ -0,0 +1,339 @@
+package clustering
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	statDataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	statServiceV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	dac "emotibot.com/emotigo/pkg/api/dac/v1"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+const (
+	//scheduleUserID is the user id of reports created by schedule
+	scheduleUserID = "scheduler"
+	//scheduleCronPeriod is how often the due schedules are checked
+	scheduleCronPeriod = "@every 10m"
+	//minSchedulePeriod is the minimum seconds between two scheduled reports
+	minSchedulePeriod int64 = 3600
+	//defaultSchedulePeriod is one day
+	defaultSchedulePeriod int64 = 86400
+	//defaultScoreThreshold is percentage, same as score of records
+	defaultScoreThreshold = 80
+	//recordLogTimeFormat is the format of VisitRecordsData.LogTime in local time
+	recordLogTimeFormat = "2006-01-02 15:04:05"
+	//recordsPageLimit is the limitation of records in one query of elastic search
+	recordsPageLimit = 10000
+)
+
+//NewGetScheduleHandler create a http.Handler for getting schedule setting of the app
+func NewGetScheduleHandler(ss ReportSchedulesService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appid := requestheader.GetAppID(r)
+		schedule, err := ss.GetSchedule(appid)
+		if err == sql.ErrNoRows {
+			schedule = ReportSchedule{
+				AppID:          appid,
+				Period:         defaultSchedulePeriod,
+				ScoreThreshold: defaultScoreThreshold,
+			}
+		} else if err != nil {
+			logger.Error.Printf("get schedule failed, %v\n", err)
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, "internal server error"), nil)
+			return
+		}
+		util.WriteJSON(w, schedule)
+	}
+}
+
+//NewSetScheduleHandler create a http.Handler for updating schedule setting of the app
+func NewSetScheduleHandler(ss ReportSchedulesService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var schedule ReportSchedule
+		err := json.NewDecoder(r.Body).Decode(&schedule)
+		defer r.Body.Close()
+		if err != nil {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "input format error"), nil)
+			return
+		}
+		if schedule.Period < minSchedulePeriod {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("period should be at least %d seconds", minSchedulePeriod)), nil)
+			return
+		}
+		if schedule.ScoreThreshold < 0 || schedule.ScoreThreshold > 100 {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, "score_threshold should be between 0 and 100"), nil)
+			return
+		}
+		schedule.AppID = requestheader.GetAppID(r)
+		schedule.LastRunTime = 0
+		schedule.UpdatedTime = time.Now().Unix()
+		if err = ss.SetSchedule(schedule); err != nil {
+			logger.Error.Printf("set schedule failed, %v\n", err)
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoDBError, "internal server error"), nil)
+			return
+		}
+		util.WriteJSON(w, schedule)
+	}
+}
+
+//newScheduledReportsJob create the cron job which run the due schedules.
+func newScheduledReportsJob(rs ReportsService, ss ReportSchedulesService, as ClusterActionsService, simpleFTService SimpleFTService, dacClient *dac.Client) func() {
+	return func() {
+		now := time.Now().Unix()
+		schedules, err := ss.DueSchedules(now)
+		if err != nil {
+			logger.Error.Printf("get due schedules failed, %v\n", err)
+			return
+		}
+		for _, schedule := range schedules {
+			err = runSchedule(rs, ss, as, simpleFTService, dacClient, schedule, now)
+			if err != nil {
+				logger.Error.Printf("scheduled report of %s failed, %v\n", schedule.AppID, err)
+			}
+		}
+	}
+}
+
+//runSchedule fetch the records since last run, count the results of cluster actions,
+//and cluster the unanswered or low confidence user questions.
+func runSchedule(rs ReportsService, ss ReportSchedulesService, as ClusterActionsService, simpleFTService SimpleFTService, dacClient *dac.Client, schedule ReportSchedule, now int64) error {
+	appid := schedule.AppID
+	//update run time first, so a failed schedule will not be retried until next period.
+	//It is compared with the last run time, so only one instance runs the schedule.
+	claimed, err := ss.UpdateScheduleRunTime(appid, schedule.LastRunTime, now)
+	if err != nil {
+		return err
+	} else if !claimed {
+		logger.Trace.Printf("schedule of %s has been run by others\n", appid)
+		return nil
+	}
+	start := schedule.LastRunTime
+	if start == 0 {
+		start = now - schedule.Period
+	}
+	query := statDataV1.RecordQuery{
+		AppID:     appid,
+		StartTime: &start,
+		EndTime:   &now,
+	}
+	records, err := fetchWindowRecords(query)
+	if err != nil {
+		return fmt.Errorf("get records failed, %v", err)
+	}
+
+	actions, err := as.GetClusterActions(appid)
+	if err != nil {
+		return fmt.Errorf("get cluster actions failed, %v", err)
+	}
+	for id, count := range countActionResults(actions, records) {
+		if err = as.AddClusterActionCounts(id, count.hit, count.resolved); err != nil {
+			logger.Error.Printf("update counts of cluster action %d failed, %v\n", id, err)
+		}
+	}
+
+	s := int(ReportStatusRunning)
+	thirtyMinAgo := now - 1800
+	reports, err := rs.QueryReports(ReportQuery{
+		AppID: appid,
+		UpdatedTime: &searchPeriod{
+			StartTime: &thirtyMinAgo,
+			EndTime:   &now,
+		},
+		Status: &s,
+	})
+	if err != nil {
+		return err
+	}
+	if len(reports) > 0 {
+		logger.Info.Printf("skip scheduled report of %s, there is a running report\n", appid)
+		return nil
+	}
+
+	questions, err := dacClient.GetQuestionsMap(appid)
+	if err != nil {
+		return fmt.Errorf("get sq failed, %v", err)
+	}
+	threshold := schedule.ScoreThreshold
+	if threshold == 0 {
+		threshold = defaultScoreThreshold
+	}
+	inputs, newReport := selectScheduledInputs(records, questions, ignoredContents(actions), threshold)
+	if len(inputs) == 0 {
+		logger.Trace.Printf("no records to cluster for scheduled report of %s\n", appid)
+		return nil
+	}
+
+	//create the report first, so errors below are recorded on it
+	rawQuery, _ := json.Marshal(query)
+	newReport.CreatedTime = time.Now().Unix()
+	newReport.UpdatedTime = newReport.CreatedTime
+	newReport.Condition = string(rawQuery)
+	newReport.UserID = scheduleUserID
+	newReport.AppID = appid
+	newReport.Status = ReportStatusRunning
+	id, err := rs.NewReport(newReport)
+	if err != nil {
+		return err
+	}
+
+	model, err := simpleFTService.GetFTModel(appid)
+	if err == sql.ErrNoRows {
+		newReportError(rs, "bad request, need train model before use", id)
+		return err
+	}
+	if err != nil {
+		newReportError(rs, "Failed to get simpleFT model, "+err.Error(), id)
+		return err
+	}
+
+	var paramas = map[string]interface{}{
+		"model_version": model,
+	}
+	return worker(id, paramas, inputs)
+}
+
+//visitRecordsQuery is the query of records, which is replaced in tests
+var visitRecordsQuery = statServiceV1.VisitRecordsQuery
+
+//fetchWindowRecords fetch all records between StartTime and EndTime of query.
+//Elastic search returns at most recordsPageLimit records from the latest one,
+//so the window is shrunk to the oldest fetched record until all records are fetched.
+func fetchWindowRecords(query statDataV1.RecordQuery) ([]*statDataV1.VisitRecordsData, error) {
+	start, end := *query.StartTime, *query.EndTime
+	records := []*statDataV1.VisitRecordsData{}
+	fetched := map[string]bool{}
+	for {
+		query.StartTime, query.EndTime = &start, &end
+		query.Limit = recordsPageLimit
+		result, err := visitRecordsQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range result.Hits {
+			if !fetched[h.UniqueID] {
+				fetched[h.UniqueID] = true
+				records = append(records, h)
+			}
+		}
+		total, _ := result.Aggs["total_size"].(int64)
+		if len(result.Hits) == 0 || int64(len(result.Hits)) >= total {
+			return records, nil
+		}
+
+		oldest, err := time.ParseInLocation(recordLogTimeFormat, result.Hits[len(result.Hits)-1].LogTime, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("parse log time failed, %v", err)
+		}
+		if oldest.Unix() < end {
+			end = oldest.Unix()
+		} else {
+			//records of the same second exceed the page, skip the rest of that second
+			logger.Warn.Printf("more than %d records of %s at %d, some are not fetched\n", recordsPageLimit, query.AppID, end)
+			end--
+		}
+		if end < start {
+			return records, nil
+		}
+	}
+}
+
+//isLowConfidence determine the record is not answered by a standard question, or the score is lower than threshold.
+func isLowConfidence(record *statDataV1.VisitRecordsData, threshold float64) bool {
+	return record.StdQ == "" || record.Score < threshold
+}
+
+func normalizeContent(content string) string {
+	return strings.ToLower(strings.TrimSpace(content))
+}
+
+//ignoredContents collect contents of the ignore actions
+func ignoredContents(actions []ClusterAction) map[string]bool {
+	ignored := map[string]bool{}
+	for _, a := range actions {
+		if a.Type != ClusterActionIgnore {
+			continue
+		}
+		for _, c := range a.Contents {
+			ignored[normalizeContent(c)] = true
+		}
+	}
+	return ignored
+}
+
+//selectScheduledInputs return the clustering inputs of records, and a report filled with sizes of skipped records.
+//Marked, ignored, answered with confidence, standard question and contents of ignore actions are not included.
+func selectScheduledInputs(records []*statDataV1.VisitRecordsData, questions map[string]string, ignored map[string]bool, threshold float64) ([]interface{}, Report) {
+	var report Report
+	var inputs = []interface{}{}
+	for _, h := range records {
+		if h.IsMarked {
+			report.MarkedSize++
+			continue
+		}
+		if h.IsIgnored || ignored[normalizeContent(h.UserQ)] {
+			report.IgnoredSize++
+			continue
+		}
+		if !isLowConfidence(h, threshold) {
+			continue
+		}
+		if _, ok := questions[h.UserQ]; ok {
+			report.SkippedSize++
+			continue
+		}
+		inputs = append(inputs, map[string]string{
+			"id":    h.UniqueID,
+			"value": h.UserQ,
+		})
+	}
+	return inputs, report
+}
+
+type actionCount struct {
+	hit      int64
+	resolved int64
+}
+
+//countActionResults count records asked after the action is taken.
+//A record is hit if its content is one of the action's contents or the action's question,
+//and is resolved if it is answered by the action's question.
+func countActionResults(actions []ClusterAction, records []*statDataV1.VisitRecordsData) map[uint64]actionCount {
+	counts := map[uint64]actionCount{}
+	contents := make([]map[string]bool, len(actions))
+	for i, a := range actions {
+		contents[i] = map[string]bool{}
+		for _, c := range a.Contents {
+			contents[i][normalizeContent(c)] = true
+		}
+		if a.Question != "" {
+			contents[i][normalizeContent(a.Question)] = true
+		}
+	}
+	for _, h := range records {
+		logTime, err := time.ParseInLocation(recordLogTimeFormat, h.LogTime, time.Local)
+		if err != nil {
+			continue
+		}
+		userQ := normalizeContent(h.UserQ)
+		for i, a := range actions {
+			if logTime.Unix() < a.CreatedTime || !contents[i][userQ] {
+				continue
+			}
+			count := counts[a.ID]
+			count.hit++
+			if a.Question != "" && h.StdQ == a.Question {
+				count.resolved++
+			}
+			counts[a.ID] = count
+		}
+	}
+	return counts
+}
@@ -0,0 +1,116 @@
+package clustering
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	statDataV1 "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	servicesCommon "emotibot.com/emotigo/module/admin-api/ELKStats/services/common"
+	statServiceV1 "emotibot.com/emotigo/module/admin-api/ELKStats/services/v1"
+)
+
+func newTestRecord(id, userQ, stdQ string, score float64, logTime time.Time) *statDataV1.VisitRecordsData {
+	return &statDataV1.VisitRecordsData{
+		VisitRecordsDataBase: statDataV1.VisitRecordsDataBase{
+			UserQ:   userQ,
+			StdQ:    stdQ,
+			Score:   score,
+			LogTime: logTime.Format(recordLogTimeFormat),
+		},
+		UniqueID: id,
+	}
+}
+
+func TestSelectScheduledInputs(t *testing.T) {
+	now := time.Now()
+	records := []*statDataV1.VisitRecordsData{
+		newTestRecord("1", "怎么办信用卡", "", 0, now),
+		newTestRecord("2", "信用卡额度", "信用卡额度", 95, now),
+		newTestRecord("3", "信用卡额度多少", "信用卡额度", 60, now),
+		newTestRecord("4", "如何办理信用卡", "", 0, now),
+		newTestRecord("5", "不要这个", "", 0, now),
+		newTestRecord("6", "已经标注", "", 0, now),
+	}
+	records[5].IsMarked = true
+	questions := map[string]string{"如何办理信用卡": ""}
+	ignored := map[string]bool{"不要这个": true}
+
+	inputs, report := selectScheduledInputs(records, questions, ignored, 80)
+	if len(inputs) != 2 {
+		t.Fatalf("expect 2 inputs but got %v", inputs)
+	}
+	if id := inputs[1].(map[string]string)["id"]; id != "3" {
+		t.Fatalf("expect low confidence record 3 to be clustered but got %s", id)
+	}
+	if report.SkippedSize != 1 || report.IgnoredSize != 1 || report.MarkedSize != 1 {
+		t.Fatalf("expect skipped, ignored and marked size to be 1 but got %+v", report)
+	}
+}
+
+func TestCountActionResults(t *testing.T) {
+	now := time.Now()
+	actions := []ClusterAction{
+		ClusterAction{ID: 1, Type: ClusterActionSimilarQuestion, Question: "如何办理信用卡", Contents: []string{"怎么办信用卡"}, CreatedTime: now.Add(-time.Hour).Unix()},
+		ClusterAction{ID: 2, Type: ClusterActionIgnore, Contents: []string{"不要这个"}, CreatedTime: now.Add(-time.Hour).Unix()},
+		ClusterAction{ID: 3, Type: ClusterActionNewQuestion, Question: "信用卡额度", Contents: []string{"额度多少"}, CreatedTime: now.Add(time.Hour).Unix()},
+	}
+	records := []*statDataV1.VisitRecordsData{
+		newTestRecord("1", "怎么办信用卡", "如何办理信用卡", 90, now),
+		newTestRecord("2", " 怎么办信用卡", "", 0, now),
+		newTestRecord("3", "不要这个", "", 0, now),
+		newTestRecord("4", "额度多少", "信用卡额度", 90, now),
+		newTestRecord("5", "怎么办信用卡", "如何办理信用卡", 90, now.Add(-2*time.Hour)),
+	}
+
+	counts := countActionResults(actions, records)
+	if c := counts[1]; c.hit != 2 || c.resolved != 1 {
+		t.Fatalf("expect action 1 hit 2 and resolved 1 but got %+v", c)
+	}
+	if c := counts[2]; c.hit != 1 || c.resolved != 0 {
+		t.Fatalf("expect action 2 hit 1 and resolved 0 but got %+v", c)
+	}
+	if _, found := counts[3]; found {
+		t.Fatalf("expect records before action 3 not counted but got %+v", counts[3])
+	}
+}
+
+func TestFetchWindowRecords(t *testing.T) {
+	defer func() { visitRecordsQuery = statServiceV1.VisitRecordsQuery }()
+
+	//records of every second, the latest first, same as elastic search
+	start := time.Now().Add(-time.Hour * 24).Unix()
+	size := recordsPageLimit*2 + 500
+	all := []*statDataV1.VisitRecordsData{}
+	for i := size - 1; i >= 0; i-- {
+		all = append(all, newTestRecord(fmt.Sprint(i), "q", "", 0, time.Unix(start+int64(i), 0)))
+	}
+	queries := 0
+	visitRecordsQuery = func(query statDataV1.RecordQuery, _ ...servicesCommon.ElasticSearchCommand) (*statServiceV1.RecordResult, error) {
+		queries++
+		hits := []*statDataV1.VisitRecordsData{}
+		for idx, r := range all {
+			i := int64(size - 1 - idx)
+			if start+i >= *query.StartTime && start+i <= *query.EndTime {
+				hits = append(hits, r)
+			}
+		}
+		total := int64(len(hits))
+		if len(hits) > query.Limit {
+			hits = hits[:query.Limit]
+		}
+		return &statServiceV1.RecordResult{Hits: hits, Aggs: map[string]interface{}{"total_size": total}}, nil
+	}
+
+	end := start + int64(size) - 1
+	records, err := fetchWindowRecords(statDataV1.RecordQuery{AppID: "app", StartTime: &start, EndTime: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != size {
+		t.Fatalf("expect all %d records in window but got %d", size, len(records))
+	}
+	if queries != 3 {
+		t.Fatalf("expect 3 queries but got %d", queries)
+	}
+}
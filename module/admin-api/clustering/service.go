@@ -55,3 +55,22 @@ func newReportErrorWithStatus(s ReportsService, errMsg string, id uint64, status
 	}
 	s.NewReportError(err)
 }
+
+//ReportSchedulesService define the operations of the scheduled reports.
+type ReportSchedulesService interface {
+	GetSchedule(appID string) (ReportSchedule, error)
+	SetSchedule(schedule ReportSchedule) error
+	//DueSchedules retrive the enabled schedules which should be run at given time
+	DueSchedules(now int64) ([]ReportSchedule, error)
+	//UpdateScheduleRunTime set the run time only if last run time is still lastRunTime,
+	//false is returned if the schedule has been run by others.
+	UpdateScheduleRunTime(appID string, lastRunTime, runTime int64) (bool, error)
+}
+
+//ClusterActionsService define the operations of the cluster's actions.
+type ClusterActionsService interface {
+	NewClusterAction(action ClusterAction) (uint64, error)
+	GetClusterActions(appID string) ([]ClusterAction, error)
+	//AddClusterActionCounts increase the hit & resolved counts of an action
+	AddClusterActionCounts(id uint64, hit, resolved int64) error
+}
@@ -230,3 +230,99 @@ func (s *sqlService) GetFTModel(appID string) (string, error) {
 
 	return p.Model, nil
 }
+
+func (s *sqlService) GetSchedule(appID string) (ReportSchedule, error) {
+	query := "SELECT `app_id`, `enabled`, `period`, `score_threshold`, `last_run_time`, `updated_time` FROM `report_schedules` WHERE `app_id` = ?"
+	var rs ReportSchedule
+	err := s.db.QueryRow(query, appID).Scan(&rs.AppID, &rs.Enabled, &rs.Period, &rs.ScoreThreshold, &rs.LastRunTime, &rs.UpdatedTime)
+	if err == sql.ErrNoRows {
+		return rs, err
+	}
+	if err != nil {
+		return rs, fmt.Errorf("sql query row failed, %v", err)
+	}
+	return rs, nil
+}
+
+func (s *sqlService) SetSchedule(schedule ReportSchedule) error {
+	query := "INSERT INTO `report_schedules`(`app_id`, `enabled`, `period`, `score_threshold`, `last_run_time`, `updated_time`) VALUE(?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE `enabled` = VALUES(`enabled`), `period` = VALUES(`period`), `score_threshold` = VALUES(`score_threshold`), `updated_time` = VALUES(`updated_time`)"
+	_, err := s.db.Exec(query, schedule.AppID, schedule.Enabled, schedule.Period, schedule.ScoreThreshold, schedule.LastRunTime, schedule.UpdatedTime)
+	if err != nil {
+		return fmt.Errorf("exec sql failed, %v", err)
+	}
+	return nil
+}
+
+func (s *sqlService) DueSchedules(now int64) ([]ReportSchedule, error) {
+	query := "SELECT `app_id`, `enabled`, `period`, `score_threshold`, `last_run_time`, `updated_time` FROM `report_schedules` WHERE `enabled` = 1 AND `last_run_time` + `period` <= ?"
+	rows, err := s.db.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed, %v", err)
+	}
+	defer rows.Close()
+	var schedules = []ReportSchedule{}
+	for rows.Next() {
+		var rs ReportSchedule
+		rows.Scan(&rs.AppID, &rs.Enabled, &rs.Period, &rs.ScoreThreshold, &rs.LastRunTime, &rs.UpdatedTime)
+		schedules = append(schedules, rs)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql scan failed, %v", err)
+	}
+	return schedules, nil
+}
+
+func (s *sqlService) UpdateScheduleRunTime(appID string, lastRunTime, runTime int64) (bool, error) {
+	query := "UPDATE `report_schedules` SET `last_run_time` = ? WHERE `app_id` = ? AND `last_run_time` = ?"
+	result, err := s.db.Exec(query, runTime, appID, lastRunTime)
+	if err != nil {
+		return false, fmt.Errorf("exec sql failed, %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get affected rows failed, %v", err)
+	}
+	return affected == 1, nil
+}
+
+func (s *sqlService) NewClusterAction(action ClusterAction) (uint64, error) {
+	contents, _ := json.Marshal(action.Contents)
+	query := "INSERT INTO `cluster_actions`(`app_id`, `report_id`, `cluster_id`, `type`, `question`, `contents`, `user_id`, `created_time`, `hit_count`, `resolved_count`) VALUE(?, ?, ?, ?, ?, ?, ?, ?, 0, 0)"
+	result, err := s.db.Exec(query, action.AppID, action.ReportID, action.ClusterID, action.Type, action.Question, string(contents), action.UserID, action.CreatedTime)
+	if err != nil {
+		return 0, fmt.Errorf("insert cluster action failed, %v", err)
+	}
+	id, err := result.LastInsertId()
+	return uint64(id), err
+}
+
+func (s *sqlService) GetClusterActions(appID string) ([]ClusterAction, error) {
+	query := "SELECT `id`, `app_id`, `report_id`, `cluster_id`, `type`, `question`, `contents`, `user_id`, `created_time`, `hit_count`, `resolved_count` FROM `cluster_actions` WHERE `app_id` = ? ORDER BY `id`"
+	rows, err := s.db.Query(query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("sql query failed, %v", err)
+	}
+	defer rows.Close()
+	var actions = []ClusterAction{}
+	for rows.Next() {
+		var a ClusterAction
+		var contents string
+		rows.Scan(&a.ID, &a.AppID, &a.ReportID, &a.ClusterID, &a.Type, &a.Question, &contents, &a.UserID, &a.CreatedTime, &a.HitCount, &a.ResolvedCount)
+		json.Unmarshal([]byte(contents), &a.Contents)
+		actions = append(actions, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql scan failed, %v", err)
+	}
+	return actions, nil
+}
+
+func (s *sqlService) AddClusterActionCounts(id uint64, hit, resolved int64) error {
+	query := "UPDATE `cluster_actions` SET `hit_count` = `hit_count` + ?, `resolved_count` = `resolved_count` + ? WHERE `id` = ?"
+	_, err := s.db.Exec(query, hit, resolved, id)
+	if err != nil {
+		return fmt.Errorf("exec sql failed, %v", err)
+	}
+	return nil
+}
@@ -152,3 +152,47 @@ func TestGetFTModel(t *testing.T) {
 		t.Fatalf("expect model name to be unknown_20180903222359 but got %s", modelName)
 	}
 }
+
+func TestGetClusterActions(t *testing.T) {
+	appID := "csbot"
+	db, writer, _ := sqlmock.New()
+	columns := []string{"id", "app_id", "report_id", "cluster_id", "type", "question", "contents", "user_id", "created_time", "hit_count", "resolved_count"}
+	writer.ExpectQuery("SELECT .+ FROM `cluster_actions`").WithArgs(appID).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, appID, 2, 3, "similar_question", "如何办理信用卡", `["怎么办信用卡","信用卡怎么办"]`, "user", 100, 5, 4))
+	var service = sqlService{
+		db: db,
+	}
+	actions, err := service.GetClusterActions(appID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || len(actions[0].Contents) != 2 || actions[0].Type != ClusterActionSimilarQuestion || actions[0].ResolvedCount != 4 {
+		t.Fatalf("expect one similar_question action with 2 contents but got %+v", actions)
+	}
+	if err = writer.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateScheduleRunTime(t *testing.T) {
+	appID := "csbot"
+	db, writer, _ := sqlmock.New()
+	writer.ExpectExec("UPDATE `report_schedules` SET `last_run_time` = \\? WHERE `app_id` = \\? AND `last_run_time` = \\?").
+		WithArgs(200, appID, 100).WillReturnResult(sqlmock.NewResult(0, 1))
+	writer.ExpectExec("UPDATE `report_schedules`").
+		WithArgs(200, appID, 100).WillReturnResult(sqlmock.NewResult(0, 0))
+	var service = sqlService{
+		db: db,
+	}
+	claimed, err := service.UpdateScheduleRunTime(appID, 100, 200)
+	if err != nil || !claimed {
+		t.Fatalf("expect schedule claimed but got %v, %v", claimed, err)
+	}
+	claimed, err = service.UpdateScheduleRunTime(appID, 100, 200)
+	if err != nil || claimed {
+		t.Fatalf("expect schedule run by others not claimed but got %v, %v", claimed, err)
+	}
+	if err = writer.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,24 @@
+ALTER TABLE `audit_record` ADD `prev_hash` CHAR(64) NOT NULL DEFAULT '', ADD `hash` CHAR(64) NOT NULL DEFAULT '', ADD INDEX `enterprise_id` (`enterprise`, `id`);
+
+CREATE TABLE `audit_archive_segment` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `enterprise` char(36) NOT NULL DEFAULT '',
+  `first_id` bigint(20) NOT NULL,
+  `last_id` bigint(20) NOT NULL,
+  `record_count` int(11) NOT NULL,
+  `first_prev_hash` char(64) NOT NULL DEFAULT '',
+  `last_hash` char(64) NOT NULL DEFAULT '',
+  `file_path` varchar(512) NOT NULL,
+  `file_hash` char(64) NOT NULL,
+  `created_time` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `enterprise_last_id` (`enterprise`, `last_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `audit_retention` (
+  `enterprise` char(36) NOT NULL,
+  `days` int(11) NOT NULL DEFAULT 0,
+  `update_time` bigint(20) NOT NULL,
+  `archive_claim_time` bigint(20) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`enterprise`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
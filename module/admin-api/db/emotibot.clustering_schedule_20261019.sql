@@ -0,0 +1,28 @@
+-- Scheduled clustering reports of each app, period and times are in seconds
+CREATE TABLE `report_schedules` (
+  `app_id` varchar(64) NOT NULL,
+  `enabled` tinyint(1) NOT NULL DEFAULT 0,
+  `period` bigint(20) NOT NULL,
+  `score_threshold` double NOT NULL DEFAULT 0,
+  `last_run_time` bigint(20) NOT NULL DEFAULT 0,
+  `updated_time` bigint(20) NOT NULL,
+  PRIMARY KEY (`app_id`),
+  KEY `enabled` (`enabled`, `last_run_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Follow-up actions taken on clusters and how later user questions are resolved by them
+CREATE TABLE `cluster_actions` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `app_id` varchar(64) NOT NULL,
+  `report_id` bigint(20) NOT NULL,
+  `cluster_id` bigint(20) NOT NULL,
+  `type` varchar(32) NOT NULL,
+  `question` text NOT NULL,
+  `contents` mediumtext NOT NULL,
+  `user_id` varchar(64) NOT NULL DEFAULT '',
+  `created_time` bigint(20) NOT NULL,
+  `hit_count` bigint(20) NOT NULL DEFAULT 0,
+  `resolved_count` bigint(20) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `app_id` (`app_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
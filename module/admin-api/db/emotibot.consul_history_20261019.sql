@@ -0,0 +1,22 @@
+-- Writes of consul keys by admin-api, op is set or delete
+CREATE TABLE `consul_history` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `consul_key` varchar(512) NOT NULL,
+  `op` varchar(16) NOT NULL,
+  `old_value` mediumtext NOT NULL,
+  `new_value` mediumtext NOT NULL,
+  `actor` varchar(64) NOT NULL DEFAULT '',
+  `reason` varchar(512) NOT NULL DEFAULT '',
+  `created_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `consul_key` (`consul_key`(191)),
+  KEY `created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Path segments of keys in consul_history, which finds records of an appid or enterprise
+CREATE TABLE `consul_history_segments` (
+  `segment` varchar(191) NOT NULL,
+  `history_id` bigint(20) NOT NULL,
+  PRIMARY KEY (`segment`, `history_id`),
+  KEY `history_id` (`history_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,16 @@
+-- Uploaded files waiting to be committed by two-phase importers, claimed is set when committing or discarding
+CREATE TABLE `import_previews` (
+  `id` varchar(64) NOT NULL,
+  `appid` varchar(64) NOT NULL,
+  `module` varchar(64) NOT NULL,
+  `filename` varchar(256) NOT NULL DEFAULT '',
+  `content` mediumblob NOT NULL,
+  `form` text NOT NULL,
+  `errors` mediumtext NOT NULL,
+  `diff` mediumtext NOT NULL,
+  `claimed` tinyint(4) NOT NULL DEFAULT 0,
+  `create_time` bigint(20) NOT NULL,
+  `expire_time` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `expire_time` (`expire_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+-- Default config keys of platforms, setPlatformConfig only accepts keys listed here
+INSERT INTO `integration` (`appid`, `platform`, `pkey`, `pvalue`) VALUES
+  ('', 'webhook', 'token', ''),
+  ('', 'webhook', 'callback', ''),
+  ('', 'telegram', 'token', ''),
+  ('', 'telegram', 'secret', ''),
+  ('', 'telegram', 'api', '');
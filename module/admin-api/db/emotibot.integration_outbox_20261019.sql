@@ -0,0 +1,20 @@
+-- Outbound replies of integration platforms, status: 0 pending, 1 sending, 2 sent, 3 failed
+CREATE TABLE `integration_outbox` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `appid` char(36) NOT NULL,
+  `platform` varchar(32) NOT NULL,
+  `idempotency_key` varchar(191) NOT NULL,
+  `locale` varchar(16) NOT NULL DEFAULT '',
+  `message` text NOT NULL,
+  `answers` mediumtext NOT NULL,
+  `status` tinyint(4) NOT NULL DEFAULT 0,
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `next_attempt_at` bigint(20) NOT NULL,
+  `last_error` varchar(1024) NOT NULL DEFAULT '',
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idempotency_key` (`idempotency_key`),
+  KEY `status_next_attempt` (`status`, `next_attempt_at`),
+  KEY `appid_status` (`appid`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,24 @@
+-- Intent k-fold cross validation tasks, status is the same as intent_test_versions
+CREATE TABLE `intent_cross_validations` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `app_id` varchar(64) NOT NULL,
+  `status` tinyint(4) NOT NULL DEFAULT 0,
+  `folds` int(11) NOT NULL,
+  `fold` int(11) NOT NULL DEFAULT 0,
+  `sentences_count` bigint(20) NOT NULL DEFAULT 0,
+  `progress` bigint(20) NOT NULL DEFAULT 0,
+  `start_time` bigint(20) NOT NULL,
+  `end_time` bigint(20) DEFAULT NULL,
+  `message` varchar(1024) NOT NULL DEFAULT '',
+  `result` mediumtext,
+  PRIMARY KEY (`id`),
+  KEY `app_id` (`app_id`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Training data of temporary app IDs returned to intent-engine instead of the intents
+CREATE TABLE `intent_train_data_overrides` (
+  `appid` varchar(128) NOT NULL,
+  `content` longtext NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`appid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,36 @@
+-- Images in media library, the same content is stored once in an app
+CREATE TABLE `media` (
+  `id` varchar(64) NOT NULL,
+  `appid` varchar(64) NOT NULL,
+  `name` varchar(255) NOT NULL DEFAULT '',
+  `hash` char(64) NOT NULL,
+  `mime_type` varchar(64) NOT NULL,
+  `size` bigint(20) NOT NULL,
+  `width` int(11) NOT NULL,
+  `height` int(11) NOT NULL,
+  `variants` varchar(255) NOT NULL DEFAULT '',
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `appid_hash` (`appid`, `hash`),
+  KEY `appid_created_at` (`appid`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `media_tags` (
+  `media_id` varchar(64) NOT NULL,
+  `appid` varchar(64) NOT NULL,
+  `tag` varchar(64) NOT NULL,
+  PRIMARY KEY (`media_id`, `tag`),
+  KEY `appid_tag` (`appid`, `tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Contents using media, media cannot be deleted while it is referenced
+CREATE TABLE `media_references` (
+  `appid` varchar(64) NOT NULL,
+  `media_id` varchar(64) NOT NULL,
+  `ref_type` varchar(32) NOT NULL,
+  `ref_id` varchar(128) NOT NULL,
+  `created_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`media_id`, `ref_type`, `ref_id`),
+  KEY `appid_ref` (`appid`, `ref_type`, `ref_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,24 @@
+-- Changes of robot configs, old_value and new_value are the values before and after the change
+CREATE TABLE `robot_config_histories` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `appid` varchar(64) NOT NULL,
+  `code` varchar(128) NOT NULL,
+  `module` varchar(64) NOT NULL DEFAULT '',
+  `old_value` mediumtext NOT NULL,
+  `new_value` mediumtext NOT NULL,
+  `user_id` varchar(64) NOT NULL DEFAULT '',
+  `create_time` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `appid_id` (`appid`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Schemas of robot configs which can not be inferred from default value, like range of number
+-- or options of enum, enum_values is json array of options
+CREATE TABLE `robot_config_schemas` (
+  `code` varchar(128) NOT NULL,
+  `type` varchar(16) NOT NULL,
+  `min_value` double DEFAULT NULL,
+  `max_value` double DEFAULT NULL,
+  `enum_values` text,
+  PRIMARY KEY (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
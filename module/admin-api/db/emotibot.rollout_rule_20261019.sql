@@ -0,0 +1,11 @@
+-- Gradual rollout rules of switches and robot functions, rule is the json of rollout.Rule
+CREATE TABLE `rollout_rules` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `appid` varchar(64) NOT NULL,
+  `type` varchar(32) NOT NULL,
+  `code` varchar(128) NOT NULL,
+  `rule` text NOT NULL,
+  `update_time` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `appid_type_code` (`appid`, `type`, `code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
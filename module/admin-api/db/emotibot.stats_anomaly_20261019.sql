@@ -0,0 +1,42 @@
+CREATE TABLE `stats_anomaly_baselines` (
+  `app_id` char(36) NOT NULL,
+  `metric` varchar(32) NOT NULL,
+  `weekday` tinyint(4) NOT NULL,
+  `hour` tinyint(4) NOT NULL,
+  `samples` int(11) NOT NULL DEFAULT 0,
+  `mean` double NOT NULL DEFAULT 0,
+  `variance` double NOT NULL DEFAULT 0,
+  `last_slot` bigint(20) NOT NULL DEFAULT 0,
+  `updated_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`app_id`, `metric`, `weekday`, `hour`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `stats_anomaly_alerts` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `app_id` char(36) NOT NULL,
+  `metric` varchar(32) NOT NULL,
+  `direction` varchar(8) NOT NULL,
+  `start_time` bigint(20) NOT NULL,
+  `end_time` bigint(20) NOT NULL,
+  `value` double NOT NULL,
+  `expected` double NOT NULL,
+  `std_dev` double NOT NULL,
+  `score` double NOT NULL,
+  `notified` tinyint(1) NOT NULL DEFAULT 0,
+  `notify_attempts` int(11) NOT NULL DEFAULT 0,
+  `created_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `app_metric_time` (`app_id`, `metric`, `start_time`),
+  KEY `app_time` (`app_id`, `start_time`),
+  KEY `notified` (`notified`, `notify_attempts`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `stats_anomaly_settings` (
+  `app_id` char(36) NOT NULL,
+  `enabled` tinyint(1) NOT NULL DEFAULT 1,
+  `threshold` double NOT NULL DEFAULT 3,
+  `webhook_url` varchar(512) NOT NULL DEFAULT '',
+  `webhook_secret` varchar(64) NOT NULL DEFAULT '',
+  `updated_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`app_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,28 @@
+CREATE TABLE `stats_reports` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `enterprise_id` char(36) NOT NULL DEFAULT '',
+  `app_id` char(36) NOT NULL DEFAULT '',
+  `name` varchar(128) NOT NULL DEFAULT '',
+  `stats` varchar(256) NOT NULL DEFAULT '',
+  `filters` text NOT NULL,
+  `period` varchar(16) NOT NULL DEFAULT 'day',
+  `schedule` varchar(64) NOT NULL DEFAULT '',
+  `delivery` text NOT NULL,
+  `locale` varchar(16) NOT NULL DEFAULT '',
+  `enabled` tinyint(1) NOT NULL DEFAULT 1,
+  `next_run_at` bigint(20) NOT NULL DEFAULT 0,
+  `created_at` bigint(20) NOT NULL,
+  `updated_at` bigint(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `app_id` (`app_id`),
+  KEY `next_run_at` (`enabled`, `next_run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `records_export`
+  ADD `report_id` bigint(20) NOT NULL DEFAULT 0,
+  ADD `start_time` bigint(20) NOT NULL DEFAULT 0,
+  ADD `end_time` bigint(20) NOT NULL DEFAULT 0,
+  ADD `summary` mediumtext,
+  ADD `delivered` tinyint(1) NOT NULL DEFAULT 0,
+  ADD `finished_at` bigint(20) NOT NULL DEFAULT 0,
+  ADD INDEX `report_id` (`report_id`, `created_at`);
@@ -0,0 +1,338 @@
+package dictionary
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// WordbankReferenceFunc returns texts of an app which may refer wordbanks,
+// like intent sentences or task-engine scenario content
+type WordbankReferenceFunc func(appid string) ([]string, error)
+
+var (
+	wordbankReferences     = map[string]WordbankReferenceFunc{}
+	wordbankReferencesLock sync.RWMutex
+)
+
+// RegisterWordbankReference is used by other modules to provide texts using wordbanks.
+// Modules depend on dictionary, so they have to register instead of dictionary query them.
+func RegisterWordbankReference(module string, f WordbankReferenceFunc) {
+	wordbankReferencesLock.Lock()
+	defer wordbankReferencesLock.Unlock()
+	wordbankReferences[module] = f
+}
+
+// getWordbankReferences collects texts from all registered modules
+func getWordbankReferences(appid string) ([]string, error) {
+	wordbankReferencesLock.RLock()
+	defer wordbankReferencesLock.RUnlock()
+	texts := []string{}
+	for module, f := range wordbankReferences {
+		moduleTexts, err := f(appid)
+		if err != nil {
+			logger.Error.Printf("Get wordbank references of %s fail: %s\n", module, err.Error())
+			return nil, err
+		}
+		texts = append(texts, moduleTexts...)
+	}
+	return texts, nil
+}
+
+// WordbankCheckReport is the result of consistency check of V3 wordbanks
+type WordbankCheckReport struct {
+	// Duplicates are words existed in more than one wordbanks
+	Duplicates []*WordbankWordConflict `json:"duplicates"`
+	// SynonymChains are wordbanks merged together by shared words,
+	// which contains wordbanks sharing no word with each other
+	SynonymChains []*WordbankSynonymChain `json:"synonym_chains"`
+	// Unused are wordbanks not referred by any intent or scenario
+	Unused []string `json:"unused"`
+	// Sensitive are words existed in both sensitive wordbank and other wordbanks
+	Sensitive []*WordbankWordConflict `json:"sensitive_conflicts"`
+}
+
+type WordbankWordConflict struct {
+	Word      string   `json:"word"`
+	Wordbanks []string `json:"wordbanks"`
+}
+
+type WordbankSynonymChain struct {
+	Wordbanks []string               `json:"wordbanks"`
+	Links     []*WordbankSynonymLink `json:"links"`
+}
+
+type WordbankSynonymLink struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Words []string `json:"words"`
+}
+
+// HasConflict returns true if there are words conflicted, unused wordbanks are not counted
+func (report *WordbankCheckReport) HasConflict() bool {
+	return len(report.Duplicates) > 0 || len(report.SynonymChains) > 0 || len(report.Sensitive) > 0
+}
+
+// checkedWordbank is a wordbank with its path for checking
+type checkedWordbank struct {
+	Path      string
+	Sensitive bool
+	Words     []string
+}
+
+func flattenWordbanksV3(root *WordBankClassV3) []*checkedWordbank {
+	ret := []*checkedWordbank{}
+	var walk func(class *WordBankClassV3, path []string)
+	walk = func(class *WordBankClassV3, path []string) {
+		if class == nil {
+			return
+		}
+		// path didn't need append when current class is virtual root
+		if class.ID != -1 {
+			path = append(append([]string{}, path...), class.Name)
+		}
+		for _, child := range class.Children {
+			walk(child, path)
+		}
+		sensitive := len(path) > 0 && path[0] == util.Msg["SensitiveWordbank"]
+		for _, wordbank := range class.Wordbank {
+			words := []string{}
+			existed := map[string]bool{}
+			for _, word := range append([]string{wordbank.Name}, wordbank.SimilarWords...) {
+				word = normalizeWord(word)
+				if word == "" || existed[word] {
+					continue
+				}
+				existed[word] = true
+				words = append(words, word)
+			}
+			ret = append(ret, &checkedWordbank{
+				Path:      strings.Join(append(append([]string{}, path...), wordbank.Name), "/"),
+				Sensitive: sensitive,
+				Words:     words,
+			})
+		}
+	}
+	walk(root, []string{})
+	return ret
+}
+
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+// CheckWordbanksV3 checks the wordbanks of appid stored in DB
+func CheckWordbanksV3(appid string) (*WordbankCheckReport, error) {
+	root, err := getWordbanksV3(appid)
+	if err != nil {
+		return nil, err
+	}
+	return CheckWordbanksV3Content(appid, root)
+}
+
+// CheckWordbanksV3Content checks the wordbanks in root, which may not be saved yet
+func CheckWordbanksV3Content(appid string, root *WordBankClassV3) (*WordbankCheckReport, error) {
+	references, err := getWordbankReferences(appid)
+	if err != nil {
+		return nil, err
+	}
+	return checkWordbanks(flattenWordbanksV3(root), references), nil
+}
+
+func checkWordbanks(wordbanks []*checkedWordbank, references []string) *WordbankCheckReport {
+	report := &WordbankCheckReport{
+		Duplicates:    []*WordbankWordConflict{},
+		SynonymChains: []*WordbankSynonymChain{},
+		Unused:        []string{},
+		Sensitive:     []*WordbankWordConflict{},
+	}
+
+	// word to index of wordbanks
+	normal := map[string][]int{}
+	sensitive := map[string]bool{}
+	for idx, wordbank := range wordbanks {
+		for _, word := range wordbank.Words {
+			if wordbank.Sensitive {
+				sensitive[word] = true
+			} else {
+				normal[word] = append(normal[word], idx)
+			}
+		}
+	}
+
+	words := make([]string, 0, len(normal))
+	for word := range normal {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	paths := func(indexes []int) []string {
+		ret := make([]string, len(indexes))
+		for i, idx := range indexes {
+			ret[i] = wordbanks[idx].Path
+		}
+		return ret
+	}
+
+	// shared words between each two wordbanks, key is [small index, large index]
+	links := map[[2]int][]string{}
+	for _, word := range words {
+		indexes := normal[word]
+		if len(indexes) > 1 {
+			report.Duplicates = append(report.Duplicates, &WordbankWordConflict{
+				Word:      word,
+				Wordbanks: paths(indexes),
+			})
+			for i := range indexes {
+				for j := i + 1; j < len(indexes); j++ {
+					key := [2]int{indexes[i], indexes[j]}
+					links[key] = append(links[key], word)
+				}
+			}
+		}
+		if sensitive[word] {
+			report.Sensitive = append(report.Sensitive, &WordbankWordConflict{
+				Word:      word,
+				Wordbanks: paths(indexes),
+			})
+		}
+	}
+
+	report.SynonymChains = findSynonymChains(wordbanks, links)
+
+	usedWords := referredWords(wordbanks, references)
+	for _, wordbank := range wordbanks {
+		if wordbank.Sensitive {
+			continue
+		}
+		used := false
+		for _, word := range wordbank.Words {
+			if usedWords[word] {
+				used = true
+				break
+			}
+		}
+		if !used {
+			report.Unused = append(report.Unused, wordbank.Path)
+		}
+	}
+	return report
+}
+
+// referredWords returns words of wordbanks appeared as tokens in references. References are
+// segmented by longest match of words, so a word inside a longer word or inside an English word
+// is not counted, e.g. 机 in 座机 or app in apple.
+func referredWords(wordbanks []*checkedWordbank, references []string) map[string]bool {
+	dict := map[string]bool{}
+	maxLen := 0
+	for _, wordbank := range wordbanks {
+		for _, word := range wordbank.Words {
+			dict[word] = true
+			if length := utf8.RuneCountInString(word); length > maxLen {
+				maxLen = length
+			}
+		}
+	}
+
+	used := map[string]bool{}
+	for _, reference := range references {
+		text := []rune(normalizeWord(reference))
+		for start := 0; start < len(text); {
+			matched := 0
+			for end := start + maxLen; end > start; end-- {
+				if end > len(text) || !dict[string(text[start:end])] {
+					continue
+				}
+				// English word must end at word boundary
+				if isWordRune(text[end-1]) && end < len(text) && isWordRune(text[end]) {
+					continue
+				}
+				matched = end - start
+				break
+			}
+			if matched > 0 {
+				used[string(text[start:start+matched])] = true
+				start += matched
+				continue
+			}
+			// skip the whole English word, so words inside it are not matched
+			if isWordRune(text[start]) {
+				for start < len(text) && isWordRune(text[start]) {
+					start++
+				}
+				continue
+			}
+			start++
+		}
+	}
+	return used
+}
+
+// isWordRune returns true if r is a part of English word, which is separated by space or punctuation
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '_'
+}
+
+// findSynonymChains groups wordbanks connected by shared words, and reports the groups
+// in which some wordbanks are only connected through others
+func findSynonymChains(wordbanks []*checkedWordbank, links map[[2]int][]string) []*WordbankSynonymChain {
+	parent := make([]int, len(wordbanks))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+	var find func(int) int
+	find = func(idx int) int {
+		if parent[idx] != idx {
+			parent[idx] = find(parent[idx])
+		}
+		return parent[idx]
+	}
+	for key := range links {
+		parent[find(key[0])] = find(key[1])
+	}
+
+	groups := map[int][]int{}
+	for idx := range wordbanks {
+		root := find(idx)
+		groups[root] = append(groups[root], idx)
+	}
+
+	chains := []*WordbankSynonymChain{}
+	for _, members := range groups {
+		size := len(members)
+		// a group is a chain if not every two wordbanks share words directly
+		if size < 3 {
+			continue
+		}
+		groupLinks := []*WordbankSynonymLink{}
+		for i := range members {
+			for j := i + 1; j < size; j++ {
+				if words, ok := links[[2]int{members[i], members[j]}]; ok {
+					groupLinks = append(groupLinks, &WordbankSynonymLink{
+						From:  wordbanks[members[i]].Path,
+						To:    wordbanks[members[j]].Path,
+						Words: words,
+					})
+				}
+			}
+		}
+		if len(groupLinks) == size*(size-1)/2 {
+			continue
+		}
+
+		chain := &WordbankSynonymChain{Links: groupLinks}
+		for _, idx := range members {
+			chain.Wordbanks = append(chain.Wordbanks, wordbanks[idx].Path)
+		}
+		chains = append(chains, chain)
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Wordbanks[0] < chains[j].Wordbanks[0]
+	})
+	return chains
+}
@@ -0,0 +1,88 @@
+package dictionary
+
+import (
+	"reflect"
+	"testing"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+func TestCheckWordbanks(t *testing.T) {
+	root := &WordBankClassV3{
+		ID: -1,
+		Children: []*WordBankClassV3{
+			&WordBankClassV3{
+				Name: "专有词库",
+				Wordbank: []*WordBankV3{
+					&WordBankV3{Name: "苹果", SimilarWords: []string{"Apple", "红富士"}},
+					&WordBankV3{Name: "手机", SimilarWords: []string{"apple", "iphone"}},
+					&WordBankV3{Name: "电话", SimilarWords: []string{"iPhone", "座机"}},
+					&WordBankV3{Name: "香蕉", SimilarWords: []string{"芭蕉"}},
+				},
+			},
+			&WordBankClassV3{
+				Name: util.Msg["SensitiveWordbank"],
+				Wordbank: []*WordBankV3{
+					&WordBankV3{Name: "脏话", SimilarWords: []string{"芭蕉"}},
+				},
+			},
+		},
+	}
+
+	report := checkWordbanks(flattenWordbanksV3(root), []string{"我想买个红富士", "我的座机坏了"})
+
+	duplicates := map[string][]string{}
+	for _, d := range report.Duplicates {
+		duplicates[d.Word] = d.Wordbanks
+	}
+	expectDuplicates := map[string][]string{
+		"apple":  []string{"专有词库/苹果", "专有词库/手机"},
+		"iphone": []string{"专有词库/手机", "专有词库/电话"},
+	}
+	if !reflect.DeepEqual(duplicates, expectDuplicates) {
+		t.Errorf("expect duplicates %v, but got %v", expectDuplicates, duplicates)
+	}
+
+	// 苹果 and 电话 share nothing but are merged through 手机
+	if len(report.SynonymChains) != 1 || len(report.SynonymChains[0].Wordbanks) != 3 ||
+		len(report.SynonymChains[0].Links) != 2 {
+		t.Errorf("expect one synonym chain of 3 wordbanks, but got %+v", report.SynonymChains)
+	}
+
+	if !reflect.DeepEqual(report.Unused, []string{"专有词库/手机", "专有词库/香蕉"}) {
+		t.Errorf("unexpected unused wordbanks: %v", report.Unused)
+	}
+
+	if len(report.Sensitive) != 1 || report.Sensitive[0].Word != "芭蕉" ||
+		!reflect.DeepEqual(report.Sensitive[0].Wordbanks, []string{"专有词库/香蕉"}) {
+		t.Errorf("unexpected sensitive conflicts: %+v", report.Sensitive)
+	}
+	if !report.HasConflict() {
+		t.Error("expect report has conflict")
+	}
+
+	report = checkWordbanks(flattenWordbanksV3(&WordBankClassV3{ID: -1}), nil)
+	if report.HasConflict() || len(report.Unused) != 0 {
+		t.Errorf("expect empty report, but got %+v", report)
+	}
+}
+
+func TestReferredWords(t *testing.T) {
+	wordbanks := []*checkedWordbank{
+		&checkedWordbank{Path: "机", Words: []string{"机"}},
+		&checkedWordbank{Path: "座机", Words: []string{"座机"}},
+		&checkedWordbank{Path: "app", Words: []string{"app"}},
+		&checkedWordbank{Path: "apple", Words: []string{"apple", "iphone x"}},
+	}
+
+	used := referredWords(wordbanks, []string{"我的座机坏了", "Apple pie", "apps 和 iPhone X", "happy"})
+	expect := map[string]bool{"座机": true, "apple": true, "iphone x": true}
+	if !reflect.DeepEqual(used, expect) {
+		t.Errorf("expect referred words %v, but got %v", expect, used)
+	}
+
+	report := checkWordbanks(wordbanks, []string{"我的座机坏了", "买个apple"})
+	if !reflect.DeepEqual(report.Unused, []string{"机", "app"}) {
+		t.Errorf("unexpected unused wordbanks: %v", report.Unused)
+	}
+}
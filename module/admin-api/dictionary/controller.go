@@ -17,6 +17,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/auth"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/pkg/logger"
@@ -67,6 +68,7 @@ func init() {
 
 			util.NewEntryPointWithVer("POST", "upload", []string{"view"}, handleUploadToMySQLV3, 3),
 			util.NewEntryPointWithVer("GET", "export", []string{}, handleExportFromMySQLV3, 3),
+			util.NewEntryPointWithVer("GET", "consistency", []string{"view"}, handleCheckWordbanksV3, 3),
 			util.NewEntryPointWithVer("GET", "download/{file}", []string{}, handleDownloadFromMySQLV3, 3),
 			util.NewEntryPointWithVer("GET", "words/{appid}", []string{}, handleGetWordV3, 3),
 			util.NewEntryPointWithVer("GET", "synonyms/{appid}", []string{}, handleGetSynonymsV3, 3),
@@ -74,6 +76,8 @@ func init() {
 			util.NewEntryPointWithCustom("Get", "sync/{appid}", []string{"edit"}, handleSyncConsul, 3, false, true),
 		},
 	}
+	ModuleInfo.EntryPoints = append(ModuleInfo.EntryPoints,
+		importpreview.EntryPoints("upload/preview", []string{"view"}, 3, "dictionary", "file", parseWordbankPreviewV3)...)
 	maxDirDepth = 4
 }
 
@@ -147,7 +151,7 @@ func handleUpdateWordbank(w http.ResponseWriter, r *http.Request) {
 		auditRet = 0
 	} else {
 		http.Error(w, "", http.StatusOK)
-		SyncWordbank(appid, 2)
+		SyncWordbank(appid, 2, util.NewConsulChange(r, "update wordbank"))
 	}
 
 	var buffer bytes.Buffer
@@ -201,7 +205,7 @@ func handlePutWordbank(w http.ResponseWriter, r *http.Request) {
 		auditRet = 0
 	} else {
 		http.Error(w, "", http.StatusOK)
-		SyncWordbank(appid, 2)
+		SyncWordbank(appid, 2, util.NewConsulChange(r, "put wordbank"))
 	}
 	if newWordBank != nil {
 		util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, newWordBank))
@@ -405,7 +409,7 @@ func handleUploadToMySQL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, wordbanks))
-	TriggerUpdateWordbank(appid, wordbanks, 2)
+	TriggerUpdateWordbank(appid, wordbanks, 2, util.NewConsulChange(r, "upload wordbanks"))
 }
 
 func handleDownloadFromMySQL(w http.ResponseWriter, r *http.Request) {
@@ -511,7 +515,7 @@ func handleDeleteWordbank(w http.ResponseWriter, r *http.Request) {
 	}
 	ret = 1
 	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, errMsg))
-	SyncWordbank(appid, 2)
+	SyncWordbank(appid, 2, util.NewConsulChange(r, "delete wordbank"))
 }
 
 func handleDeleteWordbankDir(w http.ResponseWriter, r *http.Request) {
@@ -543,7 +547,7 @@ func handleDeleteWordbankDir(w http.ResponseWriter, r *http.Request) {
 	errMsg += fmt.Sprintf(": %s %d %s", util.Msg["Delete"], rowCount, util.Msg["Row"])
 	ret = 1
 	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, errMsg))
-	SyncWordbank(appid, 2)
+	SyncWordbank(appid, 2, util.NewConsulChange(r, "delete wordbank dir"))
 }
 
 func handleGetWordbanksV3(w http.ResponseWriter, r *http.Request) {
@@ -654,7 +658,7 @@ func handleDeleteWordbankV3(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	result = 1
-	go TriggerUpdateWordbankV3(appid)
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "delete wordbank"))
 	return
 }
 func handleDeleteWordbankClassV3(w http.ResponseWriter, r *http.Request) {
@@ -698,7 +702,7 @@ func handleDeleteWordbankClassV3(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	util.WriteJSON(w, util.GenSimpleRetObj(ApiError.SUCCESS))
-	go TriggerUpdateWordbankV3(appid)
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "delete wordbank class"))
 	return
 }
 
@@ -717,6 +721,9 @@ func handleUploadToMySQLV3(w http.ResponseWriter, r *http.Request) {
 		}
 		if err == nil {
 			util.WriteJSON(w, util.GenRetObj(errno, ret))
+		} else if ret != nil {
+			// return check report with error if import is blocked
+			util.WriteJSONWithStatus(w, util.GenRetObjWithCustomMsg(errno, err.Error(), ret), ApiError.GetHttpStatus(errno))
 		} else {
 			util.WriteJSONWithStatus(w, util.GenRetObj(errno, err.Error()), ApiError.GetHttpStatus(errno))
 		}
@@ -766,7 +773,20 @@ func handleUploadToMySQLV3(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. save to mysql
+	// 2. check consistency, block the import when strict is set and conflicts are found
+	report, checkErr := CheckWordbanksV3Content(appid, root)
+	if checkErr != nil {
+		logger.Warn.Println("Check wordbanks consistency fail: ", checkErr.Error())
+	} else if report.HasConflict() && r.FormValue("strict") == "true" {
+		errno = ApiError.REQUEST_ERROR
+		err = errors.New(util.Msg["ErrorWordbankConflict"])
+		ret = map[string]interface{}{
+			"report": report,
+		}
+		return
+	}
+
+	// 3. save to mysql
 	err = SaveWordbankV3Rows(appid, root)
 	if err != nil {
 		errno = ApiError.DB_ERROR
@@ -774,9 +794,21 @@ func handleUploadToMySQLV3(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ret = map[string]interface{}{
-		"root": root,
+		"root":   root,
+		"report": report,
+	}
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "upload wordbanks"))
+}
+
+func handleCheckWordbanksV3(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+
+	report, err := CheckWordbanksV3(appid)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
 	}
-	go TriggerUpdateWordbankV3(appid)
+	util.WriteJSON(w, util.GenRetObj(ApiError.SUCCESS, report))
 }
 
 func handleDownloadFromMySQLV3(w http.ResponseWriter, r *http.Request) {
@@ -919,7 +951,7 @@ func handleAddWordbankClassV3(w http.ResponseWriter, r *http.Request) {
 	} else {
 		result = class
 	}
-	go TriggerUpdateWordbankV3(appid)
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "add wordbank class"))
 }
 func handleUpdateWordbankClassV3(w http.ResponseWriter, r *http.Request) {
 	var err error
@@ -982,7 +1014,7 @@ func handleUpdateWordbankClassV3(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		retCode, result = ApiError.DB_ERROR, err.Error()
 	}
-	go TriggerUpdateWordbankV3(appid)
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "update wordbank class"))
 }
 
 func handleAddWordbankV3(w http.ResponseWriter, r *http.Request) {
@@ -1050,7 +1082,7 @@ func handleAddWordbankV3(w http.ResponseWriter, r *http.Request) {
 		wb.ID = id
 		result = wb
 	}
-	go TriggerUpdateWordbankV3(appid)
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "add wordbank"))
 }
 func handleUpdateWordbankV3(w http.ResponseWriter, r *http.Request) {
 	var err error
@@ -1128,7 +1160,7 @@ func handleUpdateWordbankV3(w http.ResponseWriter, r *http.Request) {
 		wb.ID = id
 		result = wb
 	}
-	go TriggerUpdateWordbankV3(appid)
+	go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "update wordbank"))
 }
 func handleMoveWordbankV3(w http.ResponseWriter, r *http.Request) {
 	var err error
@@ -1220,7 +1252,7 @@ func handleMoveWordbankV3(w http.ResponseWriter, r *http.Request) {
 		retCode = ApiError.DB_ERROR
 		result = err.Error()
 	} else {
-		go TriggerUpdateWordbankV3(appid)
+		go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "move wordbank"))
 	}
 }
 
@@ -1298,13 +1330,13 @@ func handleSyncConsul(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go syncConsul(appids)
+	go syncConsul(appids, util.NewConsulChange(r, "sync consul"))
 	util.Return(w, nil, appids)
 }
 
-func syncConsul(appids []string) {
+func syncConsul(appids []string, change *util.ConsulChange) {
 	for idx, appid := range appids {
-		err := TriggerUpdateWordbankV3(appid)
+		err := TriggerUpdateWordbankV3(appid, change)
 		if err != nil {
 			logger.Error.Println("Update wordbank consul fail, ", err.Error())
 			return
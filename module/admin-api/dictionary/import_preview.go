@@ -0,0 +1,64 @@
+package dictionary
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+)
+
+// parseWordbankPreviewV3 parses uploaded V3 wordbanks and diffs them with wordbanks in DB,
+// the wordbanks are replaced when preview is committed, same as handleUploadToMySQLV3
+func parseWordbankPreviewV3(r *http.Request, appid string, upload *importpreview.Upload) (importpreview.RowErrors, *importpreview.Diff, func() error, AdminErrors.AdminError) {
+	locale := requestheader.GetLocale(r)
+	root, err := parseDictionaryFromXLSXV3(upload.Content, locale)
+	if err != nil {
+		return importpreview.ToRowErrors(err), nil, nil, nil
+	}
+
+	current, err := getWordbanksV3(appid)
+	if err != nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	diff := importpreview.DiffRecords(wordbankRecordsV3(current), wordbankRecordsV3(root))
+
+	commit := func() error {
+		filename := fmt.Sprintf("wordbank_%s.xlsx", time.Now().Format("20060102150405"))
+		err := SaveWordbankV3Rows(appid, root)
+		RecordDictionaryImportProcess(appid, filename, upload.Content, err)
+		if err != nil {
+			return err
+		}
+		go TriggerUpdateWordbankV3(appid, util.NewConsulChange(r, "import wordbanks"))
+		return nil
+	}
+	return nil, diff, commit, nil
+}
+
+// wordbankRecordsV3 returns wordbanks in root with their path as key
+func wordbankRecordsV3(root *WordBankClassV3) map[string]string {
+	records := map[string]string{}
+	var walk func(class *WordBankClassV3, path []string)
+	walk = func(class *WordBankClassV3, path []string) {
+		if class == nil {
+			return
+		}
+		if class.ID != -1 {
+			path = append(append([]string{}, path...), class.Name)
+		}
+		for _, child := range class.Children {
+			walk(child, path)
+		}
+		for _, wordbank := range class.Wordbank {
+			key := strings.Join(append(append([]string{}, path...), wordbank.Name), "/")
+			records[key] = importpreview.RecordContent(wordbank.SimilarWords...) + "\n" + wordbank.Answer
+		}
+	}
+	walk(root, []string{})
+	return records
+}
@@ -98,12 +98,12 @@ func GetEntities(appid string) ([]*WordBank, error) {
 	return wordbanks, err
 }
 
-func SyncWordbank(appid string, version int) {
+func SyncWordbank(appid string, version int, change *util.ConsulChange) {
 	wordbanks, err := getWordbankRows(appid)
 	if err != nil {
 		return
 	}
-	TriggerUpdateWordbank(appid, wordbanks, version)
+	TriggerUpdateWordbank(appid, wordbanks, version, change)
 }
 
 func DeleteWordbankDir(appid string, paths []string) (int, error) {
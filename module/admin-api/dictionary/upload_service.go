@@ -15,6 +15,7 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
 
@@ -332,7 +333,7 @@ func GetWordbankFile(appid string, filename string) ([]byte, error) {
 	return getWordbankFile(appid, filename)
 }
 
-func TriggerUpdateWordbank(appid string, wordbanks []*WordBankRow, version int) (err error) {
+func TriggerUpdateWordbank(appid string, wordbanks []*WordBankRow, version int, change *util.ConsulChange) (err error) {
 	// 1. save to local file which can be get from url
 	err, md5Words, md5Synonyms := SaveWordbankToFile(appid, wordbanks)
 	if err != nil {
@@ -353,12 +354,12 @@ func TriggerUpdateWordbank(appid string, wordbanks []*WordBankRow, version int)
 		"synonym-md5": md5Synonyms,
 		"timestamp":   now.UnixNano() / 1000000,
 	}
-	util.ConsulUpdateEntity(appid, consulJSON)
+	util.ConsulUpdateEntity(appid, consulJSON, change)
 	logger.Info.Printf("Update to consul:\n%+v\n", consulJSON)
 	return
 }
 
-func TriggerUpdateWordbankV3(appid string) (err error) {
+func TriggerUpdateWordbankV3(appid string, change *util.ConsulChange) (err error) {
 	// Update consul key
 	// TODO: use relative to compose the url
 	url := getEnvironment("INTERNAL_URL")
@@ -380,10 +381,10 @@ func TriggerUpdateWordbankV3(appid string) (err error) {
 		"synonym-md5": fmt.Sprintf("%x", md5Synonyms),
 		"timestamp":   now.UnixNano() / 1000000,
 	}
-	util.ConsulUpdateEntity(appid, consulJSON)
+	util.ConsulUpdateEntity(appid, consulJSON, change)
 	logger.Info.Printf("Update to consul:\n%+v\n", consulJSON)
 	// inform TE to reload mapping table
-	util.ConsulUpdateTaskEngineMappingTableAll()
+	util.ConsulUpdateTaskEngineMappingTableAll(change)
 	logger.Info.Printf("Update consul key: te/mapping_table_all")
 	return
 }
@@ -409,11 +410,24 @@ func parseDictionaryFromXLSXV3(buf []byte, locale string) (root *WordBankClassV3
 		return
 	}
 
-	_, rows, err := getSheetRowsInWordbankXLSX(xlsxFile, locale)
+	sheet, rows, err := getSheetRowsInWordbankXLSX(xlsxFile, locale)
 	if err != nil {
 		return
 	}
+	header := rows[0]
 	rows = rows[1:]
+	columnName := func(cellIdx int) string {
+		if cellIdx < len(header.Cells) {
+			return strings.TrimSpace(header.Cells[cellIdx].Value)
+		}
+		return ""
+	}
+
+	// collect errors of all rows instead of stopping at first one
+	rowErrs := importpreview.RowErrors{}
+	addRowErr := func(rowNum int, cellIdx int, msg string) {
+		rowErrs.Add(sheet.Name, rowNum, columnName(cellIdx), msg)
+	}
 
 	// classReadOnly's key is class path
 	classReadOnly := map[string]bool{}
@@ -422,8 +436,10 @@ func parseDictionaryFromXLSXV3(buf []byte, locale string) (root *WordBankClassV3
 	var lastWordbankRow *WordBankRow
 	wordbankRowList := []*WordBankRow{}
 	for idx, row := range rows {
+		// row number in sheet, including header, which is used in both row error and its message
+		rowNum := idx + 2
 		if row.Cells == nil {
-			logger.Error.Printf("Cannot get cell from row %d\n", idx)
+			logger.Error.Printf("Cannot get cell from row %d\n", rowNum)
 			continue
 		}
 		if len(row.Cells) == 0 {
@@ -434,7 +450,7 @@ func parseDictionaryFromXLSXV3(buf []byte, locale string) (root *WordBankClassV3
 			rowCellStr[cellIdx] = strings.TrimSpace(cell.Value)
 		}
 		if strings.TrimSpace(strings.Join(rowCellStr, "")) == "" {
-			logger.Trace.Printf("Skip empty row %d\n", idx+1)
+			logger.Trace.Printf("Skip empty row %d\n", rowNum)
 			continue
 		}
 
@@ -444,48 +460,48 @@ func parseDictionaryFromXLSXV3(buf []byte, locale string) (root *WordBankClassV3
 		similars := strings.Split(currentWordbankRow.SimilarWords, ",")
 		for _, similar := range similars {
 			if utf8.RuneCountInString(similar) > maxSimilaryLen {
-				err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorSimilarTooLongTpl"), idx+1)
-				return
+				addRowErr(rowNum, 5, fmt.Sprintf(localemsg.Get(locale, "DictionaryErrorSimilarTooLongTpl"), rowNum))
+				break
 			}
 		}
 
-		if currentWordbankRow.IsExtendedRow() {
+		if currentWordbankRow.IsExtendedRow() && lastWordbankRow != nil {
 			lastWordbankRow.SimilarWords += "," + currentWordbankRow.SimilarWords
 			continue
 		}
 
-		if utf8.RuneCountInString(currentWordbankRow.Level1) > maxDirNameLen {
-			err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorPathTooLongTpl"), idx+1)
-			return
-		}
-		if utf8.RuneCountInString(currentWordbankRow.Level2) > maxDirNameLen {
-			err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorPathTooLongTpl"), idx+1)
-			return
-		}
-		if utf8.RuneCountInString(currentWordbankRow.Level3) > maxDirNameLen {
-			err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorPathTooLongTpl"), idx+1)
-			return
-		}
-		if utf8.RuneCountInString(currentWordbankRow.Level4) > maxDirNameLen {
-			err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorPathTooLongTpl"), idx+1)
-			return
+		rowValid := true
+		levels := []string{currentWordbankRow.Level1, currentWordbankRow.Level2, currentWordbankRow.Level3, currentWordbankRow.Level4}
+		for levelIdx, level := range levels {
+			if utf8.RuneCountInString(level) > maxDirNameLen {
+				addRowErr(rowNum, levelIdx, fmt.Sprintf(localemsg.Get(locale, "DictionaryErrorPathTooLongTpl"), rowNum))
+				rowValid = false
+			}
 		}
 
 		if utf8.RuneCountInString(currentWordbankRow.Name) > maxNameLen {
-			err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorNameTooLongTpl"), idx+1)
-			return
+			addRowErr(rowNum, 4, fmt.Sprintf(localemsg.Get(locale, "DictionaryErrorNameTooLongTpl"), rowNum))
+			rowValid = false
 		}
 
-		err = fillV3RowWithLast(currentWordbankRow, lastWordbankRow)
-		if err != nil {
-			err = fmt.Errorf(localemsg.Get(locale, "DictionaryErrorRowErrorTpl"), idx+1, err.Error())
-			return
+		fillErr := fillV3RowWithLast(currentWordbankRow, lastWordbankRow)
+		if fillErr != nil {
+			addRowErr(rowNum, 0, fmt.Sprintf(localemsg.Get(locale, "DictionaryErrorRowErrorTpl"), rowNum, fillErr.Error()))
+			rowValid = false
+		}
+		if !rowValid {
+			continue
 		}
 
 		wordbankRowList = append(wordbankRowList, currentWordbankRow)
 		lastWordbankRow = currentWordbankRow
 	}
 
+	if len(rowErrs) > 0 {
+		err = rowErrs
+		return
+	}
+
 	if len(wordbankRowList) == 0 {
 		err = errors.New(localemsg.Get(locale, "DictionaryEmptyRows"))
 		return
@@ -0,0 +1,175 @@
+package integration
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"emotibot.com/emotigo/pkg/logger"
+	"github.com/siongui/gojianfan"
+)
+
+// Adapter integrates a chat platform with bot, a chat request goes through
+// Verify, Parse and Render in webhook handler, and Send in the reply queue
+type Adapter interface {
+	// Platform is the platform name sent to bot with user input
+	Platform() string
+	// Verify checks the request is sent by the platform. handled is true if the request
+	// is a handshake of platform, such as URL validation, and is already responded.
+	Verify(w http.ResponseWriter, req *Request) (handled bool, err error)
+	// Parse returns messages sent by users in the request
+	Parse(req *Request) ([]*Message, error)
+	// Render converts answers of bot into messages of platform
+	Render(req *Request, msg *Message, answers []*Answer) ([]interface{}, error)
+	// Send replies rendered messages to the user of msg
+	Send(req *Request, msg *Message, replies []interface{}) error
+	// Reset drops cached clients of app after its config is changed
+	Reset(appid string)
+}
+
+// commandAdapter is implemented by adapters which accept bot commands,
+// commands are answered by adapter without asking bot
+type commandAdapter interface {
+	Command(req *Request, msg *Message) ([]*Answer, bool)
+}
+
+// configGenerator is implemented by adapters which generate config values, such as tokens
+type configGenerator interface {
+	GenerateConfig(values map[string]string) map[string]string
+}
+
+// configValidator is implemented by adapters which check config values, such as URLs to post to
+type configValidator interface {
+	ValidateConfig(values map[string]string) error
+}
+
+// adapters is all supported platforms, key is the platform in URL
+var adapters = map[string]Adapter{
+	"line":       &lineAdapter{},
+	"workweixin": &workWeixinAdapter{},
+	"webhook":    &webhookAdapter{},
+	"telegram":   &telegramAdapter{},
+}
+
+// Request is a webhook request from platform with config of app
+type Request struct {
+	AppID  string
+	Config map[string]string
+	Locale string
+	HTTP   *http.Request
+	Body   []byte
+	// IdempotencyKey is the key of delivery being sent, which is empty when receiving
+	IdempotencyKey string
+}
+
+// httpRequest returns the original request with body rewound,
+// so it can be passed to platform SDKs which read body themselves
+func (req *Request) httpRequest() *http.Request {
+	req.HTTP.Body = ioutil.NopCloser(bytes.NewReader(req.Body))
+	return req.HTTP
+}
+
+func (req *Request) convertText(text string) string {
+	if req.Locale == "zhtw" {
+		return gojianfan.S2T(text)
+	}
+	return gojianfan.T2S(text)
+}
+
+// Message is a text message from user, it is stored with answers in outbox until replied
+type Message struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// ChatID is the conversation to reply to, which is the same as UserID in private chat
+	ChatID     string `json:"chat_id"`
+	Text       string `json:"text"`
+	ReplyToken string `json:"reply_token,omitempty"`
+	// Meta is platform specific values needed to reply
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// clientCache is the cache of platform clients, key is appid
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]interface{}
+}
+
+func (c *clientCache) get(appid string, create func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.clients[appid]; ok {
+		return client, nil
+	}
+	client, err := create()
+	if err != nil {
+		return nil, err
+	}
+	if c.clients == nil {
+		c.clients = map[string]interface{}{}
+	}
+	c.clients[appid] = client
+	return client, nil
+}
+
+func (c *clientCache) reset(appid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clients, appid)
+}
+
+// handleAdapterChat answers messages in request with bot, replies are sent via queue
+// to avoid webhook timeout
+func handleAdapterChat(w http.ResponseWriter, platform string, adapter Adapter, req *Request) {
+	handled, err := adapter.Verify(w, req)
+	if err != nil {
+		logger.Error.Println("Request verification fail: ", err.Error())
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if handled {
+		return
+	}
+
+	messages, err := adapter.Parse(req)
+	if err != nil {
+		logger.Error.Println("Parse request fail: ", err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, msg := range messages {
+		// Platforms redeliver messages if webhook is slow or failed, skip answered ones
+		if key := deliveryKey(platform, req.AppID, msg); key != "" {
+			exists, err := deliveryExists(key)
+			if err != nil {
+				logger.Error.Println("Check delivery fail: ", err.Error())
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if exists {
+				logger.Trace.Println("Skip answered message: ", key)
+				continue
+			}
+		}
+
+		var answers []*Answer
+		isCommand := false
+		if commander, ok := adapter.(commandAdapter); ok {
+			answers, isCommand = commander.Command(req, msg)
+		}
+		if !isCommand {
+			answers = NewAnswers(GetChatResult(req.AppID, msg.UserID, msg.Text, adapter.Platform()))
+		}
+
+		// Replies are sent from outbox to avoid webhook timeout
+		if _, err := enqueueDelivery(platform, req, msg, answers); err != nil {
+			logger.Error.Println("Enqueue delivery fail: ", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		logger.Trace.Printf("Queue %d answers to %s\n", len(answers), msg.ChatID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
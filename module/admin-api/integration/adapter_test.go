@@ -0,0 +1,299 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/QA"
+	"emotibot.com/emotigo/pkg/services/workweixin"
+)
+
+// adapterFixture creates requests of a platform for the conformance test.
+// newRequest returns a request of user "user1" sending "hello",
+// which is signed if sign is true.
+type adapterFixture struct {
+	config     func(server string) map[string]string
+	newRequest func(t *testing.T, config map[string]string, sign bool) *http.Request
+}
+
+var adapterFixtures = map[string]adapterFixture{
+	"line": {
+		config: func(server string) map[string]string {
+			return map[string]string{"token": "line-token", "secret": "line-secret", "endpoint": server}
+		},
+		newRequest: func(t *testing.T, config map[string]string, sign bool) *http.Request {
+			body := `{"events":[{"replyToken":"reply","type":"message","timestamp":1462629479859,` +
+				`"source":{"type":"user","userId":"user1"},"message":{"id":"1","type":"text","text":"hello"}}]}`
+			r := httptest.NewRequest(http.MethodPost, "/chat/line/app", strings.NewReader(body))
+			secret := config["secret"]
+			if !sign {
+				secret = "wrong"
+			}
+			hash := hmac.New(sha256.New, []byte(secret))
+			hash.Write([]byte(body))
+			r.Header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(hash.Sum(nil)))
+			return r
+		},
+	},
+	"workweixin": {
+		config: func(server string) map[string]string {
+			key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32))
+			return map[string]string{"token": "ww-token", "encoded-aes": key[:43], "corpid": "corp", "secret": "ww-secret"}
+		},
+		newRequest: newWorkWeixinTestRequest,
+	},
+	"webhook": {
+		config: func(server string) map[string]string {
+			return map[string]string{"token": "webhook-token", "callback": server + "/callback"}
+		},
+		newRequest: func(t *testing.T, config map[string]string, sign bool) *http.Request {
+			body := []byte(`{"message_id":"1","user_id":"user1","text":"hello"}`)
+			r := httptest.NewRequest(http.MethodPost, "/chat/webhook/app", bytes.NewReader(body))
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			token := config["token"]
+			if !sign {
+				token = "wrong"
+			}
+			r.Header.Set(webhookTimestampHeader, timestamp)
+			r.Header.Set(webhookSignatureHeader, hex.EncodeToString(signWebhook(token, timestamp, body)))
+			return r
+		},
+	},
+	"telegram": {
+		config: func(server string) map[string]string {
+			return map[string]string{"token": "123:abc", "secret": "tg-secret", "api": server}
+		},
+		newRequest: func(t *testing.T, config map[string]string, sign bool) *http.Request {
+			body := `{"update_id":1,"message":{"message_id":1,"from":{"id":1},"chat":{"id":1},"text":"hello"}}`
+			r := httptest.NewRequest(http.MethodPost, "/chat/telegram/app", strings.NewReader(body))
+			if sign {
+				r.Header.Set(telegramSecretHeader, config["secret"])
+			}
+			return r
+		},
+	},
+}
+
+func newWorkWeixinTestRequest(t *testing.T, config map[string]string, sign bool) *http.Request {
+	key, err := base64.StdEncoding.DecodeString(config["encoded-aes"] + "=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte(`<xml><ToUserName>corp</ToUserName><FromUserName>user1</FromUserName>` +
+		`<CreateTime>1</CreateTime><MsgType>text</MsgType><Content>hello</Content>` +
+		`<MsgId>1</MsgId><AgentID>1</AgentID></xml>`)
+
+	// random(16) + length(4) + msg + corpid, padded to 32 bytes by PKCS#7
+	plain := bytes.Repeat([]byte("r"), 16)
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(msg)))
+	plain = append(append(append(plain, length...), msg...), config["corpid"]...)
+	pad := 32 - len(plain)%32
+	plain = append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, key[:16]).CryptBlocks(encrypted, plain)
+	encryptStr := base64.StdEncoding.EncodeToString(encrypted)
+
+	token := config["token"]
+	if !sign {
+		token = "wrong"
+	}
+	params := []string{token, "1", "nonce", encryptStr}
+	sort.Strings(params)
+	signature := fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(params, ""))))
+
+	body := fmt.Sprintf(`<xml><ToUserName>corp</ToUserName><AgentID>1</AgentID><Encrypt>%s</Encrypt></xml>`, encryptStr)
+	target := fmt.Sprintf("/chat/workweixin/app?msg_signature=%s&timestamp=1&nonce=nonce", signature)
+	return httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+}
+
+func newTestRequest(t *testing.T, appid string, config map[string]string, r *http.Request) *Request {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Request{AppID: appid, Config: config, HTTP: r, Body: body}
+}
+
+var testAnswers = []*Answer{
+	{Type: AnswerTypeText, Text: "text"},
+	{Type: AnswerTypeButtons, Text: "question", Options: []string{"a", "b"}},
+	{Type: AnswerTypeCard, Title: "title", Text: "card", URL: "http://host/a.jpg", Link: "http://host/page"},
+	{Type: AnswerTypeImage, URL: "http://host/a.jpg"},
+	{Type: AnswerTypeFile, URL: "http://host/a.pdf", Name: "a.pdf"},
+}
+
+// TestAdapterConformance checks every adapter verifies, parses, renders all answer types and sends
+func TestAdapterConformance(t *testing.T) {
+	var sent int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&sent, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	// test server listens on loopback, which webhook client refuses
+	defer func(client *http.Client) { webhookClient = client }(webhookClient)
+	webhookClient = server.Client()
+
+	// work weixin sends to fixed hosts
+	defer func(send func(*workweixin.Client, []interface{}) error) { workWeixinSend = send }(workWeixinSend)
+	workWeixinSend = func(bot *workweixin.Client, replies []interface{}) error {
+		atomic.AddInt32(&sent, int32(len(replies)))
+		return nil
+	}
+
+	for platform, adapter := range adapters {
+		fixture, ok := adapterFixtures[platform]
+		if !ok {
+			t.Errorf("%s: adapter has no conformance fixture", platform)
+			continue
+		}
+		appid := "app-" + platform
+		config := fixture.config(server.URL)
+
+		if adapter.Platform() == "" {
+			t.Errorf("%s: expect platform name of bot", platform)
+		}
+
+		req := newTestRequest(t, appid, config, fixture.newRequest(t, config, false))
+		if _, err := adapter.Verify(httptest.NewRecorder(), req); err == nil {
+			t.Errorf("%s: expect request with invalid signature rejected", platform)
+		}
+
+		req = newTestRequest(t, appid, config, fixture.newRequest(t, config, true))
+		handled, err := adapter.Verify(httptest.NewRecorder(), req)
+		if err != nil || handled {
+			t.Errorf("%s: expect signed message accepted, but got %v, %v", platform, handled, err)
+			continue
+		}
+
+		messages, err := adapter.Parse(req)
+		if err != nil {
+			t.Errorf("%s: parse fail: %v", platform, err)
+			continue
+		}
+		if len(messages) != 1 || messages[0].Text != "hello" || messages[0].UserID == "" || messages[0].ChatID == "" {
+			t.Errorf("%s: expect a hello message from user, but got %+v", platform, messages)
+			continue
+		}
+
+		for _, answer := range testAnswers {
+			replies, err := adapter.Render(req, messages[0], []*Answer{answer})
+			if err != nil || len(replies) == 0 {
+				t.Errorf("%s: expect %s answer rendered, but got %v, %v", platform, answer.Type, replies, err)
+			}
+		}
+
+		replies, err := adapter.Render(req, messages[0], testAnswers)
+		if err != nil {
+			t.Errorf("%s: render fail: %v", platform, err)
+			continue
+		}
+		atomic.StoreInt32(&sent, 0)
+		if err = adapter.Send(req, messages[0], replies); err != nil {
+			t.Errorf("%s: send fail: %v", platform, err)
+		}
+		if atomic.LoadInt32(&sent) == 0 {
+			t.Errorf("%s: expect replies sent to platform", platform)
+		}
+		adapter.Reset(appid)
+	}
+}
+
+func TestTelegramSecretRequired(t *testing.T) {
+	adapter := &telegramAdapter{}
+	body := `{"update_id":1,"message":{"message_id":1,"from":{"id":1},"chat":{"id":1},"text":"hello"}}`
+	config := map[string]string{"token": "123:abc"}
+	req := newTestRequest(t, "app", config,
+		httptest.NewRequest(http.MethodPost, "/chat/telegram/app", strings.NewReader(body)))
+	if _, err := adapter.Verify(httptest.NewRecorder(), req); err == nil {
+		t.Error("expect request rejected without secret")
+	}
+
+	config = adapter.GenerateConfig(config)
+	if len(config["secret"]) != 32 {
+		t.Errorf("expect secret generated, but got %q", config["secret"])
+	}
+	if config = adapter.GenerateConfig(map[string]string{"secret": "given"}); config["secret"] != "given" {
+		t.Errorf("expect given secret kept, but got %q", config["secret"])
+	}
+}
+
+func TestNewAnswers(t *testing.T) {
+	answers := NewAnswers([]*QA.BFOPOpenapiAnswer{
+		nil,
+		{Type: "text", SubType: "text", Value: `<p>hi</p><p><img src="http://host/a.jpg"></p>`},
+		{Type: "text", SubType: "text", Value: "plain"},
+		{Type: "text", SubType: "relatelist", Value: "question", Data: []interface{}{"a", "b"}},
+		{Type: "url", SubType: "image", Value: "http://host/b.jpg"},
+		{Type: "url", SubType: "docs", Value: "http://host/a.pdf",
+			Data: []interface{}{map[string]interface{}{"name": "a.pdf"}}},
+		{Type: "card", Value: "card", Data: []interface{}{map[string]interface{}{
+			"title": "title", "image": "http://host/c.jpg", "url": "http://host/page"}}},
+		{Type: "cmd", Value: "command"},
+	})
+
+	expected := []Answer{
+		{Type: AnswerTypeText, Text: "hi"},
+		{Type: AnswerTypeImage, URL: "http://host/a.jpg"},
+		{Type: AnswerTypeText, Text: "plain"},
+		{Type: AnswerTypeButtons, Text: "question", Options: []string{"a", "b"}},
+		{Type: AnswerTypeImage, URL: "http://host/b.jpg"},
+		{Type: AnswerTypeFile, URL: "http://host/a.pdf", Name: "a.pdf"},
+		{Type: AnswerTypeCard, Text: "card", Title: "title", URL: "http://host/c.jpg", Link: "http://host/page"},
+		{Type: AnswerTypeText, Text: "command"},
+	}
+	if len(answers) != len(expected) {
+		t.Fatalf("expect %d answers, but got %d", len(expected), len(answers))
+	}
+	for idx := range expected {
+		if fmt.Sprintf("%+v", *answers[idx]) != fmt.Sprintf("%+v", expected[idx]) {
+			t.Errorf("expect answer %d to be %+v, but got %+v", idx, expected[idx], *answers[idx])
+		}
+	}
+}
+
+func TestWebhookTokenRequired(t *testing.T) {
+	adapter := &webhookAdapter{}
+	body := []byte(`{"message_id":"1","user_id":"user1","text":"hello"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	r := httptest.NewRequest(http.MethodPost, "/chat/webhook/app", bytes.NewReader(body))
+	r.Header.Set(webhookTimestampHeader, timestamp)
+	r.Header.Set(webhookSignatureHeader, hex.EncodeToString(signWebhook("", timestamp, body)))
+	req := newTestRequest(t, "app", map[string]string{"callback": "http://host/callback"}, r)
+	if _, err := adapter.Verify(httptest.NewRecorder(), req); err == nil {
+		t.Error("expect request signed by empty token rejected")
+	}
+
+	config := adapter.GenerateConfig(map[string]string{})
+	if len(config["token"]) != 32 {
+		t.Errorf("expect token generated, but got %q", config["token"])
+	}
+	if err := adapter.ValidateConfig(map[string]string{"callback": "http://127.0.0.1/callback"}); err == nil {
+		t.Error("expect internal callback rejected")
+	}
+}
@@ -0,0 +1,141 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/QA"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Answer types which adapters render into platform messages
+const (
+	AnswerTypeText    = "text"
+	AnswerTypeButtons = "buttons"
+	AnswerTypeCard    = "card"
+	AnswerTypeImage   = "image"
+	AnswerTypeFile    = "file"
+)
+
+// AnswerTypes is all answer types an adapter should render
+var AnswerTypes = []string{AnswerTypeText, AnswerTypeButtons, AnswerTypeCard, AnswerTypeImage, AnswerTypeFile}
+
+// Answer is a platform independent answer of bot
+type Answer struct {
+	Type string `json:"type"`
+	// Text is the content of text, the question of buttons and the description of card
+	Text  string `json:"text,omitempty"`
+	Title string `json:"title,omitempty"`
+	// URL is the URL of image and file, or the image of card
+	URL string `json:"url,omitempty"`
+	// Link is the page opened by clicking card
+	Link string `json:"link,omitempty"`
+	// Name is the file name
+	Name    string   `json:"name,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// String returns answer in plain text, used by platforms without the answer type
+func (a *Answer) String() string {
+	lines := []string{}
+	for _, line := range []string{a.Title, a.Text, a.Name} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	for idx, opt := range a.Options {
+		lines = append(lines, fmt.Sprintf("%d. %s", idx+1, opt))
+	}
+	for _, line := range []string{a.Link, a.URL} {
+		if line != "" && a.Type != AnswerTypeImage {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewAnswers converts answers of BFOP openapi into answers of adapters.
+// HTML text is split into text and image answers by paragraphs.
+// Card answers have title, image and url in the first item of data.
+func NewAnswers(answers []*QA.BFOPOpenapiAnswer) []*Answer {
+	ret := []*Answer{}
+	for _, answer := range answers {
+		if answer == nil {
+			continue
+		}
+
+		switch {
+		case answer.Type == "text" && (answer.SubType == "relatelist" || answer.SubType == "guslist") &&
+			len(answer.Data) > 0:
+			options := []string{}
+			for _, d := range answer.Data {
+				options = append(options, fmt.Sprintf("%v", d))
+			}
+			ret = append(ret, &Answer{Type: AnswerTypeButtons, Text: answer.Value, Options: options})
+		case answer.Type == "text" && answer.SubType == "text":
+			ret = append(ret, newHTMLAnswers(answer.ToString())...)
+		case answer.Type == "url" && answer.SubType == "image":
+			ret = append(ret, &Answer{Type: AnswerTypeImage, URL: answer.Value})
+		case answer.Type == "url" && answer.SubType == "docs":
+			ret = append(ret, &Answer{
+				Type: AnswerTypeFile,
+				URL:  answer.Value,
+				Name: answerDataValue(answer, "name"),
+			})
+		case answer.Type == "card":
+			ret = append(ret, &Answer{
+				Type:  AnswerTypeCard,
+				Text:  answer.Value,
+				Title: answerDataValue(answer, "title"),
+				URL:   answerDataValue(answer, "image"),
+				Link:  answerDataValue(answer, "url"),
+			})
+		default:
+			if text := answer.ToString(); text != "" {
+				ret = append(ret, &Answer{Type: AnswerTypeText, Text: text})
+			}
+		}
+	}
+	return ret
+}
+
+// newHTMLAnswers splits paragraphs and images of HTML into answers,
+// text without paragraph is returned as a single text answer
+func newHTMLAnswers(text string) []*Answer {
+	html, err := goquery.NewDocumentFromReader(strings.NewReader(text))
+	if err != nil {
+		return []*Answer{{Type: AnswerTypeText, Text: text}}
+	}
+
+	filterTags := html.Find("p, img")
+	if len(filterTags.Nodes) == 0 {
+		return []*Answer{{Type: AnswerTypeText, Text: strings.TrimSpace(html.Find("body").Text())}}
+	}
+
+	ret := []*Answer{}
+	filterTags.Each(func(i int, s *goquery.Selection) {
+		switch s.Nodes[0].Data {
+		case "img":
+			if src, ok := s.Attr("src"); ok && src != "" {
+				ret = append(ret, &Answer{Type: AnswerTypeImage, URL: src})
+			}
+		case "p":
+			if content := strings.TrimSpace(s.Text()); content != "" {
+				ret = append(ret, &Answer{Type: AnswerTypeText, Text: content})
+			}
+		}
+	})
+	return ret
+}
+
+func answerDataValue(answer *QA.BFOPOpenapiAnswer, key string) string {
+	if len(answer.Data) == 0 {
+		return ""
+	}
+	if data, ok := answer.Data[0].(map[string]interface{}); ok {
+		if value, ok := data[key].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -2,19 +2,19 @@ package integration
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync"
 
 	robotConfig "emotibot.com/emotigo/module/admin-api/Robot/config.v1"
-	"emotibot.com/emotigo/pkg/services/workweixin"
 
 	"emotibot.com/emotigo/pkg/misc/adminerrors"
 
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 
-	"github.com/siongui/gojianfan"
-
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
 	"emotibot.com/emotigo/pkg/logger"
 
@@ -23,11 +23,7 @@ import (
 
 var (
 	// ModuleInfo is needed for module define
-	ModuleInfo      util.ModuleInfo
-	textConverter   = gojianfan.T2S
-	queueSize       = 10
-	lineTaskQueue   = make(chan *lineTask, queueSize)
-	workWeixinQueue = make(chan *workWeixinTask, queueSize)
+	ModuleInfo util.ModuleInfo
 )
 
 func init() {
@@ -42,36 +38,14 @@ func init() {
 			//util.NewEntryPoint("GET", "config/{platform}/{appid}", []string{"view"}, handleGetConfig),
 			util.NewEntryPoint("POST", "config/{platform}", []string{}, handleSetConfig),
 			util.NewEntryPoint("DELETE", "config/{platform}", []string{}, handleDeleteConfig),
+			util.NewEntryPoint("GET", "deliveries", []string{"view"}, handleGetDeliveries),
+			util.NewEntryPoint("POST", "deliveries/{id}/retry", []string{"edit"}, handleRetryDelivery),
+		},
+		OneTimeFunc: map[string]func(){
+			"BackfillLineFlexMedia": backfillLineFlexMedia,
 		},
 	}
-	go sendFromQueue()
-}
-
-// sendFromQueue will get reply task from queue to avoid webhook timeout
-func sendFromQueue() {
-	for {
-		select {
-		case task := <-lineTaskQueue:
-			if task != nil {
-				logger.Trace.Printf("Send %d line reply\n", len(task.Messages))
-				if _, err := task.Bot.ReplyMessage(task.ReplyToken, task.Messages).Do(); err != nil {
-					logger.Error.Println("Reply message fail: ", err.Error())
-				}
-			}
-		case task := <-workWeixinQueue:
-			if task != nil {
-				logger.Trace.Printf("Send %d work weixin reply\n", len(task.Messages))
-				if err := task.Bot.SendMessages(task.Messages); err != nil {
-					logger.Error.Println("Reply message fail: ", err.Error())
-				}
-			}
-		}
-	}
-}
-
-var handlers = map[string]func(w http.ResponseWriter, r *http.Request, appid string, config map[string]string){
-	"line":       handleLineReply,
-	"workweixin": handleWorkWeixinReply,
+	go deliverFromOutbox()
 }
 
 func handlePlatformChat(w http.ResponseWriter, r *http.Request) {
@@ -86,42 +60,68 @@ func handlePlatformChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if config cache is valid of not
-	var err error
-	key := fmt.Sprintf("%s-%s", appid, platform)
-	config, ok := configCache[key]
-	if !ok {
-		config, err = GetPlatformConfig(appid, platform)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			logger.Error.Println("Get platform conf failed: ", err.Error())
-			return
-		}
-		if len(config["token"]) == 0 {
-			w.WriteHeader(http.StatusInternalServerError)
-			logger.Error.Println("Get platform conf empty")
-			return
-		}
-		configCache[key] = config
-		logger.Trace.Println("Add cache")
+	config, err := loadPlatformConfig(appid, platform)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logger.Error.Println("Get platform conf failed: ", err.Error())
+		return
 	}
 
-	logger.Trace.Printf("Get platform config of %s, %s: %+v\n", appid, platform, config)
-
-	// Get handler via platfrom value, which is get from URL var
-	handler := handlers[platform]
-	if handler == nil {
+	// Get adapter via platfrom value, which is get from URL var
+	adapter := adapters[platform]
+	if adapter == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		logger.Error.Println("Unsupported platform:", platform)
 		return
 	}
-	handler(w, r, appid, config)
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logger.Error.Println("Read request fail: ", err.Error())
+		return
+	}
+	handleAdapterChat(w, platform, adapter, &Request{
+		AppID:  appid,
+		Config: config,
+		Locale: r.URL.Query().Get("locale"),
+		HTTP:   r,
+		Body:   body,
+	})
 }
 
 var configCache = map[string]map[string]string{}
+var configCacheLock = sync.Mutex{}
+
+// loadPlatformConfig gets config of platform from cache, or from DB if not cached
+func loadPlatformConfig(appid, platform string) (map[string]string, error) {
+	key := fmt.Sprintf("%s-%s", appid, platform)
+	configCacheLock.Lock()
+	config, ok := configCache[key]
+	configCacheLock.Unlock()
+	if ok {
+		return config, nil
+	}
+
+	config, err := GetPlatformConfig(appid, platform)
+	if err != nil {
+		return nil, err
+	}
+	if len(config["token"]) == 0 {
+		return nil, errors.New("platform config is empty")
+	}
+	configCacheLock.Lock()
+	configCache[key] = config
+	configCacheLock.Unlock()
+	logger.Trace.Println("Add cache")
+	return config, nil
+}
 
 func handleReloadPlatformConfig(w http.ResponseWriter, r *http.Request) {
+	configCacheLock.Lock()
 	configCache = map[string]map[string]string{}
+	configCacheLock.Unlock()
 }
 
 func handleGetConfig(w http.ResponseWriter, r *http.Request) {
@@ -145,6 +145,12 @@ func handleSetConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	values = generateConfigOfPlatform(platform, values)
+	if validator, ok := adapters[platform].(configValidator); ok {
+		if err := validator.ValidateConfig(values); err != nil {
+			util.ReturnError(w, adminerrors.ErrnoRequestError, err.Error())
+			return
+		}
+	}
 
 	configs, err := SetPlatformConfig(appid, platform, values)
 	if err != nil {
@@ -156,17 +162,13 @@ func handleSetConfig(w http.ResponseWriter, r *http.Request) {
 
 	// 更新配置缓存
 	key := fmt.Sprintf("%s-%s", appid, platform)
+	configCacheLock.Lock()
 	configCache[key] = configs
-
-	fullEncoded := strings.Replace(configs["encoded-aes"], "=", "", -1) + "="
-	bot, err1 := workweixin.New(configs["corpid"], configs["secret"], configs["token"], fullEncoded)
-	if err1 != nil {
-		err = AdminErrors.New(adminerrors.ErrnoDBError, err.Error())
-	} else {
-		// 更新配置缓存信息
-		workWeixinBot[appid] = bot
+	configCacheLock.Unlock()
+	// 清除平台客户端缓存，下次请求时以新配置创建
+	if adapter, ok := adapters[platform]; ok {
+		adapter.Reset(appid)
 	}
-	logger.Info.Println("workWeixinBot update : ", workWeixinBot)
 
 	util.Return(w, err, configs)
 }
@@ -177,17 +179,79 @@ func handleDeleteConfig(w http.ResponseWriter, r *http.Request) {
 	err := DeletePlatformConfig(appid, platform)
 	if err == nil {
 		// 更新配置缓存信息
-		workWeixinBot[appid] = nil
+		if adapter, ok := adapters[platform]; ok {
+			adapter.Reset(appid)
+		}
 		key := fmt.Sprintf("%s-%s", appid, platform)
-		configCache[key] = nil
+		configCacheLock.Lock()
+		delete(configCache, key)
+		configCacheLock.Unlock()
 	}
 
 	util.Return(w, err, nil)
 }
 
+func handleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	params := r.URL.Query()
+	query := &DeliveryQuery{
+		AppID:    appid,
+		Platform: params.Get("platform"),
+		Limit:    20,
+	}
+
+	if name := params.Get("status"); name != "" {
+		for status, statusName := range deliveryStatusNames {
+			if statusName == name {
+				s := status
+				query.Status = &s
+			}
+		}
+		if query.Status == nil {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, "status invalid")
+			return
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 || l > 100 {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, "limit invalid")
+			return
+		}
+		query.Limit = l
+	}
+	if page := params.Get("page"); page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil || p <= 0 {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, "page invalid")
+			return
+		}
+		query.Offset = (p - 1) * query.Limit
+	}
+
+	ret, err := GetDeliveries(query)
+	util.Return(w, err, ret)
+}
+
+func handleRetryDelivery(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	id, err := strconv.ParseInt(util.GetMuxVar(r, "id"), 10, 64)
+	if err != nil {
+		util.ReturnError(w, AdminErrors.ErrnoRequestError, "id invalid")
+		return
+	}
+
+	retried, retryErr := RetryDelivery(appid, id)
+	if retryErr == nil && !retried {
+		util.ReturnError(w, AdminErrors.ErrnoRequestError, "delivery is not failed")
+		return
+	}
+	util.Return(w, retryErr, nil)
+}
+
 func generateConfigOfPlatform(platform string, values map[string]string) map[string]string {
-	if platform == "workweixin" {
-		return generateWorkWeixinConfig(values)
+	if generator, ok := adapters[platform].(configGenerator); ok {
+		return generator.GenerateConfig(values)
 	}
 	return values
 }
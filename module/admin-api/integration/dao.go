@@ -1,6 +1,8 @@
 package integration
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -130,3 +132,330 @@ func deletePlatformConfig(appid, platform string) error {
 	_, err := db.Exec(sql, appid, platform)
 	return err
 }
+
+// getAllPlatformConfigs returns configs of platform of all apps, keyed by appid
+func getAllPlatformConfigs(platform string) (map[string]map[string]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := "SELECT appid, pkey, pvalue FROM integration WHERE appid != '' AND platform = ?"
+	rows, err := db.Query(queryStr, platform)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := map[string]map[string]string{}
+	for rows.Next() {
+		appid, key, value := "", "", ""
+		if err = rows.Scan(&appid, &key, &value); err != nil {
+			return nil, err
+		}
+		if _, ok := ret[appid]; !ok {
+			ret[appid] = map[string]string{}
+		}
+		ret[appid][key] = value
+	}
+	return ret, rows.Err()
+}
+
+const outboxTable = "integration_outbox"
+
+// deliveryErrorSize is the max length of last error of delivery
+const deliveryErrorSize = 1024
+
+const deliveryColumns = `id, appid, platform, idempotency_key, locale, message, answers,
+	status, attempts, next_attempt_at, last_error, created_at, updated_at`
+
+func scanDelivery(rows *sql.Rows) (*Delivery, error) {
+	delivery := &Delivery{}
+	message, answers := "", ""
+	err := rows.Scan(&delivery.ID, &delivery.AppID, &delivery.Platform, &delivery.IdempotencyKey,
+		&delivery.Locale, &message, &answers, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(message), &delivery.Message); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(answers), &delivery.Answers); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func deliveryExists(idempotencyKey string) (bool, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE idempotency_key = ?", outboxTable)
+	count := 0
+	err := db.QueryRow(queryStr, idempotencyKey).Scan(&count)
+	return count > 0, err
+}
+
+// insertDelivery stores delivery, inserted is false if delivery with the same idempotency key exists
+func insertDelivery(delivery *Delivery) (inserted bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	message, err := json.Marshal(delivery.Message)
+	if err != nil {
+		return
+	}
+	answers, err := json.Marshal(delivery.Answers)
+	if err != nil {
+		return
+	}
+
+	queryStr := fmt.Sprintf(`
+		INSERT IGNORE INTO %s (appid, platform, idempotency_key, locale, message, answers,
+			status, attempts, next_attempt_at, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, '', ?, ?)`, outboxTable)
+	result, err := db.Exec(queryStr, delivery.AppID, delivery.Platform, delivery.IdempotencyKey,
+		delivery.Locale, string(message), string(answers), delivery.Status, delivery.NextAttemptAt,
+		delivery.CreatedAt, delivery.UpdatedAt)
+	if err != nil {
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return
+	}
+	delivery.ID, err = result.LastInsertId()
+	return err == nil, err
+}
+
+// dueDeliveries returns deliveries to send at now, which are pending deliveries
+// whose next attempt time has come and sending deliveries whose lock expired
+func dueDeliveries(now int64, limit int) ([]*Delivery, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE status IN (?, ?) AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?`, deliveryColumns, outboxTable)
+	rows, err := db.Query(queryStr, DeliveryStatusPending, DeliveryStatusSending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []*Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, delivery)
+	}
+	return due, rows.Err()
+}
+
+// claimDelivery locks a due delivery until lockUntil for sending.
+// The row is claimed by comparing its status and next attempt time, so a delivery is
+// claimed by only one instance. The lock time of a claimed delivery is the claim,
+// which is checked by releaseDelivery, deliverySent and deliveryFailed.
+// Reclaiming a sending delivery whose claim expired counts an attempt.
+func claimDelivery(delivery *Delivery, now int64, lockUntil int64) (claimed bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	expired := 0
+	if delivery.Status == DeliveryStatusSending {
+		expired = 1
+	}
+	queryStr := fmt.Sprintf(`
+		UPDATE %s SET status = ?, next_attempt_at = ?, attempts = attempts + ?, updated_at = ?
+		WHERE id = ? AND status = ? AND next_attempt_at = ?`, outboxTable)
+	result, err := db.Exec(queryStr, DeliveryStatusSending, lockUntil, expired, now,
+		delivery.ID, delivery.Status, delivery.NextAttemptAt)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return
+	}
+
+	delivery.Status = DeliveryStatusSending
+	delivery.NextAttemptAt = lockUntil
+	delivery.Attempts += expired
+	return true, nil
+}
+
+// claimedDelivery is the condition of a delivery still claimed by the caller
+const claimedDelivery = "id = ? AND status = ? AND next_attempt_at = ?"
+
+// releaseDelivery puts a claimed delivery back to pending without counting an attempt,
+// released is false if the claim has expired and the delivery is claimed by others
+func releaseDelivery(delivery *Delivery, nextAttemptAt int64) (released bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("UPDATE %s SET status = ?, next_attempt_at = ? WHERE %s",
+		outboxTable, claimedDelivery)
+	result, err := db.Exec(queryStr, DeliveryStatusPending, nextAttemptAt,
+		delivery.ID, DeliveryStatusSending, delivery.NextAttemptAt)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// deliverySent marks a claimed delivery as sent, updated is false if the claim has expired
+func deliverySent(delivery *Delivery, now int64) (updated bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s SET status = ?, attempts = attempts + 1, last_error = '', updated_at = ?
+		WHERE %s`, outboxTable, claimedDelivery)
+	result, err := db.Exec(queryStr, DeliveryStatusSent, now,
+		delivery.ID, DeliveryStatusSending, delivery.NextAttemptAt)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// deliveryFailed records a failed attempt of a claimed delivery, updated is false if the claim has expired
+func deliveryFailed(delivery *Delivery, status int, attempts int, nextAttemptAt int64,
+	lastError string, now int64) (updated bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	if len(lastError) > deliveryErrorSize {
+		lastError = lastError[:deliveryErrorSize]
+	}
+	queryStr := fmt.Sprintf(`
+		UPDATE %s SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ?
+		WHERE %s`, outboxTable, claimedDelivery)
+	result, err := db.Exec(queryStr, status, attempts, nextAttemptAt, lastError, now,
+		delivery.ID, DeliveryStatusSending, delivery.NextAttemptAt)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// retryDelivery moves failed delivery of app to pending, retried is false if not found
+func retryDelivery(appid string, id int64, now int64) (retried bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND appid = ? AND status = ?`, outboxTable)
+	result, err := db.Exec(queryStr, DeliveryStatusPending, now, now, id, appid, DeliveryStatusFailed)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func deleteSentDeliveries(before int64) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("DELETE FROM %s WHERE status = ? AND updated_at < ?", outboxTable)
+	_, err := db.Exec(queryStr, DeliveryStatusSent, before)
+	return err
+}
+
+// countDeliveries returns the number of deliveries of app in each status
+func countDeliveries(appid string, platform string) (map[int]int64, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("SELECT status, COUNT(*) FROM %s WHERE appid = ?", outboxTable)
+	params := []interface{}{appid}
+	if platform != "" {
+		queryStr += " AND platform = ?"
+		params = append(params, platform)
+	}
+	queryStr += " GROUP BY status"
+
+	rows, err := db.Query(queryStr, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int64{}
+	for rows.Next() {
+		status, count := 0, int64(0)
+		if err = rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+func getDeliveries(query *DeliveryQuery) ([]*Delivery, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("SELECT %s FROM %s WHERE appid = ?", deliveryColumns, outboxTable)
+	params := []interface{}{query.AppID}
+	if query.Platform != "" {
+		queryStr += " AND platform = ?"
+		params = append(params, query.Platform)
+	}
+	if query.Status != nil {
+		queryStr += " AND status = ?"
+		params = append(params, *query.Status)
+	}
+	queryStr += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	params = append(params, query.Limit, query.Offset)
+
+	rows, err := db.Query(queryStr, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
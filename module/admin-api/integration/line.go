@@ -1,21 +1,21 @@
 package integration
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
-	"emotibot.com/emotigo/module/admin-api/QA"
 	"emotibot.com/emotigo/pkg/logger"
 	"emotibot.com/emotigo/pkg/services/linebot"
-	"github.com/siongui/gojianfan"
 )
 
-// lineBots is the cache of linebot client, key is appid, value is bot client
-var lineBots = map[string]*linebot.Client{}
-
-// lineConverters is the converter of reply message type
-var lineConverters = [](func(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessage){
+// lineConverters is the converter of buttons answer
+var lineConverters = [](func(req *Request, answer *Answer) linebot.SendingMessage){
 	createLineTextMessage,
 	createLineButtonTemplateMessage,
 	createLineFlexMessage,
@@ -24,113 +24,160 @@ var lineConverters = [](func(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessag
 // lineConverterName is used to show the converter name to bot command
 var lineConverterName = []string{"text", "button template", "flex"}
 
-// lineConverterIdx is setting of converter, key is appid
-var lineConverterIdx = map[string]int{}
+// lineAdapter receives LINE webhook events, config needs token and secret,
+// and endpoint if LINE API is accessed via proxy
+type lineAdapter struct {
+	// bots is the cache of linebot client
+	bots clientCache
+
+	mu sync.Mutex
+	// converterIdx is setting of converter, key is appid
+	converterIdx map[string]int
+}
+
+func (a *lineAdapter) Platform() string {
+	return PlatformLine
+}
+
+func (a *lineAdapter) Verify(w http.ResponseWriter, req *Request) (bool, error) {
+	if req.Config["token"] == "" || req.Config["secret"] == "" {
+		return false, errors.New("line config is not complete")
+	}
 
-func handleLineReply(w http.ResponseWriter, r *http.Request, appid string, config map[string]string) {
-	if config["token"] == "" || config["secret"] == "" {
-		return
+	decoded, err := base64.StdEncoding.DecodeString(req.HTTP.Header.Get("X-Line-Signature"))
+	if err != nil {
+		return false, linebot.ErrInvalidSignature
 	}
-	// If client is not created, create it with config
-	if _, ok := lineBots[appid]; !ok {
-		bot, err := linebot.New(config["secret"], config["token"])
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			logger.Error.Println("Linebot init fail: ", err.Error())
-			return
-		}
-		lineBots[appid] = bot
+	hash := hmac.New(sha256.New, []byte(req.Config["secret"]))
+	hash.Write(req.Body)
+	if !hmac.Equal(decoded, hash.Sum(nil)) {
+		return false, linebot.ErrInvalidSignature
 	}
+	return false, nil
+}
 
-	bot := lineBots[appid]
-	events, err := bot.ParseRequest(r)
+func (a *lineAdapter) Parse(req *Request) ([]*Message, error) {
+	events, err := linebot.ParseRequest(req.Config["secret"], req.httpRequest())
 	if err != nil {
-		if err == linebot.ErrInvalidSignature {
-			logger.Error.Println("Request signature check fail: ", err.Error())
-		} else {
-			logger.Error.Println("Unknown error: ", err.Error())
+		return nil, err
+	}
+
+	messages := []*Message{}
+	for _, event := range events {
+		if event.Type != linebot.EventTypeMessage {
+			continue
 		}
-		return
+		message, ok := event.Message.(*linebot.TextMessage)
+		if !ok {
+			continue
+		}
+
+		chatID := ""
+		switch event.Source.Type {
+		case linebot.EventSourceTypeGroup:
+			chatID = event.Source.GroupID
+		case linebot.EventSourceTypeRoom:
+			chatID = event.Source.RoomID
+		case linebot.EventSourceTypeUser:
+			chatID = event.Source.UserID
+		}
+		messages = append(messages, &Message{
+			ID:         message.ID,
+			UserID:     event.Source.UserID,
+			ChatID:     chatID,
+			Text:       message.Text,
+			ReplyToken: event.ReplyToken,
+		})
 	}
+	return messages, nil
+}
 
-	locale := r.URL.Query().Get("locale")
-	if locale == "zhtw" {
-		textConverter = gojianfan.S2T
-	} else {
-		textConverter = gojianfan.T2S
+// Command handles message with '##' prefix, which are commands for users for now
+func (a *lineAdapter) Command(req *Request, msg *Message) ([]*Answer, bool) {
+	if strings.Index(msg.Text, "##") != 0 {
+		return nil, false
 	}
-	if _, ok := lineConverterIdx[appid]; !ok {
-		lineConverterIdx[appid] = 0
+
+	command := strings.Replace(msg.Text, "##", "", 1)
+	logger.Trace.Println("command series,", command)
+	answers := []*Answer{}
+	// 'change' command will change message format converter
+	if command == "change" {
+		logger.Trace.Println("change converter")
+		a.mu.Lock()
+		if a.converterIdx == nil {
+			a.converterIdx = map[string]int{}
+		}
+		a.converterIdx[req.AppID] = (a.converterIdx[req.AppID] + 1) % len(lineConverters)
+		name := lineConverterName[a.converterIdx[req.AppID]]
+		a.mu.Unlock()
+		answers = append(answers, &Answer{Type: AnswerTypeText, Text: name})
 	}
+	return answers, true
+}
 
-	for _, event := range events {
-		switch event.Type {
-		case linebot.EventTypeMessage:
-			switch message := event.Message.(type) {
-			case *linebot.TextMessage:
-				lineAnswers := []linebot.SendingMessage{}
-
-				// message with '##' prefix will be commands for users for now
-				if strings.Index(message.Text, "##") == 0 {
-					logger.Trace.Println("command series,", strings.Replace(message.Text, "##", "", 1))
-					// 'change' command will change message format converter
-					if strings.Replace(message.Text, "##", "", 1) == "change" {
-						logger.Trace.Println("change converter")
-						lineConverterIdx[appid]++
-						lineConverterIdx[appid] = lineConverterIdx[appid] % len(lineConverters)
-						lineAnswers = append(lineAnswers, linebot.NewTextMessage(lineConverterName[lineConverterIdx[appid]]))
-					}
-				} else {
-					answers := GetChatResult(appid, event.Source.UserID, message.Text, PlatformLine)
-					for _, answer := range answers {
-						if answer == nil {
-							continue
-						}
-						if answer.Type == "text" {
-							if (answer.SubType == "relatelist" || answer.SubType == "guslist") && len(answer.Data) > 0 {
-								lineAnswers = append(lineAnswers, lineConverters[lineConverterIdx[appid]](answer))
-							} else {
-								lineAnswers = append(lineAnswers, linebot.NewTextMessage(textConverter(answer.ToString())))
-							}
-						}
-					}
-				}
-
-				sourceID := ""
-				switch event.Source.Type {
-				case linebot.EventSourceTypeGroup:
-					sourceID = event.Source.GroupID
-				case linebot.EventSourceTypeRoom:
-					sourceID = event.Source.RoomID
-				case linebot.EventSourceTypeUser:
-					sourceID = event.Source.UserID
-				}
-				logger.Trace.Printf("Reply %d messages to %s\n", len(lineAnswers), sourceID)
-				lineTaskQueue <- &lineTask{
-					Bot:        bot,
-					ReplyToken: event.ReplyToken,
-					Messages:   lineAnswers,
-				}
-
-				// if _, err := bot.ReplyMessage(event.ReplyToken, lineAnswers).Do(); err != nil {
-				// 	logger.Error.Println("Reply message fail: ", err.Error())
-				// }
-			}
+func (a *lineAdapter) Render(req *Request, msg *Message, answers []*Answer) ([]interface{}, error) {
+	a.mu.Lock()
+	converter := lineConverters[a.converterIdx[req.AppID]]
+	a.mu.Unlock()
+
+	replies := []interface{}{}
+	for _, answer := range answers {
+		var reply linebot.SendingMessage
+		switch answer.Type {
+		case AnswerTypeButtons:
+			reply = converter(req, answer)
+		case AnswerTypeImage:
+			reply = linebot.NewImageMessage(answer.URL, answer.URL)
+		case AnswerTypeCard:
+			reply = createLineCardMessage(req, answer)
+		default:
+			reply = createLineTextMessage(req, answer)
 		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+func (a *lineAdapter) Send(req *Request, msg *Message, replies []interface{}) error {
+	client, err := a.bots.get(req.AppID, func() (interface{}, error) {
+		options := []linebot.ClientOption{}
+		if req.Config["endpoint"] != "" {
+			options = append(options, linebot.WithEndpointBase(req.Config["endpoint"]))
+		}
+		return linebot.New(req.Config["secret"], req.Config["token"], options...)
+	})
+	if err != nil {
+		return err
+	}
+
+	messages := []linebot.SendingMessage{}
+	for _, reply := range replies {
+		messages = append(messages, reply.(linebot.SendingMessage))
 	}
+	_, err = client.(*linebot.Client).ReplyMessage(msg.ReplyToken, messages).Do()
+	// Reply token expires soon, retrying invalid reply is useless
+	if apiErr, ok := err.(*linebot.APIError); ok {
+		return statusError(apiErr.Code, err)
+	}
+	return err
+}
+
+func (a *lineAdapter) Reset(appid string) {
+	a.bots.reset(appid)
 }
 
-func createLineFlexMessage(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessage {
+func createLineFlexMessage(req *Request, answer *Answer) linebot.SendingMessage {
 	contents := []linebot.FlexComponent{
 		&linebot.TextComponent{
 			Type:  linebot.FlexComponentTypeText,
-			Text:  textConverter(answer.Value),
+			Text:  req.convertText(answer.Text),
 			Align: linebot.FlexComponentAlignTypeStart,
 			Wrap:  true,
 		},
 	}
-	for idx, d := range answer.Data {
-		opt := textConverter(d.(string))
+	for idx, d := range answer.Options {
+		opt := req.convertText(d)
 		contents = append(contents,
 			&linebot.TextComponent{
 				Type:   linebot.FlexComponentTypeText,
@@ -142,7 +189,7 @@ func createLineFlexMessage(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessage
 			})
 	}
 	return linebot.NewFlexMessage(
-		textConverter(answer.ToString()),
+		req.convertText(answer.String()),
 		&linebot.BubbleContainer{
 			Type: linebot.FlexContainerTypeBubble,
 			Body: &linebot.BoxComponent{
@@ -155,16 +202,36 @@ func createLineFlexMessage(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessage
 	)
 }
 
-func createLineButtonTemplateMessage(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessage {
+func createLineButtonTemplateMessage(req *Request, answer *Answer) linebot.SendingMessage {
 	options := []linebot.TemplateAction{}
-	for _, d := range answer.Data {
-		opt := textConverter(d.(string))
+	for _, d := range answer.Options {
+		opt := req.convertText(d)
 		options = append(options, linebot.NewMessageAction(opt, opt))
 	}
-	buttons := linebot.NewButtonsTemplate("", "", textConverter(answer.Value), options...)
-	return linebot.NewTemplateMessage(answer.ToString(), buttons)
+	buttons := linebot.NewButtonsTemplate("", "", req.convertText(answer.Text), options...)
+	return linebot.NewTemplateMessage(answer.String(), buttons)
+}
+
+// createLineCardMessage creates buttons template with image of card, clicking button opens link,
+// card without link is sent as text because template needs at least one action
+func createLineCardMessage(req *Request, answer *Answer) linebot.SendingMessage {
+	if answer.Link == "" {
+		return createLineTextMessage(req, answer)
+	}
+
+	label := []rune(req.convertText(answer.Title))
+	if len(label) == 0 {
+		label = []rune(answer.Link)
+	}
+	// label of action is limited to 20 characters
+	if len(label) > 20 {
+		label = label[:20]
+	}
+	card := linebot.NewButtonsTemplate(answer.URL, req.convertText(answer.Title),
+		req.convertText(answer.Text), linebot.NewURIAction(string(label), answer.Link))
+	return linebot.NewTemplateMessage(req.convertText(answer.String()), card)
 }
 
-func createLineTextMessage(answer *QA.BFOPOpenapiAnswer) linebot.SendingMessage {
-	return linebot.NewTextMessage(textConverter(answer.ToString()))
+func createLineTextMessage(req *Request, answer *Answer) linebot.SendingMessage {
+	return linebot.NewTextMessage(req.convertText(answer.String()))
 }
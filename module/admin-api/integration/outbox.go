@@ -0,0 +1,315 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/pkg/logger"
+	"emotibot.com/emotigo/pkg/misc/adminerrors"
+)
+
+// Status of deliveries in outbox
+const (
+	DeliveryStatusPending = iota
+	DeliveryStatusSending
+	DeliveryStatusSent
+	// DeliveryStatusFailed is the dead letter, which is not retried until retried by admin
+	DeliveryStatusFailed
+)
+
+var deliveryStatusNames = map[int]string{
+	DeliveryStatusPending: "pending",
+	DeliveryStatusSending: "sending",
+	DeliveryStatusSent:    "sent",
+	DeliveryStatusFailed:  "failed",
+}
+
+const (
+	deliveryMaxAttempts  = 8
+	deliveryBaseBackoff  = 2 * time.Second
+	deliveryMaxBackoff   = 10 * time.Minute
+	deliveryBatchSize    = 50
+	deliveryPollInterval = time.Second
+	// deliveryLockTimeout is the time a sending delivery is locked, a delivery of
+	// crashed process is sent again after the lock timeout.
+	// Deliveries are claimed one by one right before sending, so the lock only covers
+	// rendering and sending of one delivery.
+	deliveryLockTimeout = time.Minute
+	// deliveryRetention is the time sent deliveries are kept for admin view
+	deliveryRetention = 7 * 24 * time.Hour
+)
+
+// Delivery is an outbound reply in outbox, answers are rendered when sending,
+// so config changes are applied to pending deliveries
+type Delivery struct {
+	ID             int64     `json:"id"`
+	AppID          string    `json:"appid"`
+	Platform       string    `json:"platform"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Locale         string    `json:"locale"`
+	Message        *Message  `json:"message"`
+	Answers        []*Answer `json:"answers"`
+	Status         int       `json:"status"`
+	StatusText     string    `json:"status_text"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  int64     `json:"next_attempt_at"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      int64     `json:"created_at"`
+	UpdatedAt      int64     `json:"updated_at"`
+}
+
+// DeliveryQuery filters deliveries of app in admin view
+type DeliveryQuery struct {
+	AppID    string
+	Platform string
+	Status   *int
+	Limit    int
+	Offset   int
+}
+
+type DeliveriesResponse struct {
+	Counts map[string]int64 `json:"counts"`
+	Total  int64            `json:"total"`
+	Data   []*Delivery      `json:"data"`
+}
+
+// permanentError is a send error which retrying cannot fix, such as expired reply token,
+// delivery with permanent error goes to dead letter immediately
+type permanentError struct {
+	error
+}
+
+// statusError classifies error of platform API by status code,
+// client errors except rate limiting are permanent
+func statusError(status int, err error) error {
+	if status >= 400 && status < 500 && status != 429 {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// deliveryNotify wakes up outbox worker when a delivery is enqueued
+var deliveryNotify = make(chan struct{}, 1)
+
+// deliveryKey returns the idempotency key of reply to msg, messages redelivered
+// by platform have the same key. Empty key is returned if message has no ID.
+func deliveryKey(platform string, appid string, msg *Message) string {
+	if msg.ID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s:%s", platform, appid, msg.ID)
+}
+
+// enqueueDelivery stores answers of msg into outbox, queued is false if the message is already answered
+func enqueueDelivery(platform string, req *Request, msg *Message, answers []*Answer) (queued bool, err error) {
+	now := time.Now().Unix()
+	delivery := &Delivery{
+		AppID:          req.AppID,
+		Platform:       platform,
+		IdempotencyKey: deliveryKey(platform, req.AppID, msg),
+		Locale:         req.Locale,
+		Message:        msg,
+		Answers:        answers,
+		Status:         DeliveryStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if delivery.IdempotencyKey == "" {
+		delivery.IdempotencyKey = fmt.Sprintf("%s:%s:%s", platform, req.AppID, util.GenRandomString(32))
+	}
+
+	queued, err = insertDelivery(delivery)
+	if err != nil || !queued {
+		return
+	}
+
+	select {
+	case deliveryNotify <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// deliverFromOutbox sends pending deliveries, it is woken up by new deliveries or polling
+func deliverFromOutbox() {
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+	cleanupAt := time.Now()
+	limitsLoaded := false
+	for {
+		select {
+		case <-deliveryNotify:
+		case <-ticker.C:
+		}
+		if util.GetMainDB() == nil {
+			continue
+		}
+		// envs are loaded before DB is connected
+		if !limitsLoaded {
+			limitsLoaded = true
+			deliveryLimiter.setLimits(loadDeliveryRateLimits(util.GetEnvOf("server")))
+		}
+		deliverPending(time.Now())
+
+		if now := time.Now(); now.Sub(cleanupAt) > time.Hour {
+			cleanupAt = now
+			deliveryLimiter.cleanup(now)
+			if err := deleteSentDeliveries(now.Add(-deliveryRetention).Unix()); err != nil {
+				logger.Error.Println("Delete sent deliveries fail: ", err.Error())
+			}
+		}
+	}
+}
+
+func deliverPending(now time.Time) {
+	deliveries, err := dueDeliveries(now.Unix(), deliveryBatchSize)
+	if err != nil {
+		logger.Error.Println("Get due deliveries fail: ", err.Error())
+		return
+	}
+	for _, delivery := range deliveries {
+		claimAt := time.Now()
+		claimed, err := claimDelivery(delivery, claimAt.Unix(), claimAt.Add(deliveryLockTimeout).Unix())
+		if err != nil {
+			logger.Error.Printf("Claim delivery %d fail: %s\n", delivery.ID, err.Error())
+			return
+		}
+		if !claimed {
+			continue
+		}
+		// a delivery reclaimed after its claim expired counts the crashed attempt,
+		// so a delivery crashing the sender goes to dead letter eventually
+		if delivery.Attempts >= deliveryMaxAttempts {
+			recordDeliveryFailure(delivery, &permanentError{errors.New("delivery claim expired too many times")}, claimAt)
+			continue
+		}
+		deliver(delivery, claimAt)
+	}
+}
+
+// deliver renders and sends a claimed delivery, failed delivery is retried with exponential backoff
+func deliver(delivery *Delivery, now time.Time) {
+	var err error
+	adapter := adapters[delivery.Platform]
+	if adapter == nil {
+		err = &permanentError{fmt.Errorf("unsupported platform: %s", delivery.Platform)}
+		recordDeliveryFailure(delivery, err, now)
+		return
+	}
+
+	config, err := loadPlatformConfig(delivery.AppID, delivery.Platform)
+	if err != nil {
+		recordDeliveryFailure(delivery, err, now)
+		return
+	}
+	req := &Request{
+		AppID:          delivery.AppID,
+		Config:         config,
+		Locale:         delivery.Locale,
+		IdempotencyKey: delivery.IdempotencyKey,
+	}
+
+	replies, err := adapter.Render(req, delivery.Message, delivery.Answers)
+	if err != nil {
+		recordDeliveryFailure(delivery, &permanentError{err}, now)
+		return
+	}
+
+	if wait := deliveryLimiter.reserve(delivery, len(replies), now); wait > 0 {
+		released, err := releaseDelivery(delivery, now.Add(wait).Unix())
+		if err != nil {
+			logger.Error.Printf("Release delivery %d fail: %s\n", delivery.ID, err.Error())
+		} else if !released {
+			logger.Warn.Printf("Delivery %d is claimed by others before released\n", delivery.ID)
+		}
+		return
+	}
+
+	logger.Trace.Printf("Send %d %s reply\n", len(replies), delivery.Platform)
+	if err = adapter.Send(req, delivery.Message, replies); err != nil {
+		recordDeliveryFailure(delivery, err, now)
+		return
+	}
+	updated, err := deliverySent(delivery, time.Now().Unix())
+	if err != nil {
+		logger.Error.Printf("Update delivery %d fail: %s\n", delivery.ID, err.Error())
+	} else if !updated {
+		logger.Warn.Printf("Delivery %d is sent after its claim expired\n", delivery.ID)
+	}
+}
+
+func recordDeliveryFailure(delivery *Delivery, err error, now time.Time) {
+	logger.Error.Printf("Reply message %d fail: %s\n", delivery.ID, err.Error())
+
+	attempts := delivery.Attempts + 1
+	status := DeliveryStatusPending
+	nextAttemptAt := now.Add(deliveryBackoff(attempts)).Unix()
+	if _, ok := err.(*permanentError); ok || attempts >= deliveryMaxAttempts {
+		status = DeliveryStatusFailed
+		if attempts > deliveryMaxAttempts {
+			attempts = deliveryMaxAttempts
+		}
+	}
+
+	updated, err := deliveryFailed(delivery, status, attempts, nextAttemptAt, err.Error(), now.Unix())
+	if err != nil {
+		logger.Error.Printf("Update delivery %d fail: %s\n", delivery.ID, err.Error())
+	} else if !updated {
+		logger.Warn.Printf("Delivery %d failed after its claim expired\n", delivery.ID)
+	}
+}
+
+// deliveryBackoff returns the delay before the next attempt after attempts failures
+func deliveryBackoff(attempts int) time.Duration {
+	backoff := deliveryBaseBackoff
+	for i := 1; i < attempts && backoff < deliveryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > deliveryMaxBackoff {
+		backoff = deliveryMaxBackoff
+	}
+	return backoff
+}
+
+// GetDeliveries returns deliveries of app and the number of deliveries in each status
+func GetDeliveries(query *DeliveryQuery) (*DeliveriesResponse, adminerrors.AdminError) {
+	counts, err := countDeliveries(query.AppID, query.Platform)
+	if err != nil {
+		return nil, adminerrors.New(adminerrors.ErrnoDBError, err.Error())
+	}
+
+	response := &DeliveriesResponse{Counts: map[string]int64{}}
+	for status, name := range deliveryStatusNames {
+		response.Counts[name] = counts[status]
+		if query.Status == nil || *query.Status == status {
+			response.Total += counts[status]
+		}
+	}
+
+	response.Data, err = getDeliveries(query)
+	if err != nil {
+		return nil, adminerrors.New(adminerrors.ErrnoDBError, err.Error())
+	}
+	for _, delivery := range response.Data {
+		delivery.StatusText = deliveryStatusNames[delivery.Status]
+	}
+	return response, nil
+}
+
+// RetryDelivery moves a failed delivery of app back to pending with attempts reset
+func RetryDelivery(appid string, id int64) (bool, adminerrors.AdminError) {
+	retried, err := retryDelivery(appid, id, time.Now().Unix())
+	if err != nil {
+		return false, adminerrors.New(adminerrors.ErrnoDBError, err.Error())
+	}
+	if retried {
+		select {
+		case deliveryNotify <- struct{}{}:
+		default:
+		}
+	}
+	return retried, nil
+}
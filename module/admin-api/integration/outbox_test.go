@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeliveryBackoff(t *testing.T) {
+	expects := map[int]time.Duration{
+		1:  2 * time.Second,
+		2:  4 * time.Second,
+		5:  32 * time.Second,
+		9:  512 * time.Second,
+		10: deliveryMaxBackoff,
+		50: deliveryMaxBackoff,
+	}
+	for attempts, expect := range expects {
+		if backoff := deliveryBackoff(attempts); backoff != expect {
+			t.Errorf("Expect backoff %s after %d attempts, get %s", expect, attempts, backoff)
+		}
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	err := errors.New("fail")
+	for _, status := range []int{400, 401, 403, 404} {
+		if _, ok := statusError(status, err).(*permanentError); !ok {
+			t.Errorf("Expect status %d permanent", status)
+		}
+	}
+	for _, status := range []int{0, 429, 500, 503} {
+		if _, ok := statusError(status, err).(*permanentError); ok {
+			t.Errorf("Expect status %d retried", status)
+		}
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := &rateLimiter{}
+	now := time.Unix(1000000, 0)
+	newDelivery := func(chatID string) *Delivery {
+		return &Delivery{Platform: PlatformTelegram, AppID: "app", Message: &Message{ChatID: chatID}}
+	}
+	limit := deliveryRateLimits[PlatformTelegram]
+
+	// Burst is available at once, then the chat waits for refill
+	if wait := limiter.reserve(newDelivery("chat1"), 3, now); wait != 0 {
+		t.Errorf("Expect burst reserved, but wait %s", wait)
+	}
+	wait := limiter.reserve(newDelivery("chat1"), 3, now)
+	if expect := time.Duration((3 - float64(limit.Burst-3)) / limit.PerChat * float64(time.Second)); wait != expect {
+		t.Errorf("Expect wait %s for tokens of chat, get %s", expect, wait)
+	}
+
+	// Waiting reservation takes nothing, so the rest of app tokens are available to other chats
+	if wait := limiter.reserve(newDelivery("chat2"), limit.Burst-3, now); wait != 0 {
+		t.Errorf("Expect other chat not limited, but wait %s", wait)
+	}
+	if wait := limiter.reserve(newDelivery("chat2"), 1, now); wait == 0 {
+		t.Error("Expect other chat limited by app tokens")
+	}
+	if wait := limiter.reserve(newDelivery("chat1"), 2, now.Add(wait)); wait != 0 {
+		t.Errorf("Expect reserved after waiting, but wait %s", wait)
+	}
+
+	// Batch platforms take one token for all replies of a delivery
+	batch := &Delivery{Platform: PlatformLine, AppID: "app", Message: &Message{}}
+	for i := 0; i < deliveryRateLimits[PlatformLine].Burst; i++ {
+		if wait := limiter.reserve(batch, 5, now); wait != 0 {
+			t.Errorf("Expect batch delivery %d reserved, but wait %s", i, wait)
+			return
+		}
+	}
+	if wait := limiter.reserve(batch, 5, now); wait == 0 {
+		t.Error("Expect batch delivery limited after burst")
+	}
+
+	limiter.cleanup(now.Add(time.Hour))
+	if len(limiter.buckets) != 0 {
+		t.Errorf("Expect idle buckets dropped, get %d", len(limiter.buckets))
+	}
+}
+
+func TestLoadDeliveryRateLimits(t *testing.T) {
+	limits := loadDeliveryRateLimits(map[string]string{})
+	if limits[PlatformTelegram] != deliveryRateLimits[PlatformTelegram] {
+		t.Errorf("Expect default limits, get %+v", limits[PlatformTelegram])
+	}
+
+	limits = loadDeliveryRateLimits(map[string]string{
+		"INTEGRATION_DELIVERY_INSTANCES":    "4",
+		"INTEGRATION_RATE_TELEGRAM_PER_APP": "40",
+		"INTEGRATION_RATE_LINE_BURST":       "bad",
+	})
+	telegram := limits[PlatformTelegram]
+	if telegram.PerApp != 10 || telegram.PerChat != 0.25 || telegram.Burst != 1 {
+		t.Errorf("Expect telegram quota divided by instances, get %+v", telegram)
+	}
+	if line := limits[PlatformLine]; line.Burst != deliveryRateLimits[PlatformLine].Burst/4 || !line.Batch {
+		t.Errorf("Expect invalid burst ignored, get %+v", line)
+	}
+}
@@ -0,0 +1,183 @@
+package integration
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// rateLimit is the API quota of a platform. PerApp limits API calls of an app,
+// PerChat limits API calls to a conversation, zero means no limit.
+// Batch is true if all replies of a delivery are sent in one API call.
+type rateLimit struct {
+	PerApp  float64
+	PerChat float64
+	Burst   int
+	Batch   bool
+}
+
+// deliveryRateLimits is the default rate limits of platforms in calls per second
+var deliveryRateLimits = map[string]rateLimit{
+	// Messaging API allows 2,000 requests per second a channel
+	"line": {PerApp: 1000, Burst: 100, Batch: true},
+	// Work weixin allows 30 messages a minute to a member
+	"workweixin": {PerChat: 0.5, Burst: 30},
+	// Bot API allows 30 messages per second, and about 1 message per second to a chat
+	"telegram": {PerApp: 30, PerChat: 1, Burst: 5},
+	"webhook":  {PerApp: 50, Burst: 50, Batch: true},
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// loadDeliveryRateLimits returns rate limits of each admin-api process.
+// Limits of platform are overridden by envs such as INTEGRATION_RATE_TELEGRAM_PER_APP,
+// INTEGRATION_RATE_TELEGRAM_PER_CHAT and INTEGRATION_RATE_TELEGRAM_BURST.
+// Every process running the outbox worker has its own buckets, so the quota is divided
+// by INTEGRATION_DELIVERY_INSTANCES, which is the number of admin-api processes.
+func loadDeliveryRateLimits(envs map[string]string) map[string]rateLimit {
+	instances := 1
+	if value := envs["INTEGRATION_DELIVERY_INSTANCES"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			logger.Warn.Printf("Invalid INTEGRATION_DELIVERY_INSTANCES %s, use 1\n", value)
+		} else {
+			instances = n
+		}
+	}
+
+	limits := map[string]rateLimit{}
+	for platform, limit := range deliveryRateLimits {
+		prefix := "INTEGRATION_RATE_" + strings.ToUpper(platform)
+		limit.PerApp = envRate(envs, prefix+"_PER_APP", limit.PerApp)
+		limit.PerChat = envRate(envs, prefix+"_PER_CHAT", limit.PerChat)
+		limit.Burst = int(envRate(envs, prefix+"_BURST", float64(limit.Burst)))
+
+		limit.PerApp /= float64(instances)
+		limit.PerChat /= float64(instances)
+		limit.Burst /= instances
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		limits[platform] = limit
+	}
+	return limits
+}
+
+func envRate(envs map[string]string, key string, dft float64) float64 {
+	value := envs[key]
+	if value == "" {
+		return dft
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 {
+		logger.Warn.Printf("Invalid %s %s, use %v\n", key, value, dft)
+		return dft
+	}
+	return rate
+}
+
+// rateLimiter is token buckets of delivery rate limits, key is platform, appid and chat.
+// Buckets are kept in memory of each admin-api process, limits are the quota of
+// one process loaded by loadDeliveryRateLimits, deliveryRateLimits is used if not loaded.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]rateLimit
+	buckets map[string]*tokenBucket
+}
+
+func (l *rateLimiter) setLimits(limits map[string]rateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+var deliveryLimiter = &rateLimiter{}
+
+// reserve takes tokens of delivery if both app and chat buckets have enough tokens,
+// otherwise nothing is taken and the time to wait is returned
+func (l *rateLimiter) reserve(delivery *Delivery, replies int, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limits := l.limits
+	if limits == nil {
+		limits = deliveryRateLimits
+	}
+	limit, ok := limits[delivery.Platform]
+	if !ok {
+		return 0
+	}
+	cost := float64(replies)
+	if limit.Batch || cost < 1 {
+		cost = 1
+	}
+	if cost > float64(limit.Burst) {
+		cost = float64(limit.Burst)
+	}
+
+	if l.buckets == nil {
+		l.buckets = map[string]*tokenBucket{}
+	}
+
+	type reservation struct {
+		bucket *tokenBucket
+		rate   float64
+	}
+	reservations := []reservation{}
+	if limit.PerApp > 0 {
+		key := fmt.Sprintf("%s/%s", delivery.Platform, delivery.AppID)
+		reservations = append(reservations, reservation{l.bucket(key, limit, now), limit.PerApp})
+	}
+	if limit.PerChat > 0 {
+		key := fmt.Sprintf("%s/%s/%s", delivery.Platform, delivery.AppID, delivery.Message.ChatID)
+		reservations = append(reservations, reservation{l.bucket(key, limit, now), limit.PerChat})
+	}
+
+	var wait time.Duration
+	for _, r := range reservations {
+		r.bucket.tokens += now.Sub(r.bucket.last).Seconds() * r.rate
+		if r.bucket.tokens > float64(limit.Burst) {
+			r.bucket.tokens = float64(limit.Burst)
+		}
+		r.bucket.last = now
+		if r.bucket.tokens < cost {
+			lack := time.Duration((cost - r.bucket.tokens) / r.rate * float64(time.Second))
+			if lack > wait {
+				wait = lack
+			}
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, r := range reservations {
+		r.bucket.tokens -= cost
+	}
+	return 0
+}
+
+func (l *rateLimiter) bucket(key string, limit rateLimit, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+	return bucket
+}
+
+// cleanup drops buckets idle for a while, which are full and same as new buckets
+func (l *rateLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) > 10*time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
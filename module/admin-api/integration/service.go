@@ -7,11 +7,14 @@ import (
 	"emotibot.com/emotigo/pkg/misc/adminerrors"
 
 	"emotibot.com/emotigo/module/admin-api/QA"
+	"emotibot.com/emotigo/module/admin-api/media"
 	"emotibot.com/emotigo/pkg/logger"
 )
 
 const PlatformWorkWeixin = "微信"
 const PlatformLine = "line"
+const PlatformWebhook = "webhook"
+const PlatformTelegram = "telegram"
 
 func genPureTextNode(input string) *QA.BFOPOpenapiAnswer {
 	return &QA.BFOPOpenapiAnswer{
@@ -78,6 +81,7 @@ func SetPlatformConfig(appid, platform string, values map[string]string) (map[st
 	if err != nil {
 		return nil, adminerrors.New(adminerrors.ErrnoDBError, err.Error())
 	}
+	syncLineFlexMedia(appid, platform, configs)
 	return configs, nil
 }
 
@@ -86,5 +90,44 @@ func DeletePlatformConfig(appid, platform string) adminerrors.AdminError {
 	if err != nil {
 		return adminerrors.New(adminerrors.ErrnoDBError, err.Error())
 	}
+	syncLineFlexMedia(appid, platform, nil)
 	return nil
 }
+
+// lineFlexContents returns config values of LINE, which are used in flex messages
+func lineFlexContents(configs map[string]string) []string {
+	contents := []string{}
+	for _, value := range configs {
+		contents = append(contents, value)
+	}
+	return contents
+}
+
+// syncLineFlexMedia records images used by flex messages of LINE config in media library,
+// references are removed if configs is nil. Failure is only logged.
+func syncLineFlexMedia(appid, platform string, configs map[string]string) {
+	if platform != PlatformLine {
+		return
+	}
+	if adminErr := media.SyncReferences(appid, media.RefTypeLineFlex, platform, lineFlexContents(configs)...); adminErr != nil {
+		logger.Error.Printf("Sync media references of %s config fail: %s\n", platform, adminErr.Error())
+	}
+}
+
+// backfillLineFlexMedia records images used by LINE configs saved before media library
+func backfillLineFlexMedia() {
+	configs, err := getAllPlatformConfigs(PlatformLine)
+	if err != nil {
+		logger.Error.Printf("Get %s configs for media references fail: %s\n", PlatformLine, err.Error())
+		return
+	}
+	contents := map[string]map[string][]string{}
+	for appid, values := range configs {
+		contents[appid] = map[string][]string{
+			PlatformLine: lineFlexContents(values),
+		}
+	}
+	if adminErr := media.BackfillReferences(media.RefTypeLineFlex, contents); adminErr != nil {
+		logger.Error.Printf("Backfill media references of %s configs fail: %s\n", PlatformLine, adminErr.Error())
+	}
+}
@@ -0,0 +1,251 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+const (
+	telegramAPI          = "https://api.telegram.org"
+	telegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	// telegramCallbackDataLimit is the max bytes of callback data of inline keyboard button
+	telegramCallbackDataLimit = 64
+)
+
+type telegramUser struct {
+	ID int64 `json:"id"`
+}
+
+type telegramChat struct {
+	ID int64 `json:"id"`
+}
+
+type telegramMessage struct {
+	MessageID int64         `json:"message_id"`
+	From      *telegramUser `json:"from"`
+	Chat      *telegramChat `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+type telegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    *telegramUser    `json:"from"`
+	Message *telegramMessage `json:"message"`
+	Data    string           `json:"data"`
+}
+
+type telegramUpdate struct {
+	UpdateID      int64                  `json:"update_id"`
+	Message       *telegramMessage       `json:"message"`
+	CallbackQuery *telegramCallbackQuery `json:"callback_query"`
+}
+
+// telegramCall is a call of bot API method
+type telegramCall struct {
+	Method string
+	Params map[string]interface{}
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+// telegramAdapter receives updates of Telegram-style bot API, config needs token of bot,
+// secret to check the secret token header, and api if not the official API server.
+// The secret is generated if not given, it should be set as secret_token of setWebhook.
+// Pressed buttons are received as callback queries and answered like text messages.
+type telegramAdapter struct{}
+
+func (a *telegramAdapter) Platform() string {
+	return PlatformTelegram
+}
+
+func (a *telegramAdapter) Verify(w http.ResponseWriter, req *Request) (bool, error) {
+	secret := req.Config["secret"]
+	if req.Config["token"] == "" || secret == "" {
+		return false, errors.New("telegram config is not complete")
+	}
+	if subtle.ConstantTimeCompare([]byte(req.HTTP.Header.Get(telegramSecretHeader)), []byte(secret)) != 1 {
+		return false, errors.New("invalid telegram secret token")
+	}
+	return false, nil
+}
+
+func (a *telegramAdapter) Parse(req *Request) ([]*Message, error) {
+	update := telegramUpdate{}
+	if err := json.Unmarshal(req.Body, &update); err != nil {
+		return nil, err
+	}
+
+	messages := []*Message{}
+	if msg := update.Message; msg != nil && msg.From != nil && msg.Chat != nil && msg.Text != "" {
+		messages = append(messages, &Message{
+			ID:     strconv.FormatInt(msg.MessageID, 10),
+			UserID: strconv.FormatInt(msg.From.ID, 10),
+			ChatID: strconv.FormatInt(msg.Chat.ID, 10),
+			Text:   msg.Text,
+		})
+	}
+	if query := update.CallbackQuery; query != nil && query.From != nil && query.Message != nil &&
+		query.Message.Chat != nil && query.Data != "" {
+		messages = append(messages, &Message{
+			ID:     query.ID,
+			UserID: strconv.FormatInt(query.From.ID, 10),
+			ChatID: strconv.FormatInt(query.Message.Chat.ID, 10),
+			Text:   query.Data,
+			Meta:   map[string]string{"callback_query_id": query.ID},
+		})
+	}
+	return messages, nil
+}
+
+func (a *telegramAdapter) Render(req *Request, msg *Message, answers []*Answer) ([]interface{}, error) {
+	replies := []interface{}{}
+	// Stop the loading of pressed button
+	if queryID := msg.Meta["callback_query_id"]; queryID != "" {
+		replies = append(replies, &telegramCall{
+			Method: "answerCallbackQuery",
+			Params: map[string]interface{}{"callback_query_id": queryID},
+		})
+	}
+
+	for _, answer := range answers {
+		params := map[string]interface{}{"chat_id": msg.ChatID}
+		call := &telegramCall{Method: "sendMessage", Params: params}
+		switch answer.Type {
+		case AnswerTypeButtons:
+			params["text"] = req.convertText(answer.Text)
+			params["reply_markup"] = newTelegramKeyboard(req, answer.Options)
+		case AnswerTypeImage:
+			call.Method = "sendPhoto"
+			params["photo"] = answer.URL
+		case AnswerTypeFile:
+			call.Method = "sendDocument"
+			params["document"] = answer.URL
+			if answer.Name != "" {
+				params["caption"] = answer.Name
+			}
+		case AnswerTypeCard:
+			text := strings.TrimSpace(req.convertText(answer.Title + "\n" + answer.Text))
+			if answer.URL != "" {
+				call.Method = "sendPhoto"
+				params["photo"] = answer.URL
+				params["caption"] = text
+			} else {
+				params["text"] = text
+			}
+			if answer.Link != "" {
+				label := req.convertText(answer.Title)
+				if label == "" {
+					label = answer.Link
+				}
+				params["reply_markup"] = map[string]interface{}{
+					"inline_keyboard": [][]map[string]string{{{"text": label, "url": answer.Link}}},
+				}
+			}
+		default:
+			params["text"] = req.convertText(answer.String())
+		}
+		replies = append(replies, call)
+	}
+	return replies, nil
+}
+
+// newTelegramKeyboard creates inline keyboard with an option a row,
+// reply keyboard is used instead if any option is too long as callback data
+func newTelegramKeyboard(req *Request, options []string) map[string]interface{} {
+	inline := true
+	for _, opt := range options {
+		if len(req.convertText(opt)) > telegramCallbackDataLimit {
+			inline = false
+		}
+	}
+
+	rows := [][]map[string]string{}
+	for _, opt := range options {
+		opt = req.convertText(opt)
+		if inline {
+			rows = append(rows, []map[string]string{{"text": opt, "callback_data": opt}})
+		} else {
+			rows = append(rows, []map[string]string{{"text": opt}})
+		}
+	}
+	if inline {
+		return map[string]interface{}{"inline_keyboard": rows}
+	}
+	return map[string]interface{}{"keyboard": rows, "one_time_keyboard": true, "resize_keyboard": true}
+}
+
+func (a *telegramAdapter) Send(req *Request, msg *Message, replies []interface{}) error {
+	api := strings.TrimRight(req.Config["api"], "/")
+	if api == "" {
+		api = telegramAPI
+	}
+
+	errs := []string{}
+	for _, reply := range replies {
+		call := reply.(*telegramCall)
+		if err := callTelegram(api, req.Config["token"], call); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", call.Method, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (a *telegramAdapter) Reset(appid string) {}
+
+// GenerateConfig generates secret if not given
+func (a *telegramAdapter) GenerateConfig(values map[string]string) map[string]string {
+	if values["secret"] == "" {
+		values["secret"] = util.GenRandomString(32)
+	}
+	return values
+}
+
+func (a *telegramAdapter) ValidateConfig(values map[string]string) error {
+	if values["api"] == "" {
+		return nil
+	}
+	return checkConfigURL("api", values["api"])
+}
+
+func callTelegram(api string, token string, call *telegramCall) error {
+	body, err := json.Marshal(call.Params)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/%s", api, token, call.Method)
+	resp, err := webhookClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// Error of url contains the bot token
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	ret := telegramResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return fmt.Errorf("invalid response with status %d", resp.StatusCode)
+	}
+	if !ret.OK {
+		return statusError(ret.ErrorCode, errors.New(ret.Description))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+const (
+	webhookSignatureHeader = "X-Emotibot-Signature"
+	webhookTimestampHeader = "X-Emotibot-Timestamp"
+	// webhookIdempotencyHeader is the same in retries of a reply, so receivers can drop duplicates
+	webhookIdempotencyHeader = "X-Emotibot-Idempotency-Key"
+	// webhookSignatureTolerance is the max difference in seconds between timestamp of request and now
+	webhookSignatureTolerance = 300
+)
+
+// webhookClient posts to URLs of app config, such as callback of webhook and api of telegram,
+// so it refuses to connect to internal addresses
+var webhookClient = util.NewWebhookClient(time.Duration(10) * time.Second)
+
+// webhookInput is the message posted to generic webhook
+type webhookInput struct {
+	MessageID string `json:"message_id"`
+	UserID    string `json:"user_id"`
+	Text      string `json:"text"`
+}
+
+// webhookOutput is the reply posted to callback of generic webhook
+type webhookOutput struct {
+	ReplyTo string    `json:"reply_to"`
+	UserID  string    `json:"user_id"`
+	Answers []*Answer `json:"answers"`
+}
+
+// webhookAdapter is a generic webhook for platforms without an adapter.
+// Requests in both directions are signed by the token of config,
+// the signature is hex encoded HMAC-SHA256 of "<timestamp>.<body>".
+// Replies are posted to callback of config.
+type webhookAdapter struct{}
+
+func (a *webhookAdapter) Platform() string {
+	return PlatformWebhook
+}
+
+func (a *webhookAdapter) Verify(w http.ResponseWriter, req *Request) (bool, error) {
+	if req.Config["token"] == "" {
+		return false, errors.New("webhook config is not complete")
+	}
+	timestamp := req.HTTP.Header.Get(webhookTimestampHeader)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false, errors.New("invalid webhook timestamp")
+	}
+	if diff := time.Now().Unix() - ts; diff > webhookSignatureTolerance || diff < -webhookSignatureTolerance {
+		return false, errors.New("webhook timestamp expired")
+	}
+
+	signature, err := hex.DecodeString(req.HTTP.Header.Get(webhookSignatureHeader))
+	if err != nil || !hmac.Equal(signature, signWebhook(req.Config["token"], timestamp, req.Body)) {
+		return false, errors.New("invalid webhook signature")
+	}
+	return false, nil
+}
+
+func (a *webhookAdapter) Parse(req *Request) ([]*Message, error) {
+	input := webhookInput{}
+	if err := json.Unmarshal(req.Body, &input); err != nil {
+		return nil, err
+	}
+	if input.UserID == "" || input.Text == "" {
+		return nil, errors.New("user_id and text are required")
+	}
+
+	return []*Message{{
+		ID:     input.MessageID,
+		UserID: input.UserID,
+		ChatID: input.UserID,
+		Text:   input.Text,
+	}}, nil
+}
+
+// Render keeps answers as they are, so receivers get all answer types
+func (a *webhookAdapter) Render(req *Request, msg *Message, answers []*Answer) ([]interface{}, error) {
+	replies := []interface{}{}
+	for _, answer := range answers {
+		reply := *answer
+		reply.Text = req.convertText(answer.Text)
+		reply.Title = req.convertText(answer.Title)
+		options := []string{}
+		for _, opt := range answer.Options {
+			options = append(options, req.convertText(opt))
+		}
+		if len(options) > 0 {
+			reply.Options = options
+		}
+		replies = append(replies, &reply)
+	}
+	return replies, nil
+}
+
+func (a *webhookAdapter) Send(req *Request, msg *Message, replies []interface{}) error {
+	callback := req.Config["callback"]
+	if callback == "" {
+		return errors.New("webhook callback is not set")
+	}
+
+	output := webhookOutput{
+		ReplyTo: msg.ID,
+		UserID:  msg.UserID,
+		Answers: []*Answer{},
+	}
+	for _, reply := range replies {
+		output.Answers = append(output.Answers, reply.(*Answer))
+	}
+	body, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, callback, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhookTimestampHeader, timestamp)
+	request.Header.Set(webhookSignatureHeader, hex.EncodeToString(signWebhook(req.Config["token"], timestamp, body)))
+	if req.IdempotencyKey != "" {
+		request.Header.Set(webhookIdempotencyHeader, req.IdempotencyKey)
+	}
+
+	resp, err := webhookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(resp.StatusCode, fmt.Errorf("webhook callback responded with status %d", resp.StatusCode))
+	}
+	return nil
+}
+
+func (a *webhookAdapter) Reset(appid string) {}
+
+// GenerateConfig generates token if not given
+func (a *webhookAdapter) GenerateConfig(values map[string]string) map[string]string {
+	if values["token"] == "" {
+		values["token"] = util.GenRandomString(32)
+	}
+	return values
+}
+
+func (a *webhookAdapter) ValidateConfig(values map[string]string) error {
+	if values["callback"] == "" {
+		return nil
+	}
+	return checkConfigURL("callback", values["callback"])
+}
+
+// checkConfigURL checks URL of config which admin-api will post to, internal addresses are rejected
+func checkConfigURL(key string, target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s url: %s", key, target)
+	}
+	return util.CheckWebhookHost(u.Hostname())
+}
+
+func signWebhook(token string, timestamp string, body []byte) []byte {
+	hash := hmac.New(sha256.New, []byte(token))
+	hash.Write([]byte(timestamp))
+	hash.Write([]byte("."))
+	hash.Write(body)
+	return hash.Sum(nil)
+}
@@ -1,142 +1,180 @@
 package integration
 
 import (
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"emotibot.com/emotigo/module/admin-api/QA"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/pkg/logger"
 	"emotibot.com/emotigo/pkg/services/workweixin"
-	"github.com/PuerkitoBio/goquery"
-	"github.com/siongui/gojianfan"
 )
 
-var workWeixinBot = map[string]*workweixin.Client{}
+// workWeixinMedia is image or file reply, which is uploaded before sending
+type workWeixinMedia struct {
+	To      string
+	AgentID int
+	Answer  *QA.BFOPOpenapiAnswer
+}
+
+// workWeixinSend uploads media and sends replies, it is replaced in tests
+var workWeixinSend = sendWorkWeixinReplies
+
+// workWeixinAdapter receives work weixin callbacks, config needs token, encoded-aes, corpid and secret
+type workWeixinAdapter struct {
+	bots clientCache
+}
+
+func (a *workWeixinAdapter) Platform() string {
+	return PlatformWorkWeixin
+}
 
-func handleWorkWeixinReply(w http.ResponseWriter, r *http.Request, appid string, config map[string]string) {
+func (a *workWeixinAdapter) bot(req *Request) (*workweixin.Client, error) {
+	config := req.Config
 	if config["token"] == "" || config["encoded-aes"] == "" || config["corpid"] == "" || config["secret"] == "" {
-		return
+		return nil, errors.New("work weixin config is not complete")
 	}
+
 	// If client is not created, create it with config
-	if _, ok := workWeixinBot[appid]; !ok {
+	bot, err := a.bots.get(req.AppID, func() (interface{}, error) {
 		fullEncoded := strings.Replace(config["encoded-aes"], "=", "", -1) + "="
-		bot, err := workweixin.New(config["corpid"], config["secret"], config["token"], fullEncoded)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			logger.Error.Println("workWeixinBot init fail: ", err.Error())
-			return
+		return workweixin.New(config["corpid"], config["secret"], config["token"], fullEncoded)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bot.(*workweixin.Client), nil
+}
+
+func (a *workWeixinAdapter) Verify(w http.ResponseWriter, req *Request) (bool, error) {
+	bot, err := a.bot(req)
+	if err != nil {
+		return false, err
+	}
+
+	if req.HTTP.Method == http.MethodGet {
+		// Work weixin will use GET method to validate webhook validation
+		bot.VerifyURL(w, req.HTTP)
+		return true, nil
+	}
+
+	input := workweixin.Input{}
+	if err = xml.Unmarshal(req.Body, &input); err != nil {
+		return false, err
+	}
+	query := req.HTTP.URL.Query()
+	params := []string{req.Config["token"], query.Get("timestamp"), query.Get("nonce"), input.Encrypted}
+	sort.Strings(params)
+	signature := fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(params, ""))))
+	if signature != query.Get("msg_signature") {
+		return false, workweixin.ErrInvalidSignature
+	}
+	return false, nil
+}
+
+func (a *workWeixinAdapter) Parse(req *Request) ([]*Message, error) {
+	bot, err := a.bot(req)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := bot.ParseRequest(req.httpRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []*Message{}
+	if message, ok := msg.(*workweixin.TextMessage); ok {
+		logger.Trace.Printf("Receive: %s\n", message.Content)
+		messages = append(messages, &Message{
+			ID:     message.MsgID,
+			UserID: message.From,
+			ChatID: message.From,
+			Text:   message.Content,
+			Meta:   map[string]string{"agent_id": strconv.Itoa(message.AgentID)},
+		})
+	}
+	return messages, nil
+}
+
+func (a *workWeixinAdapter) Render(req *Request, msg *Message, answers []*Answer) ([]interface{}, error) {
+	agentID, err := strconv.Atoi(msg.Meta["agent_id"])
+	if err != nil {
+		return nil, errors.New("invalid agent id of work weixin message")
+	}
+
+	replies := []interface{}{}
+	for _, answer := range answers {
+		switch answer.Type {
+		case AnswerTypeImage:
+			replies = append(replies, &workWeixinMedia{
+				To:      msg.UserID,
+				AgentID: agentID,
+				Answer:  &QA.BFOPOpenapiAnswer{Type: "url", SubType: "image", Value: answer.URL},
+			})
+		case AnswerTypeFile:
+			replies = append(replies, &workWeixinMedia{
+				To:      msg.UserID,
+				AgentID: agentID,
+				Answer: &QA.BFOPOpenapiAnswer{Type: "url", SubType: "docs", Value: answer.URL,
+					Data: []interface{}{map[string]interface{}{"name": answer.Name}}},
+			})
+		default:
+			text := req.convertText(answer.String())
+			replies = append(replies, workweixin.NewTextMessage(msg.UserID, agentID, text))
 		}
-		workWeixinBot[appid] = bot
 	}
+	return replies, nil
+}
 
-	locale := r.URL.Query().Get("locale")
-	if locale == "zhtw" {
-		textConverter = gojianfan.S2T
-	} else {
-		textConverter = gojianfan.T2S
+func (a *workWeixinAdapter) Send(req *Request, msg *Message, replies []interface{}) error {
+	bot, err := a.bot(req)
+	if err != nil {
+		return err
 	}
+	return workWeixinSend(bot, replies)
+}
 
-	bot := workWeixinBot[appid]
-	bot.GetNewAccessToken()
-	logger.Info.Println("bot config: ", bot)
+func (a *workWeixinAdapter) Reset(appid string) {
+	a.bots.reset(appid)
+}
 
-	if r.Method == http.MethodGet {
-		// Work weixin will use GET method to validate webhook validation
-		bot.VerifyURL(w, r)
-		return
-	} else if r.Method == http.MethodPost {
-		msg, err := bot.ParseRequest(r)
+func (a *workWeixinAdapter) GenerateConfig(values map[string]string) map[string]string {
+	return generateWorkWeixinConfig(values)
+}
+
+func sendWorkWeixinReplies(bot *workweixin.Client, replies []interface{}) error {
+	// UploadMedia uses access token without refreshing
+	if err := bot.GetNewAccessToken(); err != nil {
+		return err
+	}
+
+	messages := []workweixin.SendingMessage{}
+	for _, reply := range replies {
+		media, ok := reply.(*workWeixinMedia)
+		if !ok {
+			messages = append(messages, reply)
+			continue
+		}
+
+		mediaID, err := bot.UploadMedia(media.Answer)
 		if err != nil {
-			logger.Error.Println("workWeixinBot parse fail: ", err.Error())
+			logger.Error.Println("Upload media fail: ", err.Error())
+			continue
 		}
-		switch message := msg.(type) {
-		case *workweixin.TextMessage:
-			logger.Trace.Printf("Receive: %s\n", message.Content)
-			answers := GetChatResult(appid, message.From, message.Content, PlatformWorkWeixin)
-
-			var replyMessages []workweixin.SendingMessage
-			for _, answer := range answers {
-				if answer == nil {
-					continue
-				}
-				var objMessage interface{}
-				if answer.Type == "text" && answer.SubType == "text" {
-					answerText := textConverter(answer.ToString())
-
-					html, err := goquery.NewDocumentFromReader(strings.NewReader(answerText))
-					if err != nil {
-						logger.Info.Println("answerText: ", answerText)
-						objMessage = workweixin.NewTextMessage(message.From, message.AgentID, answerText)
-					} else {
-						filterTags := html.Find("p, img")
-						if len(filterTags.Nodes) == 0 {
-							rText := html.Find("body").Text()
-							logger.Info.Println("rText: ", rText)
-							objMessage = workweixin.NewTextMessage(message.From, message.AgentID, strings.TrimSpace(rText))
-						} else {
-							filterTags.Each(func(i int, s *goquery.Selection) {
-								nodeTag := s.Nodes[0].Data
-								node := s.Nodes[0]
-								logger.Info.Println("nodeTag: ", nodeTag)
-								logger.Info.Println(node)
-								if nodeTag == "img" {
-									imgAnswer := QA.BFOPOpenapiAnswer{}
-									imgAnswer.Type = "url"
-									imgAnswer.SubType = "image"
-									imgAnswer.Value = node.Attr[0].Val
-									imgAnswer.Data = answer.Data
-									logger.Info.Println(imgAnswer)
-									mediaId, err := bot.UploadMedia(&imgAnswer)
-									if err == nil {
-										imgMessage := workweixin.NewImageMessage(message.From, message.AgentID, mediaId)
-										logger.Info.Println("imgMessage: ", imgMessage)
-										replyMessages = append(replyMessages, imgMessage)
-									}
-								}
-								if nodeTag == "p" {
-									txtMessage := workweixin.NewTextMessage(message.From, message.AgentID, strings.TrimSpace(s.Text()))
-									logger.Info.Println("txtMessage: ", txtMessage)
-									replyMessages = append(replyMessages, txtMessage)
-								}
-							})
-						}
-					}
-				}
-				// relatelist相关问，guslist相似问，guslist推荐问回答处理
-				if answer.Type == "text" && (answer.SubType == "relatelist" || answer.SubType == "guslist") {
-					objMessage = workweixin.NewTextMessage(message.From, message.AgentID, answer.ToString())
-				}
-				if answer.Type == "cmd" {
-					objMessage = workweixin.NewTextMessage(message.From, message.AgentID, textConverter(answer.ToString()))
-				}
-				if answer.Type == "url" && answer.SubType == "image" {
-					mediaId, err := bot.UploadMedia(answer)
-					if err == nil {
-						objMessage = workweixin.NewImageMessage(message.From, message.AgentID, mediaId)
-					}
-				}
-				if answer.Type == "url" && answer.SubType == "docs" {
-					mediaId, err := bot.UploadMedia(answer)
-					if err == nil {
-						objMessage = workweixin.NewFileMessage(message.From, message.AgentID, mediaId)
-					}
-				}
-
-				if objMessage != nil {
-					replyMessages = append(replyMessages, objMessage)
-				}
-			}
-
-			logger.Info.Println("replyMessages: ", replyMessages)
-
-			workWeixinQueue <- &workWeixinTask{
-				Bot:      bot,
-				Messages: replyMessages,
-			}
+		if media.Answer.SubType == "image" {
+			messages = append(messages, workweixin.NewImageMessage(media.To, media.AgentID, mediaID))
+		} else {
+			messages = append(messages, workweixin.NewFileMessage(media.To, media.AgentID, mediaID))
 		}
-		w.WriteHeader(http.StatusOK)
 	}
+	return bot.SendMessages(messages)
 }
 
 func generateWorkWeixinConfig(values map[string]string) map[string]string {
@@ -12,9 +12,11 @@ import (
 
 	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
 
+	"emotibot.com/emotigo/module/admin-api/dictionary"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
 	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/module/admin-api/util/validate"
@@ -23,6 +25,9 @@ import (
 
 func init() {
 	initV2Dao()
+	dictionary.RegisterWordbankReference("intents", getIntentSentences)
+	EntryList = append(EntryList,
+		importpreview.EntryPoints("import/preview", []string{"view"}, 2, moduleName, "file", parseImportIntentPreview)...)
 }
 
 func initV2Dao() {
@@ -501,3 +506,85 @@ func handleDeleteMultiIntentV2(w http.ResponseWriter, r *http.Request) {
 	err = DeleteIntents(appid, input.ID)
 	return
 }
+
+func handleGetVersionsV2(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+
+	versions, err := GetVersions(appid)
+	if err != nil {
+		util.Return(w, err, nil)
+		return
+	}
+	util.Return(w, nil, versions)
+}
+
+// parseVersionParam will parse version in query, empty or draft means the latest draft
+func parseVersionParam(r *http.Request, key string) (*int, error) {
+	value := strings.TrimSpace(r.URL.Query().Get(key))
+	if value == "" || value == "draft" {
+		return nil, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func handleDiffVersionsV2(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	locale := requestheader.GetLocale(r)
+
+	from, convertErr := parseVersionParam(r, "from")
+	if convertErr != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, localemsg.Get(locale, "IntentVersion")), nil)
+		return
+	}
+	to, convertErr := parseVersionParam(r, "to")
+	if convertErr != nil {
+		util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, localemsg.Get(locale, "IntentVersion")), nil)
+		return
+	}
+
+	diff, err := DiffVersions(appid, from, to)
+	if err != nil {
+		util.Return(w, err, nil)
+		return
+	}
+	util.Return(w, nil, diff)
+}
+
+func handleRollbackVersionV2(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	locale := requestheader.GetLocale(r)
+	version, convertErr := util.GetMuxIntVar(r, "version")
+	retrain := r.URL.Query().Get("retrain") == "true"
+	var err AdminErrors.AdminError
+	var ret interface{}
+
+	defer func() {
+		result := 0
+		auditMsg := fmt.Sprintf(localemsg.Get(locale, "RollbackIntentVersionTpl"), version)
+		if err == nil {
+			result = 1
+		} else {
+			ret = auditMsg
+		}
+		audit.AddAuditFromRequestAuto(r, auditMsg, result)
+		util.Return(w, err, ret)
+	}()
+
+	if convertErr != nil {
+		err = AdminErrors.New(AdminErrors.ErrnoRequestError, localemsg.Get(locale, "IntentVersion"))
+		return
+	}
+
+	newVersion, err := RollbackVersion(appid, version, retrain)
+	if err != nil {
+		return
+	}
+	if retrain {
+		ret = newVersion
+	}
+	return
+}
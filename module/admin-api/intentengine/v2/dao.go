@@ -44,6 +44,8 @@ type intentDaoInterface interface {
 	CommitIntent(appid string) (version int, ret []*IntentV2, err error)
 	NeedCommit(appid string) (ret bool, err error)
 	GetVersionInfo(appid string, version int) (ret *VersionInfoV2, err error)
+	// GetVersions will return all committed versions of appid, latest first
+	GetVersions(appid string) (ret []*VersionInfoV2, err error)
 	GetLatestVersion(appid string) (version int, err error)
 	UpdateLatestIntents(appid string, intents []*IntentV2) (err error)
 	UpdateVersionStart(version int, start int64, modelID string) (err error)
@@ -58,6 +60,13 @@ type intentDaoInterface interface {
 	DelIntentSentences(appid string, intentID int64, sentences []string) (err error)
 
 	GetUnfinishedTraining() (trainings []*TrainMission, err error)
+
+	// SetTrainDataOverride, GetTrainDataOverride and DeleteTrainDataOverride keep the
+	// training data of temporary appid used in evaluation, content is the json of train data.
+	// GetTrainDataOverride returns sql.ErrNoRows if appid has no override
+	SetTrainDataOverride(appid string, content string) error
+	GetTrainDataOverride(appid string) (content string, err error)
+	DeleteTrainDataOverride(appid string) error
 }
 
 // intentDaoV2 implement interface of intentDaoInterface, which will store for service to use
@@ -692,15 +701,16 @@ func (dao intentDaoV2) GetVersionInfo(appid string, version int) (ret *VersionIn
 		return nil, util.ErrDBNotInit
 	}
 	queryStr := `
-		SELECT ie_model_id, re_model_id, in_used, start_train, end_train, sentence_count, result
+		SELECT ie_model_id, re_model_id, in_used, commit_time, start_train, end_train, sentence_count, result
 		FROM intent_versions
 		WHERE appid = ? AND version = ?`
-	info := VersionInfoV2{}
+	info := VersionInfoV2{Version: version}
 	inUse, count := 0, 0
 	err = dao.db.QueryRow(queryStr, appid, version).Scan(
-		&info.IntentEngineModel, &info.RuleEngineModel, &inUse,
+		&info.IntentEngineModel, &info.RuleEngineModel, &inUse, &info.CommitTime,
 		&info.TrainStartTime, &info.TrainEndTime, &count, &info.TrainResult)
 	info.InUse = inUse != 0
+	info.SentenceCount = count
 	if info.TrainStartTime == nil {
 		info.Progress = 0
 	} else if info.TrainEndTime != nil {
@@ -717,6 +727,43 @@ func (dao intentDaoV2) GetVersionInfo(appid string, version int) (ret *VersionIn
 	}
 	return &info, nil
 }
+func (dao intentDaoV2) GetVersions(appid string) (ret []*VersionInfoV2, err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+	dao.checkDB()
+	if dao.db == nil {
+		return nil, util.ErrDBNotInit
+	}
+	queryStr := `
+		SELECT version, ie_model_id, in_used, commit_time, start_train, end_train, sentence_count, result
+		FROM intent_versions
+		WHERE appid = ?
+		ORDER BY version desc`
+	rows, err := dao.db.Query(queryStr, appid)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	ret = []*VersionInfoV2{}
+	for rows.Next() {
+		info := &VersionInfoV2{}
+		inUse := 0
+		err = rows.Scan(&info.Version, &info.IntentEngineModel, &inUse, &info.CommitTime,
+			&info.TrainStartTime, &info.TrainEndTime, &info.SentenceCount, &info.TrainResult)
+		if err != nil {
+			return nil, err
+		}
+		info.InUse = inUse != 0
+		if info.TrainEndTime != nil {
+			info.Progress = 100
+		}
+		ret = append(ret, info)
+	}
+	err = rows.Err()
+	return
+}
 func (dao intentDaoV2) GetLatestVersion(appid string) (version int, err error) {
 	defer func() {
 		util.ShowError(err)
@@ -779,6 +826,42 @@ func (dao intentDaoV2) UpdateLatestIntents(appid string, intents []*IntentV2) (e
 	err = tx.Commit()
 	return
 }
+func (dao intentDaoV2) SetTrainDataOverride(appid string, content string) (err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+	dao.checkDB()
+	if dao.db == nil {
+		return util.ErrDBNotInit
+	}
+
+	queryStr := "REPLACE INTO intent_train_data_overrides (appid, content, created_at) VALUES (?, ?, ?)"
+	_, err = dao.db.Exec(queryStr, appid, content, time.Now().Unix())
+	return
+}
+func (dao intentDaoV2) GetTrainDataOverride(appid string) (content string, err error) {
+	dao.checkDB()
+	if dao.db == nil {
+		return "", util.ErrDBNotInit
+	}
+
+	queryStr := "SELECT content FROM intent_train_data_overrides WHERE appid = ?"
+	err = dao.db.QueryRow(queryStr, appid).Scan(&content)
+	return
+}
+func (dao intentDaoV2) DeleteTrainDataOverride(appid string) (err error) {
+	defer func() {
+		util.ShowError(err)
+	}()
+	dao.checkDB()
+	if dao.db == nil {
+		return util.ErrDBNotInit
+	}
+
+	queryStr := "DELETE FROM intent_train_data_overrides WHERE appid = ?"
+	_, err = dao.db.Exec(queryStr, appid)
+	return
+}
 func (dao intentDaoV2) UpdateVersionStart(version int, start int64, modelID string) (err error) {
 	defer func() {
 		util.ShowError(err)
@@ -0,0 +1,68 @@
+package intentenginev2
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// SetTrainDataOverride makes the training data of appid returned from data directly
+// instead of the intents in DB. It is stored in DB because intent-engine may get
+// the training data from another admin-api instance.
+func SetTrainDataOverride(appid string, data *TrainDataResponse) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return dao.SetTrainDataOverride(appid, string(content))
+}
+
+// RemoveTrainDataOverride removes the training data set by SetTrainDataOverride
+func RemoveTrainDataOverride(appid string) error {
+	return dao.DeleteTrainDataOverride(appid)
+}
+
+// getTrainDataOverride returns nil if appid has no training data override
+func getTrainDataOverride(appid string) (*TrainDataResponse, error) {
+	content, err := dao.GetTrainDataOverride(appid)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := &TrainDataResponse{}
+	err = json.Unmarshal([]byte(content), ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// TrainIntentModel starts training of appid in intent-engine without updating
+// the intent versions in DB, and returns the model id to be checked
+func TrainIntentModel(appid string) (string, error) {
+	modelID, err := trainIntent(appid)
+	if err != nil {
+		return "", err
+	}
+	if modelID == "" {
+		return "", errors.New("no model id returned from intent-engine")
+	}
+	return modelID, nil
+}
+
+// IntentModelReady checks if the model is trained, error is returned if training failed
+func IntentModelReady(appid, modelID string) (bool, error) {
+	status, err := getIntentModelStatus(appid, modelID)
+	if err != nil {
+		return false, err
+	}
+	switch status.Status {
+	case statusIETrainReady:
+		return true, nil
+	case statusIETrainError:
+		return false, errors.New("intent-engine train fail")
+	}
+	return false, nil
+}
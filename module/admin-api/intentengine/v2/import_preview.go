@@ -0,0 +1,43 @@
+package intentenginev2
+
+import (
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+)
+
+// parseImportIntentPreview parses uploaded intents and diffs them with draft intents,
+// committing the preview replaces draft intents like handleImportIntentV2
+func parseImportIntentPreview(r *http.Request, appid string, upload *importpreview.Upload) (importpreview.RowErrors, *importpreview.Diff, func() error, AdminErrors.AdminError) {
+	locale := requestheader.GetLocale(r)
+	intents, err := ParseImportIntentFile(upload.Content, locale)
+	if err != nil {
+		return importpreview.ToRowErrors(err), nil, nil, nil
+	}
+
+	current, err := dao.GetIntentsDetail(appid, nil)
+	if err != nil {
+		return nil, nil, nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	diff := importpreview.DiffRecords(intentRecords(current), intentRecords(intents))
+
+	commit := func() error {
+		if err := UpdateLatestIntents(appid, intents); err != nil {
+			return err
+		}
+		return nil
+	}
+	return nil, diff, commit, nil
+}
+
+// intentRecords returns content of intents with intent name as key
+func intentRecords(intents []*IntentV2) map[string]string {
+	records := map[string]string{}
+	for _, intent := range intents {
+		records[intent.Name] = importpreview.RecordContent(sentenceContents(intent.Positive)...) + "\n|\n" +
+			importpreview.RecordContent(sentenceContents(intent.Negative)...)
+	}
+	return records
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -16,6 +17,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/dictionary"
 	"emotibot.com/emotigo/module/admin-api/util"
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/importpreview"
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 	"emotibot.com/emotigo/pkg/logger"
 	"github.com/tealeg/xlsx"
@@ -233,8 +235,8 @@ func trainIntent(appid string) (modelID string, err error) {
 
 const ErrRetryCount = 6
 
-func checkIntentModelStatus(appid, modelID string, version int, errRetry int) {
-	time.Sleep(time.Second * 5)
+// getIntentModelStatus gets the training status of model from intent-engine
+func getIntentModelStatus(appid, modelID string) (*IETrainStatus, error) {
 	payload := map[string]string{
 		"app_id":   appid,
 		"model_id": modelID,
@@ -249,11 +251,20 @@ func checkIntentModelStatus(appid, modelID string, version int, errRetry int) {
 
 	body, err := util.HTTPPostJSON(trainURL, payload, 30)
 	if err != nil {
-		return
+		return nil, err
 	}
 	logger.Trace.Printf("Get status of [%s][%s] from intent-engine: %s\n", appid, modelID, body)
 	ret := IETrainStatus{}
 	err = json.Unmarshal([]byte(body), &ret)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func checkIntentModelStatus(appid, modelID string, version int, errRetry int) {
+	time.Sleep(time.Second * 5)
+	ret, err := getIntentModelStatus(appid, modelID)
 	if err != nil {
 		return
 	}
@@ -270,7 +281,7 @@ func checkIntentModelStatus(appid, modelID string, version int, errRetry int) {
 		}
 	case statusIETrainReady:
 		dao.UpdateVersionStatus(appid, version, now, trainResultSuccess)
-		util.ConsulUpdateIntent(appid)
+		util.ConsulUpdateIntent(appid, &util.ConsulChange{Reason: "intent model trained"})
 
 		// Update autofills
 		autofill.UpdateAutofills(appid, &autofillData.AutofillOption{
@@ -283,6 +294,12 @@ func checkIntentModelStatus(appid, modelID string, version int, errRetry int) {
 }
 
 func GetTrainData(appid string) (*TrainDataResponse, AdminErrors.AdminError) {
+	override, err := getTrainDataOverride(appid)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	} else if override != nil {
+		return override, nil
+	}
 	wordbanks, errno, err := dictionary.GetWordbanksV3(appid)
 	if err != nil {
 		return nil, AdminErrors.New(errno, err.Error())
@@ -392,7 +409,8 @@ func ParseImportIntentFile(buf []byte, locale string) (intents []*IntentV2, err
 			format = typeBF2
 			break
 		}
-		if sheets[idx].Name == localemsg.Get(localemsg.ZhCn, "IntentBF2NewSheetName") {
+		if sheets[idx].Name == localemsg.Get(locale, "IntentBF2NewSheetName") ||
+			sheets[idx].Name == localemsg.Get(localemsg.ZhCn, "IntentBF2NewSheetName") {
 			format = typeBF2New
 		}
 	}
@@ -502,6 +520,8 @@ func getBF2ColumnIdx(row *xlsx.Row, locale string) (nameIdx, sentenceIdx int) {
 
 func parseBF2Sheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2, err error) {
 	intentMap := map[string]*IntentV2{}
+	// errors of rows are collected, so that all invalid rows can be shown at once
+	rowErrs := importpreview.RowErrors{}
 	for idx := range sheets {
 		var sentenceType int
 		if sheets[idx].Name == localemsg.Get(locale, "IntentBF2Sheet1Name") ||
@@ -523,12 +543,14 @@ func parseBF2Sheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2, e
 			return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadHeaderErrTpl"), sheets[idx].Name)
 		}
 
+		header := rows[0]
 		rows = rows[1:]
 		for rowIdx := range rows {
 			cells := rows[rowIdx].Cells
 			if len(cells) < 2 {
-				return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadBF2RowInvalidTpl"),
-					sheets[idx].Name, rowIdx+1)
+				rowErrs.Add(sheets[idx].Name, rowIdx+2, "", fmt.Sprintf(localemsg.Get(locale, "IntentUploadBF2RowInvalidTpl"),
+					sheets[idx].Name, rowIdx+1))
+				continue
 			}
 			name := strings.TrimSpace(cells[nameIdx].String())
 			sentence := strings.TrimSpace(cells[sentenceIdx].String())
@@ -536,12 +558,14 @@ func parseBF2Sheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2, e
 				continue
 			}
 			if name == "" {
-				return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadBF2RowNoNameTpl"),
-					sheets[idx].Name, rowIdx+1)
+				rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[nameIdx].String(), fmt.Sprintf(localemsg.Get(locale, "IntentUploadBF2RowNoNameTpl"),
+					sheets[idx].Name, rowIdx+1))
+				continue
 			}
 			if sentence == "" {
-				return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadBF2RowNoSentenceTpl"),
-					sheets[idx].Name, rowIdx+1)
+				rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[sentenceIdx].String(), fmt.Sprintf(localemsg.Get(locale, "IntentUploadBF2RowNoSentenceTpl"),
+					sheets[idx].Name, rowIdx+1))
+				continue
 			}
 			if utf8.RuneCountInString(name) > 35 || utf8.RuneCountInString(sentence) > 50 {
 			//	logger.Trace.Print("%d, %d", length, namelength)
@@ -566,6 +590,10 @@ func parseBF2Sheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2, e
 		}
 	}
 
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
 	intents = []*IntentV2{}
 	for name := range intentMap {
 		intents = append(intents, intentMap[name])
@@ -575,6 +603,7 @@ func parseBF2Sheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2, e
 
 func parseBF2NewSheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2, err error) {
 	intentMap := map[string]*IntentV2{}
+	rowErrs := importpreview.RowErrors{}
 	for idx := range sheets {
 		if sheets[idx].Name != localemsg.Get(locale, "IntentBF2NewSheetName") {
 			continue
@@ -590,6 +619,7 @@ func parseBF2NewSheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2
 			return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadHeaderErrTpl"), sheets[idx].Name)
 		}
 
+		header := rows[0]
 		rows = rows[1:]
 		for rowIdx := range rows {
 			cells := rows[rowIdx].Cells
@@ -610,12 +640,14 @@ func parseBF2NewSheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2
 				continue
 			}
 			if name == "" {
-				return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadBF2RowNoNameTpl"),
-					sheets[idx].Name, rowIdx+1)
+				rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[nameIdx].String(), fmt.Sprintf(localemsg.Get(locale, "IntentUploadBF2RowNoNameTpl"),
+					sheets[idx].Name, rowIdx+1))
+				continue
 			}
 			if posSentence == "" && negSentence == "" {
-				return nil, fmt.Errorf(localemsg.Get(locale, "IntentUploadBF2RowNoSentenceTpl"),
-					sheets[idx].Name, rowIdx+1)
+				rowErrs.Add(sheets[idx].Name, rowIdx+2, header.Cells[posIdx].String(), fmt.Sprintf(localemsg.Get(locale, "IntentUploadBF2RowNoSentenceTpl"),
+					sheets[idx].Name, rowIdx+1))
+				continue
 			}
 
 			if _, ok := intentMap[name]; !ok {
@@ -637,6 +669,10 @@ func parseBF2NewSheets(sheets []*xlsx.Sheet, locale string) (intents []*IntentV2
 		}
 	}
 
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
 	intents = []*IntentV2{}
 	for name := range intentMap {
 		intents = append(intents, intentMap[name])
@@ -778,3 +814,259 @@ func DelIntentSentences(appid string, intentID int64, sentences []string) error
 	}
 	return nil
 }
+
+// getIntentSentences returns names and sentences of the latest draft intents,
+// which is used to check if wordbanks are used by intents
+func getIntentSentences(appid string) ([]string, error) {
+	intents, err := dao.GetIntentsDetail(appid, nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, intent := range intents {
+		ret = append(ret, intent.Name)
+		ret = append(ret, sentenceContents(intent.Positive)...)
+		ret = append(ret, sentenceContents(intent.Negative)...)
+	}
+	return ret, nil
+}
+
+func GetVersions(appid string) ([]*VersionInfoV2, AdminErrors.AdminError) {
+	versions, err := dao.GetVersions(appid)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return versions, nil
+}
+
+// checkVersionExisted will return not found error if version is not committed in app,
+// because intents of unknown version are empty instead of error
+func checkVersionExisted(appid string, version int) AdminErrors.AdminError {
+	versions, err := GetVersions(appid)
+	if err != nil {
+		return err
+	}
+	for _, info := range versions {
+		if info.Version == version {
+			return nil
+		}
+	}
+	return AdminErrors.New(AdminErrors.ErrnoNotFound, fmt.Sprintf("version %d", version))
+}
+
+// getVersionIntents will return intents with sentences of version, nil version means the latest draft
+func getVersionIntents(appid string, version *int) ([]*IntentV2, AdminErrors.AdminError) {
+	if version != nil {
+		if err := checkVersionExisted(appid, *version); err != nil {
+			return nil, err
+		}
+	}
+	intents, err := dao.GetIntentsDetail(appid, version)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return intents, nil
+}
+
+// DiffVersions will compare intents between two versions, nil version means the latest draft
+func DiffVersions(appid string, from, to *int) (*IntentVersionDiff, AdminErrors.AdminError) {
+	fromIntents, err := getVersionIntents(appid, from)
+	if err != nil {
+		return nil, err
+	}
+	toIntents, err := getVersionIntents(appid, to)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffIntents(fromIntents, toIntents)
+	diff.From, diff.To = from, to
+	return diff, nil
+}
+
+// RollbackVersion will recreate intents of version as the latest draft, and start training if retrain is true.
+// The draft is committed as a version before it is overwritten, so it can be rolled back again.
+// The version of new training is returned if retrain.
+func RollbackVersion(appid string, version int, retrain bool) (int, AdminErrors.AdminError) {
+	if retrain {
+		status, err := GetIntentEngineStatus(appid)
+		if err != nil {
+			return 0, err
+		}
+		if status.Status == statusTraining {
+			return 0, AdminErrors.New(AdminErrors.ErrnoRequestError, util.Msg["PreviousStillRunning"])
+		}
+	}
+
+	intents, err := getVersionIntents(appid, &version)
+	if err != nil {
+		return 0, err
+	}
+	draftVersion, _, daoErr := dao.CommitIntent(appid)
+	if daoErr != nil {
+		return 0, AdminErrors.New(AdminErrors.ErrnoDBError, daoErr.Error())
+	}
+	logger.Trace.Printf("Rollback %d intents of version %d to draft, previous draft is kept in version %d\n",
+		len(intents), version, draftVersion)
+	err = UpdateLatestIntents(appid, intents)
+	if err != nil || !retrain {
+		return 0, err
+	}
+	return StartTrain(appid)
+}
+
+// renameSimilarity is the minimal ratio of same sentences to take a removed intent
+// and an added intent as renamed
+const renameSimilarity = 0.5
+
+func diffIntents(from, to []*IntentV2) *IntentVersionDiff {
+	diff := &IntentVersionDiff{
+		Added:    []string{},
+		Removed:  []string{},
+		Renamed:  []*IntentRename{},
+		Modified: []*IntentSentenceDiff{},
+	}
+
+	fromMap := map[string]*IntentV2{}
+	for _, intent := range from {
+		fromMap[intent.Name] = intent
+	}
+	toMap := map[string]*IntentV2{}
+	for _, intent := range to {
+		toMap[intent.Name] = intent
+	}
+
+	added, removed := []*IntentV2{}, []*IntentV2{}
+	for _, intent := range to {
+		if orig, ok := fromMap[intent.Name]; ok {
+			if sentenceDiff := diffIntentSentences(orig, intent); sentenceDiff != nil {
+				diff.Modified = append(diff.Modified, sentenceDiff)
+			}
+		} else {
+			added = append(added, intent)
+		}
+	}
+	for _, intent := range from {
+		if _, ok := toMap[intent.Name]; !ok {
+			removed = append(removed, intent)
+		}
+	}
+
+	// Pair removed and added intents with most same sentences as renamed
+	type candidate struct {
+		removed, added int
+		similarity     float64
+	}
+	candidates := []candidate{}
+	for i := range removed {
+		for j := range added {
+			similarity := intentSimilarity(removed[i], added[j])
+			if similarity >= renameSimilarity {
+				candidates = append(candidates, candidate{i, j, similarity})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	renamedFrom, renamedTo := map[int]bool{}, map[int]bool{}
+	for _, c := range candidates {
+		if renamedFrom[c.removed] || renamedTo[c.added] {
+			continue
+		}
+		renamedFrom[c.removed], renamedTo[c.added] = true, true
+		diff.Renamed = append(diff.Renamed, &IntentRename{
+			From: removed[c.removed].Name,
+			To:   added[c.added].Name,
+		})
+		if sentenceDiff := diffIntentSentences(removed[c.removed], added[c.added]); sentenceDiff != nil {
+			diff.Modified = append(diff.Modified, sentenceDiff)
+		}
+	}
+
+	for idx, intent := range added {
+		if !renamedTo[idx] {
+			diff.Added = append(diff.Added, intent.Name)
+		}
+	}
+	for idx, intent := range removed {
+		if !renamedFrom[idx] {
+			diff.Removed = append(diff.Removed, intent.Name)
+		}
+	}
+	return diff
+}
+
+func sentenceContents(sentences *[]*SentenceV2) []string {
+	ret := []string{}
+	if sentences == nil {
+		return ret
+	}
+	for _, sentence := range *sentences {
+		ret = append(ret, sentence.Content)
+	}
+	return ret
+}
+
+// diffStrings returns strings only in a and strings only in b
+func diffStrings(a, b []string) (onlyA, onlyB []string) {
+	setA, setB := map[string]bool{}, map[string]bool{}
+	for _, s := range a {
+		setA[s] = true
+	}
+	for _, s := range b {
+		setB[s] = true
+	}
+	onlyA, onlyB = []string{}, []string{}
+	for _, s := range a {
+		if !setB[s] {
+			onlyA = append(onlyA, s)
+		}
+	}
+	for _, s := range b {
+		if !setA[s] {
+			onlyB = append(onlyB, s)
+		}
+	}
+	return
+}
+
+// diffIntentSentences returns nil if sentences of both intents are the same
+func diffIntentSentences(from, to *IntentV2) *IntentSentenceDiff {
+	ret := &IntentSentenceDiff{Name: to.Name}
+	ret.RemovedPositive, ret.AddedPositive = diffStrings(sentenceContents(from.Positive), sentenceContents(to.Positive))
+	ret.RemovedNegative, ret.AddedNegative = diffStrings(sentenceContents(from.Negative), sentenceContents(to.Negative))
+	if len(ret.RemovedPositive)+len(ret.AddedPositive)+len(ret.RemovedNegative)+len(ret.AddedNegative) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// intentSimilarity is the jaccard index of sentences in both intents
+func intentSimilarity(a, b *IntentV2) float64 {
+	setA := map[string]bool{}
+	for _, s := range sentenceContents(a.Positive) {
+		setA["+"+s] = true
+	}
+	for _, s := range sentenceContents(a.Negative) {
+		setA["-"+s] = true
+	}
+	setB := map[string]bool{}
+	for _, s := range sentenceContents(b.Positive) {
+		setB["+"+s] = true
+	}
+	for _, s := range sentenceContents(b.Negative) {
+		setB["-"+s] = true
+	}
+
+	same := 0
+	for s := range setA {
+		if setB[s] {
+			same++
+		}
+	}
+	union := len(setA) + len(setB) - same
+	if union == 0 {
+		return 0
+	}
+	return float64(same) / float64(union)
+}
@@ -0,0 +1,131 @@
+package intentenginev2
+
+import (
+	"reflect"
+	"testing"
+
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+)
+
+func newTestIntent(name string, positive, negative []string) *IntentV2 {
+	toSentences := func(contents []string) *[]*SentenceV2 {
+		sentences := []*SentenceV2{}
+		for _, content := range contents {
+			sentences = append(sentences, &SentenceV2{Content: content})
+		}
+		return &sentences
+	}
+	return &IntentV2{
+		Name:     name,
+		Positive: toSentences(positive),
+		Negative: toSentences(negative),
+	}
+}
+
+func TestDiffIntents(t *testing.T) {
+	from := []*IntentV2{
+		newTestIntent("查余额", []string{"余额多少", "还有多少钱"}, []string{"转账"}),
+		newTestIntent("转账", []string{"我要转账", "转钱给他", "汇款"}, nil),
+		newTestIntent("挂失", []string{"卡丢了"}, nil),
+	}
+	to := []*IntentV2{
+		newTestIntent("查余额", []string{"余额多少", "账户余额"}, []string{}),
+		newTestIntent("转帐", []string{"我要转账", "转钱给他", "汇款", "转帐"}, nil),
+		newTestIntent("开户", []string{"我要开户"}, nil),
+	}
+
+	diff := diffIntents(from, to)
+	if !reflect.DeepEqual(diff.Added, []string{"开户"}) {
+		logError(t, "added intents", []string{"开户"}, diff.Added)
+	}
+	if !reflect.DeepEqual(diff.Removed, []string{"挂失"}) {
+		logError(t, "removed intents", []string{"挂失"}, diff.Removed)
+	}
+	if len(diff.Renamed) != 1 || diff.Renamed[0].From != "转账" || diff.Renamed[0].To != "转帐" {
+		logError(t, "renamed intents", "转账 -> 转帐", diff.Renamed)
+	}
+
+	if len(diff.Modified) != 2 {
+		t.Fatalf("expect 2 modified intents, get %d", len(diff.Modified))
+	}
+	modified := diff.Modified[0]
+	if modified.Name != "查余额" ||
+		!reflect.DeepEqual(modified.AddedPositive, []string{"账户余额"}) ||
+		!reflect.DeepEqual(modified.RemovedPositive, []string{"还有多少钱"}) ||
+		!reflect.DeepEqual(modified.RemovedNegative, []string{"转账"}) ||
+		len(modified.AddedNegative) != 0 {
+		logError(t, "modified intent", "查余额", modified)
+	}
+	if renamed := diff.Modified[1]; renamed.Name != "转帐" || !reflect.DeepEqual(renamed.AddedPositive, []string{"转帐"}) {
+		logError(t, "modified renamed intent", "转帐", renamed)
+	}
+
+	diff = diffIntents(from, from)
+	if len(diff.Added)+len(diff.Removed)+len(diff.Renamed)+len(diff.Modified) != 0 {
+		logError(t, "diff of same version", "empty", diff)
+	}
+}
+
+// versionTestDao keeps draft intents and committed versions in memory
+type versionTestDao struct {
+	intentDaoInterface
+	draft    []*IntentV2
+	versions map[int][]*IntentV2
+}
+
+func (dao *versionTestDao) GetVersions(appid string) ([]*VersionInfoV2, error) {
+	ret := []*VersionInfoV2{}
+	for version := range dao.versions {
+		ret = append(ret, &VersionInfoV2{Version: version})
+	}
+	return ret, nil
+}
+
+func (dao *versionTestDao) GetIntentsDetail(appid string, version *int) ([]*IntentV2, error) {
+	if version == nil {
+		return dao.draft, nil
+	}
+	// unknown version has no intents, the same as db
+	return dao.versions[*version], nil
+}
+
+func (dao *versionTestDao) CommitIntent(appid string) (int, []*IntentV2, error) {
+	version := len(dao.versions) + 1
+	dao.versions[version] = dao.draft
+	return version, dao.draft, nil
+}
+
+func (dao *versionTestDao) UpdateLatestIntents(appid string, intents []*IntentV2) error {
+	dao.draft = intents
+	return nil
+}
+
+func TestRollbackVersion(t *testing.T) {
+	origDao := dao
+	defer func() { dao = origDao }()
+
+	draft := []*IntentV2{newTestIntent("查余额", []string{"余额多少"}, nil)}
+	committed := []*IntentV2{newTestIntent("转账", []string{"我要转账"}, nil)}
+	testDao := &versionTestDao{draft: draft, versions: map[int][]*IntentV2{1: committed}}
+	dao = testDao
+
+	if _, err := RollbackVersion("app", 2, false); err == nil || err.Errno() != AdminErrors.ErrnoNotFound {
+		logError(t, "rollback to missing version", "not found error", err)
+	}
+	if !reflect.DeepEqual(testDao.draft, draft) || len(testDao.versions) != 1 {
+		logError(t, "draft after rollback to missing version", draft, testDao.draft)
+	}
+	if _, err := DiffVersions("app", nil, &[]int{2}[0]); err == nil || err.Errno() != AdminErrors.ErrnoNotFound {
+		logError(t, "diff with missing version", "not found error", err)
+	}
+
+	if _, err := RollbackVersion("app", 1, false); err != nil {
+		t.Fatalf("rollback to version 1 fail: %s", err.Error())
+	}
+	if !reflect.DeepEqual(testDao.draft, committed) {
+		logError(t, "draft after rollback", committed, testDao.draft)
+	}
+	if !reflect.DeepEqual(testDao.versions[2], draft) {
+		logError(t, "snapshot of previous draft", draft, testDao.versions[2])
+	}
+}
@@ -33,12 +33,38 @@ type VersionInfoV2 struct {
 	IntentEngineModel *string `json:"intent_engine_model"`
 	RuleEngineModel   *string `json:"rule_engine_model,omitempty"`
 	InUse             bool    `json:"in_use"`
+	CommitTime        int64   `json:"commit_time"`
+	SentenceCount     int     `json:"sentence_count"`
 	TrainStartTime    *int64  `json:"start_train"`
 	TrainEndTime      *int64  `json:"end_train"`
 	Progress          int     `json:"progress"`
 	TrainResult       int     `json:"train_result`
 }
 
+// IntentVersionDiff describe the changes from one version to another
+type IntentVersionDiff struct {
+	From     *int                  `json:"from"`
+	To       *int                  `json:"to"`
+	Added    []string              `json:"added_intents"`
+	Removed  []string              `json:"removed_intents"`
+	Renamed  []*IntentRename       `json:"renamed_intents"`
+	Modified []*IntentSentenceDiff `json:"modified_intents"`
+}
+
+type IntentRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// IntentSentenceDiff describe the sentences changed of a intent existed in both versions
+type IntentSentenceDiff struct {
+	Name            string   `json:"name"`
+	AddedPositive   []string `json:"added_positive"`
+	RemovedPositive []string `json:"removed_positive"`
+	AddedNegative   []string `json:"added_negative"`
+	RemovedNegative []string `json:"removed_negative"`
+}
+
 type StatusV2 struct {
 	Status           string `json:"status"`
 	LastFinishTime   *int64 `json:"last_train,omitempty"`
@@ -61,6 +61,9 @@ var (
 
 		util.NewEntryPointWithVer("POST", "train", []string{"view"}, handleStartTrain, 2),
 		util.NewEntryPointWithVer("GET", "status", []string{"view"}, handleGetIntentStatusV2, 2),
+		util.NewEntryPointWithVer("GET", "versions", []string{"view"}, handleGetVersionsV2, 2),
+		util.NewEntryPointWithVer("GET", "versions/diff", []string{"view"}, handleDiffVersionsV2, 2),
+		util.NewEntryPointWithVer("POST", "versions/{version}/rollback", []string{"edit"}, handleRollbackVersionV2, 2),
 
 		util.NewEntryPointWithVer("GET", "getData", []string{}, handleGetTrainDataV2, 2),
 		util.NewEntryPointWithVer("POST", "import", []string{"view"}, handleImportIntentV2, 2),
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -310,3 +311,53 @@ func createExportFilename() string {
 	now := time.Now()
 	return fmt.Sprintf("intent_test_%s.xlsx", now.Format("20060102_150405"))
 }
+
+func CrossValidationStartHandler(w http.ResponseWriter, r *http.Request) {
+	appID := requestheader.GetAppID(r)
+	locale := requestheader.GetLocale(r)
+
+	folds := data.CrossValidationDefaultFolds
+	foldsStr := strings.TrimSpace(r.FormValue("folds"))
+	if foldsStr != "" {
+		var convErr error
+		folds, convErr = strconv.Atoi(foldsStr)
+		if convErr != nil {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError,
+				"folds"), nil)
+			return
+		}
+	}
+
+	err := services.StartCrossValidation(appID, folds, locale)
+	if err != nil {
+		util.Return(w, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func CrossValidationGetHandler(w http.ResponseWriter, r *http.Request) {
+	appID := requestheader.GetAppID(r)
+	result, err := services.GetCrossValidation(appID)
+	if err != nil {
+		util.Return(w, err, nil)
+		return
+	}
+
+	util.Return(w, nil, result)
+}
+
+func CrossValidationExportHandler(w http.ResponseWriter, r *http.Request) {
+	appID := requestheader.GetAppID(r)
+	locale := requestheader.GetLocale(r)
+
+	buf, err := services.ExportCrossValidation(appID, locale)
+	if err != nil {
+		util.Return(w, err, nil)
+		return
+	}
+
+	filename := fmt.Sprintf("intent_cross_validation_%s.xlsx", time.Now().Format("20060102_150405"))
+	util.ReturnFile(w, filename, buf)
+}
@@ -0,0 +1,181 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/intentengineTest/data"
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+const IntentCrossValidationsTable = "intent_cross_validations"
+
+func (dao IntentTestDao) CreateCrossValidation(appID string, folds int,
+	sentencesCount int64) (id int64, err error) {
+	if dao.db == nil {
+		return 0, ErrDBNotInit
+	}
+
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer util.ClearTransition(tx)
+
+	// Lock the running cross validation of app, so there is only one
+	// cross validation created even if requests come to different instances
+	now := time.Now()
+	var runningID, startTime int64
+	queryStr := fmt.Sprintf(`
+		SELECT id, start_time
+		FROM %s
+		WHERE app_id = ? AND status = ?
+		ORDER BY id DESC
+		LIMIT 1
+		FOR UPDATE`, IntentCrossValidationsTable)
+	err = tx.QueryRow(queryStr, appID, data.TestStatusTesting).Scan(&runningID,
+		&startTime)
+	if err == nil {
+		if now.Sub(time.Unix(startTime, 0)) <= data.CrossValidationExpiredDuration {
+			return 0, data.ErrCrossValidationInProcess
+		}
+
+		queryStr = fmt.Sprintf(`
+			UPDATE %s
+			SET end_time = ?, status = ?, message = ?
+			WHERE id = ?`, IntentCrossValidationsTable)
+		_, err = tx.Exec(queryStr, now.Unix(), data.TestStatusFailed,
+			data.ErrTestTaskExpired.Error(), runningID)
+		if err != nil {
+			return 0, err
+		}
+	} else if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	queryStr = fmt.Sprintf(`
+		INSERT INTO %s (app_id, status, folds, sentences_count, start_time)
+		VALUES (?, ?, ?, ?, ?)`, IntentCrossValidationsTable)
+	result, err := tx.Exec(queryStr, appID, data.TestStatusTesting, folds,
+		sentencesCount, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err = result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	return
+}
+
+func (dao IntentTestDao) CrossValidationFoldFinished(id int64) (err error) {
+	if dao.db == nil {
+		return ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET fold = fold + 1
+		WHERE id = ?`, IntentCrossValidationsTable)
+	_, err = dao.db.Exec(queryStr, id)
+	return
+}
+
+func (dao IntentTestDao) UpdateCrossValidationProgress(id int64,
+	newProgress int) (err error) {
+	if dao.db == nil {
+		return ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET progress = progress + ?
+		WHERE id = ?`, IntentCrossValidationsTable)
+	_, err = dao.db.Exec(queryStr, newProgress, id)
+	return
+}
+
+func (dao IntentTestDao) CrossValidationFinished(id int64,
+	result *data.CrossValidationResult) (err error) {
+	if dao.db == nil {
+		return ErrDBNotInit
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET status = ?, end_time = ?, result = ?
+		WHERE id = ?`, IntentCrossValidationsTable)
+	_, err = dao.db.Exec(queryStr, data.TestStatusTested, time.Now().Unix(),
+		string(content), id)
+	return
+}
+
+func (dao IntentTestDao) CrossValidationFailed(id int64,
+	errMsg string) (err error) {
+	if dao.db == nil {
+		return ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf(`
+		UPDATE %s
+		SET status = ?, end_time = ?, message = ?
+		WHERE id = ?`, IntentCrossValidationsTable)
+	_, err = dao.db.Exec(queryStr, data.TestStatusFailed, time.Now().Unix(),
+		errMsg, id)
+	return
+}
+
+func (dao IntentTestDao) GetLatestCrossValidation(appID string) (cv *data.CrossValidation,
+	err error) {
+	if dao.db == nil {
+		return nil, ErrDBNotInit
+	}
+
+	var endTime sql.NullInt64
+	var result sql.NullString
+	cv = &data.CrossValidation{}
+	queryStr := fmt.Sprintf(`
+		SELECT id, status, folds, fold, sentences_count, progress,
+			start_time, end_time, message, result
+		FROM %s
+		WHERE app_id = ?
+		ORDER BY id DESC
+		LIMIT 1`, IntentCrossValidationsTable)
+	err = dao.db.QueryRow(queryStr, appID).Scan(&cv.ID, &cv.Status, &cv.Folds,
+		&cv.Fold, &cv.SentencesCount, &cv.Progress, &cv.StartTime, &endTime,
+		&cv.Message, &result)
+	if err == sql.ErrNoRows {
+		return nil, data.ErrCrossValidationNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if endTime.Valid {
+		cv.EndTime = endTime.Int64
+	}
+	if result.Valid && result.String != "" {
+		cv.Result = &data.CrossValidationResult{}
+		err = json.Unmarshal([]byte(result.String), cv.Result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The instance running the cross validation may be gone
+	if cv.Status == data.TestStatusTesting &&
+		time.Since(time.Unix(cv.StartTime, 0)) > data.CrossValidationExpiredDuration {
+		cv.Status = data.TestStatusFailed
+		cv.Message = data.ErrTestTaskExpired.Error()
+	}
+	return cv, nil
+}
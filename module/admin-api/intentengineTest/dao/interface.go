@@ -141,4 +141,29 @@ type Dao interface {
 	// GetLatestIntentNames return the names of latest intents
 	// (which version is NULL)
 	GetLatestIntentNames(appID string) (intentNames []string, err error)
+
+	// CreateCrossValidation will create a new cross validation task
+	// It will return data.ErrCrossValidationInProcess if there is a running one
+	CreateCrossValidation(appID string, folds int, sentencesCount int64) (id int64,
+		err error)
+
+	// CrossValidationFoldFinished will increase the finished folds of
+	// the given cross validation
+	CrossValidationFoldFinished(id int64) (err error)
+
+	// UpdateCrossValidationProgress will increase the predicted sentences of
+	// the given cross validation
+	UpdateCrossValidationProgress(id int64, newProgress int) (err error)
+
+	// CrossValidationFinished will mark the given cross validation as finished
+	// with its result
+	CrossValidationFinished(id int64, result *data.CrossValidationResult) (err error)
+
+	// CrossValidationFailed will mark the given cross validation as failed
+	// with error message
+	CrossValidationFailed(id int64, errMsg string) (err error)
+
+	// GetLatestCrossValidation will return the latest cross validation of app
+	// It will return data.ErrCrossValidationNotFound if app has never run one
+	GetLatestCrossValidation(appID string) (cv *data.CrossValidation, err error)
 }
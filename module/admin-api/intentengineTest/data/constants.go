@@ -27,3 +27,28 @@ const (
 	MaxRecentTested  = 5
 	MaxRecentSaved   = 10
 )
+
+const (
+	CrossValidationDefaultFolds = 5
+	CrossValidationMinFolds     = 2
+	CrossValidationMaxFolds     = 10
+	// MaxConfusedPairs is the number of most confused intent pairs in cross validation result
+	MaxConfusedPairs = 20
+	// MaxConfusedSentences is the number of example sentences of each confused pair
+	MaxConfusedSentences = 5
+)
+
+const (
+	// CrossValidationParallelFolds is the number of folds trained and predicted at the same time
+	CrossValidationParallelFolds = 3
+	// CrossValidationPredictWorkers is the number of concurrent predictions of each fold
+	CrossValidationPredictWorkers = 5
+)
+
+var (
+	CrossValidationTrainTimeout    = 30 * time.Minute
+	CrossValidationLoadTimeout     = 30 * time.Second
+	CrossValidationExpiredDuration = 3 * time.Hour
+	// CrossValidationPollInterval is the interval to check training and loading of fold models
+	CrossValidationPollInterval = 2 * time.Second
+)
@@ -0,0 +1,50 @@
+package data
+
+// CrossValidation is a k-fold cross validation task, Fold is the number of finished folds
+type CrossValidation struct {
+	ID             int64                  `json:"id"`
+	Status         int64                  `json:"status"`
+	Folds          int                    `json:"folds"`
+	Fold           int                    `json:"fold"`
+	SentencesCount int64                  `json:"sentences_count"`
+	Progress       int64                  `json:"progress"`
+	StartTime      int64                  `json:"start_time"`
+	EndTime        int64                  `json:"end_time,omitempty"`
+	Message        string                 `json:"message,omitempty"`
+	Result         *CrossValidationResult `json:"result,omitempty"`
+}
+
+type CrossValidationResult struct {
+	Accuracy       float64                  `json:"accuracy"`
+	MacroPrecision float64                  `json:"macro_precision"`
+	MacroRecall    float64                  `json:"macro_recall"`
+	MacroF1        float64                  `json:"macro_f1"`
+	Intents        []*CrossValidationIntent `json:"intents"`
+	// Labels are the predicted labels of matrix columns, the last one is
+	// empty string which means no intent predicted
+	Labels        []string                   `json:"labels"`
+	Matrix        [][]int64                  `json:"confusion_matrix"`
+	ConfusedPairs []*CrossValidationConfused `json:"confused_pairs"`
+}
+
+type CrossValidationIntent struct {
+	Name      string  `json:"name"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	Support   int64   `json:"support"`
+}
+
+type CrossValidationConfused struct {
+	Expected  string   `json:"expected"`
+	Predicted string   `json:"predicted"`
+	Count     int64    `json:"count"`
+	Sentences []string `json:"sentences"`
+}
+
+// CrossValidationSample is a held-out sentence and the intent predicted by the fold model
+type CrossValidationSample struct {
+	Sentence  string
+	Expected  string
+	Predicted string
+}
@@ -13,6 +13,8 @@ var (
 	ErrTestImportSheetFormat        = errors.New("Intent test import file format error")
 	ErrTestImportSheetNoHeader      = errors.New("Intent test import file sheet header not found")
 	ErrTestImportSheetEmptySentence = errors.New("Intent test import file with empty sentence")
+	ErrCrossValidationNotFound      = errors.New("Cross validation not found")
+	ErrCrossValidationInProcess     = errors.New("Cross validation is in process")
 )
 
 type ErrorResponse struct {
@@ -25,6 +25,9 @@ func init() {
 			util.NewEntryPoint("GET", "export", []string{"view"}, controllers.LatestIntentTestExportHandler),
 			util.NewEntryPoint("POST", "test", []string{"view"}, controllers.IntentTestsTestHandler),
 			util.NewEntryPoint("GET", "models", []string{"view"}, controllers.UsableModelsGetHandler),
+			util.NewEntryPoint("POST", "cross_validation", []string{"view"}, controllers.CrossValidationStartHandler),
+			util.NewEntryPoint("GET", "cross_validation", []string{"view"}, controllers.CrossValidationGetHandler),
+			util.NewEntryPoint("GET", "cross_validation/export", []string{"view"}, controllers.CrossValidationExportHandler),
 			util.NewEntryPoint("GET", "{intent_test_id}", []string{"view"}, controllers.IntentTestGetHandler),
 			util.NewEntryPoint("PATCH", "{intent_test_id}", []string{"view"}, controllers.IntentTestPatchHandler),
 			util.NewEntryPoint("POST", "{intent_test_id}/save", []string{"edit"}, controllers.IntentTestSaveHandler),
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/intentengine/v2"
+	"emotibot.com/emotigo/module/admin-api/intentengineTest/data"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
+	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
+	"emotibot.com/emotigo/pkg/logger"
+
+	"github.com/tealeg/xlsx"
+)
+
+// crossValidationSeed makes the fold assignment reproducible between runs
+const crossValidationSeed = 1
+
+// crossValidationProgressStep is the number of predicted sentences to update progress
+const crossValidationProgressStep = 100
+
+// crossValidationFold is the training data and held-out sentences of one fold
+type crossValidationFold struct {
+	Train   *intentenginev2.TrainDataResponse
+	Samples []*data.CrossValidationSample
+}
+
+func StartCrossValidation(appID string, folds int,
+	locale string) AdminErrors.AdminError {
+	if folds < data.CrossValidationMinFolds || folds > data.CrossValidationMaxFolds {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, "folds")
+	}
+
+	trainData, adminErr := intentenginev2.GetTrainData(appID)
+	if adminErr != nil {
+		return adminErr
+	}
+
+	foldData := splitFolds(trainData, folds, crossValidationSeed)
+	var total int64
+	for _, fold := range foldData {
+		total += int64(len(fold.Samples))
+	}
+	if total == 0 {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError,
+			localemsg.Get(locale, "CrossValidationNoSentence"))
+	}
+
+	id, err := intentTestDao.CreateCrossValidation(appID, folds, total)
+	if err == data.ErrCrossValidationInProcess {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError,
+			localemsg.Get(locale, "PreviousCrossValidationStillRunning"))
+	} else if err != nil {
+		return AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+
+	go runCrossValidation(id, appID, foldData)
+	return nil
+}
+
+// GetCrossValidation returns the latest cross validation of app
+func GetCrossValidation(appID string) (*data.CrossValidation, AdminErrors.AdminError) {
+	cv, err := intentTestDao.GetLatestCrossValidation(appID)
+	if err == data.ErrCrossValidationNotFound {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, err.Error())
+	} else if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return cv, nil
+}
+
+func ExportCrossValidation(appID string, locale string) ([]byte, AdminErrors.AdminError) {
+	cv, err := GetCrossValidation(appID)
+	if err != nil {
+		return nil, err
+	}
+	if cv.Result == nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound,
+			data.ErrCrossValidationNotFound.Error())
+	}
+	return createExportCrossValidationXlsx(cv.Result, locale)
+}
+
+// runCrossValidation runs at most data.CrossValidationParallelFolds folds at the same time
+func runCrossValidation(id int64, appID string, folds []*crossValidationFold) {
+	errs := make([]error, len(folds))
+	slots := make(chan struct{}, data.CrossValidationParallelFolds)
+	var wg sync.WaitGroup
+	for idx, fold := range folds {
+		wg.Add(1)
+		go func(idx int, fold *crossValidationFold) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			errs[idx] = runCrossValidationFold(id, appID, idx, fold)
+			if errs[idx] != nil {
+				return
+			}
+			err := intentTestDao.CrossValidationFoldFinished(id)
+			if err != nil {
+				logger.Error.Printf("Update finished fold of cross validation %d failed: %s.\n", id, err.Error())
+			}
+		}(idx, fold)
+	}
+	wg.Wait()
+
+	samples := []*data.CrossValidationSample{}
+	for idx, fold := range folds {
+		if errs[idx] != nil {
+			logger.Error.Printf("Cross validation of fold %d failed: %s.\n", idx+1, errs[idx].Error())
+			err := intentTestDao.CrossValidationFailed(id, errs[idx].Error())
+			if err != nil {
+				logger.Error.Printf("Update failed cross validation %d failed: %s.\n", id, err.Error())
+			}
+			return
+		}
+		samples = append(samples, fold.Samples...)
+	}
+
+	intents := make([]string, 0, len(folds[0].Train.Intent))
+	for _, intent := range folds[0].Train.Intent {
+		intents = append(intents, intent.Name)
+	}
+	result := evaluateCrossValidation(intents, samples)
+
+	err := intentTestDao.CrossValidationFinished(id, result)
+	if err != nil {
+		logger.Error.Printf("Update finished cross validation %d failed: %s.\n", id, err.Error())
+		return
+	}
+	logger.Info.Println("Intent cross validation finished.")
+}
+
+// runCrossValidationFold trains a model with a fake app ID and predicts the held-out sentences
+func runCrossValidationFold(id int64, appID string, idx int,
+	fold *crossValidationFold) (err error) {
+	if len(fold.Samples) == 0 {
+		return nil
+	}
+
+	fakeAppID := fmt.Sprintf("%s_cv_%s_%d", appID, time.Now().Format("20060102_150405"), idx)
+	fold.Train.AppID = fakeAppID
+	err = intentenginev2.SetTrainDataOverride(fakeAppID, fold.Train)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if removeErr := intentenginev2.RemoveTrainDataOverride(fakeAppID); removeErr != nil {
+			logger.Error.Printf("Remove training data of fake app ID %s failed: %s.\n",
+				fakeAppID, removeErr.Error())
+		}
+	}()
+
+	logger.Info.Printf("Train fold %d of cross validation with fake app ID: %s.\n", idx+1, fakeAppID)
+	ieModelID, err := intentenginev2.TrainIntentModel(fakeAppID)
+	if err != nil {
+		return
+	}
+
+	ready, err := pollUntil(data.CrossValidationTrainTimeout, func() (bool, error) {
+		return intentenginev2.IntentModelReady(fakeAppID, ieModelID)
+	})
+	if err != nil {
+		return
+	} else if !ready {
+		return fmt.Errorf("Intent Engine model %s train timeout", ieModelID)
+	}
+
+	err = loadIEModel(fakeAppID, ieModelID)
+	if err != nil {
+		return
+	}
+	defer func() {
+		logger.Info.Printf("Unload Intent Engine model with fake app ID: %s.\n", fakeAppID)
+		unloadIEModel(fakeAppID)
+	}()
+
+	payload := map[string]interface{}{
+		"app_id":   fakeAppID,
+		"sentence": "测试语句",
+	}
+	loaded, err := pollUntil(data.CrossValidationLoadTimeout, func() (bool, error) {
+		return ieModelLoaded(payload)
+	})
+	if err != nil {
+		return
+	} else if !loaded {
+		return fmt.Errorf("Intent Engine model %s not loaded in %s", ieModelID,
+			data.CrossValidationLoadTimeout)
+	}
+
+	return predictSamples(id, fakeAppID, fold.Samples)
+}
+
+// predictSamples predicts samples with data.CrossValidationPredictWorkers workers
+func predictSamples(id int64, appID string, samples []*data.CrossValidationSample) error {
+	workers := data.CrossValidationPredictWorkers
+	if len(samples) < workers {
+		workers = len(samples)
+	}
+
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func(worker int) {
+			var count int
+			for idx := worker; idx < len(samples); idx += workers {
+				predicted, err := predictLabel(appID, samples[idx].Sentence)
+				if err != nil {
+					errs <- err
+					return
+				}
+				samples[idx].Predicted = predicted
+
+				count++
+				if count%crossValidationProgressStep == 0 {
+					intentTestDao.UpdateCrossValidationProgress(id, crossValidationProgressStep)
+				}
+			}
+			if count%crossValidationProgressStep != 0 {
+				intentTestDao.UpdateCrossValidationProgress(id, count%crossValidationProgressStep)
+			}
+			errs <- nil
+		}(i)
+	}
+
+	var predictErr error
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil {
+			predictErr = err
+		}
+	}
+	return predictErr
+}
+
+// pollUntil checks ready every data.CrossValidationPollInterval,
+// false is returned if it is not ready before timeout
+func pollUntil(timeout time.Duration, ready func() (bool, error)) (bool, error) {
+	ticker := time.NewTicker(data.CrossValidationPollInterval)
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := ready()
+		if err != nil || ok {
+			return ok, err
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		<-ticker.C
+	}
+}
+
+// predictLabel returns the top intent predicted, or empty string if no intent is predicted
+func predictLabel(appID string, sentence string) (label string, err error) {
+	intentEngineURL := getEnvironment("INTENT_ENGINE_URL")
+	if intentEngineURL == "" {
+		intentEngineURL = defaultIntentEngineURL
+	}
+	iePredictURL := fmt.Sprintf("%s/%s", intentEngineURL, "predict")
+
+	payload := map[string]interface{}{
+		"app_id":   appID,
+		"sentence": zhconverter.T2S(sentence),
+	}
+
+	for retry := 0; retry < maxRetries; retry++ {
+		var body string
+		body, err = util.HTTPPostJSON(iePredictURL, payload, 30)
+		if err != nil {
+			continue
+		}
+
+		resp := data.IEPredictResp{}
+		err = json.Unmarshal([]byte(body), &resp)
+		if err != nil {
+			continue
+		}
+
+		if !strings.EqualFold(resp.Status, "OK") {
+			err = fmt.Errorf("status: %s; error: %s", resp.Status, resp.Error)
+			continue
+		}
+
+		if len(resp.Predictions) == 0 {
+			return "", nil
+		}
+		return resp.Predictions[0].Label, nil
+	}
+	return
+}
+
+// splitFolds assigns the positive sentences of each intent to folds evenly,
+// negative sentences are always used in training
+func splitFolds(trainData *intentenginev2.TrainDataResponse, k int,
+	seed int64) []*crossValidationFold {
+	random := rand.New(rand.NewSource(seed))
+	folds := make([]*crossValidationFold, k)
+	for idx := range folds {
+		folds[idx] = &crossValidationFold{
+			Train: &intentenginev2.TrainDataResponse{
+				Status:     trainData.Status,
+				Intent:     make([]*intentenginev2.TrainIntent, 0, len(trainData.Intent)),
+				IntentDict: trainData.IntentDict,
+			},
+			Samples: []*data.CrossValidationSample{},
+		}
+	}
+
+	for _, intent := range trainData.Intent {
+		positives := []string{}
+		negatives := []string{}
+		if intent.Sentences != nil {
+			positives = append(positives, intent.Sentences.Positive...)
+			negatives = intent.Sentences.Negative
+		}
+		random.Shuffle(len(positives), func(i, j int) {
+			positives[i], positives[j] = positives[j], positives[i]
+		})
+
+		for idx, fold := range folds {
+			train := []string{}
+			for i, sentence := range positives {
+				if i%k == idx {
+					fold.Samples = append(fold.Samples, &data.CrossValidationSample{
+						Sentence: sentence,
+						Expected: intent.Name,
+					})
+				} else {
+					train = append(train, sentence)
+				}
+			}
+			// At least add intent itself as positive sentence to avoid error in intent trainer
+			if len(train) == 0 {
+				train = append(train, intent.Name)
+			}
+			fold.Train.Intent = append(fold.Train.Intent, &intentenginev2.TrainIntent{
+				Name:     intent.Name,
+				Features: intent.Features,
+				Sentences: &intentenginev2.TrainSentence{
+					Positive: train,
+					Negative: negatives,
+				},
+			})
+		}
+	}
+	return folds
+}
+
+// evaluateCrossValidation computes the confusion matrix and metrics of each intent
+func evaluateCrossValidation(intents []string,
+	samples []*data.CrossValidationSample) *data.CrossValidationResult {
+	labels := append(append([]string{}, intents...), "")
+	index := map[string]int{}
+	for idx, label := range labels {
+		index[label] = idx
+	}
+
+	matrix := make([][]int64, len(intents))
+	for idx := range matrix {
+		matrix[idx] = make([]int64, len(labels))
+	}
+	examples := map[[2]int][]string{}
+
+	var correct int64
+	for _, sample := range samples {
+		row, ok := index[sample.Expected]
+		if !ok || row == len(intents) {
+			continue
+		}
+		col, ok := index[sample.Predicted]
+		if !ok {
+			col = len(intents)
+		}
+		matrix[row][col]++
+		if row == col {
+			correct++
+		} else if key := [2]int{row, col}; len(examples[key]) < data.MaxConfusedSentences {
+			examples[key] = append(examples[key], sample.Sentence)
+		}
+	}
+
+	result := &data.CrossValidationResult{
+		Intents:       make([]*data.CrossValidationIntent, len(intents)),
+		Labels:        labels,
+		Matrix:        matrix,
+		ConfusedPairs: []*data.CrossValidationConfused{},
+	}
+
+	var total int64
+	for idx, name := range intents {
+		var support, predicted int64
+		for col := range labels {
+			support += matrix[idx][col]
+		}
+		for row := range intents {
+			predicted += matrix[row][idx]
+		}
+		total += support
+
+		tp := matrix[idx][idx]
+		precision := ratio(tp, predicted)
+		recall := ratio(tp, support)
+		var f1 float64
+		if precision+recall > 0 {
+			f1 = 2 * precision * recall / (precision + recall)
+		}
+		result.Intents[idx] = &data.CrossValidationIntent{
+			Name:      name,
+			Precision: round(precision),
+			Recall:    round(recall),
+			F1:        round(f1),
+			Support:   support,
+		}
+		result.MacroPrecision += precision
+		result.MacroRecall += recall
+		result.MacroF1 += f1
+	}
+
+	if len(intents) > 0 {
+		count := float64(len(intents))
+		result.MacroPrecision = round(result.MacroPrecision / count)
+		result.MacroRecall = round(result.MacroRecall / count)
+		result.MacroF1 = round(result.MacroF1 / count)
+	}
+	result.Accuracy = round(ratio(correct, total))
+
+	for row := range intents {
+		for col, label := range labels {
+			if row == col || matrix[row][col] == 0 {
+				continue
+			}
+			result.ConfusedPairs = append(result.ConfusedPairs, &data.CrossValidationConfused{
+				Expected:  intents[row],
+				Predicted: label,
+				Count:     matrix[row][col],
+				Sentences: examples[[2]int{row, col}],
+			})
+		}
+	}
+	sort.SliceStable(result.ConfusedPairs, func(i, j int) bool {
+		return result.ConfusedPairs[i].Count > result.ConfusedPairs[j].Count
+	})
+	if len(result.ConfusedPairs) > data.MaxConfusedPairs {
+		result.ConfusedPairs = result.ConfusedPairs[:data.MaxConfusedPairs]
+	}
+	return result
+}
+
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func round(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
+
+func createExportCrossValidationXlsx(result *data.CrossValidationResult,
+	locale string) ([]byte, AdminErrors.AdminError) {
+	file := xlsx.NewFile()
+	labelName := func(label string) string {
+		if label == "" {
+			return localemsg.Get(locale, "CrossValidationNoIntent")
+		}
+		return label
+	}
+
+	sheet, err := file.AddSheet(localemsg.Get(locale, "CrossValidationMetrics"))
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoIOError, err.Error())
+	}
+	headerRow := sheet.AddRow()
+	headerRow.AddCell().SetString(localemsg.Get(locale, "IntentName"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "CrossValidationPrecision"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "CrossValidationRecall"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "CrossValidationF1"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "CrossValidationSupport"))
+	for _, intent := range result.Intents {
+		row := sheet.AddRow()
+		row.AddCell().SetString(intent.Name)
+		row.AddCell().SetFloat(intent.Precision)
+		row.AddCell().SetFloat(intent.Recall)
+		row.AddCell().SetFloat(intent.F1)
+		row.AddCell().SetInt64(intent.Support)
+	}
+	row := sheet.AddRow()
+	row.AddCell().SetString(localemsg.Get(locale, "CrossValidationMacroAverage"))
+	row.AddCell().SetFloat(result.MacroPrecision)
+	row.AddCell().SetFloat(result.MacroRecall)
+	row.AddCell().SetFloat(result.MacroF1)
+	row = sheet.AddRow()
+	row.AddCell().SetString(localemsg.Get(locale, "CrossValidationAccuracy"))
+	row.AddCell().SetFloat(result.Accuracy)
+
+	sheet, err = file.AddSheet(localemsg.Get(locale, "CrossValidationConfusionMatrix"))
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoIOError, err.Error())
+	}
+	headerRow = sheet.AddRow()
+	headerRow.AddCell().SetString(localemsg.Get(locale, "IntentName"))
+	for _, label := range result.Labels {
+		headerRow.AddCell().SetString(labelName(label))
+	}
+	for idx, counts := range result.Matrix {
+		row := sheet.AddRow()
+		row.AddCell().SetString(result.Intents[idx].Name)
+		for _, count := range counts {
+			row.AddCell().SetInt64(count)
+		}
+	}
+
+	sheet, err = file.AddSheet(localemsg.Get(locale, "CrossValidationConfusedPairs"))
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoIOError, err.Error())
+	}
+	headerRow = sheet.AddRow()
+	headerRow.AddCell().SetString(localemsg.Get(locale, "IntentName"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "CrossValidationPredicted"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "CrossValidationCount"))
+	headerRow.AddCell().SetString(localemsg.Get(locale, "TestSentence"))
+	for _, pair := range result.ConfusedPairs {
+		for _, sentence := range pair.Sentences {
+			row := sheet.AddRow()
+			row.AddCell().SetString(pair.Expected)
+			row.AddCell().SetString(labelName(pair.Predicted))
+			row.AddCell().SetInt64(pair.Count)
+			row.AddCell().SetString(sentence)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	ioErr := file.Write(writer)
+	if ioErr != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoIOError, ioErr.Error())
+	}
+	writer.Flush()
+
+	return buf.Bytes(), nil
+}
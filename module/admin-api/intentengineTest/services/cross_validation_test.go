@@ -0,0 +1,138 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/intentengine/v2"
+	"emotibot.com/emotigo/module/admin-api/intentengineTest/data"
+)
+
+func TestSplitFolds(t *testing.T) {
+	trainData := &intentenginev2.TrainDataResponse{
+		Intent: []*intentenginev2.TrainIntent{
+			&intentenginev2.TrainIntent{
+				Name: "A",
+				Sentences: &intentenginev2.TrainSentence{
+					Positive: []string{"a1", "a2", "a3", "a4", "a5"},
+					Negative: []string{"n1"},
+				},
+			},
+			&intentenginev2.TrainIntent{
+				Name: "B",
+				Sentences: &intentenginev2.TrainSentence{
+					Positive: []string{"b1"},
+				},
+			},
+		},
+	}
+
+	folds := splitFolds(trainData, 3, 1)
+	if len(folds) != 3 {
+		t.Fatalf("Expect 3 folds, but got %d", len(folds))
+	}
+
+	seen := map[string]int{}
+	for _, fold := range folds {
+		heldOut := map[string]bool{}
+		for _, sample := range fold.Samples {
+			seen[sample.Sentence]++
+			heldOut[sample.Sentence] = true
+		}
+		for _, intent := range fold.Train.Intent {
+			for _, sentence := range intent.Sentences.Positive {
+				if heldOut[sentence] {
+					t.Errorf("Held-out sentence %s is used in training", sentence)
+				}
+			}
+			if len(intent.Sentences.Positive) == 0 {
+				t.Errorf("Intent %s has no positive sentence in training", intent.Name)
+			}
+		}
+		if negatives := fold.Train.Intent[0].Sentences.Negative; len(negatives) != 1 {
+			t.Errorf("Expect negative sentences in every fold, but got %v", negatives)
+		}
+		if a := len(fold.Samples); a < 1 || a > 3 {
+			t.Errorf("Expect samples are split evenly, but got %d", a)
+		}
+	}
+	if len(seen) != 6 {
+		t.Errorf("Expect all 6 positive sentences held out, but got %v", seen)
+	}
+	for sentence, count := range seen {
+		if count != 1 {
+			t.Errorf("Expect %s held out once, but got %d", sentence, count)
+		}
+	}
+}
+
+func TestEvaluateCrossValidation(t *testing.T) {
+	sample := func(expected, predicted, sentence string) *data.CrossValidationSample {
+		return &data.CrossValidationSample{Expected: expected, Predicted: predicted, Sentence: sentence}
+	}
+	samples := []*data.CrossValidationSample{
+		sample("A", "A", "a1"),
+		sample("A", "A", "a2"),
+		sample("A", "B", "a3"),
+		sample("A", "", "a4"),
+		sample("B", "B", "b1"),
+		sample("B", "A", "b2"),
+	}
+
+	result := evaluateCrossValidation([]string{"A", "B"}, samples)
+
+	expectedMatrix := [][]int64{{2, 1, 1}, {1, 1, 0}}
+	for row := range expectedMatrix {
+		for col := range expectedMatrix[row] {
+			if result.Matrix[row][col] != expectedMatrix[row][col] {
+				t.Fatalf("Expect confusion matrix %v, but got %v", expectedMatrix, result.Matrix)
+			}
+		}
+	}
+	if len(result.Labels) != 3 || result.Labels[2] != "" {
+		t.Errorf("Expect labels end with no intent, but got %v", result.Labels)
+	}
+
+	a := result.Intents[0]
+	if a.Precision != 0.6667 || a.Recall != 0.5 || a.F1 != 0.5714 || a.Support != 4 {
+		t.Errorf("Unexpected metrics of A: %+v", a)
+	}
+	b := result.Intents[1]
+	if b.Precision != 0.5 || b.Recall != 0.5 || b.F1 != 0.5 || b.Support != 2 {
+		t.Errorf("Unexpected metrics of B: %+v", b)
+	}
+	if result.Accuracy != 0.5 {
+		t.Errorf("Expect accuracy 0.5, but got %f", result.Accuracy)
+	}
+
+	if len(result.ConfusedPairs) != 3 {
+		t.Fatalf("Expect 3 confused pairs, but got %d", len(result.ConfusedPairs))
+	}
+	first := result.ConfusedPairs[0]
+	if first.Expected != "A" || first.Predicted != "B" || first.Sentences[0] != "a3" {
+		t.Errorf("Unexpected confused pair: %+v", first)
+	}
+}
+
+func TestPollUntil(t *testing.T) {
+	defer func(interval time.Duration) {
+		data.CrossValidationPollInterval = interval
+	}(data.CrossValidationPollInterval)
+	data.CrossValidationPollInterval = time.Millisecond
+
+	checks := 0
+	ready, err := pollUntil(time.Second, func() (bool, error) {
+		checks++
+		return checks == 3, nil
+	})
+	if !ready || err != nil || checks != 3 {
+		t.Errorf("Expect ready after 3 checks, but got ready: %t, checks: %d, err: %v", ready, checks, err)
+	}
+
+	ready, err = pollUntil(5*time.Millisecond, func() (bool, error) {
+		return false, nil
+	})
+	if ready || err != nil {
+		t.Errorf("Expect timeout without error, but got ready: %t, err: %v", ready, err)
+	}
+}
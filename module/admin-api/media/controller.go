@@ -2,10 +2,11 @@ package media
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
-
-	config "emotibot.com/emotigo/module/admin-api/Robot/config.v1"
+	"strings"
 
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
 
@@ -21,12 +22,21 @@ var (
 	bucketName        = "media"
 )
 
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 func Init() {
 	ModuleInfo = util.ModuleInfo{
 		ModuleName: "media",
 		EntryPoints: []util.EntryPoint{
 			util.NewEntryPoint("POST", "image", []string{"edit"}, handleUploadImage),
 			util.NewEntryPoint("GET", "image/{appid}/{id}", []string{}, handleGetImage),
+			util.NewEntryPoint("GET", "library", []string{"view"}, handleListMedia),
+			util.NewEntryPoint("GET", "library/{id}", []string{"view"}, handleGetMedia),
+			util.NewEntryPoint("PUT", "library/{id}", []string{"edit"}, handleUpdateMedia),
+			util.NewEntryPoint("DELETE", "library/{id}", []string{"edit"}, handleDeleteMedia),
 		},
 	}
 	mediaBucket, found := util.GetEnvOf("server")["MINIO_BUCKET_MEDIA"]
@@ -52,27 +62,23 @@ func handleUploadImage(w http.ResponseWriter, r *http.Request) {
 	logger.Info.Printf("Receive uploaded file: %s", info.Filename)
 	logger.Trace.Printf("Uploaded file info %#v", info.Header)
 
-	id, err := AddFile(appid, bucketName, file)
-	if err != nil {
-		util.Return(w, err, nil)
+	// Read one more byte than limit, so too large file can be detected
+	content, readErr := ioutil.ReadAll(io.LimitReader(file, maxMediaSize+1))
+	if readErr != nil {
+		util.ReturnError(w, AdminErrors.ErrnoIOError, fmt.Sprintf("Read upload file fail, %s", readErr.Error()))
 		return
 	}
 
-	config, err := config.GetConfig(appid, OuterURLConfigKey)
-	if err != nil {
-		util.Return(w, err, nil)
-		return
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = info.Filename
 	}
-	outerURL := ""
-	if config != nil {
-		outerURL = config.Value
+	tags := []string{}
+	if tagStr := r.FormValue("tags"); tagStr != "" {
+		tags = strings.Split(tagStr, ",")
 	}
 
-	url := fmt.Sprintf("%s/api/v1/media/image/%s/%s", outerURL, appid, id)
-	ret := &map[string]string{
-		"id":  id,
-		"url": url,
-	}
+	ret, err := UploadMedia(appid, name, content, tags)
 	util.Return(w, err, ret)
 }
 
@@ -84,7 +90,7 @@ func handleGetImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	buf, err := GetFile(appid, bucketName, id)
+	buf, err := GetMediaFile(appid, id, r.URL.Query().Get("size"))
 	if err != nil {
 		w.Write([]byte(fmt.Sprintf("Get file fail, %s", err.Error())))
 		return
@@ -96,3 +102,74 @@ func handleGetImage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
 	w.Write(buf)
 }
+
+func handleListMedia(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := &MediaQuery{
+		AppID:    requestheader.GetAppID(r),
+		Keyword:  strings.TrimSpace(params.Get("keyword")),
+		Tag:      strings.TrimSpace(params.Get("tag")),
+		MimeType: params.Get("mime"),
+		Limit:    defaultListLimit,
+	}
+	if limit := params.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 || l > maxListLimit {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, "limit invalid")
+			return
+		}
+		query.Limit = l
+	}
+	if page := params.Get("page"); page != "" {
+		p, err := strconv.Atoi(page)
+		if err != nil || p <= 0 {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, "page invalid")
+			return
+		}
+		query.Offset = (p - 1) * query.Limit
+	}
+
+	ret, err := ListMedia(query)
+	util.Return(w, err, ret)
+}
+
+func handleGetMedia(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	id := util.GetMuxVar(r, "id")
+
+	ret, err := GetMedia(appid, id)
+	util.Return(w, err, ret)
+}
+
+func handleUpdateMedia(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	id := util.GetMuxVar(r, "id")
+
+	input := struct {
+		Name string `json:"name"`
+		// Tags is kept if it is not in request
+		Tags []string `json:"tags"`
+	}{}
+	if err := util.ReadJSON(r, &input); err != nil {
+		util.ReturnError(w, AdminErrors.ErrnoJSONParse, err.Error())
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		util.ReturnError(w, AdminErrors.ErrnoRequestError, "name is empty")
+		return
+	}
+
+	ret, err := UpdateMedia(appid, id, input.Name, input.Tags)
+	util.Return(w, err, ret)
+}
+
+func handleDeleteMedia(w http.ResponseWriter, r *http.Request) {
+	appid := requestheader.GetAppID(r)
+	id := util.GetMuxVar(r, "id")
+
+	// Contents using the media are returned if it cannot be deleted,
+	// so user can remove the image from them first
+	refs, err := DeleteMedia(appid, id)
+	util.Return(w, err, refs)
+}
@@ -0,0 +1,412 @@
+package media
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+const mediaColumns = "id, appid, name, hash, mime_type, size, width, height, variants, created_at, updated_at"
+
+func scanMedia(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Media, error) {
+	media := &Media{}
+	variants := ""
+	err := scanner.Scan(&media.ID, &media.AppID, &media.Name, &media.Hash, &media.MimeType, &media.Size,
+		&media.Width, &media.Height, &variants, &media.CreatedAt, &media.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	media.Variants = []string{}
+	if variants != "" {
+		media.Variants = strings.Split(variants, ",")
+	}
+	media.Tags = []string{}
+	return media, nil
+}
+
+func getMedia(appid string, id string) (*Media, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("SELECT %s FROM media WHERE appid = ? AND id = ?", mediaColumns)
+	media, err := scanMedia(db.QueryRow(queryStr, appid, id))
+	if err != nil {
+		return nil, err
+	}
+	if err = fillMediaInfo(db, []*Media{media}); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+func getMediaByHash(appid string, hash string) (*Media, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	queryStr := fmt.Sprintf("SELECT %s FROM media WHERE appid = ? AND hash = ?", mediaColumns)
+	media, err := scanMedia(db.QueryRow(queryStr, appid, hash))
+	if err != nil {
+		return nil, err
+	}
+	if err = fillMediaInfo(db, []*Media{media}); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// insertMedia inserts media with its tags, inserted is false if media of the same hash exists
+func insertMedia(media *Media) (inserted bool, err error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+	defer util.ClearTransition(tx)
+
+	queryStr := fmt.Sprintf(`
+		INSERT IGNORE INTO media (%s)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, mediaColumns)
+	result, err := tx.Exec(queryStr, media.ID, media.AppID, media.Name, media.Hash, media.MimeType, media.Size,
+		media.Width, media.Height, strings.Join(media.Variants, ","), media.CreatedAt, media.UpdatedAt)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return
+	}
+	if err = insertMediaTags(tx, media.AppID, media.ID, media.Tags); err != nil {
+		return
+	}
+	return true, tx.Commit()
+}
+
+func insertMediaTags(tx *sql.Tx, appid string, id string, tags []string) error {
+	queryStr := "INSERT IGNORE INTO media_tags (media_id, appid, tag) VALUES (?, ?, ?)"
+	for _, tag := range tags {
+		if _, err := tx.Exec(queryStr, id, appid, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMedia updates name of media, and replaces tags if tags is not nil
+func updateMedia(appid string, id string, name string, tags []string, now int64) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer util.ClearTransition(tx)
+
+	result, err := tx.Exec("UPDATE media SET name = ?, updated_at = ? WHERE appid = ? AND id = ?", name, now, appid, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if tags != nil {
+		if _, err = tx.Exec("DELETE FROM media_tags WHERE media_id = ?", id); err != nil {
+			return err
+		}
+		if err = insertMediaTags(tx, appid, id, tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addMediaTags adds tags to media, which is used when the same content is uploaded again
+func addMediaTags(appid string, id string, tags []string) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer util.ClearTransition(tx)
+
+	if err = insertMediaTags(tx, appid, id, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteMedia deletes media if it is not referenced, references are returned if it is referenced.
+// Media row is locked, so references cannot be added while deleting.
+func deleteMedia(appid string, id string) ([]*Reference, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer util.ClearTransition(tx)
+
+	mediaID := ""
+	err = tx.QueryRow("SELECT id FROM media WHERE appid = ? AND id = ? FOR UPDATE", appid, id).Scan(&mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT media_id, ref_type, ref_id, created_at FROM media_references
+		WHERE media_id = ? FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := scanReferences(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		return refs, nil
+	}
+
+	if _, err = tx.Exec("DELETE FROM media_tags WHERE media_id = ?", id); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec("DELETE FROM media WHERE appid = ? AND id = ?", appid, id); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+// getMediaList returns media matched query and the total number
+func getMediaList(query *MediaQuery) ([]*Media, int64, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, 0, util.ErrDBNotInit
+	}
+
+	conditions := []string{"appid = ?"}
+	params := []interface{}{query.AppID}
+	if query.Keyword != "" {
+		conditions = append(conditions, "(name LIKE ? OR id = ?)")
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query.Keyword)
+		params = append(params, "%"+escaped+"%", query.Keyword)
+	}
+	if query.MimeType != "" {
+		conditions = append(conditions, "mime_type = ?")
+		params = append(params, query.MimeType)
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, "id IN (SELECT media_id FROM media_tags WHERE appid = ? AND tag = ?)")
+		params = append(params, query.AppID, query.Tag)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	err := db.QueryRow("SELECT COUNT(*) FROM media WHERE "+where, params...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	queryStr := fmt.Sprintf("SELECT %s FROM media WHERE %s ORDER BY created_at DESC, id LIMIT ? OFFSET ?", mediaColumns, where)
+	rows, err := db.Query(queryStr, append(params, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := []*Media{}
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, media)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	if err = fillMediaInfo(db, list); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// fillMediaInfo fills tags and number of references of media
+func fillMediaInfo(db *sql.DB, list []*Media) error {
+	if len(list) == 0 {
+		return nil
+	}
+	mediaMap := map[string]*Media{}
+	params := []interface{}{}
+	for _, media := range list {
+		mediaMap[media.ID] = media
+		params = append(params, media.ID)
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(list)), ",")
+
+	rows, err := db.Query(fmt.Sprintf("SELECT media_id, tag FROM media_tags WHERE media_id IN (%s) ORDER BY tag", in), params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		id, tag := "", ""
+		if err = rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		mediaMap[id].Tags = append(mediaMap[id].Tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	rows, err = db.Query(fmt.Sprintf(`
+		SELECT media_id, COUNT(*) FROM media_references
+		WHERE media_id IN (%s) GROUP BY media_id`, in), params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		id, count := "", 0
+		if err = rows.Scan(&id, &count); err != nil {
+			return err
+		}
+		mediaMap[id].RefCount = count
+	}
+	return rows.Err()
+}
+
+func getMediaReferences(appid string, id string) ([]*Reference, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	rows, err := db.Query(`
+		SELECT media_id, ref_type, ref_id, created_at FROM media_references
+		WHERE appid = ? AND media_id = ? ORDER BY ref_type, ref_id`, appid, id)
+	if err != nil {
+		return nil, err
+	}
+	return scanReferences(rows)
+}
+
+func scanReferences(rows *sql.Rows) ([]*Reference, error) {
+	defer rows.Close()
+	refs := []*Reference{}
+	for rows.Next() {
+		ref := &Reference{}
+		if err := rows.Scan(&ref.MediaID, &ref.RefType, &ref.RefID, &ref.CreatedAt); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// setReferences replaces media used by a content with ids. Media not in library,
+// such as images uploaded before library, are ignored. Media rows are locked,
+// so media cannot be deleted while adding references to it.
+func setReferences(appid string, refType string, refID string, ids []string, now int64) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer util.ClearTransition(tx)
+
+	_, err = tx.Exec("DELETE FROM media_references WHERE appid = ? AND ref_type = ? AND ref_id = ?", appid, refType, refID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		mediaID := ""
+		err = tx.QueryRow("SELECT id FROM media WHERE appid = ? AND id = ? FOR UPDATE", appid, id).Scan(&mediaID)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT IGNORE INTO media_references (appid, media_id, ref_type, ref_id, created_at)
+			VALUES (?, ?, ?, ?, ?)`, appid, id, refType, refID, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// getReferenceIDs returns ids of contents of refType using media in app
+func getReferenceIDs(appid string, refType string) ([]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT ref_id FROM media_references
+		WHERE appid = ? AND ref_type = ?`, appid, refType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		id := ""
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// getReferenceAppIDs returns apps which have contents of refType using media
+func getReferenceAppIDs(refType string) ([]string, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	rows, err := db.Query("SELECT DISTINCT appid FROM media_references WHERE ref_type = ?", refType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appids := []string{}
+	for rows.Next() {
+		appid := ""
+		if err := rows.Scan(&appid); err != nil {
+			return nil, err
+		}
+		appids = append(appids, appid)
+	}
+	return appids, rows.Err()
+}
@@ -1,11 +1,19 @@
 package media
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"regexp"
+	"strings"
 	"time"
 
+	config "emotibot.com/emotigo/module/admin-api/Robot/config.v1"
 	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/pkg/logger"
 
 	"emotibot.com/emotigo/pkg/services/fileservice"
 
@@ -14,9 +22,21 @@ import (
 
 const IDLength = 16
 
+const (
+	maxNameLength = 255
+	maxTagLength  = 64
+	maxTagCount   = 20
+)
+
+// mediaURLPattern matches url of uploaded image in contents, with appid and id as submatches
+var mediaURLPattern = regexp.MustCompile(`/api/v1/media/image/([\w-]+)/([\w-]+)`)
+
+func newMediaID() string {
+	return fmt.Sprintf("%s-%s", time.Now().Format("20060102150405"), util.GenRandomString(IDLength))
+}
+
 func AddFile(appid string, bucketName string, input io.Reader) (string, AdminErrors.AdminError) {
-	now := time.Now()
-	id := fmt.Sprintf("%s-%s", now.Format("20060102150405"), util.GenRandomString(IDLength))
+	id := newMediaID()
 	path := fmt.Sprintf("%s/%s", appid, id)
 	err := fileservice.AddFile(bucketName, path, input)
 	if err != nil {
@@ -33,3 +53,395 @@ func GetFile(appid string, bucketName string, id string) ([]byte, AdminErrors.Ad
 	}
 	return buf, nil
 }
+
+func variantPath(appid string, id string, variant string) string {
+	if variant == "" {
+		return fmt.Sprintf("%s/%s", appid, id)
+	}
+	return fmt.Sprintf("%s/%s_%s", appid, id, variant)
+}
+
+// UploadMedia adds image into library of app. If the same content is uploaded before,
+// existed media is returned with new tags added, and no file is stored again.
+func UploadMedia(appid string, name string, content []byte, tags []string) (*UploadResult, AdminErrors.AdminError) {
+	tags, adminErr := normalizeTags(tags)
+	if adminErr != nil {
+		return nil, adminErr
+	}
+	if len(name) > maxNameLength {
+		return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, "name is too long")
+	}
+
+	mimeType, imageConfig, err := validateImage(content)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, err.Error())
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	existed, adminErr := getDuplicatedMedia(appid, hash, tags)
+	if adminErr != nil || existed != nil {
+		return existed, adminErr
+	}
+
+	variants, err := generateVariants(content, mimeType)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid image, %s", err.Error()))
+	}
+
+	now := time.Now().Unix()
+	media := &Media{
+		ID:        newMediaID(),
+		AppID:     appid,
+		Name:      name,
+		Hash:      hash,
+		MimeType:  mimeType,
+		Size:      int64(len(content)),
+		Width:     imageConfig.Width,
+		Height:    imageConfig.Height,
+		Tags:      tags,
+		Variants:  []string{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = fileservice.AddFile(bucketName, variantPath(appid, media.ID, ""), bytes.NewReader(content)); err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoAPIError, err.Error())
+	}
+	for _, size := range variantSizes {
+		data, ok := variants[size.Name]
+		if !ok {
+			continue
+		}
+		if err = fileservice.AddFile(bucketName, variantPath(appid, media.ID, size.Name), bytes.NewReader(data)); err != nil {
+			removeMediaFiles(media)
+			return nil, AdminErrors.New(AdminErrors.ErrnoAPIError, err.Error())
+		}
+		media.Variants = append(media.Variants, size.Name)
+	}
+
+	inserted, err := insertMedia(media)
+	if err != nil {
+		removeMediaFiles(media)
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	if !inserted {
+		// The same content is uploaded at the same time, keep the one in library
+		removeMediaFiles(media)
+		existed, adminErr = getDuplicatedMedia(appid, hash, tags)
+		if adminErr == nil && existed == nil {
+			adminErr = AdminErrors.New(AdminErrors.ErrnoDBError, "media of the same content is not found")
+		}
+		return existed, adminErr
+	}
+
+	if adminErr = fillMediaURL(appid, []*Media{media}); adminErr != nil {
+		return nil, adminErr
+	}
+	return &UploadResult{Media: media}, nil
+}
+
+// getDuplicatedMedia returns media of the same hash after adding tags, nil is returned if not existed
+func getDuplicatedMedia(appid string, hash string, tags []string) (*UploadResult, AdminErrors.AdminError) {
+	media, err := getMediaByHash(appid, hash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+
+	if len(tags) > 0 {
+		if err = addMediaTags(appid, media.ID, tags); err != nil {
+			return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+		}
+		if media, err = getMedia(appid, media.ID); err != nil {
+			return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+		}
+	}
+	if adminErr := fillMediaURL(appid, []*Media{media}); adminErr != nil {
+		return nil, adminErr
+	}
+	return &UploadResult{Media: media, Duplicated: true}, nil
+}
+
+// removeMediaFiles removes stored files of media, failure is only logged because
+// files without record in library will not be used
+func removeMediaFiles(media *Media) {
+	paths := []string{variantPath(media.AppID, media.ID, "")}
+	for _, variant := range media.Variants {
+		paths = append(paths, variantPath(media.AppID, media.ID, variant))
+	}
+	for _, path := range paths {
+		if err := fileservice.DeleteFile(bucketName, path); err != nil {
+			logger.Error.Printf("Remove media file %s fail: %s\n", path, err.Error())
+		}
+	}
+}
+
+// GetMediaFile returns content of image, or its size variant if variant is not empty.
+// Original image is returned if it is smaller than the variant or uploaded before library.
+func GetMediaFile(appid string, id string, variant string) ([]byte, AdminErrors.AdminError) {
+	path := variantPath(appid, id, "")
+	if variant != "" {
+		valid := false
+		for _, size := range variantSizes {
+			if size.Name == variant {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, "size invalid")
+		}
+
+		media, err := getMedia(appid, id)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+		}
+		if media != nil {
+			for _, v := range media.Variants {
+				if v == variant {
+					path = variantPath(appid, id, variant)
+				}
+			}
+		}
+	}
+
+	buf, err := fileservice.GetFile(bucketName, path)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoAPIError, err.Error())
+	}
+	return buf, nil
+}
+
+func ListMedia(query *MediaQuery) (*MediaList, AdminErrors.AdminError) {
+	list, total, err := getMediaList(query)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	if adminErr := fillMediaURL(query.AppID, list); adminErr != nil {
+		return nil, adminErr
+	}
+	return &MediaList{Total: total, Data: list}, nil
+}
+
+// GetMedia returns media with contents using it
+func GetMedia(appid string, id string) (*Media, AdminErrors.AdminError) {
+	media, err := getMedia(appid, id)
+	if err == sql.ErrNoRows {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "media not found")
+	} else if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+
+	media.References, err = getMediaReferences(appid, id)
+	if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	if adminErr := fillMediaURL(appid, []*Media{media}); adminErr != nil {
+		return nil, adminErr
+	}
+	return media, nil
+}
+
+// UpdateMedia updates name and tags of media, tags are kept if tags is nil
+func UpdateMedia(appid string, id string, name string, tags []string) (*Media, AdminErrors.AdminError) {
+	if len(name) > maxNameLength {
+		return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, "name is too long")
+	}
+	if tags != nil {
+		var adminErr AdminErrors.AdminError
+		if tags, adminErr = normalizeTags(tags); adminErr != nil {
+			return nil, adminErr
+		}
+	}
+
+	err := updateMedia(appid, id, name, tags, time.Now().Unix())
+	if err == sql.ErrNoRows {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "media not found")
+	} else if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return GetMedia(appid, id)
+}
+
+// DeleteMedia deletes media and its files. Media used by any content cannot be deleted,
+// and the contents using it are returned with the error.
+func DeleteMedia(appid string, id string) ([]*Reference, AdminErrors.AdminError) {
+	media, err := getMedia(appid, id)
+	if err == sql.ErrNoRows {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "media not found")
+	} else if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+
+	refs, err := deleteMedia(appid, id)
+	if err == sql.ErrNoRows {
+		return nil, AdminErrors.New(AdminErrors.ErrnoNotFound, "media not found")
+	} else if err != nil {
+		return nil, AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	if len(refs) > 0 {
+		return refs, AdminErrors.New(AdminErrors.ErrnoRequestError,
+			fmt.Sprintf("media is used by %d contents", len(refs)))
+	}
+
+	removeMediaFiles(media)
+	return nil, nil
+}
+
+// SyncReferences records media used by contents of refType and refID, which replaces
+// media recorded before. It should be called after the content is saved.
+func SyncReferences(appid string, refType string, refID string, contents ...string) AdminErrors.AdminError {
+	if !refTypes[refType] {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid ref type %s", refType))
+	}
+	if refID == "" {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, "ref id is empty")
+	}
+
+	ids := []string{}
+	existed := map[string]bool{}
+	for _, content := range contents {
+		for _, id := range extractMediaIDs(appid, content) {
+			if !existed[id] {
+				existed[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if err := setReferences(appid, refType, refID, ids, time.Now().Unix()); err != nil {
+		return AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	return nil
+}
+
+// RemoveReferences removes all media used by content, it should be called after the content is deleted
+func RemoveReferences(appid string, refType string, refID string) AdminErrors.AdminError {
+	return SyncReferences(appid, refType, refID)
+}
+
+// ReplaceReferences records media used by all contents of refType in app, contents is keyed by ref id.
+// References of contents not given are removed, so it is used when contents are changed in bulk.
+func ReplaceReferences(appid string, refType string, contents map[string][]string) AdminErrors.AdminError {
+	if !refTypes[refType] {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid ref type %s", refType))
+	}
+
+	refIDs, err := getReferenceIDs(appid, refType)
+	if err != nil {
+		return AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	for refID, refContents := range contents {
+		if adminErr := SyncReferences(appid, refType, refID, refContents...); adminErr != nil {
+			return adminErr
+		}
+	}
+	for _, refID := range refIDs {
+		if _, ok := contents[refID]; ok {
+			continue
+		}
+		if adminErr := RemoveReferences(appid, refType, refID); adminErr != nil {
+			return adminErr
+		}
+	}
+	return nil
+}
+
+// BackfillReferences replaces references of refType in all apps, contents is keyed by appid and ref id.
+// It records contents saved before references are synced, apps not in contents lose references of refType,
+// and apps with nil contents are skipped, which is for apps whose contents cannot be loaded.
+func BackfillReferences(refType string, contents map[string]map[string][]string) AdminErrors.AdminError {
+	if !refTypes[refType] {
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid ref type %s", refType))
+	}
+
+	appids, err := getReferenceAppIDs(refType)
+	if err != nil {
+		return AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+	}
+	for _, appid := range appids {
+		if _, ok := contents[appid]; !ok {
+			contents[appid] = map[string][]string{}
+		}
+	}
+
+	failed := 0
+	for appid, appContents := range contents {
+		if appContents == nil {
+			continue
+		}
+		if adminErr := ReplaceReferences(appid, refType, appContents); adminErr != nil {
+			logger.Error.Printf("Backfill %s media references of app %s fail: %s\n", refType, appid, adminErr.Error())
+			failed++
+		}
+	}
+	if failed > 0 {
+		return AdminErrors.New(AdminErrors.ErrnoDBError, fmt.Sprintf("backfill %s media references of %d apps fail", refType, failed))
+	}
+	return nil
+}
+
+// extractMediaIDs returns ids of images of app whose url is in content
+func extractMediaIDs(appid string, content string) []string {
+	ids := []string{}
+	for _, match := range mediaURLPattern.FindAllStringSubmatch(content, -1) {
+		if match[1] == appid {
+			ids = append(ids, match[2])
+		}
+	}
+	return ids
+}
+
+// normalizeTags trims tags and removes empty and duplicated ones
+func normalizeTags(tags []string) ([]string, AdminErrors.AdminError) {
+	ret := []string{}
+	existed := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || existed[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("tag %s is too long", tag))
+		}
+		existed[tag] = true
+		ret = append(ret, tag)
+	}
+	if len(ret) > maxTagCount {
+		return nil, AdminErrors.New(AdminErrors.ErrnoRequestError, fmt.Sprintf("media can have at most %d tags", maxTagCount))
+	}
+	return ret, nil
+}
+
+// getOuterURL returns url prefix of images of app, which is empty if not configured
+func getOuterURL(appid string) (string, AdminErrors.AdminError) {
+	config, err := config.GetConfig(appid, OuterURLConfigKey)
+	if err != nil {
+		if err.Errno() == AdminErrors.ErrnoNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	if config == nil {
+		return "", nil
+	}
+	return config.Value, nil
+}
+
+func mediaURL(outerURL string, appid string, id string) string {
+	return fmt.Sprintf("%s/api/v1/media/image/%s/%s", outerURL, appid, id)
+}
+
+func fillMediaURL(appid string, list []*Media) AdminErrors.AdminError {
+	if len(list) == 0 {
+		return nil
+	}
+	outerURL, err := getOuterURL(appid)
+	if err != nil {
+		return err
+	}
+	for _, media := range list {
+		media.URL = mediaURL(outerURL, appid, media.ID)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package media
+
+// Types of references to media, which are contents using the image
+const (
+	RefTypeFAQAnswer   = "faq_answer"
+	RefTypeRobotWord   = "robot_word"
+	RefTypeRobotAnswer = "robot_answer"
+	RefTypeLineFlex    = "line_flex"
+)
+
+var refTypes = map[string]bool{
+	RefTypeFAQAnswer:   true,
+	RefTypeRobotWord:   true,
+	RefTypeRobotAnswer: true,
+	RefTypeLineFlex:    true,
+}
+
+// Media is an image in media library, the same content is stored once in an app
+type Media struct {
+	ID       string   `json:"id"`
+	AppID    string   `json:"appid"`
+	Name     string   `json:"name"`
+	Hash     string   `json:"hash"`
+	MimeType string   `json:"mime_type"`
+	Size     int64    `json:"size"`
+	Width    int      `json:"width"`
+	Height   int      `json:"height"`
+	Tags     []string `json:"tags"`
+	// Variants is the names of generated size variants
+	Variants   []string     `json:"variants"`
+	RefCount   int          `json:"ref_count"`
+	References []*Reference `json:"references,omitempty"`
+	URL        string       `json:"url"`
+	CreatedAt  int64        `json:"created_at"`
+	UpdatedAt  int64        `json:"updated_at"`
+}
+
+// Reference is a content using media, RefID is the id of content in its module
+type Reference struct {
+	MediaID   string `json:"media_id"`
+	RefType   string `json:"ref_type"`
+	RefID     string `json:"ref_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// MediaQuery filters media of app in library, keyword matches name or id
+type MediaQuery struct {
+	AppID    string
+	Keyword  string
+	Tag      string
+	MimeType string
+	Limit    int
+	Offset   int
+}
+
+type MediaList struct {
+	Total int64    `json:"total"`
+	Data  []*Media `json:"data"`
+}
+
+// UploadResult is the result of upload, Duplicated is true if the same content is uploaded before
+type UploadResult struct {
+	*Media
+	Duplicated bool `json:"duplicated"`
+}
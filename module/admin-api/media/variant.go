@@ -0,0 +1,173 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	// Register decoders of gif, which is used by image.Decode
+	_ "image/gif"
+)
+
+const (
+	// maxMediaSize is the max size of uploaded file in bytes
+	maxMediaSize = 10 << 20
+	// maxMediaPixels limits decoded size of image, so small files of huge images are rejected
+	maxMediaPixels = 40000000
+	variantQuality = 85
+)
+
+// variantSize is a size variant, image is scaled to fit MaxSide keeping aspect ratio
+type variantSize struct {
+	Name    string
+	MaxSide int
+}
+
+var variantSizes = []variantSize{
+	{Name: "thumb", MaxSide: 128},
+	{Name: "small", MaxSide: 320},
+	{Name: "medium", MaxSide: 800},
+}
+
+// allowedMimeTypes is the types of image which can be uploaded, value is format of image package
+var allowedMimeTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+}
+
+var (
+	errMediaEmpty    = errors.New("file is empty")
+	errMediaTooLarge = fmt.Errorf("file is larger than %d MB", maxMediaSize>>20)
+)
+
+// validateImage checks size and type of content by its data instead of file name,
+// and returns MIME type and config of image
+func validateImage(content []byte) (string, image.Config, error) {
+	if len(content) == 0 {
+		return "", image.Config{}, errMediaEmpty
+	}
+	if len(content) > maxMediaSize {
+		return "", image.Config{}, errMediaTooLarge
+	}
+
+	mimeType := http.DetectContentType(content)
+	format, ok := allowedMimeTypes[mimeType]
+	if !ok {
+		return "", image.Config{}, fmt.Errorf("unsupported file type %s", mimeType)
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return "", image.Config{}, fmt.Errorf("invalid image, %s", err.Error())
+	}
+	if decodedFormat != format {
+		return "", image.Config{}, fmt.Errorf("image format %s mismatches type %s", decodedFormat, mimeType)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxMediaPixels {
+		return "", image.Config{}, fmt.Errorf("invalid image size %dx%d", config.Width, config.Height)
+	}
+	return mimeType, config, nil
+}
+
+// generateVariants scales image to variant sizes smaller than it.
+// Variants of png and gif are png to keep transparency, others are jpeg.
+func generateVariants(content []byte, mimeType string) (map[string][]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	variants := map[string][]byte{}
+	bounds := src.Bounds()
+	for _, size := range variantSizes {
+		width, height := fitSize(bounds.Dx(), bounds.Dy(), size.MaxSide)
+		if width == bounds.Dx() && height == bounds.Dy() {
+			continue
+		}
+		dst := resizeImage(src, width, height)
+
+		buf := bytes.Buffer{}
+		if mimeType == "image/jpeg" {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: variantQuality})
+		} else {
+			err = png.Encode(&buf, dst)
+		}
+		if err != nil {
+			return nil, err
+		}
+		variants[size.Name] = buf.Bytes()
+	}
+	return variants, nil
+}
+
+// fitSize returns size scaled to fit maxSide, size smaller than maxSide is not changed
+func fitSize(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		h := height * maxSide / width
+		if h < 1 {
+			h = 1
+		}
+		return maxSide, h
+	}
+	w := width * maxSide / height
+	if w < 1 {
+		w = 1
+	}
+	return w, maxSide
+}
+
+// resizeImage downscales src by averaging source pixels covered by each target pixel
+func resizeImage(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	rgba := image.NewNRGBA(bounds)
+	draw.Draw(rgba, bounds, src, bounds.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := (y + 1) * srcH / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := (x + 1) * srcW / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(bounds.Min.X+x0, bounds.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					pix := rgba.Pix[offset : offset+4]
+					// Weight colors by alpha, so transparent pixels do not darken edges
+					alpha := uint64(pix[3])
+					r += uint64(pix[0]) * alpha
+					g += uint64(pix[1]) * alpha
+					b += uint64(pix[2]) * alpha
+					a += alpha
+					count++
+					offset += 4
+				}
+			}
+			c := color.NRGBA{}
+			if a > 0 {
+				c = color.NRGBA{R: uint8(r / a), G: uint8(g / a), B: uint8(b / a), A: uint8(a / count)}
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
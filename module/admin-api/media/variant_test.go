@@ -0,0 +1,125 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidateImage(t *testing.T) {
+	if _, _, err := validateImage(nil); err != errMediaEmpty {
+		t.Errorf("Expect empty file rejected, get %v", err)
+	}
+	if _, _, err := validateImage(make([]byte, maxMediaSize+1)); err != errMediaTooLarge {
+		t.Errorf("Expect large file rejected, get %v", err)
+	}
+	if _, _, err := validateImage([]byte("<html><body>not image</body></html>")); err == nil {
+		t.Error("Expect html rejected")
+	}
+	// Broken image with png signature
+	content := encodePNG(t, 10, 10)
+	if _, _, err := validateImage(content[:20]); err == nil {
+		t.Error("Expect broken png rejected")
+	}
+
+	mimeType, config, err := validateImage(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mimeType != "image/png" || config.Width != 10 || config.Height != 10 {
+		t.Errorf("Expect 10x10 png, get %s %dx%d", mimeType, config.Width, config.Height)
+	}
+}
+
+func TestGenerateVariants(t *testing.T) {
+	variants, err := generateVariants(encodePNG(t, 400, 200), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// medium is larger than the image, so it is not generated
+	expects := map[string][2]int{"thumb": {128, 64}, "small": {320, 160}}
+	if len(variants) != len(expects) {
+		t.Errorf("Expect variants %v, get %d variants", expects, len(variants))
+	}
+	for name, size := range expects {
+		img, format, err := image.Decode(bytes.NewReader(variants[name]))
+		if err != nil {
+			t.Fatalf("Decode variant %s fail, %s", name, err.Error())
+		}
+		if format != "png" || img.Bounds().Dx() != size[0] || img.Bounds().Dy() != size[1] {
+			t.Errorf("Expect %s png of %v, get %s of %v", name, size, format, img.Bounds())
+		}
+	}
+
+	buf := bytes.Buffer{}
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 200)), nil); err != nil {
+		t.Fatal(err)
+	}
+	variants, err = generateVariants(buf.Bytes(), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, format, err := image.Decode(bytes.NewReader(variants["thumb"])); err != nil || format != "jpeg" {
+		t.Errorf("Expect jpeg thumb of jpeg, get %s, %v", format, err)
+	}
+
+	variants, err = generateVariants(encodePNG(t, 100, 50), "image/png")
+	if err != nil || len(variants) != 0 {
+		t.Errorf("Expect no variants of small image, get %d, %v", len(variants), err)
+	}
+}
+
+func TestFitSize(t *testing.T) {
+	cases := [][5]int{
+		// width, height, maxSide, expected width, expected height
+		{100, 50, 128, 100, 50},
+		{1000, 500, 128, 128, 64},
+		{500, 1000, 128, 64, 128},
+		{10000, 1, 128, 128, 1},
+	}
+	for _, c := range cases {
+		if w, h := fitSize(c[0], c[1], c[2]); w != c[3] || h != c[4] {
+			t.Errorf("Expect %dx%d fit to %dx%d, get %dx%d", c[0], c[1], c[3], c[4], w, h)
+		}
+	}
+}
+
+func TestExtractMediaIDs(t *testing.T) {
+	content := `<img src="http://host/api/v1/media/image/app1/20261019120000-abcDEF?size=thumb">` +
+		`[image](/api/v1/media/image/app2/20261019120000-other) /api/v1/media/image/app1/20261019120001-x`
+	expect := []string{"20261019120000-abcDEF", "20261019120001-x"}
+	if ids := extractMediaIDs("app1", content); !reflect.DeepEqual(ids, expect) {
+		t.Errorf("Expect ids %v, get %v", expect, ids)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{" logo", "", "logo", "banner "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"logo", "banner"}; !reflect.DeepEqual(tags, expect) {
+		t.Errorf("Expect tags %v, get %v", expect, tags)
+	}
+	if _, err := normalizeTags([]string{string(make([]byte, maxTagLength+1))}); err == nil {
+		t.Error("Expect long tag rejected")
+	}
+}
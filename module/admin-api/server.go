@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/BF"
 	"emotibot.com/emotigo/module/admin-api/CustomChat"
 	"emotibot.com/emotigo/module/admin-api/ELKStats"
+	"emotibot.com/emotigo/module/admin-api/ELKStats/repository"
 	"emotibot.com/emotigo/module/admin-api/FAQ"
 	"emotibot.com/emotigo/module/admin-api/QA"
 	"emotibot.com/emotigo/module/admin-api/QADoc"
@@ -135,8 +137,10 @@ func init() {
 	}
 	logger.SetLevel(logLevel)
 	logger.Info.Printf("Set log level %s\n", logLevel)
-	initConsul()
+	initConfigStore()
 	initDB()
+	initConsulHistory()
+	initLocaleCatalogs()
 
 	accessLog := serverEnvs["ACCESS_LOG"]
 	if accessLog == "1" {
@@ -441,6 +445,10 @@ func setRoute() *mux.Router {
 		handler := http.FileServer(http.Dir(util.GetMountDir()))
 
 		if newPath := strings.TrimPrefix(r.URL.Path, "/Files/"); len(newPath) < len(r.URL.Path) {
+			// Locales without translated files, such as en-us, use files of default locale
+			if _, err := os.Stat(fmt.Sprintf("%s/%s/%s", util.GetMountDir(), locale, newPath)); err != nil {
+				locale = localemsg.DefaultLocale
+			}
 			newRequest := new(http.Request)
 			*newRequest = *r
 			newRequest.URL = new(url.URL)
@@ -565,6 +573,17 @@ func initDB() {
 }
 
 func initElasticsearch() (err error) {
+	// Stats can run on the embedded repository for deployments without Elasticsearch
+	if getServerEnv("STATS_REPOSITORY") == "embedded" {
+		repo, err := repository.NewEmbedded(getServerEnv("STATS_EMBEDDED_DIR"))
+		if err != nil {
+			return err
+		}
+		repository.Set(repo)
+		logger.Info.Println("Stats use embedded repository")
+		return nil
+	}
+
 	host := getServerEnv("ELASTICSEARCH_HOST")
 	port := getServerEnv("ELASTICSEARCH_PORT")
 	basicAuthUsername := getServerEnv("ELASTICSEARCH_BASIC_AUTH_USERNAME")
@@ -647,6 +666,35 @@ func setupCron() {
 	}
 }
 
+// initConfigStore init config store of CONFIG_STORE, which is consul by default.
+// etcd needs ETCD_URL, and file needs CONFIG_STORE_FILE. Keys are under CONSUL_PREFIX for all stores.
+func initConfigStore() {
+	serverEnvs := util.GetEnvOf("server")
+	backend := serverEnvs["CONFIG_STORE"]
+	if backend == "" || backend == util.ConfigStoreConsul {
+		initConsul()
+		util.DefaultConfigStore = util.DefaultConsulClient
+		util.RootConfigStore = util.RootConsulClient
+		return
+	}
+
+	address := ""
+	switch backend {
+	case util.ConfigStoreEtcd:
+		address = serverEnvs["ETCD_URL"]
+	case util.ConfigStoreFile:
+		address = serverEnvs["CONFIG_STORE_FILE"]
+	}
+	root, err := util.NewConfigStore(backend, address)
+	if err != nil {
+		logger.Error.Printf("Init config store %s failed, %v\n", backend, err)
+		os.Exit(-1)
+	}
+	util.RootConfigStore = root
+	util.DefaultConfigStore = util.NewPrefixConfigStore(root, serverEnvs["CONSUL_PREFIX"])
+	logger.Info.Printf("Init config store %s with prefix: %s\n", backend, serverEnvs["CONSUL_PREFIX"])
+}
+
 func initConsul() {
 	//Init Consul Client
 	serverEnvs := util.GetEnvOf("server")
@@ -682,6 +730,49 @@ func initConsul() {
 	logger.Info.Printf("Init root consul client with url: %s\n", rootURL.String())
 }
 
+// initConsulHistory records writes of config keys in main db,
+// or in file of CONSUL_HISTORY_FILE if set.
+// Records are kept for CONSUL_HISTORY_RETENTION_DAYS, which is 180 days by default.
+func initConsulHistory() {
+	var history util.ConsulHistoryStore = util.NewSQLConsulHistoryStore(util.GetMainDB())
+	if path := getServerEnv("CONSUL_HISTORY_FILE"); path != "" {
+		store, err := util.NewFileConsulHistoryStore(path)
+		if err != nil {
+			logger.Error.Println("Init consul history file fail, ", err.Error())
+			initErrors = append(initErrors, err)
+			return
+		}
+		history = store
+	}
+	store := util.NewHistoryConfigStore(util.DefaultConfigStore, history)
+
+	retention := util.DefaultConsulHistoryRetention
+	if value := getServerEnv("CONSUL_HISTORY_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			logger.Warn.Printf("Invalid CONSUL_HISTORY_RETENTION_DAYS %s, use default\n", value)
+		} else {
+			retention = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	store.SetRetention(retention)
+	util.DefaultConfigStore = store
+}
+
+// initLocaleCatalogs loads message catalogs in LOCALE_CATALOG_DIR and reloads them when changed.
+// Messages compiled into binary are used if it is not set.
+func initLocaleCatalogs() {
+	dir := getServerEnv("LOCALE_CATALOG_DIR")
+	if dir == "" {
+		return
+	}
+	if err := localemsg.LoadCatalogs(dir); err != nil {
+		logger.Error.Println("Load locale catalogs fail, ", err.Error())
+		initErrors = append(initErrors, err)
+	}
+	go localemsg.WatchCatalogs(dir, nil)
+}
+
 type healthResult struct {
 	MemoryUsage uint64            `json:"memory"`
 	MaxProcess  int               `json:"max_process"`
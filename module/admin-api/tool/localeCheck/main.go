@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
+
+	// Packages registering tables keyed by locale, such as headers of exported sheets
+	_ "emotibot.com/emotigo/module/admin-api/BF"
+	_ "emotibot.com/emotigo/module/admin-api/ELKStats/data/v1"
+	_ "emotibot.com/emotigo/module/admin-api/ELKStats/data/v2"
+	_ "emotibot.com/emotigo/module/admin-api/Stats/v2"
+)
+
+// localeCheck reports keys missing in each locale, including catalogs in dir if -d is set,
+// and locales missing in tables registered by localemsg.RegisterTable.
+// It exits with 1 if any key or locale is missing, so it can be used in CI.
+func main() {
+	dirFlag := flag.String("d", "", "Dir of locale catalogs, only messages in binary are checked if not set")
+	flag.Parse()
+
+	if *dirFlag != "" {
+		if err := localemsg.LoadCatalogs(*dirFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Load catalogs fail: %s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+
+	missing := localemsg.MissingKeys()
+	locales := []string{}
+	for locale := range missing {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	for _, locale := range locales {
+		for _, key := range missing[locale] {
+			fmt.Printf("%s\t%s\n", locale, key)
+		}
+	}
+
+	missingTables := localemsg.MissingTables()
+	names := []string{}
+	for name := range missingTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, locale := range missingTables[name] {
+			fmt.Printf("%s\ttable %s\n", locale, name)
+		}
+	}
+
+	if len(missing) > 0 || len(missingTables) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("No missing keys in locales %v\n", localemsg.Locales())
+}
@@ -0,0 +1,274 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/pkg/logger"
+	"emotibot.com/emotigo/pkg/services/fileservice"
+)
+
+// ArchiveNamespace is the minio bucket of archived segments, so every instance can read them
+const ArchiveNamespace = "audit-archive"
+
+// archiveClaimTimeout is how long an instance owns the archiving of an enterprise
+const archiveClaimTimeout = int64(time.Hour / time.Second)
+
+// file operations of segments, replaced in test
+var (
+	putArchiveFile    = fileservice.AddFile
+	getArchiveFile    = fileservice.GetFile
+	deleteArchiveFile = fileservice.DeleteFile
+)
+
+// ArchiveSegment is a file of archived audit records of an enterprise, FilePath is its path in ArchiveNamespace.
+// FirstPrevHash and LastHash keep the hash chain continuous after records are removed from DB.
+type ArchiveSegment struct {
+	ID            int64  `json:"id"`
+	EnterpriseID  string `json:"enterprise"`
+	FirstID       int64  `json:"first_id"`
+	LastID        int64  `json:"last_id"`
+	Count         int    `json:"count"`
+	FirstPrevHash string `json:"first_prev_hash"`
+	LastHash      string `json:"last_hash"`
+	FilePath      string `json:"file_path"`
+	FileHash      string `json:"file_hash"`
+	CreatedTime   int64  `json:"created_time"`
+}
+
+// Retention is the days to keep audit records of an enterprise in DB, 0 means forever
+type Retention struct {
+	EnterpriseID string `json:"enterprise"`
+	Days         int    `json:"days"`
+	UpdateTime   int64  `json:"update_time"`
+}
+
+// GetArchiveSegments returns archived segments of enterprise in order
+func GetArchiveSegments(enterpriseID string) ([]*ArchiveSegment, error) {
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return nil, errors.New("Audit DB connection hasn't init")
+	}
+	rows, err := auditDB.Query(`SELECT id, enterprise, first_id, last_id, record_count, first_prev_hash, last_hash, file_path, file_hash, created_time
+		FROM audit_archive_segment WHERE enterprise = ? ORDER BY last_id`, enterpriseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []*ArchiveSegment{}
+	for rows.Next() {
+		s := &ArchiveSegment{}
+		err = rows.Scan(&s.ID, &s.EnterpriseID, &s.FirstID, &s.LastID, &s.Count, &s.FirstPrevHash, &s.LastHash,
+			&s.FilePath, &s.FileHash, &s.CreatedTime)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, rows.Err()
+}
+
+// GetRetentions returns the retention settings of all enterprises
+func GetRetentions() ([]*Retention, error) {
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return nil, errors.New("Audit DB connection hasn't init")
+	}
+	rows, err := auditDB.Query("SELECT enterprise, days, update_time FROM audit_retention")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []*Retention{}
+	for rows.Next() {
+		r := &Retention{}
+		if err = rows.Scan(&r.EnterpriseID, &r.Days, &r.UpdateTime); err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, rows.Err()
+}
+
+// SetRetention sets days to keep audit records of enterprise in DB
+func SetRetention(enterpriseID string, days int) error {
+	if days < 0 {
+		return errors.New("days should not be negative")
+	}
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return errors.New("Audit DB connection hasn't init")
+	}
+	_, err := auditDB.Exec(`INSERT INTO audit_retention (enterprise, days, update_time) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE days = VALUES(days), update_time = VALUES(update_time)`,
+		enterpriseID, days, time.Now().Unix())
+	return err
+}
+
+// claimArchive marks enterprise as being archived by this instance, false is returned if
+// another instance has claimed it within archiveClaimTimeout
+func claimArchive(enterpriseID string, now int64) (bool, error) {
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return false, errors.New("Audit DB connection hasn't init")
+	}
+	result, err := auditDB.Exec(`UPDATE audit_retention SET archive_claim_time = ?
+		WHERE enterprise = ? AND archive_claim_time < ?`, now, enterpriseID, now-archiveClaimTimeout)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ArchiveExpiredRecords archives records older than retention of each enterprise into ArchiveNamespace.
+// Every instance runs it, an enterprise is only archived by the instance which claims it.
+func ArchiveExpiredRecords() {
+	retentions, err := GetRetentions()
+	if err != nil {
+		logger.Error.Printf("Get audit retentions fail: %s\n", err.Error())
+		return
+	}
+	now := time.Now()
+	for _, retention := range retentions {
+		if retention.Days <= 0 {
+			continue
+		}
+		claimed, err := claimArchive(retention.EnterpriseID, now.Unix())
+		if err != nil {
+			logger.Error.Printf("Claim archiving audit of %s fail: %s\n", retention.EnterpriseID, err.Error())
+			continue
+		}
+		if !claimed {
+			logger.Trace.Printf("Audit of %s is archived by another instance\n", retention.EnterpriseID)
+			continue
+		}
+		before := now.AddDate(0, 0, -retention.Days).Unix()
+		segment, err := ArchiveRecords(retention.EnterpriseID, before)
+		if err != nil {
+			logger.Error.Printf("Archive audit of %s fail: %s\n", retention.EnterpriseID, err.Error())
+			continue
+		}
+		if segment != nil {
+			logger.Info.Printf("Archive %d audit records of %s to %s\n", segment.Count, segment.EnterpriseID, segment.FilePath)
+		}
+	}
+}
+
+// ArchiveRecords moves records of enterprise created before the timestamp into a gzipped
+// JSON Lines file in ArchiveNamespace, and removes them from DB. Records are archived as a prefix of
+// the chain, so the remaining records still follow the last archived hash.
+// nil is returned if there is nothing to archive.
+func ArchiveRecords(enterpriseID string, before int64) (*ArchiveSegment, error) {
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return nil, errors.New("Audit DB connection hasn't init")
+	}
+
+	var lastID *int64
+	err := auditDB.QueryRow("SELECT MAX(id) FROM audit_record WHERE enterprise = ? AND create_time < FROM_UNIXTIME(?)",
+		enterpriseID, before).Scan(&lastID)
+	if err != nil {
+		return nil, err
+	}
+	if lastID == nil {
+		return nil, nil
+	}
+
+	rows, err := auditDB.Query("SELECT "+chainColumns+" FROM audit_record WHERE enterprise = ? AND id <= ? ORDER BY id",
+		enterpriseID, *lastID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segment := &ArchiveSegment{
+		EnterpriseID: enterpriseID,
+		LastID:       *lastID,
+		CreatedTime:  time.Now().Unix(),
+	}
+	segment.FilePath = fmt.Sprintf("%s/audit_%d_%d.jsonl.gz", path.Base(enterpriseID), segment.CreatedTime, *lastID)
+	buf := &bytes.Buffer{}
+	fileHash := sha256.New()
+	zw := gzip.NewWriter(io.MultiWriter(buf, fileHash))
+
+	for rows.Next() {
+		rec, err := scanChainRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		if segment.Count == 0 {
+			segment.FirstID = rec.ID
+			segment.FirstPrevHash = rec.PrevHash
+		}
+		if rec.Hash != "" {
+			segment.LastHash = rec.Hash
+		}
+		segment.Count++
+		if err = writeRecord(zw, rec, ExportFormatJSONL); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	if segment.LastHash == "" {
+		// all archived records are unchained, chain of DB still starts from the last segment
+		segment.LastHash, err = lastArchivedHash(auditDB, enterpriseID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	segment.FileHash = hex.EncodeToString(fileHash.Sum(nil))
+	if err = putArchiveFile(ArchiveNamespace, segment.FilePath, buf); err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := deleteArchiveFile(ArchiveNamespace, segment.FilePath); err != nil {
+				logger.Warn.Printf("Remove uncommitted archive %s fail: %s\n", segment.FilePath, err.Error())
+			}
+		}
+	}()
+
+	tx, err := auditDB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer util.ClearTransition(tx)
+	result, err := tx.Exec(`INSERT INTO audit_archive_segment
+		(enterprise, first_id, last_id, record_count, first_prev_hash, last_hash, file_path, file_hash, created_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		segment.EnterpriseID, segment.FirstID, segment.LastID, segment.Count, segment.FirstPrevHash, segment.LastHash,
+		segment.FilePath, segment.FileHash, segment.CreatedTime)
+	if err != nil {
+		return nil, err
+	}
+	segment.ID, _ = result.LastInsertId()
+	_, err = tx.Exec("DELETE FROM audit_record WHERE enterprise = ? AND id <= ?", enterpriseID, segment.LastID)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	return segment, nil
+}
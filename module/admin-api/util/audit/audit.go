@@ -3,6 +3,7 @@ package audit
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"emotibot.com/emotigo/module/admin-api/util/localemsg"
 
@@ -113,6 +114,12 @@ var moduleLocalKeyMap = map[string]string{
 	AuditModuleMaterialLibrary:   "AuditModuleMaterialLibrary",
 }
 
+// Retries of inserting an audit log into hash chain, the wait grows with each retry
+const (
+	auditInsertRetries       = 3
+	auditInsertRetryInterval = 500 * time.Millisecond
+)
+
 type auditLog struct {
 	EnterpriseID string
 	AppID        string
@@ -188,34 +195,28 @@ func logRoutine() {
 	}
 }
 
+// addAuditLog inserts log into hash chain of its enterprise. Inserting without hash would
+// break the chain, so failed inserts are retried and then reported as error with the log.
 func addAuditLog(log auditLog) {
 	auditDB := util.GetAuditDB()
 	if auditDB == nil {
-		logger.Error.Printf("Audit DB connection hasn't init")
+		logger.Error.Printf("Audit DB connection hasn't init, audit lost: %+v\n", log)
 		return
 	}
 
 	logger.Trace.Printf("Get audit: %+v", log)
 
-	_, errWithEnterprise := auditDB.Exec("insert audit_record(enterprise, appid, user_id, ip_source, module, operation, content, result) values (?, ?, ?, ?, ?, ?, ?, ?)",
-		log.EnterpriseID, log.AppID, log.UserID, log.UserIP, log.Module, log.Operation, log.Content, log.Result)
-	if errWithEnterprise == nil {
-		return
-	}
-
-	logger.Warn.Println("Schema of audit_record should be upgraded or err happen,", errWithEnterprise.Error())
-	_, errWithAppID := auditDB.Exec("insert audit_record(appid, user_id, ip_source, module, operation, content, result) values (?, ?, ?, ?, ?, ?, ?)",
-		log.AppID, log.UserID, log.UserIP, log.Module, log.Operation, log.Content, log.Result)
-	if errWithAppID == nil {
-		return
-	}
-	logger.Warn.Println("Schema of audit_record should be upgraded,", errWithAppID.Error())
-
-	_, errWithoutAppID := auditDB.Exec("insert audit_record(enterprise, user_id, ip_source, module, operation, content, result) values (?, ?, ?, ?, ?, ?)",
-		log.UserID, log.UserIP, log.Module, log.Operation, log.Content, log.Result)
-	if errWithAppID != nil && errWithoutAppID != nil {
-		logger.Error.Printf("insert audit fail: %s", errWithoutAppID.Error())
+	var err error
+	for retry := 0; retry < auditInsertRetries; retry++ {
+		if retry > 0 {
+			time.Sleep(time.Duration(retry) * auditInsertRetryInterval)
+		}
+		if err = addChainedAuditLog(auditDB, log); err == nil {
+			return
+		}
+		logger.Warn.Printf("Insert audit fail, retry %d: %s\n", retry, err.Error())
 	}
+	logger.Error.Printf("Insert audit fail after %d retries, audit lost: %+v, %s\n", auditInsertRetries, log, err.Error())
 }
 
 // GetAuditModuleName will get module name by locale
@@ -0,0 +1,248 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+// Types of issue found when verifying the hash chain
+const (
+	ChainIssueModified  = "modified"
+	ChainIssueGap       = "gap"
+	ChainIssueUnchained = "unchained"
+	ChainIssueArchive   = "archive"
+)
+
+// maxChainIssues is the max number of issues returned by verification
+const maxChainIssues = 100
+
+// ChainRecord is an audit record with the hash chain of its enterprise.
+// Hash is computed from PrevHash and all other fields except ID, so editing,
+// inserting or deleting a record breaks the chain.
+type ChainRecord struct {
+	ID           int64  `json:"id"`
+	EnterpriseID string `json:"enterprise"`
+	AppID        string `json:"appid"`
+	UserID       string `json:"user"`
+	UserIP       string `json:"user_ip"`
+	Module       string `json:"module"`
+	Operation    string `json:"operation"`
+	Content      string `json:"content"`
+	Result       int    `json:"result"`
+	CreateTime   int64  `json:"create_time"`
+	PrevHash     string `json:"prev_hash"`
+	Hash         string `json:"hash"`
+}
+
+// ChainIssue is a problem found in the hash chain
+type ChainIssue struct {
+	RecordID int64  `json:"record_id"`
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+}
+
+// VerifyResult is the result of verifying the hash chain of an enterprise
+type VerifyResult struct {
+	EnterpriseID string        `json:"enterprise"`
+	Valid        bool          `json:"valid"`
+	Checked      int           `json:"checked"`
+	Unchained    int           `json:"unchained"`
+	HeadHash     string        `json:"head_hash"`
+	Issues       []*ChainIssue `json:"issues"`
+}
+
+// ComputeHash returns the hash of record, which is sha256 of the json array of fields
+func (rec *ChainRecord) ComputeHash() string {
+	fields := []interface{}{
+		rec.PrevHash, rec.EnterpriseID, rec.AppID, rec.UserID, rec.UserIP,
+		rec.Module, rec.Operation, rec.Content, rec.Result, rec.CreateTime,
+	}
+	buf, _ := json.Marshal(fields)
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// chainVerifier checks records of an enterprise in the order of id
+type chainVerifier struct {
+	result   *VerifyResult
+	prevHash string
+	started  bool
+}
+
+// newChainVerifier creates a verifier, anchor is the last hash of archived records
+func newChainVerifier(enterpriseID, anchor string) *chainVerifier {
+	return &chainVerifier{
+		result: &VerifyResult{
+			EnterpriseID: enterpriseID,
+			Valid:        true,
+			HeadHash:     anchor,
+			Issues:       []*ChainIssue{},
+		},
+		prevHash: anchor,
+		started:  anchor != "",
+	}
+}
+
+func (v *chainVerifier) addIssue(id int64, issueType, msg string) {
+	v.result.Valid = false
+	if len(v.result.Issues) < maxChainIssues {
+		v.result.Issues = append(v.result.Issues, &ChainIssue{RecordID: id, Type: issueType, Message: msg})
+	}
+}
+
+// check verifies a record. Records written before hash chain is enabled have no hash,
+// they are only allowed before the first chained record.
+func (v *chainVerifier) check(rec *ChainRecord) {
+	if rec.Hash == "" {
+		if v.started {
+			v.addIssue(rec.ID, ChainIssueUnchained, "record without hash after chain started")
+		} else {
+			v.result.Unchained++
+		}
+		return
+	}
+	v.result.Checked++
+	if rec.ComputeHash() != rec.Hash {
+		v.addIssue(rec.ID, ChainIssueModified, "hash of record does not match its content")
+	}
+	if v.started && rec.PrevHash != v.prevHash {
+		v.addIssue(rec.ID, ChainIssueGap, fmt.Sprintf("previous hash should be %s, but got %s", v.prevHash, rec.PrevHash))
+	}
+	v.started = true
+	v.prevHash = rec.Hash
+	v.result.HeadHash = rec.Hash
+}
+
+// addChainedAuditLog inserts log with the hash of last record of the same enterprise.
+// The last record is locked in transaction, so that concurrent writers get the correct previous hash.
+func addChainedAuditLog(auditDB *sql.DB, log auditLog) error {
+	tx, err := auditDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer util.ClearTransition(tx)
+
+	prevHash := ""
+	err = tx.QueryRow("SELECT hash FROM audit_record WHERE enterprise = ? AND hash != '' ORDER BY id DESC LIMIT 1 FOR UPDATE",
+		log.EnterpriseID).Scan(&prevHash)
+	if err == sql.ErrNoRows {
+		prevHash, err = lastArchivedHash(tx, log.EnterpriseID)
+	}
+	if err != nil {
+		return err
+	}
+
+	rec := ChainRecord{
+		EnterpriseID: log.EnterpriseID,
+		AppID:        log.AppID,
+		UserID:       log.UserID,
+		UserIP:       log.UserIP,
+		Module:       log.Module,
+		Operation:    log.Operation,
+		Content:      log.Content,
+		Result:       log.Result,
+		CreateTime:   time.Now().Unix(),
+		PrevHash:     prevHash,
+	}
+	rec.Hash = rec.ComputeHash()
+	_, err = tx.Exec(`INSERT audit_record(enterprise, appid, user_id, ip_source, module, operation, content, result, create_time, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, FROM_UNIXTIME(?), ?, ?)`,
+		rec.EnterpriseID, rec.AppID, rec.UserID, rec.UserIP, rec.Module, rec.Operation, rec.Content, rec.Result,
+		rec.CreateTime, rec.PrevHash, rec.Hash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// lastArchivedHash returns the last hash of archived records of enterprise, which is the
+// previous hash of the first record in audit_record
+func lastArchivedHash(db queryRower, enterpriseID string) (string, error) {
+	hash := ""
+	err := db.QueryRow("SELECT last_hash FROM audit_archive_segment WHERE enterprise = ? ORDER BY last_id DESC LIMIT 1",
+		enterpriseID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+const chainColumns = "id, enterprise, appid, user_id, ip_source, module, operation, content, result, UNIX_TIMESTAMP(create_time), prev_hash, hash"
+
+func scanChainRecord(rows *sql.Rows) (*ChainRecord, error) {
+	rec := &ChainRecord{}
+	err := rows.Scan(&rec.ID, &rec.EnterpriseID, &rec.AppID, &rec.UserID, &rec.UserIP, &rec.Module,
+		&rec.Operation, &rec.Content, &rec.Result, &rec.CreateTime, &rec.PrevHash, &rec.Hash)
+	return rec, err
+}
+
+// VerifyChain verifies the hash chain of audit records of enterprise, the continuity
+// of its archived segments and the hash of segment files. Deleting the newest records can not be detected by chain,
+// so HeadHash should be kept by caller to compare with later verification.
+func VerifyChain(enterpriseID string) (*VerifyResult, error) {
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return nil, errors.New("Audit DB connection hasn't init")
+	}
+
+	segments, err := GetArchiveSegments(enterpriseID)
+	if err != nil {
+		return nil, err
+	}
+	anchor := ""
+	if len(segments) > 0 {
+		anchor = segments[len(segments)-1].LastHash
+	}
+	v := newChainVerifier(enterpriseID, anchor)
+	for _, segment := range segments {
+		if err := verifySegmentFile(segment); err != nil {
+			v.addIssue(segment.FirstID, ChainIssueArchive,
+				fmt.Sprintf("archive segment %d: %s", segment.ID, err.Error()))
+		}
+	}
+	for i := 1; i < len(segments); i++ {
+		if segments[i].FirstPrevHash != segments[i-1].LastHash {
+			v.addIssue(segments[i].FirstID, ChainIssueGap,
+				fmt.Sprintf("archive segment %d does not follow previous segment", segments[i].ID))
+		}
+	}
+
+	rows, err := auditDB.Query("SELECT "+chainColumns+" FROM audit_record WHERE enterprise = ? ORDER BY id", enterpriseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rec, err := scanChainRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		v.check(rec)
+	}
+	return v.result, rows.Err()
+}
+
+// verifySegmentFile re-hashes the file of segment and compares with the hash stored when archiving
+func verifySegmentFile(segment *ArchiveSegment) error {
+	content, err := getArchiveFile(ArchiveNamespace, segment.FilePath)
+	if err != nil {
+		return fmt.Errorf("read file fail: %s", err.Error())
+	}
+
+	sum := sha256.Sum256(content)
+	if hash := hex.EncodeToString(sum[:]); hash != segment.FileHash {
+		return fmt.Errorf("hash of file should be %s, but got %s", segment.FileHash, hash)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestChain(n int) []*ChainRecord {
+	records := []*ChainRecord{}
+	prevHash := ""
+	for i := 0; i < n; i++ {
+		rec := &ChainRecord{
+			ID:           int64(i + 1),
+			EnterpriseID: "e1",
+			AppID:        "app",
+			UserID:       "user",
+			Module:       AuditModuleFAQ,
+			Operation:    AuditOperationEdit,
+			Content:      "content",
+			Result:       1,
+			CreateTime:   int64(1500000000 + i),
+			PrevHash:     prevHash,
+		}
+		rec.Hash = rec.ComputeHash()
+		prevHash = rec.Hash
+		records = append(records, rec)
+	}
+	return records
+}
+
+func verifyRecords(anchor string, records []*ChainRecord) *VerifyResult {
+	v := newChainVerifier("e1", anchor)
+	for _, rec := range records {
+		v.check(rec)
+	}
+	return v.result
+}
+
+func TestVerifyChain(t *testing.T) {
+	records := newTestChain(5)
+	legacy := &ChainRecord{ID: 0, EnterpriseID: "e1", Content: "before chain"}
+	result := verifyRecords("", append([]*ChainRecord{legacy}, records...))
+	if !result.Valid || result.Checked != 5 || result.Unchained != 1 || result.HeadHash != records[4].Hash {
+		t.Fatalf("expect valid chain, but got %+v", result)
+	}
+
+	modified := newTestChain(5)
+	modified[2].Content = "edited"
+	result = verifyRecords("", modified)
+	if result.Valid || len(result.Issues) != 1 || result.Issues[0].Type != ChainIssueModified || result.Issues[0].RecordID != 3 {
+		t.Errorf("expect record 3 to be modified, but got %+v", result.Issues)
+	}
+
+	deleted := newTestChain(5)
+	deleted = append(deleted[:2], deleted[3:]...)
+	result = verifyRecords("", deleted)
+	if result.Valid || len(result.Issues) != 1 || result.Issues[0].Type != ChainIssueGap || result.Issues[0].RecordID != 4 {
+		t.Errorf("expect gap before record 4, but got %+v", result.Issues)
+	}
+
+	cleared := newTestChain(5)
+	cleared[3].Hash = ""
+	result = verifyRecords("", cleared)
+	if result.Valid || result.Issues[0].Type != ChainIssueUnchained {
+		t.Errorf("expect record without hash to be reported, but got %+v", result.Issues)
+	}
+
+	// records after archived segment should follow its last hash
+	archived := newTestChain(5)
+	result = verifyRecords(archived[1].Hash, archived[2:])
+	if !result.Valid {
+		t.Errorf("expect records after archive to be valid, but got %+v", result.Issues)
+	}
+	result = verifyRecords(archived[0].Hash, archived[2:])
+	if result.Valid || result.Issues[0].Type != ChainIssueGap {
+		t.Errorf("expect gap after archive, but got %+v", result.Issues)
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	rec := &ChainRecord{
+		ID:           7,
+		EnterpriseID: "e1",
+		UserID:       "user",
+		UserIP:       "127.0.0.1",
+		Module:       "faq|a",
+		Operation:    AuditOperationAdd,
+		Content:      "a=b\nc\\d",
+		Result:       0,
+		CreateTime:   1500000000,
+		Hash:         "h",
+	}
+	cef := FormatCEF(rec)
+	expected := `CEF:0|Emotibot|admin-api|1.0|faq\|a.add|add faq\|a|6|rt=1500000000000 suser=user src=127.0.0.1 outcome=failure msg=a\=b\nc\\d cs1Label=enterprise cs1=e1 cs3Label=hash cs3=h cn1Label=recordId cn1=7`
+	if cef != expected {
+		t.Errorf("unexpected cef:\n%s\n%s", cef, expected)
+	}
+
+	syslog := FormatSyslog(rec, "host")
+	if !strings.HasPrefix(syslog, "<108>1 2017-07-14T02:40:00Z host admin-api - - - CEF:0|") {
+		t.Errorf("unexpected syslog: %s", syslog)
+	}
+}
+
+func TestVerifySegmentFile(t *testing.T) {
+	files := map[string][]byte{}
+	defer func(get func(string, string) ([]byte, error)) { getArchiveFile = get }(getArchiveFile)
+	getArchiveFile = func(namespace, path string) ([]byte, error) {
+		content, ok := files[namespace+"/"+path]
+		if !ok {
+			return nil, errors.New("object not found")
+		}
+		return content, nil
+	}
+
+	path := "enterprise/segment.jsonl.gz"
+	files[ArchiveNamespace+"/"+path] = []byte("archived")
+	sum := sha256.Sum256([]byte("archived"))
+	segment := &ArchiveSegment{ID: 1, FilePath: path, FileHash: hex.EncodeToString(sum[:])}
+	if err := verifySegmentFile(segment); err != nil {
+		t.Errorf("expect segment file to be valid, but got %v", err)
+	}
+
+	files[ArchiveNamespace+"/"+path] = []byte("modified")
+	if err := verifySegmentFile(segment); err == nil {
+		t.Error("expect modified segment file to be invalid")
+	}
+
+	segment.FilePath = "enterprise/missing.jsonl.gz"
+	if err := verifySegmentFile(segment); err == nil {
+		t.Error("expect missing segment file to be invalid")
+	}
+}
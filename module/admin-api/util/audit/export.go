@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+// Formats of exported audit records for SIEM
+const (
+	ExportFormatJSONL  = "jsonl"
+	ExportFormatCEF    = "cef"
+	ExportFormatSyslog = "syslog"
+)
+
+const (
+	cefVendor  = "Emotibot"
+	cefProduct = "admin-api"
+	cefVersion = "1.0"
+	// syslog facility 13 is log audit
+	syslogFacility = 13
+)
+
+var syslogHostname, _ = os.Hostname()
+
+// IsValidExportFormat returns if format can be used to export audit records
+func IsValidExportFormat(format string) bool {
+	return format == ExportFormatJSONL || format == ExportFormatCEF || format == ExportFormatSyslog
+}
+
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+var cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// FormatCEF returns record in ArcSight Common Event Format
+func FormatCEF(rec *ChainRecord) string {
+	severity := 3
+	outcome := "success"
+	if rec.Result != 1 {
+		severity = 6
+		outcome = "failure"
+	}
+	header := []string{
+		"CEF:0", cefVendor, cefProduct, cefVersion,
+		cefHeaderEscaper.Replace(rec.Module + "." + rec.Operation),
+		cefHeaderEscaper.Replace(rec.Operation + " " + rec.Module),
+		fmt.Sprint(severity),
+	}
+	pairs := []string{}
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, key+"="+cefExtensionEscaper.Replace(value))
+		}
+	}
+	// custom fields are only added with their label if value is not empty
+	addCustom := func(key, label, value string) {
+		if value != "" {
+			add(key+"Label", label)
+			add(key, value)
+		}
+	}
+	add("rt", fmt.Sprint(rec.CreateTime*1000))
+	add("suser", rec.UserID)
+	add("src", rec.UserIP)
+	add("outcome", outcome)
+	add("msg", rec.Content)
+	addCustom("cs1", "enterprise", rec.EnterpriseID)
+	addCustom("cs2", "appid", rec.AppID)
+	addCustom("cs3", "hash", rec.Hash)
+	addCustom("cs4", "prevHash", rec.PrevHash)
+	addCustom("cn1", "recordId", fmt.Sprint(rec.ID))
+	return strings.Join(header, "|") + "|" + strings.Join(pairs, " ")
+}
+
+// FormatSyslog returns record as RFC 5424 syslog message with CEF content
+func FormatSyslog(rec *ChainRecord, hostname string) string {
+	// severity 6 is informational and 4 is warning
+	severity := 6
+	if rec.Result != 1 {
+		severity = 4
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+	timestamp := time.Unix(rec.CreateTime, 0).UTC().Format(time.RFC3339)
+	return fmt.Sprintf("<%d>1 %s %s %s - - - %s", syslogFacility*8+severity, timestamp, hostname, cefProduct, FormatCEF(rec))
+}
+
+func writeRecord(w io.Writer, rec *ChainRecord, format string) error {
+	var line []byte
+	switch format {
+	case ExportFormatJSONL:
+		buf, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		line = buf
+	case ExportFormatCEF:
+		line = []byte(FormatCEF(rec))
+	case ExportFormatSyslog:
+		line = []byte(FormatSyslog(rec, syslogHostname))
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+	_, err := w.Write(append(line, '\n'))
+	return err
+}
+
+// ExportRecords writes records of enterprise between start and end, which are unix timestamps,
+// into w with format, one record per line. Records are in the order of chain, so that SIEM can
+// verify the hash chain with the exported hash and prev_hash.
+func ExportRecords(w io.Writer, enterpriseID string, start, end int64, format string) (int, error) {
+	if !IsValidExportFormat(format) {
+		return 0, fmt.Errorf("unknown format %s", format)
+	}
+	auditDB := util.GetAuditDB()
+	if auditDB == nil {
+		return 0, errors.New("Audit DB connection hasn't init")
+	}
+	rows, err := auditDB.Query("SELECT "+chainColumns+` FROM audit_record
+		WHERE enterprise = ? AND create_time BETWEEN FROM_UNIXTIME(?) AND FROM_UNIXTIME(?) ORDER BY id`,
+		enterpriseID, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		rec, err := scanChainRecord(rows)
+		if err != nil {
+			return count, err
+		}
+		if err = writeRecord(w, rec, format); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
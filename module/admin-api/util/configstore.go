@@ -0,0 +1,162 @@
+package util
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backends of config store, which is selected by CONFIG_STORE of server env
+const (
+	ConfigStoreConsul = "consul"
+	ConfigStoreEtcd   = "etcd"
+	ConfigStoreFile   = "file"
+	ConfigStoreMemory = "memory"
+)
+
+// configWatchInterval is the polling interval of stores without change notification
+var configWatchInterval = 5 * time.Second
+
+// ConfigStore is the kv store of configs shared with other modules, such as consul.
+// Keys are separated by "/", missing keys are returned as empty value.
+type ConfigStore interface {
+	Get(key string) (string, error)
+	Put(key string, value string) error
+	Delete(key string) error
+	// Tree returns values of keys under prefix, keys are relative to prefix
+	Tree(prefix string) (map[string]string, error)
+	// Lock returns a lock of key, which is acquired by Locker.Lock
+	Lock(key string) (Locker, error)
+	// Watch sends the new value of key when it is changed until stopCh is closed
+	Watch(key string, stopCh <-chan struct{}) (<-chan string, error)
+}
+
+// DefaultConfigStore is the store used by convenient functions in package, keys are under prefix of deployment.
+var DefaultConfigStore ConfigStore = DefaultConsulClient
+
+// RootConfigStore is the store used for access values from root
+var RootConfigStore ConfigStore = RootConsulClient
+
+// NewConfigStore create root store of backend. For consul and etcd, address is the URL of server,
+// for file, address is the path of file, and it is ignored for memory.
+func NewConfigStore(backend string, address string) (ConfigStore, error) {
+	switch backend {
+	case "", ConfigStoreConsul:
+		return NewConsulConfigStore(address)
+	case ConfigStoreEtcd:
+		return NewEtcdConfigStore(address, nil)
+	case ConfigStoreFile:
+		return NewFileConfigStore(address)
+	case ConfigStoreMemory:
+		return NewMemoryConfigStore(), nil
+	}
+	return nil, ErrUnsupportedConfigStore
+}
+
+// prefixConfigStore put keys under prefix of the wrapped store
+type prefixConfigStore struct {
+	store  ConfigStore
+	prefix string
+}
+
+// NewPrefixConfigStore returns store with keys under prefix of store
+func NewPrefixConfigStore(store ConfigStore, prefix string) ConfigStore {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return store
+	}
+	return &prefixConfigStore{store: store, prefix: prefix}
+}
+
+func (s *prefixConfigStore) key(key string) string {
+	return s.prefix + "/" + strings.TrimPrefix(key, "/")
+}
+
+func (s *prefixConfigStore) Get(key string) (string, error) {
+	return s.store.Get(s.key(key))
+}
+
+func (s *prefixConfigStore) Put(key string, value string) error {
+	return s.store.Put(s.key(key), value)
+}
+
+func (s *prefixConfigStore) Delete(key string) error {
+	return s.store.Delete(s.key(key))
+}
+
+func (s *prefixConfigStore) Tree(prefix string) (map[string]string, error) {
+	return s.store.Tree(s.key(prefix))
+}
+
+func (s *prefixConfigStore) Lock(key string) (Locker, error) {
+	return s.store.Lock(s.key(key))
+}
+
+func (s *prefixConfigStore) Watch(key string, stopCh <-chan struct{}) (<-chan string, error) {
+	return s.store.Watch(s.key(key), stopCh)
+}
+
+// pollWatch watches key by getting value periodically, which is used by stores without notification
+func pollWatch(get func(key string) (string, error), key string, stopCh <-chan struct{}) (<-chan string, error) {
+	last, err := get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+			value, err := get(key)
+			if err != nil || value == last {
+				continue
+			}
+			last = value
+			select {
+			case ch <- value:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// localLocker is lock of a key in process, which is used by file and memory stores
+type localLocker struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	lost chan struct{}
+}
+
+func (l *localLocker) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-stopCh:
+		// Same as consul, nil is returned if stopped before acquiring lock
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lost = make(chan struct{})
+	return l.lost, nil
+}
+
+func (l *localLocker) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost == nil {
+		return ErrLockNotHeld
+	}
+	close(l.lost)
+	l.lost = nil
+	<-l.sem
+	return nil
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewConsulConfigStore create a consul client of root kv with address of consul agent, ex: http://127.0.0.1:8500
+func NewConsulConfigStore(address string) (*ConsulClient, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/v1/kv/", strings.TrimRight(address, "/")))
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout: time.Duration(5) * time.Second,
+	}
+	return NewConsulClientWithCustomHTTP(u, client), nil
+}
+
+// Get implements ConfigStore with the get handler
+func (c *ConsulClient) Get(key string) (string, error) {
+	value, _, err := c.getHandler(key)
+	return value, err
+}
+
+// Put implements ConfigStore with the update handler, value is sent as it is
+func (c *ConsulClient) Put(key string, value string) error {
+	_, err := c.updateHandler(key, value)
+	return err
+}
+
+// Delete implements ConfigStore with the delete handler
+func (c *ConsulClient) Delete(key string) error {
+	_, _, err := c.deleteHandler(key)
+	return err
+}
+
+// Tree implements ConfigStore with the get tree handler
+func (c *ConsulClient) Tree(prefix string) (map[string]string, error) {
+	tree, _, err := c.getTreeHandler(prefix)
+	if tree == nil && err == nil {
+		tree = map[string]string{}
+	}
+	return tree, err
+}
+
+// Watch implements ConfigStore by polling value of key
+func (c *ConsulClient) Watch(key string, stopCh <-chan struct{}) (<-chan string, error) {
+	return pollWatch(c.Get, key, stopCh)
+}
@@ -0,0 +1,277 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// etcdLockTTL is TTL in seconds of lease of lock, lease is kept alive while lock is held
+	etcdLockTTL = 15
+	// etcdKeepAliveInterval should be less than etcdLockTTL
+	etcdKeepAliveInterval = 5 * time.Second
+)
+
+// EtcdConfigStore accesses etcd v3 by its JSON gateway, so no grpc client is needed.
+// Any server compatible with the gateway API can be used.
+type EtcdConfigStore struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewEtcdConfigStore create store with address of etcd, ex: http://127.0.0.1:2379.
+// client is http client with 5 seconds timeout if nil, lock requests use it without timeout.
+func NewEtcdConfigStore(address string, client *http.Client) (*EtcdConfigStore, error) {
+	if address == "" {
+		return nil, fmt.Errorf("etcd address is empty")
+	}
+	if client == nil {
+		client = &http.Client{
+			Timeout: time.Duration(5) * time.Second,
+		}
+	}
+	return &EtcdConfigStore{
+		endpoint: strings.TrimRight(address, "/"),
+		client:   client,
+	}, nil
+}
+
+type etcdKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+type etcdLeaseResponse struct {
+	ID  string `json:"ID"`
+	TTL string `json:"TTL"`
+}
+
+type etcdLockResponse struct {
+	Key string `json:"key"`
+}
+
+// etcdKeepAliveResponse is the streaming response of keepalive, which has only one message here
+type etcdKeepAliveResponse struct {
+	Result etcdLeaseResponse `json:"result"`
+}
+
+func etcdEncode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func etcdDecode(value string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	return string(decoded), err
+}
+
+// etcdRangeEnd returns the end of range of keys with prefix
+func etcdRangeEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// All keys when prefix is all 0xff
+	return "\x00"
+}
+
+func (s *EtcdConfigStore) call(path string, input interface{}, output interface{}) error {
+	return s.callWithContext(context.Background(), s.client, path, input, output)
+}
+
+func (s *EtcdConfigStore) callWithContext(ctx context.Context, client *http.Client, path string, input interface{}, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, s.endpoint+"/v3/"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request = request.WithContext(ctx)
+
+	logTraceConsul("etcd", path)
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd %s fail with status %d: %s", path, response.StatusCode, string(content))
+	}
+	if output == nil {
+		return nil
+	}
+	return json.Unmarshal(content, output)
+}
+
+func (s *EtcdConfigStore) Get(key string) (string, error) {
+	ret := etcdRangeResponse{}
+	err := s.call("kv/range", map[string]string{"key": etcdEncode(strings.Trim(key, "/"))}, &ret)
+	if err != nil || len(ret.Kvs) == 0 {
+		return "", err
+	}
+	return etcdDecode(ret.Kvs[0].Value)
+}
+
+func (s *EtcdConfigStore) Put(key string, value string) error {
+	input := map[string]string{
+		"key":   etcdEncode(strings.Trim(key, "/")),
+		"value": etcdEncode(value),
+	}
+	return s.call("kv/put", input, nil)
+}
+
+func (s *EtcdConfigStore) Delete(key string) error {
+	return s.call("kv/deleterange", map[string]string{"key": etcdEncode(strings.Trim(key, "/"))}, nil)
+}
+
+func (s *EtcdConfigStore) Tree(prefix string) (map[string]string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	input := map[string]string{
+		"key":       etcdEncode(prefix),
+		"range_end": etcdEncode(etcdRangeEnd(prefix)),
+	}
+	if prefix == "" {
+		input["key"] = etcdEncode("\x00")
+	}
+	ret := etcdRangeResponse{}
+	if err := s.call("kv/range", input, &ret); err != nil {
+		return nil, err
+	}
+
+	tree := map[string]string{}
+	for _, kv := range ret.Kvs {
+		key, err := etcdDecode(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := etcdDecode(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		tree[strings.TrimPrefix(key, prefix)] = value
+	}
+	return tree, nil
+}
+
+func (s *EtcdConfigStore) Lock(key string) (Locker, error) {
+	return &etcdLocker{store: s, name: strings.Trim(key, "/")}, nil
+}
+
+// Watch implements ConfigStore by polling, because watch of gateway is a long streaming response
+func (s *EtcdConfigStore) Watch(key string, stopCh <-chan struct{}) (<-chan string, error) {
+	return pollWatch(s.Get, key, stopCh)
+}
+
+// etcdLocker is lock of etcd concurrency API, which is attached to a lease kept alive while held
+type etcdLocker struct {
+	store *EtcdConfigStore
+	name  string
+
+	mu      sync.Mutex
+	leaseID string
+	key     string
+	stop    chan struct{}
+}
+
+func (l *etcdLocker) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	lease := etcdLeaseResponse{}
+	err := l.store.call("lease/grant", map[string]interface{}{"TTL": etcdLockTTL}, &lease)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock blocks until acquired, cancel it when stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	ret := etcdLockResponse{}
+	input := map[string]string{"name": etcdEncode(l.name), "lease": lease.ID}
+	err = l.store.callWithContext(ctx, &http.Client{Transport: l.store.client.Transport}, "lock/lock", input, &ret)
+	if err != nil {
+		l.store.call("lease/revoke", map[string]string{"ID": lease.ID}, nil)
+		if ctx.Err() != nil {
+			// Same as consul, nil is returned if stopped before acquiring lock
+			return nil, nil
+		}
+		return nil, err
+	}
+	cancel()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leaseID = lease.ID
+	l.key = ret.Key
+	l.stop = make(chan struct{})
+	lost := make(chan struct{})
+	go l.keepAlive(lease.ID, l.stop, lost)
+	return lost, nil
+}
+
+// keepAlive refreshes lease until stopped, lost is closed when lease cannot be refreshed
+func (l *etcdLocker) keepAlive(leaseID string, stop chan struct{}, lost chan struct{}) {
+	defer close(lost)
+	ticker := time.NewTicker(etcdKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ret := etcdKeepAliveResponse{}
+		err := l.store.call("lease/keepalive", map[string]string{"ID": leaseID}, &ret)
+		if err != nil || ret.Result.TTL == "" || ret.Result.TTL == "0" {
+			return
+		}
+	}
+}
+
+func (l *etcdLocker) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop == nil {
+		return ErrLockNotHeld
+	}
+	close(l.stop)
+	l.stop = nil
+
+	err := l.store.call("lock/unlock", map[string]string{"key": l.key}, nil)
+	// Revoking lease releases the lock too, so it is done even if unlock fails
+	revokeErr := l.store.call("lease/revoke", map[string]string{"ID": l.leaseID}, nil)
+	if err == nil {
+		err = revokeErr
+	}
+	return err
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MemoryConfigStore keeps configs in memory, which is used by tests and small deployments
+type MemoryConfigStore struct {
+	mu       sync.Mutex
+	data     map[string]string
+	locks    map[string]*localLocker
+	watchers map[string][]chan string
+	// persist is called after each write if set, the write is reverted if it fails
+	persist func(data map[string]string) error
+}
+
+// NewMemoryConfigStore create an empty store in memory
+func NewMemoryConfigStore() *MemoryConfigStore {
+	return &MemoryConfigStore{
+		data:     map[string]string{},
+		locks:    map[string]*localLocker{},
+		watchers: map[string][]chan string{},
+	}
+}
+
+func (s *MemoryConfigStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[strings.Trim(key, "/")], nil
+}
+
+func (s *MemoryConfigStore) Put(key string, value string) error {
+	return s.write(strings.Trim(key, "/"), value, true)
+}
+
+func (s *MemoryConfigStore) Delete(key string) error {
+	return s.write(strings.Trim(key, "/"), "", false)
+}
+
+func (s *MemoryConfigStore) write(key string, value string, exists bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, existed := s.data[key]
+	if exists {
+		s.data[key] = value
+	} else {
+		delete(s.data, key)
+	}
+	if s.persist != nil {
+		if err := s.persist(s.data); err != nil {
+			if existed {
+				s.data[key] = old
+			} else {
+				delete(s.data, key)
+			}
+			return err
+		}
+	}
+
+	if old == value {
+		return nil
+	}
+	for _, ch := range s.watchers[key] {
+		// Watchers only need the latest value, replace the one not received yet
+		select {
+		case ch <- value:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- value
+		}
+	}
+	return nil
+}
+
+func (s *MemoryConfigStore) Tree(prefix string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix = strings.Trim(prefix, "/")
+	ret := map[string]string{}
+	for key, value := range s.data {
+		if prefix == "" {
+			ret[key] = value
+		} else if strings.HasPrefix(key, prefix+"/") {
+			ret[strings.TrimPrefix(key, prefix+"/")] = value
+		}
+	}
+	return ret, nil
+}
+
+// Lock returns lock in process, so it only works with a single admin-api instance
+func (s *MemoryConfigStore) Lock(key string) (Locker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key = strings.Trim(key, "/")
+	lock, ok := s.locks[key]
+	if !ok {
+		lock = &localLocker{sem: make(chan struct{}, 1)}
+		s.locks[key] = lock
+	}
+	return lock, nil
+}
+
+func (s *MemoryConfigStore) Watch(key string, stopCh <-chan struct{}) (<-chan string, error) {
+	key = strings.Trim(key, "/")
+	ch := make(chan string, 1)
+	s.mu.Lock()
+	s.watchers[key] = append(s.watchers[key], ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-stopCh
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		watchers := s.watchers[key]
+		for idx := range watchers {
+			if watchers[idx] == ch {
+				s.watchers[key] = append(watchers[:idx], watchers[idx+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// FileConfigStore keeps configs in memory and saves them to a JSON file after each write
+type FileConfigStore struct {
+	*MemoryConfigStore
+	path string
+}
+
+// NewFileConfigStore create store with configs in file of path, the file is created on first write
+func NewFileConfigStore(path string) (*FileConfigStore, error) {
+	s := &FileConfigStore{
+		MemoryConfigStore: NewMemoryConfigStore(),
+		path:              path,
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(content) > 0 {
+		if err = json.Unmarshal(content, &s.data); err != nil {
+			return nil, err
+		}
+	}
+	s.persist = s.save
+	return s, nil
+}
+
+// save writes to a temp file then renames it, so the file is never partially written
+func (s *FileConfigStore) save(data map[string]string) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,218 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeConsul serves consul kv API in memory
+func newFakeConsul() *httptest.Server {
+	mu := sync.Mutex{}
+	kv := map[string]string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			kv[key] = string(body)
+		case http.MethodDelete:
+			delete(kv, key)
+		case http.MethodGet:
+			objs := []map[string]string{}
+			for k, v := range kv {
+				if k == key || (r.URL.Query().Get("recurse") != "" && strings.HasPrefix(k, key)) {
+					objs = append(objs, map[string]string{"Key": k, "Value": base64.StdEncoding.EncodeToString([]byte(v))})
+				}
+			}
+			if len(objs) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(objs)
+		}
+	}))
+}
+
+// newFakeEtcd serves kv, lease and lock API of etcd JSON gateway in memory
+func newFakeEtcd() *httptest.Server {
+	mu := sync.Mutex{}
+	kv := map[string]string{}
+	lock := make(chan struct{}, 1)
+	decode := func(value string) string {
+		decoded, _ := base64.StdEncoding.DecodeString(value)
+		return string(decoded)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&input)
+		str := func(name string) string {
+			value, _ := input[name].(string)
+			return value
+		}
+
+		switch strings.TrimPrefix(r.URL.Path, "/v3/") {
+		case "lease/grant":
+			json.NewEncoder(w).Encode(map[string]string{"ID": "1", "TTL": "15"})
+			return
+		case "lease/keepalive":
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]string{"ID": "1", "TTL": "15"}})
+			return
+		case "lease/revoke":
+			return
+		case "lock/lock":
+			select {
+			case lock <- struct{}{}:
+				json.NewEncoder(w).Encode(map[string]string{"key": str("name")})
+			case <-r.Context().Done():
+			}
+			return
+		case "lock/unlock":
+			<-lock
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		key := decode(str("key"))
+		switch strings.TrimPrefix(r.URL.Path, "/v3/") {
+		case "kv/put":
+			kv[key] = decode(str("value"))
+		case "kv/deleterange":
+			delete(kv, key)
+		case "kv/range":
+			end := decode(str("range_end"))
+			kvs := []map[string]string{}
+			for k, v := range kv {
+				if k == key || (end != "" && k >= key && (end == "\x00" || k < end)) {
+					kvs = append(kvs, map[string]string{"key": etcdEncode(k), "value": etcdEncode(v)})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs})
+		}
+	}))
+}
+
+func TestConfigStoreConformance(t *testing.T) {
+	defer func(interval time.Duration) { configWatchInterval = interval }(configWatchInterval)
+	configWatchInterval = 10 * time.Millisecond
+
+	consul := newFakeConsul()
+	defer consul.Close()
+	etcd := newFakeEtcd()
+	defer etcd.Close()
+	dir, err := ioutil.TempDir("", "configstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := map[string]func() (ConfigStore, error){
+		ConfigStoreConsul: func() (ConfigStore, error) { return NewConfigStore(ConfigStoreConsul, consul.URL) },
+		ConfigStoreEtcd:   func() (ConfigStore, error) { return NewConfigStore(ConfigStoreEtcd, etcd.URL) },
+		ConfigStoreFile: func() (ConfigStore, error) {
+			return NewConfigStore(ConfigStoreFile, filepath.Join(dir, "config.json"))
+		},
+		ConfigStoreMemory: func() (ConfigStore, error) { return NewConfigStore(ConfigStoreMemory, "") },
+		"prefix": func() (ConfigStore, error) {
+			return NewPrefixConfigStore(NewMemoryConfigStore(), "/idc/"), nil
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := newStore()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if value, err := store.Get("app/missing"); err != nil || value != "" {
+				t.Errorf("Expect empty value of missing key, get %q, %v", value, err)
+			}
+			for key, value := range map[string]string{"app/a": "1", "app/b": `{"b":true}`, "app/c/d": "3", "other": "x"} {
+				if err := store.Put(key, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if value, err := store.Get("/app/b"); err != nil || value != `{"b":true}` {
+				t.Errorf("Expect value of put, get %q, %v", value, err)
+			}
+			tree, err := store.Tree("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expect := map[string]string{"a": "1", "b": `{"b":true}`, "c/d": "3"}; !reflect.DeepEqual(tree, expect) {
+				t.Errorf("Expect tree %v, get %v", expect, tree)
+			}
+			if err := store.Delete("app/a"); err != nil {
+				t.Fatal(err)
+			}
+			if value, _ := store.Get("app/a"); value != "" {
+				t.Errorf("Expect key deleted, get %q", value)
+			}
+
+			stopCh := make(chan struct{})
+			ch, err := store.Watch("app/b", stopCh)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Put("app/b", "changed"); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case value := <-ch:
+				if value != "changed" {
+					t.Errorf("Expect watched value changed, get %q", value)
+				}
+			case <-time.After(time.Second):
+				t.Error("Expect change of key watched")
+			}
+			close(stopCh)
+
+			// Lock of consul needs a real agent, which is tested in TestLock
+			if name == ConfigStoreConsul {
+				return
+			}
+			lock, err := store.Lock("app/lock")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := lock.Lock(nil); err != nil {
+				t.Fatal(err)
+			}
+			other, _ := store.Lock("app/lock")
+			stop := make(chan struct{})
+			time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+			if lost, err := other.Lock(stop); lost != nil || err != nil {
+				t.Errorf("Expect held lock not acquired until stopped, get %v, %v", lost, err)
+			}
+			if err := lock.Unlock(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := other.Lock(nil); err != nil {
+				t.Errorf("Expect lock acquired after unlock, get %v", err)
+			}
+			other.Unlock()
+		})
+	}
+
+	// Configs of file store are kept when it is opened again
+	store, err := NewFileConfigStore(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get("app/c/d"); value != "3" {
+		t.Errorf("Expect value in file kept, get %q", value)
+	}
+}
@@ -39,6 +39,8 @@ const (
 	ConsulReleaseInfoKey       = "release_versions"
 	ConsulBFOPConfigKey        = "setting/bfop_config"
 	ConsulBFConfigKey          = "bf2_config_by_appid/%s"
+	// ConsulRolloutKey is a helper value used in ConsulUpdateRollout, format with type and appid
+	ConsulRolloutKey = "rollout/%s/%s"
 )
 
 // ConsulAPI define the method should be implemented by ConsulClient.
@@ -86,7 +88,8 @@ type ConsulClient struct {
 	client         *http.Client
 }
 
-// DefaultConsulClient is a used for convenient function packed in package.
+// DefaultConsulClient is a used for convenient function packed in package,
+// it is the DefaultConfigStore if config store is consul.
 var DefaultConsulClient = NewConsulClient(&url.URL{
 	Host:   "127.0.0.1:8500",
 	Scheme: "http",
@@ -349,59 +352,59 @@ func (c ConsulClient) ConsulGetTreeVal(key string) (map[string]string, int, erro
 }
 
 //ConsulUpdateEntity is a convenient function for updating wordbank's Consul Key
-func ConsulUpdateEntity(appid string, value interface{}) (int, error) {
+func ConsulUpdateEntity(appid string, value interface{}, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulEntityKey, appid)
-	return ConsulUpdateVal(key, value)
+	return ConsulUpdateValWithChange(key, value, change)
 }
 
 //ConsulUpdateFAQ is a convenient function for updating Task Engine's Consul Key
-func ConsulUpdateFAQ(appid string) (int, error) {
+func ConsulUpdateFAQ(appid string, change *ConsulChange) (int, error) {
 	now := time.Now().Unix()
 	key := fmt.Sprintf(ConsulFAQKey, appid)
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 //ConsulUpdateTaskEngine update Task Engine enabling Consul Key
 //to enable/disable Task Engine
-func ConsulUpdateTaskEngine(appid string, val bool) (int, error) {
+func ConsulUpdateTaskEngine(appid string, val bool, change *ConsulChange) (int, error) {
 	//contains no appid, becaues this can only be use in vipshop for now
-	return ConsulUpdateVal(ConsulTEKey, val)
+	return ConsulUpdateValWithChange(ConsulTEKey, val, change)
 }
 
 //ConsulUpdateTaskEngineMappingTable update the mapping table consul key
 //to inform TaskEngine to reload new tables
-func ConsulUpdateTaskEngineMappingTable() (int, error) {
+func ConsulUpdateTaskEngineMappingTable(change *ConsulChange) (int, error) {
 	t := time.Now()
 	val := t.Format("2006-01-02 15:04:05")
-	return ConsulUpdateVal(ConsulTEMappingTableKey, val)
+	return ConsulUpdateValWithChange(ConsulTEMappingTableKey, val, change)
 }
 
 //ConsulUpdateTaskEngineMappingTableAll update the mapping table all consul key
 //to inform TaskEngine to reload all tables
-func ConsulUpdateTaskEngineMappingTableAll() (int, error) {
+func ConsulUpdateTaskEngineMappingTableAll(change *ConsulChange) (int, error) {
 	t := time.Now()
 	val := t.Format("2006-01-02 15:04:05")
-	return ConsulUpdateVal(ConsulTEMappingTableAllKey, val)
+	return ConsulUpdateValWithChange(ConsulTEMappingTableAllKey, val, change)
 }
 
 //ConsulUpdateTaskEngineScenario update the scenario consul key to inform TE to reload scenario
-func ConsulUpdateTaskEngineScenario() (int, error) {
+func ConsulUpdateTaskEngineScenario(change *ConsulChange) (int, error) {
 	t := time.Now()
 	val := t.Format("2006-01-02 15:04:05")
-	return ConsulUpdateVal(ConsulTEScenarioKey, val)
+	return ConsulUpdateValWithChange(ConsulTEScenarioKey, val, change)
 }
 
 //ConsulUpdateTaskEngineScenarioAll update the scenario all consul key to inform TE to reload all scenario
-func ConsulUpdateTaskEngineScenarioAll() (int, error) {
+func ConsulUpdateTaskEngineScenarioAll(change *ConsulChange) (int, error) {
 	t := time.Now()
 	val := t.Format("2006-01-02 15:04:05")
-	return ConsulUpdateVal(ConsulTEScenarioAllKey, val)
+	return ConsulUpdateValWithChange(ConsulTEScenarioAllKey, val, change)
 }
 
 //ConsulUpdateTaskEngineApp update the app-scenario pair to consul for TE to reload
-func ConsulUpdateTaskEngineApp(appid, val string) (int, error) {
+func ConsulUpdateTaskEngineApp(appid, val string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf("%s/%s", ConsulTEAppKey, appid)
-	return ConsulUpdateVal(key, val)
+	return ConsulUpdateValWithChange(key, val, change)
 }
 
 //ConsulGetTaskEngineConfig return the TE config json string
@@ -411,91 +414,134 @@ func ConsulGetTaskEngineConfig() (string, int, error) {
 }
 
 //ConsulUpdateFunctionStatus is a convenient function for updating Robot Chat's Consul Key
-func ConsulUpdateFunctionStatus(appid string) (int, error) {
+func ConsulUpdateFunctionStatus(appid string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulFunctionKey, appid)
 	now := time.Now().Unix()
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
+}
+
+//ConsulUpdateRollout publish the resolved rollout rules of switches or functions of an app
+func ConsulUpdateRollout(ruleType, appid string, val interface{}, change *ConsulChange) (int, error) {
+	key := fmt.Sprintf(ConsulRolloutKey, ruleType, appid)
+	return ConsulUpdateValWithChange(key, val, change)
 }
 
 //ConsulUpdateRobotChat is a convenient function for updating Robot Chat's Consul Key
-func ConsulUpdateRobotChat(appid string) (int, error) {
+func ConsulUpdateRobotChat(appid string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulRCKey, appid)
 	now := time.Now().Unix()
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 //ConsulUpdateRule is a convenient function for updating Robot Chat's Consul Key
-func ConsulUpdateRule(appid string) (int, error) {
+func ConsulUpdateRule(appid string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulRuleKey, appid)
 	now := time.Now().Unix()
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 //ConsulUpdateCmd is a convenient function for updating Robot Chat's Consul Key
-func ConsulUpdateCmd(appid string) (int, error) {
+func ConsulUpdateCmd(appid string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulCmdKey, appid)
 	now := time.Now().Unix()
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 //ConsulUpdateProfile is a convenient function for updating Robot Chat's Consul Key
-func ConsulUpdateProfile(appid string) (int, error) {
+func ConsulUpdateProfile(appid string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulProfileKey, appid)
 	now := time.Now().Unix()
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 //ConsulUpdateIntent is a convenient function for updating Robot Chat's Consul Key
-func ConsulUpdateIntent(appid string) (int, error) {
+func ConsulUpdateIntent(appid string, change *ConsulChange) (int, error) {
 	key := fmt.Sprintf(ConsulIntentKey, appid)
 	now := time.Now().Unix()
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 func ConsulGetControllerSetting() (string, int, error) {
 	key := ConsulControllerSettingKey
 	return ConsulGetVal(key)
 }
-func ConsulSetControllerSetting(val string) (int, error) {
+func ConsulSetControllerSetting(val string, change *ConsulChange) (int, error) {
 	key := ConsulControllerSettingKey
-	return ConsulUpdateVal(key, val)
+	return ConsulUpdateValWithChange(key, val, change)
 }
 
-func ConsulUpdateBFOPSetting() (int, error) {
+func ConsulUpdateBFOPSetting(change *ConsulChange) (int, error) {
 	now := time.Now().Unix()
 	key := ConsulBFOPConfigKey
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
-func ConsulUpdateBFSetting(appid string) (int, error) {
+func ConsulUpdateBFSetting(appid string, change *ConsulChange) (int, error) {
 	now := time.Now().Unix()
 	key := fmt.Sprintf(ConsulBFConfigKey, appid)
-	return ConsulUpdateVal(key, now)
+	return ConsulUpdateValWithChange(key, now, change)
 }
 
 func ConsulGetReleaseSetting() (map[string]string, int, error) {
 	key := ConsulReleaseInfoKey
-	return ConsulGetTreeFromRoot(key)
+	tree, errno, err := ConsulGetTreeFromRoot(key)
+	// Value of release version is prefixed with module name
+	for module, value := range tree {
+		tree[module] = strings.TrimPrefix(value, module+":")
+	}
+	return tree, errno, err
 }
 
 // ConsulUpdateVal is a convenient function for updating Consul KV Store.
 // ConsulUpdateVal update Consul KV Store by the given key, value pair.
-// value will be formatted by json.Marshal(val), and send to consul's web api by PUT Method.
-// It is a wrapper around DefaultConsulClient.ConsulUpdateVal(key, val).
+// value will be formatted by json.Marshal(val) if it is not string.
+// It is a wrapper around DefaultConfigStore.Put(key, val), the write is recorded as written by system.
 func ConsulUpdateVal(key string, val interface{}) (int, error) {
-	return DefaultConsulClient.ConsulUpdateVal(key, val)
+	return ConsulUpdateValWithChange(key, val, nil)
+}
+
+// ConsulUpdateValWithChange is ConsulUpdateVal with who and why the key is written recorded in history
+func ConsulUpdateValWithChange(key string, val interface{}, change *ConsulChange) (int, error) {
+	value, err := consulValueString(val)
+	if err != nil {
+		return ApiError.JSON_PARSE_ERROR, err
+	}
+	if err = ConfigPutWithChange(key, value, change); err != nil {
+		logConsulError(err)
+		return ApiError.CONSUL_SERVICE_ERROR, err
+	}
+	return ApiError.SUCCESS, nil
 }
 
 func ConsulGetVal(key string) (string, int, error) {
-	return DefaultConsulClient.ConsulGetVal(key)
+	value, err := DefaultConfigStore.Get(key)
+	if err != nil {
+		logConsulError(err)
+		return "", ApiError.CONSUL_SERVICE_ERROR, err
+	}
+	return value, ApiError.SUCCESS, nil
 }
 
 func ConsulDeleteKey(key string) (string, int, error) {
-	return DefaultConsulClient.ConsulDeleteKey(key)
+	return ConsulDeleteKeyWithChange(key, nil)
+}
+
+// ConsulDeleteKeyWithChange is ConsulDeleteKey with who and why the key is deleted recorded in history
+func ConsulDeleteKeyWithChange(key string, change *ConsulChange) (string, int, error) {
+	if err := ConfigDeleteWithChange(key, change); err != nil {
+		logConsulError(err)
+		return "", ApiError.CONSUL_SERVICE_ERROR, err
+	}
+	return "", ApiError.SUCCESS, nil
 }
 
 func ConsulGetTreeFromRoot(key string) (map[string]string, int, error) {
-	return RootConsulClient.ConsulGetTreeVal(key)
+	tree, err := RootConfigStore.Tree(key)
+	if err != nil {
+		logConsulError(err)
+		return nil, ApiError.CONSUL_SERVICE_ERROR, err
+	}
+	return tree, ApiError.SUCCESS, nil
 }
 
 func ConsulGetLogo(enterprise string, iconType string) ([]byte, error) {
@@ -503,29 +549,29 @@ func ConsulGetLogo(enterprise string, iconType string) ([]byte, error) {
 		enterprise = "system"
 	}
 	key := fmt.Sprintf("ui/icon/%s/%s", enterprise, iconType)
-	value, _, err := DefaultConsulClient.ConsulGetVal(key)
+	value, _, err := ConsulGetVal(key)
 	if err != nil {
 		return nil, err
 	}
 	return base64.StdEncoding.DecodeString(value)
 }
 
-func ConsulDeleteLogo(enterprise string, iconType string) error {
+func ConsulDeleteLogo(enterprise string, iconType string, change *ConsulChange) error {
 	if enterprise == "" {
 		enterprise = "system"
 	}
 	key := fmt.Sprintf("ui/icon/%s/%s", enterprise, iconType)
-	_, _, err := DefaultConsulClient.ConsulDeleteKey(key)
+	_, _, err := ConsulDeleteKeyWithChange(key, change)
 	return err
 }
 
-func ConsulUpdateLogo(enterprise string, iconType string, input []byte) error {
+func ConsulUpdateLogo(enterprise string, iconType string, input []byte, change *ConsulChange) error {
 	if enterprise == "" {
 		enterprise = "system"
 	}
 	key := fmt.Sprintf("ui/icon/%s/%s", enterprise, iconType)
 	encoded := base64.StdEncoding.EncodeToString(input)
-	_, err := DefaultConsulClient.ConsulUpdateVal(key, encoded)
+	_, err := ConsulUpdateValWithChange(key, encoded, change)
 	return err
 }
 
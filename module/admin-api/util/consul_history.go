@@ -0,0 +1,330 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// Operations of consul history record
+const (
+	ConsulOpSet    = "set"
+	ConsulOpDelete = "delete"
+)
+
+// ConsulSystemActor is the actor of writes without ConsulChange, which are usually
+// timestamps updated to notify other modules to reload
+const ConsulSystemActor = "system"
+
+// ErrConsulHistoryNotInit used when DefaultConfigStore does not record history
+var ErrConsulHistoryNotInit = errors.New("Consul history not init")
+
+// ConsulHistoryRecord is a write of a consul key
+type ConsulHistoryRecord struct {
+	ID       int64  `json:"id"`
+	Key      string `json:"key"`
+	Op       string `json:"op"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+	Actor    string `json:"actor"`
+	Reason   string `json:"reason"`
+	Time     int64  `json:"time"`
+}
+
+// ConsulChange is who and why a key is written, which is recorded in history
+type ConsulChange struct {
+	Actor  string
+	Reason string
+}
+
+// ConsulKeyDiff is the difference of a key between two trees.
+// Config stores return empty value for missing keys, so empty value is regarded as missing.
+type ConsulKeyDiff struct {
+	Key    string `json:"key"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Change is added, removed or modified
+	Change string `json:"change"`
+}
+
+// DefaultConsulHistoryRetention is the time history records are kept
+const DefaultConsulHistoryRetention = 180 * 24 * time.Hour
+
+// consulHistoryPageSize is the number of records read at a time when replaying history
+const consulHistoryPageSize = 500
+
+// HistoryConfigStore records writes to the wrapped store in history
+type HistoryConfigStore struct {
+	ConfigStore
+	history ConsulHistoryStore
+	// retention is the time records are kept, zero means records are kept forever
+	retention time.Duration
+}
+
+// NewHistoryConfigStore wraps store to record its writes in history
+func NewHistoryConfigStore(store ConfigStore, history ConsulHistoryStore) *HistoryConfigStore {
+	return &HistoryConfigStore{ConfigStore: store, history: history}
+}
+
+// Put implements ConfigStore, the change is recorded as written by system
+func (s *HistoryConfigStore) Put(key string, value string) error {
+	return s.PutWithChange(key, value, nil)
+}
+
+// Delete implements ConfigStore, the change is recorded as written by system
+func (s *HistoryConfigStore) Delete(key string) error {
+	return s.DeleteWithChange(key, nil)
+}
+
+// PutWithChange put value of key and record the change in history
+func (s *HistoryConfigStore) PutWithChange(key string, value string, change *ConsulChange) error {
+	oldValue := s.oldValue(key)
+	if err := s.ConfigStore.Put(key, value); err != nil {
+		return err
+	}
+	s.record(key, ConsulOpSet, oldValue, value, change)
+	return nil
+}
+
+// DeleteWithChange delete key and record the change in history
+func (s *HistoryConfigStore) DeleteWithChange(key string, change *ConsulChange) error {
+	oldValue := s.oldValue(key)
+	if err := s.ConfigStore.Delete(key); err != nil {
+		return err
+	}
+	s.record(key, ConsulOpDelete, oldValue, "", change)
+	return nil
+}
+
+func (s *HistoryConfigStore) oldValue(key string) string {
+	value, err := s.ConfigStore.Get(key)
+	if err != nil {
+		logger.Warn.Printf("Get old value of config key %s fail: %s\n", key, err.Error())
+	}
+	return value
+}
+
+func (s *HistoryConfigStore) record(key, op, oldValue, newValue string, change *ConsulChange) {
+	record := &ConsulHistoryRecord{
+		Key:      strings.Trim(key, "/"),
+		Op:       op,
+		OldValue: oldValue,
+		NewValue: newValue,
+		Actor:    ConsulSystemActor,
+		Time:     time.Now().Unix(),
+	}
+	if change != nil {
+		if change.Actor != "" {
+			record.Actor = change.Actor
+		}
+		record.Reason = change.Reason
+	}
+	// Value is written already, failure of history should not fail the write
+	if err := s.history.AddRecord(record); err != nil {
+		logger.Error.Printf("Record history of config key %s fail: %s\n", key, err.Error())
+	}
+}
+
+// ConfigPutWithChange put value to DefaultConfigStore, change is recorded if the store records history
+func ConfigPutWithChange(key string, value string, change *ConsulChange) error {
+	if store, ok := DefaultConfigStore.(*HistoryConfigStore); ok {
+		return store.PutWithChange(key, value, change)
+	}
+	return DefaultConfigStore.Put(key, value)
+}
+
+// ConfigDeleteWithChange delete key from DefaultConfigStore, change is recorded if the store records history
+func ConfigDeleteWithChange(key string, change *ConsulChange) error {
+	if store, ok := DefaultConfigStore.(*HistoryConfigStore); ok {
+		return store.DeleteWithChange(key, change)
+	}
+	return DefaultConfigStore.Delete(key)
+}
+
+// NewConsulChange returns the change made by user of request r, reason is the operation of request
+func NewConsulChange(r *http.Request, reason string) *ConsulChange {
+	return &ConsulChange{Actor: requestheader.GetUserID(r), Reason: reason}
+}
+
+// ConfigHistory returns DefaultConfigStore if it records history
+func ConfigHistory() (*HistoryConfigStore, error) {
+	if store, ok := DefaultConfigStore.(*HistoryConfigStore); ok {
+		return store, nil
+	}
+	return nil, ErrConsulHistoryNotInit
+}
+
+// consulValueString returns the value sent to consul, which is the same as update handler
+func consulValueString(val interface{}) (string, error) {
+	if str, ok := val.(string); ok {
+		return str, nil
+	}
+	body, err := json.Marshal(val)
+	return string(body), err
+}
+
+// History returns history records matched query, newest first
+func (s *HistoryConfigStore) History(query *ConsulHistoryQuery) ([]*ConsulHistoryRecord, error) {
+	return s.history.ListRecords(query)
+}
+
+// SetRetention sets the time records are kept, zero means records are kept forever
+func (s *HistoryConfigStore) SetRetention(retention time.Duration) {
+	s.retention = retention
+}
+
+// RemoveExpiredHistory removes records older than retention,
+// the latest record of each key is kept so it can still be replayed
+func (s *HistoryConfigStore) RemoveExpiredHistory() error {
+	if s.retention <= 0 {
+		return nil
+	}
+	removed, err := s.history.RemoveRecords(time.Now().Add(-s.retention).Unix())
+	if err != nil {
+		return err
+	}
+	logger.Info.Printf("Removed %d expired consul history records\n", removed)
+	return nil
+}
+
+// HistoryTree replays history to get values of keys under prefix at time at,
+// only keys of owners are replayed if owners is not empty.
+// Keys never written through the store are not in the tree.
+// Records older than retention are removed, so time before retention cannot be replayed.
+func (s *HistoryConfigStore) HistoryTree(prefix string, owners []string, at int64) (map[string]string, error) {
+	if s.retention > 0 {
+		if expired := time.Now().Add(-s.retention); at < expired.Unix() {
+			return nil, fmt.Errorf("history before %s is not kept", expired.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	tree := map[string]string{}
+	replayed := map[string]bool{}
+	// Records are newest first and read page by page. The latest record before at is
+	// the value at that time, if all records of key are after at, the old value of
+	// the earliest one is used.
+	query := &ConsulHistoryQuery{Prefix: prefix, Owners: owners, Limit: consulHistoryPageSize}
+	for {
+		records, err := s.History(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if replayed[record.Key] {
+				continue
+			}
+			if record.Time <= at {
+				replayed[record.Key] = true
+				tree[record.Key] = record.NewValue
+			} else {
+				tree[record.Key] = record.OldValue
+			}
+		}
+		if len(records) < query.Limit {
+			break
+		}
+		query.BeforeID = records[len(records)-1].ID
+	}
+	for key, value := range tree {
+		if value == "" {
+			delete(tree, key)
+		}
+	}
+	return tree, nil
+}
+
+// DiffHistory returns keys of owners under prefix changed between from and to
+func (s *HistoryConfigStore) DiffHistory(prefix string, owners []string, from, to int64) ([]*ConsulKeyDiff, error) {
+	before, err := s.HistoryTree(prefix, owners, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.HistoryTree(prefix, owners, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffConsulTree(before, after), nil
+}
+
+// RestoreHistory writes the tree of prefix at time at back to the store, only keys of owners
+// are restored if owners is not empty.
+// Only keys in history are restored, keys missing at that time are deleted.
+// It returns the changes applied, which are recorded in history with change.
+func (s *HistoryConfigStore) RestoreHistory(prefix string, owners []string, at int64,
+	change *ConsulChange) ([]*ConsulKeyDiff, error) {
+	target, err := s.HistoryTree(prefix, owners, at)
+	if err != nil {
+		return nil, err
+	}
+	// Keys in history at any time, which may be changed after at
+	latest, err := s.HistoryTree(prefix, owners, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]string{}
+	for _, tree := range []map[string]string{target, latest} {
+		for key := range tree {
+			if _, ok := current[key]; ok {
+				continue
+			}
+			value, err := s.ConfigStore.Get(key)
+			if err != nil {
+				return nil, err
+			}
+			current[key] = value
+		}
+	}
+	for key, value := range current {
+		if value == "" {
+			delete(current, key)
+		}
+	}
+
+	if change == nil {
+		change = &ConsulChange{}
+	}
+	if change.Reason == "" {
+		change.Reason = fmt.Sprintf("restore %s to %s", prefix, time.Unix(at, 0).Format("2006-01-02 15:04:05"))
+	}
+
+	applied := []*ConsulKeyDiff{}
+	for _, diff := range diffConsulTree(current, target) {
+		if diff.Change == "removed" {
+			err = s.DeleteWithChange(diff.Key, change)
+		} else {
+			err = s.PutWithChange(diff.Key, diff.After, change)
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, diff)
+	}
+	return applied, nil
+}
+
+func diffConsulTree(before, after map[string]string) []*ConsulKeyDiff {
+	diffs := []*ConsulKeyDiff{}
+	for key, value := range before {
+		newValue, ok := after[key]
+		if !ok {
+			diffs = append(diffs, &ConsulKeyDiff{Key: key, Before: value, Change: "removed"})
+		} else if newValue != value {
+			diffs = append(diffs, &ConsulKeyDiff{Key: key, Before: value, After: newValue, Change: "modified"})
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok {
+			diffs = append(diffs, &ConsulKeyDiff{Key: key, After: value, Change: "added"})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs
+}
@@ -0,0 +1,367 @@
+package util
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConsulHistoryStore persists history of consul keys written by ConsulClient
+type ConsulHistoryStore interface {
+	// AddRecord stores record and assigns its ID
+	AddRecord(record *ConsulHistoryRecord) error
+	// ListRecords returns records matched query, newest first
+	ListRecords(query *ConsulHistoryQuery) ([]*ConsulHistoryRecord, error)
+	// RemoveRecords removes records created before time before, except the latest record
+	// of each key, so the value of a key not written since then can still be replayed.
+	// It returns the number of removed records.
+	RemoveRecords(before int64) (int64, error)
+}
+
+// ConsulHistoryQuery filters history records. Key matches prefix if it is the prefix itself
+// or under the prefix. From and To are unix time in seconds, zero means no bound.
+// Limit zero means all records.
+// Owners limits keys to those with a path segment equal to one of owners, such as
+// appid or enterprise id of caller, empty means any key.
+// BeforeID limits records to those with ID less than it, which is the cursor of next page
+// as records are newest first, zero means no bound.
+type ConsulHistoryQuery struct {
+	Prefix   string
+	Owners   []string
+	From     int64
+	To       int64
+	BeforeID int64
+	Limit    int
+	Offset   int
+}
+
+func (query *ConsulHistoryQuery) match(record *ConsulHistoryRecord) bool {
+	if query.BeforeID > 0 && record.ID >= query.BeforeID {
+		return false
+	}
+	if !consulKeyHasPrefix(record.Key, query.Prefix) {
+		return false
+	}
+	if !consulKeyOwned(record.Key, query.Owners) {
+		return false
+	}
+	if query.From > 0 && record.Time < query.From {
+		return false
+	}
+	if query.To > 0 && record.Time > query.To {
+		return false
+	}
+	return true
+}
+
+func consulKeyHasPrefix(key, prefix string) bool {
+	prefix = strings.Trim(prefix, "/")
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+"/")
+}
+
+func consulKeyOwned(key string, owners []string) bool {
+	if len(owners) == 0 {
+		return true
+	}
+	for _, segment := range strings.Split(key, "/") {
+		for _, owner := range owners {
+			if segment == owner {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// consulKeySegmentSize is the max length of segments indexed in consul_history_segments,
+// longer segments are not appid or enterprise id, so they are not indexed
+const consulKeySegmentSize = 191
+
+// consulKeySegments returns distinct path segments of key which can be owners
+func consulKeySegments(key string) []string {
+	segments := []string{}
+	found := map[string]bool{}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || len(segment) > consulKeySegmentSize || found[segment] {
+			continue
+		}
+		found[segment] = true
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// SQLConsulHistoryStore stores history in table consul_history, path segments of keys are
+// indexed in table consul_history_segments, so records of owners are found without scanning keys
+type SQLConsulHistoryStore struct {
+	db *sql.DB
+}
+
+// NewSQLConsulHistoryStore create a store with given db, which is usually the main db
+func NewSQLConsulHistoryStore(db *sql.DB) *SQLConsulHistoryStore {
+	return &SQLConsulHistoryStore{db: db}
+}
+
+func (s *SQLConsulHistoryStore) AddRecord(record *ConsulHistoryRecord) error {
+	if s.db == nil {
+		return ErrDBNotInit
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer ClearTransition(tx)
+
+	queryStr := `
+		INSERT INTO consul_history (consul_key, op, old_value, new_value, actor, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(queryStr, record.Key, record.Op, record.OldValue, record.NewValue,
+		record.Actor, record.Reason, record.Time)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if segments := consulKeySegments(record.Key); len(segments) > 0 {
+		queryStr = "INSERT INTO consul_history_segments (segment, history_id) VALUES " +
+			strings.Repeat("(?, ?),", len(segments)-1) + "(?, ?)"
+		params := []interface{}{}
+		for _, segment := range segments {
+			params = append(params, segment, id)
+		}
+		if _, err = tx.Exec(queryStr, params...); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	record.ID = id
+	return nil
+}
+
+func (s *SQLConsulHistoryStore) ListRecords(query *ConsulHistoryQuery) ([]*ConsulHistoryRecord, error) {
+	if s.db == nil {
+		return nil, ErrDBNotInit
+	}
+
+	conditions := []string{"1 = 1"}
+	params := []interface{}{}
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	if prefix := strings.Trim(query.Prefix, "/"); prefix != "" {
+		conditions = append(conditions, "(consul_key = ? OR consul_key LIKE ?)")
+		params = append(params, prefix, escaper.Replace(prefix)+"/%")
+	}
+	if len(query.Owners) > 0 {
+		conditions = append(conditions, `id IN (
+			SELECT history_id FROM consul_history_segments
+			WHERE segment IN (?`+strings.Repeat(", ?", len(query.Owners)-1)+`))`)
+		for _, owner := range query.Owners {
+			params = append(params, owner)
+		}
+	}
+	if query.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		params = append(params, query.BeforeID)
+	}
+	if query.From > 0 {
+		conditions = append(conditions, "created_at >= ?")
+		params = append(params, query.From)
+	}
+	if query.To > 0 {
+		conditions = append(conditions, "created_at <= ?")
+		params = append(params, query.To)
+	}
+
+	queryStr := `
+		SELECT id, consul_key, op, old_value, new_value, actor, reason, created_at
+		FROM consul_history
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id DESC`
+	if query.Limit > 0 {
+		queryStr += " LIMIT ? OFFSET ?"
+		params = append(params, query.Limit, query.Offset)
+	}
+
+	rows, err := s.db.Query(queryStr, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*ConsulHistoryRecord{}
+	for rows.Next() {
+		record := &ConsulHistoryRecord{}
+		err = rows.Scan(&record.ID, &record.Key, &record.Op, &record.OldValue, &record.NewValue,
+			&record.Actor, &record.Reason, &record.Time)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLConsulHistoryStore) RemoveRecords(before int64) (int64, error) {
+	if s.db == nil {
+		return 0, ErrDBNotInit
+	}
+
+	queryStr := `
+		DELETE h, s
+		FROM consul_history h
+		INNER JOIN consul_history n ON n.consul_key = h.consul_key AND n.id > h.id
+		LEFT JOIN consul_history_segments s ON s.history_id = h.id
+		WHERE h.created_at < ?`
+	result, err := s.db.Exec(queryStr, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// FileConsulHistoryStore stores history in a local file with one JSON record a line,
+// which is used when there is no db, such as in tests
+type FileConsulHistoryStore struct {
+	path   string
+	mu     sync.Mutex
+	lastID int64
+}
+
+// NewFileConsulHistoryStore create a store with file in path, records in existed file are kept
+func NewFileConsulHistoryStore(path string) (*FileConsulHistoryStore, error) {
+	s := &FileConsulHistoryStore{path: path}
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.ID > s.lastID {
+			s.lastID = record.ID
+		}
+	}
+	return s, nil
+}
+
+func (s *FileConsulHistoryStore) AddRecord(record *ConsulHistoryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.ID = s.lastID + 1
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.lastID = record.ID
+	return nil
+}
+
+func (s *FileConsulHistoryStore) ListRecords(query *ConsulHistoryQuery) ([]*ConsulHistoryRecord, error) {
+	s.mu.Lock()
+	records, err := s.readAll()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*ConsulHistoryRecord{}
+	for _, record := range records {
+		if query.match(record) {
+			matched = append(matched, record)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+
+	if query.Limit > 0 {
+		if query.Offset >= len(matched) {
+			return []*ConsulHistoryRecord{}, nil
+		}
+		matched = matched[query.Offset:]
+		if len(matched) > query.Limit {
+			matched = matched[:query.Limit]
+		}
+	}
+	return matched, nil
+}
+
+func (s *FileConsulHistoryStore) RemoveRecords(before int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.readAll()
+	if err != nil {
+		return 0, err
+	}
+	latest := map[string]int64{}
+	for _, record := range records {
+		if record.ID > latest[record.Key] {
+			latest[record.Key] = record.ID
+		}
+	}
+
+	var removed int64
+	content := []byte{}
+	for _, record := range records {
+		if record.Time < before && record.ID != latest[record.Key] {
+			removed++
+			continue
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return 0, err
+		}
+		content = append(append(content, line...), '\n')
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, content, 0644); err != nil {
+		return 0, err
+	}
+	return removed, os.Rename(tmpPath, s.path)
+}
+
+func (s *FileConsulHistoryStore) readAll() ([]*ConsulHistoryRecord, error) {
+	records := []*ConsulHistoryRecord{}
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// Values such as logo may be larger than default buffer of scanner
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &ConsulHistoryRecord{}
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,232 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newHistoryTestStore create a store with kv in memory and history in a temp file
+func newHistoryTestStore(t *testing.T, kv map[string]string) (*HistoryConfigStore, *FileConsulHistoryStore) {
+	dir, err := ioutil.TempDir("", "consul_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	history, err := NewFileConsulHistoryStore(filepath.Join(dir, "history.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryConfigStore()
+	for key, value := range kv {
+		store.Put(key, value)
+	}
+	return NewHistoryConfigStore(store, history), history
+}
+
+func TestConsulHistoryRecord(t *testing.T) {
+	kv := map[string]string{"setting/controller": `{"qps":100}`}
+	c, store := newHistoryTestStore(t, kv)
+
+	change := &ConsulChange{Actor: "admin", Reason: "raise qps"}
+	if err := c.PutWithChange("setting/controller", `{"qps":200}`, change); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("chat/app", "12345"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("chat/app"); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := c.History(&ConsulHistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expect 3 records, get %d", len(records))
+	}
+	if r := records[2]; r.ID != 1 || r.Key != "setting/controller" || r.Op != ConsulOpSet ||
+		r.OldValue != `{"qps":100}` || r.NewValue != `{"qps":200}` || r.Actor != "admin" || r.Reason != "raise qps" {
+		t.Errorf("Unexpected record of setting: %+v", r)
+	}
+	if r := records[1]; r.NewValue != "12345" || r.Actor != ConsulSystemActor {
+		t.Errorf("Unexpected record of update without change: %+v", r)
+	}
+	if r := records[0]; r.Op != ConsulOpDelete || r.OldValue != "12345" || r.NewValue != "" {
+		t.Errorf("Unexpected record of delete: %+v", r)
+	}
+
+	records, err = c.History(&ConsulHistoryQuery{Prefix: "chat", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != 2 {
+		t.Errorf("Expect the second record of chat, get %+v", records)
+	}
+
+	// Records are kept when store is opened again
+	reopened, err := NewFileConsulHistoryStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.lastID != 3 {
+		t.Errorf("Expect last id 3 after reopen, get %d", reopened.lastID)
+	}
+}
+
+func TestConsulHistoryDiffAndRestore(t *testing.T) {
+	kv := map[string]string{
+		"chat/app1": "300",
+		"chat/app3": "c",
+		"faq/app1":  "faq",
+	}
+	c, store := newHistoryTestStore(t, kv)
+	history := []*ConsulHistoryRecord{
+		{Key: "chat/app1", Op: ConsulOpSet, OldValue: "100", NewValue: "200", Time: 100},
+		{Key: "chat/app2", Op: ConsulOpSet, OldValue: "", NewValue: "b", Time: 150},
+		{Key: "chat/app1", Op: ConsulOpSet, OldValue: "200", NewValue: "300", Time: 200},
+		{Key: "chat/app2", Op: ConsulOpDelete, OldValue: "b", NewValue: "", Time: 200},
+		{Key: "chat/app3", Op: ConsulOpSet, OldValue: "", NewValue: "c", Time: 250},
+		{Key: "faq/app1", Op: ConsulOpSet, OldValue: "", NewValue: "faq", Time: 250},
+	}
+	for _, record := range history {
+		if err := store.AddRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := c.HistoryTree("chat", nil, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]string{"chat/app1": "100"}; !reflect.DeepEqual(tree, expect) {
+		t.Errorf("Expect tree before history %v, get %v", expect, tree)
+	}
+	tree, err = c.HistoryTree("chat", nil, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]string{"chat/app1": "200", "chat/app2": "b"}; !reflect.DeepEqual(tree, expect) {
+		t.Errorf("Expect tree at 150 %v, get %v", expect, tree)
+	}
+
+	diffs, err := c.DiffHistory("chat", nil, 150, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectDiffs := []*ConsulKeyDiff{
+		{Key: "chat/app1", Before: "200", After: "300", Change: "modified"},
+		{Key: "chat/app2", Before: "b", Change: "removed"},
+		{Key: "chat/app3", After: "c", Change: "added"},
+	}
+	if !reflect.DeepEqual(diffs, expectDiffs) {
+		t.Errorf("Unexpected diff between 150 and 250")
+		for _, diff := range diffs {
+			t.Logf("%+v", diff)
+		}
+	}
+
+	// Keys of other apps are not visible to owner app1
+	diffs, err = c.DiffHistory("", []string{"app1"}, 150, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectDiffs = []*ConsulKeyDiff{
+		{Key: "chat/app1", Before: "200", After: "300", Change: "modified"},
+		{Key: "faq/app1", After: "faq", Change: "added"},
+	}
+	if !reflect.DeepEqual(diffs, expectDiffs) {
+		t.Errorf("Unexpected diff of app1 between 150 and 250")
+		for _, diff := range diffs {
+			t.Logf("%+v", diff)
+		}
+	}
+
+	applied, err := c.RestoreHistory("chat", nil, 150, &ConsulChange{Actor: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("Expect 3 keys restored, get %d", len(applied))
+	}
+	tree, err = c.Tree("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := c.Get("faq/app1"); value != "faq" {
+		t.Errorf("Expect keys out of prefix not restored, get %s", value)
+	}
+	if expect := map[string]string{"app1": "200", "app2": "b"}; !reflect.DeepEqual(tree, expect) {
+		t.Errorf("Expect kv after restore %v, get %v", expect, tree)
+	}
+
+	records, err := c.History(&ConsulHistoryQuery{From: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expect restore recorded in history, get %d records", len(records))
+	}
+	for _, record := range records {
+		if record.Actor != "admin" || record.Reason == "" {
+			t.Errorf("Expect restore recorded with actor and reason, get %+v", record)
+		}
+	}
+}
+
+func TestConsulHistoryRetention(t *testing.T) {
+	c, store := newHistoryTestStore(t, map[string]string{})
+	now := time.Now().Unix()
+	history := []*ConsulHistoryRecord{
+		{Key: "chat/app1", Op: ConsulOpSet, NewValue: "1", Time: now - 300*24*3600},
+		{Key: "chat/app1", Op: ConsulOpSet, OldValue: "1", NewValue: "2", Time: now - 200*24*3600},
+		{Key: "chat/app2", Op: ConsulOpSet, NewValue: "a", Time: now - 200*24*3600},
+		{Key: "chat/app1", Op: ConsulOpSet, OldValue: "2", NewValue: "3", Time: now - 3600},
+	}
+	for _, record := range history {
+		if err := store.AddRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Trees are replayed page by page
+	records, err := c.History(&ConsulHistoryQuery{Prefix: "chat", BeforeID: 3, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != 2 {
+		t.Errorf("Expect the record before id 3, get %+v", records)
+	}
+
+	c.SetRetention(DefaultConsulHistoryRetention)
+	if err = c.RemoveExpiredHistory(); err != nil {
+		t.Fatal(err)
+	}
+	records, err = c.History(&ConsulHistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{}
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	if expect := []int64{4, 3}; !reflect.DeepEqual(ids, expect) {
+		t.Errorf("Expect latest records of keys kept %v, get %v", expect, ids)
+	}
+
+	tree, err := c.HistoryTree("chat", nil, now-7*24*3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]string{"chat/app1": "2", "chat/app2": "a"}; !reflect.DeepEqual(tree, expect) {
+		t.Errorf("Expect tree in retention %v, get %v", expect, tree)
+	}
+	if _, err = c.HistoryTree("chat", nil, now-250*24*3600); err == nil {
+		t.Error("Expect tree before retention rejected")
+	}
+}
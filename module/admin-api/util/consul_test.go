@@ -119,7 +119,7 @@ func TestConsulUpdateTaskEngine(t *testing.T) {
 		t.Fatal(err)
 	}
 	DefaultConsulClient.Address = u
-	ConsulUpdateTaskEngine("", true)
+	ConsulUpdateTaskEngine("", true, nil)
 }
 
 func TestConsulUpdateRobotChat(t *testing.T) {
@@ -138,7 +138,7 @@ func TestConsulUpdateRobotChat(t *testing.T) {
 		t.Fatal(err)
 	}
 	DefaultConsulClient.Address = u
-	ConsulUpdateRobotChat(appid)
+	ConsulUpdateRobotChat(appid, nil)
 }
 
 type mockedLocker struct {
@@ -21,6 +21,12 @@ var (
 	ErrSQLAlreadyOccupied = errors.New("db row already updated")
 
 	ErrParameter = errors.New("Invalid parameter")
+
+	// ErrUnsupportedConfigStore used when backend of config store is unknown
+	ErrUnsupportedConfigStore = errors.New("Unsupported config store")
+
+	// ErrLockNotHeld used when unlock a lock which is not held
+	ErrLockNotHeld = errors.New("Lock not held")
 )
 
 func GenNotFoundError(name string) error {
@@ -176,11 +176,13 @@ func CompressFiles(filePaths []string, outFilePath string) error {
 	return nil
 }
 
+// GetTemplateDir returns dir of template files in locale, templates of default locale
+// are used if there is no template of locale, such as en-us
 func GetTemplateDir(locale string) string {
-	actualLocale := locale
-	if locale != localemsg.ZhCn && locale != localemsg.ZhTw {
-		actualLocale = localemsg.ZhCn
-	}
 	mountPath := getGlobalEnv(mountDirPathKey)
-	return fmt.Sprintf("%s/%s", mountPath, actualLocale)
+	dir := fmt.Sprintf("%s/%s", mountPath, locale)
+	if info, err := os.Stat(dir); locale == "" || err != nil || !info.IsDir() {
+		dir = fmt.Sprintf("%s/%s", mountPath, localemsg.DefaultLocale)
+	}
+	return dir
 }
@@ -0,0 +1,158 @@
+package importpreview
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
+	"emotibot.com/emotigo/module/admin-api/util/audit"
+	"emotibot.com/emotigo/module/admin-api/util/localemsg"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// ParseFunc parses the uploaded file of appid. Row errors are returned in rowErrs,
+// and err is only used when the file can not be previewed at all.
+// commit is the function to apply the imported data, which must be atomic.
+// It is called again with the commit request when the preview is committed,
+// so form values must be read from upload instead of r.
+type ParseFunc func(r *http.Request, appid string, upload *Upload) (rowErrs RowErrors, diff *Diff, commit func() error, err AdminErrors.AdminError)
+
+// PreviewKey is the mux var of preview id in routes
+const PreviewKey = "preview_id"
+
+// UploadHandler returns the handler which parses file in form field and creates preview
+func UploadHandler(module string, field string, parse ParseFunc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appid := requestheader.GetAppID(r)
+		locale := requestheader.GetLocale(r)
+
+		file, info, err := r.FormFile(field)
+		if err != nil {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoRequestError, localemsg.Get(locale, "ImportPreviewNoFile")), nil)
+			return
+		}
+		defer file.Close()
+		buf, err := ioutil.ReadAll(file)
+		if err != nil {
+			util.Return(w, AdminErrors.New(AdminErrors.ErrnoIOError, err.Error()), nil)
+			return
+		}
+
+		upload := &Upload{
+			Filename: info.Filename,
+			Content:  buf,
+			Form:     map[string]string{},
+		}
+		if r.MultipartForm != nil {
+			for key, values := range r.MultipartForm.Value {
+				if len(values) > 0 {
+					upload.Form[key] = values[0]
+				}
+			}
+		}
+
+		rowErrs, diff, _, adminErr := parse(r, appid, upload)
+		if adminErr != nil {
+			util.Return(w, adminErr, nil)
+			return
+		}
+		preview, err := New(appid, module, upload, rowErrs, diff)
+		if err != nil {
+			util.Return(w, toAdminError(locale, err), nil)
+			return
+		}
+		logger.Trace.Printf("Create import preview %s of %s, %d errors\n", preview.ID, module, len(rowErrs))
+		util.Return(w, nil, preview)
+	}
+}
+
+// GetHandler returns the handler to get preview of module
+func GetHandler(module string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appid := requestheader.GetAppID(r)
+		locale := requestheader.GetLocale(r)
+		preview, err := Get(appid, module, util.GetMuxVar(r, PreviewKey))
+		if err != nil {
+			util.Return(w, toAdminError(locale, err), nil)
+			return
+		}
+		util.Return(w, nil, preview)
+	}
+}
+
+// CommitHandler returns the handler to apply preview of module, the file is parsed by parse again
+func CommitHandler(module string, parse ParseFunc) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appid := requestheader.GetAppID(r)
+		locale := requestheader.GetLocale(r)
+		id := util.GetMuxVar(r, PreviewKey)
+
+		preview, err := Commit(appid, module, id, func(upload *Upload) (RowErrors, *Diff, func() error, error) {
+			rowErrs, diff, commit, adminErr := parse(r, appid, upload)
+			if adminErr != nil {
+				return nil, nil, nil, adminErr
+			}
+			return rowErrs, diff, commit, nil
+		})
+		if preview == nil {
+			util.Return(w, toAdminError(locale, err), nil)
+			return
+		}
+
+		auditMsg := fmt.Sprintf("%s: %s", localemsg.Get(locale, "ImportPreviewCommit"), preview.Filename)
+		auditRet := 1
+		if err != nil {
+			logger.Error.Printf("Commit import preview %s of %s fail: %s\n", id, module, err.Error())
+			auditRet = 0
+		}
+		audit.AddAuditFromRequestAutoWithOP(r, auditMsg, auditRet, audit.AuditOperationImport)
+		if err != nil {
+			util.Return(w, toAdminError(locale, err), nil)
+			return
+		}
+		util.Return(w, nil, preview)
+	}
+}
+
+// DiscardHandler returns the handler to remove preview of module
+func DiscardHandler(module string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appid := requestheader.GetAppID(r)
+		locale := requestheader.GetLocale(r)
+		err := Discard(appid, module, util.GetMuxVar(r, PreviewKey))
+		if err != nil {
+			util.Return(w, toAdminError(locale, err), nil)
+			return
+		}
+		util.Return(w, nil, nil)
+	}
+}
+
+// EntryPoints returns routes of preview with path prefix, like "import/preview"
+func EntryPoints(prefix string, privileges []string, version int, module string, field string, parse ParseFunc) []util.EntryPoint {
+	idPath := fmt.Sprintf("%s/{%s}", prefix, PreviewKey)
+	return []util.EntryPoint{
+		util.NewEntryPointWithVer(http.MethodPost, prefix, privileges, UploadHandler(module, field, parse), version),
+		util.NewEntryPointWithVer(http.MethodGet, idPath, privileges, GetHandler(module), version),
+		util.NewEntryPointWithVer(http.MethodPost, idPath+"/commit", privileges, CommitHandler(module, parse), version),
+		util.NewEntryPointWithVer(http.MethodDelete, idPath, privileges, DiscardHandler(module), version),
+	}
+}
+
+func toAdminError(locale string, err error) AdminErrors.AdminError {
+	switch err {
+	case ErrPreviewNotFound:
+		return AdminErrors.New(AdminErrors.ErrnoNotFound, localemsg.Get(locale, "ImportPreviewNotFound"))
+	case ErrPreviewHasErrors:
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, localemsg.Get(locale, "ImportPreviewHasErrors"))
+	case ErrPreviewOutdated:
+		return AdminErrors.New(AdminErrors.ErrnoRequestError, localemsg.Get(locale, "ImportPreviewOutdated"))
+	}
+	if adminErr, ok := err.(AdminErrors.AdminError); ok {
+		return adminErr
+	}
+	return AdminErrors.New(AdminErrors.ErrnoDBError, err.Error())
+}
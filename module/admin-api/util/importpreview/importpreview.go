@@ -0,0 +1,261 @@
+// Package importpreview provides a two-phase import for xlsx importers.
+// Importers parse the uploaded file into a preview with row errors and diff against
+// current data, and the changes are only applied when the preview is committed.
+package importpreview
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+var (
+	// ErrPreviewNotFound means preview is not existed, expired or committed
+	ErrPreviewNotFound = errors.New("import preview not found")
+	// ErrPreviewHasErrors means preview can not be committed because of row errors
+	ErrPreviewHasErrors = errors.New("import preview has errors")
+	// ErrPreviewOutdated means current data has been changed after the preview is created
+	ErrPreviewOutdated = errors.New("import preview is outdated")
+)
+
+// PreviewExpiration is how long a preview is kept before committed
+var PreviewExpiration = 30 * time.Minute
+
+// RowError describe an invalid cell or row in uploaded file
+type RowError struct {
+	Sheet string `json:"sheet"`
+	// Row is the row number shown in excel, starting from 1. 0 means the error is of whole file or sheet
+	Row     int    `json:"row"`
+	Column  string `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e *RowError) Error() string {
+	if e.Row == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s[%d] %s: %s", e.Sheet, e.Row, e.Column, e.Message)
+}
+
+// RowErrors collects all errors of a file, parsers can keep parsing after a row error
+// and return RowErrors as error
+type RowErrors []*RowError
+
+func (errs RowErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for idx := range errs {
+		msgs[idx] = errs[idx].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add appends a new error of sheet, row and column
+func (errs *RowErrors) Add(sheet string, row int, column string, message string) {
+	*errs = append(*errs, &RowError{
+		Sheet:   sheet,
+		Row:     row,
+		Column:  column,
+		Message: message,
+	})
+}
+
+// Err returns nil if there is no error, which is used as the return error of parser
+func (errs RowErrors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ToRowErrors converts the error returned by parser to row errors
+func ToRowErrors(err error) RowErrors {
+	switch e := err.(type) {
+	case nil:
+		return RowErrors{}
+	case RowErrors:
+		return e
+	case *RowError:
+		return RowErrors{e}
+	default:
+		return RowErrors{&RowError{Message: err.Error()}}
+	}
+}
+
+// Diff describe the changes of records from current data to imported data
+type Diff struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Modified  []string `json:"modified"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// DiffRecords compares records with same key by their content
+func DiffRecords(current, imported map[string]string) *Diff {
+	diff := &Diff{
+		Added:    []string{},
+		Removed:  []string{},
+		Modified: []string{},
+	}
+	for key, content := range imported {
+		orig, ok := current[key]
+		if !ok {
+			diff.Added = append(diff.Added, key)
+		} else if orig != content {
+			diff.Modified = append(diff.Modified, key)
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for key := range current {
+		if _, ok := imported[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff
+}
+
+// RecordContent joins the values into a content used in DiffRecords,
+// the values are sorted so that order of them are ignored
+func RecordContent(values ...string) string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\n")
+}
+
+// Upload is the uploaded file and form values of the request. It is stored with the
+// preview, so the file can be parsed again when the preview is committed.
+type Upload struct {
+	Filename string            `json:"-"`
+	Content  []byte            `json:"-"`
+	Form     map[string]string `json:"form"`
+}
+
+// FormValue returns the form value uploaded with the file
+func (u *Upload) FormValue(key string) string {
+	return u.Form[key]
+}
+
+// Preview is a parsed file waiting to be committed
+type Preview struct {
+	ID         string    `json:"id"`
+	Module     string    `json:"module"`
+	Filename   string    `json:"filename"`
+	CreateTime int64     `json:"create_time"`
+	ExpireTime int64     `json:"expire_time"`
+	Errors     RowErrors `json:"errors"`
+	Diff       *Diff     `json:"diff"`
+	appid      string
+	upload     *Upload
+}
+
+// New stores the preview of upload.
+// module is used to prevent a preview from being committed by other importers.
+func New(appid, module string, upload *Upload, rowErrs RowErrors, diff *Diff) (*Preview, error) {
+	now := time.Now()
+	if rowErrs == nil {
+		rowErrs = RowErrors{}
+	}
+	preview := &Preview{
+		ID:         util.GenRandomUUIDSameAsOpenAPI(),
+		Module:     module,
+		Filename:   upload.Filename,
+		CreateTime: now.Unix(),
+		ExpireTime: now.Add(PreviewExpiration).Unix(),
+		Errors:     rowErrs,
+		Diff:       diff,
+		appid:      appid,
+		upload:     upload,
+	}
+
+	err := previewStore.removeExpired(now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	err = previewStore.create(preview)
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+func getPreview(appid, module, id string) (*Preview, error) {
+	preview, err := previewStore.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if preview.appid != appid || preview.Module != module ||
+		preview.ExpireTime < time.Now().Unix() {
+		return nil, ErrPreviewNotFound
+	}
+	return preview, nil
+}
+
+// Get returns the preview of appid in module
+func Get(appid, module, id string) (*Preview, error) {
+	return getPreview(appid, module, id)
+}
+
+// CommitParseFunc parses the upload of preview again, commit is the function to apply it
+type CommitParseFunc func(upload *Upload) (rowErrs RowErrors, diff *Diff, commit func() error, err error)
+
+// Commit parses the file of preview again and applies it only if the file is still valid
+// and the diff is the same as the previewed one, otherwise ErrPreviewOutdated is returned.
+// A preview can only be committed once even if commit failed.
+func Commit(appid, module, id string, parse CommitParseFunc) (*Preview, error) {
+	preview, err := getPreview(appid, module, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(preview.Errors) > 0 {
+		return nil, ErrPreviewHasErrors
+	}
+
+	claimed, err := previewStore.claim(id)
+	if err != nil {
+		return nil, err
+	} else if !claimed {
+		return nil, ErrPreviewNotFound
+	}
+	defer previewStore.remove(id)
+
+	rowErrs, diff, commit, err := parse(preview.upload)
+	if err != nil {
+		return preview, err
+	}
+	if len(rowErrs) > 0 {
+		return preview, ErrPreviewHasErrors
+	}
+	if !sameDiff(preview.Diff, diff) {
+		return preview, ErrPreviewOutdated
+	}
+	return preview, commit()
+}
+
+func sameDiff(a, b *Diff) bool {
+	contentA, _ := json.Marshal(a)
+	contentB, _ := json.Marshal(b)
+	return string(contentA) == string(contentB)
+}
+
+// Discard removes the preview without applying it
+func Discard(appid, module, id string) error {
+	_, err := getPreview(appid, module, id)
+	if err != nil {
+		return err
+	}
+	claimed, err := previewStore.claim(id)
+	if err != nil {
+		return err
+	} else if !claimed {
+		return ErrPreviewNotFound
+	}
+	return previewStore.remove(id)
+}
@@ -0,0 +1,151 @@
+package importpreview
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRecords(t *testing.T) {
+	current := map[string]string{
+		"a": RecordContent("1", "2"),
+		"b": RecordContent("3"),
+		"c": RecordContent("4"),
+	}
+	imported := map[string]string{
+		"a": RecordContent("2", "1"),
+		"b": RecordContent("3", "5"),
+		"d": RecordContent("6"),
+	}
+
+	diff := DiffRecords(current, imported)
+	expect := &Diff{
+		Added:     []string{"d"},
+		Removed:   []string{"c"},
+		Modified:  []string{"b"},
+		Unchanged: 1,
+	}
+	if !reflect.DeepEqual(diff, expect) {
+		t.Errorf("Expect diff %+v, but got %+v", expect, diff)
+	}
+}
+
+func TestToRowErrors(t *testing.T) {
+	rowErrs := RowErrors{}
+	if rowErrs.Err() != nil {
+		t.Error("Expect nil error of empty row errors")
+	}
+	rowErrs.Add("sheet", 2, "name", "empty")
+	rowErrs.Add("sheet", 3, "name", "too long")
+	if converted := ToRowErrors(rowErrs.Err()); len(converted) != 2 {
+		t.Errorf("Expect 2 row errors, but got %v", converted)
+	}
+
+	converted := ToRowErrors(errors.New("invalid file"))
+	if len(converted) != 1 || converted[0].Row != 0 || converted[0].Message != "invalid file" {
+		t.Errorf("Expect file error, but got %+v", converted)
+	}
+	if len(ToRowErrors(nil)) != 0 {
+		t.Error("Expect no row error of nil")
+	}
+}
+
+// memoryStore keeps previews in memory for tests
+type memoryStore struct {
+	previews map[string]*Preview
+	claimed  map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		previews: map[string]*Preview{},
+		claimed:  map[string]bool{},
+	}
+}
+
+func (s *memoryStore) create(preview *Preview) error {
+	s.previews[preview.ID] = preview
+	return nil
+}
+
+func (s *memoryStore) get(id string) (*Preview, error) {
+	preview, ok := s.previews[id]
+	if !ok || s.claimed[id] {
+		return nil, ErrPreviewNotFound
+	}
+	return preview, nil
+}
+
+func (s *memoryStore) claim(id string) (bool, error) {
+	if _, ok := s.previews[id]; !ok || s.claimed[id] {
+		return false, nil
+	}
+	s.claimed[id] = true
+	return true, nil
+}
+
+func (s *memoryStore) remove(id string) error {
+	delete(s.previews, id)
+	delete(s.claimed, id)
+	return nil
+}
+
+func (s *memoryStore) removeExpired(now int64) error {
+	for id, preview := range s.previews {
+		if preview.ExpireTime < now {
+			delete(s.previews, id)
+		}
+	}
+	return nil
+}
+
+func TestCommit(t *testing.T) {
+	defer func(s store) { previewStore = s }(previewStore)
+	previewStore = newMemoryStore()
+
+	committed := 0
+	diff := &Diff{Added: []string{"a"}, Removed: []string{}, Modified: []string{}}
+	parse := func(upload *Upload) (RowErrors, *Diff, func() error, error) {
+		if upload.FormValue("key") != "value" {
+			t.Errorf("Expect form value of upload, but got %v", upload.Form)
+		}
+		return nil, diff, func() error {
+			committed++
+			return nil
+		}, nil
+	}
+	upload := &Upload{Filename: "file.xlsx", Form: map[string]string{"key": "value"}}
+
+	preview, err := New("app", "module", upload, nil, diff)
+	if err != nil {
+		t.Fatalf("Expect preview created, but got %v", err)
+	}
+	if _, err := Commit("app", "other", preview.ID, parse); err != ErrPreviewNotFound {
+		t.Errorf("Expect preview of other module not found, but got %v", err)
+	}
+	if _, err := Commit("app", "module", preview.ID, parse); err != nil || committed != 1 {
+		t.Errorf("Expect committed once, but got %v, %d", err, committed)
+	}
+	if _, err := Commit("app", "module", preview.ID, parse); err != ErrPreviewNotFound || committed != 1 {
+		t.Errorf("Expect preview can only be committed once, but got %v", err)
+	}
+
+	// current data is changed after preview
+	preview, _ = New("app", "module", upload, nil, &Diff{Added: []string{}, Removed: []string{}, Modified: []string{}})
+	if _, err := Commit("app", "module", preview.ID, parse); err != ErrPreviewOutdated || committed != 1 {
+		t.Errorf("Expect outdated preview not committed, but got %v", err)
+	}
+
+	rowErrs := RowErrors{}
+	rowErrs.Add("sheet", 2, "name", "empty")
+	preview, _ = New("app", "module", upload, rowErrs, nil)
+	if _, err := Commit("app", "module", preview.ID, parse); err != ErrPreviewHasErrors || committed != 1 {
+		t.Errorf("Expect preview with errors can not be committed, but got %v", err)
+	}
+	if err := Discard("app", "module", preview.ID); err != nil {
+		t.Errorf("Expect preview discarded, but got %v", err)
+	}
+	if _, err := Get("app", "module", preview.ID); err != ErrPreviewNotFound {
+		t.Errorf("Expect discarded preview not found, but got %v", err)
+	}
+}
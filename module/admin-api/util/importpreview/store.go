@@ -0,0 +1,121 @@
+package importpreview
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+)
+
+// store keeps previews in a shared place, so a preview can be committed by any instance
+type store interface {
+	create(preview *Preview) error
+	// get returns ErrPreviewNotFound if the preview does not exist or has been claimed
+	get(id string) (*Preview, error)
+	// claim marks the preview as being committed or discarded,
+	// false is returned if it has been claimed already
+	claim(id string) (bool, error)
+	remove(id string) error
+	removeExpired(now int64) error
+}
+
+var previewStore store = sqlStore{}
+
+// sqlStore stores previews in import_previews of main DB
+type sqlStore struct{}
+
+func (s sqlStore) create(preview *Preview) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+	form, err := json.Marshal(preview.upload.Form)
+	if err != nil {
+		return err
+	}
+	rowErrs, err := json.Marshal(preview.Errors)
+	if err != nil {
+		return err
+	}
+	diff, err := json.Marshal(preview.Diff)
+	if err != nil {
+		return err
+	}
+
+	queryStr := `
+		INSERT INTO import_previews
+		(id, appid, module, filename, content, form, errors, diff, create_time, expire_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = db.Exec(queryStr, preview.ID, preview.appid, preview.Module, preview.Filename,
+		preview.upload.Content, string(form), string(rowErrs), string(diff),
+		preview.CreateTime, preview.ExpireTime)
+	return err
+}
+
+func (s sqlStore) get(id string) (*Preview, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return nil, util.ErrDBNotInit
+	}
+
+	var form, rowErrs, diff string
+	preview := &Preview{ID: id, upload: &Upload{}}
+	queryStr := `
+		SELECT appid, module, filename, content, form, errors, diff, create_time, expire_time
+		FROM import_previews
+		WHERE id = ? AND claimed = 0`
+	err := db.QueryRow(queryStr, id).Scan(&preview.appid, &preview.Module, &preview.Filename,
+		&preview.upload.Content, &form, &rowErrs, &diff, &preview.CreateTime, &preview.ExpireTime)
+	if err == sql.ErrNoRows {
+		return nil, ErrPreviewNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	preview.upload.Filename = preview.Filename
+
+	if err = json.Unmarshal([]byte(form), &preview.upload.Form); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(rowErrs), &preview.Errors); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(diff), &preview.Diff); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+func (s sqlStore) claim(id string) (bool, error) {
+	db := util.GetMainDB()
+	if db == nil {
+		return false, util.ErrDBNotInit
+	}
+
+	result, err := db.Exec("UPDATE import_previews SET claimed = 1 WHERE id = ? AND claimed = 0", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s sqlStore) remove(id string) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+	_, err := db.Exec("DELETE FROM import_previews WHERE id = ?", id)
+	return err
+}
+
+func (s sqlStore) removeExpired(now int64) error {
+	db := util.GetMainDB()
+	if db == nil {
+		return util.ErrDBNotInit
+	}
+	_, err := db.Exec("DELETE FROM import_previews WHERE expire_time < ?", now)
+	return err
+}
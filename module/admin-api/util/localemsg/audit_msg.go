@@ -38,7 +38,8 @@ var auditMsg = map[string]map[string]string{
 		"AuditOperationActive":   "启动",
 		"AuditOperationDeactive": "关闭",
 
-		"AuditRobotConfigChangeTemplate": "修改设定 %s 的值为 %s",
+		"AuditRobotConfigChangeTemplate":   "修改设定 %s 的值为 %s",
+		"AuditRobotConfigRollbackTemplate": "回滚设定至修改记录 %d 之前",
 	},
 	ZhTw: map[string]string{
 		// Modules
@@ -77,6 +78,47 @@ var auditMsg = map[string]map[string]string{
 		"AuditOperationActive":   "啟動",
 		"AuditOperationDeactive": "關閉",
 
-		"AuditRobotConfigChangeTemplate": "修改設定 %s 的值為 %s",
+		"AuditRobotConfigChangeTemplate":   "修改設定 %s 的值為 %s",
+		"AuditRobotConfigRollbackTemplate": "回滾設定至修改記錄 %d 之前",
+	},
+	EnUs: map[string]string{
+		// Modules
+		"AuditModuleSSM":               "FAQ Management",
+		"AuditModuleFAQ":               "FAQ Management",
+		"AuditModuleQALabel":           "Label Management",
+		"AuditModuleTaskEngine":        "Task Engine",
+		"AuditModuleIntentManage":      "Intent Engine",
+		"AuditModuleWordbank":          "Wordbank",
+		"AuditModuleStatisticDaily":    "Log Management",
+		"AuditModuleStatisticAnalysis": "Statistics",
+		"AuditModuleStatisticAudit":    "Audit Log",
+		"AuditModuleRobotProfile":      "Robot Profile",
+		"AuditModuleRobotChatSkill":    "Robot Words",
+		"AuditModuleRobotFunction":     "Robot Skills",
+		"AuditModuleRobotCommand":      "Commands",
+		"AuditModuleRobotCustomChat":   "Custom Chat",
+		"AuditModuleRobotConfig":       "System Config",
+		"AuditModuleIntegration":       "Integration",
+		"AuditModuleManageUser":        "Enterprise Users",
+		"AuditModuleManageRobot":       "Robots",
+		"AuditModuleManageAdmin":       "System Admins",
+		"AuditModuleManageEnterprise":  "Enterprises",
+		"AuditModuleNerFactory":        "Custom NER",
+		"AuditModuleCCS":               "Central Control",
+		"AuditModuleMaterial":          "Material Library",
+		"AuditModuleMaterialLibrary":   "Material Library",
+		// Operation
+		"AuditOperationAdd":      "Add",
+		"AuditOperationEdit":     "Edit",
+		"AuditOperationDelete":   "Delete",
+		"AuditOperationImport":   "Import",
+		"AuditOperationExport":   "Export",
+		"AuditOperationLogin":    "Login",
+		"AuditOperationPublish":  "Publish",
+		"AuditOperationActive":   "Activate",
+		"AuditOperationDeactive": "Deactivate",
+
+		"AuditRobotConfigChangeTemplate":   "Change value of config %s to %s",
+		"AuditRobotConfigRollbackTemplate": "Roll back config to before change %d",
 	},
 }
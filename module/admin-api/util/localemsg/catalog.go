@@ -0,0 +1,337 @@
+package localemsg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+// Plural categories of messages, only one and other are used by supported locales
+const (
+	pluralOne   = "one"
+	pluralOther = "other"
+)
+
+// catalogWatchInterval is the interval to check changes of catalog files
+var catalogWatchInterval = 10 * time.Second
+
+// message is a localized message, with text of each plural category
+type message map[string]string
+
+func (msg message) form(category string) string {
+	if text, ok := msg[category]; ok {
+		return text
+	}
+	return msg[pluralOther]
+}
+
+// catalog is messages of all locales, which is replaced as a whole when reloaded
+type catalog struct {
+	messages map[string]map[string]message
+}
+
+var (
+	currentCatalog     *catalog
+	currentCatalogLock = sync.RWMutex{}
+)
+
+func getCatalog() *catalog {
+	currentCatalogLock.RLock()
+	defer currentCatalogLock.RUnlock()
+	return currentCatalog
+}
+
+func setCatalog(c *catalog) {
+	currentCatalogLock.Lock()
+	defer currentCatalogLock.Unlock()
+	currentCatalog = c
+}
+
+// builtinCatalog returns catalog of messages compiled into binary
+func builtinCatalog() *catalog {
+	c := &catalog{messages: map[string]map[string]message{}}
+	for locale, msgs := range localeMsg {
+		c.messages[locale] = map[string]message{}
+		for key, text := range msgs {
+			c.messages[locale][key] = message{pluralOther: text}
+		}
+	}
+	return c
+}
+
+func (c *catalog) merge(locale string, msgs map[string]message) {
+	if _, ok := c.messages[locale]; !ok {
+		c.messages[locale] = map[string]message{}
+	}
+	for key, msg := range msgs {
+		c.messages[locale][key] = msg
+	}
+}
+
+// pluralCategory returns plural category of count in locale.
+// Chinese has no plural forms, other languages use one for 1 like English.
+func pluralCategory(locale string, count int) string {
+	if strings.HasPrefix(locale, "zh") || count != 1 {
+		return pluralOther
+	}
+	return pluralOne
+}
+
+// pluralCategories returns categories of msgstr[n] in PO files of locale in order
+func pluralCategories(locale string) []string {
+	if strings.HasPrefix(locale, "zh") {
+		return []string{pluralOther}
+	}
+	return []string{pluralOne, pluralOther}
+}
+
+// LoadCatalogs loads message catalogs in dir, which override messages compiled into binary.
+// Catalogs are JSON or PO files named <locale>.json, <locale>.po, or with a domain like
+// audit.en-us.json, so new locales can be added without rebuilding. Catalogs loaded before
+// are kept if any file is invalid.
+func LoadCatalogs(dir string) error {
+	files, err := catalogFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	c := builtinCatalog()
+	for _, file := range files {
+		locale := catalogLocale(file)
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		var msgs map[string]message
+		if filepath.Ext(file) == ".po" {
+			msgs, err = parsePO(content, locale)
+		} else {
+			msgs, err = parseJSONCatalog(content)
+		}
+		if err != nil {
+			return fmt.Errorf("Parse catalog %s fail, %s", file, err.Error())
+		}
+		c.merge(locale, msgs)
+	}
+
+	setCatalog(c)
+	logger.Info.Printf("Load %d locale catalogs in %s, locales: %v\n", len(files), dir, Locales())
+	for locale, keys := range MissingKeys() {
+		logger.Warn.Printf("Locale [%s] misses %d keys: %s\n", locale, len(keys), strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// WatchCatalogs reloads catalogs in dir when files are changed, until stopCh is closed.
+// Invalid catalogs are logged and ignored, so messages loaded before are still used.
+func WatchCatalogs(dir string, stopCh <-chan struct{}) {
+	last, _ := catalogSignature(dir)
+	ticker := time.NewTicker(catalogWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		signature, err := catalogSignature(dir)
+		if err != nil {
+			logger.Error.Printf("Check locale catalogs in %s fail: %s\n", dir, err.Error())
+			continue
+		}
+		if signature == last {
+			continue
+		}
+		last = signature
+		if err = LoadCatalogs(dir); err != nil {
+			logger.Error.Printf("Reload locale catalogs fail: %s\n", err.Error())
+		}
+	}
+}
+
+func catalogFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, info := range infos {
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || (ext != ".json" && ext != ".po") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, info.Name()))
+	}
+	// Catalogs of locale, such as en-us.json, are loaded before catalogs of domain,
+	// so messages of domain take precedence. Files are sorted by name otherwise.
+	sort.SliceStable(files, func(i, j int) bool {
+		return strings.Count(filepath.Base(files[i]), ".") < strings.Count(filepath.Base(files[j]), ".")
+	})
+	return files, nil
+}
+
+// catalogSignature returns names, sizes and modified times of catalog files, which changes when any file changes
+func catalogSignature(dir string) (string, error) {
+	files, err := catalogFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.Buffer{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return buf.String(), nil
+}
+
+// catalogLocale returns locale in name of file, such as en-us of audit.en_US.json
+func catalogLocale(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return normalizeLocale(name)
+}
+
+// parseJSONCatalog parses catalog of key to message, message with plural forms is
+// an object of plural categories, such as {"one": "%d file", "other": "%d files"}
+func parseJSONCatalog(content []byte) (map[string]message, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	msgs := map[string]message{}
+	for key, value := range raw {
+		text := ""
+		if err := json.Unmarshal(value, &text); err == nil {
+			msgs[key] = message{pluralOther: text}
+			continue
+		}
+		forms := map[string]string{}
+		if err := json.Unmarshal(value, &forms); err != nil {
+			return nil, fmt.Errorf("invalid message of key %s", key)
+		}
+		if _, ok := forms[pluralOther]; !ok {
+			return nil, fmt.Errorf("plural form other of key %s is missing", key)
+		}
+		msgs[key] = message(forms)
+	}
+	return msgs, nil
+}
+
+// parsePO parses gettext PO file, msgid is used as key. Text of msgstr[n] is the
+// n-th plural category of locale. Entries with empty msgstr are not translated and ignored.
+func parsePO(content []byte, locale string) (map[string]message, error) {
+	msgs := map[string]message{}
+	categories := pluralCategories(locale)
+
+	id := ""
+	plural := false
+	strs := map[int]*string{}
+	var current *string
+	flush := func() {
+		msg := message{}
+		if !plural && strs[0] != nil {
+			msg[pluralOther] = *strs[0]
+		} else if plural {
+			last := -1
+			for idx, text := range strs {
+				if idx < len(categories) && *text != "" {
+					msg[categories[idx]] = *text
+				}
+				if idx > last && *text != "" {
+					last = idx
+				}
+			}
+			if _, ok := msg[pluralOther]; !ok && last >= 0 {
+				msg[pluralOther] = *strs[last]
+			}
+		}
+		// Header of PO file has empty msgid
+		if id != "" && msg[pluralOther] != "" {
+			msgs[id] = msg
+		}
+		id, plural, strs, current = "", false, map[int]*string{}, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword := ""
+		quoted := line
+		if !strings.HasPrefix(line, `"`) {
+			fields := strings.SplitN(line, " ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: invalid line", lineNo)
+			}
+			keyword, quoted = fields[0], strings.TrimSpace(fields[1])
+		}
+		text, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string, %s", lineNo, err.Error())
+		}
+
+		switch {
+		case keyword == "":
+			// Continued string of the previous keyword
+			if current == nil {
+				return nil, fmt.Errorf("line %d: string without keyword", lineNo)
+			}
+			*current += text
+			continue
+		case keyword == "msgctxt":
+			// Context is not used, because msgid is unique key
+			flush()
+			current = new(string)
+		case keyword == "msgid":
+			if len(strs) > 0 {
+				flush()
+			}
+			current = &id
+		case keyword == "msgid_plural":
+			plural = true
+			current = new(string)
+		case keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr["):
+			idx := 0
+			if keyword != "msgstr" {
+				idx, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(keyword, "msgstr["), "]"))
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("line %d: invalid plural index", lineNo)
+				}
+			}
+			strs[idx] = new(string)
+			current = strs[idx]
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %s", lineNo, keyword)
+		}
+		*current = text
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return msgs, nil
+}
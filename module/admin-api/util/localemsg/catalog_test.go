@@ -0,0 +1,216 @@
+package localemsg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuiltinCatalogComplete(t *testing.T) {
+	if missing := MissingKeys(); len(missing) > 0 {
+		t.Errorf("Expect no missing keys in built-in messages, get %v", missing)
+	}
+	if expect := []string{EnUs, ZhCn, ZhTw}; !reflect.DeepEqual(Locales(), expect) {
+		t.Errorf("Expect locales %v, get %v", expect, Locales())
+	}
+}
+
+func TestGetFallback(t *testing.T) {
+	defer setCatalog(builtinCatalog())
+	c := builtinCatalog()
+	delete(c.messages[EnUs], "Success")
+	setCatalog(c)
+
+	if msg := Get(EnUs, "Fail"); msg != "Failed" {
+		t.Errorf("Expect message of en-us, get %s", msg)
+	}
+	if msg := Get(EnUs, "Success"); msg != "成功" {
+		t.Errorf("Expect missing key fallback to default locale, get %s", msg)
+	}
+	if msg := Get("fr-fr", "Fail"); msg != "失败" {
+		t.Errorf("Expect unsupported locale fallback to default locale, get %s", msg)
+	}
+	if msg := Get(EnUs, "NotExistedKey"); msg != "" {
+		t.Errorf("Expect empty message of unknown key, get %s", msg)
+	}
+	if msg := Format(EnUs, "RollbackIntentVersionTpl", 3); msg != "Roll back intents to version 3" {
+		t.Errorf("Expect formatted message, get %s", msg)
+	}
+}
+
+func writeCatalog(t *testing.T, dir string, name string, content string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCatalogs(t *testing.T) {
+	defer setCatalog(builtinCatalog())
+	dir, err := ioutil.TempDir("", "localemsg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCatalog(t, dir, "en-us.json", `{
+		"Success": "Done",
+		"FileCount": {"one": "one file", "other": "%d files"},
+		"RowError": "row %d of %s"
+	}`)
+	writeCatalog(t, dir, "audit.en_US.json", `{"RowError": "%[2]s row %[1]d"}`)
+	writeCatalog(t, dir, "ja-jp.po", `# Japanese catalog
+msgid ""
+msgstr ""
+"Language: ja\n"
+
+msgid "Success"
+msgstr "成功しました"
+
+msgid "FileCount"
+msgid_plural "%d files"
+msgstr[0] "%d 個"
+msgstr[1] "%d 個のファイル"
+
+msgid "Untranslated"
+msgstr ""
+
+msgid "Fail"
+msgstr "失敗"
+"しました"
+`)
+	if err := LoadCatalogs(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := Get(EnUs, "Success"); msg != "Done" {
+		t.Errorf("Expect message of catalog override built-in, get %s", msg)
+	}
+	if msg := Get(EnUs, "Fail"); msg != "Failed" {
+		t.Errorf("Expect built-in message kept, get %s", msg)
+	}
+	if msg := Plural(EnUs, "FileCount", 1); msg != "one file" {
+		t.Errorf("Expect singular form, get %s", msg)
+	}
+	if msg := Plural(EnUs, "FileCount", 3); msg != "3 files" {
+		t.Errorf("Expect plural form, get %s", msg)
+	}
+	if msg := Format(EnUs, "RowError", 2, "sheet"); msg != "sheet row 2" {
+		t.Errorf("Expect catalog of domain override catalog of locale, get %s", msg)
+	}
+
+	if !IsSupported("ja-jp") {
+		t.Fatal("Expect locale of po file supported")
+	}
+	if msg := Get("ja-jp", "Fail"); msg != "失敗しました" {
+		t.Errorf("Expect continued string of po, get %s", msg)
+	}
+	if msg := Plural("ja-jp", "FileCount", 1); msg != "1 個" {
+		t.Errorf("Expect first plural form of po, get %s", msg)
+	}
+	if msg := Get("ja-jp", "Untranslated"); msg != "" {
+		t.Errorf("Expect untranslated entry ignored, get %s", msg)
+	}
+	missing := MissingKeys()
+	if len(missing["ja-jp"]) == 0 || len(missing[ZhCn]) == 0 {
+		t.Errorf("Expect keys missing in ja-jp and zh-cn, get %v", missing)
+	}
+
+	// Invalid catalog is rejected and messages loaded before are kept
+	writeCatalog(t, dir, "zh-tw.json", `{"Success": ["invalid"]}`)
+	if err := LoadCatalogs(dir); err == nil {
+		t.Error("Expect invalid catalog rejected")
+	}
+	if msg := Get(EnUs, "Success"); msg != "Done" {
+		t.Errorf("Expect catalogs kept after invalid reload, get %s", msg)
+	}
+}
+
+func TestWatchCatalogs(t *testing.T) {
+	defer setCatalog(builtinCatalog())
+	defer func(interval time.Duration) { catalogWatchInterval = interval }(catalogWatchInterval)
+	catalogWatchInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "localemsg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeCatalog(t, dir, "en-us.json", `{"Success": "Done"}`)
+	if err := LoadCatalogs(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go WatchCatalogs(dir, stopCh)
+	time.Sleep(20 * time.Millisecond)
+	writeCatalog(t, dir, "en-us.json", `{"Success": "Completed!"}`)
+
+	deadline := time.Now().Add(time.Second)
+	for Get(EnUs, "Success") != "Completed!" {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expect catalog reloaded, get %s", Get(EnUs, "Success"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	matches := map[string]string{
+		"zh-TW":      ZhTw,
+		"zh_CN":      ZhCn,
+		"zh":         ZhCn,
+		"zh-Hant-HK": ZhTw,
+		"zh-HK":      ZhTw,
+		"en":         EnUs,
+		"en-GB":      EnUs,
+		"fr":         "",
+		"*":          "",
+	}
+	for tag, expect := range matches {
+		if locale := Match(tag); locale != expect {
+			t.Errorf("Expect %s matches %q, get %q", tag, expect, locale)
+		}
+	}
+
+	headers := map[string]string{
+		"en-US,en;q=0.9,zh-CN;q=0.8": EnUs,
+		"fr-FR,fr;q=0.9,zh-TW;q=0.5": ZhTw,
+		"zh-CN;q=0.5,en;q=0.8":       EnUs,
+		"en;q=0,zh-tw":               ZhTw,
+		"fr, de":                     "",
+		"":                           "",
+	}
+	for header, expect := range headers {
+		if locale := Negotiate(header); locale != expect {
+			t.Errorf("Expect Accept-Language %s negotiates %q, get %q", header, expect, locale)
+		}
+	}
+}
+
+func TestMissingTables(t *testing.T) {
+	table := map[string][]string{
+		ZhCn: {"a", "b"},
+		ZhTw: {"a"},
+	}
+	RegisterTable("test/table", table)
+	defer func() {
+		tablesLock.Lock()
+		delete(tables, "test/table")
+		tablesLock.Unlock()
+	}()
+
+	if locale := TableLocale(table, EnUs); locale != DefaultLocale {
+		t.Errorf("Expect missing locale to use %s, get %s", DefaultLocale, locale)
+	}
+	if locale := TableLocale(table, ZhTw); locale != ZhTw {
+		t.Errorf("Expect %s in table, get %s", ZhTw, locale)
+	}
+	missing := MissingTables()["test/table"]
+	if expect := []string{EnUs, ZhTw}; !reflect.DeepEqual(missing, expect) {
+		t.Errorf("Expect missing locales %v, get %v", expect, missing)
+	}
+}
@@ -0,0 +1,30 @@
+package localemsg
+
+import "sort"
+
+// MissingKeys returns keys missing in each locale, which exist in any other locale.
+// Locales without missing keys are not in result.
+func MissingKeys() map[string][]string {
+	c := getCatalog()
+	allKeys := map[string]bool{}
+	for _, msgs := range c.messages {
+		for key := range msgs {
+			allKeys[key] = true
+		}
+	}
+
+	ret := map[string][]string{}
+	for locale, msgs := range c.messages {
+		missing := []string{}
+		for key := range allKeys {
+			if _, ok := msgs[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			ret[locale] = missing
+		}
+	}
+	return ret
+}
@@ -4,6 +4,9 @@ package localemsg
 var cmdMsg = map[string]map[string]string{
 	ZhCn: map[string]string{
 		"CmdUploadSheetErr":            "上传的资料表数量错误",
+		"CmdUploadTemplateErr":         "上传的资料表标头与模板不符",
+		"CmdUploadSizeExceed":          "上传的文件过大",
+		"CmdUploadRowsExceed":          "上传的指令数量超过上限",
 		"CmdSheetName":                 "指令模板",
 		"CmdTargetQuestion":            "用户问题",
 		"CmdTargetAnswer":              "出话内容",
@@ -24,6 +27,9 @@ var cmdMsg = map[string]map[string]string{
 	},
 	ZhTw: map[string]string{
 		"CmdUploadSheetErr":            "上传的资料表数量错误",
+		"CmdUploadTemplateErr":         "上傳的資料表標頭與模板不符",
+		"CmdUploadSizeExceed":          "上傳的文件過大",
+		"CmdUploadRowsExceed":          "上傳的指令數量超過上限",
 		"CmdSheetName":                 "指令模板",
 		"CmdTargetQuestion":            "用戶問題",
 		"CmdTargetAnswer":              "出話內容",
@@ -41,6 +47,28 @@ var cmdMsg = map[string]map[string]string{
 		"CmdImportStatusError":                      "导入失败",
 		"CmdImportErrorReason":                      "錯誤原因",
 	},
+	EnUs: map[string]string{
+		"CmdUploadSheetErr":    "Wrong number of sheets in uploaded file",
+		"CmdUploadTemplateErr": "Headers of uploaded sheet mismatch the template",
+		"CmdUploadSizeExceed":  "Uploaded file is too large",
+		"CmdUploadRowsExceed":  "Number of uploaded commands exceeds the limit",
+		"CmdSheetName":         "Command Template",
+		"CmdTargetQuestion":    "User Question",
+		"CmdTargetAnswer":      "Robot Answer",
+
+		"CmdResponseReplace": "Replace answer",
+		"CmdResponseBefore":  "Before answer",
+		"CmdResponseAfter":   "After answer",
+
+		"CmdRecordStatusClassExceedMax":    "Category exceeds 20 characters",
+		"CmdRecordStatusNameUnnormal":      "Invalid command name",
+		"CmdRecordStatusTargetError":       "Invalid target of rule",
+		"CmdRecordStatusPriodError":        "Invalid effective period",
+		"CmdRecordStatusResponseTypeError": "Invalid response position",
+		"CmdRecordStatusOK":                "OK",
+		"CmdImportStatusError":             "Import failed",
+		"CmdImportErrorReason":             "Error Reason",
+	},
 }
 
 
@@ -39,5 +39,24 @@ var customChatMsg = map[string]map[string]string{
 		"CustomChatUploadExtendDuplicate":    "资料表 %s 中第 %d 行出現重複",
 		"CustomChatUploadQuestionAnswerCountExceedLimit": "资料表 %s 中問題 %s 的答案个数超过%d个",
 	},
+	EnUs: map[string]string{
+		"CustomChatUploadSheetErr":                       "Wrong number of sheets in uploaded file",
+		"CustomChatQuestionSheetName":                    "Chat Answers",
+		"CustomChatExtendSheetName":                      "Chat Corpus",
+		"CustomChatUploadNoHeaderTpl":                    "Headers are missing in sheet %s",
+		"CustomChatCategory":                             "Category",
+		"CustomChatQuestion":                             "Question",
+		"CustomChatAnswer":                               "Answer",
+		"CustomChatExtend":                               "Similar Question",
+		"CustomChatUploadQuestionRowInvalidTpl":          "Sheet %s row %d: columns are missing",
+		"CustomChatUploadQuestionRowNoQuestionTpl":       "Sheet %s row %d: question is empty",
+		"CustomChatUploadQuestionRowNoAnswerTpl":         "Sheet %s row %d: answer is empty",
+		"CustomChatUploadQuestionRowNoExtendTpl":         "Sheet %s row %d: similar question is empty",
+		"CustomChatExport":                               "Export Custom Chat",
+		"CustomChatUploadQuestionExceedLimit":            "Sheet %s row %d: question exceeds %d characters",
+		"CustomChatUploadAnswerExceedLimit":              "Sheet %s row %d: answer exceeds %d characters",
+		"CustomChatUploadExtendDuplicate":                "Sheet %s row %d is duplicated",
+		"CustomChatUploadQuestionAnswerCountExceedLimit": "Sheet %s: question %s has more than %d answers",
+	},
 }
 
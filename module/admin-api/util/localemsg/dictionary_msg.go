@@ -39,4 +39,23 @@ var dictionaryMsg = map[string]map[string]string{
 		"DictionaryErrorMoveTarget":        "目標目錄已有相同名稱的詞庫",
 		"DictionaryExport":                 "導出詞庫",
 	},
+	EnUs: map[string]string{
+		"DictionaryNoClass":                "Uncategorized",
+		"DictionaryTemplateXLSXName":       "Wordbank Template",
+		"DictionarySheetError":             "Failed to read sheet of wordbank template",
+		"DictionaryEmptyRows":              "No data in sheet",
+		"DictionaryErrorEmptyNameTpl":      "Row %d: wordbank name is empty",
+		"DictionaryErrorNameTooLongTpl":    "Row %d: wordbank name exceeds 35 characters",
+		"DictionaryErrorSimilarTooLongTpl": "Row %d: synonym exceeds 64 characters",
+		"DictionaryErrorPathTooLongTpl":    "Row %d: directory name exceeds 20 characters",
+		"DictionaryErrorRowErrorTpl":       "Row %d: %s",
+		"DictionaryErrorPathLevelTpl":      "Invalid content in level %d of path",
+		"DictionaryErrorNotEditable":       "This wordbank is not editable",
+		"DictionaryErrorRequestErrorTpl":   "Invalid parameters",
+		"DictionaryErrorAPINameTooLong":    "Wordbank name exceeds 35 characters",
+		"DictionaryErrorAPISimilarTooLong": "Synonym exceeds 64 characters",
+		"DictionaryErrorAPIPathTooLong":    "Directory name exceeds 20 characters",
+		"DictionaryErrorMoveTarget":        "A wordbank of the same name exists in target directory",
+		"DictionaryExport":                 "Export Wordbank",
+	},
 }
@@ -0,0 +1,31 @@
+package localemsg
+
+var importPreviewMsg = map[string]map[string]string{
+	ZhCn: map[string]string{
+		"ImportPreviewNoFile":    "未上传导入文件",
+		"ImportPreviewNotFound":  "导入预览不存在或已过期",
+		"ImportPreviewHasErrors": "导入文件有错误，无法提交",
+		"ImportPreviewOutdated":  "预览后资料已变更，请重新上传",
+		"ImportPreviewCommit":    "提交导入预览",
+		"ImportPreviewEmptyCell": "栏位不得为空",
+		"ImportPreviewDuplicate": "重复的资料",
+	},
+	ZhTw: map[string]string{
+		"ImportPreviewNoFile":    "未上傳導入文件",
+		"ImportPreviewNotFound":  "導入預覽不存在或已過期",
+		"ImportPreviewHasErrors": "導入文件有錯誤，無法提交",
+		"ImportPreviewOutdated":  "預覽後資料已變更，請重新上傳",
+		"ImportPreviewCommit":    "提交導入預覽",
+		"ImportPreviewEmptyCell": "欄位不得為空",
+		"ImportPreviewDuplicate": "重複的資料",
+	},
+	EnUs: map[string]string{
+		"ImportPreviewNoFile":    "No import file is uploaded",
+		"ImportPreviewNotFound":  "Import preview does not exist or has expired",
+		"ImportPreviewHasErrors": "Import file has errors and cannot be committed",
+		"ImportPreviewOutdated":  "Data has been changed after preview, please upload again",
+		"ImportPreviewCommit":    "Commit Import Preview",
+		"ImportPreviewEmptyCell": "Field must not be empty",
+		"ImportPreviewDuplicate": "Duplicated data",
+	},
+}
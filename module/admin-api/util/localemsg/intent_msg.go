@@ -27,6 +27,8 @@ var intentMsg = map[string]map[string]string{
 		"IntentUploadBF2RowNoNameTpl":     "资料表 %s 中第 %d 行名字为空",
 		"IntentUploadBF2RowNoSentenceTpl": "资料表 %s 中第 %d 行语句为空",
 		"IntentExport":                    "导出意图",
+		"IntentVersion":                   "意图版本",
+		"RollbackIntentVersionTpl":        "回滚意图至版本 %d",
 	},
 	ZhTw: map[string]string{
 		"Fail":                            "失敗",
@@ -54,5 +56,36 @@ var intentMsg = map[string]map[string]string{
 		"IntentUploadBF2RowNoNameTpl":     "資料表 %s 中第 %d 行名字為空",
 		"IntentUploadBF2RowNoSentenceTpl": "資料表 %s 中第 %d 行語句為空",
 		"IntentExport":                    "導出意圖",
+		"IntentVersion":                   "意圖版本",
+		"RollbackIntentVersionTpl":        "回滾意圖至版本 %d",
+	},
+	EnUs: map[string]string{
+		"Fail":                            "Failed",
+		"IntentID":                        "Intent ID",
+		"DeleteIntent":                    "Delete Intent",
+		"AddIntent":                       "Add Intent",
+		"UpdateIntent":                    "Update Intent",
+		"IntentName":                      "Intent Name",
+		"IntentPositive":                  "Positive Corpus",
+		"IntentNegative":                  "Negative Corpus",
+		"IntentSentence":                  "Sentence",
+		"AddIntentSummaryTpl":             ": %s, %d positive sentences, %d negative sentences",
+		"UpdateIntentSummaryTpl":          ": %s, %d sentences changed, %d sentences deleted",
+		"IntentModifyUpdate":              "Changed Sentences",
+		"IntentModifyDelete":              "Sentences to Delete",
+		"IntentUploadSheetErr":            "Wrong number of sheets in uploaded file",
+		"IntentBF2Sheet1Name":             "Positive sentences of intents",
+		"IntentBF2Sheet2Name":             "Negative sentences of intents",
+		"IntentBF2NewSheetName":           "Corpus Export",
+		"BF2NewPositive":                  "Positive Corpus",
+		"BF2NewNegative":                  "Negative Corpus",
+		"IntentUploadNoHeaderTpl":         "Headers are missing in sheet %s",
+		"IntentUploadHeaderErrTpl":        "Invalid headers in sheet %s",
+		"IntentUploadBF2RowInvalidTpl":    "Sheet %s row %d: columns are missing",
+		"IntentUploadBF2RowNoNameTpl":     "Sheet %s row %d: name is empty",
+		"IntentUploadBF2RowNoSentenceTpl": "Sheet %s row %d: sentence is empty",
+		"IntentExport":                    "Export Intents",
+		"IntentVersion":                   "Intent Version",
+		"RollbackIntentVersionTpl":        "Roll back intents to version %d",
 	},
 }
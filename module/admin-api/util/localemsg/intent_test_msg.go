@@ -14,6 +14,21 @@ var intentTestMsg = map[string]map[string]string{
 		"IntentTestUploadNoHeaderTpl":    "上传的资料表标头遗失",
 		"IntestTestSentenceEmptyErr":     "测试语句不得为空字串",
 		"NegativeTestIntentName":         "负类测试意图",
+
+		"CrossValidationNoSentence":           "没有可用于交叉验证的意图语料",
+		"PreviousCrossValidationStillRunning": "前一次交叉验证进行中",
+		"CrossValidationMetrics":              "意图评估指标",
+		"CrossValidationConfusionMatrix":      "混淆矩阵",
+		"CrossValidationConfusedPairs":        "易混淆意图",
+		"CrossValidationPrecision":            "精确率",
+		"CrossValidationRecall":               "召回率",
+		"CrossValidationF1":                   "F1",
+		"CrossValidationSupport":              "语料数",
+		"CrossValidationMacroAverage":         "宏平均",
+		"CrossValidationAccuracy":             "准确率",
+		"CrossValidationPredicted":            "预测意图",
+		"CrossValidationCount":                "次数",
+		"CrossValidationNoIntent":             "无意图",
 	},
 	ZhTw: map[string]string{
 		"IntentTestSentences":            "意圖測試集測試語句",
@@ -28,5 +43,49 @@ var intentTestMsg = map[string]map[string]string{
 		"IntentTestUploadNoHeaderTpl":    "上傳的資料表標頭遺失",
 		"IntestTestSentenceEmptyErr":     "測試語句不得為空字串",
 		"NegativeTestIntentName":         "負類測試意圖",
+
+		"CrossValidationNoSentence":           "沒有可用於交叉驗證的意圖語料",
+		"PreviousCrossValidationStillRunning": "前一次交叉驗證進行中",
+		"CrossValidationMetrics":              "意圖評估指標",
+		"CrossValidationConfusionMatrix":      "混淆矩陣",
+		"CrossValidationConfusedPairs":        "易混淆意圖",
+		"CrossValidationPrecision":            "精確率",
+		"CrossValidationRecall":               "召回率",
+		"CrossValidationF1":                   "F1",
+		"CrossValidationSupport":              "語料數",
+		"CrossValidationMacroAverage":         "宏平均",
+		"CrossValidationAccuracy":             "準確率",
+		"CrossValidationPredicted":            "預測意圖",
+		"CrossValidationCount":                "次數",
+		"CrossValidationNoIntent":             "無意圖",
+	},
+	EnUs: map[string]string{
+		"IntentTestSentences":            "Test Sentences of Intent Tests",
+		"IntentName":                     "Intent Name",
+		"TestSentence":                   "Test Sentence",
+		"UploadIntentTests":              "Upload Intent Tests",
+		"ExportIntentTests":              "Export Intent Tests",
+		"TestNotFoundError":              "Intent test is not found",
+		"TestIntentNotFoundError":        "Intent is not found",
+		"PreviousTestStillRunning":       "Previous intent test is still running",
+		"IntentTestUploadSheetFormatErr": "Invalid format of uploaded sheet",
+		"IntentTestUploadNoHeaderTpl":    "Headers are missing in uploaded sheet",
+		"IntestTestSentenceEmptyErr":     "Test sentence must not be empty",
+		"NegativeTestIntentName":         "Negative Test Intent",
+
+		"CrossValidationNoSentence":           "No intent corpus for cross validation",
+		"PreviousCrossValidationStillRunning": "Previous cross validation is still running",
+		"CrossValidationMetrics":              "Intent Metrics",
+		"CrossValidationConfusionMatrix":      "Confusion Matrix",
+		"CrossValidationConfusedPairs":        "Confused Intents",
+		"CrossValidationPrecision":            "Precision",
+		"CrossValidationRecall":               "Recall",
+		"CrossValidationF1":                   "F1",
+		"CrossValidationSupport":              "Support",
+		"CrossValidationMacroAverage":         "Macro Average",
+		"CrossValidationAccuracy":             "Accuracy",
+		"CrossValidationPredicted":            "Predicted Intent",
+		"CrossValidationCount":                "Count",
+		"CrossValidationNoIntent":             "No Intent",
 	},
 }
@@ -1,10 +1,20 @@
 package localemsg
 
-import "emotibot.com/emotigo/pkg/logger"
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
 
 const (
 	ZhCn = "zh-cn"
 	ZhTw = "zh-tw"
+	EnUs = "en-us"
+
+	// DefaultLocale is used when message is missing in requested locale
+	DefaultLocale = ZhCn
 )
 
 var localeMsg = map[string]map[string]string{
@@ -16,11 +26,18 @@ var localeMsg = map[string]map[string]string{
 		"Success": "成功",
 		"Fail":    "失敗",
 	},
+	EnUs: map[string]string{
+		"Success": "Success",
+		"Fail":    "Failed",
+	},
 }
 
+// missingWarned records missing keys which have been logged, so each key is logged once
+var missingWarned = sync.Map{}
+
 func init() {
 	allMsg := []map[string]map[string]string{
-		intentMsg, intentTestMsg, auditMsg, dictionaryMsg, statsMsg, taskengineMsg, recordsMsg, sessionsMsg, customChatMsg,cmdMsg,
+		intentMsg, intentTestMsg, auditMsg, dictionaryMsg, statsMsg, taskengineMsg, recordsMsg, sessionsMsg, customChatMsg,cmdMsg, importPreviewMsg, robotConfigMsg,
 	}
 
 	// merge all module lang map
@@ -34,16 +51,53 @@ func init() {
 			}
 		}
 	}
+
+	setCatalog(builtinCatalog())
 }
 
+// Get returns message of key in locale. Message in DefaultLocale is returned
+// if locale is not supported or key is missing in locale.
 func Get(locale string, key string) string {
-	if _, ok := localeMsg[locale]; !ok {
-		return localeMsg[ZhCn][key]
+	return lookup(locale, key, pluralOther)
+}
+
+// Format returns message of key in locale formatted with args. Messages use verbs of fmt,
+// and translations may reorder arguments with explicit index, such as %[2]s.
+func Format(locale string, key string, args ...interface{}) string {
+	return format(Get(locale, key), args)
+}
+
+// Plural returns the plural form of message for count, formatted with args.
+// count is used as the only argument if args is not given.
+func Plural(locale string, key string, count int, args ...interface{}) string {
+	if len(args) == 0 {
+		args = []interface{}{count}
+	}
+	return format(lookup(locale, key, pluralCategory(locale, count)), args)
+}
+
+func format(tpl string, args []interface{}) string {
+	// Forms without verbs, such as "one file", are used as is
+	if len(args) == 0 || !strings.Contains(tpl, "%") {
+		return tpl
+	}
+	return fmt.Sprintf(tpl, args...)
+}
+
+func lookup(locale string, key string, category string) string {
+	c := getCatalog()
+	if msgs, ok := c.messages[locale]; ok {
+		if msg, ok := msgs[key]; ok {
+			return msg.form(category)
+		}
+		if _, warned := missingWarned.LoadOrStore(locale+"/"+key, true); !warned {
+			logger.Warn.Printf("Key [%s] in locale [%s] is not existed, use locale [%s]\n", key, locale, DefaultLocale)
+		}
 	}
 
-	if _, ok := localeMsg[locale][key]; !ok {
-		logger.Trace.Printf("Key [%s] in locale [%s] is not existed\n", key, locale)
-		return ""
+	if msg, ok := c.messages[DefaultLocale][key]; ok {
+		return msg.form(category)
 	}
-	return localeMsg[locale][key]
+	logger.Trace.Printf("Key [%s] is not existed in any locale\n", key)
+	return ""
 }
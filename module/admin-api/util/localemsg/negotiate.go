@@ -0,0 +1,112 @@
+package localemsg
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// localeAliases maps language tags to supported locales, which are not matched by language only
+var localeAliases = map[string]string{
+	"zh":      ZhCn,
+	"zh-hans": ZhCn,
+	"zh-sg":   ZhCn,
+	"zh-hant": ZhTw,
+	"zh-hk":   ZhTw,
+	"zh-mo":   ZhTw,
+}
+
+// Locales returns supported locales in order, including locales loaded from catalogs
+func Locales() []string {
+	c := getCatalog()
+	locales := []string{}
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// IsSupported returns if there are messages of locale
+func IsSupported(locale string) bool {
+	_, ok := getCatalog().messages[locale]
+	return ok
+}
+
+// normalizeLocale converts tags like zh_TW and en-US to the form of locales, such as zh-tw
+func normalizeLocale(tag string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(tag), "_", "-", -1))
+}
+
+// Match returns supported locale of language tag, empty string is returned if no locale matches.
+// Script and region are ignored if they are not supported, so en-GB matches en-us.
+func Match(tag string) string {
+	tag = normalizeLocale(tag)
+	if tag == "" || tag == "*" {
+		return ""
+	}
+	if IsSupported(tag) {
+		return tag
+	}
+
+	// Try tag without region and script one by one, like zh-hant-tw, zh-hant and zh
+	for parts := strings.Split(tag, "-"); len(parts) > 0; parts = parts[:len(parts)-1] {
+		prefix := strings.Join(parts, "-")
+		if locale, ok := localeAliases[prefix]; ok && IsSupported(locale) {
+			return locale
+		}
+	}
+
+	language := strings.Split(tag, "-")[0]
+	for _, locale := range Locales() {
+		if strings.Split(locale, "-")[0] == language {
+			return locale
+		}
+	}
+	return ""
+}
+
+// Negotiate returns supported locale which matches Accept-Language header best,
+// empty string is returned if no locale matches
+func Negotiate(acceptLanguage string) string {
+	type languageRange struct {
+		tag     string
+		quality float64
+	}
+
+	ranges := []languageRange{}
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		params := strings.Split(item, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil {
+				q = 0
+			}
+			quality = q
+		}
+		// Quality 0 means not acceptable
+		if quality <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, quality: quality})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	for _, r := range ranges {
+		if locale := Match(r.tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
@@ -81,4 +81,44 @@ var recordsMsg = map[string]map[string]string{
 		"threshold":      "出話閾值",
 		"respondTime":    "響應時間",
 	},
+	EnUs: map[string]string{
+		// Modules
+		"backfill":    "Backfill",
+		"chat":        "Chat",
+		"keyword":     "Other",
+		"function":    "Robot Skill",
+		"faq":         "FAQ",
+		"task_engine": "Task Engine",
+		"to_human":    "Transfer to Human",
+		"knowledge":   "Knowledge",
+		"domain_kg":   "Knowledge Graph",
+		"command":     "Robot Words",
+		"emotion":     "Emotion Words",
+
+		// Export records
+		"unique_id":      "Record ID",
+		"sessionId":      "Session ID",
+		"taskEngineId":   "Task Engine ID",
+		"userId":         "User ID",
+		"userQ":          "User Question",
+		"FAQ":            "Standard Question",
+		"robotAnswer":    "Robot Answer",
+		"robotRawAnswer": "Robot Answer JSON",
+		"matchScore":     "Match Score",
+		"module":         "Module",
+		"source":         "Source",
+		"logTime":        "Time",
+		"emotionCol":     "Emotion",
+		"emotionScore":   "Emotion Score",
+		"intent":         "Intent",
+		"intentScore":    "Intent Score",
+		"customInfo":     "Custom Info",
+		"FAQCategory":    "FAQ Category",
+		"FAQLabel":       "FAQ Label",
+		"feedback":       "Feedback",
+		"customFeedback": "Feedback Text",
+		"feedbackTime":   "Feedback Time",
+		"threshold":      "Threshold",
+		"respondTime":    "Response Time",
+	},
 }
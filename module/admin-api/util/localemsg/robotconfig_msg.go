@@ -0,0 +1,16 @@
+package localemsg
+
+var robotConfigMsg = map[string]map[string]string{
+	ZhCn: map[string]string{
+		"RobotConfigInvalidValue":   "设定 %s 的值无效: %s",
+		"RobotConfigModuleMismatch": "设定所属模组不符",
+	},
+	ZhTw: map[string]string{
+		"RobotConfigInvalidValue":   "設定 %s 的值無效: %s",
+		"RobotConfigModuleMismatch": "設定所屬模組不符",
+	},
+	EnUs: map[string]string{
+		"RobotConfigInvalidValue":   "Invalid value of config %s: %s",
+		"RobotConfigModuleMismatch": "Module of config mismatches",
+	},
+}
@@ -26,4 +26,17 @@ var sessionsMsg = map[string]map[string]string{
 		"customFeedback": "反饋文字",
 		"feedbackTime":   "反饋時間",
 	},
+	EnUs: map[string]string{
+		// Export records
+		"sessionId":      "Session ID",
+		"startTime":      "Start Time",
+		"endTime":        "End Time",
+		"userId":         "User ID",
+		"rating":         "Rating",
+		"customInfo":     "Custom Info",
+		"robotAnswer":    "Robot Answer",
+		"feedback":       "Feedback",
+		"customFeedback": "Feedback Text",
+		"feedbackTime":   "Feedback Time",
+	},
 }
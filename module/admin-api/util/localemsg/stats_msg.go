@@ -41,4 +41,24 @@ var statsMsg = map[string]map[string]string{
 		"ChatCate":  "聊天類",
 		"OtherCate": "其他",
 	},
+	EnUs: map[string]string{
+		// Modules
+		"StatsItem":            "Item",
+		"TotalChatCount":       "Total Sessions",
+		"IndependentUserCount": "Unique Users",
+		"AddedUserCount":       "New Users",
+		"TotalQCount":          "Total Questions",
+		"StandardResponse":     "Standard Response",
+		"Chat":                 "Chat",
+		"Other":                "Other",
+		"BackfillResponse":     "Backfill Response",
+		"Unresolved":           "Unresolved",
+		"SuccessRate":          "Success Rate",
+		"ResolveRate":          "Resolve Rate",
+		"AverageChatCount":     "Average Messages per Session",
+
+		"BizCate":   "Business",
+		"ChatCate":  "Chat",
+		"OtherCate": "Other",
+	},
 }
@@ -0,0 +1,65 @@
+package localemsg
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// tables are maps keyed by locale out of message catalogs, such as headers of exported sheets
+var tables = map[string]interface{}{}
+var tablesLock = sync.Mutex{}
+
+// RegisterTable registers table which is a map keyed by locale, so locales missing
+// in the table are reported by MissingTables
+func RegisterTable(name string, table interface{}) {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+	tables[name] = table
+}
+
+// TableLocale returns locale if it is in table, otherwise DefaultLocale is returned,
+// so locales added by catalogs get the table of DefaultLocale
+func TableLocale(table interface{}, locale string) string {
+	value := reflect.ValueOf(table)
+	if value.Kind() == reflect.Map && value.MapIndex(reflect.ValueOf(locale)).IsValid() {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// MissingTables returns locales missing in registered tables, keyed by name of table.
+// Locale whose entries are not as many as entries of DefaultLocale is also missing.
+func MissingTables() map[string][]string {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+
+	ret := map[string][]string{}
+	for name, table := range tables {
+		value := reflect.ValueOf(table)
+		if value.Kind() != reflect.Map {
+			continue
+		}
+		dft := value.MapIndex(reflect.ValueOf(DefaultLocale))
+		missing := []string{}
+		for _, locale := range Locales() {
+			entries := value.MapIndex(reflect.ValueOf(locale))
+			if !entries.IsValid() || (dft.IsValid() && entryCount(entries) != entryCount(dft)) {
+				missing = append(missing, locale)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			ret[name] = missing
+		}
+	}
+	return ret
+}
+
+func entryCount(entries reflect.Value) int {
+	switch entries.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return entries.Len()
+	}
+	return 1
+}
@@ -9,4 +9,8 @@ var taskengineMsg = map[string]map[string]string{
 		"TaskEngineWordbank": "任務引擎詞庫",
 		"TaskEngineScenario": "任務引擎場景",
 	},
+	EnUs: map[string]string{
+		"TaskEngineWordbank": "Task Engine Wordbank",
+		"TaskEngineScenario": "Task Engine Scenario",
+	},
 }
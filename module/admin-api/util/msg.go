@@ -93,6 +93,7 @@ var (
 		"ErrorSimilarTooLongTpl": "行 %d: 同义词超过64字",
 		"ErrorPathTooLongTpl":    "行 %d: 目录名超过20字",
 		"ErrorRowErrorTpl":       "行 %d：%s",
+		"ErrorWordbankConflict":  "词库中存在重复词、同义词串联或敏感词冲突",
 		"ErrorPathLevelTpl":      "路径 %d 级内容错误",
 		"ErrorNotEditable":       "该词库不可编辑",
 		"ErrorRequestErrorTpl":   "传入参数有误",
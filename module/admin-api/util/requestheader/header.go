@@ -18,9 +18,12 @@ const (
 	// ConstUserIPHeaderKey is header record the userip
 	ConstUserIPHeaderKey = "X-Real-IP"
 
-	// ConstLocaleHeaderKey is header record the request locale, which may be zh-cn or zh-tw
+	// ConstLocaleHeaderKey is header record the request locale, which may be zh-cn, zh-tw or en-us
 	ConstLocaleHeaderKey = "X-Locale"
-	defaultLocale        = localemsg.ZhCn
+	defaultLocale        = localemsg.DefaultLocale
+
+	// ConstAcceptLanguageHeaderKey is standard header of browser, which is used if locale is not specified
+	ConstAcceptLanguageHeaderKey = "Accept-Language"
 
 	ConstEnterpriseIDHeaderKey = "X-EnterpriseID"
 	ConstAppIDHeaderKey        = "X-AppID"
@@ -72,20 +75,19 @@ func GetUserIP(r *http.Request) string {
 	return r.Header.Get(ConstUserIPHeaderKey)
 }
 
+// GetLocale will get supported locale of request from X-Locale header, locale in query
+// and Accept-Language header in order, default locale is returned if none matches
 func GetLocale(r *http.Request) string {
-	locale := r.Header.Get(ConstLocaleHeaderKey)
-	if locale != localemsg.ZhCn && locale != localemsg.ZhTw {
-		locale = ""
+	if locale := localemsg.Match(r.Header.Get(ConstLocaleHeaderKey)); locale != "" {
+		return locale
 	}
-	if locale != "" {
+	if locale := localemsg.Match(r.URL.Query().Get("locale")); locale != "" {
 		return locale
 	}
-
-	locale = r.URL.Query().Get("locale")
-	if locale == "" {
-		locale = defaultLocale
+	if locale := localemsg.Negotiate(r.Header.Get(ConstAcceptLanguageHeaderKey)); locale != "" {
+		return locale
 	}
-	return locale
+	return defaultLocale
 }
 
 // SetEnterprise will set EnterpriseID to http header
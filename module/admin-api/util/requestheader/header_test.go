@@ -0,0 +1,34 @@
+package requestheader
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetLocale(t *testing.T) {
+	cases := []struct {
+		url            string
+		locale         string
+		acceptLanguage string
+		expect         string
+	}{
+		{url: "/", expect: "zh-cn"},
+		{url: "/", locale: "zh-TW", acceptLanguage: "en-US", expect: "zh-tw"},
+		{url: "/?locale=en-us", acceptLanguage: "zh-TW", expect: "en-us"},
+		{url: "/?locale=fr", acceptLanguage: "fr-FR,en;q=0.5", expect: "en-us"},
+		{url: "/", locale: "unknown", acceptLanguage: "zh-HK", expect: "zh-tw"},
+		{url: "/", acceptLanguage: "fr-FR", expect: "zh-cn"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.url, nil)
+		if c.locale != "" {
+			r.Header.Set(ConstLocaleHeaderKey, c.locale)
+		}
+		if c.acceptLanguage != "" {
+			r.Header.Set(ConstAcceptLanguageHeaderKey, c.acceptLanguage)
+		}
+		if locale := GetLocale(r); locale != c.expect {
+			t.Errorf("Expect locale %s of %+v, get %s", c.expect, c, locale)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package rolloutrule stores the gradual rollout rules of switches and robot functions,
+// and publishes the resolved rules of an app to consul.
+package rolloutrule
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/pkg/logger"
+	"emotibot.com/emotigo/pkg/misc/rollout"
+)
+
+// Types of rollout rule, which is also part of consul key
+const (
+	TypeSwitch   = "switch"
+	TypeFunction = "function"
+)
+
+// GetRules returns rules of ruleType in appid, key is the code of switch or function
+func GetRules(appid, ruleType string) (map[string]*rollout.Rule, error) {
+	mySQL := util.GetMainDB()
+	if mySQL == nil {
+		return nil, errors.New("DB not init")
+	}
+
+	queryStr := "SELECT code, rule FROM rollout_rules WHERE appid = ? AND type = ?"
+	rows, err := mySQL.Query(queryStr, appid, ruleType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := map[string]*rollout.Rule{}
+	for rows.Next() {
+		var code, ruleStr string
+		if err := rows.Scan(&code, &ruleStr); err != nil {
+			return nil, err
+		}
+		rule := &rollout.Rule{}
+		if err := json.Unmarshal([]byte(ruleStr), rule); err != nil {
+			logger.Warn.Printf("Invalid rollout rule of %s %s: %s\n", ruleType, code, err.Error())
+			continue
+		}
+		ret[code] = rule
+	}
+	return ret, rows.Err()
+}
+
+// GetRule returns rule of the code, nil if no rule is set
+func GetRule(appid, ruleType, code string) (*rollout.Rule, error) {
+	rules, err := GetRules(appid, ruleType)
+	if err != nil {
+		return nil, err
+	}
+	return rules[code], nil
+}
+
+// SetRule saves rule of the code, rule will be removed if it is nil
+func SetRule(appid, ruleType, code string, rule *rollout.Rule) error {
+	mySQL := util.GetMainDB()
+	if mySQL == nil {
+		return errors.New("DB not init")
+	}
+
+	if rule == nil {
+		_, err := mySQL.Exec("DELETE FROM rollout_rules WHERE appid = ? AND type = ? AND code = ?",
+			appid, ruleType, code)
+		return err
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	ruleStr, _ := json.Marshal(rule)
+	queryStr := `
+		INSERT INTO rollout_rules (appid, type, code, rule, update_time) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE rule = VALUES(rule), update_time = VALUES(update_time)`
+	_, err := mySQL.Exec(queryStr, appid, ruleType, code, string(ruleStr), time.Now().Unix())
+	return err
+}
+
+// Resolve combines on/off status with rules, status key is the code of switch or function
+func Resolve(status map[string]bool, rules map[string]*rollout.Rule) rollout.Features {
+	features := rollout.Features{}
+	for code, enabled := range status {
+		features[code] = &rollout.Feature{
+			Enabled: enabled,
+			Rule:    rules[code],
+		}
+	}
+	return features
+}
+
+// Publish resolves the rules with status and updates consul
+func Publish(appid, ruleType string, status map[string]bool, change *util.ConsulChange) error {
+	rules, err := GetRules(appid, ruleType)
+	if err != nil {
+		return err
+	}
+	ret, err := util.ConsulUpdateRollout(ruleType, appid, Resolve(status, rules), change)
+	if err != nil {
+		logger.Info.Printf("Update consul result: %d, %s", ret, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// NewWebhookClient returns a client which refuses to connect to internal addresses,
+// which are checked again at dialing in case the host is resolved differently.
+// Proxy is not used, otherwise only the address of proxy is checked at dialing.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: (&net.Dialer{
+				Timeout: time.Duration(5) * time.Second,
+				Control: func(network string, address string, c syscall.RawConn) error {
+					host, _, err := net.SplitHostPort(address)
+					if err != nil {
+						return err
+					}
+					if ip := net.ParseIP(host); ip == nil || IsInternalIP(ip) {
+						return fmt.Errorf("webhook cannot connect to internal address %s", host)
+					}
+					return nil
+				},
+			}).DialContext,
+		},
+	}
+}
+
+// CheckWebhookHost rejects host which is or resolves to an internal address
+func CheckWebhookHost(host string) error {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("invalid webhook host %s: %v", host, err)
+		}
+	}
+	for _, ip := range ips {
+		if IsInternalIP(ip) {
+			return fmt.Errorf("webhook host %s is an internal address", host)
+		}
+	}
+	return nil
+}
+
+func IsInternalIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		// 100.64.0.0/10 is shared address space of carrier-grade NAT
+		if ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+			return true
+		}
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified()
+}
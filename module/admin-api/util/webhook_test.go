@@ -0,0 +1,28 @@
+package util
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	testTable := map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.16.0.1":      true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00::1":         true,
+		"8.8.8.8":         false,
+		"100.128.0.1":     false,
+		"2001:4860::8888": false,
+	}
+	for ip, expected := range testTable {
+		if IsInternalIP(net.ParseIP(ip)) != expected {
+			t.Errorf("%s: expect internal %t", ip, expected)
+		}
+	}
+}
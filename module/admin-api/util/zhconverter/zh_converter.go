@@ -3,32 +3,45 @@ package zhconverter
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"os"
-    "strings"
+	"path/filepath"
+	"strings"
 
 	"emotibot.com/emotigo/pkg/logger"
 )
 
-const t2sDictFile = "./InitFiles/zh_converter/zh-tw2zh-cn.properties"
-const s2tDictFile = "./InitFiles/zh_converter/zh-cn2zh-tw.properties"
+const dictDir = "./InitFiles/zh_converter"
+const t2sDictName = "zh-tw2zh-cn.properties"
+const s2tDictName = "zh-cn2zh-tw.properties"
 
 var t2sMapping = make(map[string]string)
 var s2tMapping = make(map[string]string)
 
 func init() {
 	logger.Error.Println("Start loading zh-converter dictionaries")
+	if err := LoadDictionaries(dictDir); err != nil {
+		logger.Error.Println(err.Error())
+		return
+	}
+	logger.Error.Println("zh-converter dictionaries load completed")
+}
+
+// LoadDictionaries loads the zh-tw to zh-cn and zh-cn to zh-tw dictionaries in dir,
+// which is used when dictionaries are not in the default directory, such as in tests
+func LoadDictionaries(dir string) error {
+	t2sDictFile := filepath.Join(dir, t2sDictName)
+	s2tDictFile := filepath.Join(dir, s2tDictName)
 
 	t2sDict, err := os.Open(t2sDictFile)
 	if err != nil {
-		logger.Error.Printf("Load %s failed: %s\n", t2sDictFile, err.Error())
-		return
+		return fmt.Errorf("Load %s failed: %s", t2sDictFile, err.Error())
 	}
 	defer t2sDict.Close()
 
 	s2tDict, err := os.Open(s2tDictFile)
 	if err != nil {
-		logger.Error.Printf("Load %s failed: %s\n", s2tDictFile, err.Error())
-		return
+		return fmt.Errorf("Load %s failed: %s", s2tDictFile, err.Error())
 	}
 	defer s2tDict.Close()
 
@@ -71,8 +84,7 @@ func init() {
 		tw := dict[1]
 		s2tMapping[cn] = tw
 	}
-
-	logger.Error.Println("zh-converter dictionaries load completed")
+	return nil
 }
 
 // Convert Traditional Chinese to Simplified Chinese
@@ -0,0 +1,284 @@
+package manual
+
+import (
+	"net/http"
+	"strconv"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type CalibrationInReq struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	FullScore   int      `json:"full_score"`
+	Outlines    []int64  `json:"outline_ids"`
+	Calls       []int64  `json:"call_ids"`
+	Reviewers   []string `json:"reviewer_ids"`
+}
+
+type CalibrationInRes struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Creator     string   `json:"creator"`
+	Status      int8     `json:"status"`
+	CreateTime  int64    `json:"create_time"`
+	FullScore   int      `json:"full_score"`
+	Outlines    []int64  `json:"outline_ids"`
+	Calls       []int64  `json:"call_ids"`
+	Reviewers   []string `json:"reviewer_ids"`
+}
+
+type CalibrationScoreInRes struct {
+	CallID    int64  `json:"call_id"`
+	Reviewer  string `json:"reviewer"`
+	OutlineID int64  `json:"outline_id"`
+	Score     int    `json:"score"`
+	Consensus bool   `json:"consensus"`
+}
+
+type CalibrationScoresInReq struct {
+	Scores []struct {
+		OutlineID int64 `json:"outline_id"`
+		Score     int   `json:"score"`
+	} `json:"scores"`
+}
+
+func calibrationToCalibrationInRes(c *model.Calibration) *CalibrationInRes {
+	return &CalibrationInRes{
+		ID:          c.ID,
+		Name:        c.Name,
+		Description: c.Description,
+		Creator:     c.Creator,
+		Status:      c.Status,
+		CreateTime:  c.CreateTime * 1000, // second to milisecond
+		FullScore:   c.FullScore,
+		Outlines:    c.Outlines,
+		Calls:       c.Calls,
+		Reviewers:   c.Reviewers,
+	}
+}
+
+// calibrationRequest loads the user and the calibration in the path for the handler
+func calibrationRequest(next func(w http.ResponseWriter, r *http.Request, user *model.Staff, c *model.Calibration)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := requestheader.GetUserID(r)
+		user, err := GetUser(userID)
+		if err != nil {
+			logger.Error.Printf("error while get user in calibrationRequest, reason: %s", err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if user == nil {
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(general.ParseID(r), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c, err := GetCalibration(id, requestheader.GetEnterpriseID(r))
+		if err != nil {
+			logger.Error.Printf("error while get calibration in calibrationRequest, reason: %s", err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if c == nil || (user.Type != ADMIN_USER && !containsString(c.Reviewers, userID)) {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r, user, c)
+	}
+}
+
+func handleCreateCalibration(w http.ResponseWriter, r *http.Request) {
+	userID := requestheader.GetUserID(r)
+	user, err := GetUser(userID)
+	if err != nil {
+		logger.Error.Printf("error while get user in handleCreateCalibration, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil || user.Type != ADMIN_USER {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	inreq := CalibrationInReq{}
+	err = util.ReadJSON(r, &inreq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c := &model.Calibration{
+		Name:        inreq.Name,
+		Description: inreq.Description,
+		Enterprise:  requestheader.GetEnterpriseID(r),
+		Creator:     userID,
+		FullScore:   inreq.FullScore,
+		Outlines:    inreq.Outlines,
+		Calls:       inreq.Calls,
+		Reviewers:   inreq.Reviewers,
+	}
+
+	id, err := CreateCalibration(c)
+	if err == ErrCalibrationSetting || err == ErrCalibrationTarget {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		logger.Error.Printf("error while create calibration in handleCreateCalibration, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		ID int64 `json:"id"`
+	}
+
+	util.WriteJSON(w, Response{ID: id})
+}
+
+func handleGetCalibrations(w http.ResponseWriter, r *http.Request) {
+	userID := requestheader.GetUserID(r)
+	user, err := GetUser(userID)
+	if err != nil {
+		logger.Error.Printf("error while get user in handleGetCalibrations, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	filter := &model.CalibrationFilter{
+		Enterprise: requestheader.GetEnterpriseID(r),
+	}
+	// reviewers only get the calibrations assigned to them
+	if user.Type != ADMIN_USER {
+		filter.Reviewers = []string{userID}
+	}
+
+	calibrations, err := GetCalibrations(filter)
+	if err != nil {
+		logger.Error.Printf("error while get calibrations in handleGetCalibrations, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]*CalibrationInRes, len(calibrations))
+	for idx := range calibrations {
+		response[idx] = calibrationToCalibrationInRes(&calibrations[idx])
+	}
+	util.WriteJSON(w, response)
+}
+
+func handleGetCalibration(w http.ResponseWriter, r *http.Request, user *model.Staff, c *model.Calibration) {
+	scores, err := GetCalibrationScores(c, user.UUID, user.Type == ADMIN_USER)
+	if err != nil {
+		logger.Error.Printf("error while get calibration scores in handleGetCalibration, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		*CalibrationInRes
+		Scores []CalibrationScoreInRes `json:"scores"`
+	}
+
+	response := Response{
+		CalibrationInRes: calibrationToCalibrationInRes(c),
+		Scores:           make([]CalibrationScoreInRes, len(scores)),
+	}
+	for idx, s := range scores {
+		response.Scores[idx] = CalibrationScoreInRes{
+			CallID:    s.CallID,
+			Reviewer:  s.StaffID,
+			OutlineID: s.OutlineID,
+			Score:     s.Score,
+			Consensus: s.Consensus == 1,
+		}
+	}
+	util.WriteJSON(w, response)
+}
+
+// handleSubmitCalibrationScores records the scores of the reviewer,
+// or the consensus scores if the path is consensus which is only allowed for admin
+func handleSubmitCalibrationScores(w http.ResponseWriter, r *http.Request, user *model.Staff, c *model.Calibration) {
+	consensus := mux.Vars(r)["kind"] == "consensus"
+	if consensus && user.Type != ADMIN_USER {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	callID, err := strconv.ParseInt(mux.Vars(r)["call_id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inreq := CalibrationScoresInReq{}
+	err = util.ReadJSON(r, &inreq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scores := map[int64]int{}
+	for _, s := range inreq.Scores {
+		scores[s.OutlineID] = s.Score
+	}
+
+	err = SubmitCalibrationScores(c, user.UUID, callID, scores, consensus)
+	switch err {
+	case nil:
+	case ErrCalibrationClosed, ErrNotReviewer, ErrNotCalibrationCall, ErrIncompleteScores:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Error.Printf("error while submit calibration scores in handleSubmitCalibrationScores, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func handleGetCalibrationReport(w http.ResponseWriter, r *http.Request, user *model.Staff, c *model.Calibration) {
+	// reviewers can not see the others until the calibration is closed
+	if user.Type != ADMIN_USER && c.Status == model.CalibrationOpen {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := GetCalibrationReport(c)
+	if err != nil {
+		logger.Error.Printf("error while get calibration report in handleGetCalibrationReport, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	util.WriteJSON(w, report)
+}
+
+func handleCloseCalibration(w http.ResponseWriter, r *http.Request, user *model.Staff, c *model.Calibration) {
+	if user.Type != ADMIN_USER {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	err := CloseCalibration(c)
+	if err != nil {
+		logger.Error.Printf("error while close calibration in handleCloseCalibration, reason: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
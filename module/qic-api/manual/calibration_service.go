@@ -0,0 +1,495 @@
+package manual
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+)
+
+var (
+	ErrCalibrationSetting = fmt.Errorf("A calibration needs at least one call, one outline, two reviewers and a positive full score")
+	ErrCalibrationTarget  = fmt.Errorf("The calls and outlines of a calibration should exist in the enterprise")
+	ErrCalibrationClosed  = fmt.Errorf("The calibration is closed")
+	ErrNotReviewer        = fmt.Errorf("The user is not the reviewer of the calibration")
+	ErrNotCalibrationCall = fmt.Errorf("The call is not in the calibration")
+	ErrIncompleteScores   = fmt.Errorf("Every outline of the calibration should be scored")
+)
+
+var calibrationDao model.CalibrationDao = &model.CalibrationSqlDao{}
+var creditDao model.CreditDao = &model.CreditSQLDao{}
+var callDao model.CallDao = &model.CallSQLDao{}
+
+// machine call credit type, which is the root credit of a call
+const machineCallCredit = 0
+
+// machineFullScore is the base score of a machine call credit
+const machineFullScore = 100
+
+// Agreement is the inter-rater agreement of the subjects scored by all reviewers
+type Agreement struct {
+	Subjects    int     `json:"subjects"`
+	Observed    float64 `json:"observed_agreement"`
+	FleissKappa float64 `json:"fleiss_kappa"`
+	CohenKappa  float64 `json:"cohen_kappa"`
+}
+
+type ItemAgreement struct {
+	OutlineID int64 `json:"outline_id"`
+	Agreement
+}
+
+// ReviewerReport compares a reviewer to the consensus and the machine
+type ReviewerReport struct {
+	Reviewer string `json:"reviewer"`
+	Scored   int    `json:"scored_calls"`
+	// ConsensusAgreement is the ratio of the outline scores equals to the consensus one
+	ConsensusAgreement *float64 `json:"consensus_agreement"`
+	// MachineDiff is the average absolute difference between the call total score and the machine score,
+	// both are taken as the ratio of their full score
+	MachineDiff *float64 `json:"machine_diff"`
+}
+
+type CallComparison struct {
+	CallID         int64          `json:"call_id"`
+	MachineScore   *int           `json:"machine_score"`
+	ConsensusScore *int           `json:"consensus_score"`
+	ReviewerScores map[string]int `json:"reviewer_scores"`
+}
+
+type CalibrationReport struct {
+	Overall   Agreement        `json:"overall"`
+	Items     []ItemAgreement  `json:"items"`
+	Reviewers []ReviewerReport `json:"reviewers"`
+	Calls     []CallComparison `json:"calls"`
+}
+
+func CreateCalibration(c *model.Calibration) (id int64, err error) {
+	if c == nil {
+		err = ErrCalibrationSetting
+		return
+	}
+	c.Outlines = uniqueInt64(c.Outlines)
+	c.Calls = uniqueInt64(c.Calls)
+	c.Reviewers = uniqueString(c.Reviewers)
+	if len(c.Outlines) == 0 || len(c.Calls) == 0 || len(c.Reviewers) < 2 || c.FullScore <= 0 {
+		err = ErrCalibrationSetting
+		return
+	}
+
+	err = checkCalibrationTarget(c)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	c.Status = model.CalibrationOpen
+	c.CreateTime = now
+	c.UpdateTime = now
+
+	tx, err := manualDB.Begin()
+	if err != nil {
+		return
+	}
+	defer manualDB.ClearTransition(tx)
+
+	id, err = calibrationDao.Create(c, tx)
+	if err != nil {
+		return
+	}
+	err = manualDB.Commit(tx)
+	return
+}
+
+// checkCalibrationTarget gives ErrCalibrationTarget if any call is not in the enterprise of the calibration
+// or any outline does not exist
+func checkCalibrationTarget(c *model.Calibration) error {
+	conn := manualDB.Conn()
+	count, err := callDao.Count(conn, model.CallQuery{
+		ID:           c.Calls,
+		EnterpriseID: &c.Enterprise,
+	})
+	if err != nil {
+		return err
+	}
+	if count != int64(len(c.Calls)) {
+		return ErrCalibrationTarget
+	}
+
+	outlines, err := taskDao.Outlines(conn)
+	if err != nil {
+		return err
+	}
+	exists := map[int64]bool{}
+	for _, outline := range outlines {
+		exists[outline.ID] = true
+	}
+	for _, outline := range c.Outlines {
+		if !exists[outline] {
+			return ErrCalibrationTarget
+		}
+	}
+	return nil
+}
+
+func GetCalibrations(filter *model.CalibrationFilter) ([]model.Calibration, error) {
+	return calibrationDao.GetBy(filter, manualDB.Conn())
+}
+
+// GetCalibration gives nil if the calibration does not exist in the enterprise
+func GetCalibration(id int64, enterprise string) (*model.Calibration, error) {
+	filter := &model.CalibrationFilter{
+		ID:         []int64{id},
+		Enterprise: enterprise,
+	}
+	calibrations, err := calibrationDao.GetBy(filter, manualDB.Conn())
+	if err != nil || len(calibrations) == 0 {
+		return nil, err
+	}
+	return &calibrations[0], nil
+}
+
+// GetCalibrationScores gives the scores of the calibration,
+// reviewers only see their own scores until the calibration is closed, which keeps the scoring blind.
+func GetCalibrationScores(c *model.Calibration, staffID string, isAdmin bool) ([]model.CalibrationScore, error) {
+	filter := &model.CalibrationScoreFilter{
+		CalibrationID: []int64{c.ID},
+	}
+	if !isAdmin && c.Status == model.CalibrationOpen {
+		var reviewer int8
+		filter.StaffIDs = []string{staffID}
+		filter.Consensus = &reviewer
+	}
+	return calibrationDao.Scores(filter, manualDB.Conn())
+}
+
+// SubmitCalibrationScores records the outline scores of the call by the reviewer,
+// if consensus is true the scores are recorded as the reference of the call.
+func SubmitCalibrationScores(c *model.Calibration, staffID string, callID int64, scores map[int64]int, consensus bool) error {
+	if c.Status != model.CalibrationOpen {
+		return ErrCalibrationClosed
+	}
+	if !consensus && !containsString(c.Reviewers, staffID) {
+		return ErrNotReviewer
+	}
+	if !containsInt64(c.Calls, callID) {
+		return ErrNotCalibrationCall
+	}
+	if len(scores) != len(c.Outlines) {
+		return ErrIncompleteScores
+	}
+
+	var consensusFlag int8
+	if consensus {
+		consensusFlag = 1
+	}
+	now := time.Now().Unix()
+	records := make([]model.CalibrationScore, 0, len(scores))
+	for _, outline := range c.Outlines {
+		score, ok := scores[outline]
+		if !ok {
+			return ErrIncompleteScores
+		}
+		records = append(records, model.CalibrationScore{
+			CalibrationID: c.ID,
+			CallID:        callID,
+			StaffID:       staffID,
+			OutlineID:     outline,
+			Score:         score,
+			Consensus:     consensusFlag,
+			CreateTime:    now,
+		})
+	}
+
+	tx, err := manualDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer manualDB.ClearTransition(tx)
+
+	err = calibrationDao.SetScores(records, tx)
+	if err != nil {
+		return err
+	}
+	return manualDB.Commit(tx)
+}
+
+func CloseCalibration(c *model.Calibration) error {
+	return calibrationDao.UpdateStatus(c.ID, model.CalibrationClosed, manualDB.Conn())
+}
+
+// GetCalibrationReport gives the agreement between reviewers, consensus and machine credits
+func GetCalibrationReport(c *model.Calibration) (*CalibrationReport, error) {
+	conn := manualDB.Conn()
+	scores, err := calibrationDao.Scores(&model.CalibrationScoreFilter{CalibrationID: []int64{c.ID}}, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	callIDs := make([]uint64, len(c.Calls))
+	for idx, call := range c.Calls {
+		callIDs[idx] = uint64(call)
+	}
+	credits, err := creditDao.GetCallCredit(conn, &model.CreditQuery{Calls: callIDs, Type: []int{machineCallCredit}})
+	if err != nil {
+		return nil, err
+	}
+	machine := map[int64]int{}
+	for _, credit := range credits {
+		if credit.ParentID == 0 {
+			machine[int64(credit.CallID)] = credit.Score
+		}
+	}
+
+	return calibrationReport(c, scores, machine), nil
+}
+
+// calibrationReport computes the report by the scores and the machine score of each call
+func calibrationReport(c *model.Calibration, scores []model.CalibrationScore, machine map[int64]int) *CalibrationReport {
+	// reviewer -> call -> outline -> score
+	given := map[string]map[int64]map[int64]int{}
+	consensus := map[int64]map[int64]int{}
+	for _, s := range scores {
+		target := consensus
+		if s.Consensus == 0 {
+			if given[s.StaffID] == nil {
+				given[s.StaffID] = map[int64]map[int64]int{}
+			}
+			target = given[s.StaffID]
+		}
+		if target[s.CallID] == nil {
+			target[s.CallID] = map[int64]int{}
+		}
+		target[s.CallID][s.OutlineID] = s.Score
+	}
+
+	// collect the subjects scored by every reviewer
+	ratings := func(outlines []int64) [][]int {
+		resp := [][]int{}
+		for _, call := range c.Calls {
+			for _, outline := range outlines {
+				row := make([]int, 0, len(c.Reviewers))
+				for _, reviewer := range c.Reviewers {
+					score, ok := given[reviewer][call][outline]
+					if !ok {
+						break
+					}
+					row = append(row, score)
+				}
+				if len(row) == len(c.Reviewers) {
+					resp = append(resp, row)
+				}
+			}
+		}
+		return resp
+	}
+
+	report := &CalibrationReport{
+		Overall:   agreement(ratings(c.Outlines)),
+		Items:     make([]ItemAgreement, 0, len(c.Outlines)),
+		Reviewers: make([]ReviewerReport, 0, len(c.Reviewers)),
+		Calls:     make([]CallComparison, 0, len(c.Calls)),
+	}
+	for _, outline := range c.Outlines {
+		report.Items = append(report.Items, ItemAgreement{
+			OutlineID: outline,
+			Agreement: agreement(ratings([]int64{outline})),
+		})
+	}
+
+	total := func(s map[int64]int) int {
+		var sum int
+		for _, v := range s {
+			sum += v
+		}
+		return sum
+	}
+
+	for _, call := range c.Calls {
+		comparison := CallComparison{
+			CallID:         call,
+			ReviewerScores: map[string]int{},
+		}
+		if score, ok := machine[call]; ok {
+			comparison.MachineScore = &score
+		}
+		if s, ok := consensus[call]; ok {
+			score := total(s)
+			comparison.ConsensusScore = &score
+		}
+		for _, reviewer := range c.Reviewers {
+			if s, ok := given[reviewer][call]; ok {
+				comparison.ReviewerScores[reviewer] = total(s)
+			}
+		}
+		report.Calls = append(report.Calls, comparison)
+	}
+
+	for _, reviewer := range c.Reviewers {
+		r := ReviewerReport{
+			Reviewer: reviewer,
+			Scored:   len(given[reviewer]),
+		}
+		var same, compared, machineCompared int
+		var diff float64
+		for call, s := range given[reviewer] {
+			for outline, score := range s {
+				if ref, ok := consensus[call][outline]; ok {
+					compared++
+					if ref == score {
+						same++
+					}
+				}
+			}
+			if m, ok := machine[call]; ok && c.FullScore > 0 {
+				machineCompared++
+				diff += math.Abs(float64(total(s))/float64(c.FullScore) - float64(m)/machineFullScore)
+			}
+		}
+		if compared > 0 {
+			v := roundRatio(float64(same) / float64(compared))
+			r.ConsensusAgreement = &v
+		}
+		if machineCompared > 0 {
+			v := roundRatio(diff / float64(machineCompared))
+			r.MachineDiff = &v
+		}
+		report.Reviewers = append(report.Reviewers, r)
+	}
+	return report
+}
+
+// agreement computes the statistics of ratings, which is ratings[subject][reviewer] = score.
+// Each score value is taken as a category.
+func agreement(ratings [][]int) Agreement {
+	a := Agreement{Subjects: len(ratings)}
+	if len(ratings) == 0 || len(ratings[0]) < 2 {
+		return a
+	}
+	observed, fleiss := fleissKappa(ratings)
+	a.Observed = roundRatio(observed)
+	a.FleissKappa = roundRatio(fleiss)
+
+	raters := len(ratings[0])
+	var sum float64
+	var pairs int
+	for i := 0; i < raters; i++ {
+		for j := i + 1; j < raters; j++ {
+			a1 := make([]int, len(ratings))
+			a2 := make([]int, len(ratings))
+			for s, row := range ratings {
+				a1[s] = row[i]
+				a2[s] = row[j]
+			}
+			sum += cohenKappa(a1, a2)
+			pairs++
+		}
+	}
+	a.CohenKappa = roundRatio(sum / float64(pairs))
+	return a
+}
+
+// fleissKappa gives the observed agreement and the Fleiss' kappa,
+// every subject should be rated by the same number of raters.
+func fleissKappa(ratings [][]int) (observed float64, kappa float64) {
+	subjects := float64(len(ratings))
+	raters := float64(len(ratings[0]))
+	total := map[int]float64{}
+	for _, row := range ratings {
+		counts := map[int]float64{}
+		for _, v := range row {
+			counts[v]++
+			total[v]++
+		}
+		var agreed float64
+		for _, n := range counts {
+			agreed += n * (n - 1)
+		}
+		observed += agreed / (raters * (raters - 1))
+	}
+	observed /= subjects
+
+	var expected float64
+	for _, n := range total {
+		p := n / (subjects * raters)
+		expected += p * p
+	}
+	return observed, kappaOf(observed, expected)
+}
+
+// cohenKappa gives the Cohen's kappa of two raters
+func cohenKappa(a, b []int) float64 {
+	n := float64(len(a))
+	countA := map[int]float64{}
+	countB := map[int]float64{}
+	var agreed float64
+	for i := range a {
+		countA[a[i]]++
+		countB[b[i]]++
+		if a[i] == b[i] {
+			agreed++
+		}
+	}
+	var expected float64
+	for v, c := range countA {
+		expected += (c / n) * (countB[v] / n)
+	}
+	return kappaOf(agreed/n, expected)
+}
+
+// kappaOf gives 1 if the chance agreement is 1, which means all raters give the same single score
+func kappaOf(observed, expected float64) float64 {
+	if expected >= 1 {
+		return 1
+	}
+	return (observed - expected) / (1 - expected)
+}
+
+func roundRatio(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+func uniqueInt64(values []int64) []int64 {
+	exists := map[int64]bool{}
+	resp := []int64{}
+	for _, v := range values {
+		if !exists[v] {
+			exists[v] = true
+			resp = append(resp, v)
+		}
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i] < resp[j] })
+	return resp
+}
+
+func uniqueString(values []string) []string {
+	exists := map[string]bool{}
+	resp := []string{}
+	for _, v := range values {
+		if v != "" && !exists[v] {
+			exists[v] = true
+			resp = append(resp, v)
+		}
+	}
+	return resp
+}
+
+func containsInt64(values []int64, target int64) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
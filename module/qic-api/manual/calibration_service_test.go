@@ -0,0 +1,93 @@
+package manual
+
+import (
+	"testing"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+)
+
+func TestAgreement(t *testing.T) {
+	ratings := [][]int{
+		[]int{1, 1},
+		[]int{1, 0},
+		[]int{0, 0},
+		[]int{0, 0},
+	}
+
+	a := agreement(ratings)
+	if a.Subjects != 4 || a.Observed != 0.75 {
+		t.Errorf("expect 4 subjects with 0.75 observed agreement, but got: %+v", a)
+	}
+	if a.CohenKappa != 0.5 {
+		t.Errorf("expect cohen's kappa 0.5, but got: %f", a.CohenKappa)
+	}
+	if a.FleissKappa != 0.4667 {
+		t.Errorf("expect fleiss' kappa 0.4667, but got: %f", a.FleissKappa)
+	}
+
+	// all reviewers give the same single score
+	a = agreement([][]int{[]int{5, 5, 5}, []int{5, 5, 5}})
+	if a.Observed != 1 || a.CohenKappa != 1 || a.FleissKappa != 1 {
+		t.Errorf("expect perfect agreement, but got: %+v", a)
+	}
+
+	a = agreement([][]int{})
+	if a.Subjects != 0 || a.FleissKappa != 0 {
+		t.Errorf("expect empty agreement, but got: %+v", a)
+	}
+}
+
+func TestCalibrationReport(t *testing.T) {
+	c := &model.Calibration{
+		ID:        1,
+		FullScore: 4,
+		Outlines:  []int64{10, 20},
+		Calls:     []int64{100, 200},
+		Reviewers: []string{"a", "b"},
+	}
+
+	score := func(call int64, staff string, outline int64, value int, consensus int8) model.CalibrationScore {
+		return model.CalibrationScore{CalibrationID: 1, CallID: call, StaffID: staff, OutlineID: outline, Score: value, Consensus: consensus}
+	}
+	scores := []model.CalibrationScore{
+		score(100, "a", 10, 1, 0),
+		score(100, "a", 20, 2, 0),
+		score(100, "b", 10, 1, 0),
+		score(100, "b", 20, 0, 0),
+		score(100, "admin", 10, 1, 1),
+		score(100, "admin", 20, 2, 1),
+		// call 200 is only scored by a, which is not counted in agreement
+		score(200, "a", 10, 0, 0),
+		score(200, "a", 20, 0, 0),
+	}
+	machine := map[int64]int{100: 50, 200: 10}
+
+	report := calibrationReport(c, scores, machine)
+	if report.Overall.Subjects != 2 {
+		t.Errorf("expect 2 subjects, but got: %d", report.Overall.Subjects)
+	}
+	if len(report.Items) != 2 || report.Items[0].Observed != 1 || report.Items[1].Observed != 0 {
+		t.Errorf("unexpected item agreements: %+v", report.Items)
+	}
+
+	ra := report.Reviewers[0]
+	if ra.Scored != 2 || ra.ConsensusAgreement == nil || *ra.ConsensusAgreement != 1 {
+		t.Errorf("unexpected report of reviewer a: %+v", ra)
+	}
+	// |3/4-50/100| and |0/4-10/100|
+	if ra.MachineDiff == nil || *ra.MachineDiff != 0.175 {
+		t.Errorf("expect machine diff 0.175 of reviewer a, but got: %v", ra.MachineDiff)
+	}
+	rb := report.Reviewers[1]
+	if *rb.ConsensusAgreement != 0.5 {
+		t.Errorf("expect consensus agreement 0.5 of reviewer b, but got: %f", *rb.ConsensusAgreement)
+	}
+
+	call := report.Calls[0]
+	if *call.ConsensusScore != 3 || *call.MachineScore != 50 || call.ReviewerScores["b"] != 1 {
+		t.Errorf("unexpected call comparison: %+v", call)
+	}
+	if report.Calls[1].ConsensusScore != nil {
+		t.Errorf("expect no consensus of call 200, but got: %d", *report.Calls[1].ConsensusScore)
+	}
+}
@@ -33,6 +33,12 @@ func init() {
 			util.NewEntryPoint("GET", "inspector", []string{}, handleGetInspectors),
 			util.NewEntryPoint("GET", "staff", []string{}, handleGetCustomerStaffs),
 			util.NewEntryPoint("GET", "evaluationForm", []string{}, handleGetScoreForms),
+			util.NewEntryPoint("POST", "calibrations", []string{}, handleCreateCalibration),
+			util.NewEntryPoint("GET", "calibrations", []string{}, handleGetCalibrations),
+			util.NewEntryPoint("GET", "calibrations/{id}", []string{}, calibrationRequest(handleGetCalibration)),
+			util.NewEntryPoint("PUT", "calibrations/{id}/calls/{call_id}/{kind:scores|consensus}", []string{}, calibrationRequest(handleSubmitCalibrationScores)),
+			util.NewEntryPoint("GET", "calibrations/{id}/report", []string{}, calibrationRequest(handleGetCalibrationReport)),
+			util.NewEntryPoint("POST", "calibrations/{id}/close", []string{}, calibrationRequest(handleCloseCalibration)),
 		},
 		OneTimeFunc: map[string]func(){
 			"init db": func() {
@@ -0,0 +1,476 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+//calibration status
+const (
+	CalibrationOpen   int8 = 0
+	CalibrationClosed int8 = 1
+)
+
+//Calibration is a session that the same calls are scored by multiple reviewers blindly
+type Calibration struct {
+	ID          int64
+	Name        string
+	Enterprise  string
+	Description string
+	Creator     string
+	Status      int8
+	CreateTime  int64
+	UpdateTime  int64
+	//FullScore is the highest total score of the outlines a reviewer can give,
+	//which scales the reviewer scores to the machine one
+	FullScore int
+	Outlines  []int64
+	Calls     []int64
+	Reviewers []string
+}
+
+type CalibrationFilter struct {
+	ID         []int64
+	Enterprise string
+	Reviewers  []string
+}
+
+//CalibrationScore is the score of an outline item of a call given by a reviewer,
+//the consensus one is the agreed score recorded as the reference
+type CalibrationScore struct {
+	CalibrationID int64
+	CallID        int64
+	StaffID       string
+	OutlineID     int64
+	Score         int
+	Consensus     int8
+	CreateTime    int64
+}
+
+type CalibrationScoreFilter struct {
+	CalibrationID []int64
+	CallIDs       []int64
+	StaffIDs      []string
+	Consensus     *int8
+}
+
+type CalibrationDao interface {
+	Create(c *Calibration, sql SqlLike) (int64, error)
+	GetBy(filter *CalibrationFilter, sql SqlLike) ([]Calibration, error)
+	UpdateStatus(id int64, status int8, sql SqlLike) error
+	SetScores(scores []CalibrationScore, sql SqlLike) error
+	Scores(filter *CalibrationScoreFilter, sql SqlLike) ([]CalibrationScore, error)
+}
+
+type CalibrationSqlDao struct{}
+
+func (dao *CalibrationSqlDao) Create(c *Calibration, sql SqlLike) (id int64, err error) {
+	if c == nil {
+		err = fmt.Errorf("Nil Calibration Error")
+		return
+	}
+
+	fields := []string{
+		fldName,
+		fldEnterprise,
+		fldDescription,
+		fldCreator,
+		fldStatus,
+		fldCreateTime,
+		fldUpdateTime,
+		CALIBFullScore,
+	}
+	values := []interface{}{
+		c.Name,
+		c.Enterprise,
+		c.Description,
+		c.Creator,
+		c.Status,
+		c.CreateTime,
+		c.UpdateTime,
+		c.FullScore,
+	}
+
+	insertStr := fmt.Sprintf(
+		"INSERT INTO `%s` (`%s`) VALUES (%s)",
+		tblCalibration,
+		strings.Join(fields, "`, `"),
+		fmt.Sprintf("?%s", strings.Repeat(", ?", len(values)-1)),
+	)
+
+	result, err := sql.Exec(insertStr, values...)
+	if err != nil {
+		err = fmt.Errorf("error while create calibration in dao.Create, err: %s", err.Error())
+		return
+	}
+
+	id, err = result.LastInsertId()
+	if err != nil {
+		err = fmt.Errorf("error while get calibration id in dao.Create, err: %s", err.Error())
+		return
+	}
+
+	outlineNum := len(c.Outlines)
+	if outlineNum > 0 {
+		values = make([]interface{}, 0, outlineNum*2)
+		for _, outline := range c.Outlines {
+			values = append(values, id, outline)
+		}
+
+		insertStr = fmt.Sprintf(
+			"INSERT INTO %s (%s, %s) VALUES %s",
+			tblRelCalibrationOutline,
+			CALIBCalibrationID,
+			CALIBOutlineID,
+			fmt.Sprintf("(?, ?)%s", strings.Repeat(", (?, ?)", outlineNum-1)),
+		)
+
+		_, err = sql.Exec(insertStr, values...)
+		if err != nil {
+			err = fmt.Errorf("error while insert outline relation in dao.Create, err: %s", err.Error())
+			return
+		}
+	}
+
+	//every reviewer is assigned to every call
+	assignNum := len(c.Calls) * len(c.Reviewers)
+	if assignNum > 0 {
+		values = make([]interface{}, 0, assignNum*3)
+		for _, call := range c.Calls {
+			for _, reviewer := range c.Reviewers {
+				values = append(values, id, call, reviewer)
+			}
+		}
+
+		insertStr = fmt.Sprintf(
+			"INSERT INTO %s (%s, %s, %s) VALUES %s",
+			tblRelCalibrationCall,
+			CALIBCalibrationID,
+			CALIBCallID,
+			CALIBStaffID,
+			fmt.Sprintf("(?, ?, ?)%s", strings.Repeat(", (?, ?, ?)", assignNum-1)),
+		)
+
+		_, err = sql.Exec(insertStr, values...)
+		if err != nil {
+			err = fmt.Errorf("error while insert call relation in dao.Create, err: %s", err.Error())
+			return
+		}
+	}
+	return
+}
+
+func (dao *CalibrationSqlDao) GetBy(filter *CalibrationFilter, sql SqlLike) (calibrations []Calibration, err error) {
+	conditions := []string{}
+	values := []interface{}{}
+
+	if filter.Enterprise != "" {
+		conditions = append(conditions, fmt.Sprintf("%s=?", fldEnterprise))
+		values = append(values, filter.Enterprise)
+	}
+
+	if len(filter.ID) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s IN (?%s)", fldID, strings.Repeat(", ?", len(filter.ID)-1)))
+		for _, id := range filter.ID {
+			values = append(values, id)
+		}
+	}
+
+	if len(filter.Reviewers) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"%s IN (SELECT %s FROM %s WHERE %s IN (?%s))",
+			fldID,
+			CALIBCalibrationID,
+			tblRelCalibrationCall,
+			CALIBStaffID,
+			strings.Repeat(", ?", len(filter.Reviewers)-1),
+		))
+		for _, reviewer := range filter.Reviewers {
+			values = append(values, reviewer)
+		}
+	}
+
+	conditionStr := ""
+	if len(conditions) > 0 {
+		conditionStr = fmt.Sprintf("WHERE %s", strings.Join(conditions, " and "))
+	}
+
+	queryStr := fmt.Sprintf(
+		"SELECT %s, %s, %s, %s, %s, %s, %s, %s, %s FROM %s %s ORDER BY %s DESC",
+		fldID,
+		fldName,
+		fldEnterprise,
+		fldDescription,
+		fldCreator,
+		fldStatus,
+		fldCreateTime,
+		fldUpdateTime,
+		CALIBFullScore,
+		tblCalibration,
+		conditionStr,
+		fldID,
+	)
+
+	rows, err := sql.Query(queryStr, values...)
+	if err != nil {
+		err = fmt.Errorf("error while get calibrations in dao.GetBy, err: %s", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	calibrations = []Calibration{}
+	for rows.Next() {
+		c := Calibration{}
+		err = rows.Scan(
+			&c.ID,
+			&c.Name,
+			&c.Enterprise,
+			&c.Description,
+			&c.Creator,
+			&c.Status,
+			&c.CreateTime,
+			&c.UpdateTime,
+			&c.FullScore,
+		)
+		if err != nil {
+			err = fmt.Errorf("error while scan calibration in dao.GetBy, err: %s", err.Error())
+			return
+		}
+		calibrations = append(calibrations, c)
+	}
+
+	if len(calibrations) == 0 {
+		return
+	}
+
+	idx := map[int64]*Calibration{}
+	values = make([]interface{}, len(calibrations))
+	for i := range calibrations {
+		idx[calibrations[i].ID] = &calibrations[i]
+		values[i] = calibrations[i].ID
+	}
+	idStr := fmt.Sprintf("?%s", strings.Repeat(", ?", len(values)-1))
+
+	queryStr = fmt.Sprintf(
+		"SELECT %s, %s FROM %s WHERE %s IN (%s)",
+		CALIBCalibrationID,
+		CALIBOutlineID,
+		tblRelCalibrationOutline,
+		CALIBCalibrationID,
+		idStr,
+	)
+	outlineRows, err := sql.Query(queryStr, values...)
+	if err != nil {
+		err = fmt.Errorf("error while get calibration outlines in dao.GetBy, err: %s", err.Error())
+		return
+	}
+	defer outlineRows.Close()
+
+	for outlineRows.Next() {
+		var id, outline int64
+		err = outlineRows.Scan(&id, &outline)
+		if err != nil {
+			err = fmt.Errorf("error while scan calibration outline in dao.GetBy, err: %s", err.Error())
+			return
+		}
+		c := idx[id]
+		c.Outlines = append(c.Outlines, outline)
+	}
+
+	queryStr = fmt.Sprintf(
+		"SELECT %s, %s, %s FROM %s WHERE %s IN (%s) ORDER BY %s, %s",
+		CALIBCalibrationID,
+		CALIBCallID,
+		CALIBStaffID,
+		tblRelCalibrationCall,
+		CALIBCalibrationID,
+		idStr,
+		CALIBCallID,
+		CALIBStaffID,
+	)
+	callRows, err := sql.Query(queryStr, values...)
+	if err != nil {
+		err = fmt.Errorf("error while get calibration calls in dao.GetBy, err: %s", err.Error())
+		return
+	}
+	defer callRows.Close()
+
+	calls := map[int64]map[int64]bool{}
+	reviewers := map[int64]map[string]bool{}
+	for callRows.Next() {
+		var id, call int64
+		var staff string
+		err = callRows.Scan(&id, &call, &staff)
+		if err != nil {
+			err = fmt.Errorf("error while scan calibration call in dao.GetBy, err: %s", err.Error())
+			return
+		}
+		c := idx[id]
+		if calls[id] == nil {
+			calls[id] = map[int64]bool{}
+			reviewers[id] = map[string]bool{}
+		}
+		if !calls[id][call] {
+			c.Calls = append(c.Calls, call)
+			calls[id][call] = true
+		}
+		if !reviewers[id][staff] {
+			c.Reviewers = append(c.Reviewers, staff)
+			reviewers[id][staff] = true
+		}
+	}
+	return
+}
+
+func (dao *CalibrationSqlDao) UpdateStatus(id int64, status int8, sql SqlLike) (err error) {
+	updateStr := fmt.Sprintf(
+		"UPDATE %s SET %s=? WHERE %s=?",
+		tblCalibration,
+		fldStatus,
+		fldID,
+	)
+
+	_, err = sql.Exec(updateStr, status, id)
+	if err != nil {
+		err = fmt.Errorf("error while update calibration status in dao.UpdateStatus, err: %s", err.Error())
+	}
+	return
+}
+
+//SetScores replaces the scores of the same calibration, call, staff and consensus by the given scores,
+//and marks the reviewer's call as finished
+func (dao *CalibrationSqlDao) SetScores(scores []CalibrationScore, sql SqlLike) (err error) {
+	if len(scores) == 0 {
+		return
+	}
+	first := scores[0]
+
+	deleteStr := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s=? and %s=? and %s=? and %s=?",
+		tblCalibrationScore,
+		CALIBCalibrationID,
+		CALIBCallID,
+		CALIBStaffID,
+		CALIBConsensus,
+	)
+	_, err = sql.Exec(deleteStr, first.CalibrationID, first.CallID, first.StaffID, first.Consensus)
+	if err != nil {
+		err = fmt.Errorf("error while delete calibration scores in dao.SetScores, err: %s", err.Error())
+		return
+	}
+
+	values := make([]interface{}, 0, len(scores)*7)
+	for _, s := range scores {
+		values = append(values, s.CalibrationID, s.CallID, s.StaffID, s.OutlineID, s.Score, s.Consensus, s.CreateTime)
+	}
+	insertStr := fmt.Sprintf(
+		"INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES %s",
+		tblCalibrationScore,
+		CALIBCalibrationID,
+		CALIBCallID,
+		CALIBStaffID,
+		CALIBOutlineID,
+		fldScore,
+		CALIBConsensus,
+		fldCreateTime,
+		fmt.Sprintf("(?, ?, ?, ?, ?, ?, ?)%s", strings.Repeat(", (?, ?, ?, ?, ?, ?, ?)", len(scores)-1)),
+	)
+	_, err = sql.Exec(insertStr, values...)
+	if err != nil {
+		err = fmt.Errorf("error while insert calibration scores in dao.SetScores, err: %s", err.Error())
+		return
+	}
+
+	if first.Consensus == 0 {
+		updateStr := fmt.Sprintf(
+			"UPDATE %s SET %s=1 WHERE %s=? and %s=? and %s=?",
+			tblRelCalibrationCall,
+			fldStatus,
+			CALIBCalibrationID,
+			CALIBCallID,
+			CALIBStaffID,
+		)
+		_, err = sql.Exec(updateStr, first.CalibrationID, first.CallID, first.StaffID)
+		if err != nil {
+			err = fmt.Errorf("error while set calibration call finished in dao.SetScores, err: %s", err.Error())
+			return
+		}
+	}
+	return
+}
+
+func (dao *CalibrationSqlDao) Scores(filter *CalibrationScoreFilter, sql SqlLike) (scores []CalibrationScore, err error) {
+	conditions := []string{}
+	values := []interface{}{}
+
+	if len(filter.CalibrationID) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s IN (?%s)", CALIBCalibrationID, strings.Repeat(", ?", len(filter.CalibrationID)-1)))
+		for _, id := range filter.CalibrationID {
+			values = append(values, id)
+		}
+	}
+
+	if len(filter.CallIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s IN (?%s)", CALIBCallID, strings.Repeat(", ?", len(filter.CallIDs)-1)))
+		for _, id := range filter.CallIDs {
+			values = append(values, id)
+		}
+	}
+
+	if len(filter.StaffIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s IN (?%s)", CALIBStaffID, strings.Repeat(", ?", len(filter.StaffIDs)-1)))
+		for _, id := range filter.StaffIDs {
+			values = append(values, id)
+		}
+	}
+
+	if filter.Consensus != nil {
+		conditions = append(conditions, fmt.Sprintf("%s=?", CALIBConsensus))
+		values = append(values, *filter.Consensus)
+	}
+
+	conditionStr := ""
+	if len(conditions) > 0 {
+		conditionStr = fmt.Sprintf("WHERE %s", strings.Join(conditions, " and "))
+	}
+
+	queryStr := fmt.Sprintf(
+		"SELECT %s, %s, %s, %s, %s, %s, %s FROM %s %s",
+		CALIBCalibrationID,
+		CALIBCallID,
+		CALIBStaffID,
+		CALIBOutlineID,
+		fldScore,
+		CALIBConsensus,
+		fldCreateTime,
+		tblCalibrationScore,
+		conditionStr,
+	)
+
+	rows, err := sql.Query(queryStr, values...)
+	if err != nil {
+		err = fmt.Errorf("error while get calibration scores in dao.Scores, err: %s", err.Error())
+		return
+	}
+	defer rows.Close()
+
+	scores = []CalibrationScore{}
+	for rows.Next() {
+		s := CalibrationScore{}
+		err = rows.Scan(
+			&s.CalibrationID,
+			&s.CallID,
+			&s.StaffID,
+			&s.OutlineID,
+			&s.Score,
+			&s.Consensus,
+			&s.CreateTime,
+		)
+		if err != nil {
+			err = fmt.Errorf("error while scan calibration score in dao.Scores, err: %s", err.Error())
+			return
+		}
+		scores = append(scores, s)
+	}
+	return
+}
@@ -119,6 +119,8 @@ type Call struct {
 	RightChanRole      int8
 	IsDeal             int8
 	RemoteFile         *string
+	Locale             string   //the locale of the conversation
+	AdherenceScore     *float64 //the navigation flow adherence percentage, nil if not evaluated
 }
 
 // the type of the call is created, different type indicate different incoming source of call.
@@ -188,6 +190,7 @@ func (c *CallSQLDao) Calls(delegatee SqlLike, query CallQuery) ([]Call, error) {
 		fldCallLeftSpeed, fldCallRightSpeed, fldCallType,
 		fldCallLeftChan, fldCallRightChan, fldCallStatus,
 		fldCallDeal, fldCallDemoFilePath, fldCallRemoteFile,
+		fldCallLocale, fldCallAdherenceScore,
 	}
 	wheresql, data := query.whereSQL("")
 	limitsql := ""
@@ -214,6 +217,7 @@ func (c *CallSQLDao) Calls(delegatee SqlLike, query CallQuery) ([]Call, error) {
 			rSpeed      sql.NullFloat64
 			demoFp      sql.NullString
 			remoteFp    sql.NullString
+			adhScore    sql.NullFloat64
 		)
 		err := rows.Scan(&c.ID, &c.UUID, &fileName,
 			&filePath, &description, &c.DurationMillSecond,
@@ -224,6 +228,7 @@ func (c *CallSQLDao) Calls(delegatee SqlLike, query CallQuery) ([]Call, error) {
 			&lSpeed, &rSpeed, &c.Type,
 			&c.LeftChanRole, &c.RightChanRole, &c.Status,
 			&c.IsDeal, &demoFp, &remoteFp,
+			&c.Locale, &adhScore,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan error: %v", err)
@@ -255,6 +260,9 @@ func (c *CallSQLDao) Calls(delegatee SqlLike, query CallQuery) ([]Call, error) {
 		if remoteFp.Valid {
 			c.RemoteFile = &remoteFp.String
 		}
+		if adhScore.Valid {
+			c.AdherenceScore = &adhScore.Float64
+		}
 
 		calls = append(calls, c)
 	}
@@ -278,6 +286,7 @@ func (c *CallSQLDao) NewCalls(delegatee SqlLike, calls []Call) ([]Call, error) {
 		fldCallUploadedUser, fldCallLeftSilenceTime, fldCallRightSilenceTime,
 		fldCallLeftSpeed, fldCallRightSpeed, fldCallType,
 		fldCallLeftChan, fldCallRightChan, fldCallDeal, fldCallRemoteFile,
+		fldCallLocale,
 	}
 
 	rawquery := "INSERT INTO `" + tblCall + "` (`" + strings.Join(insertCols, "`, `") + "`) VALUE(?" + strings.Repeat(",? ", len(insertCols)-1) + ")"
@@ -298,7 +307,8 @@ func (c *CallSQLDao) NewCalls(delegatee SqlLike, calls []Call) ([]Call, error) {
 			c.CustomerName, c.CustomerPhone, c.EnterpriseID,
 			c.UploadUser, c.LeftSilenceTime, c.RightSilenceTime,
 			c.LeftSpeed, c.RightSpeed, c.Type,
-			c.LeftChanRole, c.RightChanRole, c.IsDeal, c.RemoteFile)
+			c.LeftChanRole, c.RightChanRole, c.IsDeal, c.RemoteFile,
+			c.Locale)
 		if err != nil {
 			return nil, fmt.Errorf("create new call failed, %v", err)
 		}
@@ -397,6 +407,7 @@ func createCallUpdateSQL(c Call) (string, []interface{}) {
 		fldCallRightChan, fldCallStatus, fldCallDemoFilePath,
 		fldCallDeal, fldCallLeftSilenceTime, fldCallRightSilenceTime,
 		fldCallLeftSpeed, fldCallRightSpeed, fldCallRemoteFile,
+		fldCallLocale,
 	}
 
 	data := []interface{}{
@@ -409,6 +420,7 @@ func createCallUpdateSQL(c Call) (string, []interface{}) {
 		c.RightChanRole, c.Status, c.DemoFilePath,
 		c.IsDeal, c.LeftSilenceTime, c.RightSilenceTime,
 		c.LeftSpeed, c.RightSpeed, c.RemoteFile,
+		c.Locale,
 	}
 
 	for _, colName := range updateCols {
@@ -71,6 +71,7 @@ type SimpleCredit struct {
 	UpdateTime int64
 	Whos       int
 	Comment    string
+	Incomplete int //1 if the result is not determined because some segments failed to predict
 }
 
 //SegmentMatch is the structure used to insert the matched segment
@@ -107,13 +108,14 @@ func (c *CreditSQLDao) InsertCredit(conn SqlLike, credit *SimpleCredit) (int64,
 		fldCreateTime,
 		fldUpdateTime,
 		fldWhos,
+		fldIncomplete,
 	}
 
 	var params []interface{}
 
 	params = append(params, credit.CallID, credit.Type, credit.ParentID,
 		credit.OrgID, credit.Valid, credit.Revise, credit.Score, credit.CreateTime,
-		credit.CreateTime, credit.Whos)
+		credit.CreateTime, credit.Whos, credit.Incomplete)
 
 	return insertRecord(conn, table, insertFlds, params)
 }
@@ -251,6 +253,7 @@ func (c *CreditSQLDao) GetCallCredit(conn SqlLike, q *CreditQuery) ([]*SimpleCre
 		fldUpdateTime,
 		fldWhos,
 		fldDescription,
+		fldIncomplete,
 	}
 	for i, v := range flds {
 		flds[i] = "`" + v + "`"
@@ -275,7 +278,7 @@ func (c *CreditSQLDao) GetCallCredit(conn SqlLike, q *CreditQuery) ([]*SimpleCre
 	resp := make([]*SimpleCredit, 0, 10)
 	for rows.Next() {
 		var s SimpleCredit
-		err = rows.Scan(&s.ID, &s.CallID, &s.Type, &s.ParentID, &s.OrgID, &s.Valid, &s.Revise, &s.Score, &s.CreateTime, &s.UpdateTime, &s.Whos, &s.Comment)
+		err = rows.Scan(&s.ID, &s.CallID, &s.Type, &s.ParentID, &s.OrgID, &s.Valid, &s.Revise, &s.Score, &s.CreateTime, &s.UpdateTime, &s.Whos, &s.Comment, &s.Incomplete)
 		if err != nil {
 			logger.Error.Printf("Scan failed. %s\n", err)
 			return nil, err
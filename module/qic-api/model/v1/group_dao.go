@@ -1,6 +1,7 @@
 package model
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 	"time"
@@ -105,6 +106,10 @@ type Group struct {
 	SilenceRules     []SilenceRule
 	SpeedRules       []SpeedRule
 	InterposalRules  []InterposalRule
+	MonologueRules   []MonologueRule
+	TalkRatioRules   []TalkRatioRule
+	HoldRules        []HoldRule
+	LatencyRules     []LatencyRule
 	Condition        *Condition
 	CustomConditions []UserValue
 }
@@ -878,8 +883,50 @@ const (
 	GroupRuleTypeSilence GroupRuleType = iota
 	GroupRuleTypeSpeed
 	GroupRuleTypeInterposal
+	GroupRuleTypeMonologue
+	GroupRuleTypeTalkRatio
+	GroupRuleTypeHold
+	GroupRuleTypeLatency
 )
 
+//talkRuleRelations is the relation table and its rule uuid field of the talk-time rules
+var talkRuleRelations = []struct {
+	typ   GroupRuleType
+	table string
+	fld   string
+	name  string
+}{
+	{typ: GroupRuleTypeMonologue, table: tblRelRGMonologue, fld: fldMonologueUUID, name: "monologue"},
+	{typ: GroupRuleTypeTalkRatio, table: tblRelRGTalkRatio, fld: fldTalkRatioUUID, name: "talk ratio"},
+	{typ: GroupRuleTypeHold, table: tblRelRGHold, fld: fldHoldUUID, name: "hold"},
+	{typ: GroupRuleTypeLatency, table: tblRelRGLatency, fld: fldLatencyUUID, name: "latency"},
+}
+
+func groupRelatedRuleUUIDs(delegatee SqlLike, groupUUID string, table string, fld string) ([]string, error) {
+	rawsql := fmt.Sprintf(
+		"SELECT `%s` FROM `%s` WHERE `%s` = ? ORDER BY `%s` ASC",
+		fld,
+		table,
+		fldRGUUID,
+		fld,
+	)
+	rows, err := delegatee.Query(rawsql, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	uuids := make([]string, 0)
+	for rows.Next() {
+		var uuid string
+		rows.Scan(&uuid)
+		uuids = append(uuids, uuid)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan row err: %v", err)
+	}
+	return uuids, nil
+}
+
 func (s *GroupSQLDao) GroupRules(delegatee SqlLike, group Group) (conversationRules []int64, OtherGroupRules map[GroupRuleType][]string, err error) {
 	if delegatee == nil {
 		delegatee = s.conn
@@ -906,7 +953,7 @@ func (s *GroupSQLDao) GroupRules(delegatee SqlLike, group Group) (conversationRu
 	if err = rows.Err(); err != nil {
 		return nil, nil, fmt.Errorf("scan row err: %v", err)
 	}
-	OtherGroupRules = make(map[GroupRuleType][]string, 3)
+	OtherGroupRules = make(map[GroupRuleType][]string, 3+len(talkRuleRelations))
 	rawsql = fmt.Sprintf(
 		"SELECT `%s` FROM `%s` WHERE `%s` = ? ORDER BY `%s` ASC",
 		fldSilenceUUID,
@@ -976,6 +1023,14 @@ func (s *GroupSQLDao) GroupRules(delegatee SqlLike, group Group) (conversationRu
 	}
 	OtherGroupRules[GroupRuleTypeInterposal] = interposals
 
+	for _, rel := range talkRuleRelations {
+		uuids, err := groupRelatedRuleUUIDs(delegatee, group.UUID, rel.table, rel.fld)
+		if err != nil {
+			return nil, nil, fmt.Errorf("query %s rules relation failed, %v", rel.name, err)
+		}
+		OtherGroupRules[rel.typ] = uuids
+	}
+
 	return conversationRules, OtherGroupRules, nil
 }
 
@@ -1010,6 +1065,19 @@ func (s *GroupSQLDao) ResetGroupRules(delegatee SqlLike, groups ...Group) error
 		return fmt.Errorf("sql prepare failed, %v", err)
 	}
 	defer interposalStmt.Close()
+	talkStmts := make([]*sql.Stmt, 0, len(talkRuleRelations))
+	for _, rel := range talkRuleRelations {
+		stmt, err := delegatee.Prepare(fmt.Sprintf(
+			"DELETE FROM `%s` WHERE `%s` = ?",
+			rel.table,
+			fldRGUUID,
+		))
+		if err != nil {
+			return fmt.Errorf("sql prepare failed, %v", err)
+		}
+		defer stmt.Close()
+		talkStmts = append(talkStmts, stmt)
+	}
 
 	for _, g := range groups {
 		_, err := silenceStmt.Exec(g.UUID)
@@ -1024,6 +1092,12 @@ func (s *GroupSQLDao) ResetGroupRules(delegatee SqlLike, groups ...Group) error
 		if err != nil {
 			return fmt.Errorf("delete interposal relation failed, %v", err)
 		}
+		for idx, stmt := range talkStmts {
+			_, err = stmt.Exec(g.UUID)
+			if err != nil {
+				return fmt.Errorf("delete %s relation failed, %v", talkRuleRelations[idx].name, err)
+			}
+		}
 	}
 
 	return nil
@@ -1075,6 +1149,20 @@ func (s *GroupSQLDao) SetGroupRules(delegatee SqlLike, groups ...Group) error {
 		return fmt.Errorf("sql prepare failed, %v", err)
 	}
 	defer interposalStmt.Close()
+	talkStmts := make(map[GroupRuleType]*sql.Stmt, len(talkRuleRelations))
+	for _, rel := range talkRuleRelations {
+		stmt, err := delegatee.Prepare(fmt.Sprintf(
+			"INSERT INTO `%s` (`%s`, `%s`) VALUES(?, ?)",
+			rel.table,
+			fldRGUUID,
+			rel.fld,
+		))
+		if err != nil {
+			return fmt.Errorf("sql prepare failed, %v", err)
+		}
+		defer stmt.Close()
+		talkStmts[rel.typ] = stmt
+	}
 
 	for _, g := range groups {
 		for _, r := range g.Rules {
@@ -1101,6 +1189,30 @@ func (s *GroupSQLDao) SetGroupRules(delegatee SqlLike, groups ...Group) error {
 				return fmt.Errorf("insert interposal relation failed, %v", err)
 			}
 		}
+		for _, r := range g.MonologueRules {
+			_, err := talkStmts[GroupRuleTypeMonologue].Exec(g.UUID, r.UUID)
+			if err != nil {
+				return fmt.Errorf("insert monologue relation failed, %v", err)
+			}
+		}
+		for _, r := range g.TalkRatioRules {
+			_, err := talkStmts[GroupRuleTypeTalkRatio].Exec(g.UUID, r.UUID)
+			if err != nil {
+				return fmt.Errorf("insert talk ratio relation failed, %v", err)
+			}
+		}
+		for _, r := range g.HoldRules {
+			_, err := talkStmts[GroupRuleTypeHold].Exec(g.UUID, r.UUID)
+			if err != nil {
+				return fmt.Errorf("insert hold relation failed, %v", err)
+			}
+		}
+		for _, r := range g.LatencyRules {
+			_, err := talkStmts[GroupRuleTypeLatency].Exec(g.UUID, r.UUID)
+			if err != nil {
+				return fmt.Errorf("insert latency relation failed, %v", err)
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//NavAdherence is the adherence result of the navigation flows in a call.
+//Percentage is stored on the call, so it can be listed and filtered with the call,
+//while the report of each step is kept in its own table since it is only needed in detail view.
+type NavAdherence struct {
+	ID         int64
+	CallID     int64
+	Percentage float64
+	Report     string //the report of each step in json
+	CreateTime int64
+}
+
+//NavAdherenceDao is the interface to access the adherence result of the call
+type NavAdherenceDao interface {
+	SetAdherence(conn SqlLike, a *NavAdherence) (int64, error)
+	GetAdherence(conn SqlLike, callIDs []int64) ([]*NavAdherence, error)
+}
+
+//NavAdherenceSQLDao implements the NavAdherenceDao with sql
+type NavAdherenceSQLDao struct {
+}
+
+//SetAdherence replaces the adherence result of the call, and updates the adherence score of the call.
+//conn should be a transaction to keep the score and the report consistent
+func (n *NavAdherenceSQLDao) SetAdherence(conn SqlLike, a *NavAdherence) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if a == nil {
+		return 0, ErrNeedRequest
+	}
+
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE %s=?", tblNavAdherence, fldAdhCallID)
+	_, err := conn.Exec(deleteSQL, a.CallID)
+	if err != nil {
+		logger.Error.Printf("delete old adherence failed. %s. %s\n", deleteSQL, err)
+		return 0, err
+	}
+
+	updateSQL := fmt.Sprintf("UPDATE `%s` SET `%s`=? WHERE `%s`=?", tblCall, fldCallAdherenceScore, fldCallID)
+	_, err = conn.Exec(updateSQL, a.Percentage, a.CallID)
+	if err != nil {
+		logger.Error.Printf("update adherence score of call failed. %s. %s\n", updateSQL, err)
+		return 0, err
+	}
+
+	flds := []string{
+		fldAdhCallID,
+		fldAdhReport,
+		fldCreateTime,
+	}
+	return insertRow(conn, tblNavAdherence, flds, []interface{}{a.CallID, a.Report, a.CreateTime})
+}
+
+//GetAdherence gets the adherence result of the calls, with the adherence score stored on the call
+func (n *NavAdherenceSQLDao) GetAdherence(conn SqlLike, callIDs []int64) ([]*NavAdherence, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	if len(callIDs) == 0 {
+		return nil, ErrNeedCondition
+	}
+
+	flds := []string{
+		"a." + fldID,
+		"a." + fldAdhCallID,
+		"c." + fldCallAdherenceScore,
+		"a." + fldAdhReport,
+		"a." + fldCreateTime,
+	}
+	params := make([]interface{}, 0, len(callIDs))
+	for _, v := range callIDs {
+		params = append(params, v)
+	}
+	querySQL := fmt.Sprintf("SELECT %s FROM `%s` AS a INNER JOIN `%s` AS c ON a.%s=c.%s WHERE a.%s IN (?%s)",
+		strings.Join(flds, ","), tblNavAdherence, tblCall, fldAdhCallID, fldCallID,
+		fldAdhCallID, strings.Repeat(",?", len(callIDs)-1))
+
+	rows, err := conn.Query(querySQL, params...)
+	if err != nil {
+		logger.Error.Printf("query failed. %s %+v\n", querySQL, params)
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := make([]*NavAdherence, 0, len(callIDs))
+	for rows.Next() {
+		var (
+			a     NavAdherence
+			score sql.NullFloat64
+		)
+		err = rows.Scan(&a.ID, &a.CallID, &score, &a.Report, &a.CreateTime)
+		if err != nil {
+			logger.Error.Printf("scan failed. %s\n", err)
+			return nil, err
+		}
+		a.Percentage = score.Float64
+		resp = append(resp, &a)
+	}
+	return resp, nil
+}
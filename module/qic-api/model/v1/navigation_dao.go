@@ -46,6 +46,7 @@ type NavFlow struct {
 	CreateTime   int64
 	UpdateTime   int64
 	NodeOrder    string
+	Adherence    string //the adherence setting in json
 }
 
 type NavFlowUpdate struct {
@@ -54,6 +55,7 @@ type NavFlowUpdate struct {
 	IntentName   *string
 	IntentLinkID *int64
 	UpdateTime   *int64
+	Adherence    *string
 }
 
 //NavQuery is query condition used in navigation api
@@ -103,10 +105,11 @@ func (n *NavigationSQLDao) NewFlow(conn SqlLike, p *NavFlow) (int64, error) {
 		fldCreateTime,
 		fldUpdateTime,
 		fldNodeOrder,
+		fldAdherence,
 	}
 
-	vals := make([]interface{}, 0, 8)
-	vals = append(vals, p.Name, p.UUID, p.IgnoreIntent, p.IntentName, p.IntentLinkID, p.Enterprise, p.CreateTime, p.CreateTime, "[]")
+	vals := make([]interface{}, 0, 9)
+	vals = append(vals, p.Name, p.UUID, p.IgnoreIntent, p.IntentName, p.IntentLinkID, p.Enterprise, p.CreateTime, p.CreateTime, "[]", "{}")
 	return insertRow(conn, tblNavigation, flds, vals)
 }
 
@@ -141,6 +144,7 @@ func (n *NavigationSQLDao) GetFlows(conn SqlLike, q *NavQuery, l *NavLimit) ([]*
 		fldCreateTime,
 		fldUpdateTime,
 		fldNodeOrder,
+		fldAdherence,
 	}
 
 	querySQL := fmt.Sprintf("SELECT %s FROM %s %s %s", strings.Join(flds, ","), tblNavigation, condition, limitStr)
@@ -155,7 +159,7 @@ func (n *NavigationSQLDao) GetFlows(conn SqlLike, q *NavQuery, l *NavLimit) ([]*
 	for rows.Next() {
 		var n NavFlow
 		err = rows.Scan(&n.ID, &n.Name, &n.UUID, &n.IgnoreIntent, &n.IntentName,
-			&n.IntentLinkID, &n.Enterprise, &n.CreateTime, &n.UpdateTime, &n.NodeOrder)
+			&n.IntentLinkID, &n.Enterprise, &n.CreateTime, &n.UpdateTime, &n.NodeOrder, &n.Adherence)
 		if err != nil {
 			logger.Error.Printf("scan failed. %s\n", err)
 			return nil, err
@@ -309,6 +313,7 @@ func (n *NavigationSQLDao) UpdateFlows(conn SqlLike, q *NavQuery, d *NavFlowUpda
 		fldIntenName,
 		fldIntentLink,
 		fldUpdateTime,
+		fldAdherence,
 	}
 
 	setStr, sparams, err := makeSets(d, flds)
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+//quoteFlds returns the copy of the flds quoted by backtick with the given table alias
+func quoteFlds(flds []string, alias string) []string {
+	resp := make([]string, 0, len(flds))
+	for _, f := range flds {
+		resp = append(resp, alias+"`"+f+"`")
+	}
+	return resp
+}
+
+//copyRow duplicates the row with the given id, flds must exclude the id field
+func copyRow(conn SqlLike, table string, flds []string, id int64) (int64, error) {
+	fieldsSQL := strings.Join(quoteFlds(flds, ""), ",")
+	copySQL := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s WHERE %s=?", table,
+		fieldsSQL, fieldsSQL, table, fldID)
+
+	res, err := conn.Exec(copySQL, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+//ruleByGroupSQL generates the query joining the rule group relation table and the rule table.
+//q.UUID is the rule group uuid, relFld is the field in relation table refers to the rule uuid
+func ruleByGroupSQL(q *GeneralQuery, flds []string, relTable, table, relFld string) (string, []interface{}) {
+	params := make([]interface{}, 0, len(q.UUID))
+	for _, v := range q.UUID {
+		params = append(params, v)
+	}
+
+	condition := "WHERE a.`" + fldRGUUID + "` IN (?" + strings.Repeat(",?", len(q.UUID)-1) + ")"
+	if q.IsDelete != nil {
+		condition += " AND b." + fldIsDelete + "=?"
+		params = append(params, *q.IsDelete)
+	}
+	if q.Enterprise != nil {
+		condition += " AND b." + fldEnterprise + "=?"
+		params = append(params, *q.Enterprise)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s AS a INNER JOIN %s AS b ON a.%s=b.%s %s",
+		strings.Join(quoteFlds(flds, "b."), ","),
+		relTable, table,
+		relFld, fldUUID,
+		condition)
+	return query, params
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//HoldRule limits the number of holds, the silence longer than the given seconds in the middle of the call
+type HoldRule struct {
+	ID         int64  `json:"-"`
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	Seconds    int    `json:"seconds"`
+	Times      int    `json:"times"`
+	Exception  string `json:"-"`
+	Enterprise string `json:"-"`
+	IsDelete   int    `json:"-"`
+	CreateTime int64  `json:"-"`
+	UpdateTime int64  `json:"-"`
+	UUID       string `json:"hold_id"`
+}
+
+type HoldUpdateSet struct {
+	Name      *string `json:"name"`
+	Score     *int    `json:"score"`
+	Seconds   *int    `json:"seconds"`
+	Times     *int    `json:"times"`
+	Exception *string `json:"-"`
+}
+
+type HoldRuleDao interface {
+	Add(conn SqlLike, r *HoldRule) (int64, error)
+	Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*HoldRule, error)
+	Count(conn SqlLike, q *GeneralQuery) (int64, error)
+	SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error)
+	Update(conn SqlLike, q *GeneralQuery, d *HoldUpdateSet) (int64, error)
+	Copy(conn SqlLike, q *GeneralQuery) (int64, error)
+	GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*HoldRule, error)
+}
+
+type HoldRuleSQLDao struct {
+}
+
+var holdFlds = []string{
+	fldID,
+	fldName,
+	fldScore,
+	fldHoldSecond,
+	fldHoldTime,
+	fldExcpt,
+	fldEnterprise,
+	fldIsDelete,
+	fldCreateTime,
+	fldUpdateTime,
+	fldUUID,
+}
+
+//Add inserts a new record
+func (s *HoldRuleSQLDao) Add(conn SqlLike, r *HoldRule) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if r == nil {
+		return 0, ErrNeedRequest
+	}
+	flds := quoteFlds(holdFlds, "")
+
+	vals := make([]interface{}, 0, len(flds))
+	err := extractSimpleStructureValue(&vals, r)
+	if err != nil {
+		return 0, err
+	}
+	//remove the ID
+	vals = vals[1:]
+	flds = flds[1:]
+
+	return insertRow(conn, tblHoldRule, flds, vals)
+}
+
+//Get gets the data under the condition
+func (s *HoldRuleSQLDao) Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*HoldRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	flds := quoteFlds(holdFlds, "")
+
+	var condition string
+	var params []interface{}
+	var err error
+	var offset string
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return nil, ErrGenCondition
+		}
+	}
+	if p != nil {
+		offset = p.offsetSQL()
+	}
+	querySQL := fmt.Sprintf("SELECT %s FROM %s %s %s", strings.Join(flds, ","), tblHoldRule, condition, offset)
+	return getHoldRules(conn, querySQL, params)
+}
+
+//Count counts number of the rows under the condition
+func (s *HoldRuleSQLDao) Count(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	var condition string
+	var params []interface{}
+	var err error
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return 0, ErrGenCondition
+		}
+	}
+	return countRows(conn, tblHoldRule, condition, params)
+}
+
+//SoftDelete simply set the is_delete to 1
+func (s *HoldRuleSQLDao) SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return softDelete(conn, q, tblHoldRule)
+}
+
+//Update updates the records
+func (s *HoldRuleSQLDao) Update(conn SqlLike, q *GeneralQuery, d *HoldUpdateSet) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	flds := []string{
+		fldName,
+		fldScore,
+		fldHoldSecond,
+		fldHoldTime,
+		fldExcpt,
+	}
+	return updateSQL(conn, q, d, tblHoldRule, flds)
+}
+
+//Copy copys only one record, only use the first ID in q
+func (s *HoldRuleSQLDao) Copy(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return copyRow(conn, tblHoldRule, holdFlds[1:], q.ID[0])
+}
+
+//GetByRuleGroup gets the rule under the conditon of RuleGroup.
+//Hence, q is the condition for getting RuleGroup
+func (s *HoldRuleSQLDao) GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*HoldRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	if q == nil || len(q.UUID) == 0 {
+		return nil, ErrNeedCondition
+	}
+	query, params := ruleByGroupSQL(q, holdFlds, tblRelRGHold, tblHoldRule, fldHoldUUID)
+	return getHoldRules(conn, query, params)
+}
+
+func getHoldRules(conn SqlLike, querySQL string, params []interface{}) ([]*HoldRule, error) {
+	rows, err := conn.Query(querySQL, params...)
+	if err != nil {
+		logger.Error.Printf("query failed. %s %+v\n", querySQL, params)
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*HoldRule, 0, 4)
+	for rows.Next() {
+		var d HoldRule
+		err = rows.Scan(&d.ID, &d.Name, &d.Score, &d.Seconds, &d.Times,
+			&d.Exception, &d.Enterprise, &d.IsDelete, &d.CreateTime, &d.UpdateTime, &d.UUID)
+		if err != nil {
+			logger.Error.Printf("scan failed. %s\n", err)
+			return nil, err
+		}
+		resp = append(resp, &d)
+	}
+	return resp, nil
+}
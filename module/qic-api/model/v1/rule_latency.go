@@ -0,0 +1,200 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//LatencyRule limits how long the staff takes to respond to the customer's questions.
+//Trigger records the sentence groups that define the question, any customer segment is a question if it is empty
+type LatencyRule struct {
+	ID         int64  `json:"-"`
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	Seconds    int    `json:"seconds"`
+	Times      int    `json:"times"`
+	Trigger    string `json:"-"`
+	Exception  string `json:"-"`
+	Enterprise string `json:"-"`
+	IsDelete   int    `json:"-"`
+	CreateTime int64  `json:"-"`
+	UpdateTime int64  `json:"-"`
+	UUID       string `json:"latency_id"`
+}
+
+type LatencyUpdateSet struct {
+	Name      *string `json:"name"`
+	Score     *int    `json:"score"`
+	Seconds   *int    `json:"seconds"`
+	Times     *int    `json:"times"`
+	Trigger   *string `json:"-"`
+	Exception *string `json:"-"`
+}
+
+type LatencyRuleDao interface {
+	Add(conn SqlLike, r *LatencyRule) (int64, error)
+	Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*LatencyRule, error)
+	Count(conn SqlLike, q *GeneralQuery) (int64, error)
+	SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error)
+	Update(conn SqlLike, q *GeneralQuery, d *LatencyUpdateSet) (int64, error)
+	Copy(conn SqlLike, q *GeneralQuery) (int64, error)
+	GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*LatencyRule, error)
+}
+
+type LatencyRuleSQLDao struct {
+}
+
+var latencyFlds = []string{
+	fldID,
+	fldName,
+	fldScore,
+	fldLatSecond,
+	fldLatTime,
+	fldTrigger,
+	fldExcpt,
+	fldEnterprise,
+	fldIsDelete,
+	fldCreateTime,
+	fldUpdateTime,
+	fldUUID,
+}
+
+//Add inserts a new record
+func (s *LatencyRuleSQLDao) Add(conn SqlLike, r *LatencyRule) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if r == nil {
+		return 0, ErrNeedRequest
+	}
+	flds := quoteFlds(latencyFlds, "")
+
+	vals := make([]interface{}, 0, len(flds))
+	err := extractSimpleStructureValue(&vals, r)
+	if err != nil {
+		return 0, err
+	}
+	//remove the ID
+	vals = vals[1:]
+	flds = flds[1:]
+
+	return insertRow(conn, tblLatencyRule, flds, vals)
+}
+
+//Get gets the data under the condition
+func (s *LatencyRuleSQLDao) Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*LatencyRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	flds := quoteFlds(latencyFlds, "")
+
+	var condition string
+	var params []interface{}
+	var err error
+	var offset string
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return nil, ErrGenCondition
+		}
+	}
+	if p != nil {
+		offset = p.offsetSQL()
+	}
+	querySQL := fmt.Sprintf("SELECT %s FROM %s %s %s", strings.Join(flds, ","), tblLatencyRule, condition, offset)
+	return getLatencyRules(conn, querySQL, params)
+}
+
+//Count counts number of the rows under the condition
+func (s *LatencyRuleSQLDao) Count(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	var condition string
+	var params []interface{}
+	var err error
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return 0, ErrGenCondition
+		}
+	}
+	return countRows(conn, tblLatencyRule, condition, params)
+}
+
+//SoftDelete simply set the is_delete to 1
+func (s *LatencyRuleSQLDao) SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return softDelete(conn, q, tblLatencyRule)
+}
+
+//Update updates the records
+func (s *LatencyRuleSQLDao) Update(conn SqlLike, q *GeneralQuery, d *LatencyUpdateSet) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	flds := []string{
+		fldName,
+		fldScore,
+		fldLatSecond,
+		fldLatTime,
+		fldTrigger,
+		fldExcpt,
+	}
+	return updateSQL(conn, q, d, tblLatencyRule, flds)
+}
+
+//Copy copys only one record, only use the first ID in q
+func (s *LatencyRuleSQLDao) Copy(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return copyRow(conn, tblLatencyRule, latencyFlds[1:], q.ID[0])
+}
+
+//GetByRuleGroup gets the rule under the conditon of RuleGroup.
+//Hence, q is the condition for getting RuleGroup
+func (s *LatencyRuleSQLDao) GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*LatencyRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	if q == nil || len(q.UUID) == 0 {
+		return nil, ErrNeedCondition
+	}
+	query, params := ruleByGroupSQL(q, latencyFlds, tblRelRGLatency, tblLatencyRule, fldLatencyUUID)
+	return getLatencyRules(conn, query, params)
+}
+
+func getLatencyRules(conn SqlLike, querySQL string, params []interface{}) ([]*LatencyRule, error) {
+	rows, err := conn.Query(querySQL, params...)
+	if err != nil {
+		logger.Error.Printf("query failed. %s %+v\n", querySQL, params)
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*LatencyRule, 0, 4)
+	for rows.Next() {
+		var d LatencyRule
+		err = rows.Scan(&d.ID, &d.Name, &d.Score, &d.Seconds, &d.Times,
+			&d.Trigger, &d.Exception, &d.Enterprise, &d.IsDelete, &d.CreateTime, &d.UpdateTime, &d.UUID)
+		if err != nil {
+			logger.Error.Printf("scan failed. %s\n", err)
+			return nil, err
+		}
+		resp = append(resp, &d)
+	}
+	return resp, nil
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//MonologueRule limits how long the staff can keep talking without the customer's response
+type MonologueRule struct {
+	ID         int64  `json:"-"`
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	Seconds    int    `json:"seconds"`
+	Times      int    `json:"times"`
+	Exception  string `json:"-"`
+	Enterprise string `json:"-"`
+	IsDelete   int    `json:"-"`
+	CreateTime int64  `json:"-"`
+	UpdateTime int64  `json:"-"`
+	UUID       string `json:"monologue_id"`
+}
+
+type MonologueUpdateSet struct {
+	Name      *string `json:"name"`
+	Score     *int    `json:"score"`
+	Seconds   *int    `json:"seconds"`
+	Times     *int    `json:"times"`
+	Exception *string `json:"-"`
+}
+
+type MonologueRuleDao interface {
+	Add(conn SqlLike, r *MonologueRule) (int64, error)
+	Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*MonologueRule, error)
+	Count(conn SqlLike, q *GeneralQuery) (int64, error)
+	SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error)
+	Update(conn SqlLike, q *GeneralQuery, d *MonologueUpdateSet) (int64, error)
+	Copy(conn SqlLike, q *GeneralQuery) (int64, error)
+	GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*MonologueRule, error)
+}
+
+type MonologueRuleSQLDao struct {
+}
+
+var monologueFlds = []string{
+	fldID,
+	fldName,
+	fldScore,
+	fldMonoSecond,
+	fldMonoTime,
+	fldExcpt,
+	fldEnterprise,
+	fldIsDelete,
+	fldCreateTime,
+	fldUpdateTime,
+	fldUUID,
+}
+
+//Add inserts a new record
+func (s *MonologueRuleSQLDao) Add(conn SqlLike, r *MonologueRule) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if r == nil {
+		return 0, ErrNeedRequest
+	}
+	flds := quoteFlds(monologueFlds, "")
+
+	vals := make([]interface{}, 0, len(flds))
+	err := extractSimpleStructureValue(&vals, r)
+	if err != nil {
+		return 0, err
+	}
+	//remove the ID
+	vals = vals[1:]
+	flds = flds[1:]
+
+	return insertRow(conn, tblMonologueRule, flds, vals)
+}
+
+//Get gets the data under the condition
+func (s *MonologueRuleSQLDao) Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*MonologueRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	flds := quoteFlds(monologueFlds, "")
+
+	var condition string
+	var params []interface{}
+	var err error
+	var offset string
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return nil, ErrGenCondition
+		}
+	}
+	if p != nil {
+		offset = p.offsetSQL()
+	}
+	querySQL := fmt.Sprintf("SELECT %s FROM %s %s %s", strings.Join(flds, ","), tblMonologueRule, condition, offset)
+	return getMonologueRules(conn, querySQL, params)
+}
+
+//Count counts number of the rows under the condition
+func (s *MonologueRuleSQLDao) Count(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	var condition string
+	var params []interface{}
+	var err error
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return 0, ErrGenCondition
+		}
+	}
+	return countRows(conn, tblMonologueRule, condition, params)
+}
+
+//SoftDelete simply set the is_delete to 1
+func (s *MonologueRuleSQLDao) SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return softDelete(conn, q, tblMonologueRule)
+}
+
+//Update updates the records
+func (s *MonologueRuleSQLDao) Update(conn SqlLike, q *GeneralQuery, d *MonologueUpdateSet) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	flds := []string{
+		fldName,
+		fldScore,
+		fldMonoSecond,
+		fldMonoTime,
+		fldExcpt,
+	}
+	return updateSQL(conn, q, d, tblMonologueRule, flds)
+}
+
+//Copy copys only one record, only use the first ID in q
+func (s *MonologueRuleSQLDao) Copy(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return copyRow(conn, tblMonologueRule, monologueFlds[1:], q.ID[0])
+}
+
+//GetByRuleGroup gets the rule under the conditon of RuleGroup.
+//Hence, q is the condition for getting RuleGroup
+func (s *MonologueRuleSQLDao) GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*MonologueRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	if q == nil || len(q.UUID) == 0 {
+		return nil, ErrNeedCondition
+	}
+	query, params := ruleByGroupSQL(q, monologueFlds, tblRelRGMonologue, tblMonologueRule, fldMonologueUUID)
+	return getMonologueRules(conn, query, params)
+}
+
+func getMonologueRules(conn SqlLike, querySQL string, params []interface{}) ([]*MonologueRule, error) {
+	rows, err := conn.Query(querySQL, params...)
+	if err != nil {
+		logger.Error.Printf("query failed. %s %+v\n", querySQL, params)
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*MonologueRule, 0, 4)
+	for rows.Next() {
+		var d MonologueRule
+		err = rows.Scan(&d.ID, &d.Name, &d.Score, &d.Seconds, &d.Times,
+			&d.Exception, &d.Enterprise, &d.IsDelete, &d.CreateTime, &d.UpdateTime, &d.UUID)
+		if err != nil {
+			logger.Error.Printf("scan failed. %s\n", err)
+			return nil, err
+		}
+		resp = append(resp, &d)
+	}
+	return resp, nil
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//TalkRatioRule bounds the percentage of the staff talk time to the total talk time
+type TalkRatioRule struct {
+	ID         int64  `json:"-"`
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	Min        int    `json:"min"`
+	Max        int    `json:"max"`
+	Exception  string `json:"-"`
+	Enterprise string `json:"-"`
+	IsDelete   int    `json:"-"`
+	CreateTime int64  `json:"-"`
+	UpdateTime int64  `json:"-"`
+	UUID       string `json:"talk_ratio_id"`
+}
+
+type TalkRatioUpdateSet struct {
+	Name      *string `json:"name"`
+	Score     *int    `json:"score"`
+	Min       *int    `json:"min"`
+	Max       *int    `json:"max"`
+	Exception *string `json:"-"`
+}
+
+type TalkRatioRuleDao interface {
+	Add(conn SqlLike, r *TalkRatioRule) (int64, error)
+	Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*TalkRatioRule, error)
+	Count(conn SqlLike, q *GeneralQuery) (int64, error)
+	SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error)
+	Update(conn SqlLike, q *GeneralQuery, d *TalkRatioUpdateSet) (int64, error)
+	Copy(conn SqlLike, q *GeneralQuery) (int64, error)
+	GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*TalkRatioRule, error)
+}
+
+type TalkRatioRuleSQLDao struct {
+}
+
+var talkRatioFlds = []string{
+	fldID,
+	fldName,
+	fldScore,
+	fldMin,
+	fldMax,
+	fldExcpt,
+	fldEnterprise,
+	fldIsDelete,
+	fldCreateTime,
+	fldUpdateTime,
+	fldUUID,
+}
+
+//Add inserts a new record
+func (s *TalkRatioRuleSQLDao) Add(conn SqlLike, r *TalkRatioRule) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if r == nil {
+		return 0, ErrNeedRequest
+	}
+	flds := quoteFlds(talkRatioFlds, "")
+
+	vals := make([]interface{}, 0, len(flds))
+	err := extractSimpleStructureValue(&vals, r)
+	if err != nil {
+		return 0, err
+	}
+	//remove the ID
+	vals = vals[1:]
+	flds = flds[1:]
+
+	return insertRow(conn, tblTalkRatioRule, flds, vals)
+}
+
+//Get gets the data under the condition
+func (s *TalkRatioRuleSQLDao) Get(conn SqlLike, q *GeneralQuery, p *Pagination) ([]*TalkRatioRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	flds := quoteFlds(talkRatioFlds, "")
+
+	var condition string
+	var params []interface{}
+	var err error
+	var offset string
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return nil, ErrGenCondition
+		}
+	}
+	if p != nil {
+		offset = p.offsetSQL()
+	}
+	querySQL := fmt.Sprintf("SELECT %s FROM %s %s %s", strings.Join(flds, ","), tblTalkRatioRule, condition, offset)
+	return getTalkRatioRules(conn, querySQL, params)
+}
+
+//Count counts number of the rows under the condition
+func (s *TalkRatioRuleSQLDao) Count(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	var condition string
+	var params []interface{}
+	var err error
+	if q != nil {
+		condition, params, err = q.whereSQL()
+		if err != nil {
+			return 0, ErrGenCondition
+		}
+	}
+	return countRows(conn, tblTalkRatioRule, condition, params)
+}
+
+//SoftDelete simply set the is_delete to 1
+func (s *TalkRatioRuleSQLDao) SoftDelete(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return softDelete(conn, q, tblTalkRatioRule)
+}
+
+//Update updates the records
+func (s *TalkRatioRuleSQLDao) Update(conn SqlLike, q *GeneralQuery, d *TalkRatioUpdateSet) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	flds := []string{
+		fldName,
+		fldScore,
+		fldMin,
+		fldMax,
+		fldExcpt,
+	}
+	return updateSQL(conn, q, d, tblTalkRatioRule, flds)
+}
+
+//Copy copys only one record, only use the first ID in q
+func (s *TalkRatioRuleSQLDao) Copy(conn SqlLike, q *GeneralQuery) (int64, error) {
+	if conn == nil {
+		return 0, ErroNoConn
+	}
+	if q == nil || (len(q.ID) == 0) {
+		return 0, ErrNeedCondition
+	}
+	return copyRow(conn, tblTalkRatioRule, talkRatioFlds[1:], q.ID[0])
+}
+
+//GetByRuleGroup gets the rule under the conditon of RuleGroup.
+//Hence, q is the condition for getting RuleGroup
+func (s *TalkRatioRuleSQLDao) GetByRuleGroup(conn SqlLike, q *GeneralQuery) ([]*TalkRatioRule, error) {
+	if conn == nil {
+		return nil, ErroNoConn
+	}
+	if q == nil || len(q.UUID) == 0 {
+		return nil, ErrNeedCondition
+	}
+	query, params := ruleByGroupSQL(q, talkRatioFlds, tblRelRGTalkRatio, tblTalkRatioRule, fldTalkRatioUUID)
+	return getTalkRatioRules(conn, query, params)
+}
+
+func getTalkRatioRules(conn SqlLike, querySQL string, params []interface{}) ([]*TalkRatioRule, error) {
+	rows, err := conn.Query(querySQL, params...)
+	if err != nil {
+		logger.Error.Printf("query failed. %s %+v\n", querySQL, params)
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*TalkRatioRule, 0, 4)
+	for rows.Next() {
+		var d TalkRatioRule
+		err = rows.Scan(&d.ID, &d.Name, &d.Score, &d.Min, &d.Max,
+			&d.Exception, &d.Enterprise, &d.IsDelete, &d.CreateTime, &d.UpdateTime, &d.UUID)
+		if err != nil {
+			logger.Error.Printf("scan failed. %s\n", err)
+			return nil, err
+		}
+		resp = append(resp, &d)
+	}
+	return resp, nil
+}
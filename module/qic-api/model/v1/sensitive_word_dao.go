@@ -34,6 +34,7 @@ type SensitiveWordFilter struct {
 	Page       int
 	Limit      int
 	Deleted    *int8
+	Locale     []string
 }
 
 func (filter *SensitiveWordFilter) Where() (string, []interface{}) {
@@ -58,6 +59,10 @@ func (filter *SensitiveWordFilter) Where() (string, []interface{}) {
 	if filter.Deleted != nil {
 		builder.Eq(fldIsDelete, *filter.Deleted)
 	}
+
+	if len(filter.Locale) > 0 {
+		builder.In(fldLocale, stringToWildCard(filter.Locale...))
+	}
 	return builder.ParseWithWhere()
 }
 
@@ -82,6 +87,7 @@ type SensitiveWord struct {
 	Enterprise        string
 	CategoryID        int64
 	UserValues        []UserValue
+	Locale            string //the locale the word used for, empty means all locales
 }
 
 type SensitiveWordSqlDao struct {
@@ -106,6 +112,7 @@ func getSensitiveWordInsertSQL(words []SensitiveWord) (insertStr string, values
 		fldEnterprise,
 		fldScore,
 		fldCategoryID,
+		fldLocale,
 	}
 	fieldStr := strings.Join(fields, ", ")
 
@@ -123,6 +130,7 @@ func getSensitiveWordInsertSQL(words []SensitiveWord) (insertStr string, values
 			word.Enterprise,
 			word.Score,
 			word.CategoryID,
+			word.Locale,
 		)
 
 		valueStr = fmt.Sprintf("%s%s,", valueStr, variableStr)
@@ -248,6 +256,7 @@ func getSensitiveWordQuerySQL(filter *SensitiveWordFilter) (queryStr string, val
 		fldEnterprise,
 		fldScore,
 		fldCategoryID,
+		fldLocale,
 	}
 	fieldStr := strings.Join(fields, ", ")
 
@@ -312,6 +321,7 @@ func (dao *SensitiveWordSqlDao) GetBy(filter *SensitiveWordFilter, sqlLike SqlLi
 			&word.Enterprise,
 			&word.Score,
 			&word.CategoryID,
+			&word.Locale,
 		)
 		sensitiveWords = append(sensitiveWords, word)
 		sensitiveIDs = append(sensitiveIDs, word.ID)
@@ -50,6 +50,7 @@ type SentenceQuery struct {
 	CategoryID *uint64
 	Name       *string
 	FuzzyName  string
+	Locale     []string
 }
 
 //SentenceNewRecord is used to create a new sentence
@@ -74,6 +75,7 @@ type Sentence struct {
 	CreateTime int64
 	UpdateTime int64
 	TagIDs     []uint64
+	Locale     string //the locale the sentence used for, empty means all locales
 }
 
 func ToSimpleSentences(ss []*Sentence) []SimpleSentence {
@@ -131,6 +133,7 @@ func (q *SentenceQuery) whereSQL() (string, []interface{}) {
 	if q.FuzzyName != "" {
 		builder.Like(fldName, q.FuzzyName)
 	}
+	builder.In(fldLocale, stringToWildCard(q.Locale...))
 	whereSQL, params := builder.ParseWithWhere()
 	whereSQL += " ORDER BY " + fldID + " DESC "
 
@@ -154,8 +157,8 @@ func (d *SentenceSQLDao) GetSentences(tx SqlLike, sq *SentenceQuery) ([]*Sentenc
 		condition, params = sq.whereSQL()
 	}
 
-	queryStr := fmt.Sprintf("SELECT a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,b.%s FROM (SELECT * FROM %s %s) as a LEFT JOIN %s as b on a.%s=b.%s",
-		fldID, fldIsDelete, fldName, fldEnterprise, fldUUID, fldCreateTime, fldUpdateTime, fldCategoryID, fldRelTagID,
+	queryStr := fmt.Sprintf("SELECT a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,a.%s,b.%s FROM (SELECT * FROM %s %s) as a LEFT JOIN %s as b on a.%s=b.%s",
+		fldID, fldIsDelete, fldName, fldEnterprise, fldUUID, fldCreateTime, fldUpdateTime, fldCategoryID, fldLocale, fldRelTagID,
 		tblSentence, condition,
 		tblRelSenTag, fldID, fldRelSenID)
 
@@ -171,7 +174,7 @@ func (d *SentenceSQLDao) GetSentences(tx SqlLike, sq *SentenceQuery) ([]*Sentenc
 	for rows.Next() {
 		var s Sentence
 		var tagID sql.NullInt64
-		err = rows.Scan(&s.ID, &s.IsDelete, &s.Name, &s.Enterprise, &s.UUID, &s.CreateTime, &s.UpdateTime, &s.CategoryID, &tagID)
+		err = rows.Scan(&s.ID, &s.IsDelete, &s.Name, &s.Enterprise, &s.UUID, &s.CreateTime, &s.UpdateTime, &s.CategoryID, &s.Locale, &tagID)
 		if err != nil {
 			logger.Error.Printf("Scan error. %s\n", err)
 			return nil, err
@@ -200,7 +203,7 @@ func getSentenceInsertSQL(sentences []Sentence) (insertStr string, values []inte
 	}
 
 	fields := []string{
-		fldIsDelete, fldName, fldEnterprise, fldUUID, fldCreateTime, fldUpdateTime, fldCategoryID,
+		fldIsDelete, fldName, fldEnterprise, fldUUID, fldCreateTime, fldUpdateTime, fldCategoryID, fldLocale,
 	}
 	fieldStr := strings.Join(fields, ", ")
 
@@ -209,7 +212,7 @@ func getSentenceInsertSQL(sentences []Sentence) (insertStr string, values []inte
 	for _, s := range sentences {
 		values = append(
 			values,
-			s.IsDelete, s.Name, s.Enterprise, s.UUID, s.CreateTime, s.UpdateTime, s.CategoryID,
+			s.IsDelete, s.Name, s.Enterprise, s.UUID, s.CreateTime, s.UpdateTime, s.CategoryID, s.Locale,
 		)
 		valueStr = fmt.Sprintf("%s%s,", valueStr, variableStr)
 	}
@@ -60,6 +60,7 @@ type Tag struct {
 	UpdateTime       int64
 	Enterprise       string // the id of enterprise the tag belong to.
 	UUID             string // the presentation id for the tag, which will not changed after created
+	Locale           string // the locale the tag used for, empty means all locales
 }
 
 // TagQuery is the query against tag datastore, all fields is the condition which should be nilable.
@@ -70,6 +71,7 @@ type TagQuery struct {
 	Enterprise       *string
 	Name             *string
 	TagType          []int8
+	Locale           []string
 	UpdateTimeStart  int64
 	UpdateTimeEnd    int64
 	IgnoreSoftDelete bool
@@ -95,6 +97,9 @@ func (t *TagQuery) whereSQL() (string, []interface{}) {
 	if len(t.TagType) > 0 {
 		builder.In(fldTagType, int8ToWildCard(t.TagType...))
 	}
+	if len(t.Locale) > 0 {
+		builder.In(fldTagLocale, stringToWildCard(t.Locale...))
+	}
 	if !t.IgnoreSoftDelete {
 		builder.Eq(fldTagIsDeleted, t.IgnoreSoftDelete)
 	}
@@ -110,7 +115,7 @@ var tagSelectColumns = []string{
 	fldTagID, fldTagUUID, fldTagIsDeleted,
 	fldTagName, fldTagType, fldTagPosSen,
 	fldTagNegSen, fldTagCreateTime, fldTagUpdateTime,
-	fldTagEnterprise}
+	fldTagEnterprise, fldTagLocale}
 
 // Tags fetch the tag resource from db or tx.
 // query determine condition and how many it should fetch.
@@ -145,7 +150,7 @@ func (t *TagSQLDao) Tags(tx SqlLike, query TagQuery) ([]Tag, error) {
 			posSen sql.NullString
 		)
 		rows.Scan(&tag.ID, &tag.UUID, &tag.IsDeleted, &tag.Name,
-			&tag.Typ, &posSen, &negSen, &tag.CreateTime, &tag.UpdateTime, &tag.Enterprise)
+			&tag.Typ, &posSen, &negSen, &tag.CreateTime, &tag.UpdateTime, &tag.Enterprise, &tag.Locale)
 		if !negSen.Valid {
 			tag.NegativeSentence = "[]"
 		}
@@ -174,7 +179,7 @@ func (t *TagSQLDao) NewTags(tx SqlLike, tags []Tag) ([]Tag, error) {
 	tagInsertColumns := []string{
 		fldTagUUID, fldTagEnterprise, fldTagName,
 		fldTagType, fldTagPosSen, fldTagNegSen,
-		fldTagCreateTime, fldTagUpdateTime,
+		fldTagCreateTime, fldTagUpdateTime, fldTagLocale,
 	}
 	rawsql := "INSERT INTO `" + tblTags + "`(`" + strings.Join(tagInsertColumns, "` , `") + "`) VALUE (?" + strings.Repeat(", ?", len(tagInsertColumns)-1) + ")"
 	stmt, err := s.Prepare(rawsql)
@@ -189,7 +194,7 @@ func (t *TagSQLDao) NewTags(tx SqlLike, tags []Tag) ([]Tag, error) {
 		result, err := stmt.Exec(
 			t.UUID, t.Enterprise, t.Name,
 			t.Typ, t.PositiveSentence, t.NegativeSentence,
-			t.CreateTime, t.UpdateTime,
+			t.CreateTime, t.UpdateTime, t.Locale,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("insert sql failed, %v", err)
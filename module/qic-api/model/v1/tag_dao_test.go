@@ -56,7 +56,7 @@ func TestTagDaoTags(t *testing.T) {
 	for _, tag := range expectedTags {
 		rows.AddRow(tag.ID, tag.UUID, tag.IsDeleted, tag.Name,
 			tag.Typ, tag.PositiveSentence, tag.NegativeSentence,
-			tag.CreateTime, tag.UpdateTime, tag.Enterprise)
+			tag.CreateTime, tag.UpdateTime, tag.Enterprise, tag.Locale)
 	}
 	mocker.ExpectQuery("SELECT .+ FROM `" + tblTags + "`").WillReturnRows(rows)
 	tags, err := dao.Tags(nil, TagQuery{
@@ -28,6 +28,7 @@ type TModel struct {
 	CreateTime int64
 	UpdateTime int64
 	Status     int
+	Locale     string //the locale of the tags trained in the model, empty means the model of all tags
 }
 
 //error message
@@ -40,6 +41,7 @@ type TModelQuery struct {
 	ID         []uint64
 	Status     *int
 	Enterprise *string
+	Locale     *string
 }
 
 func (q *TModelQuery) whereSQL() (condition string, params []interface{}) {
@@ -60,6 +62,10 @@ func (q *TModelQuery) whereSQL() (condition string, params []interface{}) {
 		flds = append(flds, fldEnterprise+"=?")
 		params = append(params, *q.Enterprise)
 	}
+	if q.Locale != nil {
+		flds = append(flds, fldLocale+"=?")
+		params = append(params, *q.Locale)
+	}
 	if len(flds) > 0 {
 		condition = "WHERE "
 		condition = condition + strings.Join(flds, " AND ")
@@ -85,6 +91,7 @@ func (m *TrainedModelSQLDao) TrainedModelInfo(conn SqlLike, q *TModelQuery) ([]*
 		fldUpdateTime,
 		fldStatus,
 		fldEnterprise,
+		fldLocale,
 	}
 	querySQL := fmt.Sprintf("SELECT %s FROM %s %s",
 		strings.Join(flds, ","),
@@ -100,7 +107,7 @@ func (m *TrainedModelSQLDao) TrainedModelInfo(conn SqlLike, q *TModelQuery) ([]*
 	models := make([]*TModel, 0, 10)
 	for rows.Next() {
 		var m TModel
-		err = rows.Scan(&m.ID, &m.CreateTime, &m.UpdateTime, &m.Status, &m.Enterprise)
+		err = rows.Scan(&m.ID, &m.CreateTime, &m.UpdateTime, &m.Status, &m.Enterprise, &m.Locale)
 		if err != nil {
 			logger.Error.Printf("%s\n", err)
 			return nil, err
@@ -124,13 +131,14 @@ func (m *TrainedModelSQLDao) NewModel(conn SqlLike, q *TModel) (int64, error) {
 		fldUpdateTime,
 		fldStatus,
 		fldEnterprise,
+		fldLocale,
 	}
 
 	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s)",
 		tblTrainedModel, strings.Join(flds, ","),
 		strings.Repeat(",?", len(flds)-1))
 
-	res, err := conn.Exec(insertSQL, q.CreateTime, q.CreateTime, q.Status, q.Enterprise)
+	res, err := conn.Exec(insertSQL, q.CreateTime, q.CreateTime, q.Status, q.Enterprise, q.Locale)
 	if err != nil {
 		logger.Error.Printf("insert model info failed.%s\n sql:%s\n", err, insertSQL)
 		return 0, err
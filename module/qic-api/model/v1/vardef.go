@@ -43,17 +43,30 @@ const (
 	tblTrainedModel          = "TrainedModel"
 	tblNavigation            = "Navigation"
 	tblRelNavSenGrp          = "Relation_Nav_SenGrp"
+	tblNavAdherence          = "NavAdherence"
 	tblSilenceRule           = "SilenceRule"
 	tblSpeedRule             = "SpeedRule"
 	tblInterposalRule        = "InterposalRule"
 	tblRelRGSilence          = "Relation_RuleGroup_Silence"
 	tblRelRGInterposal       = "Relation_RuleGroup_Interposal"
 	tblRelRGSpeed            = "Relation_RuleGroup_Speed"
+	tblMonologueRule         = "MonologueRule"
+	tblTalkRatioRule         = "TalkRatioRule"
+	tblHoldRule              = "HoldRule"
+	tblLatencyRule           = "LatencyRule"
+	tblRelRGMonologue        = "Relation_RuleGroup_Monologue"
+	tblRelRGTalkRatio        = "Relation_RuleGroup_TalkRatio"
+	tblRelRGHold             = "Relation_RuleGroup_Hold"
+	tblRelRGLatency          = "Relation_RuleGroup_Latency"
 	tblCallGroup             = "CallGroup"
 	tblRelCallGroupCall      = "Relation_CallGroup_Call"
 	tblCallGroupCondition    = "CallGroupCondition"
 	tblCallGroupConditionKey = "CallGroupConditionKey"
 	tblPredictResultGroup    = "CUPredictResultGroup"
+	tblCalibration           = "Calibration"
+	tblRelCalibrationOutline = "Relation_Calibration_Outline"
+	tblRelCalibrationCall    = "Relation_Calibration_Call_Staff"
+	tblCalibrationScore      = "CalibrationScore"
 )
 
 //field name in Conversation table
@@ -122,6 +135,7 @@ const (
 	fldTagCreateTime = "create_time"
 	fldTagUpdateTime = "update_time"
 	fldTagEnterprise = "enterprise"
+	fldTagLocale     = "locale"
 )
 const (
 	fldCUPredict = "predict"
@@ -232,6 +246,7 @@ const (
 	fldMin         = "min"
 	fldMax         = "max"
 	fldNodeOrder   = "node_order"
+	fldLocale      = "locale"
 
 	fldSilSecond   = "silence_sec"
 	fldSilTime     = "silence_time"
@@ -244,10 +259,23 @@ const (
 	fldOverLappedSec   = "overlapped_sec"
 	fldOverLappedTimes = "overlapped_time"
 
+	fldMonoSecond = "monologue_sec"
+	fldMonoTime   = "monologue_time"
+	fldHoldSecond = "hold_sec"
+	fldHoldTime   = "hold_time"
+	fldLatSecond  = "latency_sec"
+	fldLatTime    = "latency_time"
+	fldTrigger    = "trigger_sg"
+	fldExcpt      = "exception"
+
 	fldRGUUID         = "rg_uuid"
 	fldInterposalUUID = "int_uuid"
 	fldSpeedUUID      = "spe_uuid"
 	fldSilenceUUID    = "sil_uuid"
+	fldMonologueUUID  = "mon_uuid"
+	fldTalkRatioUUID  = "tr_uuid"
+	fldHoldUUID       = "hold_uuid"
+	fldLatencyUUID    = "lat_uuid"
 
 	fldDurationMin = "duration_min"
 	fldDurationMax = "duration_max"
@@ -337,6 +365,8 @@ const (
 	fldCallDemoFilePath     = "demo_file_path"
 	fldCallDeal             = "deal"
 	fldCallRemoteFile       = "remote_file"
+	fldCallLocale           = "locale"
+	fldCallAdherenceScore   = "adherence_score"
 	fldWhos                 = "whos"
 )
 
@@ -369,12 +399,23 @@ const (
 	RITStaffStaffID = "staff_id"
 )
 
+// fields for Calibration, Relation_Calibration_Outline, Relation_Calibration_Call_Staff and CalibrationScore
+const (
+	CALIBCalibrationID = "calibration_id"
+	CALIBOutlineID     = "outline_id"
+	CALIBStaffID       = "staff_id"
+	CALIBCallID        = "call_id"
+	CALIBConsensus     = "consensus"
+	CALIBFullScore     = "full_score"
+)
+
 const (
-	fldParentID = "parent_id"
-	fldOrgID    = "org_id"
-	fldValid    = "valid"
-	fldRevise   = "revise"
-	fldScore    = "score"
+	fldParentID   = "parent_id"
+	fldOrgID      = "org_id"
+	fldValid      = "valid"
+	fldRevise     = "revise"
+	fldScore      = "score"
+	fldIncomplete = "incomplete"
 )
 
 const (
@@ -387,9 +428,16 @@ const (
 	fldIntenName   = "intent_name"
 	fldIgoreIntent = "ignore_intent"
 	fldIntentLink  = "intent_link_id"
+	fldAdherence   = "adherence"
 )
 
 const (
 	fldNavID    = "nav_id"
 	fldSenGrpID = "sg_id"
 )
+
+//field name in NavAdherence table
+const (
+	fldAdhCallID = "call_id"
+	fldAdhReport = "report"
+)
@@ -286,7 +286,7 @@ func CreditWorkflow(tx model.SQLTx, c *model.Call, segments []model.RealSegment)
 		return fmt.Errorf("create root call %d credit failed, %s", rootID, err)
 	}
 	if len(groups) != 0 {
-		credits, err := RuleGroupCriteria(groups, segWithSp, time.Duration(30)*time.Minute)
+		credits, err := RuleGroupCriteria(groups, segWithSp, c.Locale, time.Duration(30)*time.Minute)
 		if err != nil {
 			return fmt.Errorf("get rule group credit failed, %v", err)
 		}
@@ -302,8 +302,10 @@ func CreditWorkflow(tx model.SQLTx, c *model.Call, segments []model.RealSegment)
 			var rulesWithException []RulesException
 			var combineCredit machineCredit
 			combineCredit.credit = credits[idx]
+			//tag matched data of this group, every rule in the group checks its exception with it
+			matched := credits[idx].Matched
 
-			silenceCredit, err := RuleSilenceCheck(grp, allSegs, credits[0].Matched)
+			silenceCredit, err := RuleSilenceCheck(grp, allSegs, matched)
 			if err != nil {
 				return fmt.Errorf("get silence rule credit failed, %v", err)
 			}
@@ -315,7 +317,7 @@ func CreditWorkflow(tx model.SQLTx, c *model.Call, segments []model.RealSegment)
 				staffSpeed = *c.RightSpeed
 			}
 
-			speedCredit, err := RuleSpeedCheck(grp, credits[0].Matched, segWithSp, staffSpeed)
+			speedCredit, err := RuleSpeedCheck(grp, matched, segWithSp, staffSpeed)
 			if err != nil {
 				return fmt.Errorf("get speed rule credit failed, %v", err)
 			}
@@ -323,14 +325,34 @@ func CreditWorkflow(tx model.SQLTx, c *model.Call, segments []model.RealSegment)
 			if err != nil {
 				return fmt.Errorf("get speed rule credit failed, %v", err)
 			}
+			monologueCredit, err := RuleMonologueCheck(grp, allSegs, matched)
+			if err != nil {
+				return fmt.Errorf("get monologue rule credit failed, %v", err)
+			}
+			talkRatioCredit, err := RuleTalkRatioCheck(grp, segWithSp, matched)
+			if err != nil {
+				return fmt.Errorf("get talk ratio rule credit failed, %v", err)
+			}
+			holdCredit, err := RuleHoldCheck(grp, allSegs, matched)
+			if err != nil {
+				return fmt.Errorf("get hold rule credit failed, %v", err)
+			}
+			latencyCredit, err := RuleLatencyCheck(grp, segWithSp, matched)
+			if err != nil {
+				return fmt.Errorf("get latency rule credit failed, %v", err)
+			}
 			rulesWithException = append(rulesWithException, silenceCredit...)
 			rulesWithException = append(rulesWithException, speedCredit...)
 			rulesWithException = append(rulesWithException, interposalCredit...)
+			rulesWithException = append(rulesWithException, monologueCredit...)
+			rulesWithException = append(rulesWithException, talkRatioCredit...)
+			rulesWithException = append(rulesWithException, holdCredit...)
+			rulesWithException = append(rulesWithException, latencyCredit...)
 			combineCredit.others = rulesWithException
 			machineCredits = append(machineCredits, combineCredit)
 			for _, r := range rulesWithException {
 				score += r.Score
-				credits[idx].Score += r.Score //Add the silence/interposal/speed score to rule group
+				credits[idx].Score += r.Score //Add the silence/interposal/speed/talk-time score to rule group
 			}
 		}
 		err = StoreMachineCredit(tx, uint64(c.ID), uint64(rootID), machineCredits)
@@ -339,7 +361,7 @@ func CreditWorkflow(tx model.SQLTx, c *model.Call, segments []model.RealSegment)
 		}
 	}
 
-	swCredits, err := SensitiveWordsVerificationWithPacked(c.ID, segWithSp, c.EnterpriseID)
+	swCredits, err := SensitiveWordsVerificationWithPacked(c.ID, segWithSp, c.EnterpriseID, c.Locale)
 	if err != nil {
 		return err
 	}
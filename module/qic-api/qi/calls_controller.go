@@ -14,6 +14,7 @@ import (
 
 	"emotibot.com/emotigo/module/qic-api/model/v1"
 	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 
 	"emotibot.com/emotigo/pkg/logger"
 
@@ -229,6 +230,9 @@ func extractNewCallReq(r *http.Request) (*NewCallReq, error) {
 	if _, found := callTypeDict[reqBody.RightChannel]; !found {
 		return nil, fmt.Errorf("request body's right channel value %s is not valid. the mapping should be %v", reqBody.RightChannel, callTypeDict)
 	}
+	if !locale.IsValid(reqBody.Locale) {
+		return nil, fmt.Errorf("request body's locale %s is not valid", reqBody.Locale)
+	}
 	enterprise := requestheader.GetEnterpriseID(r)
 	if enterprise == "" {
 		return nil, fmt.Errorf("enterpriseID is required")
@@ -267,6 +271,7 @@ type NewCallReq struct {
 	CustomColumns map[string]interface{} `json:"-"` //Custom columns of the call.
 	RemoteFile    string                 `json:"remote_file"`
 	CallSource    int8                   `json:"call_source"`
+	Locale        string                 `json:"locale"`
 }
 
 // ReservedCustomKeywords is a list of keywords reserved at NewCallReq.
@@ -387,6 +392,8 @@ type CallResp struct {
 	LeftSilenceRate  float64                `json:"left_silence_rate,omitempty"`
 	RightSilenceRate float64                `json:"right_silence_rate,omitempty"`
 	Segments         []segment              `json:"segments,omitempty"`
+	Locale           string                 `json:"locale,omitempty"`
+	AdherenceScore   *float64               `json:"adherence_score,omitempty"` //nil if the navigation flow adherence is not evaluated
 	CustomColumns    map[string]interface{} `json:"-"`
 }
 
@@ -415,7 +422,7 @@ func (c CallResp) MarshalJSON() ([]byte, error) {
 				if f.Int() == 0 {
 					continue
 				}
-			case reflect.Slice, reflect.Array, reflect.Map:
+			case reflect.Slice, reflect.Map, reflect.Ptr:
 				if f.IsNil() {
 					continue
 				}
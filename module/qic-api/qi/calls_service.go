@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 )
 
 //ErrNotFound is indicated the resource is asked, but nowhere to found it.
@@ -119,27 +120,29 @@ var (
 			}
 
 			r := CallResp{
-				CallID:        c.ID,
-				CallUUID:      c.UUID,
-				FileName:      *c.FileName,
-				CallTime:      c.CallUnixTime,
-				CallComment:   *c.Description,
-				Transaction:   c.IsDeal,
-				HostID:        c.StaffID,
-				HostName:      c.StaffName,
-				Extension:     c.Ext,
-				Department:    c.Department,
-				CustomerID:    c.CustomerID,
-				CustomerName:  c.CustomerName,
-				CustomerPhone: c.CustomerPhone,
-				LeftChannel:   callRoleTypStr(c.LeftChanRole),
-				RightChannel:  callRoleTypStr(c.RightChanRole),
-				Status:        int64(c.Status),
-				UploadTime:    c.UploadUnixTime,
-				CallLength:    float64(c.DurationMillSecond) / 1000,
-				LeftSpeed:     c.LeftSpeed,
-				RightSpeed:    c.RightSpeed,
-				CustomColumns: callCustomCols,
+				CallID:         c.ID,
+				CallUUID:       c.UUID,
+				FileName:       *c.FileName,
+				CallTime:       c.CallUnixTime,
+				CallComment:    *c.Description,
+				Transaction:    c.IsDeal,
+				HostID:         c.StaffID,
+				HostName:       c.StaffName,
+				Extension:      c.Ext,
+				Department:     c.Department,
+				CustomerID:     c.CustomerID,
+				CustomerName:   c.CustomerName,
+				CustomerPhone:  c.CustomerPhone,
+				LeftChannel:    callRoleTypStr(c.LeftChanRole),
+				RightChannel:   callRoleTypStr(c.RightChanRole),
+				Status:         int64(c.Status),
+				UploadTime:     c.UploadUnixTime,
+				CallLength:     float64(c.DurationMillSecond) / 1000,
+				LeftSpeed:      c.LeftSpeed,
+				RightSpeed:     c.RightSpeed,
+				Locale:         c.Locale,
+				AdherenceScore: c.AdherenceScore,
+				CustomColumns:  callCustomCols,
 			}
 			if c.LeftSilenceTime != nil {
 				r.LeftSilenceTime = *c.LeftSilenceTime
@@ -216,6 +219,10 @@ func NewCall(c *NewCallReq) (*model.Call, error) {
 		RightChanRole:  callRoleTyp(c.RightChannel),
 		Type:           c.Type,
 		RemoteFile:     &c.RemoteFile,
+		Locale:         c.Locale,
+	}
+	if call.Locale == "" {
+		call.Locale = locale.Default
 	}
 
 	calls, err := callDao.NewCalls(tx, []model.Call{*call})
@@ -29,14 +29,20 @@ var (
 	levSilenceTyp    levelType = 11
 	levSpeedTyp      levelType = 12
 	levInterposalTyp levelType = 13
+	levMonologueTyp  levelType = 14
+	levTalkRatioTyp  levelType = 15
+	levHoldTyp       levelType = 16
+	levLatencyTyp    levelType = 17
 
 	levLStaffSenTyp    levelType = 41
 	levLCustomerSenTyp levelType = 42
 	levUStaffSenTyp    levelType = 43
 	levUCustomerSenTyp levelType = 44
+	levExcptSenGrpTyp  levelType = 45
 
 	levSegSilenceTyp    levelType = 51
 	levSegInterposalTyp levelType = 53
+	levSegTalkTyp       levelType = 54
 )
 
 var unactivate = -1
@@ -84,6 +90,9 @@ func StoreCredit(call uint64, credits []*RuleGrpCredit) error {
 		s.Type = int(levRuleGrpTyp)
 		s.Valid = unactivate
 		s.Revise = unactivate
+		if credit.Incomplete {
+			s.Incomplete = 1
+		}
 
 		lastID, err := creditDao.InsertCredit(tx, s)
 		if err != nil {
@@ -99,6 +108,9 @@ func StoreCredit(call uint64, credits []*RuleGrpCredit) error {
 			if rule.Valid {
 				s.Valid = matched
 			}
+			if rule.Incomplete {
+				s.Incomplete = 1
+			}
 
 			parentRule, err := creditDao.InsertCredit(tx, s)
 			if err != nil {
@@ -371,6 +383,12 @@ func checkAndSetSenID(senSetIDMap map[uint64]*DataSentence,
 	}
 }
 
+func newTalkRuleCredit(v *model.SimpleCredit) TalkRuleCredit {
+	return TalkRuleCredit{ID: int64(v.OrgID), Valid: validMap[v.Valid], Score: v.Score, CreditID: int64(v.ID),
+		Revise: v.Revise, Comment: v.Comment, InvalidSegs: []SegmentTimeRange{},
+		Exception: TalkExceptionCredit{SentenceGroups: []*SenGrpExceptionCredit{}}}
+}
+
 func setSentenceWithPredictionInfo(sp []*SentenceWithPrediction) {
 	for _, s := range sp {
 		if s != nil && s.Credit != nil {
@@ -415,6 +433,7 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 	}
 	var rgIDs, ruleIDs, cfIDs, senGrpIDs, senIDs, segIDs []uint64
 	var silenceIDs, speedIDs, interposalIDs []int64
+	var monologueIDs, talkRatioIDs, holdIDs, latencyIDs []int64
 	var invalidSegsID []int64
 
 	rgCreditsMap := make(map[uint64]*RuleGrpCredit)
@@ -431,6 +450,13 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 	silenceSegIDMap := make(map[uint64][]*SilenceRuleCredit)       //silence segment id to silence rule credit
 	interposalSegIDMap := make(map[uint64][]*InterposalRuleCredit) //interposal segment id to interposal rule credit
 
+	rTalkCreditMap := make(map[uint64]*TalkRuleCredit)        //talk-time rules. use the id in the CUPredictReuslt as key
+	rMonologueIDMap := make(map[int64][]*MonologueRuleCredit) //monologue of rule. use the id in the MonologueRule as the key
+	rTalkRatioIDMap := make(map[int64][]*TalkRatioRuleCredit) //talk ratio of rule. use the id in the TalkRatioRule as the key
+	rHoldIDMap := make(map[int64][]*HoldRuleCredit)           //hold of rule. use the id in the HoldRule as the key
+	rLatencyIDMap := make(map[int64][]*LatencyRuleCredit)     //latency of rule. use the id in the LatencyRule as the key
+	talkSegIDMap := make(map[uint64][]*TalkRuleCredit)        //talk-time segment id to talk-time rule credit
+
 	sensitiveCreditIDMap := make(map[uint64]*SWRuleCredit) //key is the id in the CUPredictResult
 	sensitiveCreditMap := make(map[uint64]*SWRuleCredit)   //key is the id in the SW
 	swSenCreditsMap := make(map[uint64]*SentenceCredit)
@@ -479,8 +505,10 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 					continue
 				}
 			}
-			credit := &RuleGrpCredit{ID: v.OrgID, Score: v.Score,
-				SpeedRule: []*SpeedRuleCredit{}, SilenceRule: []*SilenceRuleCredit{}, InterposalRule: []*InterposalRuleCredit{}}
+			credit := &RuleGrpCredit{ID: v.OrgID, Score: v.Score, Incomplete: v.Incomplete == 1,
+				SpeedRule: []*SpeedRuleCredit{}, SilenceRule: []*SilenceRuleCredit{}, InterposalRule: []*InterposalRuleCredit{},
+				MonologueRule: []*MonologueRuleCredit{}, TalkRatioRule: []*TalkRatioRuleCredit{},
+				HoldRule: []*HoldRuleCredit{}, LatencyRule: []*LatencyRuleCredit{}}
 			history.Credit = append(history.Credit, credit)
 			rgCreditsMap[v.ID] = credit
 			if set, ok := rgSetIDMap[v.OrgID]; ok {
@@ -493,7 +521,8 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 			rgIDs = append(rgIDs, v.OrgID)
 		case levRuleTyp:
 			if parentCredit, ok := rgCreditsMap[v.ParentID]; ok {
-				credit := &RuleCredit{ID: v.OrgID, Score: v.Score, Valid: validMap[v.Valid], CreditID: int64(v.ID), Revise: v.Revise, Comment: v.Comment}
+				credit := &RuleCredit{ID: v.OrgID, Score: v.Score, Valid: validMap[v.Valid], CreditID: int64(v.ID), Revise: v.Revise, Comment: v.Comment,
+					Incomplete: v.Incomplete == 1}
 				rCreditsMap[v.ID] = credit
 				if set, ok := rSetIDMap[v.OrgID]; ok {
 					credit.Setting = set
@@ -612,6 +641,56 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 				rInterposalCreditMap[v.ID] = credit
 				rInterposalIDMap[int64(v.OrgID)] = append(rInterposalIDMap[int64(v.OrgID)], credit)
 			}
+		case levMonologueTyp:
+			if parentCredit, ok := rgCreditsMap[v.ParentID]; ok {
+				credit := &MonologueRuleCredit{TalkRuleCredit: newTalkRuleCredit(v)}
+				parentCredit.MonologueRule = append(parentCredit.MonologueRule, credit)
+				monologueIDs = append(monologueIDs, int64(v.OrgID))
+				rTalkCreditMap[v.ID] = &credit.TalkRuleCredit
+				rMonologueIDMap[int64(v.OrgID)] = append(rMonologueIDMap[int64(v.OrgID)], credit)
+			}
+		case levTalkRatioTyp:
+			if parentCredit, ok := rgCreditsMap[v.ParentID]; ok {
+				credit := &TalkRatioRuleCredit{TalkRuleCredit: newTalkRuleCredit(v)}
+				parentCredit.TalkRatioRule = append(parentCredit.TalkRatioRule, credit)
+				talkRatioIDs = append(talkRatioIDs, int64(v.OrgID))
+				rTalkCreditMap[v.ID] = &credit.TalkRuleCredit
+				rTalkRatioIDMap[int64(v.OrgID)] = append(rTalkRatioIDMap[int64(v.OrgID)], credit)
+			}
+		case levHoldTyp:
+			if parentCredit, ok := rgCreditsMap[v.ParentID]; ok {
+				credit := &HoldRuleCredit{TalkRuleCredit: newTalkRuleCredit(v)}
+				parentCredit.HoldRule = append(parentCredit.HoldRule, credit)
+				holdIDs = append(holdIDs, int64(v.OrgID))
+				rTalkCreditMap[v.ID] = &credit.TalkRuleCredit
+				rHoldIDMap[int64(v.OrgID)] = append(rHoldIDMap[int64(v.OrgID)], credit)
+			}
+		case levLatencyTyp:
+			if parentCredit, ok := rgCreditsMap[v.ParentID]; ok {
+				credit := &LatencyRuleCredit{TalkRuleCredit: newTalkRuleCredit(v)}
+				parentCredit.LatencyRule = append(parentCredit.LatencyRule, credit)
+				latencyIDs = append(latencyIDs, int64(v.OrgID))
+				rTalkCreditMap[v.ID] = &credit.TalkRuleCredit
+				rLatencyIDMap[int64(v.OrgID)] = append(rLatencyIDMap[int64(v.OrgID)], credit)
+			}
+		case levExcptSenGrpTyp:
+			if pCredit, ok := rTalkCreditMap[v.ParentID]; ok {
+				credit := &SenGrpExceptionCredit{ID: v.OrgID, Valid: validMap[v.Valid]}
+				if set, ok := senGrpSetIDMap[v.OrgID]; ok {
+					credit.Setting = set
+				} else {
+					set := &SentenceGroupInResponse{}
+					senGrpSetIDMap[v.OrgID] = set
+					credit.Setting = set
+					senGrpIDs = append(senGrpIDs, v.OrgID)
+				}
+				pCredit.Exception.SentenceGroups = append(pCredit.Exception.SentenceGroups, credit)
+			}
+		case levSegTalkTyp:
+			if pCredit, ok := rTalkCreditMap[v.ParentID]; ok {
+				invalidSegsID = append(invalidSegsID, int64(v.OrgID))
+				talkSegIDMap[v.OrgID] = append(talkSegIDMap[v.OrgID], pCredit)
+			}
 		case levLStaffSenTyp:
 
 			if pCredit, ok := rSilenceCreditMap[v.ParentID]; ok {
@@ -903,6 +982,59 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 		}
 	}
 
+	if len(monologueIDs) > 0 {
+		monologueRules, err := GetRuleMonologues(&model.GeneralQuery{ID: monologueIDs}, nil)
+		if err != nil {
+			logger.Error.Printf("get monologue rule failed. %s\n", err)
+			return nil, err
+		}
+		for _, mr := range monologueRules {
+			for _, c := range rMonologueIDMap[mr.ID] {
+				c.Name = mr.Name
+				c.Setting = *mr
+			}
+		}
+	}
+	if len(talkRatioIDs) > 0 {
+		talkRatioRules, err := GetRuleTalkRatios(&model.GeneralQuery{ID: talkRatioIDs}, nil)
+		if err != nil {
+			logger.Error.Printf("get talk ratio rule failed. %s\n", err)
+			return nil, err
+		}
+		for _, tr := range talkRatioRules {
+			for _, c := range rTalkRatioIDMap[tr.ID] {
+				c.Name = tr.Name
+				c.Setting = *tr
+			}
+		}
+	}
+	if len(holdIDs) > 0 {
+		holdRules, err := GetRuleHolds(&model.GeneralQuery{ID: holdIDs}, nil)
+		if err != nil {
+			logger.Error.Printf("get hold rule failed. %s\n", err)
+			return nil, err
+		}
+		for _, hr := range holdRules {
+			for _, c := range rHoldIDMap[hr.ID] {
+				c.Name = hr.Name
+				c.Setting = *hr
+			}
+		}
+	}
+	if len(latencyIDs) > 0 {
+		latencyRules, err := GetRuleLatencies(&model.GeneralQuery{ID: latencyIDs}, nil)
+		if err != nil {
+			logger.Error.Printf("get latency rule failed. %s\n", err)
+			return nil, err
+		}
+		for _, lr := range latencyRules {
+			for _, c := range rLatencyIDMap[lr.ID] {
+				c.Name = lr.Name
+				c.Setting = *lr
+			}
+		}
+	}
+
 	//fill up the sensitive setting information
 	if len(swIDs) > 0 {
 		_, sws, err := sensitive.GetSensitiveWords(&model.SensitiveWordFilter{ID: swIDs})
@@ -970,6 +1102,9 @@ func buildHistroyCreditTree(callIDs []int64, credits []*model.SimpleCredit) ([]*
 				pCredit.InvalidSegs = append(pCredit.InvalidSegs, SegmentTimeRange{Start: v.StartTime, End: v.EndTime})
 			}
 		}
+		for _, pCredit := range talkSegIDMap[uint64(v.ID)] {
+			pCredit.InvalidSegs = append(pCredit.InvalidSegs, SegmentTimeRange{Start: v.StartTime, End: v.EndTime})
+		}
 	}
 
 	//desc order
@@ -987,6 +1122,10 @@ const (
 	Silence
 	Speed
 	Interposal
+	Monologue
+	TalkRatio
+	Hold
+	Latency
 )
 
 type ExceptionMatched struct {
@@ -1006,6 +1145,7 @@ type RulesException struct {
 	Exception      []*ExceptionMatched
 	SilenceSegs    []int64 //the segment id that break the silence rule
 	InterposalSegs []int64 // the segment id that break the interposal rule
+	TalkSegs       []int64 //the segment id that break the talk-time rules
 }
 
 //StoreRulesException stores the rule exception
@@ -1088,6 +1228,18 @@ func storeRulesException(tx model.SqlLike, credits []RulesException, parentID ui
 			}
 		}
 
+		//talk-time segs
+		for _, segID := range r.TalkSegs {
+			s := &model.SimpleCredit{CallID: uint64(r.CallID), Type: int(levSegTalkTyp), ParentID: uint64(rParent),
+				OrgID: uint64(segID), Score: 0, CreateTime: now, Revise: unactivate, Valid: unactivate, Whos: int(r.Whos)}
+
+			_, err = creditDao.InsertCredit(tx, s)
+			if err != nil {
+				logger.Error.Printf("insert matched tag segment  %+v failed. %s\n", s, err)
+				return err
+			}
+		}
+
 	}
 	return nil
 }
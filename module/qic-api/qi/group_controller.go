@@ -31,6 +31,10 @@ type NewGroupReq struct {
 	SilenceRules    []string `json:"silence_rules"`
 	SpeedRules      []string `json:"speed_rules"`
 	InterposalRules []string `json:"interposal_rules"`
+	MonologueRules  []string `json:"monologue_rules"`
+	TalkRatioRules  []string `json:"talk_ratio_rules"`
+	HoldRules       []string `json:"hold_rules"`
+	LatencyRules    []string `json:"latency_rules"`
 }
 
 //Group transfer NewGroupReq as a model.Group struct, any virtual fields(etc: Other, Rules...) should be handled by the caller.
@@ -44,6 +48,10 @@ func (n *NewGroupReq) Group() model.Group {
 		SilenceRules:    make([]model.SilenceRule, 0),
 		SpeedRules:      make([]model.SpeedRule, 0),
 		InterposalRules: make([]model.InterposalRule, 0),
+		MonologueRules:  make([]model.MonologueRule, 0),
+		TalkRatioRules:  make([]model.TalkRatioRule, 0),
+		HoldRules:       make([]model.HoldRule, 0),
+		LatencyRules:    make([]model.LatencyRule, 0),
 	}
 }
 
@@ -333,6 +341,10 @@ func handleGetGroup(w http.ResponseWriter, r *http.Request, group *model.Group)
 		SilenceRules []GeneralRuleResp `json:"silence_rules"`
 		SpeedRules   []GeneralRuleResp `json:"speed_rules"`
 		Interposal   []GeneralRuleResp `json:"interposal_rules"`
+		Monologue    []GeneralRuleResp `json:"monologue_rules"`
+		TalkRatio    []GeneralRuleResp `json:"talk_ratio_rules"`
+		Hold         []GeneralRuleResp `json:"hold_rules"`
+		Latency      []GeneralRuleResp `json:"latency_rules"`
 	}
 	var err error
 	group, err = GetGroupRules(*group)
@@ -346,7 +358,8 @@ func handleGetGroup(w http.ResponseWriter, r *http.Request, group *model.Group)
 		util.ReturnError(w, AdminErrors.ErrnoDBError, fmt.Sprintf("get con"))
 		return
 	}
-	ruleCount := len(group.Rules) + len(group.SpeedRules) + len(group.SilenceRules) + len(group.InterposalRules)
+	ruleCount := len(group.Rules) + len(group.SpeedRules) + len(group.SilenceRules) + len(group.InterposalRules) +
+		len(group.MonologueRules) + len(group.TalkRatioRules) + len(group.HoldRules) + len(group.LatencyRules)
 	var resp = GroupDetailResp{
 		GroupResp: GroupResp{
 			GroupID:     group.UUID,
@@ -360,6 +373,10 @@ func handleGetGroup(w http.ResponseWriter, r *http.Request, group *model.Group)
 		SilenceRules: make([]GeneralRuleResp, 0),
 		SpeedRules:   make([]GeneralRuleResp, 0),
 		Interposal:   make([]GeneralRuleResp, 0),
+		Monologue:    make([]GeneralRuleResp, 0),
+		TalkRatio:    make([]GeneralRuleResp, 0),
+		Hold:         make([]GeneralRuleResp, 0),
+		Latency:      make([]GeneralRuleResp, 0),
 	}
 	if group.IsEnable {
 		resp.IsEnable = 1
@@ -392,6 +409,30 @@ func handleGetGroup(w http.ResponseWriter, r *http.Request, group *model.Group)
 			Name: r.Name,
 		})
 	}
+	for _, r := range group.MonologueRules {
+		resp.Monologue = append(resp.Monologue, GeneralRuleResp{
+			UUID: r.UUID,
+			Name: r.Name,
+		})
+	}
+	for _, r := range group.TalkRatioRules {
+		resp.TalkRatio = append(resp.TalkRatio, GeneralRuleResp{
+			UUID: r.UUID,
+			Name: r.Name,
+		})
+	}
+	for _, r := range group.HoldRules {
+		resp.Hold = append(resp.Hold, GeneralRuleResp{
+			UUID: r.UUID,
+			Name: r.Name,
+		})
+	}
+	for _, r := range group.LatencyRules {
+		resp.Latency = append(resp.Latency, GeneralRuleResp{
+			UUID: r.UUID,
+			Name: r.Name,
+		})
+	}
 	util.WriteJSON(w, resp)
 
 }
@@ -508,6 +549,78 @@ func handleUpdateGroup(w http.ResponseWriter, r *http.Request, group *model.Grou
 			newGroup.InterposalRules = append(newGroup.InterposalRules, *rule)
 		}
 	}
+	if len(reqBody.MonologueRules) > 0 {
+		rules, err := GetRuleMonologues(&model.GeneralQuery{
+			UUID:       reqBody.MonologueRules,
+			Enterprise: &newGroup.EnterpriseID,
+			IsDelete:   &notDeleted,
+		}, nil)
+		if err != nil {
+			util.ReturnError(w, AdminErrors.ErrnoDBError, fmt.Sprintf("get monologue rules failed, %v", err))
+			return
+		}
+		if len(reqBody.MonologueRules) != len(rules) {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid monologue rules input"))
+			return
+		}
+		for _, rule := range rules {
+			newGroup.MonologueRules = append(newGroup.MonologueRules, *rule)
+		}
+	}
+	if len(reqBody.TalkRatioRules) > 0 {
+		rules, err := GetRuleTalkRatios(&model.GeneralQuery{
+			UUID:       reqBody.TalkRatioRules,
+			Enterprise: &newGroup.EnterpriseID,
+			IsDelete:   &notDeleted,
+		}, nil)
+		if err != nil {
+			util.ReturnError(w, AdminErrors.ErrnoDBError, fmt.Sprintf("get talk ratio rules failed, %v", err))
+			return
+		}
+		if len(reqBody.TalkRatioRules) != len(rules) {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid talk ratio rules input"))
+			return
+		}
+		for _, rule := range rules {
+			newGroup.TalkRatioRules = append(newGroup.TalkRatioRules, *rule)
+		}
+	}
+	if len(reqBody.HoldRules) > 0 {
+		rules, err := GetRuleHolds(&model.GeneralQuery{
+			UUID:       reqBody.HoldRules,
+			Enterprise: &newGroup.EnterpriseID,
+			IsDelete:   &notDeleted,
+		}, nil)
+		if err != nil {
+			util.ReturnError(w, AdminErrors.ErrnoDBError, fmt.Sprintf("get hold rules failed, %v", err))
+			return
+		}
+		if len(reqBody.HoldRules) != len(rules) {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid hold rules input"))
+			return
+		}
+		for _, rule := range rules {
+			newGroup.HoldRules = append(newGroup.HoldRules, *rule)
+		}
+	}
+	if len(reqBody.LatencyRules) > 0 {
+		rules, err := GetRuleLatencies(&model.GeneralQuery{
+			UUID:       reqBody.LatencyRules,
+			Enterprise: &newGroup.EnterpriseID,
+			IsDelete:   &notDeleted,
+		}, nil)
+		if err != nil {
+			util.ReturnError(w, AdminErrors.ErrnoDBError, fmt.Sprintf("get latency rules failed, %v", err))
+			return
+		}
+		if len(reqBody.LatencyRules) != len(rules) {
+			util.ReturnError(w, AdminErrors.ErrnoRequestError, fmt.Sprintf("invalid latency rules input"))
+			return
+		}
+		for _, rule := range rules {
+			newGroup.LatencyRules = append(newGroup.LatencyRules, *rule)
+		}
+	}
 
 	err = UpdateGroup(newGroup, customConditions)
 	if err != nil {
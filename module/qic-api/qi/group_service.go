@@ -384,6 +384,58 @@ func GetGroupRules(group model.Group) (*model.Group, error) {
 			group.InterposalRules = append(group.InterposalRules, *r)
 		}
 	}
+	group.MonologueRules = make([]model.MonologueRule, 0)
+	if len(otherRules[model.GroupRuleTypeMonologue]) > 0 {
+		rs, err := GetRuleMonologues(&model.GeneralQuery{
+			UUID:     otherRules[model.GroupRuleTypeMonologue],
+			IsDelete: &isDeleted,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get monologue rules failed, %v", err)
+		}
+		for _, r := range rs {
+			group.MonologueRules = append(group.MonologueRules, *r)
+		}
+	}
+	group.TalkRatioRules = make([]model.TalkRatioRule, 0)
+	if len(otherRules[model.GroupRuleTypeTalkRatio]) > 0 {
+		rs, err := GetRuleTalkRatios(&model.GeneralQuery{
+			UUID:     otherRules[model.GroupRuleTypeTalkRatio],
+			IsDelete: &isDeleted,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get talk ratio rules failed, %v", err)
+		}
+		for _, r := range rs {
+			group.TalkRatioRules = append(group.TalkRatioRules, *r)
+		}
+	}
+	group.HoldRules = make([]model.HoldRule, 0)
+	if len(otherRules[model.GroupRuleTypeHold]) > 0 {
+		rs, err := GetRuleHolds(&model.GeneralQuery{
+			UUID:     otherRules[model.GroupRuleTypeHold],
+			IsDelete: &isDeleted,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get hold rules failed, %v", err)
+		}
+		for _, r := range rs {
+			group.HoldRules = append(group.HoldRules, *r)
+		}
+	}
+	group.LatencyRules = make([]model.LatencyRule, 0)
+	if len(otherRules[model.GroupRuleTypeLatency]) > 0 {
+		rs, err := GetRuleLatencies(&model.GeneralQuery{
+			UUID:     otherRules[model.GroupRuleTypeLatency],
+			IsDelete: &isDeleted,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get latency rules failed, %v", err)
+		}
+		for _, r := range rs {
+			group.LatencyRules = append(group.LatencyRules, *r)
+		}
+	}
 
 	return &group, nil
 }
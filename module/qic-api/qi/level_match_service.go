@@ -13,6 +13,7 @@ import (
 
 	model "emotibot.com/emotigo/module/qic-api/model/v1"
 	"emotibot.com/emotigo/module/qic-api/sensitive"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 	"emotibot.com/emotigo/module/qic-api/util/logicaccess"
 )
 
@@ -27,9 +28,11 @@ var (
 )
 
 //MatchedData stores the index of input and the matched ID (tag id) and its relative data
+//Unknown is true if the prediction of this index failed, so Matched may be incomplete
 type MatchedData struct {
 	Index   int
 	Matched map[uint64]*logicaccess.AttrResult
+	Unknown bool
 	lock    sync.Mutex
 }
 
@@ -144,6 +147,50 @@ type InterposalRuleCredit struct {
 	InvalidSegs []SegmentTimeRange   `json:"invalid_segment"`
 }
 
+//SenGrpExceptionCredit is the sentence group used as the exception of the talk-time rules
+type SenGrpExceptionCredit struct {
+	ID      uint64                   `json:"id"`
+	Valid   bool                     `json:"valid"`
+	Setting *SentenceGroupInResponse `json:"setting"`
+}
+
+type TalkExceptionCredit struct {
+	SentenceGroups []*SenGrpExceptionCredit `json:"sentence_groups"`
+}
+
+//TalkRuleCredit is the common part of the talk-time rules credit
+type TalkRuleCredit struct {
+	ID          int64               `json:"id"`
+	Name        string              `json:"name"`
+	Valid       bool                `json:"valid"`
+	CreditID    int64               `json:"revise_id,string"`
+	Revise      int                 `json:"revise"`
+	Comment     string              `json:"comment"`
+	Score       int                 `json:"score"`
+	Exception   TalkExceptionCredit `json:"exception"`
+	InvalidSegs []SegmentTimeRange  `json:"invalid_segment"`
+}
+
+type MonologueRuleCredit struct {
+	TalkRuleCredit
+	Setting model.MonologueRule `json:"setting"`
+}
+
+type TalkRatioRuleCredit struct {
+	TalkRuleCredit
+	Setting model.TalkRatioRule `json:"setting"`
+}
+
+type HoldRuleCredit struct {
+	TalkRuleCredit
+	Setting model.HoldRule `json:"setting"`
+}
+
+type LatencyRuleCredit struct {
+	TalkRuleCredit
+	Setting model.LatencyRule `json:"setting"`
+}
+
 //RuleGrpCredit is the result of the segments
 type RuleGrpCredit struct {
 	ID      uint64            `json:"id"`
@@ -155,8 +202,16 @@ type RuleGrpCredit struct {
 	SilenceRule    []*SilenceRuleCredit    `json:"silence_rule"`
 	SpeedRule      []*SpeedRuleCredit      `json:"speed_rule"`
 	InterposalRule []*InterposalRuleCredit `json:"interposal_rule"`
+	MonologueRule  []*MonologueRuleCredit  `json:"monologue_rule"`
+	TalkRatioRule  []*TalkRatioRuleCredit  `json:"talk_ratio_rule"`
+	HoldRule       []*HoldRuleCredit       `json:"hold_rule"`
+	LatencyRule    []*LatencyRuleCredit    `json:"latency_rule"`
 
 	Matched []*MatchedData `json:"-"`
+	//Unknown is the segments index that failed to predict
+	Unknown []int `json:"unknown_segments,omitempty"`
+	//Incomplete is true if any rule is not determined because of the unknown segments
+	Incomplete bool `json:"incomplete"`
 }
 
 //RuleCredit stores the rule level result
@@ -169,6 +224,9 @@ type RuleCredit struct {
 	Comment  string                    `json:"comment"`
 	CFs      []*ConversationFlowCredit `json:"conversation_flow"`
 	Setting  *ConversationRuleInRes    `json:"setting"`
+	//Incomplete is true if the rule can not be determined because of the unknown segments,
+	//its score is excluded from the rule group
+	Incomplete bool `json:"incomplete,omitempty"`
 }
 
 //ConversationFlowCredit stores the conversation flow level result
@@ -198,6 +256,7 @@ type SentenceCredit struct {
 }
 
 // TagCredit stores the matched tag information and the segment id
+//
 //	Match is the keyword that segment matched.
 //	MatchTxt is the segment text that matched with the Match.
 type TagCredit struct {
@@ -331,52 +390,56 @@ type MatchedIdx struct {
 	MatchedID uint64
 }
 
-//Concurrency sets the number of goroutine used to call cu module
+//Concurrency sets the number of goroutine used to call cu module in one TagMatch,
+//the requests of all TagMatch are bounded by the scheduler of the predictor.
+//PredictBatchSize is the number of segments sent in one prediction request
 const (
-	Concurrency = 1
-	Threshold   = 60
+	Concurrency      = 4
+	Threshold        = 60
+	PredictBatchSize = 50
 )
 
-func worker(ctx context.Context, target <-chan uint64, errChan chan<- error,
+//predictJob is the unit of the prediction, segments[start:end] predicted by the model of the enterprise
+type predictJob struct {
+	model      uint64
+	enterprise string
+	start      int
+	end        int
+	done       bool
+}
+
+func worker(ctx context.Context, target <-chan *predictJob, errChan chan<- error,
 	segments []string, wg *sync.WaitGroup, collected []*MatchedData) {
 	defer wg.Done()
-	numOfData := len(collected) + 1
 	for {
 		select {
-		case id, more := <-target:
+		case job, more := <-target:
 			if !more {
 				return
 			}
-			pr, err := BatchPredict(id, Threshold, segments)
+			pr, err := BatchPredict(job.model, job.enterprise, Threshold, segments[job.start:job.end])
 			if err != nil {
-				errChan <- err
-				logger.Error.Printf("batch predict failed. %s\n", err)
-				return
-			}
-
-			for i := 0; i < len(pr.Dialogue); i++ {
-				v := pr.Dialogue[i]
-				if v.SentenceID > 0 && v.SentenceID < numOfData {
-					idx := v.SentenceID - 1
-					collected[idx].SetData(&v)
-				}
-			}
-
-			for i := 0; i < len(pr.Keyword); i++ {
-				v := pr.Keyword[i]
-				if v.SentenceID > 0 && v.SentenceID < numOfData {
-					idx := v.SentenceID - 1
-					collected[idx].SetData(&v)
+				select {
+				case errChan <- err:
+				default:
 				}
+				logger.Error.Printf("batch predict model %d on segment %d-%d failed. %s\n", job.model, job.start+1, job.end, err)
+				continue
 			}
 
-			for i := 0; i < len(pr.UsrResponse); i++ {
-				v := pr.UsrResponse[i]
-				if v.SentenceID > 0 && v.SentenceID < numOfData {
-					idx := v.SentenceID - 1
+			results := make([]logicaccess.AttrResult, 0, len(pr.Dialogue)+len(pr.Keyword)+len(pr.UsrResponse))
+			results = append(results, pr.Dialogue...)
+			results = append(results, pr.Keyword...)
+			results = append(results, pr.UsrResponse...)
+			for i := 0; i < len(results); i++ {
+				v := results[i]
+				if v.SentenceID > 0 && v.SentenceID <= job.end-job.start {
+					idx := job.start + v.SentenceID - 1
+					v.SentenceID = idx + 1
 					collected[idx].SetData(&v)
 				}
 			}
+			job.done = true
 		case <-ctx.Done():
 			return
 		}
@@ -385,11 +448,13 @@ func worker(ctx context.Context, target <-chan uint64, errChan chan<- error,
 }
 
 // TagMatch checks each segment for trained model.
+// segments are normalized before sending to the model, see locale.Normalize.
+// segments are predicted in batches, the segment in the failed batch is marked as Unknown
+// and the error is returned only if none of the batches succeeded.
 // return value: a slice of matchData gives the each sentences and its matched tag and matched data
 func TagMatch(modelIDs []uint64, segments []string, timeout time.Duration) ([]*MatchedData, error) {
 
 	numOfCtx := len(segments)
-	numOfModels := len(modelIDs)
 
 	if numOfCtx == 0 || len(modelIDs) == 0 {
 		return nil, ErrNoArgument
@@ -397,13 +462,7 @@ func TagMatch(modelIDs []uint64, segments []string, timeout time.Duration) ([]*M
 	if timeout <= 0 {
 		return nil, ErrTimeoutSet
 	}
-
-	//context and channel init
-	var wg sync.WaitGroup
-	wg.Add(Concurrency)
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	target := make(chan uint64, numOfModels)
-	defer cancel()
+	segments = locale.Normalizes(segments)
 
 	//init the response structure
 	matches := make([]*MatchedData, numOfCtx, numOfCtx)
@@ -415,23 +474,54 @@ func TagMatch(modelIDs []uint64, segments []string, timeout time.Duration) ([]*M
 
 	sort.Slice(modelIDs, func(i, j int) bool { return modelIDs[i] < modelIDs[j] })
 	var lastTag uint64
-	//start to input the target tag id
+	jobs := make([]*predictJob, 0)
 	for _, v := range modelIDs {
 		//avoid the duplicate tag
 		if lastTag != v {
-			target <- v
+			enterprise := modelEnterprise(v)
+			for start := 0; start < numOfCtx; start += PredictBatchSize {
+				end := start + PredictBatchSize
+				if end > numOfCtx {
+					end = numOfCtx
+				}
+				jobs = append(jobs, &predictJob{model: v, enterprise: enterprise, start: start, end: end})
+			}
 			lastTag = v
 		}
 	}
+
+	//context and channel init
+	var wg sync.WaitGroup
+	wg.Add(Concurrency)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	target := make(chan *predictJob, len(jobs))
+	for _, j := range jobs {
+		target <- j
+	}
 	close(target)
 
-	errChan := make(chan error, Concurrency)
+	errChan := make(chan error, 1)
 	//call goroutine to do job concurrency
 	for i := 0; i < Concurrency; i++ {
 		go worker(ctx, target, errChan, segments, &wg, matches)
 	}
 	wg.Wait()
 
+	var numOfDone int
+	for _, j := range jobs {
+		if j.done {
+			numOfDone++
+			continue
+		}
+		for idx := j.start; idx < j.end; idx++ {
+			matches[idx].Unknown = true
+		}
+	}
+	if numOfDone == len(jobs) {
+		return matches, nil
+	}
+
 	var err error
 	if len(errChan) > 0 {
 		err = <-errChan
@@ -439,8 +529,22 @@ func TagMatch(modelIDs []uint64, segments []string, timeout time.Duration) ([]*M
 	if ctx.Err() != nil {
 		err = ctx.Err()
 	}
+	if numOfDone == 0 {
+		return matches, err
+	}
+	logger.Warn.Printf("tag match is partial done, %d of %d predictions failed. %v\n", len(jobs)-numOfDone, len(jobs), err)
+	return matches, nil
+}
 
-	return matches, err
+//UnknownSegments gives the 1-based index of the segments whose prediction failed
+func UnknownSegments(matched []*MatchedData) []int {
+	resp := make([]int, 0)
+	for _, m := range matched {
+		if m != nil && m.Unknown {
+			resp = append(resp, m.Index)
+		}
+	}
+	return resp
 }
 
 //SentencesMatch gives the sentence id that is matched by which segment index
@@ -693,17 +797,8 @@ func RuleMatch(cfMatchID map[uint64]bool, criteria map[uint64]*RuleCriteria) (ma
 	resp := make(map[uint64]*RuleMatchedResult, len(criteria))
 	var totalScore int
 	for ruleID, criterion := range criteria {
-		var count int
-		var matched bool
 		var plus int
-		for _, cfID := range criterion.CFIDs {
-			if v, ok := cfMatchID[cfID]; ok && v {
-				count++
-			}
-		}
-		if count >= criterion.Min {
-			matched = true
-		}
+		matched := ruleReached(cfMatchID, criterion)
 		if criterion.Method == int(methodStringToCode["negative"]) {
 			matched = !matched
 		}
@@ -723,12 +818,24 @@ func RuleMatch(cfMatchID map[uint64]bool, criteria map[uint64]*RuleCriteria) (ma
 	return resp, totalScore, nil
 }
 
+//ruleReached checks whether the number of matched conversation flows reaches the minimum of the rule
+func ruleReached(cfMatchID map[uint64]bool, criterion *RuleCriteria) bool {
+	var count int
+	for _, cfID := range criterion.CFIDs {
+		if v, ok := cfMatchID[cfID]; ok && v {
+			count++
+		}
+	}
+	return count >= criterion.Min
+}
+
 // RuleGroupCriteria gives the result of the criteria used to the group
 // ruleGroup is the id of the rule group to validate.
 // segments must be sorted by time ascended.
+// loc is the locale of the call, only the tags and sentences used in the locale are matched.
 // timeout is used to wait for cu module result.
 // if success, a RuleGrpCredit is returned.
-func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker, timeout time.Duration) ([]*RuleGrpCredit, error) {
+func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker, loc string, timeout time.Duration) ([]*RuleGrpCredit, error) {
 	numOfLines := len(segments)
 	if numOfLines == 0 {
 		return nil, ErrNoArgument
@@ -739,18 +846,12 @@ func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker,
 
 	logger.Info.Printf("doing %+v rule group credit\n", ruleGroups)
 	enterprise := ruleGroups[0].EnterpriseID
-	models, err := GetUsingModelByEnterprise(enterprise)
+	//the model trained with the tags of the locale, so no tag of other locales is matched
+	predictModel, err := GetUsingModelByLocale(enterprise, loc)
 	if err != nil {
 		logger.Error.Printf("get the model failed. %s\n", err.Error())
 		return nil, err
 	}
-	numOfModels := len(models)
-	if numOfModels == 0 {
-		return nil, ErrNoModels
-	} else if numOfModels > 1 {
-		logger.Warn.Printf("More than 1 models is marked as using status with enterprise %s\n", enterprise)
-		logger.Warn.Printf("Using the first one %d as prediction model", models[0].ID)
-	}
 
 	//extract the words
 	lines := make([]string, 0, numOfLines)
@@ -762,13 +863,17 @@ func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker,
 
 	//--------------------------------------------------------------------------
 	//do the checking, tag match
-	tagMatchDat, err := TagMatch([]uint64{models[0].ID}, lines, timeout)
+	tagMatchDat, err := TagMatch([]uint64{predictModel.ID}, lines, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("tag match failed, %v", err)
 	}
 	if len(tagMatchDat) != numOfLines {
 		return nil, fmt.Errorf("get less tag match sentence %d with %d", len(tagMatchDat), numOfLines)
 	}
+	unknown := UnknownSegments(tagMatchDat)
+	if len(unknown) > 0 {
+		logger.Warn.Printf("%d segments failed to predict, the rules depending on them are not scored\n", len(unknown))
+	}
 
 	resp := make([]*RuleGrpCredit, 0, len(ruleGroups))
 	for _, ruleGroup := range ruleGroups {
@@ -807,8 +912,12 @@ func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker,
 		// every slice index is a segment, which has a bunch of uint64(tag id),
 		// use map for quick search later
 		segMatchedTag := extractTagMatchedData(tagMatchDat)
+		senCriteria, err := filterSentencesByLocale(levels[LevSentence], loc)
+		if err != nil {
+			return nil, err
+		}
 		//do the checking, sentence match
-		senMatchDat, err := SentencesMatch(segMatchedTag, levels[LevSentence])
+		senMatchDat, err := SentencesMatch(segMatchedTag, senCriteria)
 		if err != nil {
 			logger.Warn.Printf("doing sentence  match failed.%s\n", err)
 			return nil, err
@@ -1011,8 +1120,6 @@ func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker,
 		var rgCredit RuleGrpCredit
 
 		rgCredit.ID = uint64(ruleGroup.ID)
-		rgCredit.Plus = totalScore
-		rgCredit.Score = totalScore
 		for _, ruleID := range ruleIDs {
 			cfIDs := levels[LevRule][ruleID]
 			credit := &RuleCredit{ID: ruleID}
@@ -1020,6 +1127,14 @@ func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker,
 				credit.Valid = v.Valid
 				credit.Score = v.Score
 			}
+			//the unknown segments could only add the matched conversation flows,
+			//so the rule which doesn't reach the minimum is undetermined and excluded from scoring
+			if c, ok := ruleCriteria[ruleID]; ok && len(unknown) > 0 && !ruleReached(matchCFID, c) {
+				totalScore -= credit.Score
+				credit.Score = 0
+				credit.Incomplete = true
+				rgCredit.Incomplete = true
+			}
 
 			for _, cfID := range cfIDs {
 				if v, ok := cfCreditMap[cfID]; ok {
@@ -1031,7 +1146,10 @@ func RuleGroupCriteria(ruleGroups []model.Group, segments []*SegmentWithSpeaker,
 			}
 			rgCredit.Rules = append(rgCredit.Rules, credit)
 		}
+		rgCredit.Plus = totalScore
+		rgCredit.Score = totalScore
 		rgCredit.Matched = tagMatchDat
+		rgCredit.Unknown = unknown
 		resp = append(resp, &rgCredit)
 	}
 	return resp, nil
@@ -1049,18 +1167,11 @@ func SimpleSentenceMatch(segs []string, ids []uint64, enterprise string) (map[ui
 	}
 
 	//get the current model id by enterprise
-	models, err := GetUsingModelByEnterprise(enterprise)
+	predictModel, err := GetUsingModelByLocale(enterprise, "")
 	if err != nil {
 		logger.Error.Printf("get the model failed. %s\n", err.Error())
 		return nil, err
 	}
-	numOfModels := len(models)
-	if numOfModels == 0 {
-		return nil, ErrNoModels
-	} else if numOfModels > 1 {
-		logger.Warn.Printf("More than 1 models is marked as using status with enterprise %s\n", enterprise)
-		logger.Warn.Printf("Using the first one %d as prediction model", models[0].ID)
-	}
 
 	//get the relation table from Sentence to Tag
 	levels, _, err := GetLevelsRel(LevSentence, LevTag, ids, true)
@@ -1077,7 +1188,7 @@ func SimpleSentenceMatch(segs []string, ids []uint64, enterprise string) (map[ui
 	numOfLines := len(segs)
 	timeout := time.Duration(30 * time.Second)
 	//do the checking, tag match
-	tagMatchDat, err := TagMatch([]uint64{models[0].ID}, segs, timeout)
+	tagMatchDat, err := TagMatch([]uint64{predictModel.ID}, segs, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("tag match failed, %v", err)
 	}
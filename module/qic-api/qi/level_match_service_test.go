@@ -1,6 +1,8 @@
 package qi
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -129,6 +131,50 @@ func TestTagMatch(t *testing.T) {
 	}
 }
 
+type mockBatchFailPredictClient struct {
+	mockPredictClient
+}
+
+//BatchPredictAndUnMarshal fails the batch starts with "fail", otherwise matches tag 1 on every sentence
+func (m *mockBatchFailPredictClient) BatchPredictAndUnMarshal(d *logicaccess.BatchPredictRequest) (*logicaccess.PredictResult, error) {
+	if d.Data[0].Sentence == "fail" {
+		return nil, fmt.Errorf("mock failure")
+	}
+	var resp logicaccess.PredictResult
+	for _, v := range d.Data {
+		resp.Dialogue = append(resp.Dialogue, logicaccess.AttrResult{PredictData: *v, Tag: 1, Score: 90})
+	}
+	return &resp, nil
+}
+
+func TestTagMatchPartial(t *testing.T) {
+	defer func() { predictor = &mockPredictClient{} }()
+	predictor = &mockBatchFailPredictClient{}
+
+	segs := make([]string, PredictBatchSize+2)
+	for i := range segs {
+		segs[i] = "ok"
+	}
+	segs[PredictBatchSize] = "fail"
+	matched, err := TagMatch([]uint64{1}, segs, 3*time.Second)
+	if err != nil {
+		t.Fatalf("expecting partial result without error, but get %s\n", err)
+	}
+	unknown := UnknownSegments(matched)
+	if !reflect.DeepEqual(unknown, []int{PredictBatchSize + 1, PredictBatchSize + 2}) {
+		t.Errorf("expecting the second batch is unknown, but get %v\n", unknown)
+	}
+	if d := matched[PredictBatchSize-1].Matched[1]; d == nil || d.SentenceID != PredictBatchSize {
+		t.Errorf("expecting the last segment of first batch matched with its index, but get %+v\n", d)
+	}
+
+	segs[0] = "fail"
+	_, err = TagMatch([]uint64{1}, segs, 3*time.Second)
+	if err == nil {
+		t.Error("expecting error if all batches failed, but get nil")
+	}
+}
+
 func TestSentenceMatch(t *testing.T) {
 	predictor = &mockPredictClient{}
 	tags := mockAllTagID
@@ -641,6 +687,20 @@ func TestConversationFlowMatch(t *testing.T) {
 
 }
 
+func TestRuleReached(t *testing.T) {
+	cfMatchID := map[uint64]bool{1: true, 2: false, 3: true}
+	cases := map[*RuleCriteria]bool{
+		&RuleCriteria{Min: 2, CFIDs: []uint64{1, 2, 3}}: true,
+		&RuleCriteria{Min: 2, CFIDs: []uint64{1, 2, 4}}: false,
+		&RuleCriteria{Min: 0, CFIDs: []uint64{2}}:       true,
+	}
+	for c, expected := range cases {
+		if output := ruleReached(cfMatchID, c); output != expected {
+			t.Errorf("expecting %t for %+v, but get %t\n", expected, c, output)
+		}
+	}
+}
+
 func TestRuleMatch(t *testing.T) {
 
 	var criteria map[uint64]*RuleCriteria
@@ -1169,7 +1229,7 @@ func TestRuleGroupCriteria(t *testing.T) {
 
 	timeout := time.Duration(3 * time.Second)
 
-	cs, err := RuleGroupCriteria([]model.Group{model.Group{ID: 1}}, segments, timeout)
+	cs, err := RuleGroupCriteria([]model.Group{model.Group{ID: 1}}, segments, "", timeout)
 	if err != nil {
 		t.Errorf("expecting no error, but get %s\n", err)
 	} else {
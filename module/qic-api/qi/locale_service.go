@@ -0,0 +1,34 @@
+package qi
+
+import (
+	model "emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//filterSentencesByLocale gives the sentence to tags criteria which only contains the sentences used in the locale.
+//the given criteria is not modified
+func filterSentencesByLocale(criteria map[uint64][]uint64, loc string) (map[uint64][]uint64, error) {
+	if loc == "" || len(criteria) == 0 {
+		return criteria, nil
+	}
+	ids := make([]uint64, 0, len(criteria))
+	for sID := range criteria {
+		ids = append(ids, sID)
+	}
+	sentences, err := sentenceDao.GetSentences(nil, &model.SentenceQuery{ID: ids})
+	if err != nil {
+		logger.Error.Printf("get sentences for locale %s failed. %s\n", loc, err)
+		return nil, err
+	}
+	resp := make(map[uint64][]uint64, len(criteria))
+	for sID, tagIDs := range criteria {
+		resp[sID] = tagIDs
+	}
+	for _, s := range sentences {
+		if !locale.Applies(s.Locale, loc) {
+			delete(resp, s.ID)
+		}
+	}
+	return resp, nil
+}
@@ -128,11 +128,14 @@ func init() {
 			util.NewEntryPoint(http.MethodPut, "call-in/navigation/{id}/intent", []string{}, handleModifyIntent),
 			util.NewEntryPoint(http.MethodPost, "call-in/navigation/{id}/node", []string{}, handleNewNode),
 			util.NewEntryPoint(http.MethodPut, "call-in/navigation/{id}/node/order", []string{}, handleNodeOrder),
+			util.NewEntryPoint(http.MethodGet, "call-in/navigation/{id}/adherence", []string{}, handleGetFlowAdherence),
+			util.NewEntryPoint(http.MethodPut, "call-in/navigation/{id}/adherence", []string{}, handleModifyFlowAdherence),
 
 			util.NewEntryPoint(http.MethodPost, "call-in/conversation", []string{}, handleFlowCreate),
 			util.NewEntryPoint(http.MethodPut, "call-in/{id}", []string{}, WithFlowCallIDEnterpriseCheck(handleFlowFinish)),
 			util.NewEntryPoint(http.MethodPatch, "call-in/{id}", []string{}, callRequest(handleFlowUpdate)),
 			util.NewEntryPoint(http.MethodPost, "call-in/{id}/append", []string{}, handleStreaming),
+			util.NewEntryPoint(http.MethodGet, "call-in/{id}/adherence", []string{}, callRequest(handleGetCallAdherence)),
 			//util.NewEntryPoint(http.MethodGet, "call-in/{id}", []string{}, handleGetCurCheck),
 
 			util.NewEntryPoint(http.MethodGet, "backup/groups", []string{}, handleExportGroups),
@@ -167,6 +170,39 @@ func init() {
 			util.NewEntryPoint(http.MethodDelete, "rule/interposal/{id}", []string{}, handleDeleteRuleInterposal),
 			util.NewEntryPoint(http.MethodPut, "rule/interposal/{id}", []string{}, handleModifyRuleInterposal),
 
+			util.NewEntryPoint(http.MethodPost, "rule/monologue", []string{}, handleNewRuleMonologue),
+			util.NewEntryPoint(http.MethodGet, "rule/monologue", []string{}, handleGetRuleMonologueList),
+			util.NewEntryPoint(http.MethodGet, "rule/monologue/{id}", []string{}, handleGetRuleMonologue),
+			util.NewEntryPoint(http.MethodDelete, "rule/monologue/{id}", []string{}, handleDeleteRuleMonologue),
+			util.NewEntryPoint(http.MethodPut, "rule/monologue/{id}/name", []string{}, handleModifyRuleMonologue),
+			util.NewEntryPoint(http.MethodPut, "rule/monologue/{id}/condition", []string{}, handleModifyRuleMonologue),
+			util.NewEntryPoint(http.MethodPut, "rule/monologue/{id}/exception", []string{}, handleExceptionRuleMonologue),
+
+			util.NewEntryPoint(http.MethodPost, "rule/talk-ratio", []string{}, handleNewRuleTalkRatio),
+			util.NewEntryPoint(http.MethodGet, "rule/talk-ratio", []string{}, handleGetRuleTalkRatioList),
+			util.NewEntryPoint(http.MethodGet, "rule/talk-ratio/{id}", []string{}, handleGetRuleTalkRatio),
+			util.NewEntryPoint(http.MethodDelete, "rule/talk-ratio/{id}", []string{}, handleDeleteRuleTalkRatio),
+			util.NewEntryPoint(http.MethodPut, "rule/talk-ratio/{id}/name", []string{}, handleModifyRuleTalkRatio),
+			util.NewEntryPoint(http.MethodPut, "rule/talk-ratio/{id}/condition", []string{}, handleModifyRuleTalkRatio),
+			util.NewEntryPoint(http.MethodPut, "rule/talk-ratio/{id}/exception", []string{}, handleExceptionRuleTalkRatio),
+
+			util.NewEntryPoint(http.MethodPost, "rule/hold", []string{}, handleNewRuleHold),
+			util.NewEntryPoint(http.MethodGet, "rule/hold", []string{}, handleGetRuleHoldList),
+			util.NewEntryPoint(http.MethodGet, "rule/hold/{id}", []string{}, handleGetRuleHold),
+			util.NewEntryPoint(http.MethodDelete, "rule/hold/{id}", []string{}, handleDeleteRuleHold),
+			util.NewEntryPoint(http.MethodPut, "rule/hold/{id}/name", []string{}, handleModifyRuleHold),
+			util.NewEntryPoint(http.MethodPut, "rule/hold/{id}/condition", []string{}, handleModifyRuleHold),
+			util.NewEntryPoint(http.MethodPut, "rule/hold/{id}/exception", []string{}, handleExceptionRuleHold),
+
+			util.NewEntryPoint(http.MethodPost, "rule/latency", []string{}, handleNewRuleLatency),
+			util.NewEntryPoint(http.MethodGet, "rule/latency", []string{}, handleGetRuleLatencyList),
+			util.NewEntryPoint(http.MethodGet, "rule/latency/{id}", []string{}, handleGetRuleLatency),
+			util.NewEntryPoint(http.MethodDelete, "rule/latency/{id}", []string{}, handleDeleteRuleLatency),
+			util.NewEntryPoint(http.MethodPut, "rule/latency/{id}/name", []string{}, handleModifyRuleLatency),
+			util.NewEntryPoint(http.MethodPut, "rule/latency/{id}/condition", []string{}, handleModifyRuleLatency),
+			util.NewEntryPoint(http.MethodPut, "rule/latency/{id}/exception", []string{}, handleExceptionRuleLatency),
+			util.NewEntryPoint(http.MethodPut, "rule/latency/{id}/trigger", []string{}, handleTriggerRuleLatency),
+
 			util.NewEntryPoint(http.MethodGet, "testing/predict/sentences", []string{}, handlePredictSentences),
 			util.NewEntryPoint(http.MethodGet, "testing/sentences/{id}", []string{}, handleGetTestSentences),
 			util.NewEntryPoint(http.MethodPost, "testing/sentences", []string{}, handleNewTestSentence),
@@ -174,6 +210,7 @@ func init() {
 			util.NewEntryPoint(http.MethodGet, "testing/overview/sentences", []string{}, handleGetSentenceTestOverview),
 			util.NewEntryPoint(http.MethodGet, "testing/overview/sentences_info", []string{}, handleGetSentenceTestResult),
 			util.NewEntryPoint(http.MethodGet, "testing/overview/sentences_detail/{id}", []string{}, handleGetSentenceTestDetail),
+			util.NewEntryPoint(http.MethodGet, "testing/predict/stats", []string{}, handleGetPredictStats),
 
 			util.NewEntryPoint(http.MethodPost, "call-groups", []string{}, handleCreateCallGroupCondition),
 			util.NewEntryPoint(http.MethodGet, "call-groups", []string{}, handleGetCallGroupConditionList),
@@ -261,8 +298,9 @@ func init() {
 				sentenceTestDao = model.NewSentenceTestSQLDao(sqlConn)
 				// init cu trainer & predictor
 				cuURL := envs["LOGIC_PREDICT_URL"]
-				predictor = &logicaccess.Client{URL: cuURL, Timeout: time.Duration(300 * time.Second)}
-				trainer = &logicaccess.Client{URL: cuURL, Timeout: time.Duration(300 * time.Second)}
+				cuScheduler := newPredictScheduler(envs)
+				predictor = &logicaccess.Client{URL: cuURL, Timeout: time.Duration(300 * time.Second), Scheduler: cuScheduler}
+				trainer = &logicaccess.Client{URL: cuURL, Timeout: time.Duration(300 * time.Second), Scheduler: cuScheduler}
 				// init RABBITMQ client
 				host := envs["RABBITMQ_HOST"]
 				if host == "" {
@@ -0,0 +1,91 @@
+package qi
+
+import (
+	"net/http"
+	"strconv"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+func handleGetFlowAdherence(w http.ResponseWriter, r *http.Request) {
+	idStr := general.ParseID(r)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+	enterprise := requestheader.GetEnterpriseID(r)
+	setting, err := GetFlowAdherence(id, enterprise)
+	if err != nil {
+		logger.Error.Printf("get the flow adherence failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if setting == nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "no such id"), http.StatusBadRequest)
+		return
+	}
+	util.WriteJSON(w, setting)
+}
+
+func handleModifyFlowAdherence(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	idStr := general.ParseID(r)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+	var req AdherenceSetting
+	err = util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if req.Steps == nil {
+		req.Steps = make(map[string]*StepSetting)
+	}
+
+	affected, err := UpdateFlowAdherence(id, enterprise, &req)
+	if err != nil {
+		if err == ErrWrongTimeLimit {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+			return
+		}
+		logger.Error.Printf("update the flow adherence failed. id:%d, err: %s\n", id, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if affected == 0 {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "No such flow id"), http.StatusBadRequest)
+		return
+	}
+}
+
+func handleGetCallAdherence(w http.ResponseWriter, r *http.Request, call *model.Call) {
+	report, err := GetCallAdherence(call.ID)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		if call.Status != model.CallStatusDone {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "call is not finished"), http.StatusBadRequest)
+			return
+		}
+		//the call finished before the adherence is evaluated
+		report, err = CheckCallAdherence(call)
+		if err != nil {
+			logger.Error.Printf("check the adherence of call %d failed. %s\n", call.ID, err)
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+			return
+		}
+	}
+	util.WriteJSON(w, report)
+}
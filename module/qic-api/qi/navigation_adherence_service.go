@@ -0,0 +1,401 @@
+package qi
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	model "emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+var (
+	navAdherenceDao model.NavAdherenceDao = &model.NavAdherenceSQLDao{}
+)
+
+//ErrWrongTimeLimit is returned if the time limit of the step is negative
+var ErrWrongTimeLimit = errors.New("invalid time limit")
+
+//the status of the step in the adherence report
+const (
+	StepDone          = "done"
+	StepSkipped       = "skipped"
+	StepOutOfOrder    = "out_of_order"
+	StepOvertime      = "overtime"
+	StepNotApplicable = "not_applicable"
+)
+
+//AdherenceSetting is how the navigation flow is scored.
+//Ordered means the steps must follow the node order
+type AdherenceSetting struct {
+	Ordered bool                    `json:"ordered"`
+	Steps   map[string]*StepSetting `json:"steps"` //key is the node uuid
+}
+
+//StepSetting is the adherence setting of one node.
+//Branch is the sentence group uuid of the customer's intent, the step is only required if the branch is matched.
+//TimeLimit is the seconds allowed to finish the step after the previous step, or from the beginning of the call for the first step
+type StepSetting struct {
+	Optional  bool   `json:"optional"`
+	Branch    string `json:"branch"`
+	TimeLimit int    `json:"time_limit"`
+}
+
+//StepReport is the adherence result of one node
+type StepReport struct {
+	ID          string  `json:"sg_id"`
+	Name        string  `json:"name"`
+	Optional    bool    `json:"optional"`
+	Status      string  `json:"status"`
+	MatchedTime float64 `json:"matched_time"`
+}
+
+//FlowAdherence is the adherence result of one navigation flow
+type FlowAdherence struct {
+	NavID      int64        `json:"nav_id,string"`
+	Name       string       `json:"name"`
+	Percentage float64      `json:"percentage"`
+	Steps      []StepReport `json:"steps"`
+}
+
+//AdherenceReport is the post-call report of the navigation flows
+type AdherenceReport struct {
+	Percentage float64         `json:"percentage"`
+	Flows      []FlowAdherence `json:"flows"`
+}
+
+func newAdherenceSetting() *AdherenceSetting {
+	return &AdherenceSetting{Steps: make(map[string]*StepSetting)}
+}
+
+func parseAdherenceSetting(stored string) (*AdherenceSetting, error) {
+	resp := newAdherenceSetting()
+	if stored == "" {
+		return resp, nil
+	}
+	err := json.Unmarshal([]byte(stored), resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Steps == nil {
+		resp.Steps = make(map[string]*StepSetting)
+	}
+	return resp, nil
+}
+
+func checkAdherenceSetting(a *AdherenceSetting) error {
+	if a == nil {
+		return ErrEmptyRequest
+	}
+	for _, v := range a.Steps {
+		if v != nil && v.TimeLimit < 0 {
+			return ErrWrongTimeLimit
+		}
+	}
+	return nil
+}
+
+//GetFlowAdherence gets the adherence setting of the flow, returns nil if no such flow
+func GetFlowAdherence(nav int64, enterprise string) (*AdherenceSetting, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	isDelete := 0
+	q := &model.NavQuery{ID: []int64{nav}, IsDelete: &isDelete, Enterprise: &enterprise}
+	flows, err := navDao.GetFlows(dbLike.Conn(), q, nil)
+	if err != nil {
+		logger.Error.Printf("get flow failed. %s\n", err)
+		return nil, err
+	}
+	if len(flows) == 0 {
+		return nil, nil
+	}
+	return parseAdherenceSetting(flows[0].Adherence)
+}
+
+//UpdateFlowAdherence updates the adherence setting of the flow
+func UpdateFlowAdherence(nav int64, enterprise string, a *AdherenceSetting) (int64, error) {
+	err := checkAdherenceSetting(a)
+	if err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		logger.Error.Printf("marshal adherence setting failed. %s\n", err)
+		return 0, err
+	}
+	adherence := string(b)
+	return UpdateFlow(nav, enterprise, &model.NavFlowUpdate{Adherence: &adherence})
+}
+
+//GetCallAdherence gets the stored adherence report of the call, returns nil if it is not evaluated yet
+func GetCallAdherence(callID int64) (*AdherenceReport, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	results, err := navAdherenceDao.GetAdherence(dbLike.Conn(), []int64{callID})
+	if err != nil {
+		logger.Error.Printf("get adherence of call %d failed. %s\n", callID, err)
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	var report AdherenceReport
+	err = json.Unmarshal([]byte(results[0].Report), &report)
+	if err != nil {
+		logger.Error.Printf("unmarshal adherence report of call %d failed. %s\n", callID, err)
+		return nil, err
+	}
+	report.Percentage = results[0].Percentage
+	return &report, nil
+}
+
+//CheckCallAdherence evaluates the navigation flows adherence of the whole call and stores the result
+func CheckCallAdherence(call *model.Call) (*AdherenceReport, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	if call == nil {
+		return nil, ErrNoArgument
+	}
+
+	predicts, err := getStreamingPredict(call.ID)
+	if err != nil {
+		logger.Error.Printf("get streaming predicts failed. %s\n", err)
+		return nil, err
+	}
+	if len(predicts) == 0 {
+		return nil, ErrNoSuchID
+	}
+	var settings NavFlowSetting
+	err = json.Unmarshal([]byte(predicts[0].Predict), &settings)
+	if err != nil {
+		logger.Error.Printf("unmarshal predicts failed. %d, %s\n", call.ID, err)
+		return nil, err
+	}
+
+	navIDs := make([]int64, 0, len(settings.NavResult.NavResult.Flows))
+	for _, f := range settings.NavResult.NavResult.Flows {
+		navIDs = append(navIDs, f.ID)
+	}
+	adhSettings := make(map[int64]*AdherenceSetting)
+	branchUUIDs := make([]string, 0)
+	if len(navIDs) > 0 {
+		flows, err := navDao.GetFlows(dbLike.Conn(), &model.NavQuery{ID: navIDs}, nil)
+		if err != nil {
+			logger.Error.Printf("get flows failed. %s\n", err)
+			return nil, err
+		}
+		for _, f := range flows {
+			a, err := parseAdherenceSetting(f.Adherence)
+			if err != nil {
+				logger.Error.Printf("parse adherence setting of flow %d failed. %s\n", f.ID, err)
+				return nil, err
+			}
+			adhSettings[f.ID] = a
+			for _, step := range a.Steps {
+				if step != nil && step.Branch != "" {
+					branchUUIDs = append(branchUUIDs, step.Branch)
+				}
+			}
+		}
+	}
+
+	realSegs, err := segmentDao.Segments(dbLike.Conn(), model.SegmentQuery{CallID: []int64{call.ID}, Channel: []int8{1, 2}})
+	if err != nil {
+		logger.Error.Printf("get segments failed. %s\n", err)
+		return nil, err
+	}
+	var channelRoles = map[int8]int{
+		1: int(call.LeftChanRole),
+		2: int(call.RightChanRole),
+	}
+	segs := make([]*SegmentWithSpeaker, 0, len(realSegs))
+	lines := make([]string, 0, len(realSegs))
+	for _, s := range realSegs {
+		segs = append(segs, &SegmentWithSpeaker{RealSegment: s, Speaker: channelRoles[s.Channel]})
+		lines = append(lines, s.Text)
+	}
+
+	matchSgID := make(map[uint64][]int)
+	branches := make(map[string]bool)
+	if len(segs) > 0 {
+		if len(settings.Levels) < 2 {
+			return nil, ErrWrongLevel
+		}
+		tagMatchDat, err := TagMatch([]uint64{uint64(settings.Model)}, lines, time.Duration(30*time.Second))
+		if err != nil {
+			logger.Error.Printf("doing tag matched failed. model:%d. %s\n", settings.Model, err)
+			return nil, err
+		}
+		segMatchedTag := extractTagMatchedData(tagMatchDat)
+
+		senMatchDat, err := SentencesMatch(segMatchedTag, settings.Levels[1])
+		if err != nil {
+			logger.Error.Printf("doing sentence match failed. %s\n", err)
+			return nil, err
+		}
+		matchSgID, err = SentenceGroupMatch(senMatchDat, settings.Criteria, segs)
+		if err != nil {
+			logger.Error.Printf("doing sentence group match failed. %s\n", err)
+			return nil, err
+		}
+
+		grps, criteria, err := senGrpCriteriaByUUID(branchUUIDs, call.EnterpriseID)
+		if err != nil {
+			logger.Error.Printf("get branch sentence groups failed. %s\n", err)
+			return nil, err
+		}
+		branchMatched := matchSenGrps(criteria, segMatchedTag, segs)
+		for i, g := range criteria.groups {
+			if len(branchMatched[g.ID]) > 0 {
+				branches[grps.SentenceGroups[i].ID] = true
+			}
+		}
+	}
+
+	report := evaluateAdherence(&settings, matchSgID, segs, adhSettings, branches)
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		logger.Error.Printf("marshal adherence report failed. %s\n", err)
+		return nil, err
+	}
+	tx, err := dbLike.Begin()
+	if err != nil {
+		logger.Error.Printf("begin transaction failed. %s\n", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	_, err = navAdherenceDao.SetAdherence(tx, &model.NavAdherence{CallID: call.ID,
+		Percentage: report.Percentage, Report: string(b), CreateTime: time.Now().Unix()})
+	if err != nil {
+		logger.Error.Printf("store adherence of call %d failed. %s\n", call.ID, err)
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		logger.Error.Printf("commit adherence of call %d failed. %s\n", call.ID, err)
+		return nil, err
+	}
+	call.AdherenceScore = &report.Percentage
+	return report, nil
+}
+
+//evaluateAdherence scores the flows which is triggered in the call.
+//matchSgID is the sentence group id to its matched segments index, 1-based, in segs
+func evaluateAdherence(s *NavFlowSetting, matchSgID map[uint64][]int, segs []*SegmentWithSpeaker,
+	adhSettings map[int64]*AdherenceSetting, branches map[string]bool) *AdherenceReport {
+
+	numOfFlows := len(s.NavResult.NavResult.Flows)
+	intentMatched := make([]bool, numOfFlows)
+	nodeMatched := make([]map[int]float64, numOfFlows) //the earliest matched time of each node in each flow
+	for i := range nodeMatched {
+		nodeMatched[i] = make(map[int]float64)
+	}
+
+	for sgID, idxs := range matchSgID {
+		var first float64
+		var found bool
+		for _, idx := range idxs {
+			if idx < 1 || idx > len(segs) {
+				continue
+			}
+			t := segs[idx-1].StartTime
+			if !found || t < first {
+				first = t
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		for _, loc := range s.NodeLocal[int64(sgID)] {
+			if loc.FlowOrder >= numOfFlows {
+				continue
+			}
+			if loc.IsIntent {
+				intentMatched[loc.FlowOrder] = true
+				continue
+			}
+			if t, ok := nodeMatched[loc.FlowOrder][loc.NodeOrder]; !ok || first < t {
+				nodeMatched[loc.FlowOrder][loc.NodeOrder] = first
+			}
+		}
+	}
+
+	resp := &AdherenceReport{Percentage: 100, Flows: make([]FlowAdherence, 0)}
+	var total float64
+	for k, flow := range s.NavResult.NavResult.Flows {
+		if flow.Type == callInIntentCodeMap[1] && !intentMatched[k] {
+			continue
+		}
+		setting, ok := adhSettings[flow.ID]
+		if !ok {
+			setting = newAdherenceSetting()
+		}
+		fa := scoreFlowAdherence(flow, setting, nodeMatched[k], branches)
+		total += fa.Percentage
+		resp.Flows = append(resp.Flows, fa)
+	}
+	if len(resp.Flows) > 0 {
+		resp.Percentage = roundPercentage(total / float64(len(resp.Flows)))
+	}
+	return resp
+}
+
+//scoreFlowAdherence gives the status of each node and the percentage of the required steps that are adhered.
+//matched is the node order to its first matched time
+func scoreFlowAdherence(flow StreamingFlow, setting *AdherenceSetting, matched map[int]float64,
+	branches map[string]bool) FlowAdherence {
+
+	resp := FlowAdherence{NavID: flow.ID, Name: flow.Name, Percentage: 100, Steps: make([]StepReport, 0, len(flow.Nodes))}
+
+	var required, adhered int
+	var last float64 //the matched time of the last adhered step
+	for k, node := range flow.Nodes {
+		step := setting.Steps[node.ID]
+		if step == nil {
+			step = &StepSetting{}
+		}
+		r := StepReport{ID: node.ID, Name: node.Name, Optional: step.Optional || node.Optional}
+		if step.Branch != "" && !branches[step.Branch] {
+			r.Status = StepNotApplicable
+			resp.Steps = append(resp.Steps, r)
+			continue
+		}
+		if !r.Optional {
+			required++
+		}
+
+		t, ok := matched[k]
+		r.MatchedTime = t
+		switch {
+		case !ok:
+			r.Status = StepSkipped
+		case setting.Ordered && t < last:
+			r.Status = StepOutOfOrder
+		case step.TimeLimit > 0 && t-last > float64(step.TimeLimit):
+			r.Status = StepOvertime
+			last = t
+		default:
+			r.Status = StepDone
+			last = t
+			if !r.Optional {
+				adhered++
+			}
+		}
+		resp.Steps = append(resp.Steps, r)
+	}
+
+	if required > 0 {
+		resp.Percentage = roundPercentage(float64(adhered) * 100 / float64(required))
+	}
+	return resp
+}
+
+func roundPercentage(p float64) float64 {
+	return math.Round(p*100) / 100
+}
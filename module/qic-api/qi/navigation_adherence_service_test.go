@@ -0,0 +1,105 @@
+package qi
+
+import (
+	"testing"
+)
+
+func adherenceTestFlow() StreamingFlow {
+	return StreamingFlow{ID: 1, Type: callInIntentCodeMap[0], Name: "flow",
+		Nodes: []StreamingNode{
+			StreamingNode{ID: "greeting", Name: "greeting"},
+			StreamingNode{ID: "verify", Name: "verify"},
+			StreamingNode{ID: "refund", Name: "refund"},
+			StreamingNode{ID: "closing", Name: "closing"},
+		},
+	}
+}
+
+func checkStepStatus(t *testing.T, expected []string, r FlowAdherence) {
+	if len(expected) != len(r.Steps) {
+		t.Fatalf("expecting %d steps, but get %d\n", len(expected), len(r.Steps))
+	}
+	for k, v := range expected {
+		if v != r.Steps[k].Status {
+			t.Errorf("expecting %s at %d'th step, but get %s\n", v, k+1, r.Steps[k].Status)
+		}
+	}
+}
+
+func TestScoreFlowAdherence(t *testing.T) {
+	flow := adherenceTestFlow()
+
+	setting := &AdherenceSetting{Ordered: true, Steps: map[string]*StepSetting{
+		"verify":  &StepSetting{TimeLimit: 30},
+		"refund":  &StepSetting{Branch: "refund-intent"},
+		"closing": &StepSetting{Optional: true},
+	}}
+
+	//all done in order
+	matched := map[int]float64{0: 1, 1: 20, 2: 40, 3: 60}
+	r := scoreFlowAdherence(flow, setting, matched, map[string]bool{"refund-intent": true})
+	checkStepStatus(t, []string{StepDone, StepDone, StepDone, StepDone}, r)
+	if r.Percentage != 100 {
+		t.Errorf("expecting 100 percentage, but get %f\n", r.Percentage)
+	}
+
+	//refund branch is not matched, verify is overtime and closing is skipped
+	matched = map[int]float64{0: 1, 1: 40}
+	r = scoreFlowAdherence(flow, setting, matched, map[string]bool{})
+	checkStepStatus(t, []string{StepDone, StepOvertime, StepNotApplicable, StepSkipped}, r)
+	if r.Percentage != 50 {
+		t.Errorf("expecting 50 percentage, but get %f\n", r.Percentage)
+	}
+
+	//verify comes before greeting
+	matched = map[int]float64{0: 10, 1: 5, 2: 20}
+	r = scoreFlowAdherence(flow, setting, matched, map[string]bool{"refund-intent": true})
+	checkStepStatus(t, []string{StepDone, StepOutOfOrder, StepDone, StepSkipped}, r)
+	if r.Percentage != 66.67 {
+		t.Errorf("expecting 66.67 percentage, but get %f\n", r.Percentage)
+	}
+
+	//order is not required
+	setting.Ordered = false
+	r = scoreFlowAdherence(flow, setting, matched, map[string]bool{"refund-intent": true})
+	checkStepStatus(t, []string{StepDone, StepDone, StepDone, StepSkipped}, r)
+}
+
+func TestEvaluateAdherence(t *testing.T) {
+	_, allSegs := talkTestData()
+	intentFlow := adherenceTestFlow()
+	intentFlow.ID = 2
+	intentFlow.Type = callInIntentCodeMap[1]
+
+	s := &NavFlowSetting{
+		NodeLocal: map[int64][]CreditLoc{
+			100: []CreditLoc{CreditLoc{FlowOrder: 0, NodeOrder: 0}},
+			101: []CreditLoc{CreditLoc{FlowOrder: 0, NodeOrder: 1}},
+			102: []CreditLoc{CreditLoc{FlowOrder: 1, IsIntent: true}},
+		},
+	}
+	s.NavResult.NavResult.Flows = []StreamingFlow{adherenceTestFlow(), intentFlow}
+
+	adhSettings := map[int64]*AdherenceSetting{1: &AdherenceSetting{Steps: map[string]*StepSetting{}}}
+	matchSgID := map[uint64][]int{100: []int{5, 2}, 101: []int{7}}
+
+	r := evaluateAdherence(s, matchSgID, allSegs, adhSettings, map[string]bool{})
+	if len(r.Flows) != 1 {
+		t.Fatalf("expecting only the fixed flow is evaluated, but get %d flows\n", len(r.Flows))
+	}
+	if r.Flows[0].Steps[0].MatchedTime != allSegs[1].StartTime {
+		t.Errorf("expecting the first matched time %f, but get %f\n", allSegs[1].StartTime, r.Flows[0].Steps[0].MatchedTime)
+	}
+	if r.Percentage != 50 {
+		t.Errorf("expecting 50 percentage, but get %f\n", r.Percentage)
+	}
+
+	matchSgID[102] = []int{3}
+	r = evaluateAdherence(s, matchSgID, allSegs, adhSettings, map[string]bool{})
+	if len(r.Flows) != 2 {
+		t.Fatalf("expecting both flows are evaluated, but get %d flows\n", len(r.Flows))
+	}
+	if r.Percentage != 25 {
+		t.Errorf("expecting 25 percentage, but get %f\n", r.Percentage)
+	}
+}
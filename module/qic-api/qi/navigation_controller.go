@@ -11,6 +11,7 @@ import (
 	"emotibot.com/emotigo/module/qic-api/model/v1"
 	"emotibot.com/emotigo/module/qic-api/sensitive"
 	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 	"emotibot.com/emotigo/module/qic-api/util/timecache"
 
 	"emotibot.com/emotigo/module/admin-api/ApiError"
@@ -403,6 +404,7 @@ func handleModifyIntent(w http.ResponseWriter, r *http.Request) {
 type apiFlowCreateBody struct {
 	CreateTime int64  `json:"create_time"`
 	FileName   string `json:"file_name"`
+	Locale     string `json:"locale"`
 }
 
 type apiFlowCreateResp struct {
@@ -422,6 +424,10 @@ func handleFlowCreate(w http.ResponseWriter, r *http.Request) {
 		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
 		return
 	}
+	if !locale.IsValid(requestBody.Locale) {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "invalid locale"), http.StatusBadRequest)
+		return
+	}
 
 	uuid, err := createFlowConversation(enterprise, user, &requestBody)
 	if err != nil {
@@ -740,7 +746,7 @@ func handleStreaming(w http.ResponseWriter, r *http.Request) {
 			segWithSp = append(segWithSp, ws)
 		}
 
-		credits, err := SensitiveWordsVerificationWithPacked(call.ID, segWithSp, enterprise)
+		credits, err := SensitiveWordsVerificationWithPacked(call.ID, segWithSp, enterprise, call.Locale)
 		if err != nil {
 			logger.Error.Printf("get sensitive words failed. %s\n", err)
 			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
@@ -397,14 +397,14 @@ func createFlowConversation(enterprise string, user string, body *apiFlowCreateB
 
 	//Notice!! default sets the left channel as staff, and right channel as customer
 	reqCall := &NewCallReq{FileName: body.FileName, Enterprise: enterprise, Type: model.CallTypeRealTime, CallTime: body.CreateTime,
-		UploadUser: user, LeftChannel: CallStaffRoleName, RightChannel: CallCustomerRoleName}
+		UploadUser: user, LeftChannel: CallStaffRoleName, RightChannel: CallCustomerRoleName, Locale: body.Locale}
 	call, err := NewCall(reqCall)
 	if err != nil {
 		logger.Error.Printf("create the call failed. %s\n", err)
 		return "", err
 	}
 
-	settings, err := getCurSetting(enterprise)
+	settings, err := getCurSetting(enterprise, call.Locale)
 	if err != nil {
 		logger.Error.Printf("get flow setting %s failed. %s\n", enterprise, err)
 		return "", err
@@ -413,6 +413,13 @@ func createFlowConversation(enterprise string, user string, body *apiFlowCreateB
 	settings.NavResult.FileName = body.FileName
 	settings.NavResult.ID = call.UUID
 	settings.CallID = call.ID
+	settings.Locale = call.Locale
+	if len(settings.Levels) > 1 {
+		settings.Levels[1], err = filterSentencesByLocale(settings.Levels[1], call.Locale)
+		if err != nil {
+			return "", err
+		}
+	}
 	settingsStr, err := json.Marshal(settings)
 	if err != nil {
 		logger.Error.Printf("Marshal failed. %s\n", err)
@@ -468,6 +475,13 @@ func finishFlowQI(req *apiFlowFinish, uuid string) error {
 
 	tx.Commit()
 
+	go func(call model.Call) {
+		_, err := CheckCallAdherence(&call)
+		if err != nil {
+			logger.Error.Printf("check the adherence of call %d failed. %s\n", call.ID, err)
+		}
+	}(calls[0])
+
 	return nil
 }
 
@@ -564,6 +578,7 @@ type NavFlowSetting struct {
 	Levels    []map[uint64][]uint64        `json:"levels"`
 	CallID    int64                        `json:"callID"`
 	NodeLocal map[int64][]CreditLoc        `json:"postion"` //sentence group id to the location in the Flows
+	Locale    string                       `json:"locale"`
 }
 
 type NavResponse struct {
@@ -606,22 +621,18 @@ type MatchedFlowNode struct {
 
 //this function would get the current flows and its nodes
 //incluing the setting information, sentence group
-func getCurSetting(enterprise string) (*NavFlowSetting, error) {
+func getCurSetting(enterprise string, loc string) (*NavFlowSetting, error) {
 	if dbLike == nil {
 		return nil, ErrNilCon
 	}
 
-	//get the trained model by the enterprise
-	models, err := GetUsingModelByEnterprise(enterprise)
-	numOfModels := len(models)
-	if numOfModels == 0 {
-		return nil, ErrNoModels
-	} else if numOfModels > 1 {
-		logger.Warn.Printf("More than 1 models is marked as using status with enterprise %s\n", enterprise)
-		logger.Warn.Printf("Using the first one %d as prediction model", models[0].ID)
+	//get the trained model of the locale by the enterprise
+	predictModel, err := GetUsingModelByLocale(enterprise, loc)
+	if err != nil {
+		return nil, err
 	}
 
-	resp := &NavFlowSetting{Model: int64(models[0].ID), NodeLocal: make(map[int64][]CreditLoc)}
+	resp := &NavFlowSetting{Model: int64(predictModel.ID), NodeLocal: make(map[int64][]CreditLoc)}
 
 	//get the current navigation flows
 	isDelete := 0
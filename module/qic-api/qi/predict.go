@@ -18,7 +18,8 @@ var (
 )
 
 //BatchPredict predicts the sentences by appID. Basically appID is tagID, maybe RuleGroup id in the future.
-func BatchPredict(appID uint64, threshold int, sentences []string) (*logicaccess.PredictResult, error) {
+//enterprise is the owner of the appID, the requests are scheduled fairly between enterprises
+func BatchPredict(appID uint64, enterprise string, threshold int, sentences []string) (*logicaccess.PredictResult, error) {
 	if predictor == nil {
 		return nil, ErrNoPredictConn
 	}
@@ -31,6 +32,7 @@ func BatchPredict(appID uint64, threshold int, sentences []string) (*logicaccess
 
 	var r logicaccess.BatchPredictRequest
 	r.ID = appID
+	r.Enterprise = enterprise
 	r.Threshold = threshold
 	for i := 0; i < len(sentences); i++ {
 		s := &logicaccess.PredictData{SentenceID: i + 1, Sentence: sentences[i]}
@@ -0,0 +1,71 @@
+package qi
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/logicaccess"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//newPredictScheduler creates the scheduler shared by the cu trainer and predictor by the setting in envs,
+//unset one uses the default value
+//
+//	LOGIC_PREDICT_CONCURRENCY: number of requests sending to cu module at the same time
+//	LOGIC_PREDICT_QUEUE_SIZE: number of requests allowed to wait
+//	LOGIC_PREDICT_BREAKER_THRESHOLD: number of continuous failures to stop sending requests
+//	LOGIC_PREDICT_BREAKER_COOLDOWN: seconds to wait before sending requests again
+func newPredictScheduler(envs map[string]string) *logicaccess.Scheduler {
+	intEnv := func(name string) int {
+		text, found := envs[name]
+		if !found {
+			return 0
+		}
+		v, err := strconv.Atoi(text)
+		if err != nil {
+			logger.Error.Println("Variable ", name, " ", text, " can not convert to int: ", err)
+			return 0
+		}
+		return v
+	}
+	cfg := logicaccess.SchedulerConfig{
+		MaxConcurrency:   intEnv("LOGIC_PREDICT_CONCURRENCY"),
+		MaxQueue:         intEnv("LOGIC_PREDICT_QUEUE_SIZE"),
+		BreakerThreshold: intEnv("LOGIC_PREDICT_BREAKER_THRESHOLD"),
+		BreakerCooldown:  time.Duration(intEnv("LOGIC_PREDICT_BREAKER_COOLDOWN")) * time.Second,
+	}
+	return logicaccess.NewScheduler(cfg)
+}
+
+//modelEnterprises caches the enterprise of the trained model, which is never changed
+var modelEnterprises sync.Map
+
+//modelEnterprise gives the enterprise of the trained model, used as the scheduling key of the prediction.
+//empty string is returned if the model is not found
+func modelEnterprise(modelID uint64) string {
+	if v, ok := modelEnterprises.Load(modelID); ok {
+		return v.(string)
+	}
+	if dbLike == nil {
+		return ""
+	}
+	models, err := modelDao.TrainedModelInfo(dbLike.Conn(), &model.TModelQuery{ID: []uint64{modelID}})
+	if err != nil || len(models) == 0 {
+		logger.Warn.Printf("get enterprise of model %d failed. %v\n", modelID, err)
+		return ""
+	}
+	modelEnterprises.Store(modelID, models[0].Enterprise)
+	return models[0].Enterprise
+}
+
+func handleGetPredictStats(w http.ResponseWriter, r *http.Request) {
+	var stats logicaccess.SchedulerStats
+	if c, ok := predictor.(*logicaccess.Client); ok && c.Scheduler != nil {
+		stats = c.Scheduler.Stats()
+	}
+	util.WriteJSON(w, stats)
+}
@@ -0,0 +1,221 @@
+package qi
+
+import (
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+func checkHoldRule(r *model.HoldRule) error {
+	if r == nil {
+		return ErrEmptyRequest
+	}
+	if r.Name == "" {
+		return ErrEmptyName
+	}
+	if r.Seconds <= 0 {
+		return ErrWrongSecond
+	}
+	if r.Times <= 0 {
+		return ErrorWrongTimes
+	}
+	return nil
+}
+
+func handleNewRuleHold(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+
+	var requestBody model.HoldRule
+	err := util.ReadJSON(r, &requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkHoldRule(&requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	uuid, err := NewRuleHold(&requestBody, enterprise)
+	if err != nil {
+		logger.Error.Printf("create rule hold failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		UUID string `json:"hold_id"`
+	}{UUID: uuid})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleHoldList(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	page, limit, err := getPageLimit(r)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err), http.StatusBadRequest)
+		return
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &enterprise, IsDelete: &isDelete}
+	p := &model.Pagination{Limit: limit, Page: page}
+
+	resp, err := GetRuleHolds(q, p)
+	if err != nil {
+		logger.Error.Printf("get the hold rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := CountRuleHold(q)
+	if err != nil {
+		logger.Error.Printf("count the hold rule failed. q: %+v, err: %s\n", *q, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Page pageResp          `json:"paging"`
+		Data []*model.HoldRule `json:"data"`
+	}{
+		Page: pageResp{Current: page, Limit: limit, Total: uint64(total)},
+		Data: resp,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleHold(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+
+	settings, err := GetRuleHolds(q, nil)
+	if err != nil {
+		logger.Error.Printf("get the hold rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if len(settings) == 0 {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "no such id"), http.StatusBadRequest)
+		return
+	}
+
+	except, _, err := GetRuleHoldException(settings[0])
+	if err != nil {
+		logger.Error.Printf("get the exception rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Setting   model.HoldRule    `json:"setting"`
+		Exception TalkRuleException `json:"exception"`
+	}{
+		Setting:   *settings[0],
+		Exception: *except,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleDeleteRuleHold(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+	_, err := DeleteRuleHold(q)
+	if err != nil {
+		logger.Error.Printf("delete %s failed. %s\n", uuid, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+}
+
+func checkHoldUpdateSet(r model.HoldUpdateSet) error {
+	hr := model.HoldRule{Name: "valid name", Score: 99, Seconds: 99, Times: 99}
+	if r.Name != nil {
+		hr.Name = *r.Name
+	}
+	if r.Score != nil {
+		hr.Score = *r.Score
+	}
+	if r.Seconds != nil {
+		hr.Seconds = *r.Seconds
+	}
+	if r.Times != nil {
+		hr.Times = *r.Times
+	}
+	return checkHoldRule(&hr)
+}
+
+func handleModifyRuleHold(w http.ResponseWriter, r *http.Request) {
+	var req model.HoldUpdateSet
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkHoldUpdateSet(req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	//just in case
+	req.Exception = nil
+	updateRuleHold(w, r, &req)
+}
+
+func handleExceptionRuleHold(w http.ResponseWriter, r *http.Request) {
+	var req talkRuleExceptionInternal
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	except, err := marshalTalkRuleException(req.SentenceGroups)
+	if err != nil {
+		logger.Error.Printf("marshal %+v failed. %s\n", req.SentenceGroups, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	updateRuleHold(w, r, &model.HoldUpdateSet{Exception: &except})
+}
+
+func updateRuleHold(w http.ResponseWriter, r *http.Request, d *model.HoldUpdateSet) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	_, err := UpdateRuleHold(&model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}, d)
+	if err != nil {
+		if err == ErrNoSuchID {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		} else {
+			logger.Error.Printf("update %s failed. %s\n", uuid, err)
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		}
+	}
+}
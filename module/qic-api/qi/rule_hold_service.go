@@ -0,0 +1,236 @@
+package qi
+
+import (
+	"time"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+var (
+	ruleHoldDao model.HoldRuleDao = &model.HoldRuleSQLDao{}
+)
+
+//NewRuleHold creates the new hold rule
+func NewRuleHold(r *model.HoldRule, enterprise string) (string, error) {
+	if r == nil {
+		return "", ErrNoArgument
+	}
+	if dbLike == nil {
+		return "", ErrNilCon
+	}
+	uuid, err := general.UUID()
+	if err != nil {
+		return "", err
+	}
+	r.Enterprise = enterprise
+	r.CreateTime = time.Now().Unix()
+	r.UpdateTime = r.CreateTime
+	r.UUID = uuid
+	_, err = ruleHoldDao.Add(dbLike.Conn(), r)
+	return r.UUID, err
+}
+
+//GetRuleHolds gets the list of hold rule
+func GetRuleHolds(q *model.GeneralQuery, p *model.Pagination) ([]*model.HoldRule, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	return ruleHoldDao.Get(dbLike.Conn(), q, p)
+}
+
+//CountRuleHold counts the total number of hold rule
+func CountRuleHold(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	return ruleHoldDao.Count(dbLike.Conn(), q)
+}
+
+//GetRuleHoldException gets the hold rule exception
+func GetRuleHoldException(r *model.HoldRule) (*TalkRuleException, *senGrpCriteria, error) {
+	if r == nil {
+		return newTalkRuleException(), nil, nil
+	}
+	return getSenGrpCriteria(r.Exception, r.Enterprise)
+}
+
+//DeleteRuleHold deletes the hold rule
+func DeleteRuleHold(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	return ruleHoldDao.SoftDelete(dbLike.Conn(), q)
+}
+
+//UpdateRuleHold updates the hold rule
+func UpdateRuleHold(q *model.GeneralQuery, d *model.HoldUpdateSet) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	tx, err := dbLike.Begin()
+	if err != nil {
+		logger.Error.Printf("create session failed. %s\n", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := ruleHoldDao.Get(tx, q, &model.Pagination{Limit: 10})
+	if err != nil {
+		return 0, err
+	}
+	if len(current) == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	q.ID = append(q.ID, current[0].ID)
+	newID, err := ruleHoldDao.Copy(tx, q)
+	if err != nil {
+		logger.Error.Printf("copy new record %v failed. %s\n", *q, err)
+		return 0, err
+	}
+
+	affected, err := ruleHoldDao.SoftDelete(tx, q)
+	if err != nil {
+		logger.Error.Printf("delete failed. %s\n", err)
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	newQuery := &model.GeneralQuery{ID: []int64{newID}}
+	affected, err = ruleHoldDao.Update(tx, newQuery, d)
+	if err != nil {
+		logger.Error.Printf("update failed. %s\n", err)
+		return 0, err
+	}
+	tx.Commit()
+	return affected, err
+}
+
+type HoldRuleWithException struct {
+	RuleGroupID int64
+	model.HoldRule
+	criteria *senGrpCriteria
+}
+
+//RuleHoldCheck checks the hold rules
+func RuleHoldCheck(ruleGroup model.Group, allSegs []*SegmentWithSpeaker, matched []*MatchedData) ([]RulesException, error) {
+	if len(allSegs) == 0 {
+		return nil, nil
+	}
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &ruleGroup.EnterpriseID, IsDelete: &isDelete, UUID: []string{ruleGroup.UUID}}
+	hRules, err := ruleHoldDao.GetByRuleGroup(dbLike.Conn(), q)
+	if err != nil {
+		logger.Error.Printf("get rule hold failed. %s\n", err)
+		return nil, err
+	}
+	if len(hRules) == 0 {
+		return nil, nil
+	}
+
+	pureWordSegs := extractPureWordsSegment(allSegs)
+	if len(pureWordSegs) != len(matched) {
+		return nil, ErrSegsNotMatched
+	}
+
+	rules := make([]HoldRuleWithException, 0, len(hRules))
+	for _, r := range hRules {
+		_, criteria, err := GetRuleHoldException(r)
+		if err != nil {
+			logger.Error.Printf("get hold exception failed. %s\n", err)
+			return nil, err
+		}
+		rules = append(rules, HoldRuleWithException{RuleGroupID: ruleGroup.ID, HoldRule: *r, criteria: criteria})
+	}
+
+	return holdRuleCheck(rules, matched, allSegs, pureWordSegs), nil
+}
+
+//hold is the silence segment in the middle of the call
+type hold struct {
+	duration float64
+	talkViolation
+}
+
+//extractHolds finds the silence segments between the first and the last words segment.
+//The staff segments right before and after the hold are the place to find the exception,
+//for example, "please hold on" and "thanks for your waiting"
+func extractHolds(allSegs []*SegmentWithSpeaker) []hold {
+	wordIdx := wordSegsIndex(allSegs)
+	first, last := -1, -1
+	for k, v := range wordIdx {
+		if v < 0 {
+			continue
+		}
+		if first < 0 {
+			first = k
+		}
+		last = k
+	}
+
+	resp := make([]hold, 0)
+	for k, v := range allSegs {
+		if v.Speaker != SilenceSpeaker || k < first || k > last {
+			continue
+		}
+		h := hold{duration: v.EndTime - v.StartTime}
+		h.segIDs = []int64{v.ID}
+		if staffIdx := nearestStaffSegIndex(k, -1, allSegs); staffIdx >= 0 {
+			h.excptIdxs = append(h.excptIdxs, wordIdx[staffIdx])
+		}
+		if staffIdx := nearestStaffSegIndex(k, 1, allSegs); staffIdx >= 0 {
+			h.excptIdxs = append(h.excptIdxs, wordIdx[staffIdx])
+		}
+		resp = append(resp, h)
+	}
+	return resp
+}
+
+//nearestStaffSegIndex returns the index of the nearest staff segment from idx,
+//searching backward if step is -1 or forward if step is 1. -1 is returned if not found
+func nearestStaffSegIndex(idx int, step int, allSegs []*SegmentWithSpeaker) int {
+	for j := idx + step; j >= 0 && j < len(allSegs); j += step {
+		if allSegs[j].Speaker == int(model.CallChanStaff) {
+			return j
+		}
+	}
+	return -1
+}
+
+//holdRuleCheck checks the hold rules
+//allSegs includes the silence and interposal segments, segs and tagMatchDat are the words segments only
+func holdRuleCheck(rules []HoldRuleWithException, tagMatchDat []*MatchedData,
+	allSegs []*SegmentWithSpeaker, segs []*SegmentWithSpeaker) []RulesException {
+
+	callID := allSegs[0].CallID
+	holds := extractHolds(allSegs)
+
+	resp := make([]RulesException, 0, len(rules))
+	for _, r := range rules {
+		violations := make([]talkViolation, 0)
+		for _, h := range holds {
+			if h.duration > float64(r.Seconds) {
+				violations = append(violations, h.talkViolation)
+			}
+		}
+		result := RulesException{RuleID: r.ID, Typ: levHoldTyp, Whos: Hold,
+			CallID: callID, RuleGroupID: r.RuleGroupID}
+		checkTalkViolations(&result, r.Times, r.Score, violations, r.criteria, tagMatchDat, segs)
+		resp = append(resp, result)
+	}
+	return resp
+}
@@ -0,0 +1,248 @@
+package qi
+
+import (
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+func checkLatencyRule(r *model.LatencyRule) error {
+	if r == nil {
+		return ErrEmptyRequest
+	}
+	if r.Name == "" {
+		return ErrEmptyName
+	}
+	if r.Seconds <= 0 {
+		return ErrWrongSecond
+	}
+	if r.Times <= 0 {
+		return ErrorWrongTimes
+	}
+	return nil
+}
+
+func handleNewRuleLatency(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+
+	var requestBody model.LatencyRule
+	err := util.ReadJSON(r, &requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkLatencyRule(&requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	uuid, err := NewRuleLatency(&requestBody, enterprise)
+	if err != nil {
+		logger.Error.Printf("create rule latency failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		UUID string `json:"latency_id"`
+	}{UUID: uuid})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleLatencyList(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	page, limit, err := getPageLimit(r)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err), http.StatusBadRequest)
+		return
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &enterprise, IsDelete: &isDelete}
+	p := &model.Pagination{Limit: limit, Page: page}
+
+	resp, err := GetRuleLatencies(q, p)
+	if err != nil {
+		logger.Error.Printf("get the latency rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := CountRuleLatency(q)
+	if err != nil {
+		logger.Error.Printf("count the latency rule failed. q: %+v, err: %s\n", *q, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Page pageResp             `json:"paging"`
+		Data []*model.LatencyRule `json:"data"`
+	}{
+		Page: pageResp{Current: page, Limit: limit, Total: uint64(total)},
+		Data: resp,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleLatency(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+
+	settings, err := GetRuleLatencies(q, nil)
+	if err != nil {
+		logger.Error.Printf("get the latency rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if len(settings) == 0 {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "no such id"), http.StatusBadRequest)
+		return
+	}
+
+	trigger, _, err := GetRuleLatencyTrigger(settings[0])
+	if err != nil {
+		logger.Error.Printf("get the trigger of rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	except, _, err := GetRuleLatencyException(settings[0])
+	if err != nil {
+		logger.Error.Printf("get the exception rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Setting   model.LatencyRule `json:"setting"`
+		Trigger   TalkRuleException `json:"trigger"`
+		Exception TalkRuleException `json:"exception"`
+	}{
+		Setting:   *settings[0],
+		Trigger:   *trigger,
+		Exception: *except,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleDeleteRuleLatency(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+	_, err := DeleteRuleLatency(q)
+	if err != nil {
+		logger.Error.Printf("delete %s failed. %s\n", uuid, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+}
+
+func checkLatencyUpdateSet(r model.LatencyUpdateSet) error {
+	lr := model.LatencyRule{Name: "valid name", Score: 99, Seconds: 99, Times: 99}
+	if r.Name != nil {
+		lr.Name = *r.Name
+	}
+	if r.Score != nil {
+		lr.Score = *r.Score
+	}
+	if r.Seconds != nil {
+		lr.Seconds = *r.Seconds
+	}
+	if r.Times != nil {
+		lr.Times = *r.Times
+	}
+	return checkLatencyRule(&lr)
+}
+
+func handleModifyRuleLatency(w http.ResponseWriter, r *http.Request) {
+	var req model.LatencyUpdateSet
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkLatencyUpdateSet(req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	//just in case
+	req.Exception = nil
+	req.Trigger = nil
+	updateRuleLatency(w, r, &req)
+}
+
+func handleExceptionRuleLatency(w http.ResponseWriter, r *http.Request) {
+	var req talkRuleExceptionInternal
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	except, err := marshalTalkRuleException(req.SentenceGroups)
+	if err != nil {
+		logger.Error.Printf("marshal %+v failed. %s\n", req.SentenceGroups, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	updateRuleLatency(w, r, &model.LatencyUpdateSet{Exception: &except})
+}
+
+func handleTriggerRuleLatency(w http.ResponseWriter, r *http.Request) {
+	var req talkRuleExceptionInternal
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	trigger, err := marshalTalkRuleException(req.SentenceGroups)
+	if err != nil {
+		logger.Error.Printf("marshal %+v failed. %s\n", req.SentenceGroups, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	updateRuleLatency(w, r, &model.LatencyUpdateSet{Trigger: &trigger})
+}
+
+func updateRuleLatency(w http.ResponseWriter, r *http.Request, d *model.LatencyUpdateSet) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	_, err := UpdateRuleLatency(&model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}, d)
+	if err != nil {
+		if err == ErrNoSuchID {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		} else {
+			logger.Error.Printf("update %s failed. %s\n", uuid, err)
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package qi
+
+import (
+	"sort"
+	"time"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+var (
+	ruleLatencyDao model.LatencyRuleDao = &model.LatencyRuleSQLDao{}
+)
+
+//NewRuleLatency creates the new latency rule
+func NewRuleLatency(r *model.LatencyRule, enterprise string) (string, error) {
+	if r == nil {
+		return "", ErrNoArgument
+	}
+	if dbLike == nil {
+		return "", ErrNilCon
+	}
+	uuid, err := general.UUID()
+	if err != nil {
+		return "", err
+	}
+	r.Enterprise = enterprise
+	r.CreateTime = time.Now().Unix()
+	r.UpdateTime = r.CreateTime
+	r.UUID = uuid
+	_, err = ruleLatencyDao.Add(dbLike.Conn(), r)
+	return r.UUID, err
+}
+
+//GetRuleLatencies gets the list of latency rule
+func GetRuleLatencies(q *model.GeneralQuery, p *model.Pagination) ([]*model.LatencyRule, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	return ruleLatencyDao.Get(dbLike.Conn(), q, p)
+}
+
+//CountRuleLatency counts the total number of latency rule
+func CountRuleLatency(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	return ruleLatencyDao.Count(dbLike.Conn(), q)
+}
+
+//GetRuleLatencyException gets the latency rule exception
+func GetRuleLatencyException(r *model.LatencyRule) (*TalkRuleException, *senGrpCriteria, error) {
+	if r == nil {
+		return newTalkRuleException(), nil, nil
+	}
+	return getSenGrpCriteria(r.Exception, r.Enterprise)
+}
+
+//GetRuleLatencyTrigger gets the sentence groups which define the customer's question
+func GetRuleLatencyTrigger(r *model.LatencyRule) (*TalkRuleException, *senGrpCriteria, error) {
+	if r == nil {
+		return newTalkRuleException(), nil, nil
+	}
+	return getSenGrpCriteria(r.Trigger, r.Enterprise)
+}
+
+//DeleteRuleLatency deletes the latency rule
+func DeleteRuleLatency(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	return ruleLatencyDao.SoftDelete(dbLike.Conn(), q)
+}
+
+//UpdateRuleLatency updates the latency rule
+func UpdateRuleLatency(q *model.GeneralQuery, d *model.LatencyUpdateSet) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	tx, err := dbLike.Begin()
+	if err != nil {
+		logger.Error.Printf("create session failed. %s\n", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := ruleLatencyDao.Get(tx, q, &model.Pagination{Limit: 10})
+	if err != nil {
+		return 0, err
+	}
+	if len(current) == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	q.ID = append(q.ID, current[0].ID)
+	newID, err := ruleLatencyDao.Copy(tx, q)
+	if err != nil {
+		logger.Error.Printf("copy new record %v failed. %s\n", *q, err)
+		return 0, err
+	}
+
+	affected, err := ruleLatencyDao.SoftDelete(tx, q)
+	if err != nil {
+		logger.Error.Printf("delete failed. %s\n", err)
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	newQuery := &model.GeneralQuery{ID: []int64{newID}}
+	affected, err = ruleLatencyDao.Update(tx, newQuery, d)
+	if err != nil {
+		logger.Error.Printf("update failed. %s\n", err)
+		return 0, err
+	}
+	tx.Commit()
+	return affected, err
+}
+
+type LatencyRuleWithException struct {
+	RuleGroupID int64
+	model.LatencyRule
+	trigger  *senGrpCriteria
+	criteria *senGrpCriteria
+}
+
+//RuleLatencyCheck checks the response latency rules
+func RuleLatencyCheck(ruleGroup model.Group, segs []*SegmentWithSpeaker, matched []*MatchedData) ([]RulesException, error) {
+	if len(segs) == 0 {
+		return nil, nil
+	}
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	if len(segs) != len(matched) {
+		return nil, ErrSegsNotMatched
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &ruleGroup.EnterpriseID, IsDelete: &isDelete, UUID: []string{ruleGroup.UUID}}
+	lRules, err := ruleLatencyDao.GetByRuleGroup(dbLike.Conn(), q)
+	if err != nil {
+		logger.Error.Printf("get rule latency failed. %s\n", err)
+		return nil, err
+	}
+	if len(lRules) == 0 {
+		return nil, nil
+	}
+
+	rules := make([]LatencyRuleWithException, 0, len(lRules))
+	for _, r := range lRules {
+		_, trigger, err := GetRuleLatencyTrigger(r)
+		if err != nil {
+			logger.Error.Printf("get latency trigger failed. %s\n", err)
+			return nil, err
+		}
+		_, criteria, err := GetRuleLatencyException(r)
+		if err != nil {
+			logger.Error.Printf("get latency exception failed. %s\n", err)
+			return nil, err
+		}
+		rules = append(rules, LatencyRuleWithException{RuleGroupID: ruleGroup.ID, LatencyRule: *r,
+			trigger: trigger, criteria: criteria})
+	}
+
+	return latencyRuleCheck(rules, matched, segs), nil
+}
+
+//latency is the time between the end of the customer's question and the staff's response
+type latency struct {
+	duration float64
+	talkViolation
+}
+
+//extractLatencies finds the staff's response for each question.
+//questions is the index of the words segments which are the customer's question.
+//The latency is counted from the end of the customer's turn, so the questions in the same turn only count once.
+//The staff's response is the place to find the exception
+func extractLatencies(segs []*SegmentWithSpeaker, questions []int) []latency {
+	resp := make([]latency, 0)
+	responded := make(map[int]bool)
+	for _, q := range questions {
+		if q < 0 || q >= len(segs) || segs[q].Speaker != int(model.CallChanCustomer) {
+			continue
+		}
+		turnEnd := segs[q].EndTime
+		for i := q + 1; i < len(segs); i++ {
+			s := segs[i]
+			if s.Speaker == int(model.CallChanCustomer) {
+				if s.EndTime > turnEnd {
+					turnEnd = s.EndTime
+				}
+				continue
+			}
+			if s.Speaker != int(model.CallChanStaff) {
+				continue
+			}
+			if !responded[i] {
+				responded[i] = true
+				l := latency{duration: s.StartTime - turnEnd}
+				l.segIDs = []int64{s.ID}
+				l.excptIdxs = []int{i}
+				resp = append(resp, l)
+			}
+			break
+		}
+	}
+	return resp
+}
+
+//latencyQuestions gives the index of the words segments which are the customer's question.
+//every customer segment is a question if no trigger is given
+func latencyQuestions(trigger *senGrpCriteria, segMatchedTag []map[uint64]bool, segs []*SegmentWithSpeaker) []int {
+	resp := make([]int, 0)
+	if trigger == nil || len(trigger.groups) == 0 {
+		for k, s := range segs {
+			if s.Speaker == int(model.CallChanCustomer) {
+				resp = append(resp, k)
+			}
+		}
+		return resp
+	}
+	dup := make(map[int]bool)
+	for _, idxs := range matchSenGrps(trigger, segMatchedTag, segs) {
+		for _, idx := range idxs {
+			if !dup[idx] {
+				dup[idx] = true
+				resp = append(resp, idx)
+			}
+		}
+	}
+	sort.Ints(resp)
+	return resp
+}
+
+//latencyRuleCheck checks the latency rules, segs and tagMatchDat are the words segments only.
+func latencyRuleCheck(rules []LatencyRuleWithException, tagMatchDat []*MatchedData,
+	segs []*SegmentWithSpeaker) []RulesException {
+
+	callID := segs[0].CallID
+	segMatchedTag := extractTagMatchedData(tagMatchDat)
+
+	resp := make([]RulesException, 0, len(rules))
+	for _, r := range rules {
+		questions := latencyQuestions(r.trigger, segMatchedTag, segs)
+		violations := make([]talkViolation, 0)
+		for _, l := range extractLatencies(segs, questions) {
+			if l.duration > float64(r.Seconds) {
+				violations = append(violations, l.talkViolation)
+			}
+		}
+		result := RulesException{RuleID: r.ID, Typ: levLatencyTyp, Whos: Latency,
+			CallID: callID, RuleGroupID: r.RuleGroupID}
+		checkTalkViolations(&result, r.Times, r.Score, violations, r.criteria, tagMatchDat, segs)
+		resp = append(resp, result)
+	}
+	return resp
+}
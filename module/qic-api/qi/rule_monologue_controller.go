@@ -0,0 +1,221 @@
+package qi
+
+import (
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+func checkMonologueRule(r *model.MonologueRule) error {
+	if r == nil {
+		return ErrEmptyRequest
+	}
+	if r.Name == "" {
+		return ErrEmptyName
+	}
+	if r.Seconds <= 0 {
+		return ErrWrongSecond
+	}
+	if r.Times <= 0 {
+		return ErrorWrongTimes
+	}
+	return nil
+}
+
+func handleNewRuleMonologue(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+
+	var requestBody model.MonologueRule
+	err := util.ReadJSON(r, &requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkMonologueRule(&requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	uuid, err := NewRuleMonologue(&requestBody, enterprise)
+	if err != nil {
+		logger.Error.Printf("create rule monologue failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		UUID string `json:"monologue_id"`
+	}{UUID: uuid})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleMonologueList(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	page, limit, err := getPageLimit(r)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err), http.StatusBadRequest)
+		return
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &enterprise, IsDelete: &isDelete}
+	p := &model.Pagination{Limit: limit, Page: page}
+
+	resp, err := GetRuleMonologues(q, p)
+	if err != nil {
+		logger.Error.Printf("get the monologue rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := CountRuleMonologue(q)
+	if err != nil {
+		logger.Error.Printf("count the monologue rule failed. q: %+v, err: %s\n", *q, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Page pageResp               `json:"paging"`
+		Data []*model.MonologueRule `json:"data"`
+	}{
+		Page: pageResp{Current: page, Limit: limit, Total: uint64(total)},
+		Data: resp,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleMonologue(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+
+	settings, err := GetRuleMonologues(q, nil)
+	if err != nil {
+		logger.Error.Printf("get the monologue rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if len(settings) == 0 {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "no such id"), http.StatusBadRequest)
+		return
+	}
+
+	except, _, err := GetRuleMonologueException(settings[0])
+	if err != nil {
+		logger.Error.Printf("get the exception rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Setting   model.MonologueRule `json:"setting"`
+		Exception TalkRuleException   `json:"exception"`
+	}{
+		Setting:   *settings[0],
+		Exception: *except,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleDeleteRuleMonologue(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+	_, err := DeleteRuleMonologue(q)
+	if err != nil {
+		logger.Error.Printf("delete %s failed. %s\n", uuid, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+}
+
+func checkMonologueUpdateSet(r model.MonologueUpdateSet) error {
+	mr := model.MonologueRule{Name: "valid name", Score: 99, Seconds: 99, Times: 99}
+	if r.Name != nil {
+		mr.Name = *r.Name
+	}
+	if r.Score != nil {
+		mr.Score = *r.Score
+	}
+	if r.Seconds != nil {
+		mr.Seconds = *r.Seconds
+	}
+	if r.Times != nil {
+		mr.Times = *r.Times
+	}
+	return checkMonologueRule(&mr)
+}
+
+func handleModifyRuleMonologue(w http.ResponseWriter, r *http.Request) {
+	var req model.MonologueUpdateSet
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkMonologueUpdateSet(req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	//just in case
+	req.Exception = nil
+	updateRuleMonologue(w, r, &req)
+}
+
+func handleExceptionRuleMonologue(w http.ResponseWriter, r *http.Request) {
+	var req talkRuleExceptionInternal
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	except, err := marshalTalkRuleException(req.SentenceGroups)
+	if err != nil {
+		logger.Error.Printf("marshal %+v failed. %s\n", req.SentenceGroups, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	updateRuleMonologue(w, r, &model.MonologueUpdateSet{Exception: &except})
+}
+
+func updateRuleMonologue(w http.ResponseWriter, r *http.Request, d *model.MonologueUpdateSet) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	_, err := UpdateRuleMonologue(&model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}, d)
+	if err != nil {
+		if err == ErrNoSuchID {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		} else {
+			logger.Error.Printf("update %s failed. %s\n", uuid, err)
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package qi
+
+import (
+	"time"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+var (
+	ruleMonologueDao model.MonologueRuleDao = &model.MonologueRuleSQLDao{}
+)
+
+//NewRuleMonologue creates the new monologue rule
+func NewRuleMonologue(r *model.MonologueRule, enterprise string) (string, error) {
+	if r == nil {
+		return "", ErrNoArgument
+	}
+	if dbLike == nil {
+		return "", ErrNilCon
+	}
+	uuid, err := general.UUID()
+	if err != nil {
+		return "", err
+	}
+	r.Enterprise = enterprise
+	r.CreateTime = time.Now().Unix()
+	r.UpdateTime = r.CreateTime
+	r.UUID = uuid
+	_, err = ruleMonologueDao.Add(dbLike.Conn(), r)
+	return r.UUID, err
+}
+
+//GetRuleMonologues gets the list of monologue rule
+func GetRuleMonologues(q *model.GeneralQuery, p *model.Pagination) ([]*model.MonologueRule, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	return ruleMonologueDao.Get(dbLike.Conn(), q, p)
+}
+
+//CountRuleMonologue counts the total number of monologue rule
+func CountRuleMonologue(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	return ruleMonologueDao.Count(dbLike.Conn(), q)
+}
+
+//GetRuleMonologueException gets the monologue rule exception
+func GetRuleMonologueException(r *model.MonologueRule) (*TalkRuleException, *senGrpCriteria, error) {
+	if r == nil {
+		return newTalkRuleException(), nil, nil
+	}
+	return getSenGrpCriteria(r.Exception, r.Enterprise)
+}
+
+//DeleteRuleMonologue deletes the monologue rule
+func DeleteRuleMonologue(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	return ruleMonologueDao.SoftDelete(dbLike.Conn(), q)
+}
+
+//UpdateRuleMonologue updates the monologue rule
+func UpdateRuleMonologue(q *model.GeneralQuery, d *model.MonologueUpdateSet) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	tx, err := dbLike.Begin()
+	if err != nil {
+		logger.Error.Printf("create session failed. %s\n", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := ruleMonologueDao.Get(tx, q, &model.Pagination{Limit: 10})
+	if err != nil {
+		return 0, err
+	}
+	if len(current) == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	q.ID = append(q.ID, current[0].ID)
+	newID, err := ruleMonologueDao.Copy(tx, q)
+	if err != nil {
+		logger.Error.Printf("copy new record %v failed. %s\n", *q, err)
+		return 0, err
+	}
+
+	affected, err := ruleMonologueDao.SoftDelete(tx, q)
+	if err != nil {
+		logger.Error.Printf("delete failed. %s\n", err)
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	newQuery := &model.GeneralQuery{ID: []int64{newID}}
+	affected, err = ruleMonologueDao.Update(tx, newQuery, d)
+	if err != nil {
+		logger.Error.Printf("update failed. %s\n", err)
+		return 0, err
+	}
+	tx.Commit()
+	return affected, err
+}
+
+type MonologueRuleWithException struct {
+	RuleGroupID int64
+	model.MonologueRule
+	criteria *senGrpCriteria
+}
+
+//RuleMonologueCheck checks the monologue rules
+func RuleMonologueCheck(ruleGroup model.Group, allSegs []*SegmentWithSpeaker, matched []*MatchedData) ([]RulesException, error) {
+	if len(allSegs) == 0 {
+		return nil, nil
+	}
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &ruleGroup.EnterpriseID, IsDelete: &isDelete, UUID: []string{ruleGroup.UUID}}
+	mRules, err := ruleMonologueDao.GetByRuleGroup(dbLike.Conn(), q)
+	if err != nil {
+		logger.Error.Printf("get rule monologue failed. %s\n", err)
+		return nil, err
+	}
+	if len(mRules) == 0 {
+		return nil, nil
+	}
+
+	pureWordSegs := extractPureWordsSegment(allSegs)
+	if len(pureWordSegs) != len(matched) {
+		return nil, ErrSegsNotMatched
+	}
+
+	rules := make([]MonologueRuleWithException, 0, len(mRules))
+	for _, r := range mRules {
+		_, criteria, err := GetRuleMonologueException(r)
+		if err != nil {
+			logger.Error.Printf("get monologue exception failed. %s\n", err)
+			return nil, err
+		}
+		rules = append(rules, MonologueRuleWithException{RuleGroupID: ruleGroup.ID, MonologueRule: *r, criteria: criteria})
+	}
+
+	return monologueRuleCheck(rules, matched, allSegs, pureWordSegs), nil
+}
+
+//monologue is the continuous staff segments without any customer's segment in the middle
+type monologue struct {
+	duration float64
+	talkViolation
+}
+
+//extractMonologues finds all the monologues in the call.
+//silence and interposal segments are not treated as the end of the monologue
+func extractMonologues(allSegs []*SegmentWithSpeaker) []monologue {
+	wordIdx := wordSegsIndex(allSegs)
+	resp := make([]monologue, 0)
+	var cur *monologue
+	var start float64
+	for k, v := range allSegs {
+		switch v.Speaker {
+		case int(model.CallChanStaff):
+			if cur == nil {
+				cur = &monologue{}
+				start = v.StartTime
+			}
+			cur.duration = v.EndTime - start
+			cur.segIDs = append(cur.segIDs, v.ID)
+			cur.excptIdxs = append(cur.excptIdxs, wordIdx[k])
+		case int(model.CallChanCustomer):
+			if cur != nil {
+				resp = append(resp, *cur)
+				cur = nil
+			}
+		}
+	}
+	if cur != nil {
+		resp = append(resp, *cur)
+	}
+	return resp
+}
+
+//monologueRuleCheck checks the monologue rules
+//allSegs includes the silence and interposal segments, segs and tagMatchDat are the words segments only
+func monologueRuleCheck(rules []MonologueRuleWithException, tagMatchDat []*MatchedData,
+	allSegs []*SegmentWithSpeaker, segs []*SegmentWithSpeaker) []RulesException {
+
+	callID := allSegs[0].CallID
+	monologues := extractMonologues(allSegs)
+
+	resp := make([]RulesException, 0, len(rules))
+	for _, r := range rules {
+		violations := make([]talkViolation, 0)
+		for _, m := range monologues {
+			if m.duration > float64(r.Seconds) {
+				violations = append(violations, m.talkViolation)
+			}
+		}
+		result := RulesException{RuleID: r.ID, Typ: levMonologueTyp, Whos: Monologue,
+			CallID: callID, RuleGroupID: r.RuleGroupID}
+		checkTalkViolations(&result, r.Times, r.Score, violations, r.criteria, tagMatchDat, segs)
+		resp = append(resp, result)
+	}
+	return resp
+}
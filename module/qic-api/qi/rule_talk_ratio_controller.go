@@ -0,0 +1,222 @@
+package qi
+
+import (
+	"errors"
+	"net/http"
+
+	"emotibot.com/emotigo/module/admin-api/ApiError"
+	"emotibot.com/emotigo/module/admin-api/util"
+	"emotibot.com/emotigo/module/admin-api/util/requestheader"
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//ErrWrongRatio is returned if the talk ratio range is not in 0 to 100
+var ErrWrongRatio = errors.New("invalid ratio")
+
+func checkTalkRatioRule(r *model.TalkRatioRule) error {
+	if r == nil {
+		return ErrEmptyRequest
+	}
+	if r.Name == "" {
+		return ErrEmptyName
+	}
+	if r.Min < 0 || r.Max > 100 || r.Min > r.Max {
+		return ErrWrongRatio
+	}
+	return nil
+}
+
+func handleNewRuleTalkRatio(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+
+	var requestBody model.TalkRatioRule
+	err := util.ReadJSON(r, &requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkTalkRatioRule(&requestBody)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	uuid, err := NewRuleTalkRatio(&requestBody, enterprise)
+	if err != nil {
+		logger.Error.Printf("create rule talk ratio failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		UUID string `json:"talk_ratio_id"`
+	}{UUID: uuid})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleTalkRatioList(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	page, limit, err := getPageLimit(r)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err), http.StatusBadRequest)
+		return
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &enterprise, IsDelete: &isDelete}
+	p := &model.Pagination{Limit: limit, Page: page}
+
+	resp, err := GetRuleTalkRatios(q, p)
+	if err != nil {
+		logger.Error.Printf("get the talk ratio rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	total, err := CountRuleTalkRatio(q)
+	if err != nil {
+		logger.Error.Printf("count the talk ratio rule failed. q: %+v, err: %s\n", *q, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Page pageResp               `json:"paging"`
+		Data []*model.TalkRatioRule `json:"data"`
+	}{
+		Page: pageResp{Current: page, Limit: limit, Total: uint64(total)},
+		Data: resp,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleGetRuleTalkRatio(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+
+	settings, err := GetRuleTalkRatios(q, nil)
+	if err != nil {
+		logger.Error.Printf("get the talk ratio rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if len(settings) == 0 {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, "no such id"), http.StatusBadRequest)
+		return
+	}
+
+	except, _, err := GetRuleTalkRatioException(settings[0])
+	if err != nil {
+		logger.Error.Printf("get the exception rule failed. %s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = util.WriteJSON(w, struct {
+		Setting   model.TalkRatioRule `json:"setting"`
+		Exception TalkRuleException   `json:"exception"`
+	}{
+		Setting:   *settings[0],
+		Exception: *except,
+	})
+	if err != nil {
+		logger.Error.Printf("%s\n", err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func handleDeleteRuleTalkRatio(w http.ResponseWriter, r *http.Request) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	q := &model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}
+	_, err := DeleteRuleTalkRatio(q)
+	if err != nil {
+		logger.Error.Printf("delete %s failed. %s\n", uuid, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+}
+
+func checkTalkRatioUpdateSet(r model.TalkRatioUpdateSet) error {
+	tr := model.TalkRatioRule{Name: "valid name", Score: 99, Min: 0, Max: 100}
+	if r.Name != nil {
+		tr.Name = *r.Name
+	}
+	if r.Score != nil {
+		tr.Score = *r.Score
+	}
+	if r.Min != nil {
+		tr.Min = *r.Min
+	}
+	if r.Max != nil {
+		tr.Max = *r.Max
+	}
+	return checkTalkRatioRule(&tr)
+}
+
+func handleModifyRuleTalkRatio(w http.ResponseWriter, r *http.Request) {
+	var req model.TalkRatioUpdateSet
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = checkTalkRatioUpdateSet(req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	//just in case
+	req.Exception = nil
+	updateRuleTalkRatio(w, r, &req)
+}
+
+func handleExceptionRuleTalkRatio(w http.ResponseWriter, r *http.Request) {
+	var req talkRuleExceptionInternal
+	err := util.ReadJSON(r, &req)
+	if err != nil {
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	except, err := marshalTalkRuleException(req.SentenceGroups)
+	if err != nil {
+		logger.Error.Printf("marshal %+v failed. %s\n", req.SentenceGroups, err)
+		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.JSON_PARSE_ERROR, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	updateRuleTalkRatio(w, r, &model.TalkRatioUpdateSet{Exception: &except})
+}
+
+func updateRuleTalkRatio(w http.ResponseWriter, r *http.Request, d *model.TalkRatioUpdateSet) {
+	enterprise := requestheader.GetEnterpriseID(r)
+	uuid := general.ParseID(r)
+
+	isDelete := 0
+	_, err := UpdateRuleTalkRatio(&model.GeneralQuery{UUID: []string{uuid}, Enterprise: &enterprise, IsDelete: &isDelete}, d)
+	if err != nil {
+		if err == ErrNoSuchID {
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.REQUEST_ERROR, err.Error()), http.StatusBadRequest)
+		} else {
+			logger.Error.Printf("update %s failed. %s\n", uuid, err)
+			util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package qi
+
+import (
+	"time"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+var (
+	ruleTalkRatioDao model.TalkRatioRuleDao = &model.TalkRatioRuleSQLDao{}
+)
+
+//NewRuleTalkRatio creates the new talk ratio rule
+func NewRuleTalkRatio(r *model.TalkRatioRule, enterprise string) (string, error) {
+	if r == nil {
+		return "", ErrNoArgument
+	}
+	if dbLike == nil {
+		return "", ErrNilCon
+	}
+	uuid, err := general.UUID()
+	if err != nil {
+		return "", err
+	}
+	r.Enterprise = enterprise
+	r.CreateTime = time.Now().Unix()
+	r.UpdateTime = r.CreateTime
+	r.UUID = uuid
+	_, err = ruleTalkRatioDao.Add(dbLike.Conn(), r)
+	return r.UUID, err
+}
+
+//GetRuleTalkRatios gets the list of talk ratio rule
+func GetRuleTalkRatios(q *model.GeneralQuery, p *model.Pagination) ([]*model.TalkRatioRule, error) {
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	return ruleTalkRatioDao.Get(dbLike.Conn(), q, p)
+}
+
+//CountRuleTalkRatio counts the total number of talk ratio rule
+func CountRuleTalkRatio(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	return ruleTalkRatioDao.Count(dbLike.Conn(), q)
+}
+
+//GetRuleTalkRatioException gets the talk ratio rule exception
+func GetRuleTalkRatioException(r *model.TalkRatioRule) (*TalkRuleException, *senGrpCriteria, error) {
+	if r == nil {
+		return newTalkRuleException(), nil, nil
+	}
+	return getSenGrpCriteria(r.Exception, r.Enterprise)
+}
+
+//DeleteRuleTalkRatio deletes the talk ratio rule
+func DeleteRuleTalkRatio(q *model.GeneralQuery) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	return ruleTalkRatioDao.SoftDelete(dbLike.Conn(), q)
+}
+
+//UpdateRuleTalkRatio updates the talk ratio rule
+func UpdateRuleTalkRatio(q *model.GeneralQuery, d *model.TalkRatioUpdateSet) (int64, error) {
+	if dbLike == nil {
+		return 0, ErrNilCon
+	}
+	if q == nil || (len(q.ID) == 0 && len(q.UUID) == 0) {
+		return 0, ErrNoID
+	}
+	tx, err := dbLike.Begin()
+	if err != nil {
+		logger.Error.Printf("create session failed. %s\n", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := ruleTalkRatioDao.Get(tx, q, &model.Pagination{Limit: 10})
+	if err != nil {
+		return 0, err
+	}
+	if len(current) == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	q.ID = append(q.ID, current[0].ID)
+	newID, err := ruleTalkRatioDao.Copy(tx, q)
+	if err != nil {
+		logger.Error.Printf("copy new record %v failed. %s\n", *q, err)
+		return 0, err
+	}
+
+	affected, err := ruleTalkRatioDao.SoftDelete(tx, q)
+	if err != nil {
+		logger.Error.Printf("delete failed. %s\n", err)
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrNoSuchID
+	}
+
+	newQuery := &model.GeneralQuery{ID: []int64{newID}}
+	affected, err = ruleTalkRatioDao.Update(tx, newQuery, d)
+	if err != nil {
+		logger.Error.Printf("update failed. %s\n", err)
+		return 0, err
+	}
+	tx.Commit()
+	return affected, err
+}
+
+type TalkRatioRuleWithException struct {
+	RuleGroupID int64
+	model.TalkRatioRule
+	criteria *senGrpCriteria
+}
+
+//RuleTalkRatioCheck checks the talk ratio rules
+func RuleTalkRatioCheck(ruleGroup model.Group, segs []*SegmentWithSpeaker, matched []*MatchedData) ([]RulesException, error) {
+	if len(segs) == 0 {
+		return nil, nil
+	}
+	if dbLike == nil {
+		return nil, ErrNilCon
+	}
+	if len(segs) != len(matched) {
+		return nil, ErrSegsNotMatched
+	}
+
+	isDelete := 0
+	q := &model.GeneralQuery{Enterprise: &ruleGroup.EnterpriseID, IsDelete: &isDelete, UUID: []string{ruleGroup.UUID}}
+	tRules, err := ruleTalkRatioDao.GetByRuleGroup(dbLike.Conn(), q)
+	if err != nil {
+		logger.Error.Printf("get rule talk ratio failed. %s\n", err)
+		return nil, err
+	}
+	if len(tRules) == 0 {
+		return nil, nil
+	}
+
+	rules := make([]TalkRatioRuleWithException, 0, len(tRules))
+	for _, r := range tRules {
+		_, criteria, err := GetRuleTalkRatioException(r)
+		if err != nil {
+			logger.Error.Printf("get talk ratio exception failed. %s\n", err)
+			return nil, err
+		}
+		rules = append(rules, TalkRatioRuleWithException{RuleGroupID: ruleGroup.ID, TalkRatioRule: *r, criteria: criteria})
+	}
+
+	return talkRatioRuleCheck(rules, matched, segs), nil
+}
+
+//staffTalkRatio gives the percentage of the staff talk time to the total talk time of staff and customer.
+//returns false if nobody talks
+func staffTalkRatio(segs []*SegmentWithSpeaker) (float64, bool) {
+	var staff, total float64
+	for _, s := range segs {
+		dur := s.EndTime - s.StartTime
+		switch s.Speaker {
+		case int(model.CallChanStaff):
+			staff += dur
+			total += dur
+		case int(model.CallChanCustomer):
+			total += dur
+		}
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	return staff * 100 / total, true
+}
+
+//talkRatioRuleCheck checks the talk ratio rules, segs and tagMatchDat are the words segments only.
+//Any exception matched in the call excuses the rule
+func talkRatioRuleCheck(rules []TalkRatioRuleWithException, tagMatchDat []*MatchedData,
+	segs []*SegmentWithSpeaker) []RulesException {
+
+	callID := segs[0].CallID
+	ratio, ok := staffTalkRatio(segs)
+	allIdxs := make([]int, len(segs))
+	for i := range allIdxs {
+		allIdxs[i] = i
+	}
+
+	resp := make([]RulesException, 0, len(rules))
+	for _, r := range rules {
+		violations := make([]talkViolation, 0)
+		if ok && (ratio < float64(r.Min) || ratio > float64(r.Max)) {
+			violations = append(violations, talkViolation{excptIdxs: allIdxs})
+		}
+		result := RulesException{RuleID: r.ID, Typ: levTalkRatioTyp, Whos: TalkRatio,
+			CallID: callID, RuleGroupID: r.RuleGroupID}
+		checkTalkViolations(&result, 1, r.Score, violations, r.criteria, tagMatchDat, segs)
+		resp = append(resp, result)
+	}
+	return resp
+}
@@ -0,0 +1,211 @@
+package qi
+
+import (
+	"encoding/json"
+	"errors"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/pkg/logger"
+)
+
+//ErrSegsNotMatched is returned if the words segments can not be mapped to the tag matched data
+var ErrSegsNotMatched = errors.New("total seg without silence not equal to matched seg")
+
+//talk-time rules, monologue, talk ratio, hold and latency, share the same exception format.
+//The exception is a list of sentence groups. The role of the sentence group decides who should say it.
+
+//TalkRuleException is used to return the exception of the talk-time rules
+type TalkRuleException struct {
+	SentenceGroups []SentenceGroupInResponse `json:"sentence_groups"`
+}
+
+//talkRuleExceptionInternal is used to stored the exception sentence group uuid in db
+type talkRuleExceptionInternal struct {
+	SentenceGroups []string `json:"sentence_groups"`
+}
+
+//senGrpCriteria is the criteria to check whether the sentence groups are matched
+type senGrpCriteria struct {
+	groups    []*SenGroupCriteria
+	sentences map[uint64][]uint64 //sentence id to its tag ids
+}
+
+//talkViolation is the part of the call that breaks the talk-time rule.
+//segIDs are the segments recorded as the invalid segments
+//excptIdxs are the index of the words segments, which could excuse this violation if the exception is matched on it
+type talkViolation struct {
+	segIDs    []int64
+	excptIdxs []int
+}
+
+func newTalkRuleException() *TalkRuleException {
+	return &TalkRuleException{SentenceGroups: make([]SentenceGroupInResponse, 0)}
+}
+
+func marshalTalkRuleException(senGrps []string) (string, error) {
+	if senGrps == nil {
+		senGrps = make([]string, 0)
+	}
+	b, err := json.Marshal(talkRuleExceptionInternal{SentenceGroups: senGrps})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+//getSenGrpCriteria parses the sentence groups stored in the given column
+//and returns the sentence groups for response and the criteria for checking
+func getSenGrpCriteria(stored string, enterprise string) (*TalkRuleException, *senGrpCriteria, error) {
+	if stored == "" {
+		return newTalkRuleException(), &senGrpCriteria{sentences: make(map[uint64][]uint64)}, nil
+	}
+
+	var internal talkRuleExceptionInternal
+	err := json.Unmarshal([]byte(stored), &internal)
+	if err != nil {
+		logger.Error.Printf("unmarshal sentence group exception %s failed. %s\n", stored, err)
+		return nil, nil, err
+	}
+	return senGrpCriteriaByUUID(internal.SentenceGroups, enterprise)
+}
+
+//senGrpCriteriaByUUID gets the sentence groups by uuid,
+//returns the sentence groups for response and the criteria for checking in the same order
+func senGrpCriteriaByUUID(uuids []string, enterprise string) (*TalkRuleException, *senGrpCriteria, error) {
+	resp := newTalkRuleException()
+	criteria := &senGrpCriteria{sentences: make(map[uint64][]uint64)}
+	if len(uuids) == 0 {
+		return resp, criteria, nil
+	}
+	if dbLike == nil {
+		return nil, nil, ErrNilCon
+	}
+
+	var isDelete int8
+	groups, err := sentenceGroupDao.GetBy(&model.SentenceGroupFilter{
+		UUID:       uuids,
+		Enterprise: enterprise,
+		IsDelete:   &isDelete,
+	}, sqlConn)
+	if err != nil {
+		logger.Error.Printf("get sentence groups %+v failed. %s\n", uuids, err)
+		return nil, nil, err
+	}
+
+	senIDs := make([]uint64, 0)
+	for i := range groups {
+		g := &groups[i]
+		c := &SenGroupCriteria{ID: uint64(g.ID), Role: g.Role}
+		for _, s := range g.Sentences {
+			c.SentenceID = append(c.SentenceID, s.ID)
+			senIDs = append(senIDs, s.ID)
+		}
+		criteria.groups = append(criteria.groups, c)
+		resp.SentenceGroups = append(resp.SentenceGroups, sentenceGroupToSentenceGroupInResponse(g))
+	}
+
+	if len(senIDs) > 0 {
+		sentences, err := sentenceDao.GetSentences(dbLike.Conn(), &model.SentenceQuery{ID: senIDs, Enterprise: &enterprise})
+		if err != nil {
+			logger.Error.Printf("get sentences %+v failed. %s\n", senIDs, err)
+			return nil, nil, err
+		}
+		for _, s := range sentences {
+			criteria.sentences[s.ID] = append(criteria.sentences[s.ID], s.TagIDs...)
+		}
+	}
+	return resp, criteria, nil
+}
+
+//matchSenGrps returns the 0 based index of words segments that each sentence group is matched,
+//the role of the sentence group is checked
+func matchSenGrps(c *senGrpCriteria, segMatchedTag []map[uint64]bool, segs []*SegmentWithSpeaker) map[uint64][]int {
+	resp := make(map[uint64][]int)
+	if c == nil || len(c.groups) == 0 {
+		return resp
+	}
+	senMatched, _ := SentencesMatchWithZeroBasedIndex(segMatchedTag, c.sentences)
+	for _, g := range c.groups {
+		dup := make(map[int]bool)
+		for _, sID := range g.SentenceID {
+			for _, idx := range senMatched[sID] {
+				if idx >= len(segs) || dup[idx] {
+					continue
+				}
+				if g.Role == roleMapping["any"] || g.Role < 0 || segs[idx].Speaker == g.Role {
+					dup[idx] = true
+					resp[g.ID] = append(resp[g.ID], idx)
+				}
+			}
+		}
+	}
+	return resp
+}
+
+//wordSegsIndex maps the index of allSegs to the index of the words segments, -1 for silence or interposal segment
+func wordSegsIndex(allSegs []*SegmentWithSpeaker) []int {
+	resp := make([]int, len(allSegs))
+	var cur int
+	for k, v := range allSegs {
+		if v.Speaker == int(model.CallChanStaff) || v.Speaker == int(model.CallChanCustomer) {
+			resp[k] = cur
+			cur++
+		} else {
+			resp[k] = -1
+		}
+	}
+	return resp
+}
+
+//checkTalkViolations fills up the given result by the violations.
+//Violation is excused if any exception sentence group is matched on its excptIdxs.
+//The rule is valid if the number of unexcused violations is less than times
+//segs and tagMatchDat are the words segments and its matched tags, they must have the same length
+func checkTalkViolations(result *RulesException, times int, score int, violations []talkViolation,
+	c *senGrpCriteria, tagMatchDat []*MatchedData, segs []*SegmentWithSpeaker) {
+
+	segMatchedTag := extractTagMatchedData(tagMatchDat)
+	senGrpMatched := matchSenGrps(c, segMatchedTag, segs)
+
+	exceptions := make(map[uint64]*ExceptionMatched)
+	if c != nil {
+		for _, g := range c.groups {
+			e := &ExceptionMatched{SentenceID: int64(g.ID), Typ: levExcptSenGrpTyp}
+			exceptions[g.ID] = e
+			result.Exception = append(result.Exception, e)
+		}
+	}
+
+	var numOfBreak int
+	for _, v := range violations {
+		result.TalkSegs = append(result.TalkSegs, v.segIDs...)
+		excused := false
+		for grpID, matchedIdxs := range senGrpMatched {
+			for _, idx := range matchedIdxs {
+				for _, excptIdx := range v.excptIdxs {
+					if idx != excptIdx {
+						continue
+					}
+					excused = true
+					e := exceptions[grpID]
+					e.Valid = true
+					e.Tags = append(e.Tags, composeTagCredits(tagMatchDat[idx], segs[idx].ID)...)
+				}
+			}
+		}
+		if !excused {
+			numOfBreak++
+		}
+	}
+
+	result.Valid = numOfBreak < times
+	if result.Valid {
+		if score > 0 {
+			result.Score = score
+		}
+	} else {
+		if score < 0 {
+			result.Score = score
+		}
+	}
+}
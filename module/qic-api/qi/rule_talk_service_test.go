@@ -0,0 +1,134 @@
+package qi
+
+import (
+	"testing"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/logicaccess"
+)
+
+func talkTestData() ([]*MatchedData, []*SegmentWithSpeaker) {
+	staffSpeaker := int(model.CallChanStaff)
+	customerSpeaker := int(model.CallChanCustomer)
+
+	tagMatchDat := []*MatchedData{
+		&MatchedData{Matched: map[uint64]*logicaccess.AttrResult{11: &logicaccess.AttrResult{}}},
+		&MatchedData{Matched: map[uint64]*logicaccess.AttrResult{12: &logicaccess.AttrResult{}}},
+		&MatchedData{Matched: map[uint64]*logicaccess.AttrResult{13: &logicaccess.AttrResult{}}},
+		&MatchedData{Matched: map[uint64]*logicaccess.AttrResult{14: &logicaccess.AttrResult{}}},
+		&MatchedData{Matched: map[uint64]*logicaccess.AttrResult{15: &logicaccess.AttrResult{}}},
+		&MatchedData{Matched: map[uint64]*logicaccess.AttrResult{16: &logicaccess.AttrResult{}}},
+	}
+	allSegs := []*SegmentWithSpeaker{
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 1, CallID: 1234, StartTime: 1.11, EndTime: 2.99}, Speaker: SilenceSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 2, CallID: 1234, StartTime: 2.99, EndTime: 18.7}, Speaker: staffSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 3, CallID: 1234, StartTime: 18.11, EndTime: 21.99}, Speaker: customerSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 4, CallID: 1234, StartTime: 21.99, EndTime: 35.7}, Speaker: SilenceSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 5, CallID: 1234, StartTime: 35.8, EndTime: 49.1}, Speaker: staffSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 6, CallID: 1234, StartTime: 49.2, EndTime: 50.11}, Speaker: SilenceSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 7, CallID: 1234, StartTime: 50.12, EndTime: 59.3}, Speaker: staffSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 8, CallID: 1234, StartTime: 60, EndTime: 61.2}, Speaker: customerSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 9, CallID: 1234, StartTime: 61.3, EndTime: 78.4}, Speaker: SilenceSpeaker},
+		&SegmentWithSpeaker{RealSegment: model.RealSegment{ID: 10, CallID: 1234, StartTime: 78.5, EndTime: 89}, Speaker: staffSpeaker},
+	}
+	return tagMatchDat, allSegs
+}
+
+func talkCriteria(role int, tags ...uint64) *senGrpCriteria {
+	return &senGrpCriteria{
+		groups:    []*SenGroupCriteria{&SenGroupCriteria{ID: 1, SentenceID: []uint64{1}, Role: role}},
+		sentences: map[uint64][]uint64{1: tags},
+	}
+}
+
+func checkTalkCredits(t *testing.T, expected []bool, credits []RulesException) {
+	if len(expected) != len(credits) {
+		t.Fatalf("expecting %d credit, but get %d\n", len(expected), len(credits))
+	}
+	for k, v := range expected {
+		if v != credits[k].Valid {
+			t.Errorf("expecting get %t at %d'th credit, but get %t\n", v, k+1, credits[k].Valid)
+		}
+	}
+}
+
+func TestMonologueRuleCheck(t *testing.T) {
+	tagMatchDat, allSegs := talkTestData()
+	rules := []MonologueRuleWithException{
+		MonologueRuleWithException{MonologueRule: model.MonologueRule{Seconds: 20, Times: 1}},
+		MonologueRuleWithException{MonologueRule: model.MonologueRule{Seconds: 20, Times: 1},
+			criteria: talkCriteria(roleMapping["staff"], 13)},
+		MonologueRuleWithException{MonologueRule: model.MonologueRule{Seconds: 20, Times: 1},
+			criteria: talkCriteria(roleMapping["customer"], 13)},
+		MonologueRuleWithException{MonologueRule: model.MonologueRule{Seconds: 10, Times: 3}},
+		MonologueRuleWithException{MonologueRule: model.MonologueRule{Seconds: 16, Times: 2}},
+	}
+	expected := []bool{false, true, false, false, true}
+
+	credits := monologueRuleCheck(rules, tagMatchDat, allSegs, extractPureWordsSegment(allSegs))
+	checkTalkCredits(t, expected, credits)
+	if len(credits[0].TalkSegs) != 2 {
+		t.Errorf("expecting 2 monologue segments, but get %+v\n", credits[0].TalkSegs)
+	}
+}
+
+func TestHoldRuleCheck(t *testing.T) {
+	tagMatchDat, allSegs := talkTestData()
+	rules := []HoldRuleWithException{
+		HoldRuleWithException{HoldRule: model.HoldRule{Seconds: 15, Times: 1}},
+		HoldRuleWithException{HoldRule: model.HoldRule{Seconds: 15, Times: 1},
+			criteria: talkCriteria(roleMapping["staff"], 16)},
+		HoldRuleWithException{HoldRule: model.HoldRule{Seconds: 20, Times: 1}},
+		HoldRuleWithException{HoldRule: model.HoldRule{Seconds: 10, Times: 2}},
+	}
+	expected := []bool{false, true, true, false}
+
+	credits := holdRuleCheck(rules, tagMatchDat, allSegs, extractPureWordsSegment(allSegs))
+	checkTalkCredits(t, expected, credits)
+}
+
+func TestExtractHolds(t *testing.T) {
+	_, allSegs := talkTestData()
+	holds := extractHolds(allSegs)
+	expected := []int64{4, 6, 9}
+	if len(holds) != len(expected) {
+		t.Fatalf("expecting %d holds, but get %d\n", len(expected), len(holds))
+	}
+	for k, v := range expected {
+		if holds[k].segIDs[0] != v {
+			t.Errorf("expecting hold segment %d at %d, but get %d\n", v, k, holds[k].segIDs[0])
+		}
+	}
+}
+
+func TestTalkRatioRuleCheck(t *testing.T) {
+	tagMatchDat, allSegs := talkTestData()
+	rules := []TalkRatioRuleWithException{
+		TalkRatioRuleWithException{TalkRatioRule: model.TalkRatioRule{Min: 0, Max: 80}},
+		TalkRatioRuleWithException{TalkRatioRule: model.TalkRatioRule{Min: 0, Max: 95}},
+		TalkRatioRuleWithException{TalkRatioRule: model.TalkRatioRule{Min: 0, Max: 80},
+			criteria: talkCriteria(roleMapping["any"], 15)},
+		TalkRatioRuleWithException{TalkRatioRule: model.TalkRatioRule{Min: 95, Max: 100}},
+	}
+	expected := []bool{false, true, true, false}
+
+	credits := talkRatioRuleCheck(rules, tagMatchDat, extractPureWordsSegment(allSegs))
+	checkTalkCredits(t, expected, credits)
+}
+
+func TestLatencyRuleCheck(t *testing.T) {
+	tagMatchDat, allSegs := talkTestData()
+	rules := []LatencyRuleWithException{
+		LatencyRuleWithException{LatencyRule: model.LatencyRule{Seconds: 15, Times: 1}},
+		LatencyRuleWithException{LatencyRule: model.LatencyRule{Seconds: 20, Times: 1}},
+		LatencyRuleWithException{LatencyRule: model.LatencyRule{Seconds: 15, Times: 1},
+			trigger: talkCriteria(roleMapping["customer"], 12)},
+		LatencyRuleWithException{LatencyRule: model.LatencyRule{Seconds: 15, Times: 1},
+			criteria: talkCriteria(roleMapping["staff"], 16)},
+		LatencyRuleWithException{LatencyRule: model.LatencyRule{Seconds: 10, Times: 2}},
+	}
+	expected := []bool{false, true, true, true, false}
+
+	credits := latencyRuleCheck(rules, tagMatchDat, extractPureWordsSegment(allSegs))
+	checkTalkCredits(t, expected, credits)
+}
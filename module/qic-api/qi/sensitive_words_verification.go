@@ -5,6 +5,7 @@ import (
 
 	"emotibot.com/emotigo/module/qic-api/model/v1"
 	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 	"emotibot.com/emotigo/pkg/logger"
 	goahocorasick "github.com/anknown/ahocorasick"
 )
@@ -112,7 +113,8 @@ func StoreSensitiveCredit(conn model.SqlLike, credits []*SensitiveWordCredit, ro
 }
 
 //SensitiveWordsVerificationWithPacked packages the sensitive words result into the structure
-func SensitiveWordsVerificationWithPacked(callID int64, segments []*SegmentWithSpeaker, enterprise string) ([]*SensitiveWordCredit, error) {
+//only the sensitive words used in the locale loc are checked
+func SensitiveWordsVerificationWithPacked(callID int64, segments []*SegmentWithSpeaker, enterprise string, loc string) ([]*SensitiveWordCredit, error) {
 	if dbLike == nil {
 		return nil, ErrNilCon
 	}
@@ -122,6 +124,9 @@ func SensitiveWordsVerificationWithPacked(callID int64, segments []*SegmentWithS
 		Enterprise: &enterprise,
 		Deleted:    &deleted,
 	}
+	if loc != "" {
+		filter.Locale = []string{"", loc}
+	}
 
 	sws, err := swDao.GetBy(filter, sqlConn)
 	if err != nil {
@@ -142,9 +147,10 @@ func SensitiveWordsVerificationWithPacked(callID int64, segments []*SegmentWithS
 	swCredits := make(map[int64]*SensitiveWordCredit)
 	for idx, sw := range sws {
 		swID[idx] = sw.ID
-		swMap[sw.Name] = sw
-		swViolated[sw.Name] = false
-		swNames[idx] = sw.Name
+		name := locale.Normalize(sw.Name)
+		swMap[name] = sw
+		swViolated[name] = false
+		swNames[idx] = name
 
 		//create the sensitive credits and its exception setting
 		c := &SensitiveWordCredit{sensitiveWord: model.SimpleCredit{
@@ -235,7 +241,7 @@ func SensitiveWordsVerificationWithPacked(callID int64, segments []*SegmentWithS
 			// ignore what customer said
 			continue
 		}
-		if violates := m.MultiPatternSearch([]rune(seg.Text), false); len(violates) > 0 {
+		if violates := m.MultiPatternSearch([]rune(locale.Normalize(seg.Text)), false); len(violates) > 0 {
 			for _, term := range violates {
 				sw, ok := swMap[string(term.Word)]
 				if !ok {
@@ -376,9 +382,10 @@ func SensitiveWordsVerification(callID int64, segments []*SegmentWithSpeaker, en
 	swNames := make([]string, len(sws))
 	for idx, sw := range sws {
 		swID[idx] = sw.ID
-		swMap[sw.Name] = sw
-		swViolated[sw.Name] = false
-		swNames[idx] = sw.Name
+		name := locale.Normalize(sw.Name)
+		swMap[name] = sw
+		swViolated[name] = false
+		swNames[idx] = name
 	}
 
 	staffExceptions, customerExceptions, err := swDao.GetRels(swID, sqlConn)
@@ -441,7 +448,7 @@ func SensitiveWordsVerification(callID int64, segments []*SegmentWithSpeaker, en
 			// ignore what customer said
 			continue
 		}
-		if violates := m.MultiPatternSearch([]rune(seg.Text), false); len(violates) > 0 {
+		if violates := m.MultiPatternSearch([]rune(locale.Normalize(seg.Text)), false); len(violates) > 0 {
 			for _, term := range violates {
 				sw := swMap[string(term.Word)]
 				credit := model.SimpleCredit{
@@ -546,7 +553,7 @@ func SensitiveWordsVerification(callID int64, segments []*SegmentWithSpeaker, en
 					}
 				}
 
-				swViolated[sw.Name] = !passed
+				swViolated[locale.Normalize(sw.Name)] = !passed
 			}
 
 		}
@@ -81,13 +81,11 @@ func predictSentence(enterpriseID string, testedID uint64) error {
 		expectedTagID[tagID] = true
 	}
 
-	models, err := GetUsingModelByEnterprise(enterpriseID)
+	//the sentence is tested with the model of its locale
+	predictModel, err := GetUsingModelByLocale(enterpriseID, sentence.Locale)
 	if err != nil {
 		return err
 	}
-	if len(models) > 1 {
-		logger.Warn.Printf("more than 1 model")
-	}
 
 	testSentences, err := GetTestSentences(enterpriseID, testedID)
 	if err != nil {
@@ -101,8 +99,7 @@ func predictSentence(enterpriseID string, testedID uint64) error {
 		testSenName = append(testSenName, item.Name)
 	}
 
-	// use the first model
-	matched, err := TagMatch([]uint64{models[0].ID}, testSenName, 3*time.Second)
+	matched, err := TagMatch([]uint64{predictModel.ID}, testSenName, 3*time.Second)
 
 	sentenceTestResult := model.SentenceTestResult{
 		Name:       sentence.Name,
@@ -12,6 +12,7 @@ import (
 
 	"emotibot.com/emotigo/module/admin-api/util/AdminErrors"
 	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 
 	"emotibot.com/emotigo/module/admin-api/util"
 
@@ -61,6 +62,12 @@ func getTagQuery(r *http.Request) (*model.TagQuery, error) {
 			query.TagType = append(query.TagType, typno)
 		}
 	}
+	for _, l := range r.Form["locale"] {
+		if !locale.IsValid(l) {
+			return nil, fmt.Errorf("invalid locale '%s'", l)
+		}
+		query.Locale = append(query.Locale, l)
+	}
 	name := r.Form.Get("keyword")
 	if name != "" {
 		name = fmt.Sprintf("%%%s%%", model.EscapeLike(name))
@@ -134,6 +141,7 @@ func HandlePutTags(w http.ResponseWriter, r *http.Request) {
 		NegativeSentence: updateTag.NegativeSentence,
 		CreateTime:       tg.CreateTime,
 		UpdateTime:       updateTag.UpdateTime,
+		Locale:           updateTag.Locale,
 	})
 	if err != nil {
 		util.ReturnError(w, AdminErrors.ErrnoDBError, fmt.Sprintf("update tag failed, %v", err))
@@ -252,6 +260,9 @@ func extractTag(r *http.Request) (*model.Tag, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get tag type failed, %v", err)
 	}
+	if !locale.IsValid(reqBody.Locale) {
+		return nil, fmt.Errorf("invalid locale '%s'", reqBody.Locale)
+	}
 
 	posSentences, _ := json.Marshal(reqBody.PosSentences)
 	negSentences, _ := json.Marshal(reqBody.NegSentences)
@@ -264,6 +275,7 @@ func extractTag(r *http.Request) (*model.Tag, error) {
 		NegativeSentence: string(negSentences),
 		CreateTime:       timestamp,
 		UpdateTime:       timestamp,
+		Locale:           reqBody.Locale,
 	}, nil
 }
 
@@ -272,4 +284,5 @@ type NewTagReq struct {
 	TagType      string   `json:"tag_type"`
 	PosSentences []string `json:"pos_sentences"`
 	NegSentences []string `json:"neg_sentences"`
+	Locale       string   `json:"locale"`
 }
@@ -51,11 +51,11 @@ func TestHandleGetTag(t *testing.T) {
 			mocker: func(t model.Tag) *sql.DB {
 				db, m, _ := sqlmock.New()
 				m.ExpectQuery("SHOW").WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("Tag"))
-				rows := sqlmock.NewRows([]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}).AddRow(
+				rows := sqlmock.NewRows([]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}).AddRow(
 					t.ID, t.UUID, t.IsDeleted,
 					t.Name, t.Typ, t.PositiveSentence,
 					t.NegativeSentence, t.CreateTime, t.UpdateTime,
-					t.Enterprise)
+					t.Enterprise, t.Locale)
 				m.ExpectQuery("SELECT ").WillReturnRows(rows)
 				return db
 			},
@@ -145,6 +145,7 @@ func toTag(result ...model.Tag) ([]tag, error) {
 			TagType:      typ,
 			PosSentences: posSentences,
 			NegSentences: negSentences,
+			Locale:       t.Locale,
 		})
 	}
 	return tags, nil
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
 	"emotibot.com/emotigo/pkg/logger"
 
 	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 	"emotibot.com/emotigo/module/qic-api/util/logicaccess"
 )
 
@@ -58,6 +60,9 @@ func TrainTags(tags []model.Tag) error {
 		if err != nil {
 			return fmt.Errorf("tag %d positive sentence payload is not a valid string array, %v", tag.ID, err)
 		}
+		//prediction is done on the normalized text, so does training
+		pos = locale.Normalizes(pos)
+		neg = locale.Normalizes(neg)
 
 		logic.ID = tag.ID
 
@@ -159,7 +164,7 @@ func TrainOneModelByEnterprise(tags []model.Tag, modelID int64, enterprise strin
 	trainLogic.Tags = append(trainLogic.Tags, strconv.FormatUint(tags[0].ID, 10))
 	logic.Data = append(logic.Data, trainLogic)
 	logic.ID = uint64(modelID)
-	unit := &logicaccess.TrainUnit{Logic: logic}
+	unit := &logicaccess.TrainUnit{Logic: logic, Enterprise: enterprise}
 	unit.Dialog = &logicaccess.CommonTrainData{ID: logic.ID, Data: []*logicaccess.TrainTagData{}}
 	unit.Keyword = &logicaccess.TrainKeyword{ID: logic.ID, Data: []*logicaccess.TrainKeywordData{}}
 	unit.UsrResponse = &logicaccess.CommonTrainData{ID: logic.ID, Data: []*logicaccess.TrainTagData{}}
@@ -174,6 +179,8 @@ func TrainOneModelByEnterprise(tags []model.Tag, modelID int64, enterprise strin
 		if err != nil {
 			return fmt.Errorf("tag %d positive sentence payload is not a valid string array, %v", tag.ID, err)
 		}
+		pos = locale.Normalizes(pos)
+		neg = locale.Normalizes(neg)
 
 		switch tag.Typ {
 		case 1: //keyword
@@ -208,15 +215,15 @@ func TrainOneModelByEnterprise(tags []model.Tag, modelID int64, enterprise strin
 		return err
 	}
 
-	err = waitTrainingModel(modelID)
+	err = waitTrainingModel(modelID, enterprise)
 
 	return err
 }
 
-func waitTrainingModel(modelID int64) error {
+func waitTrainingModel(modelID int64, enterprise string) error {
 	//get the status of training model to make sure it is finished
 	for {
-		status, err := trainer.Status(&logicaccess.TrainAPPID{ID: uint64(modelID)})
+		status, err := trainer.Status(&logicaccess.TrainAPPID{ID: uint64(modelID), Enterprise: enterprise})
 		if err != nil {
 			logger.Error.Printf("get status of training failed. %s,%s\n", status, err)
 			return err
@@ -232,7 +239,10 @@ func waitTrainingModel(modelID int64) error {
 	return nil
 }
 
-//TrainModelByEnterprise trains model
+//TrainModelByEnterprise trains the models of the enterprise.
+//One model is trained with all tags for the calls without locale,
+//and one model is trained for each locale used by tags, only with the tags can be used in that locale.
+//The returned id is the model of all tags
 func TrainModelByEnterprise(enterprise string) (int64, error) {
 	if dbLike == nil {
 		return 0, ErrNilCon
@@ -248,70 +258,124 @@ func TrainModelByEnterprise(enterprise string) (int64, error) {
 		tags, err := tagDao.Tags(nil, query)
 		if err != nil {
 			logger.Error.Printf("get tag failed\n")
-			//return 0, err
 			return
 		}
+
+		//the trained model id of each locale
+		trained := map[string]int64{"": modelID}
 		err = TrainOneModelByEnterprise(tags, modelID, enterprise)
-		if err != nil {
-			logger.Error.Printf("train model failed. %s\n", err)
-			affected, _ := modelDao.UpdateModel(dbLike.Conn(), &model.TModel{ID: uint64(modelID), Status: MStatErr})
-			if affected == 0 {
-				logger.Warn.Printf("model %d may have wrong status\n", modelID)
+		if err == nil {
+			for _, loc := range tagLocales(tags) {
+				var id int64
+				id, err = trainLocaleModel(tags, enterprise, loc)
+				if id != 0 {
+					trained[loc] = id
+				}
+				if err != nil {
+					break
+				}
 			}
-
-			//return 0, err
-			return
-		}
-
-		//switch the using model, set the last using model to deprecate and new one to using
-		tx, err := dbLike.Begin()
-		if err != nil {
-			//return 0, err
-			return
 		}
-		defer tx.Rollback()
-
-		//get the using models
-		status := MStatUsing
-		models, err := modelDao.TrainedModelInfo(tx, &model.TModelQuery{Enterprise: &enterprise, Status: &status})
 		if err != nil {
-			logger.Error.Printf("get using model failed. %s\n", err)
-			//return 0, err
-			return
-		}
-		//seet the current using model to deprecate status
-		if len(models) > 0 {
-			_, err = modelDao.UpdateModel(tx, &model.TModel{ID: models[0].ID, Status: MStatDeprecate})
-			if err != nil {
-				logger.Error.Printf("update model %d status failed. %s\n", models[0].ID, err)
-				//return 0, err
-				return
+			logger.Error.Printf("train model failed. %s\n", err)
+			for _, id := range trained {
+				affected, _ := modelDao.UpdateModel(dbLike.Conn(), &model.TModel{ID: uint64(id), Status: MStatErr})
+				if affected == 0 {
+					logger.Warn.Printf("model %d may have wrong status\n", id)
+				}
 			}
-		}
-		//set the new model to using status
-		_, err = modelDao.UpdateModel(tx, &model.TModel{ID: uint64(modelID), Status: MStatUsing})
-		if err != nil {
-			logger.Error.Printf("update model %d status failed. %s\n", models[0].ID, err)
-			//return 0, err
 			return
 		}
 
-		err = tx.Commit()
+		deprecated, err := switchUsingModels(enterprise, trained)
 		if err != nil {
-			logger.Error.Printf("commit failed. %s\n", err)
-			//return 0, err
 			return
 		}
 		//if exist deprecated models which somehow doesn't be cleaned
 		cleanModel(enterprise)
-		if len(models) > 0 {
+		for _, id := range deprecated {
 			//unload the model after two hour
-			setUnloadModelTimer(int64(models[0].ID), time.Duration(2*time.Hour))
+			go setUnloadModelTimer(id, time.Duration(2*time.Hour))
 		}
 	}()
 	return modelID, nil
 }
 
+//tagLocales gives the locales used by the tags in order
+func tagLocales(tags []model.Tag) []string {
+	used := make(map[string]bool)
+	resp := make([]string, 0)
+	for _, t := range tags {
+		if t.Locale != "" && !used[t.Locale] {
+			used[t.Locale] = true
+			resp = append(resp, t.Locale)
+		}
+	}
+	sort.Strings(resp)
+	return resp
+}
+
+//trainLocaleModel trains the model of the locale with the tags can be used in the locale
+func trainLocaleModel(tags []model.Tag, enterprise string, loc string) (int64, error) {
+	locTags := make([]model.Tag, 0, len(tags))
+	for _, t := range tags {
+		if locale.Applies(t.Locale, loc) {
+			locTags = append(locTags, t)
+		}
+	}
+	now := time.Now().Unix()
+	id, err := modelDao.NewModel(dbLike.Conn(), &model.TModel{Enterprise: enterprise, CreateTime: now, UpdateTime: now,
+		Status: MStatTraining, Locale: loc})
+	if err != nil {
+		logger.Error.Printf("insert train model of locale %s failed. %s\n", loc, err)
+		return 0, err
+	}
+	return id, TrainOneModelByEnterprise(locTags, id, enterprise)
+}
+
+//switchUsingModels sets the last using models to deprecate and the new trained ones to using,
+//returns the deprecated models
+func switchUsingModels(enterprise string, trained map[string]int64) ([]int64, error) {
+	tx, err := dbLike.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//get the using models
+	status := MStatUsing
+	models, err := modelDao.TrainedModelInfo(tx, &model.TModelQuery{Enterprise: &enterprise, Status: &status})
+	if err != nil {
+		logger.Error.Printf("get using model failed. %s\n", err)
+		return nil, err
+	}
+	//set the current using models to deprecate status, including the locale which is not used by tags anymore
+	deprecated := make([]int64, 0, len(models))
+	for _, m := range models {
+		_, err = modelDao.UpdateModel(tx, &model.TModel{ID: m.ID, Status: MStatDeprecate})
+		if err != nil {
+			logger.Error.Printf("update model %d status failed. %s\n", m.ID, err)
+			return nil, err
+		}
+		deprecated = append(deprecated, int64(m.ID))
+	}
+	//set the new models to using status
+	for _, id := range trained {
+		_, err = modelDao.UpdateModel(tx, &model.TModel{ID: uint64(id), Status: MStatUsing})
+		if err != nil {
+			logger.Error.Printf("update model %d status failed. %s\n", id, err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logger.Error.Printf("commit failed. %s\n", err)
+		return nil, err
+	}
+	return deprecated, nil
+}
+
 func cleanModel(enterprise string) error {
 	models, err := GetModelByEnterprise(enterprise, MStatDeprecate)
 	if err != nil {
@@ -367,6 +431,28 @@ func GetUsingModelByEnterprise(enterprise string) ([]*model.TModel, error) {
 	return models, err
 }
 
+//GetUsingModelByLocale gets the using model to predict the calls in the locale.
+//The model of all tags is used if there is no model of the locale
+func GetUsingModelByLocale(enterprise string, loc string) (*model.TModel, error) {
+	models, err := GetUsingModelByEnterprise(enterprise)
+	if err != nil {
+		return nil, err
+	}
+	var shared *model.TModel
+	for _, m := range models {
+		if loc != "" && m.Locale == loc {
+			return m, nil
+		}
+		if m.Locale == "" && shared == nil {
+			shared = m
+		}
+	}
+	if shared == nil {
+		return nil, ErrNoModels
+	}
+	return shared, nil
+}
+
 //GetModelByEnterprise gets the model
 func GetModelByEnterprise(enterprise string, status int) ([]*model.TModel, error) {
 	if dbLike == nil {
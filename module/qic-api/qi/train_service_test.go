@@ -0,0 +1,61 @@
+package qi
+
+import (
+	"reflect"
+	"testing"
+
+	"emotibot.com/emotigo/module/qic-api/model/v1"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
+	"emotibot.com/emotigo/module/qic-api/util/test"
+)
+
+type mockLocaleModelDao struct {
+	mockTrainedModelDao
+	models []*model.TModel
+}
+
+func (m *mockLocaleModelDao) TrainedModelInfo(conn model.SqlLike, q *model.TModelQuery) ([]*model.TModel, error) {
+	return m.models, nil
+}
+
+func TestGetUsingModelByLocale(t *testing.T) {
+	dbLike = &test.MockDBLike{}
+	defer func(d model.TrainedModelDao) { modelDao = d }(modelDao)
+
+	modelDao = &mockLocaleModelDao{models: []*model.TModel{
+		&model.TModel{ID: 2, Locale: locale.ZhTW},
+		&model.TModel{ID: 1},
+	}}
+	cases := map[string]uint64{
+		"":          1,
+		locale.ZhTW: 2,
+		locale.EnUS: 1,
+	}
+	for loc, expected := range cases {
+		m, err := GetUsingModelByLocale("csbot", loc)
+		if err != nil {
+			t.Fatalf("expecting no error, but get %s\n", err)
+		}
+		if m.ID != expected {
+			t.Errorf("expecting model %d for locale %s, but get %d\n", expected, loc, m.ID)
+		}
+	}
+
+	modelDao = &mockLocaleModelDao{models: []*model.TModel{&model.TModel{ID: 2, Locale: locale.ZhTW}}}
+	if _, err := GetUsingModelByLocale("csbot", locale.EnUS); err != ErrNoModels {
+		t.Errorf("expecting ErrNoModels without model of all tags, but get %v\n", err)
+	}
+}
+
+func TestTagLocales(t *testing.T) {
+	tags := []model.Tag{
+		model.Tag{ID: 1},
+		model.Tag{ID: 2, Locale: locale.ZhTW},
+		model.Tag{ID: 3, Locale: locale.EnUS},
+		model.Tag{ID: 4, Locale: locale.ZhTW},
+	}
+	expected := []string{locale.EnUS, locale.ZhTW}
+	if output := tagLocales(tags); !reflect.DeepEqual(output, expected) {
+		t.Errorf("expecting %v, but get %v\n", expected, output)
+	}
+}
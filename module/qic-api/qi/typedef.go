@@ -56,6 +56,7 @@ type tag struct {
 	TagType      string   `json:"tag_type,omitempty"`
 	PosSentences []string `json:"pos_sentences,omitempty"`
 	NegSentences []string `json:"neg_sentences,omitempty"`
+	Locale       string   `json:"locale,omitempty"`
 }
 
 type controllerError struct {
@@ -11,6 +11,7 @@ import (
 	"emotibot.com/emotigo/module/admin-api/util/requestheader"
 	"emotibot.com/emotigo/module/qic-api/model/v1"
 	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 	"emotibot.com/emotigo/module/qic-api/util/request"
 	"emotibot.com/emotigo/pkg/logger"
 	"github.com/gorilla/mux"
@@ -27,12 +28,14 @@ type SensitiveWordInReq struct {
 	Score      int            `json:"score"`
 	Exception  ExceptionInReq `json:"exception"`
 	CategoryID int64          `json:"category_id,string"`
+	Locale     string         `json:"locale"`
 }
 
 type SensitiveWordInRes struct {
 	UUID     string `json:"sw_id"`
 	Name     string `json:"sw_name"`
 	Category int64  `json:"category_id"`
+	Locale   string `json:"locale,omitempty"`
 }
 
 type Exceptions struct {
@@ -47,6 +50,7 @@ type SensitiveWordInDetail struct {
 	Score      int        `json:"score"`
 	Exception  Exceptions `json:"exception"`
 	CategoryID int64      `json:"category_id"`
+	Locale     string     `json:"locale,omitempty"`
 }
 
 type CustomValues struct {
@@ -71,6 +75,10 @@ func handleCreateSensitiveWord(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !locale.IsValid(swInReq.Locale) {
+		http.Error(w, "invalid locale "+swInReq.Locale, http.StatusBadRequest)
+		return
+	}
 
 	userValues := []model.UserValue{}
 	for _, value := range swInReq.Exception.Values {
@@ -85,7 +93,7 @@ func handleCreateSensitiveWord(w http.ResponseWriter, r *http.Request) {
 		userValues = append(userValues, uv)
 	}
 
-	uid, err := CreateSensitiveWord(swInReq.Name, enterprise, swInReq.Score, swInReq.CategoryID, swInReq.Exception.Customer, swInReq.Exception.Staff, userValues)
+	uid, err := CreateSensitiveWord(swInReq.Name, enterprise, swInReq.Score, swInReq.CategoryID, swInReq.Locale, swInReq.Exception.Customer, swInReq.Exception.Staff, userValues)
 	if err != nil {
 		logger.Error.Printf("create sensitive word failed after CreateSensitiveWord, reason: %s", err.Error())
 		util.WriteJSONWithStatus(w, util.GenRetObj(ApiError.DB_ERROR, err.Error()), http.StatusInternalServerError)
@@ -156,6 +164,14 @@ func handleGetSensitiveWords(w http.ResponseWriter, r *http.Request) {
 		filter.Keyword = keyword
 	}
 
+	for _, l := range r.URL.Query()["locale"] {
+		if !locale.IsValid(l) {
+			http.Error(w, "invalid locale "+l, http.StatusBadRequest)
+			return
+		}
+		filter.Locale = append(filter.Locale, l)
+	}
+
 	total, words, err := GetSensitiveWords(filter)
 	if err != nil {
 		logger.Error.Printf("get sensitive words failed, err: %s", err.Error())
@@ -219,6 +235,7 @@ func handleGetSensitiveWord(w http.ResponseWriter, r *http.Request) {
 			Values:   customvals,
 		},
 		CategoryID: word.CategoryID,
+		Locale:     word.Locale,
 	}
 
 	util.WriteJSON(w, wordInDetail)
@@ -235,6 +252,10 @@ func handleUpdateSensitiveWord(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !locale.IsValid(swInReq.Locale) {
+		http.Error(w, "invalid locale "+swInReq.Locale, http.StatusBadRequest)
+		return
+	}
 
 	staffException := []model.SimpleSentence{}
 	for _, sUUID := range swInReq.Exception.Staff {
@@ -283,6 +304,7 @@ func handleUpdateSensitiveWord(w http.ResponseWriter, r *http.Request) {
 		CustomerException: customerException,
 		StaffException:    staffException,
 		UserValues:        userValues,
+		Locale:            swInReq.Locale,
 	}
 
 	err = UpdateSensitiveWord(word)
@@ -358,6 +380,7 @@ func toSensitiveWordInRes(words []model.SensitiveWord) (inres []SensitiveWordInR
 				UUID:     w.UUID,
 				Name:     w.Name,
 				Category: w.CategoryID,
+				Locale:   w.Locale,
 			},
 		)
 	}
@@ -5,6 +5,7 @@ import (
 
 	"emotibot.com/emotigo/module/qic-api/model/v1"
 	"emotibot.com/emotigo/module/qic-api/util/general"
+	"emotibot.com/emotigo/module/qic-api/util/locale"
 	"emotibot.com/emotigo/pkg/logger"
 	goahocorasick "github.com/anknown/ahocorasick"
 )
@@ -28,16 +29,23 @@ func IsSensitive(content string) ([]string, error) {
 		return matched, err
 	}
 
-	rwords := general.StringsToRunes(words)
+	//match in the normalized form, but returns the original words
+	origins := make(map[string]string, len(words))
+	normalized := make([]string, len(words))
+	for idx, w := range words {
+		normalized[idx] = locale.Normalize(w)
+		origins[normalized[idx]] = w
+	}
+	rwords := general.StringsToRunes(normalized)
 
 	m := new(goahocorasick.Machine)
 	if err = m.Build(rwords); err != nil {
 		return matched, err
 	}
 
-	terms := m.MultiPatternSearch([]rune(content), false)
+	terms := m.MultiPatternSearch([]rune(locale.Normalize(content)), false)
 	for _, t := range terms {
-		matched = append(matched, string(t.Word))
+		matched = append(matched, origins[string(t.Word)])
 	}
 
 	return matched, nil
@@ -85,7 +93,7 @@ func fillUserKeyID(values []model.UserValue, sqlLike model.SqlLike) (filledValue
 }
 
 // CreateSensitiveWord create a uuid and create a new sensitive word
-func CreateSensitiveWord(name, enterprise string, score int, categoryID int64, customerException, staffException []string, values []model.UserValue) (uid string, err error) {
+func CreateSensitiveWord(name, enterprise string, score int, categoryID int64, loc string, customerException, staffException []string, values []model.UserValue) (uid string, err error) {
 	uid, err = general.UUID()
 	if err != nil {
 		return
@@ -102,6 +110,7 @@ func CreateSensitiveWord(name, enterprise string, score int, categoryID int64, c
 		Enterprise: enterprise,
 		Score:      score,
 		CategoryID: categoryID,
+		Locale:     loc,
 	}
 
 	customerExceptionSentences, staffExceptionSentences, err := getWordExceptionSentences(customerException, staffException, enterprise, tx)
@@ -191,7 +191,7 @@ func TestCreateSensitiveWord(t *testing.T) {
 		Enterprise: "abcd",
 	}
 
-	uid, err := CreateSensitiveWord(word.Name, word.Enterprise, word.Score, 55, []string{}, []string{}, []model.UserValue{})
+	uid, err := CreateSensitiveWord(word.Name, word.Enterprise, word.Score, 55, "", []string{}, []string{}, []model.UserValue{})
 	if err != nil {
 		t.Errorf("error when create sensitive word, err: %s", err.Error())
 		return
@@ -0,0 +1,66 @@
+package locale
+
+import (
+	"strings"
+	"unicode"
+
+	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
+)
+
+//supported locales, empty locale means the resource is shared by all locales
+const (
+	ZhCN = "zh-cn"
+	ZhTW = "zh-tw"
+	ZhHK = "zh-hk"
+	EnUS = "en-us"
+)
+
+//Default is the locale used if nothing is assigned
+const Default = ZhCN
+
+var supported = map[string]bool{
+	ZhCN: true,
+	ZhTW: true,
+	ZhHK: true,
+	EnUS: true,
+}
+
+//IsValid checks the locale is supported, the empty locale is valid as shared
+func IsValid(l string) bool {
+	return l == "" || supported[l]
+}
+
+//Applies checks whether the resource with locale r can be used in the locale l
+func Applies(r string, l string) bool {
+	return r == "" || l == "" || r == l
+}
+
+//Normalize converts the text into the form used in matching.
+//traditional chinese is converted to simplified chinese, full-width to half-width and english is lower cased
+func Normalize(s string) string {
+	return strings.ToLower(zhconverter.T2S(ToHalfWidth(s)))
+}
+
+//Normalizes normalizes each text and returns the new slice
+func Normalizes(ss []string) []string {
+	resp := make([]string, len(ss))
+	for i, s := range ss {
+		resp[i] = Normalize(s)
+	}
+	return resp
+}
+
+//ToHalfWidth converts the full-width ascii and space into half-width
+func ToHalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			return r - 0xfee0
+		case unicode.Is(unicode.Zs, r) && r != ' ':
+			return ' '
+		}
+		return r
+	}, s)
+}
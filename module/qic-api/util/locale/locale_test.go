@@ -0,0 +1,60 @@
+package locale
+
+import (
+	"testing"
+
+	"emotibot.com/emotigo/module/admin-api/util/zhconverter"
+)
+
+func TestToHalfWidth(t *testing.T) {
+	cases := map[string]string{
+		"ＡＢＣ１２３":      "ABC123",
+		"你好，世界！":      "你好,世界!",
+		"hello　world": "hello world",
+		"沒有變化":        "沒有變化",
+	}
+	for input, expected := range cases {
+		if output := ToHalfWidth(input); output != expected {
+			t.Errorf("expecting %s, but get %s\n", expected, output)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	//dictionaries are loaded from the working directory in init, which is not the package directory in test
+	if err := zhconverter.LoadDictionaries("../../../admin-api/InitFiles/zh_converter"); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"Ｈｅｌｌｏ World": "hello world",
+		"請問您還有什麼問題":   "请问您还有什么问题",
+		"謝謝，再見！":      "谢谢,再见!",
+		"已经是简体":       "已经是简体",
+		"ＯＫ，沒問題":      "ok,没问题",
+	}
+	for input, expected := range cases {
+		if output := Normalize(input); output != expected {
+			t.Errorf("expecting %s for %s, but get %s\n", expected, input, output)
+		}
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	cases := map[string]bool{
+		"":      true,
+		ZhCN:    true,
+		ZhHK:    true,
+		"zh-sg": false,
+	}
+	for input, expected := range cases {
+		if output := IsValid(input); output != expected {
+			t.Errorf("expecting %t for %s, but get %t\n", expected, input, output)
+		}
+	}
+}
+
+func TestApplies(t *testing.T) {
+	if !Applies("", ZhTW) || !Applies(ZhTW, ZhTW) || Applies(ZhCN, ZhTW) {
+		t.Error("wrong locale applies result")
+	}
+}
@@ -2,6 +2,7 @@
 package logicaccess
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -18,6 +19,8 @@ type TrainUnit struct {
 	Keyword     *TrainKeyword    `json:"keyword"`
 	Dialog      *CommonTrainData `json:"dialogue_act"`
 	UsrResponse *CommonTrainData `json:"user_response"`
+	//Enterprise is the owner of the model, used as the key of Client.Scheduler
+	Enterprise string `json:"-"`
 }
 
 //TrainLogic is the logic attribute in request body for training cu module
@@ -41,6 +44,8 @@ type TrainKeyword struct {
 //TrainAPPID is common attribute, app_id, in request body for training cu module
 type TrainAPPID struct {
 	ID uint64 `json:"app_id,string"`
+	//Enterprise is the owner of the model, used as the key of Client.Scheduler
+	Enterprise string `json:"-"`
 }
 
 //TrainLogicData is the data attribute in logic which is the attribute in request body for training cu module
@@ -89,6 +94,8 @@ type BatchPredictRequest struct {
 	ID        uint64         `json:"app_id,string"`
 	Threshold int            `json:"threshold"`
 	Data      []*PredictData `json:"data"`
+	//Enterprise is the owner of the model, used as the key of Client.Scheduler
+	Enterprise string `json:"-"`
 }
 
 //SessionRequest is used to create a new session
@@ -147,9 +154,13 @@ type PredictClient interface {
 }
 
 //Client is the struct to implement the communicatoin with cu module
+//Training and batch prediction requests are sent through the Scheduler if it is set,
+//keyed by the enterprise, so the clients sharing the Scheduler are bounded together
+//and the enterprises are served fairly.
 type Client struct {
-	URL     string
-	Timeout time.Duration
+	URL       string
+	Timeout   time.Duration
+	Scheduler *Scheduler
 }
 
 //error message
@@ -162,6 +173,33 @@ type callHTTPPost func(string, interface{}, time.Duration) (int, []byte, error)
 
 var caller callHTTPPost = util.HTTPPostJSONWithStatusByteResp
 
+//schedule runs the job by the Scheduler with key, the job is run directly if no Scheduler.
+//The time waiting for its turn is bounded by the Timeout
+func (a *Client) schedule(key string, job func() error) error {
+	if a.Scheduler == nil {
+		return job()
+	}
+	ctx := context.Background()
+	if a.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.Timeout)
+		defer cancel()
+	}
+	return a.Scheduler.Do(ctx, key, job)
+}
+
+//scheduledPost posts to cu module by the Scheduler with key
+func (a *Client) scheduledPost(key string, url string, d interface{}) (resp []byte, err error) {
+	scheduleErr := a.schedule(key, func() error {
+		resp, err = a.postToModule(url, d)
+		return err
+	})
+	if err == nil {
+		err = scheduleErr
+	}
+	return
+}
+
 func (a *Client) postToModule(url string, d interface{}) ([]byte, error) {
 	if d == nil {
 		return nil, ErrNoRequest
@@ -207,13 +245,16 @@ func (a *Client) Train(d *TrainUnit) error {
 		d.Keyword = &TrainKeyword{ID: d.Logic.ID, Data: make([]*TrainKeywordData, 0)}
 	}
 
-	_, err := a.postToModule(a.URL+"/train", d)
+	_, err := a.scheduledPost(d.Enterprise, a.URL+"/train", d)
 	return err
 }
 
 //Status calls api /status to get the training status
 func (a *Client) Status(d *TrainAPPID) (string, error) {
-	resp, err := a.postToModule(a.URL+"/status", d)
+	if d == nil {
+		return "", ErrNoRequest
+	}
+	resp, err := a.scheduledPost(d.Enterprise, a.URL+"/status", d)
 	if err != nil {
 		return "", err
 	}
@@ -251,7 +292,7 @@ func (a *Client) BatchPredict(d *BatchPredictRequest) ([]byte, error) {
 	if d == nil || d.Threshold < 0 || len(d.Data) == 0 {
 		return nil, ErrRequest
 	}
-	return a.postToModule(a.URL+"/batch_predict", d)
+	return a.scheduledPost(d.Enterprise, a.URL+"/batch_predict", d)
 }
 
 //BatchPredictAndUnMarshal predicts the batch reuslt
@@ -259,7 +300,7 @@ func (a *Client) BatchPredictAndUnMarshal(d *BatchPredictRequest) (*PredictResul
 	if d == nil || d.Threshold < 0 || len(d.Data) == 0 {
 		return nil, ErrRequest
 	}
-	respBytes, err := a.postToModule(a.URL+"/batch_predict", d)
+	respBytes, err := a.scheduledPost(d.Enterprise, a.URL+"/batch_predict", d)
 	if err != nil {
 		return nil, err
 	}
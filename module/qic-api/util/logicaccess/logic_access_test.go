@@ -2,6 +2,7 @@ package logicaccess
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"testing"
@@ -132,3 +133,37 @@ func TestSessionDelete(t *testing.T) {
 		t.Errorf("expecting no error, but get %s\n", err)
 	}
 }
+
+func TestClientScheduled(t *testing.T) {
+	SetupMockPostMethod()
+	s := NewScheduler(SchedulerConfig{})
+	c := &Client{URL: "http://127.0.0.1", Timeout: time.Second, Scheduler: s}
+
+	_, err := c.BatchPredictAndUnMarshal(&BatchPredictRequest{ID: 1, Enterprise: "csbot", Data: []*PredictData{&PredictData{SentenceID: 1, Sentence: "hello"}}})
+	if err != nil {
+		t.Fatalf("expecting no error, but get %s\n", err)
+	}
+	err = c.Train(&TrainUnit{Logic: &TrainLogic{}, Keyword: &TrainKeyword{}, Enterprise: "csbot"})
+	if err != nil {
+		t.Fatalf("expecting no error, but get %s\n", err)
+	}
+	_, err = c.Status(&TrainAPPID{ID: 1, Enterprise: "csbot"})
+	if err != nil {
+		t.Fatalf("expecting no error, but get %s\n", err)
+	}
+	if stats := s.Stats(); stats.Processed != 3 {
+		t.Errorf("expecting predict, train and status are scheduled, but get %+v\n", stats)
+	}
+
+	//the circuit is opened by the failed requests, the following one is rejected without sending
+	caller = func(url string, d interface{}, timeout time.Duration) (int, []byte, error) {
+		return 0, nil, errors.New("mock failure")
+	}
+	defer SetupMockPostMethod()
+	for i := 0; i < DefaultBreakerThreshold; i++ {
+		c.Train(&TrainUnit{Logic: &TrainLogic{}, Keyword: &TrainKeyword{}, Enterprise: "csbot"})
+	}
+	if _, err = c.Status(&TrainAPPID{ID: 1, Enterprise: "csbot"}); err != ErrCircuitOpen {
+		t.Errorf("expecting ErrCircuitOpen, but get %v\n", err)
+	}
+}
@@ -0,0 +1,317 @@
+package logicaccess
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//error message of the scheduler
+var (
+	ErrQueueFull   = errors.New("too many requests are waiting for cu module")
+	ErrCircuitOpen = errors.New("cu module is unavailable, circuit is open")
+)
+
+//circuit breaker status
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+//default value of the SchedulerConfig
+const (
+	DefaultMaxConcurrency   = 8
+	DefaultMaxQueue         = 1000
+	DefaultBreakerThreshold = 10
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+//SchedulerConfig is the setting of the Scheduler, zero value uses the default one
+type SchedulerConfig struct {
+	//MaxConcurrency is the number of requests sending to cu module at the same time
+	MaxConcurrency int
+	//MaxQueue is the number of requests allowed to wait, the exceeded one is rejected by ErrQueueFull
+	MaxQueue int
+	//BreakerThreshold is the number of continuous failures to open the circuit
+	BreakerThreshold int
+	//BreakerCooldown is the duration the circuit keeps open before trying again
+	BreakerCooldown time.Duration
+}
+
+//LatencyStats gives the count, average and max duration in milliseconds
+type LatencyStats struct {
+	Count uint64  `json:"count"`
+	Avg   float64 `json:"avg_ms"`
+	Max   float64 `json:"max_ms"`
+	total time.Duration
+	max   time.Duration
+}
+
+func (l *LatencyStats) add(d time.Duration) {
+	l.Count++
+	l.total += d
+	if d > l.max {
+		l.max = d
+	}
+}
+
+func (l LatencyStats) export() LatencyStats {
+	if l.Count > 0 {
+		l.Avg = float64(l.total) / float64(l.Count) / float64(time.Millisecond)
+	}
+	l.Max = float64(l.max) / float64(time.Millisecond)
+	return l
+}
+
+//SchedulerStats is the snapshot of the Scheduler metrics
+type SchedulerStats struct {
+	Running     int            `json:"running"`
+	Queued      int            `json:"queued"`
+	QueuedByKey map[string]int `json:"queued_by_key"`
+	Processed   uint64         `json:"processed"`
+	Failed      uint64         `json:"failed"`
+	Rejected    uint64         `json:"rejected"`
+	Breaker     string         `json:"breaker"`
+	Wait        LatencyStats   `json:"wait"`
+	Latency     LatencyStats   `json:"latency"`
+}
+
+type ticket struct {
+	key      string
+	ready    chan struct{}
+	enqueued time.Time
+	granted  bool
+}
+
+//Scheduler bounds the requests sent to cu module.
+//Waiting requests are grouped by key and dispatched in round robin between keys,
+//so a key with lots of requests can not starve the others.
+//Continuous failures open the circuit and reject the requests until the cooldown is passed.
+type Scheduler struct {
+	cfg SchedulerConfig
+
+	lock    sync.Mutex
+	queues  map[string][]*ticket
+	keys    []string
+	next    int
+	queued  int
+	running int
+
+	breaker   string
+	failures  int
+	openUntil time.Time
+	probing   bool
+
+	processed uint64
+	failed    uint64
+	rejected  uint64
+	wait      LatencyStats
+	latency   LatencyStats
+}
+
+//NewScheduler creates a Scheduler, the unset config is replaced by the default value
+func NewScheduler(cfg SchedulerConfig) *Scheduler {
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if cfg.MaxQueue <= 0 {
+		cfg.MaxQueue = DefaultMaxQueue
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = DefaultBreakerCooldown
+	}
+	return &Scheduler{
+		cfg:     cfg,
+		queues:  make(map[string][]*ticket),
+		breaker: BreakerClosed,
+	}
+}
+
+//Do waits for its turn of the key and runs the job.
+//It returns ErrQueueFull or ErrCircuitOpen without running the job if the scheduler can not take it,
+//or the ctx error if ctx is done before its turn.
+func (s *Scheduler) Do(ctx context.Context, key string, job func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t := &ticket{key: key, ready: make(chan struct{}), enqueued: time.Now()}
+
+	s.lock.Lock()
+	probe, err := s.admit()
+	if err != nil {
+		s.rejected++
+		s.lock.Unlock()
+		return err
+	}
+	if s.queued >= s.cfg.MaxQueue {
+		if probe {
+			s.probing = false
+		}
+		s.rejected++
+		s.lock.Unlock()
+		return ErrQueueFull
+	}
+	s.enqueue(t)
+	s.dispatch()
+	s.lock.Unlock()
+
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+		s.lock.Lock()
+		if !t.granted {
+			s.remove(t)
+			if probe {
+				s.probing = false
+			}
+			s.lock.Unlock()
+			return ctx.Err()
+		}
+		s.lock.Unlock()
+	}
+
+	start := time.Now()
+	err = job()
+	finished := time.Now()
+
+	s.lock.Lock()
+	s.running--
+	s.processed++
+	s.wait.add(start.Sub(t.enqueued))
+	s.latency.add(finished.Sub(start))
+	if err != nil {
+		s.failed++
+	}
+	s.record(err == nil, probe, finished)
+	s.dispatch()
+	s.lock.Unlock()
+	return err
+}
+
+//admit checks the circuit, probe is true if the request is the only one allowed in half-open status
+func (s *Scheduler) admit() (probe bool, err error) {
+	switch s.breaker {
+	case BreakerOpen:
+		if time.Now().Before(s.openUntil) {
+			return false, ErrCircuitOpen
+		}
+		s.breaker = BreakerHalfOpen
+		fallthrough
+	case BreakerHalfOpen:
+		if s.probing {
+			return false, ErrCircuitOpen
+		}
+		s.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+//record updates the circuit by the job result
+func (s *Scheduler) record(success bool, probe bool, now time.Time) {
+	if probe {
+		s.probing = false
+	}
+	if success {
+		s.failures = 0
+		if probe {
+			s.breaker = BreakerClosed
+		}
+		return
+	}
+	s.failures++
+	if probe || (s.breaker == BreakerClosed && s.failures >= s.cfg.BreakerThreshold) {
+		s.breaker = BreakerOpen
+		s.openUntil = now.Add(s.cfg.BreakerCooldown)
+	}
+}
+
+func (s *Scheduler) enqueue(t *ticket) {
+	if _, ok := s.queues[t.key]; !ok {
+		s.keys = append(s.keys, t.key)
+	}
+	s.queues[t.key] = append(s.queues[t.key], t)
+	s.queued++
+}
+
+//remove takes the waiting ticket out of queue
+func (s *Scheduler) remove(t *ticket) {
+	q := s.queues[t.key]
+	for i, v := range q {
+		if v == t {
+			q = append(q[:i], q[i+1:]...)
+			s.queued--
+			break
+		}
+	}
+	if len(q) > 0 {
+		s.queues[t.key] = q
+		return
+	}
+	s.removeKey(t.key)
+}
+
+func (s *Scheduler) removeKey(key string) {
+	delete(s.queues, key)
+	for i, k := range s.keys {
+		if k == key {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			if i < s.next {
+				s.next--
+			}
+			break
+		}
+	}
+}
+
+//dispatch grants the waiting tickets in round robin of keys until no free slot
+func (s *Scheduler) dispatch() {
+	for s.running < s.cfg.MaxConcurrency && s.queued > 0 {
+		if s.next >= len(s.keys) {
+			s.next = 0
+		}
+		key := s.keys[s.next]
+		q := s.queues[key]
+		t := q[0]
+		s.queued--
+		s.running++
+		t.granted = true
+		close(t.ready)
+		if len(q) > 1 {
+			s.queues[key] = q[1:]
+			s.next++
+		} else {
+			s.removeKey(key)
+		}
+	}
+}
+
+//Stats gives the snapshot of the metrics
+func (s *Scheduler) Stats() SchedulerStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	byKey := make(map[string]int, len(s.queues))
+	for k, q := range s.queues {
+		byKey[k] = len(q)
+	}
+	breaker := s.breaker
+	if breaker == BreakerOpen && !time.Now().Before(s.openUntil) {
+		breaker = BreakerHalfOpen
+	}
+	return SchedulerStats{
+		Running:     s.running,
+		Queued:      s.queued,
+		QueuedByKey: byKey,
+		Processed:   s.processed,
+		Failed:      s.failed,
+		Rejected:    s.rejected,
+		Breaker:     breaker,
+		Wait:        s.wait.export(),
+		Latency:     s.latency.export(),
+	}
+}
@@ -0,0 +1,133 @@
+package logicaccess
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerFairness(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{MaxConcurrency: 1})
+
+	//hold the only slot until all the requests are queued
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	go s.Do(context.Background(), "a", func() error {
+		close(started)
+		<-hold
+		return nil
+	})
+	<-started
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(key string) {
+		wg.Add(1)
+		go s.Do(context.Background(), key, func() error {
+			lock.Lock()
+			order = append(order, key)
+			lock.Unlock()
+			wg.Done()
+			return nil
+		})
+		for {
+			if s.Stats().QueuedByKey[key] > 0 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	enqueue("a")
+	enqueue("a")
+	enqueue("a")
+	enqueue("b")
+	close(hold)
+	wg.Wait()
+
+	if len(order) != 4 {
+		t.Fatalf("expecting 4 jobs done, but get %d\n", len(order))
+	}
+	if order[1] != "b" {
+		t.Errorf("expecting key b is served second, but get order %v\n", order)
+	}
+	stats := s.Stats()
+	if stats.Processed != 5 || stats.Running != 0 || stats.Queued != 0 {
+		t.Errorf("unexpected stats %+v\n", stats)
+	}
+}
+
+func TestSchedulerQueueFull(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{MaxConcurrency: 1, MaxQueue: 1})
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	go s.Do(context.Background(), "a", func() error {
+		close(started)
+		<-hold
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error)
+	go func() {
+		waited <- s.Do(ctx, "a", func() error { return nil })
+	}()
+	for s.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := s.Do(context.Background(), "b", func() error { return nil })
+	if err != ErrQueueFull {
+		t.Errorf("expecting ErrQueueFull, but get %v\n", err)
+	}
+
+	cancel()
+	if err = <-waited; err != context.Canceled {
+		t.Errorf("expecting canceled, but get %v\n", err)
+	}
+	if q := s.Stats().Queued; q != 0 {
+		t.Errorf("expecting the canceled request leaves the queue, but get %d\n", q)
+	}
+	close(hold)
+}
+
+func TestSchedulerBreaker(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond})
+	fail := errors.New("fail")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := s.Do(ctx, "a", func() error { return fail }); err != fail {
+			t.Fatalf("expecting job error, but get %v\n", err)
+		}
+	}
+	if b := s.Stats().Breaker; b != BreakerOpen {
+		t.Fatalf("expecting breaker open, but get %s\n", b)
+	}
+	if err := s.Do(ctx, "a", func() error { return nil }); err != ErrCircuitOpen {
+		t.Errorf("expecting ErrCircuitOpen, but get %v\n", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	//the failed probe opens the circuit again
+	if err := s.Do(ctx, "a", func() error { return fail }); err != fail {
+		t.Errorf("expecting the probe runs, but get %v\n", err)
+	}
+	if err := s.Do(ctx, "a", func() error { return nil }); err != ErrCircuitOpen {
+		t.Errorf("expecting ErrCircuitOpen, but get %v\n", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := s.Do(ctx, "a", func() error { return nil }); err != nil {
+		t.Errorf("expecting the probe passed, but get %v\n", err)
+	}
+	if b := s.Stats().Breaker; b != BreakerClosed {
+		t.Errorf("expecting breaker closed, but get %s\n", b)
+	}
+	if r := s.Stats().Rejected; r != 2 {
+		t.Errorf("expecting 2 rejected, but get %d\n", r)
+	}
+}
@@ -264,6 +264,48 @@ func (c *Client) SetSimilarQuestion(appID, sq string, lq ...string) error {
 	return nil
 }
 
+// SetStandardQuestions insert given slice of sq to the ssm datastore.
+func (c *Client) SetStandardQuestions(appID string, sq ...string) error {
+	entities := make([]entity, len(sq))
+	for i, content := range sq {
+		entities[i] = entity{
+			Content: content,
+		}
+	}
+	var buffer bytes.Buffer
+	json.NewEncoder(&buffer).Encode(request{
+		Op:           "insert",
+		Category:     "sq",
+		AppID:        appID,
+		UserRecordID: 0,
+		Data: data{
+			Subop:    "defaultSubop",
+			Entities: entities,
+		},
+	})
+	r, err := http.NewRequest(http.MethodPost, c.address, &buffer)
+	if err != nil {
+		return fmt.Errorf("new request error, %v", err)
+	}
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return fmt.Errorf("do request failed, %v", err)
+	}
+	defer resp.Body.Close()
+	var respBody RawResponse
+	err = json.NewDecoder(resp.Body).Decode(&respBody)
+	if err != nil {
+		return fmt.Errorf("dal error: response body format failed, %v", err)
+	}
+	if respBody.ErrNo != "OK" {
+		return &DetailError{
+			ErrMsg:  "got response error " + respBody.ErrNo + " with message " + respBody.ErrMessage,
+			Results: respBody.Operation,
+		}
+	}
+	return nil
+}
+
 // DeleteSimilarQuestions Delete lq from the ssm datastore.
 func (c *Client) DeleteSimilarQuestions(appID string, lq ...string) error {
 	var entities = make([]entity, len(lq))
@@ -0,0 +1,120 @@
+// Package rollout evaluates gradual rollout rules of switches and functions.
+// The rules are published to consul by admin-api, and the chat path can use
+// Features.IsEnabled to decide if a feature is enabled for a user.
+package rollout
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Rule decide which users can use an enabled feature.
+// A user in DenyUsers is never enabled and a user in AllowUsers is always enabled,
+// other users are checked by time window, platforms and percentage in order.
+// A request without user id has no stable bucket, so it is only enabled
+// when percentage is 100.
+type Rule struct {
+	// Percentage of users enabled, from 0 to 100
+	Percentage int      `json:"percentage"`
+	AllowUsers []string `json:"allow_users,omitempty"`
+	DenyUsers  []string `json:"deny_users,omitempty"`
+	// StartTime and EndTime are unix timestamps, 0 means no limit
+	StartTime int64 `json:"start_time,omitempty"`
+	EndTime   int64 `json:"end_time,omitempty"`
+	// Platforms enabled, empty means all platforms
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// Context is the information of a request to evaluate rules
+type Context struct {
+	UserID   string
+	Platform string
+	Time     time.Time
+}
+
+// Feature is the resolved rollout of a switch or function
+type Feature struct {
+	// Enabled is the on/off status, the rule is only used when it is true
+	Enabled bool  `json:"enabled"`
+	Rule    *Rule `json:"rule,omitempty"`
+}
+
+// Features are the resolved rollout of an app, key is the code of switch or function
+type Features map[string]*Feature
+
+// Validate check the values of rule
+func (rule *Rule) Validate() error {
+	if rule.Percentage < 0 || rule.Percentage > 100 {
+		return fmt.Errorf("percentage should be between 0 and 100")
+	}
+	if rule.StartTime > 0 && rule.EndTime > 0 && rule.StartTime > rule.EndTime {
+		return fmt.Errorf("start time should be before end time")
+	}
+	return nil
+}
+
+// Bucket returns the stable bucket, from 0 to 99, of userID for feature code.
+// Code is part of the hash so that users of different features are not the same group.
+func Bucket(code, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(code))
+	h.Write([]byte{0})
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
+
+func contains(list []string, target string) bool {
+	for _, s := range list {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns if the rule of feature code matches ctx
+func (rule *Rule) Match(code string, ctx Context) bool {
+	if ctx.UserID == "" {
+		return rule.matchWindow(ctx) && rule.Percentage >= 100
+	}
+	if contains(rule.DenyUsers, ctx.UserID) {
+		return false
+	}
+	if contains(rule.AllowUsers, ctx.UserID) {
+		return true
+	}
+	return rule.matchWindow(ctx) && Bucket(code, ctx.UserID) < rule.Percentage
+}
+
+// matchWindow returns if ctx is in the time window and platforms of rule
+func (rule *Rule) matchWindow(ctx Context) bool {
+	now := ctx.Time.Unix()
+	if rule.StartTime > 0 && now < rule.StartTime {
+		return false
+	}
+	if rule.EndTime > 0 && now > rule.EndTime {
+		return false
+	}
+	if len(rule.Platforms) > 0 && !contains(rule.Platforms, ctx.Platform) {
+		return false
+	}
+	return true
+}
+
+// IsEnabled returns if feature code is enabled for ctx.
+// A feature without rule is enabled for all users when it is on.
+// found is false if the code is not in features.
+func (features Features) IsEnabled(code string, ctx Context) (enabled bool, found bool) {
+	feature, ok := features[code]
+	if !ok {
+		return false, false
+	}
+	if !feature.Enabled {
+		return false, true
+	}
+	if feature.Rule == nil {
+		return true, true
+	}
+	return feature.Rule.Match(code, ctx), true
+}
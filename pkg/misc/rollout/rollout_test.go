@@ -0,0 +1,79 @@
+package rollout
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	if Bucket("faq", "user1") != Bucket("faq", "user1") {
+		t.Fatal("expect bucket to be stable")
+	}
+	enabled := 0
+	rule := &Rule{Percentage: 30}
+	for i := 0; i < 10000; i++ {
+		if rule.Match("faq", Context{UserID: fmt.Sprintf("user%d", i), Time: time.Now()}) {
+			enabled++
+		}
+	}
+	if enabled < 2700 || enabled > 3300 {
+		t.Fatalf("expect about 30%% users enabled, but got %d", enabled)
+	}
+}
+
+func TestIsEnabled(t *testing.T) {
+	now := time.Now()
+	features := Features{
+		"off": &Feature{Enabled: false, Rule: &Rule{Percentage: 100}},
+		"on":  &Feature{Enabled: true},
+		"gray": &Feature{Enabled: true, Rule: &Rule{
+			Percentage: 0,
+			AllowUsers: []string{"allowed", "denied"},
+			DenyUsers:  []string{"denied"},
+		}},
+		"window": &Feature{Enabled: true, Rule: &Rule{
+			Percentage: 100,
+			StartTime:  now.Add(-time.Hour).Unix(),
+			EndTime:    now.Add(time.Hour).Unix(),
+			Platforms:  []string{"app"},
+		}},
+		"half": &Feature{Enabled: true, Rule: &Rule{Percentage: 50, AllowUsers: []string{""}}},
+	}
+	testCases := []struct {
+		code    string
+		ctx     Context
+		enabled bool
+		found   bool
+	}{
+		{"unknown", Context{UserID: "u", Time: now}, false, false},
+		{"off", Context{UserID: "u", Time: now}, false, true},
+		{"on", Context{UserID: "u", Time: now}, true, true},
+		{"gray", Context{UserID: "allowed", Time: now}, true, true},
+		{"gray", Context{UserID: "denied", Time: now}, false, true},
+		{"gray", Context{UserID: "other", Time: now}, false, true},
+		{"window", Context{UserID: "u", Platform: "app", Time: now}, true, true},
+		{"window", Context{UserID: "u", Platform: "web", Time: now}, false, true},
+		{"window", Context{UserID: "u", Platform: "app", Time: now.Add(2 * time.Hour)}, false, true},
+		{"window", Context{Platform: "app", Time: now}, true, true},
+		{"half", Context{Time: now}, false, true},
+	}
+	for _, tc := range testCases {
+		enabled, found := features.IsEnabled(tc.code, tc.ctx)
+		if enabled != tc.enabled || found != tc.found {
+			t.Errorf("expect %s of %+v to be (%v, %v), but got (%v, %v)", tc.code, tc.ctx, tc.enabled, tc.found, enabled, found)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (&Rule{Percentage: 101}).Validate(); err == nil {
+		t.Error("expect percentage over 100 to be invalid")
+	}
+	if err := (&Rule{Percentage: 50, StartTime: 10, EndTime: 5}).Validate(); err == nil {
+		t.Error("expect start time after end time to be invalid")
+	}
+	if err := (&Rule{Percentage: 50, StartTime: 5}).Validate(); err != nil {
+		t.Errorf("expect valid rule, but got %v", err)
+	}
+}
@@ -74,3 +74,15 @@ func GetFile(namespace string, path string) ([]byte, error) {
 
 	return b.Bytes(), nil
 }
+
+func DeleteFile(namespace string, path string) error {
+	if minioClient == nil {
+		return ErrClientNotInit
+	}
+
+	err := minioClient.RemoveObject(namespace, path)
+	if err != nil {
+		return fmt.Errorf("Remove object - %s", err.Error())
+	}
+	return nil
+}